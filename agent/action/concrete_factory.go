@@ -1,14 +1,20 @@
 package action
 
 import (
+	"github.com/pivotal-golang/clock"
+
 	boshappl "github.com/cloudfoundry/bosh-agent/agent/applier"
 	boshas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
+	boshagentblobstore "github.com/cloudfoundry/bosh-agent/agent/blobstore"
 	boshcomp "github.com/cloudfoundry/bosh-agent/agent/compiler"
 	boshscript "github.com/cloudfoundry/bosh-agent/agent/script"
+	boshsshusers "github.com/cloudfoundry/bosh-agent/agent/sshusers"
 	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
+	boshhandler "github.com/cloudfoundry/bosh-agent/handler"
 	boshjobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor"
 	boshnotif "github.com/cloudfoundry/bosh-agent/notification"
 	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	boshinventory "github.com/cloudfoundry/bosh-agent/platform/inventory"
 	boshntp "github.com/cloudfoundry/bosh-agent/platform/ntp"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	boshblob "github.com/cloudfoundry/bosh-utils/blobstore"
@@ -23,15 +29,18 @@ type concreteFactory struct {
 func NewFactory(
 	settingsService boshsettings.Service,
 	platform boshplatform.Platform,
-	blobstore boshblob.Blobstore,
+	blobstore boshagentblobstore.SwitchableBlobstore,
 	blobManager boshblob.BlobManagerInterface,
 	taskService boshtask.Service,
 	notifier boshnotif.Notifier,
+	mbusHandler boshhandler.Handler,
 	applier boshappl.Applier,
 	compiler boshcomp.Compiler,
 	jobSupervisor boshjobsuper.JobSupervisor,
 	specService boshas.V1Service,
 	jobScriptProvider boshscript.JobScriptProvider,
+	sshUserReaper boshsshusers.Reaper,
+	sshUserRoles boshsshusers.RoleStore,
 	logger boshlog.Logger,
 ) (factory Factory) {
 	compressor := platform.GetCompressor()
@@ -40,6 +49,8 @@ func NewFactory(
 	vitalsService := platform.GetVitalsService()
 	certManager := platform.GetCertManager()
 	ntpService := boshntp.NewConcreteService(platform.GetFs(), dirProvider)
+	inventoryCollector := boshinventory.NewCollector(platform.GetFs(), logger)
+	diskFreezer := NewDiskFreezer(platform, specService, jobScriptProvider, dirProvider, clock.NewClock(), logger)
 
 	factory = concreteFactory{
 		availableActions: map[string]Action{
@@ -49,15 +60,19 @@ func NewFactory(
 			"cancel_task": NewCancelTask(taskService),
 
 			// VM admin
-			"ssh":             NewSSH(settingsService, platform, dirProvider, logger),
+			"ssh":             NewSSH(settingsService, platform, dirProvider, sshUserReaper, sshUserRoles, logger),
 			"fetch_logs":      NewFetchLogs(compressor, copier, blobstore, dirProvider),
-			"update_settings": NewUpdateSettings(settingsService, platform, certManager, logger),
+			"update_settings": NewUpdateSettings(settingsService, platform, certManager, mbusHandler, blobstore, logger),
+			"instance_info":   NewInstanceInfo(settingsService, platform, inventoryCollector, dirProvider, logger),
+
+			// Trusted certificates
+			"list_trusted_certs": NewListTrustedCerts(settingsService),
 
 			// Job management
 			"prepare":    NewPrepare(applier),
 			"apply":      NewApply(applier, specService, settingsService, dirProvider.InstanceDir(), platform.GetFs()),
 			"start":      NewStart(jobSupervisor, applier, specService),
-			"stop":       NewStop(jobSupervisor),
+			"stop":       NewStop(jobSupervisor, platform),
 			"drain":      NewDrain(notifier, specService, jobScriptProvider, jobSupervisor, logger),
 			"get_state":  NewGetState(settingsService, specService, jobSupervisor, vitalsService, ntpService),
 			"run_errand": NewRunErrand(specService, dirProvider.JobsDir(), platform.GetRunner(), logger),
@@ -75,6 +90,8 @@ func NewFactory(
 			"migrate_disk": NewMigrateDisk(platform, dirProvider),
 			"mount_disk":   NewMountDisk(settingsService, platform, dirProvider, logger),
 			"unmount_disk": NewUnmountDisk(settingsService, platform),
+			"freeze_disk":  NewFreezeDisk(diskFreezer),
+			"thaw_disk":    NewThawDisk(diskFreezer),
 
 			// ARP cache management
 			"delete_arp_entries": NewDeleteARPEntries(platform),
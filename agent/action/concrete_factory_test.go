@@ -4,19 +4,25 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	"github.com/pivotal-golang/clock"
+
 	. "github.com/cloudfoundry/bosh-agent/agent/action"
 
 	boshscript "github.com/cloudfoundry/bosh-agent/agent/script"
+	boshinventory "github.com/cloudfoundry/bosh-agent/platform/inventory"
 	boshntp "github.com/cloudfoundry/bosh-agent/platform/ntp"
 	boshdir "github.com/cloudfoundry/bosh-agent/settings/directories"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 
 	fakeas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec/fakes"
 	fakeappl "github.com/cloudfoundry/bosh-agent/agent/applier/fakes"
+	fakeagentblobstore "github.com/cloudfoundry/bosh-agent/agent/blobstore/fakes"
 	fakecomp "github.com/cloudfoundry/bosh-agent/agent/compiler/fakes"
 	fakescript "github.com/cloudfoundry/bosh-agent/agent/script/fakes"
+	fakesshusers "github.com/cloudfoundry/bosh-agent/agent/sshusers/fakes"
 	faketask "github.com/cloudfoundry/bosh-agent/agent/task/fakes"
 	fakejobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor/fakes"
+	fakembus "github.com/cloudfoundry/bosh-agent/mbus/fakes"
 	fakenotif "github.com/cloudfoundry/bosh-agent/notification/fakes"
 	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
 	fakesettings "github.com/cloudfoundry/bosh-agent/settings/fakes"
@@ -29,15 +35,18 @@ var _ = Describe("concreteFactory", func() {
 	var (
 		settingsService   *fakesettings.FakeSettingsService
 		platform          *fakeplatform.FakePlatform
-		blobstore         *fakeblobstore.FakeBlobstore
+		blobstore         *fakeagentblobstore.FakeSwitchableBlobstore
 		blobManager       *fakeblobstore.FakeBlobManagerInterface
 		taskService       *faketask.FakeService
 		notifier          *fakenotif.FakeNotifier
+		mbusHandler       *fakembus.FakeHandler
 		applier           *fakeappl.FakeApplier
 		compiler          *fakecomp.FakeCompiler
 		jobSupervisor     *fakejobsuper.FakeJobSupervisor
 		specService       *fakeas.FakeV1Service
 		jobScriptProvider boshscript.JobScriptProvider
+		sshUserReaper     *fakesshusers.FakeReaper
+		sshUserRoles      *fakesshusers.FakeRoleStore
 		factory           Factory
 		logger            boshlog.Logger
 	)
@@ -45,15 +54,18 @@ var _ = Describe("concreteFactory", func() {
 	BeforeEach(func() {
 		settingsService = &fakesettings.FakeSettingsService{}
 		platform = fakeplatform.NewFakePlatform()
-		blobstore = &fakeblobstore.FakeBlobstore{}
+		blobstore = fakeagentblobstore.NewFakeSwitchableBlobstore()
 		blobManager = &fakeblobstore.FakeBlobManagerInterface{}
 		taskService = &faketask.FakeService{}
 		notifier = fakenotif.NewFakeNotifier()
+		mbusHandler = fakembus.NewFakeHandler()
 		applier = fakeappl.NewFakeApplier()
 		compiler = fakecomp.NewFakeCompiler()
 		jobSupervisor = fakejobsuper.NewFakeJobSupervisor()
 		specService = fakeas.NewFakeV1Service()
 		jobScriptProvider = &fakescript.FakeJobScriptProvider{}
+		sshUserReaper = &fakesshusers.FakeReaper{}
+		sshUserRoles = fakesshusers.NewFakeRoleStore()
 		logger = boshlog.NewLogger(boshlog.LevelNone)
 
 		factory = NewFactory(
@@ -63,11 +75,14 @@ var _ = Describe("concreteFactory", func() {
 			blobManager,
 			taskService,
 			notifier,
+			mbusHandler,
 			applier,
 			compiler,
 			jobSupervisor,
 			specService,
 			jobScriptProvider,
+			sshUserReaper,
+			sshUserRoles,
 			logger,
 		)
 	})
@@ -128,6 +143,18 @@ var _ = Describe("concreteFactory", func() {
 		Expect(action).To(Equal(NewMigrateDisk(platform, platform.GetDirProvider())))
 	})
 
+	It("freeze_disk", func() {
+		action, err := factory.Create("freeze_disk")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(action).To(Equal(NewFreezeDisk(NewDiskFreezer(platform, specService, jobScriptProvider, platform.GetDirProvider(), clock.NewClock(), logger))))
+	})
+
+	It("thaw_disk", func() {
+		action, err := factory.Create("thaw_disk")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(action).To(Equal(NewThawDisk(NewDiskFreezer(platform, specService, jobScriptProvider, platform.GetDirProvider(), clock.NewClock(), logger))))
+	})
+
 	It("mount_disk", func() {
 		action, err := factory.Create("mount_disk")
 		Expect(err).ToNot(HaveOccurred())
@@ -161,7 +188,31 @@ var _ = Describe("concreteFactory", func() {
 	It("ssh", func() {
 		action, err := factory.Create("ssh")
 		Expect(err).ToNot(HaveOccurred())
-		Expect(action).To(Equal(NewSSH(settingsService, platform, platform.GetDirProvider(), logger)))
+		Expect(action).To(Equal(NewSSH(settingsService, platform, platform.GetDirProvider(), sshUserReaper, sshUserRoles, logger)))
+	})
+
+	It("update_settings", func() {
+		action, err := factory.Create("update_settings")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(action).To(Equal(NewUpdateSettings(settingsService, platform, platform.GetCertManager(), mbusHandler, blobstore, logger)))
+	})
+
+	It("instance_info", func() {
+		action, err := factory.Create("instance_info")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(action).To(Equal(NewInstanceInfo(
+			settingsService,
+			platform,
+			boshinventory.NewCollector(platform.GetFs(), logger),
+			platform.GetDirProvider(),
+			logger,
+		)))
+	})
+
+	It("list_trusted_certs", func() {
+		action, err := factory.Create("list_trusted_certs")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(action).To(Equal(NewListTrustedCerts(settingsService)))
 	})
 
 	It("start", func() {
@@ -173,7 +224,7 @@ var _ = Describe("concreteFactory", func() {
 	It("stop", func() {
 		action, err := factory.Create("stop")
 		Expect(err).ToNot(HaveOccurred())
-		Expect(action).To(Equal(NewStop(jobSupervisor)))
+		Expect(action).To(Equal(NewStop(jobSupervisor, platform)))
 	})
 
 	It("unmount_disk", func() {
@@ -0,0 +1,354 @@
+package action
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pivotal-golang/clock"
+
+	boshas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
+	boshscript "github.com/cloudfoundry/bosh-agent/agent/script"
+	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+const (
+	preSnapshotScriptName  = "pre-snapshot"
+	postSnapshotScriptName = "post-snapshot"
+
+	autoThawRetryInterval = 5 * time.Second
+)
+
+// DiskFreezer quiesces jobs and freezes filesystems of persistent disk and
+// of associated disks mounted within it so that IaaS snapshots are
+// filesystem-consistent. Frozen disks are always thawed automatically
+// once timeout elapses.
+type DiskFreezer struct {
+	platform       boshplatform.Platform
+	specService    boshas.V1Service
+	scriptProvider boshscript.JobScriptProvider
+	dirProvider    boshdirs.Provider
+	timeService    clock.Clock
+
+	lock       sync.Mutex
+	thawCh     chan struct{}
+	autoThawed bool
+
+	// Mount points which are still frozen, in the order they were frozen
+	frozenMounts []string
+
+	logTag string
+	logger boshlog.Logger
+}
+
+func NewDiskFreezer(
+	platform boshplatform.Platform,
+	specService boshas.V1Service,
+	scriptProvider boshscript.JobScriptProvider,
+	dirProvider boshdirs.Provider,
+	timeService clock.Clock,
+	logger boshlog.Logger,
+) *DiskFreezer {
+	return &DiskFreezer{
+		platform:       platform,
+		specService:    specService,
+		scriptProvider: scriptProvider,
+		dirProvider:    dirProvider,
+		timeService:    timeService,
+
+		logTag: "DiskFreezer",
+		logger: logger,
+	}
+}
+
+func (f *DiskFreezer) Freeze(timeout time.Duration) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.thawCh != nil {
+		return bosherr.Error("Persistent disk is already frozen")
+	}
+
+	storeDir := f.dirProvider.StoreDir()
+
+	_, isMountPoint, err := f.platform.IsMountPoint(storeDir)
+	if err != nil {
+		return bosherr.WrapError(err, "Checking persistent disk mount point")
+	}
+
+	if !isMountPoint {
+		return bosherr.Error("Persistent disk is not mounted")
+	}
+
+	associatedMountPoints, err := f.associatedMountPoints()
+	if err != nil {
+		return bosherr.WrapError(err, "Finding associated persistent disk mount points")
+	}
+
+	mountPoints := append([]string{storeDir}, associatedMountPoints...)
+
+	err = f.runPreSnapshotScripts(timeout)
+	if err != nil {
+		f.runPostSnapshotScripts()
+		return bosherr.WrapError(err, "Running pre-snapshot scripts")
+	}
+
+	err = f.recordFrozenMounts(mountPoints)
+	if err != nil {
+		f.runPostSnapshotScripts()
+		return bosherr.WrapError(err, "Recording frozen persistent disk")
+	}
+
+	for i, mountPoint := range mountPoints {
+		err = f.platform.FreezeFilesystem(mountPoint)
+		if err != nil {
+			f.thawAfterFailedFreeze(mountPoints[:i])
+			f.runPostSnapshotScripts()
+			return bosherr.WrapErrorf(err, "Freezing persistent disk mounted at %s", mountPoint)
+		}
+	}
+
+	thawCh := make(chan struct{})
+
+	f.thawCh = thawCh
+	f.autoThawed = false
+	f.frozenMounts = mountPoints
+
+	timer := f.timeService.NewTimer(timeout)
+
+	go func() {
+		select {
+		case <-timer.C():
+			f.logger.Error(f.logTag, "Persistent disk was not thawed within %s, thawing automatically", timeout)
+		case <-thawCh:
+			timer.Stop()
+			return
+		}
+
+		// Disk stays frozen until thawing succeeds either here or through thaw_disk
+		for {
+			err := f.thaw(thawCh, true)
+			if err == nil {
+				return
+			}
+
+			f.logger.Error(f.logTag, "Automatically thawing persistent disk: %s", err.Error())
+
+			retryTimer := f.timeService.NewTimer(autoThawRetryInterval)
+
+			select {
+			case <-retryTimer.C():
+			case <-thawCh:
+				retryTimer.Stop()
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (f *DiskFreezer) Thaw() error {
+	f.lock.Lock()
+	thawCh := f.thawCh
+	autoThawed := f.autoThawed
+	f.lock.Unlock()
+
+	if thawCh == nil {
+		if autoThawed {
+			return bosherr.Error("Persistent disk was thawed automatically after timeout; snapshot may be inconsistent")
+		}
+		return bosherr.Error("Persistent disk is not frozen")
+	}
+
+	return f.thaw(thawCh, false)
+}
+
+func (f *DiskFreezer) thaw(thawCh chan struct{}, auto bool) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	// Disk may have been thawed concurrently by the other party
+	if f.thawCh != thawCh {
+		return nil
+	}
+
+	// Disk is considered frozen until all of its filesystems are actually
+	// thawed so that thawing can be retried; nested ones are thawed first
+	for len(f.frozenMounts) > 0 {
+		mountPoint := f.frozenMounts[len(f.frozenMounts)-1]
+
+		err := f.platform.ThawFilesystem(mountPoint)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Thawing persistent disk mounted at %s", mountPoint)
+		}
+
+		f.frozenMounts = f.frozenMounts[:len(f.frozenMounts)-1]
+
+		// Bootstrap cannot thaw filesystem which is not frozen anymore
+		if len(f.frozenMounts) > 0 {
+			err = f.recordFrozenMounts(f.frozenMounts)
+			if err != nil {
+				f.logger.Error(f.logTag, "Recording frozen persistent disk: %s", err.Error())
+			}
+		}
+	}
+
+	close(thawCh)
+	f.thawCh = nil
+	f.autoThawed = auto
+
+	f.removeFrozenDiskRecord()
+
+	err := f.runJobScripts(postSnapshotScriptName)
+	if err != nil {
+		return bosherr.WrapError(err, "Running post-snapshot scripts")
+	}
+
+	return nil
+}
+
+// associatedMountPoints returns mount points of associated disks which are
+// mounted as their own filesystems; they have to be frozen separately
+func (f *DiskFreezer) associatedMountPoints() ([]string, error) {
+	updateSettingsPath := filepath.Join(f.dirProvider.BoshDir(), "update_settings.json")
+
+	if !f.platform.GetFs().FileExists(updateSettingsPath) {
+		return nil, nil
+	}
+
+	contents, err := f.platform.GetFs().ReadFile(updateSettingsPath)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Reading update_settings.json")
+	}
+
+	var updateSettings boshsettings.UpdateSettings
+
+	err = json.Unmarshal(contents, &updateSettings)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Unmarshalling update_settings.json")
+	}
+
+	var mountPoints []string
+
+	for _, diskAssociation := range updateSettings.DiskAssociations {
+		if !diskAssociation.Mount {
+			continue
+		}
+
+		mountPoint, err := diskAssociation.ResolveMountPoint(f.dirProvider.StoreDir())
+		if err != nil {
+			return nil, err
+		}
+
+		_, isMountPoint, err := f.platform.IsMountPoint(mountPoint)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Checking mount point %s", mountPoint)
+		}
+
+		if isMountPoint {
+			mountPoints = append(mountPoints, mountPoint)
+		}
+	}
+
+	return mountPoints, nil
+}
+
+// thawAfterFailedFreeze thaws filesystems frozen before freezing failed
+func (f *DiskFreezer) thawAfterFailedFreeze(mountPoints []string) {
+	for i := len(mountPoints) - 1; i >= 0; i-- {
+		err := f.platform.ThawFilesystem(mountPoints[i])
+		if err != nil {
+			f.logger.Error(f.logTag, "Thawing persistent disk mounted at %s: %s", mountPoints[i], err.Error())
+
+			// Record is kept so that bootstrap thaws remaining filesystems
+			err = f.recordFrozenMounts(mountPoints[:i+1])
+			if err != nil {
+				f.logger.Error(f.logTag, "Recording frozen persistent disk: %s", err.Error())
+			}
+			return
+		}
+	}
+
+	f.removeFrozenDiskRecord()
+}
+
+func (f *DiskFreezer) recordFrozenMounts(mountPoints []string) error {
+	return f.platform.GetFs().WriteFileString(f.frozenDiskPath(), strings.Join(mountPoints, "\n"))
+}
+
+func (f *DiskFreezer) frozenDiskPath() string {
+	return filepath.Join(f.dirProvider.BoshDir(), boshplatform.FrozenDiskFileName)
+}
+
+func (f *DiskFreezer) removeFrozenDiskRecord() {
+	err := f.platform.GetFs().RemoveAll(f.frozenDiskPath())
+	if err != nil {
+		f.logger.Error(f.logTag, "Removing frozen persistent disk record: %s", err.Error())
+	}
+}
+
+func (f *DiskFreezer) runPostSnapshotScripts() {
+	err := f.runJobScripts(postSnapshotScriptName)
+	if err != nil {
+		f.logger.Error(f.logTag, "Running post-snapshot scripts: %s", err.Error())
+	}
+}
+
+// runPreSnapshotScripts stops waiting for scripts once timeout elapses
+// so that hanging script does not keep freezer locked indefinitely
+func (f *DiskFreezer) runPreSnapshotScripts(timeout time.Duration) error {
+	script, err := f.jobScripts(preSnapshotScriptName)
+	if err != nil {
+		return err
+	}
+
+	resultCh := make(chan error, 1)
+
+	go func() { resultCh <- script.Run() }()
+
+	timer := f.timeService.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case err = <-resultCh:
+		return err
+	case <-timer.C():
+		err = script.Cancel()
+		if err != nil {
+			f.logger.Error(f.logTag, "Canceling pre-snapshot scripts: %s", err.Error())
+		}
+
+		return bosherr.Errorf("Timed out after %s", timeout)
+	}
+}
+
+func (f *DiskFreezer) runJobScripts(scriptName string) error {
+	script, err := f.jobScripts(scriptName)
+	if err != nil {
+		return err
+	}
+
+	return script.Run()
+}
+
+func (f *DiskFreezer) jobScripts(scriptName string) (boshscript.CancellableScript, error) {
+	currentSpec, err := f.specService.Get()
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Getting current spec")
+	}
+
+	var scripts []boshscript.Script
+
+	for _, job := range currentSpec.Jobs() {
+		scripts = append(scripts, f.scriptProvider.NewScript(job.BundleName(), scriptName))
+	}
+
+	return f.scriptProvider.NewParallelScript(scriptName, scripts), nil
+}
@@ -0,0 +1,308 @@
+package action_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-golang/clock/fakeclock"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	"github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
+	fakeapplyspec "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec/fakes"
+	boshscript "github.com/cloudfoundry/bosh-agent/agent/script"
+	fakescript "github.com/cloudfoundry/bosh-agent/agent/script/fakes"
+	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
+	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+var _ = Describe("DiskFreezer", func() {
+	var (
+		platform              *fakeplatform.FakePlatform
+		specService           *fakeapplyspec.FakeV1Service
+		fakeJobScriptProvider *fakescript.FakeJobScriptProvider
+		timeService           *fakeclock.FakeClock
+		parallelScripts       map[string]*fakescript.FakeCancellableScript
+		ranScriptNames        []string
+		diskFreezer           *DiskFreezer
+	)
+
+	BeforeEach(func() {
+		platform = fakeplatform.NewFakePlatform()
+		platform.IsMountPointResult = true
+
+		specService = fakeapplyspec.NewFakeV1Service()
+		specService.Spec.RenderedTemplatesArchiveSpec = &applyspec.RenderedTemplatesArchiveSpec{}
+		specService.Spec.JobSpec.JobTemplateSpecs = []applyspec.JobTemplateSpec{{Name: "fake-job"}}
+
+		parallelScripts = map[string]*fakescript.FakeCancellableScript{
+			"pre-snapshot":  {},
+			"post-snapshot": {},
+		}
+		ranScriptNames = nil
+
+		fakeJobScriptProvider = &fakescript.FakeJobScriptProvider{}
+		fakeJobScriptProvider.NewParallelScriptStub = func(scriptName string, scripts []boshscript.Script) boshscript.CancellableScript {
+			ranScriptNames = append(ranScriptNames, scriptName)
+			return parallelScripts[scriptName]
+		}
+
+		timeService = fakeclock.NewFakeClock(time.Now())
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+
+		diskFreezer = NewDiskFreezer(platform, specService, fakeJobScriptProvider, boshdirs.NewProvider("/fake-base-dir"), timeService, logger)
+	})
+
+	Describe("Freeze", func() {
+		It("runs pre-snapshot job scripts and freezes persistent disk", func() {
+			err := diskFreezer.Freeze(time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(ranScriptNames).To(Equal([]string{"pre-snapshot"}))
+			jobName, scriptName := fakeJobScriptProvider.NewScriptArgsForCall(0)
+			Expect(jobName).To(Equal("fake-job"))
+			Expect(scriptName).To(Equal("pre-snapshot"))
+
+			Expect(platform.IsMountPointPath).To(Equal("/fake-base-dir/store"))
+			Expect(platform.FreezeFilesystemMountPoint).To(Equal("/fake-base-dir/store"))
+
+			Expect(platform.GetFs().ReadFileString("/fake-base-dir/bosh/frozen_persistent_disk")).To(Equal("/fake-base-dir/store"))
+		})
+
+		Context("when associated disks are mounted within persistent disk", func() {
+			BeforeEach(func() {
+				platform.GetFs().WriteFileString("/fake-base-dir/bosh/update_settings.json", `{"disk_associations":[
+					{"name":"fake-disk","cid":"fake-disk-cid","mount":true},
+					{"name":"fake-unmounted-disk","cid":"fake-unmounted-disk-cid"}
+				]}`)
+			})
+
+			It("freezes them after persistent disk and records all of them", func() {
+				err := diskFreezer.Freeze(time.Minute)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(platform.FreezeFilesystemMountPoints).To(Equal([]string{"/fake-base-dir/store", "/fake-base-dir/store/fake-disk"}))
+				Expect(platform.GetFs().ReadFileString("/fake-base-dir/bosh/frozen_persistent_disk")).To(Equal("/fake-base-dir/store\n/fake-base-dir/store/fake-disk"))
+			})
+
+			It("thaws them before persistent disk", func() {
+				err := diskFreezer.Freeze(time.Minute)
+				Expect(err).ToNot(HaveOccurred())
+
+				err = diskFreezer.Thaw()
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(platform.ThawFilesystemMountPoints).To(Equal([]string{"/fake-base-dir/store/fake-disk", "/fake-base-dir/store"}))
+				Expect(platform.GetFs().FileExists("/fake-base-dir/bosh/frozen_persistent_disk")).To(BeFalse())
+			})
+
+			It("thaws already frozen persistent disk when freezing associated disk fails", func() {
+				platform.FreezeFilesystemStub = func(mountPoint string) error {
+					if mountPoint == "/fake-base-dir/store/fake-disk" {
+						return errors.New("fake-freeze-err")
+					}
+					return nil
+				}
+
+				err := diskFreezer.Freeze(time.Minute)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-freeze-err"))
+
+				Expect(platform.ThawFilesystemMountPoints).To(Equal([]string{"/fake-base-dir/store"}))
+				Expect(platform.GetFs().FileExists("/fake-base-dir/bosh/frozen_persistent_disk")).To(BeFalse())
+				Expect(ranScriptNames).To(Equal([]string{"pre-snapshot", "post-snapshot"}))
+			})
+		})
+
+		It("returns error when persistent disk is already frozen", func() {
+			err := diskFreezer.Freeze(time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = diskFreezer.Freeze(time.Minute)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Persistent disk is already frozen"))
+		})
+
+		It("returns error when persistent disk is not mounted", func() {
+			platform.IsMountPointResult = false
+
+			err := diskFreezer.Freeze(time.Minute)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Persistent disk is not mounted"))
+
+			Expect(ranScriptNames).To(BeEmpty())
+			Expect(platform.FreezeFilesystemMountPoint).To(BeEmpty())
+		})
+
+		It("does not freeze persistent disk and runs post-snapshot scripts when pre-snapshot scripts fail", func() {
+			parallelScripts["pre-snapshot"].RunReturns(errors.New("fake-pre-snapshot-err"))
+
+			err := diskFreezer.Freeze(time.Minute)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-pre-snapshot-err"))
+
+			Expect(platform.FreezeFilesystemMountPoint).To(BeEmpty())
+			Expect(ranScriptNames).To(Equal([]string{"pre-snapshot", "post-snapshot"}))
+		})
+
+		It("stops waiting for pre-snapshot scripts once timeout elapses", func() {
+			scriptCh := make(chan struct{})
+
+			parallelScripts["pre-snapshot"].RunStub = func() error {
+				<-scriptCh
+				return nil
+			}
+
+			errCh := make(chan error)
+			go func() { errCh <- diskFreezer.Freeze(time.Minute) }()
+
+			timeService.WaitForWatcherAndIncrement(time.Minute)
+
+			var err error
+			Eventually(errCh).Should(Receive(&err))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Timed out after 1m0s"))
+
+			Expect(parallelScripts["pre-snapshot"].CancelCallCount()).To(Equal(1))
+			Expect(platform.FreezeFilesystemMountPoint).To(BeEmpty())
+			Expect(ranScriptNames).To(Equal([]string{"pre-snapshot", "post-snapshot"}))
+
+			// Freezer is not left locked
+			close(scriptCh)
+
+			err = diskFreezer.Freeze(time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("runs post-snapshot scripts when freezing fails", func() {
+			platform.FreezeFilesystemErr = errors.New("fake-freeze-err")
+
+			err := diskFreezer.Freeze(time.Minute)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-freeze-err"))
+
+			Expect(ranScriptNames).To(Equal([]string{"pre-snapshot", "post-snapshot"}))
+			Expect(platform.GetFs().FileExists("/fake-base-dir/bosh/frozen_persistent_disk")).To(BeFalse())
+		})
+
+		It("thaws persistent disk automatically after timeout", func() {
+			err := diskFreezer.Freeze(time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+
+			timeService.WaitForWatcherAndIncrement(time.Minute)
+
+			Eventually(platform.GetThawFilesystemMountPoints).Should(Equal([]string{"/fake-base-dir/store"}))
+			Eventually(func() int { return parallelScripts["post-snapshot"].RunCallCount() }).Should(Equal(1))
+
+			err = diskFreezer.Thaw()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("thawed automatically after timeout"))
+		})
+
+		It("keeps retrying to thaw persistent disk automatically when thawing fails", func() {
+			platform.ThawFilesystemErr = errors.New("fake-thaw-err")
+
+			err := diskFreezer.Freeze(time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+
+			timeService.WaitForWatcherAndIncrement(time.Minute)
+
+			Eventually(platform.GetThawFilesystemMountPoints).Should(HaveLen(1))
+			Consistently(func() int { return parallelScripts["post-snapshot"].RunCallCount() }).Should(Equal(0))
+
+			platform.SetThawFilesystemErr(nil)
+			timeService.WaitForWatcherAndIncrement(5 * time.Second)
+
+			Eventually(platform.GetThawFilesystemMountPoints).Should(HaveLen(2))
+			Eventually(func() int { return parallelScripts["post-snapshot"].RunCallCount() }).Should(Equal(1))
+		})
+	})
+
+	Describe("Thaw", func() {
+		It("thaws persistent disk and runs post-snapshot job scripts", func() {
+			err := diskFreezer.Freeze(time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = diskFreezer.Thaw()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(platform.ThawFilesystemMountPoints).To(Equal([]string{"/fake-base-dir/store"}))
+			Expect(ranScriptNames).To(Equal([]string{"pre-snapshot", "post-snapshot"}))
+		})
+
+		It("does not thaw persistent disk again when timeout elapses", func() {
+			err := diskFreezer.Freeze(time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = diskFreezer.Thaw()
+			Expect(err).ToNot(HaveOccurred())
+
+			timeService.Increment(time.Minute)
+
+			Consistently(platform.GetThawFilesystemMountPoints).Should(HaveLen(1))
+		})
+
+		It("allows freezing persistent disk again", func() {
+			err := diskFreezer.Freeze(time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = diskFreezer.Thaw()
+			Expect(err).ToNot(HaveOccurred())
+
+			err = diskFreezer.Freeze(time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns error when persistent disk is not frozen", func() {
+			err := diskFreezer.Thaw()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Persistent disk is not frozen"))
+		})
+
+		It("returns error when thawing fails", func() {
+			platform.ThawFilesystemErr = errors.New("fake-thaw-err")
+
+			err := diskFreezer.Freeze(time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = diskFreezer.Thaw()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-thaw-err"))
+			Expect(ranScriptNames).To(Equal([]string{"pre-snapshot"}))
+		})
+
+		It("keeps persistent disk frozen so that thawing can be retried when thawing fails", func() {
+			platform.ThawFilesystemErr = errors.New("fake-thaw-err")
+
+			err := diskFreezer.Freeze(time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = diskFreezer.Thaw()
+			Expect(err).To(HaveOccurred())
+			Expect(platform.GetFs().FileExists("/fake-base-dir/bosh/frozen_persistent_disk")).To(BeTrue())
+
+			platform.SetThawFilesystemErr(nil)
+
+			err = diskFreezer.Thaw()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(platform.ThawFilesystemMountPoints).To(Equal([]string{"/fake-base-dir/store", "/fake-base-dir/store"}))
+			Expect(ranScriptNames).To(Equal([]string{"pre-snapshot", "post-snapshot"}))
+			Expect(platform.GetFs().FileExists("/fake-base-dir/bosh/frozen_persistent_disk")).To(BeFalse())
+		})
+
+		It("returns error when post-snapshot scripts fail", func() {
+			parallelScripts["post-snapshot"].RunReturns(errors.New("fake-post-snapshot-err"))
+
+			err := diskFreezer.Freeze(time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = diskFreezer.Thaw()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-post-snapshot-err"))
+		})
+	})
+})
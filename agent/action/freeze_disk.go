@@ -0,0 +1,58 @@
+package action
+
+import (
+	"errors"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+const defaultFreezeDiskTimeout = 60 * time.Second
+
+type FreezeDiskParams struct {
+	// Persistent disk is thawed automatically after this many seconds
+	TimeoutInSeconds int `json:"timeout"`
+}
+
+type FreezeDiskAction struct {
+	diskFreezer *DiskFreezer
+}
+
+func NewFreezeDisk(diskFreezer *DiskFreezer) FreezeDiskAction {
+	return FreezeDiskAction{diskFreezer: diskFreezer}
+}
+
+func (a FreezeDiskAction) IsAsynchronous() bool {
+	return true
+}
+
+func (a FreezeDiskAction) IsPersistent() bool {
+	return false
+}
+
+func (a FreezeDiskAction) IsLoggable() bool {
+	return true
+}
+
+func (a FreezeDiskAction) Run(params ...FreezeDiskParams) (interface{}, error) {
+	timeout := defaultFreezeDiskTimeout
+
+	if len(params) > 0 && params[0].TimeoutInSeconds > 0 {
+		timeout = time.Duration(params[0].TimeoutInSeconds) * time.Second
+	}
+
+	err := a.diskFreezer.Freeze(timeout)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Freezing persistent disk")
+	}
+
+	return map[string]string{}, nil
+}
+
+func (a FreezeDiskAction) Resume() (interface{}, error) {
+	return nil, errors.New("not supported")
+}
+
+func (a FreezeDiskAction) Cancel() error {
+	return errors.New("not supported")
+}
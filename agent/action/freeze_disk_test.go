@@ -0,0 +1,84 @@
+package action_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-golang/clock/fakeclock"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	fakeapplyspec "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec/fakes"
+	fakescript "github.com/cloudfoundry/bosh-agent/agent/script/fakes"
+	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
+	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+var _ = Describe("FreezeDiskAction", func() {
+	var (
+		platform    *fakeplatform.FakePlatform
+		timeService *fakeclock.FakeClock
+		action      FreezeDiskAction
+	)
+
+	BeforeEach(func() {
+		platform = fakeplatform.NewFakePlatform()
+		platform.IsMountPointResult = true
+
+		fakeJobScriptProvider := &fakescript.FakeJobScriptProvider{}
+		fakeJobScriptProvider.NewParallelScriptReturns(&fakescript.FakeCancellableScript{})
+
+		timeService = fakeclock.NewFakeClock(time.Now())
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+
+		diskFreezer := NewDiskFreezer(platform, fakeapplyspec.NewFakeV1Service(), fakeJobScriptProvider, boshdirs.NewProvider("/fake-base-dir"), timeService, logger)
+		action = NewFreezeDisk(diskFreezer)
+	})
+
+	AssertActionIsAsynchronous(action)
+	AssertActionIsNotPersistent(action)
+	AssertActionIsLoggable(action)
+
+	AssertActionIsNotResumable(action)
+	AssertActionIsNotCancelable(action)
+
+	Describe("Run", func() {
+		It("freezes persistent disk", func() {
+			value, err := action.Run()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(value).To(Equal(map[string]string{}))
+
+			Expect(platform.FreezeFilesystemMountPoint).To(Equal("/fake-base-dir/store"))
+		})
+
+		It("thaws persistent disk after default timeout", func() {
+			_, err := action.Run()
+			Expect(err).ToNot(HaveOccurred())
+
+			timeService.WaitForWatcherAndIncrement(59 * time.Second)
+			Consistently(platform.GetThawFilesystemMountPoints).Should(BeEmpty())
+
+			timeService.Increment(time.Second)
+			Eventually(platform.GetThawFilesystemMountPoints).Should(HaveLen(1))
+		})
+
+		It("thaws persistent disk after given timeout", func() {
+			_, err := action.Run(FreezeDiskParams{TimeoutInSeconds: 5})
+			Expect(err).ToNot(HaveOccurred())
+
+			timeService.WaitForWatcherAndIncrement(5 * time.Second)
+			Eventually(platform.GetThawFilesystemMountPoints).Should(HaveLen(1))
+		})
+
+		It("returns error when freezing fails", func() {
+			platform.FreezeFilesystemErr = errors.New("fake-freeze-err")
+
+			_, err := action.Run()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-freeze-err"))
+		})
+	})
+})
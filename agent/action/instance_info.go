@@ -0,0 +1,196 @@
+package action
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	boshdpresolv "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver"
+	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	boshinventory "github.com/cloudfoundry/bosh-agent/platform/inventory"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+const instanceInfoLogTag = "InstanceInfoAction"
+
+type InstanceInfoAction struct {
+	settingsService    boshsettings.Service
+	platform           boshplatform.Platform
+	inventoryCollector boshinventory.Collector
+	dirProvider        boshdirs.Provider
+	logger             boshlog.Logger
+}
+
+// InstanceInfo describes hardware VM actually has and where it runs
+type InstanceInfo struct {
+	InstanceID      string `json:"instance_id,omitempty"`
+	StemcellVersion string `json:"stemcell_version,omitempty"`
+
+	boshinventory.Inventory
+}
+
+func NewInstanceInfo(
+	settingsService boshsettings.Service,
+	platform boshplatform.Platform,
+	inventoryCollector boshinventory.Collector,
+	dirProvider boshdirs.Provider,
+	logger boshlog.Logger,
+) (action InstanceInfoAction) {
+	action.settingsService = settingsService
+	action.platform = platform
+	action.inventoryCollector = inventoryCollector
+	action.dirProvider = dirProvider
+	action.logger = logger
+	return
+}
+
+func (a InstanceInfoAction) IsAsynchronous() bool {
+	return false
+}
+
+func (a InstanceInfoAction) IsPersistent() bool {
+	return false
+}
+
+func (a InstanceInfoAction) IsLoggable() bool {
+	return true
+}
+
+func (a InstanceInfoAction) Run() (InstanceInfo, error) {
+	inventory, err := a.inventoryCollector.Get()
+	if err != nil {
+		return InstanceInfo{}, bosherr.WrapError(err, "Collecting inventory")
+	}
+
+	settings := a.settingsService.GetSettings()
+
+	a.resolveDiskIDs(settings, inventory.BlockDevices)
+	resolveNetworks(settings.Networks, inventory.NetworkInterfaces)
+
+	info := InstanceInfo{
+		StemcellVersion: a.stemcellVersion(),
+		Inventory:       inventory,
+	}
+
+	// Not every settings source knows instance ID hence rest of info is still returned
+	instanceID, err := a.settingsService.GetInstanceID()
+	if err != nil {
+		a.logger.Warn(instanceInfoLogTag, "Failed getting instance ID: %s", err.Error())
+	} else {
+		info.InstanceID = instanceID
+	}
+
+	return info, nil
+}
+
+// resolveDiskIDs only looks up disks which are already attached;
+// rescanning buses is too slow and disruptive for a synchronous action
+func (a InstanceInfoAction) resolveDiskIDs(settings boshsettings.Settings, blockDevices []boshinventory.BlockDevice) {
+	resolver := a.platform.GetDevicePathResolver()
+
+	if settings.Disks.Ephemeral != nil {
+		a.resolveDiskID(resolver, boshinventory.EphemeralDiskID, settings.EphemeralDiskSettings(), blockDevices)
+	}
+
+	var diskIDs []string
+	for diskID := range settings.Disks.Persistent {
+		diskIDs = append(diskIDs, diskID)
+	}
+
+	sort.Strings(diskIDs)
+
+	for _, diskID := range diskIDs {
+		diskSettings, _ := settings.PersistentDiskSettings(diskID)
+		a.resolveDiskID(resolver, diskID, diskSettings, blockDevices)
+	}
+}
+
+func (a InstanceInfoAction) resolveDiskID(
+	resolver boshdpresolv.DevicePathResolver,
+	diskID string,
+	diskSettings boshsettings.DiskSettings,
+	blockDevices []boshinventory.BlockDevice,
+) {
+	devicePath, found, err := boshdpresolv.GetAttachedDevicePath(resolver, diskSettings)
+	if err != nil {
+		a.logger.Debug(instanceInfoLogTag, "Failed finding attached device of disk '%s': %s", diskID, err.Error())
+		return
+	}
+
+	if !found {
+		a.logger.Debug(instanceInfoLogTag, "Disk '%s' is not attached", diskID)
+		return
+	}
+
+	// Resolved paths may be symlinks such as /dev/disk/by-id/...
+	if targetPath, err := a.platform.GetFs().ReadAndFollowLink(devicePath); err == nil {
+		devicePath = targetPath
+	}
+
+	for i := range blockDevices {
+		if blockDevices[i].Path == devicePath || isPartitionOf(devicePath, blockDevices[i].Path) {
+			blockDevices[i].DiskID = diskID
+		}
+	}
+}
+
+// isPartitionOf matches partitions such as /dev/sdc1 and /dev/nvme1n1p1
+// but not other devices such as /dev/nvme1n10 of /dev/nvme1n1
+func isPartitionOf(partitionPath, devicePath string) bool {
+	if devicePath == "" {
+		return false
+	}
+
+	prefix := strings.TrimSuffix(boshplatform.PartitionPath(devicePath, 0), "0")
+
+	number := strings.TrimPrefix(partitionPath, prefix)
+	if number == partitionPath || number == "" {
+		return false
+	}
+
+	for _, c := range number {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func resolveNetworks(networks boshsettings.Networks, networkInterfaces []boshinventory.NetworkInterface) {
+	for name, network := range networks {
+		if network.Mac == "" {
+			continue
+		}
+
+		for i := range networkInterfaces {
+			if strings.EqualFold(networkInterfaces[i].MAC, network.Mac) {
+				networkInterfaces[i].Network = name
+			}
+		}
+	}
+}
+
+func (a InstanceInfoAction) stemcellVersion() string {
+	stemcellVersionPath := filepath.Join(a.dirProvider.EtcDir(), "stemcell_version")
+
+	contents, err := a.platform.GetFs().ReadFileString(stemcellVersionPath)
+	if err != nil {
+		a.logger.Debug(instanceInfoLogTag, "Failed reading stemcell version: %s", err.Error())
+		return ""
+	}
+
+	return strings.TrimSpace(contents)
+}
+
+func (a InstanceInfoAction) Resume() (interface{}, error) {
+	return nil, errors.New("not supported")
+}
+
+func (a InstanceInfoAction) Cancel() error {
+	return errors.New("not supported")
+}
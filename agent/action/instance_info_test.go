@@ -0,0 +1,230 @@
+package action_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	fakedpresolv "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver/fakes"
+	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
+	boshinventory "github.com/cloudfoundry/bosh-agent/platform/inventory"
+	fakeinventory "github.com/cloudfoundry/bosh-agent/platform/inventory/fakes"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
+	fakesettings "github.com/cloudfoundry/bosh-agent/settings/fakes"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+func init() {
+	Describe("InstanceInfo", func() {
+		var (
+			settingsService    *fakesettings.FakeSettingsService
+			platform           *fakeplatform.FakePlatform
+			inventoryCollector *fakeinventory.FakeCollector
+			devicePathResolver *fakedpresolv.FakeDevicePathResolver
+			action             InstanceInfoAction
+		)
+
+		BeforeEach(func() {
+			settingsService = &fakesettings.FakeSettingsService{}
+			platform = fakeplatform.NewFakePlatform()
+			inventoryCollector = &fakeinventory.FakeCollector{}
+			devicePathResolver = fakedpresolv.NewFakeDevicePathResolver()
+			platform.DevicePathResolver = devicePathResolver
+			logger := boshlog.NewLogger(boshlog.LevelNone)
+			dirProvider := boshdirs.NewProvider("/var/vcap")
+			action = NewInstanceInfo(settingsService, platform, inventoryCollector, dirProvider, logger)
+		})
+
+		AssertActionIsNotAsynchronous(action)
+		AssertActionIsNotPersistent(action)
+		AssertActionIsLoggable(action)
+
+		AssertActionIsNotResumable(action)
+		AssertActionIsNotCancelable(action)
+
+		Describe("Run", func() {
+			BeforeEach(func() {
+				inventoryCollector.GetInventory = boshinventory.Inventory{
+					CPU:    boshinventory.CPU{Model: "fake-cpu-model", Count: 2},
+					Memory: boshinventory.Memory{TotalInKb: 2048000},
+					BlockDevices: []boshinventory.BlockDevice{
+						{Name: "sda", Path: "/dev/sda", SizeInBytes: 3221225472},
+						{Name: "sdb", Path: "/dev/sdb", SizeInBytes: 5368709120},
+						{Name: "sdc", Path: "/dev/sdc", SizeInBytes: 10737418240, Serial: "fake-serial"},
+						{Name: "sdd", Path: "/dev/sdd", SizeInBytes: 1073741824},
+					},
+					NetworkInterfaces: []boshinventory.NetworkInterface{
+						{Name: "eth0", MAC: "0A:1B:2C:3D:4E:5F", Driver: "ena", SpeedInMbps: 10000},
+						{Name: "eth1", MAC: "aa:bb:cc:dd:ee:ff"},
+					},
+					Kernel: "fake-kernel",
+				}
+
+				settingsService.Settings = boshsettings.Settings{
+					Disks: boshsettings.Disks{
+						Ephemeral: "/dev/sdb",
+						Persistent: map[string]interface{}{
+							"fake-disk-id":            "fake-volume-id",
+							"fake-associated-disk-id": map[string]interface{}{"id": "fake-device-id"},
+						},
+					},
+					Networks: boshsettings.Networks{
+						"fake-net": boshsettings.Network{Mac: "0a:1b:2c:3d:4e:5f"},
+					},
+				}
+				settingsService.InstanceID = "fake-instance-id"
+
+				devicePathResolver.GetAttachedDevicePathStub = func(diskSettings boshsettings.DiskSettings) (string, bool, error) {
+					switch {
+					case diskSettings.Path == "/dev/sdb":
+						return "/dev/sdb", true, nil
+					case diskSettings.VolumeID == "fake-volume-id":
+						return "/dev/sdc", true, nil
+					case diskSettings.DeviceID == "fake-device-id":
+						return "/dev/sdd", true, nil
+					}
+					return "", false, nil
+				}
+
+				platform.Fs.WriteFileString("/var/vcap/bosh/etc/stemcell_version", "3468.1\n")
+			})
+
+			It("returns inventory with resolved disk IDs and networks", func() {
+				info, err := action.Run()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(info).To(Equal(InstanceInfo{
+					InstanceID:      "fake-instance-id",
+					StemcellVersion: "3468.1",
+					Inventory: boshinventory.Inventory{
+						CPU:    boshinventory.CPU{Model: "fake-cpu-model", Count: 2},
+						Memory: boshinventory.Memory{TotalInKb: 2048000},
+						BlockDevices: []boshinventory.BlockDevice{
+							{Name: "sda", Path: "/dev/sda", SizeInBytes: 3221225472},
+							{Name: "sdb", Path: "/dev/sdb", SizeInBytes: 5368709120, DiskID: "ephemeral"},
+							{Name: "sdc", Path: "/dev/sdc", SizeInBytes: 10737418240, Serial: "fake-serial", DiskID: "fake-disk-id"},
+							{Name: "sdd", Path: "/dev/sdd", SizeInBytes: 1073741824, DiskID: "fake-associated-disk-id"},
+						},
+						NetworkInterfaces: []boshinventory.NetworkInterface{
+							{Name: "eth0", MAC: "0A:1B:2C:3D:4E:5F", Driver: "ena", SpeedInMbps: 10000, Network: "fake-net"},
+							{Name: "eth1", MAC: "aa:bb:cc:dd:ee:ff"},
+						},
+						Kernel: "fake-kernel",
+					},
+				}))
+			})
+
+			It("returns rest of info when instance ID is not known", func() {
+				settingsService.GetInstanceIDErr = errors.New("fake-instance-id-err")
+
+				info, err := action.Run()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(info.InstanceID).To(BeEmpty())
+				Expect(info.StemcellVersion).To(Equal("3468.1"))
+			})
+
+			It("resolves disk ID from partition of nvme device", func() {
+				inventoryCollector.GetInventory.BlockDevices = []boshinventory.BlockDevice{
+					{Name: "nvme1n1", Path: "/dev/nvme1n1"},
+					{Name: "nvme1n10", Path: "/dev/nvme1n10"},
+				}
+				devicePathResolver.GetAttachedDevicePathStub = func(diskSettings boshsettings.DiskSettings) (string, bool, error) {
+					if diskSettings.ID == "fake-disk-id" {
+						return "/dev/nvme1n1p1", true, nil
+					}
+					return "", false, nil
+				}
+
+				info, err := action.Run()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(info.BlockDevices[0].DiskID).To(Equal("fake-disk-id"))
+				Expect(info.BlockDevices[1].DiskID).To(BeEmpty())
+			})
+
+			It("does not resolve disk ID of device whose name only extends name of other device", func() {
+				inventoryCollector.GetInventory.BlockDevices = []boshinventory.BlockDevice{
+					{Name: "nvme0n1", Path: "/dev/nvme0n1"},
+					{Name: "nvme0n10", Path: "/dev/nvme0n10"},
+					{Name: "loop1", Path: "/dev/loop1"},
+					{Name: "loop10", Path: "/dev/loop10"},
+				}
+				devicePathResolver.GetAttachedDevicePathStub = func(diskSettings boshsettings.DiskSettings) (string, bool, error) {
+					switch diskSettings.ID {
+					case "fake-disk-id":
+						return "/dev/nvme0n10", true, nil
+					case "fake-associated-disk-id":
+						return "/dev/loop10", true, nil
+					}
+					return "", false, nil
+				}
+
+				info, err := action.Run()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(info.BlockDevices[0].DiskID).To(BeEmpty())
+				Expect(info.BlockDevices[1].DiskID).To(Equal("fake-disk-id"))
+				Expect(info.BlockDevices[2].DiskID).To(BeEmpty())
+				Expect(info.BlockDevices[3].DiskID).To(Equal("fake-associated-disk-id"))
+			})
+
+			It("resolves disk ID of device resolved as symlink", func() {
+				platform.Fs.WriteFileString("/dev/sdc", "")
+				platform.Fs.Symlink("/dev/sdc", "/dev/disk/by-id/fake-volume-id")
+				devicePathResolver.GetAttachedDevicePathStub = func(diskSettings boshsettings.DiskSettings) (string, bool, error) {
+					if diskSettings.ID == "fake-disk-id" {
+						return "/dev/disk/by-id/fake-volume-id", true, nil
+					}
+					return "", false, nil
+				}
+
+				info, err := action.Run()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(info.BlockDevices[2].DiskID).To(Equal("fake-disk-id"))
+			})
+
+			It("only looks up attached devices of ephemeral and persistent disks without rescanning", func() {
+				_, err := action.Run()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(devicePathResolver.GetAttachedDevicePathDiskSettings).To(HaveLen(3))
+				Expect(devicePathResolver.GetAttachedDevicePathDiskSettings[0].Path).To(Equal("/dev/sdb"))
+				Expect(devicePathResolver.GetAttachedDevicePathDiskSettings[1].ID).To(Equal("fake-associated-disk-id"))
+				Expect(devicePathResolver.GetAttachedDevicePathDiskSettings[2].ID).To(Equal("fake-disk-id"))
+				Expect(devicePathResolver.GetRealDevicePathDiskSettings).To(Equal(boshsettings.DiskSettings{}))
+			})
+
+			It("does not resolve disk IDs of disks which are not attached", func() {
+				devicePathResolver.GetAttachedDevicePathStub = nil
+
+				info, err := action.Run()
+				Expect(err).ToNot(HaveOccurred())
+				for _, blockDevice := range info.BlockDevices {
+					Expect(blockDevice.DiskID).To(BeEmpty())
+				}
+			})
+
+			It("resolves rest of disk IDs when looking up one of disks fails", func() {
+				devicePathResolver.GetAttachedDevicePathStub = func(diskSettings boshsettings.DiskSettings) (string, bool, error) {
+					if diskSettings.ID == "fake-disk-id" {
+						return "/dev/sdc", true, nil
+					}
+					return "", false, errors.New("fake-resolve-err")
+				}
+
+				info, err := action.Run()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(info.BlockDevices[1].DiskID).To(BeEmpty())
+				Expect(info.BlockDevices[2].DiskID).To(Equal("fake-disk-id"))
+				Expect(info.BlockDevices[3].DiskID).To(BeEmpty())
+			})
+
+			It("returns an error if inventory cannot be collected", func() {
+				inventoryCollector.GetErr = errors.New("fake-collect-err")
+
+				_, err := action.Run()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-collect-err"))
+			})
+		})
+	})
+}
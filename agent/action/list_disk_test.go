@@ -53,5 +53,20 @@ func init() {
 			Expect(values).To(ContainElement("volume-3"))
 			Expect(len(values)).To(Equal(2))
 		})
+
+		It("lists associated disks mounted at their own mount points", func() {
+			platform.MountedDevicePaths = []string{"/dev/sdb", "/dev/sdc"}
+
+			settingsService.Settings.Disks = boshsettings.Disks{
+				Persistent: map[string]interface{}{
+					"primary-disk":    "/dev/sdb",
+					"associated-disk": map[string]interface{}{"path": "/dev/sdc"},
+				},
+			}
+
+			value, err := action.Run()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(value).To(ConsistOf("primary-disk", "associated-disk"))
+		})
 	})
 }
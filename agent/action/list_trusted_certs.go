@@ -0,0 +1,49 @@
+package action
+
+import (
+	"errors"
+
+	"github.com/cloudfoundry/bosh-agent/platform/cert"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+type ListTrustedCertsAction struct {
+	settingsService boshsettings.Service
+}
+
+func NewListTrustedCerts(settingsService boshsettings.Service) (action ListTrustedCertsAction) {
+	action.settingsService = settingsService
+	return
+}
+
+func (a ListTrustedCertsAction) IsAsynchronous() bool {
+	return false
+}
+
+func (a ListTrustedCertsAction) IsPersistent() bool {
+	return false
+}
+
+func (a ListTrustedCertsAction) IsLoggable() bool {
+	return true
+}
+
+// Run describes certificates installed through update_settings
+// which are trusted in addition to operating system certificates
+func (a ListTrustedCertsAction) Run() ([]cert.Certificate, error) {
+	certs, err := cert.ParseCertificates(a.settingsService.GetUpdateSettings().TrustedCerts)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Listing trusted certificates")
+	}
+
+	return certs, nil
+}
+
+func (a ListTrustedCertsAction) Resume() (interface{}, error) {
+	return nil, errors.New("not supported")
+}
+
+func (a ListTrustedCertsAction) Cancel() error {
+	return errors.New("not supported")
+}
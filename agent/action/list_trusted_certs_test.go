@@ -0,0 +1,77 @@
+package action_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	"github.com/cloudfoundry/bosh-agent/platform/cert"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	fakesettings "github.com/cloudfoundry/bosh-agent/settings/fakes"
+)
+
+const trustedCert = `-----BEGIN CERTIFICATE-----
+MIIBmDCCAT2gAwIBAgIUHV7zfsuSIWe9qdKgl/GwROlB5FswCgYIKoZIzj0EAwIw
+ITENMAsGA1UECgwEYm9zaDEQMA4GA1UEAwwHZmFrZS1jYTAeFw0yNjEwMTkwNjU2
+MTVaFw0zNjEwMTYwNjU2MTVaMCExDTALBgNVBAoMBGJvc2gxEDAOBgNVBAMMB2Zh
+a2UtY2EwWTATBgcqhkjOPQIBBggqhkjOPQMBBwNCAATDycAHSAx59KnbI8+m6z/f
+6tkezbbPOxEPMZ1Upq1UCne++AYEaJfOjaopdaFO/h8oM6JAO5WYiaUJrYpeNbNx
+o1MwUTAdBgNVHQ4EFgQUBA2pcSpl1SkAaE3FR34vlYAUFqMwHwYDVR0jBBgwFoAU
+BA2pcSpl1SkAaE3FR34vlYAUFqMwDwYDVR0TAQH/BAUwAwEB/zAKBggqhkjOPQQD
+AgNJADBGAiEA5sNiaOkavlXIcUkzmh3g66ZnAIjQ0J+6B/7lSSkgZ8UCIQDxuXdY
+D5e6cymwBfeY1Fm3kS1ivC+SHtIsRtq38CpI1g==
+-----END CERTIFICATE-----`
+
+func init() {
+	Describe("ListTrustedCerts", func() {
+		var (
+			settingsService *fakesettings.FakeSettingsService
+			action          ListTrustedCertsAction
+		)
+
+		BeforeEach(func() {
+			settingsService = &fakesettings.FakeSettingsService{}
+			action = NewListTrustedCerts(settingsService)
+		})
+
+		AssertActionIsNotAsynchronous(action)
+		AssertActionIsNotPersistent(action)
+		AssertActionIsLoggable(action)
+
+		AssertActionIsNotResumable(action)
+		AssertActionIsNotCancelable(action)
+
+		It("returns trusted certificates installed through update settings", func() {
+			settingsService.ApplyUpdateSettings(boshsettings.UpdateSettings{TrustedCerts: trustedCert})
+
+			certs, err := action.Run()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(certs).To(Equal([]cert.Certificate{
+				{
+					Subject:     "CN=fake-ca,O=bosh",
+					Issuer:      "CN=fake-ca,O=bosh",
+					Fingerprint: "sha256:5c2763e799f5453c4979a5b923ce9a7d25b17090b25aa369a5f2a9765eb7327f",
+					Expiry:      time.Date(2036, time.October, 16, 6, 56, 15, 0, time.UTC),
+				},
+			}))
+		})
+
+		It("returns no certificates when none are trusted", func() {
+			certs, err := action.Run()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(certs).To(BeEmpty())
+		})
+
+		It("returns error when trusted certificates cannot be parsed", func() {
+			settingsService.ApplyUpdateSettings(boshsettings.UpdateSettings{
+				TrustedCerts: "-----BEGIN CERTIFICATE-----\nZmFrZS1jZXJ0\n-----END CERTIFICATE-----",
+			})
+
+			_, err := action.Run()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Listing trusted certificates"))
+		})
+	})
+}
@@ -27,7 +27,7 @@ func (a MigrateDiskAction) IsAsynchronous() bool {
 }
 
 func (a MigrateDiskAction) IsPersistent() bool {
-	return false
+	return true
 }
 
 func (a MigrateDiskAction) IsLoggable() bool {
@@ -35,18 +35,21 @@ func (a MigrateDiskAction) IsLoggable() bool {
 }
 
 func (a MigrateDiskAction) Run() (value interface{}, err error) {
-	err = a.platform.MigratePersistentDisk(a.dirProvider.StoreDir(), a.dirProvider.StoreMigrationDir())
+	totals, err := a.platform.MigratePersistentDisk(a.dirProvider.StoreDir(), a.dirProvider.StoreMigrationDir())
 	if err != nil {
 		err = bosherr.WrapError(err, "Migrating persistent disk")
 		return
 	}
 
-	value = map[string]string{}
+	value = totals
 	return
 }
 
+// Resume continues migration interrupted by an agent restart;
+// platform keeps track of migration progress and mounts new disk
+// again if needed so it is safe to run it again
 func (a MigrateDiskAction) Resume() (interface{}, error) {
-	return nil, errors.New("not supported")
+	return a.Run()
 }
 
 func (a MigrateDiskAction) Cancel() error {
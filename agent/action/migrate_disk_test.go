@@ -1,10 +1,13 @@
 package action_test
 
 import (
+	"errors"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
 	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
 	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
 	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
 	boshassert "github.com/cloudfoundry/bosh-utils/assert"
@@ -28,19 +31,37 @@ func init() {
 		})
 
 		AssertActionIsAsynchronous(action)
-		AssertActionIsNotPersistent(action)
+		AssertActionIsPersistent(action)
 		AssertActionIsLoggable(action)
 
-		AssertActionIsNotResumable(action)
 		AssertActionIsNotCancelable(action)
 
 		It("migrate disk action run", func() {
+			platform.MigratePersistentDiskTotals = boshplatform.DiskMigrationTotals{FilesCopied: 2, BytesCopied: 32}
+
 			value, err := action.Run()
 			Expect(err).ToNot(HaveOccurred())
-			boshassert.MatchesJSONString(GinkgoT(), value, "{}")
+			boshassert.MatchesJSONString(GinkgoT(), value, `{"files_copied":2,"bytes_copied":32}`)
 
 			Expect(platform.MigratePersistentDiskFromMountPoint).To(boshassert.MatchPath("/foo/store"))
 			Expect(platform.MigratePersistentDiskToMountPoint).To(boshassert.MatchPath("/foo/store_migration_target"))
 		})
+
+		It("migrate disk action resumes interrupted migration", func() {
+			value, err := action.Resume()
+			Expect(err).ToNot(HaveOccurred())
+			boshassert.MatchesJSONString(GinkgoT(), value, `{"files_copied":0,"bytes_copied":0}`)
+
+			Expect(platform.MigratePersistentDiskFromMountPoint).To(boshassert.MatchPath("/foo/store"))
+			Expect(platform.MigratePersistentDiskToMountPoint).To(boshassert.MatchPath("/foo/store_migration_target"))
+		})
+
+		It("migrate disk action returns error when migration fails", func() {
+			platform.MigratePersistentDiskErr = errors.New("fake-migrate-err")
+
+			_, err := action.Run()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-migrate-err"))
+		})
 	})
 }
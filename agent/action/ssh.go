@@ -3,7 +3,10 @@ package action
 import (
 	"errors"
 	"path"
+	"regexp"
+	"time"
 
+	boshsshusers "github.com/cloudfoundry/bosh-agent/agent/sshusers"
 	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
@@ -15,6 +18,8 @@ type SSHAction struct {
 	settingsService boshsettings.Service
 	platform        boshplatform.Platform
 	dirProvider     boshdirs.Provider
+	userReaper      boshsshusers.Reaper
+	userRoles       boshsshusers.RoleStore
 	logger          boshlog.Logger
 }
 
@@ -22,11 +27,15 @@ func NewSSH(
 	settingsService boshsettings.Service,
 	platform boshplatform.Platform,
 	dirProvider boshdirs.Provider,
+	userReaper boshsshusers.Reaper,
+	userRoles boshsshusers.RoleStore,
 	logger boshlog.Logger,
 ) (action SSHAction) {
 	action.settingsService = settingsService
 	action.platform = platform
 	action.dirProvider = dirProvider
+	action.userReaper = userReaper
+	action.userRoles = userRoles
 	action.logger = logger
 	return
 }
@@ -48,6 +57,13 @@ type SSHParams struct {
 	User      string
 	Password  string
 	PublicKey string `json:"public_key"`
+
+	// Number of seconds after which user is deleted automatically;
+	// when not set user is kept until cleanup
+	TTLInSeconds int `json:"ttl,omitempty"`
+
+	// One of read-only, job-operator or admin; defaults to admin
+	Role string `json:"role,omitempty"`
 }
 
 type SSHResult struct {
@@ -71,18 +87,52 @@ func (a SSHAction) Run(cmd string, params SSHParams) (SSHResult, error) {
 func (a SSHAction) setupSSH(params SSHParams) (SSHResult, error) {
 	var result SSHResult
 
+	role, err := boshsshusers.NewRole(params.Role)
+	if err != nil {
+		return result, err
+	}
+
+	if params.TTLInSeconds < 0 {
+		return result, bosherr.Errorf("Expected ttl to be positive but was %d", params.TTLInSeconds)
+	}
+
+	// Expiry is recorded before user is created so that
+	// user never exists without being eventually reaped.
+	// Expiry left from previous setup of the same user is always replaced.
+	if params.TTLInSeconds > 0 {
+		err = a.userReaper.ExpireAfter(params.User, time.Duration(params.TTLInSeconds)*time.Second)
+		if err != nil {
+			return result, bosherr.WrapError(err, "Recording user expiry")
+		}
+	} else {
+		err = a.userReaper.RemoveExpiriesMatching("^" + regexp.QuoteMeta(params.User) + "$")
+		if err != nil {
+			return result, bosherr.WrapError(err, "Removing user expiry")
+		}
+	}
+
 	boshSSHPath := path.Join(a.dirProvider.BaseDir(), "bosh_ssh")
 
-	err := a.platform.CreateUser(params.User, params.Password, boshSSHPath)
+	err = a.platform.CreateUser(params.User, params.Password, boshSSHPath)
 	if err != nil {
 		return result, bosherr.WrapError(err, "Creating user")
 	}
 
-	err = a.platform.AddUserToGroups(params.User, []string{boshsettings.VCAPUsername, boshsettings.AdminGroup, boshsettings.SudoersGroup, boshsettings.SshersGroup})
+	err = a.platform.AddUserToGroups(params.User, role.Groups())
 	if err != nil {
 		return result, bosherr.WrapError(err, "Adding user to groups")
 	}
 
+	err = a.platform.SetupUserSudoCommands(params.User, role.SudoCommands(a.dirProvider))
+	if err != nil {
+		return result, bosherr.WrapError(err, "Setting up sudo commands")
+	}
+
+	err = a.userRoles.SaveRole(params.User, role)
+	if err != nil {
+		return result, bosherr.WrapError(err, "Saving user role")
+	}
+
 	err = a.platform.SetupSSH([]string{params.PublicKey}, params.User)
 	if err != nil {
 		return result, bosherr.WrapError(err, "Setting ssh public key")
@@ -116,6 +166,16 @@ func (a SSHAction) cleanupSSH(params SSHParams) (SSHResult, error) {
 		return SSHResult{}, bosherr.WrapError(err, "SSH Cleanup: Deleting Ephemeral Users")
 	}
 
+	err = a.userRoles.RemoveRolesMatching(params.UserRegex)
+	if err != nil {
+		return SSHResult{}, bosherr.WrapError(err, "SSH Cleanup: Removing User Roles")
+	}
+
+	err = a.userReaper.RemoveExpiriesMatching(params.UserRegex)
+	if err != nil {
+		return SSHResult{}, bosherr.WrapError(err, "SSH Cleanup: Removing User Expiries")
+	}
+
 	result := SSHResult{
 		Command: "cleanup",
 		Status:  "success",
@@ -5,8 +5,11 @@ import (
 	. "github.com/onsi/gomega"
 
 	"errors"
+	"time"
 
 	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	boshsshusers "github.com/cloudfoundry/bosh-agent/agent/sshusers"
+	fakesshusers "github.com/cloudfoundry/bosh-agent/agent/sshusers/fakes"
 	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
@@ -25,17 +28,21 @@ func validatePlatformSetupWithPassword(platform *fakeplatform.FakePlatform, expe
 	Expect(platform.SetupSSHPublicKeys["fake-user"]).To(ConsistOf("fake-public-key"))
 }
 
-func buildSSHAction(settingsService boshsettings.Service) (*fakeplatform.FakePlatform, SSHAction) {
+func buildSSHAction(settingsService boshsettings.Service) (*fakeplatform.FakePlatform, *fakesshusers.FakeReaper, *fakesshusers.FakeRoleStore, SSHAction) {
 	platform := fakeplatform.NewFakePlatform()
 	dirProvider := boshdirs.NewProvider("/foo")
+	userReaper := &fakesshusers.FakeReaper{}
+	userRoles := fakesshusers.NewFakeRoleStore()
 	logger := boshlog.NewLogger(boshlog.LevelNone)
-	action := NewSSH(settingsService, platform, dirProvider, logger)
-	return platform, action
+	action := NewSSH(settingsService, platform, dirProvider, userReaper, userRoles, logger)
+	return platform, userReaper, userRoles, action
 }
 
 var _ = Describe("SSHAction", func() {
 	var (
 		platform        *fakeplatform.FakePlatform
+		userReaper      *fakesshusers.FakeReaper
+		userRoles       *fakesshusers.FakeRoleStore
 		settingsService boshsettings.Service
 		action          SSHAction
 	)
@@ -43,7 +50,7 @@ var _ = Describe("SSHAction", func() {
 	Context("Action setup", func() {
 		BeforeEach(func() {
 			settingsService = &fakesettings.FakeSettingsService{}
-			platform, action = buildSSHAction(settingsService)
+			platform, userReaper, userRoles, action = buildSSHAction(settingsService)
 		})
 
 		AssertActionIsNotAsynchronous(action)
@@ -63,7 +70,11 @@ var _ = Describe("SSHAction", func() {
 				err      error
 
 				SSHParamsPassword string
+				SSHParamsTTL      int
+				SSHParamsRole     string
 				defaultIP         string
+				userReaperErr     error
+				sudoCommandsErr   error
 
 				platformPublicKeyValue string
 				platformPublicKeyErr   error
@@ -71,7 +82,11 @@ var _ = Describe("SSHAction", func() {
 
 			BeforeEach(func() {
 				SSHParamsPassword = ""
+				SSHParamsTTL = 0
+				SSHParamsRole = ""
 				defaultIP = "ww.xx.yy.zz"
+				userReaperErr = nil
+				sudoCommandsErr = nil
 
 				platformPublicKeyValue = ""
 				platformPublicKeyErr = nil
@@ -83,15 +98,19 @@ var _ = Describe("SSHAction", func() {
 					"fake-net": boshsettings.Network{IP: defaultIP},
 				}
 
-				platform, action = buildSSHAction(settingsService)
+				platform, userReaper, userRoles, action = buildSSHAction(settingsService)
 
 				platform.GetHostPublicKeyValue = platformPublicKeyValue
 				platform.GetHostPublicKeyError = platformPublicKeyErr
+				userReaper.ExpireAfterErr = userReaperErr
+				platform.SetupUserSudoCommandsErr = sudoCommandsErr
 
 				params = SSHParams{
-					User:      "fake-user",
-					PublicKey: "fake-public-key",
-					Password:  SSHParamsPassword,
+					User:         "fake-user",
+					PublicKey:    "fake-public-key",
+					Password:     SSHParamsPassword,
+					TTLInSeconds: SSHParamsTTL,
+					Role:         SSHParamsRole,
 				}
 
 				response, err = action.Run("setup", params)
@@ -138,6 +157,118 @@ var _ = Describe("SSHAction", func() {
 				})
 			})
 
+			Context("without a role", func() {
+				It("sets up user as admin", func() {
+					Expect(err).ToNot(HaveOccurred())
+					Expect(platform.SetupUserSudoCommandsCommands).To(HaveKeyWithValue("fake-user", BeNil()))
+					Expect(userRoles.Roles).To(Equal(map[string]boshsshusers.Role{"fake-user": boshsshusers.RoleAdmin}))
+				})
+			})
+
+			Context("with read-only role", func() {
+				BeforeEach(func() {
+					SSHParamsRole = "read-only"
+				})
+
+				It("only adds user to sshers group and allows reading job status and logs", func() {
+					Expect(err).ToNot(HaveOccurred())
+					Expect(platform.AddUserToGroupsGroups["fake-user"]).To(Equal([]string{boshsettings.SshersGroup}))
+					Expect(platform.SetupUserSudoCommandsCommands["fake-user"]).To(Equal([]string{
+						"/foo/bosh/bin/monit summary",
+						"/foo/bosh/bin/bosh-view-log /foo/sys/log/*",
+						"!/foo/bosh/bin/bosh-view-log * *",
+					}))
+					Expect(userRoles.Roles).To(Equal(map[string]boshsshusers.Role{"fake-user": boshsshusers.RoleReadOnly}))
+				})
+			})
+
+			Context("with job-operator role", func() {
+				BeforeEach(func() {
+					SSHParamsRole = "job-operator"
+				})
+
+				It("additionally allows managing jobs", func() {
+					Expect(err).ToNot(HaveOccurred())
+					Expect(platform.AddUserToGroupsGroups["fake-user"]).To(Equal([]string{boshsettings.SshersGroup}))
+					Expect(platform.SetupUserSudoCommandsCommands["fake-user"]).To(ContainElement("/foo/bosh/bin/monit restart *"))
+					Expect(userRoles.Roles).To(Equal(map[string]boshsshusers.Role{"fake-user": boshsshusers.RoleJobOperator}))
+				})
+			})
+
+			Context("with unknown role", func() {
+				BeforeEach(func() {
+					SSHParamsRole = "root"
+				})
+
+				It("returns an error without creating user", func() {
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("Unknown ssh role 'root'"))
+					Expect(platform.CreateUserUsername).To(BeEmpty())
+				})
+			})
+
+			Context("when setting up sudo commands fails", func() {
+				BeforeEach(func() {
+					SSHParamsRole = "read-only"
+					sudoCommandsErr = errors.New("fake-sudo-err")
+				})
+
+				It("returns an error without saving user role", func() {
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("fake-sudo-err"))
+					Expect(userRoles.Roles).To(BeEmpty())
+				})
+			})
+
+			Context("without a ttl", func() {
+				It("does not schedule user expiry", func() {
+					Expect(err).ToNot(HaveOccurred())
+					Expect(userReaper.ExpireAfterUsernames).To(BeEmpty())
+				})
+
+				It("removes user expiry left from previous setup", func() {
+					Expect(err).ToNot(HaveOccurred())
+					Expect(userReaper.RemoveExpiriesMatchingRegexes).To(Equal([]string{"^fake-user$"}))
+				})
+			})
+
+			Context("with a ttl", func() {
+				BeforeEach(func() {
+					SSHParamsTTL = 3600
+				})
+
+				It("schedules user expiry", func() {
+					Expect(err).ToNot(HaveOccurred())
+					Expect(userReaper.ExpireAfterUsernames).To(Equal([]string{"fake-user"}))
+					Expect(userReaper.ExpireAfterTTLs).To(Equal([]time.Duration{time.Hour}))
+					Expect(userReaper.RemoveExpiriesMatchingRegexes).To(BeEmpty())
+				})
+
+				Context("when scheduling user expiry fails", func() {
+					BeforeEach(func() {
+						userReaperErr = errors.New("fake-expire-err")
+					})
+
+					It("does not create user", func() {
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("fake-expire-err"))
+						Expect(platform.CreateUserUsername).To(BeEmpty())
+					})
+				})
+			})
+
+			Context("with a negative ttl", func() {
+				BeforeEach(func() {
+					SSHParamsTTL = -1
+				})
+
+				It("returns an error without creating user", func() {
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("Expected ttl to be positive but was -1"))
+					Expect(platform.CreateUserUsername).To(BeEmpty())
+				})
+			})
+
 			Context("without a host public key available", func() {
 				BeforeEach(func() {
 					platformPublicKeyErr = errors.New("Get Host Public Key Failure")
@@ -150,10 +281,17 @@ var _ = Describe("SSHAction", func() {
 		})
 
 		Context("cleanupSSH", func() {
+			BeforeEach(func() {
+				settingsService = &fakesettings.FakeSettingsService{}
+				platform, userReaper, userRoles, action = buildSSHAction(settingsService)
+			})
+
 			It("should delete ephemeral user", func() {
 				response, err := action.Run("cleanup", SSHParams{UserRegex: "^foobar.*"})
 				Expect(err).ToNot(HaveOccurred())
 				Expect(platform.DeleteEphemeralUsersMatchingRegex).To(Equal("^foobar.*"))
+				Expect(userRoles.RemoveRolesMatchingRegex).To(Equal("^foobar.*"))
+				Expect(userReaper.RemoveExpiriesMatchingRegexes).To(Equal([]string{"^foobar.*"}))
 
 				// Make sure empty ip field is not included in the response
 				boshassert.MatchesJSONMap(GinkgoT(), response, map[string]interface{}{
@@ -161,6 +299,22 @@ var _ = Describe("SSHAction", func() {
 					"status":  "success",
 				})
 			})
+
+			It("returns an error when removing user roles fails", func() {
+				userRoles.RemoveRolesMatchingErr = errors.New("fake-remove-err")
+
+				_, err := action.Run("cleanup", SSHParams{UserRegex: "^foobar.*"})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-remove-err"))
+			})
+
+			It("returns an error when removing user expiries fails", func() {
+				userReaper.RemoveExpiriesMatchingErr = errors.New("fake-remove-expiries-err")
+
+				_, err := action.Run("cleanup", SSHParams{UserRegex: "^foobar.*"})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-remove-expiries-err"))
+			})
 		})
 	})
 })
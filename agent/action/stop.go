@@ -4,16 +4,19 @@ import (
 	"errors"
 
 	boshjobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor"
+	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 )
 
 type StopAction struct {
 	jobSupervisor boshjobsuper.JobSupervisor
+	platform      boshplatform.Platform
 }
 
-func NewStop(jobSupervisor boshjobsuper.JobSupervisor) (stop StopAction) {
+func NewStop(jobSupervisor boshjobsuper.JobSupervisor, platform boshplatform.Platform) (stop StopAction) {
 	stop = StopAction{
 		jobSupervisor: jobSupervisor,
+		platform:      platform,
 	}
 	return
 }
@@ -42,6 +45,14 @@ func (a StopAction) Run(protocolVersion ProtocolVersion) (value string, err erro
 		return
 	}
 
+	// Secrets are only needed by running jobs
+	// and are delivered again by the next apply
+	err = a.platform.RemoveJobSecrets()
+	if err != nil {
+		err = bosherr.WrapError(err, "Removing job secrets")
+		return
+	}
+
 	value = "stopped"
 	return
 }
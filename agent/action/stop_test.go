@@ -1,23 +1,28 @@
 package action_test
 
 import (
+	"errors"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
 	. "github.com/cloudfoundry/bosh-agent/agent/action"
 	fakejobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor/fakes"
+	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
 )
 
 func init() {
 	Describe("Stop", func() {
 		var (
 			jobSupervisor *fakejobsuper.FakeJobSupervisor
+			platform      *fakeplatform.FakePlatform
 			action        StopAction
 		)
 
 		BeforeEach(func() {
 			jobSupervisor = fakejobsuper.NewFakeJobSupervisor()
-			action = NewStop(jobSupervisor)
+			platform = fakeplatform.NewFakePlatform()
+			action = NewStop(jobSupervisor, platform)
 		})
 
 		AssertActionIsAsynchronous(action)
@@ -50,5 +55,28 @@ func init() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(jobSupervisor.StoppedAndWaited).To(BeTrue())
 		})
+
+		It("removes job secrets after stopping job supervisor services", func() {
+			_, err := action.Run(ProtocolVersion(2))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(platform.RemoveJobSecretsCalled).To(BeTrue())
+		})
+
+		It("does not remove job secrets when stopping job supervisor services fails", func() {
+			jobSupervisor.StopErr = errors.New("fake-stop-error")
+
+			_, err := action.Run(ProtocolVersion(2))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-stop-error"))
+			Expect(platform.RemoveJobSecretsCalled).To(BeFalse())
+		})
+
+		It("returns error when removing job secrets fails", func() {
+			platform.RemoveJobSecretsErr = errors.New("fake-remove-job-secrets-error")
+
+			_, err := action.Run(ProtocolVersion(2))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-remove-job-secrets-error"))
+		})
 	})
 }
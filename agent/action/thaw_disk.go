@@ -0,0 +1,44 @@
+package action
+
+import (
+	"errors"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+type ThawDiskAction struct {
+	diskFreezer *DiskFreezer
+}
+
+func NewThawDisk(diskFreezer *DiskFreezer) ThawDiskAction {
+	return ThawDiskAction{diskFreezer: diskFreezer}
+}
+
+func (a ThawDiskAction) IsAsynchronous() bool {
+	return true
+}
+
+func (a ThawDiskAction) IsPersistent() bool {
+	return false
+}
+
+func (a ThawDiskAction) IsLoggable() bool {
+	return true
+}
+
+func (a ThawDiskAction) Run() (interface{}, error) {
+	err := a.diskFreezer.Thaw()
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Thawing persistent disk")
+	}
+
+	return map[string]string{}, nil
+}
+
+func (a ThawDiskAction) Resume() (interface{}, error) {
+	return nil, errors.New("not supported")
+}
+
+func (a ThawDiskAction) Cancel() error {
+	return errors.New("not supported")
+}
@@ -0,0 +1,64 @@
+package action_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-golang/clock/fakeclock"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	fakeapplyspec "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec/fakes"
+	fakescript "github.com/cloudfoundry/bosh-agent/agent/script/fakes"
+	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
+	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+var _ = Describe("ThawDiskAction", func() {
+	var (
+		platform    *fakeplatform.FakePlatform
+		diskFreezer *DiskFreezer
+		action      ThawDiskAction
+	)
+
+	BeforeEach(func() {
+		platform = fakeplatform.NewFakePlatform()
+		platform.IsMountPointResult = true
+
+		fakeJobScriptProvider := &fakescript.FakeJobScriptProvider{}
+		fakeJobScriptProvider.NewParallelScriptReturns(&fakescript.FakeCancellableScript{})
+
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+
+		diskFreezer = NewDiskFreezer(platform, fakeapplyspec.NewFakeV1Service(), fakeJobScriptProvider, boshdirs.NewProvider("/fake-base-dir"), fakeclock.NewFakeClock(time.Now()), logger)
+		action = NewThawDisk(diskFreezer)
+	})
+
+	AssertActionIsAsynchronous(action)
+	AssertActionIsNotPersistent(action)
+	AssertActionIsLoggable(action)
+
+	AssertActionIsNotResumable(action)
+	AssertActionIsNotCancelable(action)
+
+	Describe("Run", func() {
+		It("thaws frozen persistent disk", func() {
+			err := diskFreezer.Freeze(time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+
+			value, err := action.Run()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(value).To(Equal(map[string]string{}))
+
+			Expect(platform.ThawFilesystemMountPoints).To(Equal([]string{"/fake-base-dir/store"}))
+		})
+
+		It("returns error when persistent disk is not frozen", func() {
+			_, err := action.Run()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Persistent disk is not frozen"))
+		})
+	})
+})
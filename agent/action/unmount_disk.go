@@ -50,10 +50,10 @@ func (a UnmountDiskAction) Run(diskID string) (value interface{}, err error) {
 		return
 	}
 
-	msg := fmt.Sprintf("Partition of %+v is not mounted", diskSettings)
+	msg := fmt.Sprintf("Partition of %s is not mounted", describeDisk(diskSettings))
 
 	if didUnmount {
-		msg = fmt.Sprintf("Unmounted partition of %+v", diskSettings)
+		msg = fmt.Sprintf("Unmounted partition of %s", describeDisk(diskSettings))
 	}
 
 	type valueType struct {
@@ -64,6 +64,21 @@ func (a UnmountDiskAction) Run(diskID string) (value interface{}, err error) {
 	return
 }
 
+// describeDisk keeps message stable when fields unrelated
+// to disk identity (e.g. mount options) are added to settings
+func describeDisk(diskSettings boshsettings.DiskSettings) string {
+	return fmt.Sprintf(
+		"{ID:%s DeviceID:%s VolumeID:%s Lun:%s HostDeviceID:%s Path:%s FileSystemType:%s}",
+		diskSettings.ID,
+		diskSettings.DeviceID,
+		diskSettings.VolumeID,
+		diskSettings.Lun,
+		diskSettings.HostDeviceID,
+		diskSettings.Path,
+		diskSettings.FileSystemType,
+	)
+}
+
 func (a UnmountDiskAction) Resume() (interface{}, error) {
 	return nil, errors.New("not supported")
 }
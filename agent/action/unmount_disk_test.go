@@ -79,6 +79,33 @@ var _ = Describe("UnmountDiskAction", func() {
 		Expect(platform.UnmountPersistentDiskSettings).To(Equal(expectedDiskSettings))
 	})
 
+	Context("when disk is associated with its own mount point", func() {
+		BeforeEach(func() {
+			settingsService := &fakesettings.FakeSettingsService{
+				Settings: boshsettings.Settings{
+					Disks: boshsettings.Disks{
+						Persistent: map[string]interface{}{
+							"vol-123":        map[string]interface{}{"path": "/dev/sdf"},
+							"vol-associated": map[string]interface{}{"path": "/dev/sdg"},
+						},
+					},
+				},
+			}
+			action = NewUnmountDisk(settingsService, platform)
+		})
+
+		It("unmounts only the associated disk", func() {
+			platform.UnmountPersistentDiskDidUnmount = true
+
+			result, err := action.Run("vol-associated")
+			Expect(err).ToNot(HaveOccurred())
+			boshassert.MatchesJSONString(GinkgoT(), result, `{"message":"Unmounted partition of {ID:vol-associated DeviceID: VolumeID: Lun: HostDeviceID: Path:/dev/sdg FileSystemType:}"}`)
+
+			Expect(platform.UnmountPersistentDiskSettings.ID).To(Equal("vol-associated"))
+			Expect(platform.UnmountPersistentDiskSettings.Path).To(Equal("/dev/sdg"))
+		})
+	})
+
 	It("unmount disk when device path not found", func() {
 		_, err := action.Run("vol-456")
 		Expect(err).To(HaveOccurred())
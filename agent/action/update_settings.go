@@ -2,8 +2,11 @@ package action
 
 import (
 	"errors"
+	"reflect"
 
 	"encoding/json"
+	boshagentblobstore "github.com/cloudfoundry/bosh-agent/agent/blobstore"
+	boshhandler "github.com/cloudfoundry/bosh-agent/handler"
 	"github.com/cloudfoundry/bosh-agent/platform"
 	"github.com/cloudfoundry/bosh-agent/platform/cert"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
@@ -15,16 +18,29 @@ import (
 type UpdateSettingsAction struct {
 	trustedCertManager cert.Manager
 	logger             logger.Logger
+	logTag             string
 	settingsService    boshsettings.Service
 	platform           platform.Platform
+	mbusHandler        boshhandler.Handler
+	blobstore          boshagentblobstore.SwitchableBlobstore
 }
 
-func NewUpdateSettings(service boshsettings.Service, platform platform.Platform, trustedCertManager cert.Manager, logger logger.Logger) UpdateSettingsAction {
+func NewUpdateSettings(
+	service boshsettings.Service,
+	platform platform.Platform,
+	trustedCertManager cert.Manager,
+	mbusHandler boshhandler.Handler,
+	blobstore boshagentblobstore.SwitchableBlobstore,
+	logger logger.Logger,
+) UpdateSettingsAction {
 	return UpdateSettingsAction{
 		trustedCertManager: trustedCertManager,
 		logger:             logger,
+		logTag:             "UpdateSettings Action",
 		settingsService:    service,
 		platform:           platform,
+		mbusHandler:        mbusHandler,
+		blobstore:          blobstore,
 	}
 }
 
@@ -36,8 +52,9 @@ func (a UpdateSettingsAction) IsPersistent() bool {
 	return false
 }
 
+// IsLoggable returns false since update settings may carry rotated credentials
 func (a UpdateSettingsAction) IsLoggable() bool {
-	return true
+	return false
 }
 
 func (a UpdateSettingsAction) Run(newUpdateSettings boshsettings.UpdateSettings) (string, error) {
@@ -48,6 +65,27 @@ func (a UpdateSettingsAction) Run(newUpdateSettings boshsettings.UpdateSettings)
 
 	currentSettings := a.settingsService.GetSettings()
 
+	updateSettingsPath := filepath.Join(a.platform.GetDirProvider().BoshDir(), "update_settings.json")
+
+	// Credentials are only sent when rotated so previously
+	// rotated ones have to be kept until rotated again
+	previousUpdateSettings, err := a.loadUpdateSettings(updateSettingsPath)
+	if err != nil {
+		return "", err
+	}
+
+	if newUpdateSettings.Mbus == "" {
+		newUpdateSettings.Mbus = previousUpdateSettings.Mbus
+	}
+
+	if newUpdateSettings.Blobstore == nil {
+		newUpdateSettings.Blobstore = previousUpdateSettings.Blobstore
+	}
+
+	if newUpdateSettings.RevokedSSHKeys == nil {
+		newUpdateSettings.RevokedSSHKeys = previousUpdateSettings.RevokedSSHKeys
+	}
+
 	for _, diskAssociation := range newUpdateSettings.DiskAssociations {
 		diskSettings, found := currentSettings.PersistentDiskSettings(diskAssociation.DiskCID)
 		if !found {
@@ -58,6 +96,18 @@ func (a UpdateSettingsAction) Run(newUpdateSettings boshsettings.UpdateSettings)
 		if err != nil {
 			return "", err
 		}
+
+		if diskAssociation.Mount {
+			mountPoint, err := diskAssociation.ResolveMountPoint(a.platform.GetDirProvider().StoreDir())
+			if err != nil {
+				return "", err
+			}
+
+			err = a.platform.MountAssociatedPersistentDisk(diskSettings, mountPoint)
+			if err != nil {
+				return "", bosherr.WrapErrorf(err, "Mounting persistent disk '%s'", diskAssociation.Name)
+			}
+		}
 	}
 
 	err = a.trustedCertManager.UpdateCertificates(newUpdateSettings.TrustedCerts)
@@ -65,20 +115,98 @@ func (a UpdateSettingsAction) Run(newUpdateSettings boshsettings.UpdateSettings)
 		return "", err
 	}
 
+	if newUpdateSettings.RevokedSSHKeys != nil {
+		err = a.platform.UpdateRevokedSSHKeys(*newUpdateSettings.RevokedSSHKeys)
+		if err != nil {
+			return "", bosherr.WrapError(err, "Updating revoked ssh keys")
+		}
+	}
+
+	rollbackCredentials, err := a.rotateCredentials(currentSettings, newUpdateSettings)
+	if err != nil {
+		return "", err
+	}
+
 	updateSettingsJSON, err := json.Marshal(newUpdateSettings)
 	if err != nil {
+		rollbackCredentials()
 		return "", bosherr.WrapError(err, "Marshalling updateSettings json")
 	}
 
-	updateSettingsPath := filepath.Join(a.platform.GetDirProvider().BoshDir(), "update_settings.json")
 	err = a.platform.GetFs().WriteFile(updateSettingsPath, updateSettingsJSON)
 	if err != nil {
+		rollbackCredentials()
 		return "", bosherr.WrapError(err, "writing update settings json")
 	}
 
+	a.settingsService.ApplyUpdateSettings(newUpdateSettings)
+
 	return "updated", nil
 }
 
+// rotateCredentials switches blobstore and mbus over to rotated credentials
+// and returns a func switching them back in case update cannot be persisted
+func (a UpdateSettingsAction) rotateCredentials(currentSettings boshsettings.Settings, updateSettings boshsettings.UpdateSettings) (func(), error) {
+	var rollbacks []func()
+
+	rollback := func() {
+		for i := len(rollbacks) - 1; i >= 0; i-- {
+			rollbacks[i]()
+		}
+	}
+
+	if updateSettings.Blobstore != nil && !reflect.DeepEqual(*updateSettings.Blobstore, currentSettings.Blobstore) {
+		err := a.blobstore.Switch(*updateSettings.Blobstore)
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Switching blobstore")
+		}
+
+		rollbacks = append(rollbacks, func() {
+			err := a.blobstore.Switch(currentSettings.Blobstore)
+			if err != nil {
+				a.logger.Error(a.logTag, "Switching blobstore back: %s", err.Error())
+			}
+		})
+	}
+
+	if updateSettings.Mbus != "" && updateSettings.Mbus != currentSettings.Mbus {
+		err := a.mbusHandler.SwitchURL(updateSettings.Mbus)
+		if err != nil {
+			rollback()
+			return nil, bosherr.WrapError(err, "Switching mbus")
+		}
+
+		rollbacks = append(rollbacks, func() {
+			err := a.mbusHandler.SwitchURL(currentSettings.Mbus)
+			if err != nil {
+				a.logger.Error(a.logTag, "Switching mbus back: %s", err.Error())
+			}
+		})
+	}
+
+	return rollback, nil
+}
+
+func (a UpdateSettingsAction) loadUpdateSettings(updateSettingsPath string) (boshsettings.UpdateSettings, error) {
+	var updateSettings boshsettings.UpdateSettings
+
+	if !a.platform.GetFs().FileExists(updateSettingsPath) {
+		return updateSettings, nil
+	}
+
+	contents, err := a.platform.GetFs().ReadFile(updateSettingsPath)
+	if err != nil {
+		return updateSettings, bosherr.WrapError(err, "Reading update_settings.json")
+	}
+
+	err = json.Unmarshal(contents, &updateSettings)
+	if err != nil {
+		return updateSettings, bosherr.WrapError(err, "Unmarshalling update_settings.json")
+	}
+
+	return updateSettings, nil
+}
+
 func (a UpdateSettingsAction) Resume() (interface{}, error) {
 	return nil, errors.New("not supported")
 }
@@ -1,7 +1,12 @@
 package agent
 
 import (
+	"encoding/json"
+
+	"github.com/pivotal-golang/clock"
+
 	boshaction "github.com/cloudfoundry/bosh-agent/agent/action"
+	boshaudit "github.com/cloudfoundry/bosh-agent/agent/audit"
 	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
 	boshhandler "github.com/cloudfoundry/bosh-agent/handler"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
@@ -21,6 +26,8 @@ type concreteActionDispatcher struct {
 	taskManager   boshtask.Manager
 	actionFactory boshaction.Factory
 	actionRunner  boshaction.Runner
+	auditRecorder boshaudit.Recorder
+	timeService   clock.Clock
 }
 
 func NewActionDispatcher(
@@ -29,6 +36,8 @@ func NewActionDispatcher(
 	taskManager boshtask.Manager,
 	actionFactory boshaction.Factory,
 	actionRunner boshaction.Runner,
+	auditRecorder boshaudit.Recorder,
+	timeService clock.Clock,
 ) (dispatcher ActionDispatcher) {
 	return concreteActionDispatcher{
 		logger:        logger,
@@ -36,6 +45,8 @@ func NewActionDispatcher(
 		taskManager:   taskManager,
 		actionFactory: actionFactory,
 		actionRunner:  actionRunner,
+		auditRecorder: auditRecorder,
+		timeService:   timeService,
 	}
 }
 
@@ -63,9 +74,16 @@ func (dispatcher concreteActionDispatcher) ResumePreviouslyDispatchedTasks() {
 		taskID := taskInfo.TaskID
 		payload := taskInfo.Payload
 
+		auditEvent := dispatcher.newAuditEvent(action, boshhandler.Request{Method: taskInfo.Method, Payload: payload})
+		auditEvent.TaskID = taskID
+
 		task := dispatcher.taskService.CreateTaskWithID(
 			taskID,
-			func() (interface{}, error) { return dispatcher.actionRunner.Resume(action, payload) },
+			func() (interface{}, error) {
+				value, err := dispatcher.actionRunner.Resume(action, payload)
+				dispatcher.auditRecorder.RecordAction(auditEvent.Finished(dispatcher.timeService.Now(), err))
+				return value, err
+			},
 			func(_ boshtask.Task) error { return action.Cancel() },
 			dispatcher.removeInfo,
 		)
@@ -102,8 +120,16 @@ func (dispatcher concreteActionDispatcher) dispatchAsynchronousAction(
 	var task boshtask.Task
 	var err error
 
+	auditEvent := dispatcher.newAuditEvent(action, req)
+
 	runTask := func() (interface{}, error) {
-		return dispatcher.actionRunner.Run(action, req.GetPayload())
+		value, err := dispatcher.actionRunner.Run(action, req.GetPayload())
+
+		// Task is only started after it has been created so its ID is known by now
+		auditEvent.TaskID = task.ID
+		dispatcher.auditRecorder.RecordAction(auditEvent.Finished(dispatcher.timeService.Now(), err))
+
+		return value, err
 	}
 
 	cancelTask := func(_ boshtask.Task) error { return action.Cancel() }
@@ -155,7 +181,12 @@ func (dispatcher concreteActionDispatcher) dispatchSynchronousAction(
 ) boshhandler.Response {
 	dispatcher.logger.Info(actionDispatcherLogTag, "Running sync action %s", req.Method)
 
+	auditEvent := dispatcher.newAuditEvent(action, req)
+
 	value, err := dispatcher.actionRunner.Run(action, req.GetPayload())
+
+	dispatcher.auditRecorder.RecordAction(auditEvent.Finished(dispatcher.timeService.Now(), err))
+
 	if err != nil {
 		err = bosherr.WrapErrorf(err, "Action Failed %s", req.Method)
 		dispatcher.logger.Error(actionDispatcherLogTag, err.Error())
@@ -173,3 +204,37 @@ func (dispatcher concreteActionDispatcher) removeInfo(task boshtask.Task) {
 		dispatcher.logger.Error(actionDispatcherLogTag, err.Error())
 	}
 }
+
+// newAuditEvent starts audit event for an action; arguments of actions
+// that are not loggable are redacted since they may contain credentials
+func (dispatcher concreteActionDispatcher) newAuditEvent(action boshaction.Action, req boshhandler.Request) boshaudit.ActionEvent {
+	event := boshaudit.ActionEvent{
+		Action:    req.Method,
+		Caller:    req.Caller,
+		StartedAt: dispatcher.timeService.Now(),
+	}
+
+	var payload struct {
+		ReplyTo   string          `json:"reply_to"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+
+	// Payload is not required to be valid JSON to be audited
+	_ = json.Unmarshal(req.GetPayload(), &payload)
+
+	if event.Caller == "" {
+		event.Caller = req.ReplyTo
+	}
+
+	if event.Caller == "" {
+		event.Caller = payload.ReplyTo
+	}
+
+	if action.IsLoggable() {
+		event.Arguments = payload.Arguments
+	} else {
+		event.Redacted = true
+	}
+
+	return event
+}
@@ -4,12 +4,16 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/clock/fakeclock"
 
 	. "github.com/cloudfoundry/bosh-agent/agent"
 	fakeaction "github.com/cloudfoundry/bosh-agent/agent/action/fakes"
+	boshaudit "github.com/cloudfoundry/bosh-agent/agent/audit"
+	fakeaudit "github.com/cloudfoundry/bosh-agent/agent/audit/fakes"
 	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
 	faketask "github.com/cloudfoundry/bosh-agent/agent/task/fakes"
 	boshhandler "github.com/cloudfoundry/bosh-agent/handler"
@@ -25,6 +29,8 @@ func init() {
 			taskManager   *faketask.FakeManager
 			actionFactory *fakeaction.FakeFactory
 			actionRunner  *fakeaction.FakeRunner
+			auditRecorder *fakeaudit.FakeRecorder
+			timeService   *fakeclock.FakeClock
 			dispatcher    ActionDispatcher
 		)
 
@@ -34,7 +40,9 @@ func init() {
 			taskManager = faketask.NewFakeManager()
 			actionFactory = fakeaction.NewFakeFactory()
 			actionRunner = &fakeaction.FakeRunner{}
-			dispatcher = NewActionDispatcher(logger, taskService, taskManager, actionFactory, actionRunner)
+			auditRecorder = fakeaudit.NewFakeRecorder()
+			timeService = fakeclock.NewFakeClock(time.Date(2016, time.October, 19, 12, 0, 0, 0, time.UTC))
+			dispatcher = NewActionDispatcher(logger, taskService, taskManager, actionFactory, actionRunner, auditRecorder, timeService)
 		})
 
 		It("responds with exception when the method is unknown", func() {
@@ -81,6 +89,107 @@ func init() {
 			})
 		})
 
+		Describe("auditing", func() {
+			var (
+				req    boshhandler.Request
+				action *fakeaction.TestAction
+			)
+
+			BeforeEach(func() {
+				req = boshhandler.NewRequest(
+					"fake-reply",
+					"fake-action",
+					[]byte(`{"method":"fake-action","arguments":["fake-arg"],"reply_to":"fake-reply"}`),
+				)
+				action = &fakeaction.TestAction{Loggable: true}
+				actionFactory.RegisterAction("fake-action", action)
+			})
+
+			It("records caller, arguments, timing and outcome of synchronous action", func() {
+				dispatcher.Dispatch(req)
+
+				Expect(auditRecorder.GetActionEvents()).To(Equal([]boshaudit.ActionEvent{
+					{
+						Action:     "fake-action",
+						Caller:     "fake-reply",
+						Arguments:  json.RawMessage(`["fake-arg"]`),
+						StartedAt:  time.Date(2016, time.October, 19, 12, 0, 0, 0, time.UTC),
+						FinishedAt: time.Date(2016, time.October, 19, 12, 0, 0, 0, time.UTC),
+						Outcome:    boshaudit.OutcomeSucceeded,
+					},
+				}))
+			})
+
+			It("records failure of synchronous action", func() {
+				actionRunner.RunErr = errors.New("fake-run-error")
+
+				dispatcher.Dispatch(req)
+
+				events := auditRecorder.GetActionEvents()
+				Expect(events).To(HaveLen(1))
+				Expect(events[0].Outcome).To(Equal(boshaudit.OutcomeFailed))
+				Expect(events[0].Error).To(Equal("fake-run-error"))
+			})
+
+			It("prefers caller identified by transport over reply-to subject", func() {
+				req.Caller = "fake-user@fake-addr"
+
+				dispatcher.Dispatch(req)
+
+				Expect(auditRecorder.GetActionEvents()[0].Caller).To(Equal("fake-user@fake-addr"))
+			})
+
+			It("redacts arguments of actions that are not loggable", func() {
+				action.Loggable = false
+
+				dispatcher.Dispatch(req)
+
+				events := auditRecorder.GetActionEvents()
+				Expect(events).To(HaveLen(1))
+				Expect(events[0].Arguments).To(BeNil())
+				Expect(events[0].Redacted).To(BeTrue())
+			})
+
+			It("records asynchronous action with its task id once task finishes", func() {
+				action.Asynchronous = true
+
+				dispatcher.Dispatch(req)
+				Expect(auditRecorder.GetActionEvents()).To(BeEmpty())
+
+				timeService.Increment(time.Minute)
+
+				_, err := taskService.StartedTasks["fake-generated-task-id"].Func()
+				Expect(err).ToNot(HaveOccurred())
+
+				events := auditRecorder.GetActionEvents()
+				Expect(events).To(HaveLen(1))
+				Expect(events[0].TaskID).To(Equal("fake-generated-task-id"))
+				Expect(events[0].Outcome).To(Equal(boshaudit.OutcomeSucceeded))
+				Expect(events[0].StartedAt).To(Equal(time.Date(2016, time.October, 19, 12, 0, 0, 0, time.UTC)))
+				Expect(events[0].FinishedAt).To(Equal(time.Date(2016, time.October, 19, 12, 1, 0, 0, time.UTC)))
+			})
+
+			It("records resumed tasks with caller from saved payload", func() {
+				err := taskManager.AddInfo(boshtask.Info{
+					TaskID:  "fake-task-id",
+					Method:  "fake-action",
+					Payload: req.Payload,
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				dispatcher.ResumePreviouslyDispatchedTasks()
+
+				_, err = taskService.StartedTasks["fake-task-id"].Func()
+				Expect(err).ToNot(HaveOccurred())
+
+				events := auditRecorder.GetActionEvents()
+				Expect(events).To(HaveLen(1))
+				Expect(events[0].Caller).To(Equal("fake-reply"))
+				Expect(events[0].TaskID).To(Equal("fake-task-id"))
+				Expect(events[0].Arguments).To(Equal(json.RawMessage(`["fake-arg"]`)))
+			})
+		})
+
 		Context("when action is synchronous", func() {
 			var (
 				req boshhandler.Request
@@ -1,15 +1,19 @@
 package agent
 
 import (
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/pivotal-golang/clock"
 
 	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
 	boshas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
+	boshsshusers "github.com/cloudfoundry/bosh-agent/agent/sshusers"
 	boshhandler "github.com/cloudfoundry/bosh-agent/handler"
 	boshjobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor"
 	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	boshcert "github.com/cloudfoundry/bosh-agent/platform/cert"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	boshsyslog "github.com/cloudfoundry/bosh-agent/syslog"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
@@ -19,6 +23,8 @@ import (
 
 const (
 	agentLogTag = "agent"
+
+	trustedCertsCheckInterval = 24 * time.Hour
 )
 
 type Agent struct {
@@ -33,6 +39,15 @@ type Agent struct {
 	settingsService   boshsettings.Service
 	uuidGenerator     boshuuid.Generator
 	timeService       clock.Clock
+	sshUserReaper     boshsshusers.Reaper
+	sshUserRoles      boshsshusers.RoleStore
+
+	settingsRefresher       SettingsRefresher
+	settingsRefreshInterval time.Duration
+
+	stopCh   chan struct{}
+	stopOnce *sync.Once
+	tasksWg  *sync.WaitGroup
 }
 
 func New(
@@ -47,6 +62,10 @@ func New(
 	settingsService boshsettings.Service,
 	uuidGenerator boshuuid.Generator,
 	timeService clock.Clock,
+	sshUserReaper boshsshusers.Reaper,
+	sshUserRoles boshsshusers.RoleStore,
+	settingsRefresher SettingsRefresher,
+	settingsRefreshInterval time.Duration,
 ) Agent {
 	return Agent{
 		logger:            logger,
@@ -60,17 +79,41 @@ func New(
 		settingsService:   settingsService,
 		uuidGenerator:     uuidGenerator,
 		timeService:       timeService,
+		sshUserReaper:     sshUserReaper,
+		sshUserRoles:      sshUserRoles,
+
+		settingsRefresher:       settingsRefresher,
+		settingsRefreshInterval: settingsRefreshInterval,
+
+		stopCh:   make(chan struct{}),
+		stopOnce: &sync.Once{},
+		tasksWg:  &sync.WaitGroup{},
 	}
 }
 
 func (a Agent) Run() error {
 	errCh := make(chan error, 1)
 
+	// Closed once first heartbeat was sent over connected mbus
+	mbusConnectedCh := make(chan struct{})
+
+	// Closed once Run returns so that periodic tasks stop with it
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+
 	a.actionDispatcher.ResumePreviouslyDispatchedTasks()
 
 	go a.subscribeActionDispatcher(errCh)
 
-	go a.generateHeartbeats(errCh)
+	a.runTask(func() { a.generateHeartbeats(errCh, mbusConnectedCh, doneCh) })
+
+	a.runTask(func() { a.sshUserReaper.Run(doneCh) })
+
+	a.runTask(func() { a.checkTrustedCerts(mbusConnectedCh, doneCh) })
+
+	if a.settingsRefreshInterval > 0 {
+		a.runTask(func() { a.refreshSettings(doneCh) })
+	}
 
 	go func() {
 		err := a.jobSupervisor.MonitorJobFailures(a.handleJobFailure(errCh))
@@ -89,9 +132,26 @@ func (a Agent) Run() error {
 	select {
 	case err := <-errCh:
 		return err
+	case <-a.stopCh:
+		return nil
 	}
 }
 
+// Stop makes Run return and waits for periodic tasks started by it to finish
+func (a Agent) Stop() {
+	a.stopOnce.Do(func() { close(a.stopCh) })
+	a.tasksWg.Wait()
+}
+
+func (a Agent) runTask(task func()) {
+	a.tasksWg.Add(1)
+
+	go func() {
+		defer a.tasksWg.Done()
+		task()
+	}()
+}
+
 func (a Agent) subscribeActionDispatcher(errCh chan error) {
 	defer a.logger.HandlePanic("Agent Message Bus Handler")
 
@@ -103,36 +163,167 @@ func (a Agent) subscribeActionDispatcher(errCh chan error) {
 	errCh <- err
 }
 
-func (a Agent) generateHeartbeats(errCh chan error) {
+func (a Agent) generateHeartbeats(errCh chan error, mbusConnectedCh chan struct{}, doneCh chan struct{}) {
 	a.logger.Debug(agentLogTag, "Generating heartbeat")
 	defer a.logger.HandlePanic("Agent Generate Heartbeats")
 
-	// Send initial heartbeat
-	a.sendHeartbeat(errCh)
+	// Send initial heartbeat; agent stops when it cannot be sent
+	if !a.sendHeartbeat(errCh, doneCh) {
+		return
+	}
 
-	tickChan := time.Tick(a.heartbeatInterval)
+	close(mbusConnectedCh)
+
+	ticker := time.NewTicker(a.heartbeatInterval)
+	defer ticker.Stop()
 
 	for {
 		select {
-		case <-tickChan:
-			a.sendHeartbeat(errCh)
+		case <-ticker.C:
+			if !a.sendHeartbeat(errCh, doneCh) {
+				return
+			}
+		case <-doneCh:
+			return
 		}
 	}
 }
 
-func (a Agent) sendHeartbeat(errCh chan error) {
+func (a Agent) sendHeartbeat(errCh chan error, doneCh chan struct{}) bool {
 	heartbeat, err := a.getHeartbeat()
 	if err != nil {
-		err = bosherr.WrapError(err, "Building heartbeat")
-		errCh <- err
-		return
+		a.reportErr(errCh, doneCh, bosherr.WrapError(err, "Building heartbeat"))
+		return false
 	}
 
 	err = a.mbusHandler.Send(boshhandler.HealthMonitor, boshhandler.Heartbeat, heartbeat)
 	if err != nil {
-		err = bosherr.WrapError(err, "Sending heartbeat")
-		errCh <- err
+		a.reportErr(errCh, doneCh, bosherr.WrapError(err, "Sending heartbeat"))
+		return false
+	}
+
+	return true
+}
+
+// reportErr drops error when Run already returned with another one
+func (a Agent) reportErr(errCh chan error, doneCh chan struct{}, err error) {
+	select {
+	case errCh <- err:
+	case <-doneCh:
+	}
+}
+
+// checkTrustedCerts raises an alert once a day for each trusted certificate
+// which is about to expire or has expired; first check waits for mbus
+// so that its alerts are not lost while agent is still connecting
+func (a Agent) checkTrustedCerts(mbusConnectedCh chan struct{}, doneCh chan struct{}) {
+	defer a.logger.HandlePanic("Agent Check Trusted Certs")
+
+	select {
+	case <-mbusConnectedCh:
+	case <-doneCh:
+		return
+	}
+
+	ticker := a.timeService.NewTicker(trustedCertsCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		a.sendTrustedCertAlerts()
+
+		select {
+		case <-ticker.C():
+		case <-doneCh:
+			return
+		}
+	}
+}
+
+// sendTrustedCertAlerts only logs failures since
+// alerts are raised again on the next check
+func (a Agent) sendTrustedCertAlerts() {
+	window := a.settingsService.GetSettings().Env.GetTrustedCertsExpiryWindow()
+	if window == 0 {
+		return
+	}
+
+	certs, err := boshcert.ParseCertificates(a.settingsService.GetUpdateSettings().TrustedCerts)
+	if err != nil {
+		a.logger.Error(agentLogTag, "Checking trusted certificates expiry: %s", err.Error())
+		return
 	}
+
+	for _, cert := range certs {
+		alertAdapter := boshalert.NewCertExpiryAdapter(cert, window, a.uuidGenerator, a.timeService)
+		if alertAdapter.IsIgnorable() {
+			continue
+		}
+
+		alert, err := alertAdapter.Alert()
+		if err != nil {
+			a.logger.Error(agentLogTag, "Adapting trusted certificate alert: %s", err.Error())
+			continue
+		}
+
+		err = a.mbusHandler.Send(boshhandler.HealthMonitor, boshhandler.Alert, alert)
+		if err != nil {
+			a.logger.Error(agentLogTag, "Sending trusted certificate alert: %s", err.Error())
+		}
+	}
+}
+
+// refreshSettings periodically applies settings changes made on the IaaS side;
+// changes which cannot be applied are alerted once until they change again
+func (a Agent) refreshSettings(doneCh chan struct{}) {
+	defer a.logger.HandlePanic("Agent Refresh Settings")
+
+	ticker := a.timeService.NewTicker(a.settingsRefreshInterval)
+	defer ticker.Stop()
+
+	var alertedFields string
+
+	for {
+		select {
+		case <-ticker.C():
+		case <-doneCh:
+			return
+		}
+
+		unsafeFields, err := a.settingsRefresher.Refresh()
+		if err != nil {
+			// Settings source may be temporarily unavailable
+			a.logger.Warn(agentLogTag, "Refreshing settings: %s", err.Error())
+			continue
+		}
+
+		fields := strings.Join(unsafeFields, ",")
+		if fields == alertedFields {
+			continue
+		}
+
+		// Fields are only recorded once alerted so that failed alerts are retried on next tick
+		err = a.sendSettingsChangeAlert(unsafeFields)
+		if err != nil {
+			a.logger.Error(agentLogTag, "Sending settings change alert: %s", err.Error())
+			continue
+		}
+
+		alertedFields = fields
+	}
+}
+
+func (a Agent) sendSettingsChangeAlert(unsafeFields []string) error {
+	alertAdapter := boshalert.NewSettingsChangeAdapter(unsafeFields, a.uuidGenerator, a.timeService)
+	if alertAdapter.IsIgnorable() {
+		return nil
+	}
+
+	alert, err := alertAdapter.Alert()
+	if err != nil {
+		return bosherr.WrapError(err, "Adapting settings change alert")
+	}
+
+	return a.mbusHandler.Send(boshhandler.HealthMonitor, boshhandler.Alert, alert)
 }
 
 func (a Agent) getHeartbeat() (Heartbeat, error) {
@@ -192,6 +383,7 @@ func (a Agent) handleSyslogMsg(errCh chan error) boshsyslog.CallbackFunc {
 		alertAdapter := boshalert.NewSSHAdapter(
 			msg,
 			a.settingsService,
+			a.sshUserRoles,
 			a.uuidGenerator,
 			a.timeService,
 			a.logger,
@@ -13,11 +13,13 @@ import (
 	boshas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
 	fakeas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec/fakes"
 	fakeagent "github.com/cloudfoundry/bosh-agent/agent/fakes"
+	fakesshusers "github.com/cloudfoundry/bosh-agent/agent/sshusers/fakes"
 	boshhandler "github.com/cloudfoundry/bosh-agent/handler"
 	fakejobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor/fakes"
 	fakembus "github.com/cloudfoundry/bosh-agent/mbus/fakes"
 	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
 	boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	fakesettings "github.com/cloudfoundry/bosh-agent/settings/fakes"
 	boshsyslog "github.com/cloudfoundry/bosh-agent/syslog"
 	fakesyslog "github.com/cloudfoundry/bosh-agent/syslog/fakes"
@@ -26,20 +28,36 @@ import (
 	"github.com/pivotal-golang/clock/fakeclock"
 )
 
+// Self-signed certificate valid until 2036-10-16
+const trustedCert = `-----BEGIN CERTIFICATE-----
+MIIBmDCCAT2gAwIBAgIUHV7zfsuSIWe9qdKgl/GwROlB5FswCgYIKoZIzj0EAwIw
+ITENMAsGA1UECgwEYm9zaDEQMA4GA1UEAwwHZmFrZS1jYTAeFw0yNjEwMTkwNjU2
+MTVaFw0zNjEwMTYwNjU2MTVaMCExDTALBgNVBAoMBGJvc2gxEDAOBgNVBAMMB2Zh
+a2UtY2EwWTATBgcqhkjOPQIBBggqhkjOPQMBBwNCAATDycAHSAx59KnbI8+m6z/f
+6tkezbbPOxEPMZ1Upq1UCne++AYEaJfOjaopdaFO/h8oM6JAO5WYiaUJrYpeNbNx
+o1MwUTAdBgNVHQ4EFgQUBA2pcSpl1SkAaE3FR34vlYAUFqMwHwYDVR0jBBgwFoAU
+BA2pcSpl1SkAaE3FR34vlYAUFqMwDwYDVR0TAQH/BAUwAwEB/zAKBggqhkjOPQQD
+AgNJADBGAiEA5sNiaOkavlXIcUkzmh3g66ZnAIjQ0J+6B/7lSSkgZ8UCIQDxuXdY
+D5e6cymwBfeY1Fm3kS1ivC+SHtIsRtq38CpI1g==
+-----END CERTIFICATE-----`
+
 func init() {
 	Describe("Agent", func() {
 		var (
-			logger           boshlog.Logger
-			handler          *fakembus.FakeHandler
-			platform         *fakeplatform.FakePlatform
-			actionDispatcher *fakeagent.FakeActionDispatcher
-			jobSupervisor    *fakejobsuper.FakeJobSupervisor
-			specService      *fakeas.FakeV1Service
-			syslogServer     *fakesyslog.FakeServer
-			settingsService  *fakesettings.FakeSettingsService
-			uuidGenerator    *fakeuuid.FakeGenerator
-			timeService      *fakeclock.FakeClock
-			agent            Agent
+			logger            boshlog.Logger
+			handler           *fakembus.FakeHandler
+			platform          *fakeplatform.FakePlatform
+			actionDispatcher  *fakeagent.FakeActionDispatcher
+			jobSupervisor     *fakejobsuper.FakeJobSupervisor
+			specService       *fakeas.FakeV1Service
+			syslogServer      *fakesyslog.FakeServer
+			settingsService   *fakesettings.FakeSettingsService
+			uuidGenerator     *fakeuuid.FakeGenerator
+			timeService       *fakeclock.FakeClock
+			sshUserReaper     *fakesshusers.FakeReaper
+			sshUserRoles      *fakesshusers.FakeRoleStore
+			settingsRefresher *fakeagent.FakeSettingsRefresher
+			agent             Agent
 		)
 
 		BeforeEach(func() {
@@ -53,6 +71,9 @@ func init() {
 			settingsService = &fakesettings.FakeSettingsService{}
 			uuidGenerator = &fakeuuid.FakeGenerator{}
 			timeService = fakeclock.NewFakeClock(time.Now())
+			sshUserReaper = &fakesshusers.FakeReaper{}
+			sshUserRoles = fakesshusers.NewFakeRoleStore()
+			settingsRefresher = &fakeagent.FakeSettingsRefresher{}
 			agent = New(
 				logger,
 				handler,
@@ -65,9 +86,17 @@ func init() {
 				settingsService,
 				uuidGenerator,
 				timeService,
+				sshUserReaper,
+				sshUserRoles,
+				settingsRefresher,
+				0,
 			)
 		})
 
+		AfterEach(func() {
+			agent.Stop()
+		})
+
 		Describe("Run", func() {
 			It("lets dispatcher handle requests arriving via handler", func() {
 				err := agent.Run()
@@ -83,6 +112,12 @@ func init() {
 				Expect(resp).To(Equal(expectedResp))
 			})
 
+			It("starts reaping expired ssh users", func() {
+				err := agent.Run()
+				Expect(err).ToNot(HaveOccurred())
+				Eventually(sshUserReaper.RunCallCount).Should(Equal(1))
+			})
+
 			It("resumes persistent actions *before* dispatching new requests", func() {
 				resumedBeforeStartingToDispatch := false
 				handler.RunCallBack = func() {
@@ -144,6 +179,10 @@ func init() {
 						settingsService,
 						uuidGenerator,
 						timeService,
+						sshUserReaper,
+						sshUserRoles,
+						settingsRefresher,
+						0,
 					)
 
 					// Immediately exit after sending initial heartbeat
@@ -252,6 +291,221 @@ func init() {
 				}))
 			})
 
+			sentAlerts := func() int {
+				var alerts int
+				for _, input := range handler.SendInputs() {
+					if input.Topic == boshhandler.Alert {
+						alerts++
+					}
+				}
+				return alerts
+			}
+
+			Context("when trusted certificates are about to expire", func() {
+				var expectedAlert boshalert.Alert
+
+				BeforeEach(func() {
+					handler.KeepOnRunning()
+
+					settingsService.ApplyUpdateSettings(boshsettings.UpdateSettings{TrustedCerts: trustedCert})
+					timeService.Increment(time.Date(2036, time.October, 11, 0, 0, 0, 0, time.UTC).Sub(timeService.Now()))
+
+					uuidGenerator.GeneratedUUID = "fake-uuid"
+
+					expectedAlert = boshalert.Alert{
+						ID:        "fake-uuid",
+						Severity:  boshalert.SeverityWarning,
+						Title:     "Trusted certificate expiring",
+						Summary:   "Trusted certificate 'CN=fake-ca,O=bosh' (sha256:5c2763e799f5453c4979a5b923ce9a7d25b17090b25aa369a5f2a9765eb7327f) expires at 2036-10-16T06:56:15Z",
+						CreatedAt: timeService.Now().Unix(),
+					}
+				})
+
+				It("sends trusted certificate expiry alerts to health manager after initial heartbeat", func() {
+					go agent.Run()
+
+					Eventually(handler.SendInputs).Should(ContainElement(fakembus.SendInput{
+						Target:  boshhandler.HealthMonitor,
+						Topic:   boshhandler.Alert,
+						Message: expectedAlert,
+					}))
+
+					Expect(handler.SendInputs()[0].Topic).To(Equal(boshhandler.Heartbeat))
+				})
+
+				It("does not check trusted certificates until initial heartbeat is sent", func() {
+					handler.SendErr = errors.New("fake-send-err")
+
+					err := agent.Run()
+					Expect(err).To(HaveOccurred())
+
+					Consistently(sentAlerts).Should(Equal(0))
+				})
+
+				It("keeps running and alerts again on the next check when alert cannot be sent", func() {
+					// Only alerts fail to be sent
+					handler.SendCallback = func(input fakembus.SendInput) {
+						if input.Topic == boshhandler.Alert {
+							handler.SendErr = errors.New("fake-send-err")
+						} else {
+							handler.SendErr = nil
+						}
+					}
+
+					errCh := make(chan error, 1)
+					go func() { errCh <- agent.Run() }()
+
+					Eventually(sentAlerts).Should(Equal(1))
+
+					timeService.WaitForWatcherAndIncrement(24 * time.Hour)
+
+					Eventually(sentAlerts).Should(Equal(2))
+
+					Consistently(errCh).ShouldNot(Receive())
+				})
+
+				It("keeps running when alert cannot be built", func() {
+					uuidGenerator.GeneratedUUID = ""
+					uuidGenerator.GenerateError = errors.New("fake-uuid-err")
+
+					errCh := make(chan error, 1)
+					go func() { errCh <- agent.Run() }()
+
+					Eventually(timeService.WatcherCount).Should(Equal(1))
+					Consistently(errCh).ShouldNot(Receive())
+				})
+			})
+
+			Context("when settings are refreshed", func() {
+				BeforeEach(func() {
+					agent = New(
+						logger,
+						handler,
+						platform,
+						actionDispatcher,
+						jobSupervisor,
+						specService,
+						syslogServer,
+						5*time.Millisecond,
+						settingsService,
+						uuidGenerator,
+						timeService,
+						sshUserReaper,
+						sshUserRoles,
+						settingsRefresher,
+						time.Minute,
+					)
+
+					handler.KeepOnRunning()
+					uuidGenerator.GeneratedUUID = "fake-uuid"
+				})
+
+				It("sends alert for settings changes that were not applied to health manager", func() {
+					settingsRefresher.RefreshUnsafeFields = []string{"disks", "vm"}
+
+					go agent.Run()
+
+					// Trusted certs and settings refresh tickers
+					Eventually(timeService.WatcherCount).Should(Equal(2))
+					timeService.Increment(time.Minute)
+
+					expectedAlert := boshalert.Alert{
+						ID:        "fake-uuid",
+						Severity:  boshalert.SeverityWarning,
+						Title:     "Settings changed",
+						Summary:   "Settings changes to 'disks', 'vm' were not applied since they require VM restart",
+						CreatedAt: timeService.Now().Unix(),
+					}
+
+					Eventually(handler.SendInputs).Should(ContainElement(fakembus.SendInput{
+						Target:  boshhandler.HealthMonitor,
+						Topic:   boshhandler.Alert,
+						Message: expectedAlert,
+					}))
+				})
+
+				It("does not alert again about the same settings changes", func() {
+					settingsRefresher.RefreshUnsafeFields = []string{"disks", "vm"}
+
+					go agent.Run()
+
+					Eventually(timeService.WatcherCount).Should(Equal(2))
+					timeService.Increment(time.Minute)
+					Eventually(sentAlerts).Should(Equal(1))
+
+					timeService.Increment(time.Minute)
+					Eventually(settingsRefresher.RefreshCallCount).Should(Equal(2))
+					Consistently(sentAlerts).Should(Equal(1))
+				})
+
+				It("keeps running and alerts again on the next refresh when alert cannot be sent", func() {
+					settingsRefresher.RefreshUnsafeFields = []string{"disks", "vm"}
+
+					// Only alerts fail to be sent
+					handler.SendCallback = func(input fakembus.SendInput) {
+						if input.Topic == boshhandler.Alert {
+							handler.SendErr = errors.New("fake-send-err")
+						} else {
+							handler.SendErr = nil
+						}
+					}
+
+					errCh := make(chan error, 1)
+					go func() { errCh <- agent.Run() }()
+
+					Eventually(timeService.WatcherCount).Should(Equal(2))
+					timeService.Increment(time.Minute)
+					Eventually(sentAlerts).Should(Equal(1))
+
+					timeService.Increment(time.Minute)
+					Eventually(sentAlerts).Should(Equal(2))
+
+					Consistently(errCh).ShouldNot(Receive())
+				})
+
+				It("keeps running and alerts again on the next refresh when alert cannot be built", func() {
+					settingsRefresher.RefreshUnsafeFields = []string{"disks", "vm"}
+					uuidGenerator.GeneratedUUID = ""
+					uuidGenerator.GenerateError = errors.New("fake-uuid-err")
+
+					// UUID generator is fixed on second refresh from within
+					// refreshing goroutine which is the only one using it
+					refreshes := 0
+					settingsRefresher.RefreshCallback = func() {
+						refreshes++
+						if refreshes == 2 {
+							uuidGenerator.GeneratedUUID = "fake-uuid"
+							uuidGenerator.GenerateError = nil
+						}
+					}
+
+					errCh := make(chan error, 1)
+					go func() { errCh <- agent.Run() }()
+
+					Eventually(timeService.WatcherCount).Should(Equal(2))
+					timeService.Increment(time.Minute)
+					Eventually(settingsRefresher.RefreshCallCount).Should(Equal(1))
+					Consistently(errCh).ShouldNot(Receive())
+					Expect(sentAlerts()).To(Equal(0))
+
+					timeService.Increment(time.Minute)
+					Eventually(sentAlerts).Should(Equal(1))
+				})
+
+				It("keeps running when settings cannot be refreshed", func() {
+					settingsRefresher.RefreshErr = errors.New("fake-refresh-error")
+
+					go agent.Run()
+
+					Eventually(timeService.WatcherCount).Should(Equal(2))
+					timeService.Increment(time.Minute)
+					Eventually(settingsRefresher.RefreshCallCount).Should(Equal(1))
+
+					timeService.Increment(time.Minute)
+					Eventually(settingsRefresher.RefreshCallCount).Should(Equal(2))
+				})
+			})
+
 			It("sends ssh alerts to health manager", func() {
 				handler.KeepOnRunning()
 
@@ -286,5 +540,22 @@ func init() {
 				}))
 			})
 		})
+
+		Describe("Stop", func() {
+			It("makes Run return and stops periodic tasks", func() {
+				handler.KeepOnRunning()
+
+				errCh := make(chan error, 1)
+				go func() { errCh <- agent.Run() }()
+
+				// Trusted certs ticker
+				Eventually(timeService.WatcherCount).Should(Equal(1))
+
+				agent.Stop()
+
+				Eventually(errCh).Should(Receive(BeNil()))
+				Expect(timeService.WatcherCount()).To(Equal(0))
+			})
+		})
 	})
 }
@@ -0,0 +1,70 @@
+package alert
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cloudfoundry/bosh-agent/platform/cert"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshuuid "github.com/cloudfoundry/bosh-utils/uuid"
+	"github.com/pivotal-golang/clock"
+)
+
+type certExpiryAdapter struct {
+	certificate   cert.Certificate
+	window        time.Duration
+	uuidGenerator boshuuid.Generator
+	timeService   clock.Clock
+}
+
+// NewCertExpiryAdapter raises alert for trusted certificate
+// that expires within given window or has already expired
+func NewCertExpiryAdapter(
+	certificate cert.Certificate,
+	window time.Duration,
+	uuidGenerator boshuuid.Generator,
+	timeService clock.Clock,
+) Adapter {
+	return &certExpiryAdapter{
+		certificate:   certificate,
+		window:        window,
+		uuidGenerator: uuidGenerator,
+		timeService:   timeService,
+	}
+}
+
+func (m *certExpiryAdapter) IsIgnorable() bool {
+	return m.certificate.Expiry.After(m.timeService.Now().Add(m.window))
+}
+
+func (m *certExpiryAdapter) Alert() (Alert, error) {
+	uuid, err := m.uuidGenerator.Generate()
+	if err != nil {
+		return Alert{}, bosherr.WrapError(err, "Generating uuid")
+	}
+
+	now := m.timeService.Now()
+
+	title := "Trusted certificate expiring"
+	severity := SeverityWarning
+
+	if !m.certificate.Expiry.After(now) {
+		title = "Trusted certificate expired"
+		severity = SeverityCritical
+	}
+
+	summary := fmt.Sprintf(
+		"Trusted certificate '%s' (%s) expires at %s",
+		m.certificate.Subject,
+		m.certificate.Fingerprint,
+		m.certificate.Expiry.Format(time.RFC3339),
+	)
+
+	return Alert{
+		ID:        uuid,
+		Severity:  severity,
+		Title:     title,
+		Summary:   summary,
+		CreatedAt: now.Unix(),
+	}, nil
+}
@@ -0,0 +1,91 @@
+package alert_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/alert"
+	"github.com/cloudfoundry/bosh-agent/platform/cert"
+	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
+	"github.com/pivotal-golang/clock/fakeclock"
+)
+
+var _ = Describe("certExpiryAdapter", func() {
+	var (
+		timeService   *fakeclock.FakeClock
+		uuidGenerator *fakeuuid.FakeGenerator
+		certificate   cert.Certificate
+		adapter       Adapter
+	)
+
+	BeforeEach(func() {
+		timeService = fakeclock.NewFakeClock(time.Date(2026, time.October, 1, 12, 0, 0, 0, time.UTC))
+		uuidGenerator = &fakeuuid.FakeGenerator{GeneratedUUID: "fake-uuid"}
+		certificate = cert.Certificate{
+			Subject:     "CN=fake-ca",
+			Fingerprint: "sha256:fake-fingerprint",
+		}
+	})
+
+	JustBeforeEach(func() {
+		adapter = NewCertExpiryAdapter(certificate, 7*24*time.Hour, uuidGenerator, timeService)
+	})
+
+	Context("when certificate expires after the window", func() {
+		BeforeEach(func() {
+			certificate.Expiry = time.Date(2026, time.October, 9, 12, 0, 0, 0, time.UTC)
+		})
+
+		It("is ignorable", func() {
+			Expect(adapter.IsIgnorable()).To(BeTrue())
+		})
+	})
+
+	Context("when certificate expires within the window", func() {
+		BeforeEach(func() {
+			certificate.Expiry = time.Date(2026, time.October, 5, 12, 0, 0, 0, time.UTC)
+		})
+
+		It("is not ignorable", func() {
+			Expect(adapter.IsIgnorable()).To(BeFalse())
+		})
+
+		It("returns warning alert", func() {
+			alert, err := adapter.Alert()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(alert).To(Equal(Alert{
+				ID:        "fake-uuid",
+				Severity:  SeverityWarning,
+				Title:     "Trusted certificate expiring",
+				Summary:   "Trusted certificate 'CN=fake-ca' (sha256:fake-fingerprint) expires at 2026-10-05T12:00:00Z",
+				CreatedAt: timeService.Now().Unix(),
+			}))
+		})
+
+		It("returns error when generating uuid fails", func() {
+			uuidGenerator.GenerateError = errors.New("fake-uuid-error")
+
+			_, err := adapter.Alert()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-uuid-error"))
+		})
+	})
+
+	Context("when certificate has already expired", func() {
+		BeforeEach(func() {
+			certificate.Expiry = time.Date(2026, time.September, 30, 12, 0, 0, 0, time.UTC)
+		})
+
+		It("returns critical alert", func() {
+			Expect(adapter.IsIgnorable()).To(BeFalse())
+
+			alert, err := adapter.Alert()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(alert.Severity).To(Equal(SeverityCritical))
+			Expect(alert.Title).To(Equal("Trusted certificate expired"))
+		})
+	})
+})
@@ -0,0 +1,54 @@
+package alert
+
+import (
+	"fmt"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshuuid "github.com/cloudfoundry/bosh-utils/uuid"
+	"github.com/pivotal-golang/clock"
+)
+
+type settingsChangeAdapter struct {
+	changedFields []string
+	uuidGenerator boshuuid.Generator
+	timeService   clock.Clock
+}
+
+// NewSettingsChangeAdapter raises alert for settings changes
+// that cannot be applied without restarting the VM
+func NewSettingsChangeAdapter(
+	changedFields []string,
+	uuidGenerator boshuuid.Generator,
+	timeService clock.Clock,
+) Adapter {
+	return &settingsChangeAdapter{
+		changedFields: changedFields,
+		uuidGenerator: uuidGenerator,
+		timeService:   timeService,
+	}
+}
+
+func (m *settingsChangeAdapter) IsIgnorable() bool {
+	return len(m.changedFields) == 0
+}
+
+func (m *settingsChangeAdapter) Alert() (Alert, error) {
+	uuid, err := m.uuidGenerator.Generate()
+	if err != nil {
+		return Alert{}, bosherr.WrapError(err, "Generating uuid")
+	}
+
+	summary := fmt.Sprintf(
+		"Settings changes to '%s' were not applied since they require VM restart",
+		strings.Join(m.changedFields, "', '"),
+	)
+
+	return Alert{
+		ID:        uuid,
+		Severity:  SeverityWarning,
+		Title:     "Settings changed",
+		Summary:   summary,
+		CreatedAt: m.timeService.Now().Unix(),
+	}, nil
+}
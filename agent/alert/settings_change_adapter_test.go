@@ -0,0 +1,66 @@
+package alert_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/alert"
+	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
+	"github.com/pivotal-golang/clock/fakeclock"
+)
+
+var _ = Describe("settingsChangeAdapter", func() {
+	var (
+		timeService   *fakeclock.FakeClock
+		uuidGenerator *fakeuuid.FakeGenerator
+		changedFields []string
+		adapter       Adapter
+	)
+
+	BeforeEach(func() {
+		timeService = fakeclock.NewFakeClock(time.Date(2026, time.October, 1, 12, 0, 0, 0, time.UTC))
+		uuidGenerator = &fakeuuid.FakeGenerator{GeneratedUUID: "fake-uuid"}
+		changedFields = []string{"disks", "networks"}
+	})
+
+	JustBeforeEach(func() {
+		adapter = NewSettingsChangeAdapter(changedFields, uuidGenerator, timeService)
+	})
+
+	It("is not ignorable", func() {
+		Expect(adapter.IsIgnorable()).To(BeFalse())
+	})
+
+	It("returns warning alert listing changed fields", func() {
+		alert, err := adapter.Alert()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(alert).To(Equal(Alert{
+			ID:        "fake-uuid",
+			Severity:  SeverityWarning,
+			Title:     "Settings changed",
+			Summary:   "Settings changes to 'disks', 'networks' were not applied since they require VM restart",
+			CreatedAt: timeService.Now().Unix(),
+		}))
+	})
+
+	It("returns an error if generating uuid fails", func() {
+		uuidGenerator.GenerateError = errors.New("fake-uuid-error")
+
+		_, err := adapter.Alert()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("fake-uuid-error"))
+	})
+
+	Context("when no fields changed", func() {
+		BeforeEach(func() {
+			changedFields = nil
+		})
+
+		It("is ignorable", func() {
+			Expect(adapter.IsIgnorable()).To(BeTrue())
+		})
+	})
+})
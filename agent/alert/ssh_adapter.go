@@ -1,8 +1,10 @@
 package alert
 
 import (
+	"fmt"
 	"regexp"
 
+	boshsshusers "github.com/cloudfoundry/bosh-agent/agent/sshusers"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	boshsyslog "github.com/cloudfoundry/bosh-agent/syslog"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
@@ -19,9 +21,12 @@ var syslogMessageExpressions = map[*regexp.Regexp]string{
 	regexp.MustCompile("Connection closed by .* \\[preauth\\]"): "SSH Access Denied",
 }
 
+var sshLoginUserExpression = regexp.MustCompile("Accepted \\S+ for (\\S+) from")
+
 type sshAdapter struct {
 	message         boshsyslog.Msg
 	settingsService boshsettings.Service
+	userRoles       boshsshusers.RoleStore
 	uuidGenerator   boshuuid.Generator
 	timeService     clock.Clock
 	logger          boshlog.Logger
@@ -30,6 +35,7 @@ type sshAdapter struct {
 func NewSSHAdapter(
 	message boshsyslog.Msg,
 	settingsService boshsettings.Service,
+	userRoles boshsshusers.RoleStore,
 	uuidGenerator boshuuid.Generator,
 	timeService clock.Clock,
 	logger boshlog.Logger,
@@ -37,6 +43,7 @@ func NewSSHAdapter(
 	return &sshAdapter{
 		message:         message,
 		settingsService: settingsService,
+		userRoles:       userRoles,
 		uuidGenerator:   uuidGenerator,
 		timeService:     timeService,
 		logger:          logger,
@@ -63,11 +70,31 @@ func (m *sshAdapter) Alert() (Alert, error) {
 		ID:        uuid,
 		Severity:  SeverityWarning,
 		Title:     title,
-		Summary:   m.message.Content,
+		Summary:   m.summary(),
 		CreatedAt: m.timeService.Now().Unix(),
 	}, nil
 }
 
+// summary includes role of users created via ssh action when they log in
+func (m *sshAdapter) summary() string {
+	matches := sshLoginUserExpression.FindStringSubmatch(m.message.Content)
+	if matches == nil {
+		return m.message.Content
+	}
+
+	role, found, err := m.userRoles.FindRole(matches[1])
+	if err != nil {
+		m.logger.Error("sshAdapter", "Finding role of ssh user '%s': %s", matches[1], err.Error())
+		return m.message.Content
+	}
+
+	if !found {
+		return m.message.Content
+	}
+
+	return fmt.Sprintf("%s (role: %s)", m.message.Content, role)
+}
+
 func (m *sshAdapter) title() (title string, found bool) {
 	for expression, title := range syslogMessageExpressions {
 		if expression.MatchString(m.message.Content) {
@@ -7,6 +7,8 @@ import (
 	. "github.com/onsi/gomega"
 
 	. "github.com/cloudfoundry/bosh-agent/agent/alert"
+	boshsshusers "github.com/cloudfoundry/bosh-agent/agent/sshusers"
+	fakesshusers "github.com/cloudfoundry/bosh-agent/agent/sshusers/fakes"
 
 	fakesettings "github.com/cloudfoundry/bosh-agent/settings/fakes"
 	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
@@ -19,6 +21,7 @@ import (
 var _ = Describe("sshAdapter", func() {
 	var (
 		settingsService *fakesettings.FakeSettingsService
+		userRoles       *fakesshusers.FakeRoleStore
 		timeService     *fakeclock.FakeClock
 		logger          boshlog.Logger
 		uuidGenerator   *fakeuuid.FakeGenerator
@@ -26,6 +29,7 @@ var _ = Describe("sshAdapter", func() {
 
 	BeforeEach(func() {
 		settingsService = &fakesettings.FakeSettingsService{}
+		userRoles = fakesshusers.NewFakeRoleStore()
 		timeService = fakeclock.NewFakeClock(time.Now())
 		logger = boshlog.NewLogger(boshlog.LevelNone)
 		uuidGenerator = &fakeuuid.FakeGenerator{}
@@ -38,6 +42,7 @@ var _ = Describe("sshAdapter", func() {
 			sshAdapter := NewSSHAdapter(
 				sshMsg,
 				settingsService,
+				userRoles,
 				uuidGenerator,
 				timeService,
 				logger,
@@ -72,6 +77,7 @@ var _ = Describe("sshAdapter", func() {
 			sshAdapter := NewSSHAdapter(
 				sshMsg,
 				settingsService,
+				userRoles,
 				uuidGenerator,
 				timeService,
 				logger,
@@ -88,6 +94,7 @@ var _ = Describe("sshAdapter", func() {
 			sshAdapter := NewSSHAdapter(
 				sshMsg,
 				settingsService,
+				userRoles,
 				uuidGenerator,
 				timeService,
 				logger,
@@ -130,6 +137,7 @@ var _ = Describe("sshAdapter", func() {
 			sshAdapter := NewSSHAdapter(
 				sshMsg,
 				settingsService,
+				userRoles,
 				uuidGenerator,
 				timeService,
 				logger,
@@ -147,6 +155,7 @@ var _ = Describe("sshAdapter", func() {
 			sshAdapter := NewSSHAdapter(
 				sshMsg,
 				settingsService,
+				userRoles,
 				uuidGenerator,
 				timeService,
 				logger,
@@ -158,12 +167,50 @@ var _ = Describe("sshAdapter", func() {
 			Expect(builtAlert.CreatedAt).To(Equal(timeService.Now().Unix()))
 		})
 
+		It("Includes role of the user in login summary", func() {
+			userRoles.Roles["bosh_fake-user"] = boshsshusers.RoleReadOnly
+
+			msgContent := "Accepted publickey for bosh_fake-user from 9.9.9.9 port 58850 ssh2: RSA fake-rsa-key"
+			sshAdapter := NewSSHAdapter(
+				boshsyslog.Msg{Content: msgContent},
+				settingsService,
+				userRoles,
+				uuidGenerator,
+				timeService,
+				logger,
+			)
+
+			builtAlert, err := sshAdapter.Alert()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(builtAlert.Title).To(Equal("SSH Login"))
+			Expect(builtAlert.Summary).To(Equal(msgContent + " (role: read-only)"))
+		})
+
+		It("Does not include role of users not created via ssh action", func() {
+			msgContent := "Accepted password for vcap from 9.9.9.9 port 63696 ssh2"
+			sshAdapter := NewSSHAdapter(
+				boshsyslog.Msg{Content: msgContent},
+				settingsService,
+				userRoles,
+				uuidGenerator,
+				timeService,
+				logger,
+			)
+
+			builtAlert, err := sshAdapter.Alert()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(builtAlert.Summary).To(Equal(msgContent))
+		})
+
 		It("Sets the summary to the content of the message", func() {
 			msgContent := "disconnected by user"
 			sshMsg := boshsyslog.Msg{Content: msgContent}
 			sshAdapter := NewSSHAdapter(
 				sshMsg,
 				settingsService,
+				userRoles,
 				uuidGenerator,
 				timeService,
 				logger,
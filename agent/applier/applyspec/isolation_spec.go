@@ -0,0 +1,27 @@
+package applyspec
+
+import (
+	models "github.com/cloudfoundry/bosh-agent/agent/applier/models"
+)
+
+// IsolationSpec opts a job into running its processes in a dedicated cgroup
+type IsolationSpec struct {
+	MemoryLimitMB uint64 `json:"memory_limit_mb"`
+
+	// Percentage of a single CPU; e.g. 200 allows using two CPUs
+	CPULimitPercent uint64 `json:"cpu_limit_percent"`
+
+	PidsLimit    uint64 `json:"pids_limit"`
+	PrivateTmp   bool   `json:"private_tmp"`
+	ReadOnlyRoot bool   `json:"read_only_root"`
+}
+
+func (s IsolationSpec) AsIsolation() *models.Isolation {
+	return &models.Isolation{
+		MemoryLimitBytes: s.MemoryLimitMB * 1024 * 1024,
+		CPULimitPercent:  s.CPULimitPercent,
+		PidsLimit:        s.PidsLimit,
+		PrivateTmp:       s.PrivateTmp,
+		ReadOnlyRoot:     s.ReadOnlyRoot,
+	}
+}
@@ -0,0 +1,27 @@
+package applyspec
+
+import (
+	models "github.com/cloudfoundry/bosh-agent/agent/applier/models"
+	"github.com/cloudfoundry/bosh-utils/crypto"
+)
+
+// SecretSpec points to a single secret uploaded by BOSH director
+// so that its value never ends up in rendered templates archive
+type SecretSpec struct {
+	Sha1        crypto.MultipleDigest `json:"sha1"`
+	BlobstoreID string                `json:"blobstore_id"`
+
+	// Size in bytes is used to size memory backed dir secret is downloaded into
+	Size uint64 `json:"size,omitempty"`
+}
+
+func (s SecretSpec) AsSecret(name string) models.Secret {
+	return models.Secret{
+		Name: name,
+		Size: s.Size,
+		Source: models.Source{
+			Sha1:        s.Sha1,
+			BlobstoreID: s.BlobstoreID,
+		},
+	}
+}
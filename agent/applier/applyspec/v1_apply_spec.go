@@ -2,6 +2,7 @@ package applyspec
 
 import (
 	"encoding/json"
+	"sort"
 
 	models "github.com/cloudfoundry/bosh-agent/agent/applier/models"
 )
@@ -26,6 +27,12 @@ type V1ApplySpec struct {
 	PersistentDisk int `json:"persistent_disk"`
 
 	RenderedTemplatesArchiveSpec *RenderedTemplatesArchiveSpec `json:"rendered_templates_archive"`
+
+	// Secrets keyed by job name and then by secret name
+	SecretSpecs map[string]map[string]SecretSpec `json:"secrets,omitempty"`
+
+	// Isolation keyed by job name
+	IsolationSpecs map[string]IsolationSpec `json:"isolation,omitempty"`
 }
 
 type PropertiesSpec struct {
@@ -60,6 +67,8 @@ func (s V1ApplySpec) Jobs() []models.Job {
 		for _, j := range s.JobSpec.JobTemplateSpecsAsJobs() {
 			j.Source = s.RenderedTemplatesArchiveSpec.AsSource(j)
 			j.Packages = s.Packages()
+			j.Secrets = s.Secrets(j.Name)
+			j.Isolation = s.Isolation(j.Name)
 			jobsWithSource = append(jobsWithSource, j)
 		}
 	}
@@ -75,6 +84,33 @@ func (s V1ApplySpec) Packages() []models.Package {
 	return packages
 }
 
+// Secrets returns secrets for a job ordered by name
+func (s V1ApplySpec) Secrets(jobName string) []models.Secret {
+	var names []string
+	for name := range s.SecretSpecs[jobName] {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var secrets []models.Secret
+	for _, name := range names {
+		secrets = append(secrets, s.SecretSpecs[jobName][name].AsSecret(name))
+	}
+
+	return secrets
+}
+
+// Isolation returns nil for jobs that did not opt into isolation
+func (s V1ApplySpec) Isolation(jobName string) *models.Isolation {
+	spec, found := s.IsolationSpecs[jobName]
+	if !found {
+		return nil
+	}
+
+	return spec.AsIsolation()
+}
+
 func (s V1ApplySpec) MaxLogFileSize() string {
 	fileSize := s.PropertiesSpec.LoggingSpec.MaxLogFileSize
 	if len(fileSize) > 0 {
@@ -271,6 +271,111 @@ var _ = Describe("V1ApplySpec", func() {
 			spec := V1ApplySpec{}
 			Expect(spec.Jobs()).To(Equal([]models.Job{}))
 		})
+
+		It("attaches secrets specified for each job", func() {
+			sha1 := crypto.MustParseMultipleDigest("sha1:fake-rendered-templates-archive-sha1")
+			spec := V1ApplySpec{
+				JobSpec: JobSpec{
+					JobTemplateSpecs: []JobTemplateSpec{
+						{Name: "fake-job1-name", Version: "fake-job1-version"},
+						{Name: "fake-job2-name", Version: "fake-job2-version"},
+					},
+				},
+				RenderedTemplatesArchiveSpec: &RenderedTemplatesArchiveSpec{
+					Sha1:        &sha1,
+					BlobstoreID: "fake-rendered-templates-archive-blobstore-id",
+				},
+				SecretSpecs: map[string]map[string]SecretSpec{
+					"fake-job1-name": {
+						"fake-secret-name": {
+							Sha1:        crypto.MustParseMultipleDigest("sha1:fake-secret-sha1"),
+							BlobstoreID: "fake-secret-blob-id",
+						},
+					},
+				},
+			}
+
+			jobs := spec.Jobs()
+			Expect(jobs[0].Secrets).To(Equal([]models.Secret{
+				{
+					Name: "fake-secret-name",
+					Source: models.Source{
+						Sha1:        crypto.MustParseMultipleDigest("sha1:fake-secret-sha1"),
+						BlobstoreID: "fake-secret-blob-id",
+					},
+				},
+			}))
+			Expect(jobs[1].Secrets).To(BeEmpty())
+		})
+	})
+
+	Describe("Isolation", func() {
+		It("returns isolation of a job", func() {
+			spec := V1ApplySpec{}
+			err := json.Unmarshal([]byte(`{
+				"isolation": {
+					"fake-job-name": {
+						"memory_limit_mb": 512,
+						"cpu_limit_percent": 150,
+						"pids_limit": 100,
+						"private_tmp": true,
+						"read_only_root": true
+					}
+				}
+			}`), &spec)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(spec.Isolation("fake-job-name")).To(Equal(&models.Isolation{
+				MemoryLimitBytes: 512 * 1024 * 1024,
+				CPULimitPercent:  150,
+				PidsLimit:        100,
+				PrivateTmp:       true,
+				ReadOnlyRoot:     true,
+			}))
+		})
+
+		It("returns nil when job did not opt into isolation", func() {
+			spec := V1ApplySpec{IsolationSpecs: map[string]IsolationSpec{"fake-other-job": {}}}
+			Expect(spec.Isolation("fake-job-name")).To(BeNil())
+		})
+	})
+
+	Describe("Secrets", func() {
+		It("returns secrets of a job ordered by name", func() {
+			spec := V1ApplySpec{}
+			err := json.Unmarshal([]byte(`{
+				"secrets": {
+					"fake-job-name": {
+						"fake-secret2-name": {"blobstore_id": "fake-secret2-blob-id", "sha1": "fake-secret2-sha1", "size": 42},
+						"fake-secret1-name": {"blobstore_id": "fake-secret1-blob-id", "sha1": "fake-secret1-sha1"}
+					}
+				}
+			}`), &spec)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(spec.Secrets("fake-job-name")).To(Equal([]models.Secret{
+				{
+					Name: "fake-secret1-name",
+					Source: models.Source{
+						Sha1:        crypto.MustParseMultipleDigest("fake-secret1-sha1"),
+						BlobstoreID: "fake-secret1-blob-id",
+					},
+				},
+				{
+					Name: "fake-secret2-name",
+					Size: 42,
+					Source: models.Source{
+						Sha1:        crypto.MustParseMultipleDigest("fake-secret2-sha1"),
+						BlobstoreID: "fake-secret2-blob-id",
+					},
+				},
+			}))
+		})
+
+		It("returns no secrets when none are specified for a job", func() {
+			spec := V1ApplySpec{}
+			Expect(spec.Secrets("fake-job-name")).To(BeEmpty())
+		})
 	})
 
 	Describe("Packages", func() {
@@ -14,6 +14,7 @@ type concreteApplier struct {
 	jobApplier        jobs.Applier
 	packageApplier    packages.Applier
 	logrotateDelegate LogrotateDelegate
+	secretsDelegate   jobs.SecretsDelegate
 	jobSupervisor     boshjobsuper.JobSupervisor
 	dirProvider       boshdirs.Provider
 }
@@ -22,6 +23,7 @@ func NewConcreteApplier(
 	jobApplier jobs.Applier,
 	packageApplier packages.Applier,
 	logrotateDelegate LogrotateDelegate,
+	secretsDelegate jobs.SecretsDelegate,
 	jobSupervisor boshjobsuper.JobSupervisor,
 	dirProvider boshdirs.Provider,
 ) Applier {
@@ -29,6 +31,7 @@ func NewConcreteApplier(
 		jobApplier:        jobApplier,
 		packageApplier:    packageApplier,
 		logrotateDelegate: logrotateDelegate,
+		secretsDelegate:   secretsDelegate,
 		jobSupervisor:     jobSupervisor,
 		dirProvider:       dirProvider,
 	}
@@ -58,6 +61,11 @@ func (a *concreteApplier) Apply(currentApplySpec, desiredApplySpec as.ApplySpec)
 		return bosherr.WrapError(err, "Removing all jobs")
 	}
 
+	err = a.secretsDelegate.RemoveJobSecrets()
+	if err != nil {
+		return bosherr.WrapError(err, "Removing job secrets")
+	}
+
 	jobs := desiredApplySpec.Jobs()
 	for _, job := range jobs {
 		err = a.jobApplier.Apply(job)
@@ -56,6 +56,7 @@ func init() {
 			jobApplier        *fakejobs.FakeApplier
 			packageApplier    *fakepackages.FakeApplier
 			logRotateDelegate *FakeLogRotateDelegate
+			secretsDelegate   *fakejobs.FakeSecretsDelegate
 			jobSupervisor     *fakejobsuper.FakeJobSupervisor
 			applier           Applier
 		)
@@ -64,11 +65,13 @@ func init() {
 			jobApplier = fakejobs.NewFakeApplier()
 			packageApplier = fakepackages.NewFakeApplier()
 			logRotateDelegate = &FakeLogRotateDelegate{}
+			secretsDelegate = fakejobs.NewFakeSecretsDelegate()
 			jobSupervisor = fakejobsuper.NewFakeJobSupervisor()
 			applier = NewConcreteApplier(
 				jobApplier,
 				packageApplier,
 				logRotateDelegate,
+				secretsDelegate,
 				jobSupervisor,
 				boshdirs.NewProvider("/fake-base-dir"),
 			)
@@ -176,6 +179,21 @@ func init() {
 				Expect(err.Error()).To(ContainSubstring("fake-remove-all-jobs-error"))
 			})
 
+			It("removes secrets of previous jobs before starting to apply jobs", func() {
+				secretsDelegate.RemoveJobSecretsErr = errors.New("fake-remove-job-secrets-error")
+
+				job := buildJob()
+				err := applier.Apply(
+					&fakeas.FakeApplySpec{},
+					&fakeas.FakeApplySpec{JobResults: []models.Job{job}},
+				)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-remove-job-secrets-error"))
+
+				Expect(secretsDelegate.RemoveJobSecretsCalled).To(BeTrue())
+				Expect(jobApplier.AppliedJobs).To(Equal([]models.Job{}))
+			})
+
 			It("apply applies jobs", func() {
 				job := buildJob()
 
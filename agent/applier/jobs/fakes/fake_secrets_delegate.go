@@ -0,0 +1,36 @@
+package fakes
+
+type FakeSecretsDelegate struct {
+	SetupJobSecretsStagingCalled      bool
+	SetupJobSecretsStagingSecretSizes []uint64
+	SetupJobSecretsStagingErr         error
+
+	SetupJobSecretsSecrets map[string]map[string][]byte
+	SetupJobSecretsDir     string
+	SetupJobSecretsErr     error
+
+	RemoveJobSecretsCalled bool
+	RemoveJobSecretsErr    error
+}
+
+func NewFakeSecretsDelegate() *FakeSecretsDelegate {
+	return &FakeSecretsDelegate{
+		SetupJobSecretsSecrets: map[string]map[string][]byte{},
+	}
+}
+
+func (d *FakeSecretsDelegate) SetupJobSecretsStaging(secretSizes []uint64) (string, error) {
+	d.SetupJobSecretsStagingCalled = true
+	d.SetupJobSecretsStagingSecretSizes = secretSizes
+	return "/fake-secrets-staging-dir", d.SetupJobSecretsStagingErr
+}
+
+func (d *FakeSecretsDelegate) SetupJobSecrets(jobName string, secrets map[string][]byte) (string, error) {
+	d.SetupJobSecretsSecrets[jobName] = secrets
+	return d.SetupJobSecretsDir, d.SetupJobSecretsErr
+}
+
+func (d *FakeSecretsDelegate) RemoveJobSecrets() error {
+	d.RemoveJobSecretsCalled = true
+	return d.RemoveJobSecretsErr
+}
@@ -17,13 +17,19 @@ import (
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 )
 
-const logTag = "renderedJobApplier"
+const (
+	logTag = "renderedJobApplier"
+
+	jobSecretsLinkName = "secrets"
+)
 
 type renderedJobApplier struct {
 	jobsBc                 boshbc.BundleCollection
 	jobSupervisor          boshjobsuper.JobSupervisor
 	packageApplierProvider packages.ApplierProvider
+	secretsDelegate        SecretsDelegate
 	blobstore              boshblob.Blobstore
+	secretsBlobstore       boshblob.Blobstore
 	compressor             boshcmd.Compressor
 	fs                     boshsys.FileSystem
 	logger                 boshlog.Logger
@@ -33,7 +39,9 @@ func NewRenderedJobApplier(
 	jobsBc boshbc.BundleCollection,
 	jobSupervisor boshjobsuper.JobSupervisor,
 	packageApplierProvider packages.ApplierProvider,
+	secretsDelegate SecretsDelegate,
 	blobstore boshblob.Blobstore,
+	secretsBlobstore boshblob.Blobstore,
 	compressor boshcmd.Compressor,
 	fs boshsys.FileSystem,
 	logger boshlog.Logger,
@@ -42,7 +50,9 @@ func NewRenderedJobApplier(
 		jobsBc:                 jobsBc,
 		jobSupervisor:          jobSupervisor,
 		packageApplierProvider: packageApplierProvider,
+		secretsDelegate:        secretsDelegate,
 		blobstore:              blobstore,
+		secretsBlobstore:       secretsBlobstore,
 		compressor:             compressor,
 		fs:                     fs,
 		logger:                 logger,
@@ -90,6 +100,11 @@ func (s *renderedJobApplier) Apply(job models.Job) error {
 		return bosherr.WrapError(err, "Enabling job")
 	}
 
+	err = s.applySecrets(job, jobBundle)
+	if err != nil {
+		return err
+	}
+
 	return s.applyPackages(job)
 }
 
@@ -143,6 +158,81 @@ func (s *renderedJobApplier) downloadAndInstall(job models.Job, jobBundle boshbc
 	return nil
 }
 
+// applySecrets places job secrets on tmpfs and links them into job directory
+// (e.g. /var/vcap/jobs/job-a/secrets has symlink to /var/vcap/data/secrets/job-a)
+// so that secret values never touch ephemeral disk; secrets blobstore
+// downloads them into tmpfs staging dir for the same reason
+func (s *renderedJobApplier) applySecrets(job models.Job, jobBundle boshbc.Bundle) error {
+	_, jobDir, err := jobBundle.GetInstallPath()
+	if err != nil {
+		return bosherr.WrapError(err, "Looking up job directory")
+	}
+
+	secretsLinkPath := path.Join(jobDir, jobSecretsLinkName)
+
+	if len(job.Secrets) == 0 {
+		err = s.fs.RemoveAll(secretsLinkPath)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Removing secrets link for job %s", job.Name)
+		}
+
+		return nil
+	}
+
+	var secretSizes []uint64
+	for _, secret := range job.Secrets {
+		secretSizes = append(secretSizes, secret.Size)
+	}
+
+	_, err = s.secretsDelegate.SetupJobSecretsStaging(secretSizes)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Setting up secrets staging for job %s", job.Name)
+	}
+
+	secrets := map[string][]byte{}
+
+	for _, secret := range job.Secrets {
+		value, err := s.fetchSecret(secret)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Fetching secret %s for job %s", secret.Name, job.Name)
+		}
+
+		secrets[secret.Name] = value
+	}
+
+	secretsDir, err := s.secretsDelegate.SetupJobSecrets(job.Name, secrets)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Setting up secrets for job %s", job.Name)
+	}
+
+	err = s.fs.Symlink(secretsDir, secretsLinkPath)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Linking secrets for job %s", job.Name)
+	}
+
+	return nil
+}
+
+func (s *renderedJobApplier) fetchSecret(secret models.Secret) ([]byte, error) {
+	file, err := s.secretsBlobstore.Get(secret.Source.BlobstoreID, secret.Source.Sha1)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Getting secret from blobstore")
+	}
+
+	defer func() {
+		if err := s.secretsBlobstore.CleanUp(file); err != nil {
+			s.logger.Warn(logTag, "Failed to clean up blobstore blob: %s", err.Error())
+		}
+	}()
+
+	value, err := s.fs.ReadFile(file)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Reading secret")
+	}
+
+	return value, nil
+}
+
 // applyPackages keeps job specific packages directory up-to-date with installed packages.
 // (e.g. /var/vcap/jobs/job-a/packages/pkg-a has symlinks to /var/vcap/packages/pkg-a)
 func (s *renderedJobApplier) applyPackages(job models.Job) error {
@@ -178,9 +268,11 @@ func (s *renderedJobApplier) Configure(job models.Job, jobIndex int) (err error)
 		return
 	}
 
+	isolation := s.jobIsolation(job)
+
 	monitFilePath := path.Join(jobDir, "monit")
 	if fs.FileExists(monitFilePath) {
-		err = s.jobSupervisor.AddJob(job.Name, jobIndex, monitFilePath)
+		err = s.jobSupervisor.AddJob(job.Name, jobIndex, monitFilePath, isolation)
 		if err != nil {
 			err = bosherr.WrapError(err, "Adding monit configuration")
 			return
@@ -197,7 +289,7 @@ func (s *renderedJobApplier) Configure(job models.Job, jobIndex int) (err error)
 		label := strings.Replace(path.Base(monitFilePath), ".monit", "", 1)
 		subJobName := fmt.Sprintf("%s_%s", job.Name, label)
 
-		err = s.jobSupervisor.AddJob(subJobName, jobIndex, monitFilePath)
+		err = s.jobSupervisor.AddJob(subJobName, jobIndex, monitFilePath, isolation)
 		if err != nil {
 			err = bosherr.WrapErrorf(err, "Adding additional monit configuration %s", label)
 			return
@@ -207,6 +299,22 @@ func (s *renderedJobApplier) Configure(job models.Job, jobIndex int) (err error)
 	return nil
 }
 
+// jobIsolation places processes from all monit files of a job into a single cgroup
+func (s *renderedJobApplier) jobIsolation(job models.Job) *boshjobsuper.JobIsolation {
+	if job.Isolation == nil {
+		return nil
+	}
+
+	return &boshjobsuper.JobIsolation{
+		Cgroup:           job.Name,
+		MemoryLimitBytes: job.Isolation.MemoryLimitBytes,
+		CPULimitPercent:  job.Isolation.CPULimitPercent,
+		PidsLimit:        job.Isolation.PidsLimit,
+		PrivateTmp:       job.Isolation.PrivateTmp,
+		ReadOnlyRoot:     job.Isolation.ReadOnlyRoot,
+	}
+}
+
 func (s *renderedJobApplier) KeepOnly(jobs []models.Job) error {
 	s.logger.Debug(logTag, "Keeping only jobs %v", jobs)
 
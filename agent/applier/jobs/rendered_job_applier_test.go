@@ -9,8 +9,10 @@ import (
 	boshbc "github.com/cloudfoundry/bosh-agent/agent/applier/bundlecollection"
 	fakebc "github.com/cloudfoundry/bosh-agent/agent/applier/bundlecollection/fakes"
 	. "github.com/cloudfoundry/bosh-agent/agent/applier/jobs"
+	fakejobs "github.com/cloudfoundry/bosh-agent/agent/applier/jobs/fakes"
 	"github.com/cloudfoundry/bosh-agent/agent/applier/models"
 	fakepackages "github.com/cloudfoundry/bosh-agent/agent/applier/packages/fakes"
+	boshjobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor"
 	fakejobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor/fakes"
 	fakeblob "github.com/cloudfoundry/bosh-utils/blobstore/fakes"
 	boshcrypto "github.com/cloudfoundry/bosh-utils/crypto"
@@ -76,7 +78,9 @@ func init() {
 			jobsBc                 *fakebc.FakeBundleCollection
 			jobSupervisor          *fakejobsuper.FakeJobSupervisor
 			packageApplierProvider *fakepackages.FakeApplierProvider
+			secretsDelegate        *fakejobs.FakeSecretsDelegate
 			blobstore              *fakeblob.FakeBlobstore
+			secretsBlobstore       *fakeblob.FakeBlobstore
 			compressor             *fakecmd.FakeCompressor
 			fs                     *fakesys.FakeFileSystem
 			applier                Applier
@@ -86,7 +90,9 @@ func init() {
 			jobsBc = fakebc.NewFakeBundleCollection()
 			jobSupervisor = fakejobsuper.NewFakeJobSupervisor()
 			packageApplierProvider = fakepackages.NewFakeApplierProvider()
+			secretsDelegate = fakejobs.NewFakeSecretsDelegate()
 			blobstore = fakeblob.NewFakeBlobstore()
+			secretsBlobstore = fakeblob.NewFakeBlobstore()
 			fs = fakesys.NewFakeFileSystem()
 			compressor = fakecmd.NewFakeCompressor()
 			logger := boshlog.NewLogger(boshlog.LevelNone)
@@ -94,7 +100,9 @@ func init() {
 				jobsBc,
 				jobSupervisor,
 				packageApplierProvider,
+				secretsDelegate,
 				blobstore,
+				secretsBlobstore,
 				compressor,
 				fs,
 				logger,
@@ -109,6 +117,7 @@ func init() {
 
 			BeforeEach(func() {
 				job, bundle = buildJob(jobsBc)
+				bundle.GetDirPath = "/fake-job-install-dir"
 			})
 
 			ItInstallsJob := func(act func() error) {
@@ -368,6 +377,7 @@ func init() {
 				Context("when job is already installed", func() {
 					BeforeEach(func() {
 						bundle.Installed = true
+						packageApplierProvider.JobSpecificAppliers[job.Name] = fakepackages.NewFakeApplier()
 					})
 
 					It("does not install", func() {
@@ -422,6 +432,7 @@ func init() {
 				Context("when job is already installed", func() {
 					BeforeEach(func() {
 						bundle.Installed = true
+						packageApplierProvider.JobSpecificAppliers[job.Name] = fakepackages.NewFakeApplier()
 					})
 
 					It("does not install but only enables job", func() {
@@ -470,6 +481,101 @@ func init() {
 
 					ItUpdatesPackages(act)
 				})
+
+				Context("when job has secrets", func() {
+					BeforeEach(func() {
+						bundle.Installed = true
+						packageApplierProvider.JobSpecificAppliers[job.Name] = fakepackages.NewFakeApplier()
+
+						job.Secrets = []models.Secret{
+							{
+								Name: "fake-secret-name",
+								Size: 17,
+								Source: models.Source{
+									Sha1:        boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA1, "fake-secret-sha1"),
+									BlobstoreID: "fake-secret-blobstore-id",
+								},
+							},
+						}
+
+						secretsBlobstore.GetFileName = "/fake-secrets-staging-dir/fake-secret-file"
+						err := fs.WriteFileString("/fake-secrets-staging-dir/fake-secret-file", "fake-secret-value")
+						Expect(err).ToNot(HaveOccurred())
+
+						secretsDelegate.SetupJobSecretsDir = "/fake-secrets-dir"
+					})
+
+					It("downloads secrets into staging dir and later cleans up downloaded blobs", func() {
+						err := act()
+						Expect(err).ToNot(HaveOccurred())
+
+						Expect(secretsDelegate.SetupJobSecretsStagingCalled).To(BeTrue())
+						Expect(secretsDelegate.SetupJobSecretsStagingSecretSizes).To(Equal([]uint64{17}))
+						Expect(secretsBlobstore.GetBlobIDs).To(Equal([]string{"fake-secret-blobstore-id"}))
+						Expect(secretsBlobstore.GetFingerprints).To(Equal([]boshcrypto.Digest{
+							boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA1, "fake-secret-sha1"),
+						}))
+						Expect(secretsBlobstore.CleanUpFileName).To(Equal("/fake-secrets-staging-dir/fake-secret-file"))
+						Expect(blobstore.GetBlobIDs).ToNot(ContainElement("fake-secret-blobstore-id"))
+					})
+
+					It("does not download secrets when staging dir cannot be set up", func() {
+						secretsDelegate.SetupJobSecretsStagingErr = errors.New("fake-staging-error")
+
+						err := act()
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("fake-staging-error"))
+						Expect(secretsBlobstore.GetBlobIDs).To(BeEmpty())
+					})
+
+					It("sets up job secrets and links them into job directory", func() {
+						err := act()
+						Expect(err).ToNot(HaveOccurred())
+
+						Expect(secretsDelegate.SetupJobSecretsSecrets).To(Equal(map[string]map[string][]byte{
+							job.Name: {"fake-secret-name": []byte("fake-secret-value")},
+						}))
+
+						target, err := fs.Readlink("/fake-job-install-dir/secrets")
+						Expect(err).ToNot(HaveOccurred())
+						Expect(target).To(Equal("/fake-secrets-dir"))
+					})
+
+					It("returns error when downloading secret fails", func() {
+						secretsBlobstore.GetError = errors.New("fake-get-error")
+
+						err := act()
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("fake-get-error"))
+						Expect(secretsDelegate.SetupJobSecretsSecrets).To(BeEmpty())
+					})
+
+					It("returns error when setting up job secrets fails", func() {
+						secretsDelegate.SetupJobSecretsErr = errors.New("fake-setup-secrets-error")
+
+						err := act()
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("fake-setup-secrets-error"))
+					})
+				})
+
+				Context("when job has no secrets", func() {
+					BeforeEach(func() {
+						bundle.Installed = true
+						packageApplierProvider.JobSpecificAppliers[job.Name] = fakepackages.NewFakeApplier()
+					})
+
+					It("removes secrets link left from previous apply", func() {
+						err := fs.Symlink("/fake-secrets-dir", "/fake-job-install-dir/secrets")
+						Expect(err).ToNot(HaveOccurred())
+
+						err = act()
+						Expect(err).ToNot(HaveOccurred())
+
+						Expect(fs.FileExists("/fake-job-install-dir/secrets")).To(BeFalse())
+						Expect(secretsDelegate.SetupJobSecretsSecrets).To(BeEmpty())
+					})
+				})
 			})
 		})
 
@@ -502,6 +608,40 @@ func init() {
 				}))
 			})
 
+			It("isolates processes from all monit files of the job in a single cgroup", func() {
+				job, bundle := buildJob(jobsBc)
+				job.Isolation = &models.Isolation{
+					MemoryLimitBytes: 512 * 1024 * 1024,
+					CPULimitPercent:  50,
+					PidsLimit:        100,
+					PrivateTmp:       true,
+					ReadOnlyRoot:     true,
+				}
+
+				fs := fakesys.NewFakeFileSystem()
+				fs.WriteFileString("/path/to/job/monit", "some conf")
+				fs.SetGlob("/path/to/job/*.monit", []string{"/path/to/job/subjob.monit"})
+
+				bundle.GetDirPath = "/path/to/job"
+				bundle.GetDirFs = fs
+
+				err := applier.Configure(job, 0)
+				Expect(err).ToNot(HaveOccurred())
+
+				expectedIsolation := &boshjobsuper.JobIsolation{
+					Cgroup:           job.Name,
+					MemoryLimitBytes: 512 * 1024 * 1024,
+					CPULimitPercent:  50,
+					PidsLimit:        100,
+					PrivateTmp:       true,
+					ReadOnlyRoot:     true,
+				}
+
+				Expect(len(jobSupervisor.AddJobArgs)).To(Equal(2))
+				Expect(jobSupervisor.AddJobArgs[0].Isolation).To(Equal(expectedIsolation))
+				Expect(jobSupervisor.AddJobArgs[1].Isolation).To(Equal(expectedIsolation))
+			})
+
 			It("does not require monit script", func() {
 				job, bundle := buildJob(jobsBc)
 
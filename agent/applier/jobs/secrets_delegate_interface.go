@@ -0,0 +1,10 @@
+package jobs
+
+type SecretsDelegate interface {
+	// SetupJobSecretsStaging makes sure that secrets are downloaded
+	// into memory backed dir configured as temp dir of secrets blobstore;
+	// dir is sized to fit secrets of given sizes
+	SetupJobSecretsStaging(secretSizes []uint64) (stagingDir string, err error)
+	SetupJobSecrets(jobName string, secrets map[string][]byte) (secretsDir string, err error)
+	RemoveJobSecrets() (err error)
+}
@@ -0,0 +1,10 @@
+package models
+
+// Isolation confines processes of a job; zero limits are not enforced
+type Isolation struct {
+	MemoryLimitBytes uint64
+	CPULimitPercent  uint64
+	PidsLimit        uint64
+	PrivateTmp       bool
+	ReadOnlyRoot     bool
+}
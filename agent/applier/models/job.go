@@ -8,6 +8,13 @@ type Job struct {
 	// Packages that this job depends on; however,
 	// currently it will contain packages from all jobs
 	Packages []Package
+
+	// Secrets are not part of Source and
+	// therefore do not affect BundleVersion
+	Secrets []Secret
+
+	// Isolation is nil unless job opted into isolation
+	Isolation *Isolation
 }
 
 func (s Job) BundleName() string {
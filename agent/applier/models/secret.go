@@ -0,0 +1,9 @@
+package models
+
+// Secret is delivered to job's tmpfs backed secrets directory
+// instead of being rendered into job templates
+type Secret struct {
+	Name   string
+	Size   uint64
+	Source Source
+}
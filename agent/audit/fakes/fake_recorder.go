@@ -0,0 +1,45 @@
+package fakes
+
+import (
+	"sync"
+
+	boshaudit "github.com/cloudfoundry/bosh-agent/agent/audit"
+)
+
+type SideEffect struct {
+	Kind    string
+	Details map[string]string
+	Err     error
+}
+
+type FakeRecorder struct {
+	ActionEvents []boshaudit.ActionEvent
+	SideEffects  []SideEffect
+
+	lock sync.Mutex
+}
+
+func NewFakeRecorder() *FakeRecorder {
+	return &FakeRecorder{}
+}
+
+func (r *FakeRecorder) RecordAction(event boshaudit.ActionEvent) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.ActionEvents = append(r.ActionEvents, event)
+}
+
+func (r *FakeRecorder) RecordSideEffect(kind string, details map[string]string, err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.SideEffects = append(r.SideEffects, SideEffect{Kind: kind, Details: details, Err: err})
+}
+
+func (r *FakeRecorder) GetActionEvents() []boshaudit.ActionEvent {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.ActionEvents
+}
@@ -0,0 +1,187 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pivotal-golang/clock"
+
+	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	fileRecorderLogTag = "auditFileRecorder"
+
+	defaultMaxFileSizeInBytes = 10 * 1024 * 1024
+	defaultMaxBackups         = 5
+)
+
+type Options struct {
+	// Size audit log is rotated at; defaults to 10MB
+	MaxFileSizeInBytes int64
+
+	// Number of rotated audit logs kept; defaults to 5
+	MaxBackups int
+
+	// Syslog additionally forwards audit events to syslog
+	Syslog bool
+}
+
+func (o Options) maxFileSizeInBytes() int64 {
+	if o.MaxFileSizeInBytes <= 0 {
+		return defaultMaxFileSizeInBytes
+	}
+	return o.MaxFileSizeInBytes
+}
+
+func (o Options) maxBackups() int {
+	if o.MaxBackups <= 0 {
+		return defaultMaxBackups
+	}
+	return o.MaxBackups
+}
+
+type fileRecorder struct {
+	fs          boshsys.FileSystem
+	path        string
+	options     Options
+	auditLogger boshplatform.AuditLogger
+	timeService clock.Clock
+	logger      boshlog.Logger
+
+	lock sync.Mutex
+}
+
+// NewFileRecorder writes audit events as JSON lines to path
+// rotating it to path.1, path.2, etc. once it grows past maximum size
+func NewFileRecorder(
+	fs boshsys.FileSystem,
+	path string,
+	options Options,
+	auditLogger boshplatform.AuditLogger,
+	timeService clock.Clock,
+	logger boshlog.Logger,
+) Recorder {
+	return &fileRecorder{
+		fs:          fs,
+		path:        path,
+		options:     options,
+		auditLogger: auditLogger,
+		timeService: timeService,
+		logger:      logger,
+	}
+}
+
+func (r *fileRecorder) RecordAction(event ActionEvent) {
+	event.Type = eventTypeAction
+	r.record(event, event.Outcome)
+}
+
+func (r *fileRecorder) RecordSideEffect(kind string, details map[string]string, err error) {
+	event := SideEffectEvent{
+		Type:    eventTypeSideEffect,
+		Kind:    kind,
+		Time:    r.timeService.Now(),
+		Details: details,
+	}
+	event.Outcome, event.Error = outcomeOf(err)
+
+	r.record(event, event.Outcome)
+}
+
+func (r *fileRecorder) record(event interface{}, outcome Outcome) {
+	line, err := json.Marshal(event)
+	if err != nil {
+		r.logger.Error(fileRecorderLogTag, "Marshalling audit event: %s", err.Error())
+		return
+	}
+
+	err = r.write(append(line, '\n'))
+	if err != nil {
+		r.logger.Error(fileRecorderLogTag, "Writing audit event: %s", err.Error())
+	}
+
+	if r.options.Syslog {
+		if outcome == OutcomeFailed {
+			r.auditLogger.Err(string(line))
+		} else {
+			r.auditLogger.Debug(string(line))
+		}
+	}
+}
+
+func (r *fileRecorder) write(line []byte) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	err := r.fs.MkdirAll(filepath.Dir(r.path), os.FileMode(0750))
+	if err != nil {
+		return bosherr.WrapError(err, "Creating audit log dir")
+	}
+
+	err = r.rotateIfNeeded(int64(len(line)))
+	if err != nil {
+		return bosherr.WrapError(err, "Rotating audit log")
+	}
+
+	file, err := r.fs.OpenFile(r.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, os.FileMode(0600))
+	if err != nil {
+		return bosherr.WrapError(err, "Opening audit log")
+	}
+
+	defer func() {
+		_ = file.Close()
+	}()
+
+	_, err = file.Write(line)
+	if err != nil {
+		return bosherr.WrapError(err, "Appending to audit log")
+	}
+
+	return nil
+}
+
+func (r *fileRecorder) rotateIfNeeded(size int64) error {
+	if !r.fs.FileExists(r.path) {
+		return nil
+	}
+
+	info, err := r.fs.Stat(r.path)
+	if err != nil {
+		return bosherr.WrapError(err, "Checking audit log size")
+	}
+
+	if info.Size() == 0 || info.Size()+size <= r.options.maxFileSizeInBytes() {
+		return nil
+	}
+
+	maxBackups := r.options.maxBackups()
+
+	err = r.fs.RemoveAll(r.backupPath(maxBackups))
+	if err != nil {
+		return bosherr.WrapError(err, "Removing oldest audit log")
+	}
+
+	for i := maxBackups - 1; i >= 1; i-- {
+		if !r.fs.FileExists(r.backupPath(i)) {
+			continue
+		}
+
+		err = r.fs.Rename(r.backupPath(i), r.backupPath(i+1))
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Renaming audit log %s", r.backupPath(i))
+		}
+	}
+
+	return r.fs.Rename(r.path, r.backupPath(1))
+}
+
+func (r *fileRecorder) backupPath(i int) string {
+	return fmt.Sprintf("%s.%d", r.path, i)
+}
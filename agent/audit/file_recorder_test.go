@@ -0,0 +1,187 @@
+package audit_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/clock/fakeclock"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/audit"
+	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+var _ = Describe("FileRecorder", func() {
+	var (
+		tmpDir      string
+		auditPath   string
+		options     Options
+		auditLogger *fakeplatform.FakeAuditLogger
+		timeService *fakeclock.FakeClock
+		recorder    Recorder
+		now         time.Time
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		tmpDir, err = ioutil.TempDir("", "audit")
+		Expect(err).ToNot(HaveOccurred())
+
+		auditPath = filepath.Join(tmpDir, "log", "audit.log")
+		options = Options{}
+		auditLogger = fakeplatform.NewFakeAuditLogger()
+		now = time.Date(2016, time.October, 19, 12, 0, 0, 0, time.UTC)
+		timeService = fakeclock.NewFakeClock(now)
+	})
+
+	JustBeforeEach(func() {
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		fs := boshsys.NewOsFileSystem(logger)
+		recorder = NewFileRecorder(fs, auditPath, options, auditLogger, timeService, logger)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	readLines := func(path string) []map[string]interface{} {
+		contents, err := ioutil.ReadFile(path)
+		Expect(err).ToNot(HaveOccurred())
+
+		var lines []map[string]interface{}
+		for _, line := range strings.Split(strings.TrimSpace(string(contents)), "\n") {
+			var event map[string]interface{}
+			Expect(json.Unmarshal([]byte(line), &event)).To(Succeed())
+			lines = append(lines, event)
+		}
+
+		return lines
+	}
+
+	actionEvent := func() ActionEvent {
+		return ActionEvent{
+			Action:    "fake-action",
+			Caller:    "fake-caller",
+			TaskID:    "fake-task-id",
+			Arguments: json.RawMessage(`["fake-arg"]`),
+			StartedAt: now,
+		}.Finished(now.Add(time.Minute), nil)
+	}
+
+	It("appends action events as JSON lines", func() {
+		recorder.RecordAction(actionEvent())
+		recorder.RecordAction(actionEvent().Finished(now.Add(time.Minute), errors.New("fake-err")))
+
+		Expect(readLines(auditPath)).To(Equal([]map[string]interface{}{
+			{
+				"type":        "action",
+				"action":      "fake-action",
+				"caller":      "fake-caller",
+				"task_id":     "fake-task-id",
+				"arguments":   []interface{}{"fake-arg"},
+				"started_at":  "2016-10-19T12:00:00Z",
+				"finished_at": "2016-10-19T12:01:00Z",
+				"outcome":     "succeeded",
+			},
+			{
+				"type":        "action",
+				"action":      "fake-action",
+				"caller":      "fake-caller",
+				"task_id":     "fake-task-id",
+				"arguments":   []interface{}{"fake-arg"},
+				"started_at":  "2016-10-19T12:00:00Z",
+				"finished_at": "2016-10-19T12:01:00Z",
+				"outcome":     "failed",
+				"error":       "fake-err",
+			},
+		}))
+	})
+
+	It("only writes audit log readable by root", func() {
+		recorder.RecordAction(actionEvent())
+
+		info, err := os.Stat(auditPath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+	})
+
+	It("appends side effect events stamped with current time", func() {
+		recorder.RecordSideEffect("user_created", map[string]string{"username": "fake-user"}, nil)
+
+		Expect(readLines(auditPath)).To(Equal([]map[string]interface{}{
+			{
+				"type":    "side_effect",
+				"kind":    "user_created",
+				"time":    "2016-10-19T12:00:00Z",
+				"details": map[string]interface{}{"username": "fake-user"},
+				"outcome": "succeeded",
+			},
+		}))
+	})
+
+	It("does not forward events to syslog by default", func() {
+		recorder.RecordAction(actionEvent())
+
+		Expect(auditLogger.GetDebugMsgs()).To(BeEmpty())
+		Expect(auditLogger.GetErrMsgs()).To(BeEmpty())
+	})
+
+	Context("when syslog is enabled", func() {
+		BeforeEach(func() {
+			options.Syslog = true
+		})
+
+		It("forwards successful events as debug messages and failed ones as errors", func() {
+			recorder.RecordSideEffect("user_created", nil, nil)
+			recorder.RecordSideEffect("user_created", nil, errors.New("fake-err"))
+
+			Expect(auditLogger.GetDebugMsgs()).To(HaveLen(1))
+			Expect(auditLogger.GetDebugMsgs()[0]).To(ContainSubstring(`"outcome":"succeeded"`))
+
+			Expect(auditLogger.GetErrMsgs()).To(HaveLen(1))
+			Expect(auditLogger.GetErrMsgs()[0]).To(ContainSubstring(`"error":"fake-err"`))
+		})
+	})
+
+	Context("when audit log grows past maximum size", func() {
+		BeforeEach(func() {
+			options.MaxFileSizeInBytes = 1
+			options.MaxBackups = 2
+		})
+
+		It("rotates audit log keeping configured number of backups", func() {
+			for i := 0; i < 4; i++ {
+				recorder.RecordSideEffect("fake-kind", map[string]string{"i": strconv.Itoa(i)}, nil)
+			}
+
+			Expect(readLines(auditPath)[0]["details"]).To(Equal(map[string]interface{}{"i": "3"}))
+			Expect(readLines(auditPath + ".1")[0]["details"]).To(Equal(map[string]interface{}{"i": "2"}))
+			Expect(readLines(auditPath + ".2")[0]["details"]).To(Equal(map[string]interface{}{"i": "1"}))
+			Expect(auditPath + ".3").ToNot(BeAnExistingFile())
+		})
+	})
+
+	Context("when audit log cannot be written", func() {
+		BeforeEach(func() {
+			auditPath = filepath.Join(tmpDir, "not-a-dir", "audit.log")
+			Expect(ioutil.WriteFile(filepath.Join(tmpDir, "not-a-dir"), []byte{}, 0600)).To(Succeed())
+			options.Syslog = true
+		})
+
+		It("still forwards events to syslog", func() {
+			recorder.RecordAction(actionEvent())
+
+			Expect(auditLogger.GetDebugMsgs()).To(HaveLen(1))
+		})
+	})
+})
@@ -0,0 +1,124 @@
+package audit
+
+import (
+	"strings"
+
+	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	boshcert "github.com/cloudfoundry/bosh-agent/platform/cert"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+)
+
+// auditedPlatform records privileged side effects of platform operations
+// regardless of which action or bootstrap step triggered them
+type auditedPlatform struct {
+	boshplatform.Platform
+	recorder Recorder
+}
+
+func NewPlatform(platform boshplatform.Platform, recorder Recorder) boshplatform.Platform {
+	return auditedPlatform{Platform: platform, recorder: recorder}
+}
+
+func (p auditedPlatform) CreateUser(username, password, basePath string) error {
+	err := p.Platform.CreateUser(username, password, basePath)
+	p.recorder.RecordSideEffect("user_created", map[string]string{"username": username}, err)
+	return err
+}
+
+func (p auditedPlatform) AddUserToGroups(username string, groups []string) error {
+	err := p.Platform.AddUserToGroups(username, groups)
+	p.recorder.RecordSideEffect("user_groups_added", map[string]string{
+		"username": username,
+		"groups":   strings.Join(groups, ","),
+	}, err)
+	return err
+}
+
+func (p auditedPlatform) SetupUserSudoCommands(username string, commands []string) error {
+	err := p.Platform.SetupUserSudoCommands(username, commands)
+	p.recorder.RecordSideEffect("user_sudo_commands_set", map[string]string{
+		"username": username,
+		"commands": strings.Join(commands, ","),
+	}, err)
+	return err
+}
+
+func (p auditedPlatform) DeleteEphemeralUsersMatching(regex string) error {
+	err := p.Platform.DeleteEphemeralUsersMatching(regex)
+	p.recorder.RecordSideEffect("ephemeral_users_deleted", map[string]string{"pattern": regex}, err)
+	return err
+}
+
+func (p auditedPlatform) SetupSSH(publicKey []string, username string) error {
+	err := p.Platform.SetupSSH(publicKey, username)
+	p.recorder.RecordSideEffect("ssh_keys_set", map[string]string{"username": username}, err)
+	return err
+}
+
+func (p auditedPlatform) SetUserPassword(user, encryptedPwd string) error {
+	err := p.Platform.SetUserPassword(user, encryptedPwd)
+	p.recorder.RecordSideEffect("user_password_set", map[string]string{"username": user}, err)
+	return err
+}
+
+func (p auditedPlatform) MountPersistentDisk(diskSettings boshsettings.DiskSettings, mountPoint string) error {
+	err := p.Platform.MountPersistentDisk(diskSettings, mountPoint)
+	p.recorder.RecordSideEffect("persistent_disk_mounted", map[string]string{
+		"disk_id":     diskSettings.ID,
+		"mount_point": mountPoint,
+	}, err)
+	return err
+}
+
+func (p auditedPlatform) MountAssociatedPersistentDisk(diskSettings boshsettings.DiskSettings, mountPoint string) error {
+	err := p.Platform.MountAssociatedPersistentDisk(diskSettings, mountPoint)
+	p.recorder.RecordSideEffect("associated_disk_mounted", map[string]string{
+		"disk_id":     diskSettings.ID,
+		"mount_point": mountPoint,
+	}, err)
+	return err
+}
+
+func (p auditedPlatform) UnmountPersistentDisk(diskSettings boshsettings.DiskSettings) (bool, error) {
+	didUnmount, err := p.Platform.UnmountPersistentDisk(diskSettings)
+	if didUnmount || err != nil {
+		p.recorder.RecordSideEffect("persistent_disk_unmounted", map[string]string{"disk_id": diskSettings.ID}, err)
+	}
+	return didUnmount, err
+}
+
+func (p auditedPlatform) MigratePersistentDisk(fromMountPoint, toMountPoint string) (boshplatform.DiskMigrationTotals, error) {
+	totals, err := p.Platform.MigratePersistentDisk(fromMountPoint, toMountPoint)
+	p.recorder.RecordSideEffect("persistent_disk_migrated", map[string]string{
+		"from_mount_point": fromMountPoint,
+		"to_mount_point":   toMountPoint,
+	}, err)
+	return totals, err
+}
+
+func (p auditedPlatform) GetCertManager() boshcert.Manager {
+	return auditedCertManager{Manager: p.Platform.GetCertManager(), recorder: p.recorder}
+}
+
+type auditedCertManager struct {
+	boshcert.Manager
+	recorder Recorder
+}
+
+func (m auditedCertManager) UpdateCertificates(certs string) error {
+	err := m.Manager.UpdateCertificates(certs)
+
+	// Fingerprints identify updated certificates without bloating audit log
+	var fingerprints []string
+	if parsed, parseErr := boshcert.ParseCertificates(certs); parseErr == nil {
+		for _, cert := range parsed {
+			fingerprints = append(fingerprints, cert.Fingerprint)
+		}
+	}
+
+	m.recorder.RecordSideEffect("trusted_certs_updated", map[string]string{
+		"fingerprints": strings.Join(fingerprints, ","),
+	}, err)
+
+	return err
+}
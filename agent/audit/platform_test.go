@@ -0,0 +1,111 @@
+package audit_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/audit"
+	fakeaudit "github.com/cloudfoundry/bosh-agent/agent/audit/fakes"
+	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	fakecert "github.com/cloudfoundry/bosh-agent/platform/cert/fakes"
+	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+)
+
+const trustedCert = `-----BEGIN CERTIFICATE-----
+MIIBmDCCAT2gAwIBAgIUHV7zfsuSIWe9qdKgl/GwROlB5FswCgYIKoZIzj0EAwIw
+ITENMAsGA1UECgwEYm9zaDEQMA4GA1UEAwwHZmFrZS1jYTAeFw0yNjEwMTkwNjU2
+MTVaFw0zNjEwMTYwNjU2MTVaMCExDTALBgNVBAoMBGJvc2gxEDAOBgNVBAMMB2Zh
+a2UtY2EwWTATBgcqhkjOPQIBBggqhkjOPQMBBwNCAATDycAHSAx59KnbI8+m6z/f
+6tkezbbPOxEPMZ1Upq1UCne++AYEaJfOjaopdaFO/h8oM6JAO5WYiaUJrYpeNbNx
+o1MwUTAdBgNVHQ4EFgQUBA2pcSpl1SkAaE3FR34vlYAUFqMwHwYDVR0jBBgwFoAU
+BA2pcSpl1SkAaE3FR34vlYAUFqMwDwYDVR0TAQH/BAUwAwEB/zAKBggqhkjOPQQD
+AgNJADBGAiEA5sNiaOkavlXIcUkzmh3g66ZnAIjQ0J+6B/7lSSkgZ8UCIQDxuXdY
+D5e6cymwBfeY1Fm3kS1ivC+SHtIsRtq38CpI1g==
+-----END CERTIFICATE-----`
+
+var _ = Describe("Platform", func() {
+	var (
+		innerPlatform *fakeplatform.FakePlatform
+		recorder      *fakeaudit.FakeRecorder
+		platform      boshplatform.Platform
+	)
+
+	BeforeEach(func() {
+		innerPlatform = fakeplatform.NewFakePlatform()
+		recorder = fakeaudit.NewFakeRecorder()
+		platform = NewPlatform(innerPlatform, recorder)
+	})
+
+	It("delegates operations that are not audited", func() {
+		Expect(platform.GetDirProvider()).To(Equal(innerPlatform.GetDirProvider()))
+		Expect(recorder.SideEffects).To(BeEmpty())
+	})
+
+	It("records user creation", func() {
+		err := platform.CreateUser("fake-user", "fake-password", "/fake-base-path")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(innerPlatform.CreateUserUsername).To(Equal("fake-user"))
+		Expect(recorder.SideEffects).To(Equal([]fakeaudit.SideEffect{
+			{Kind: "user_created", Details: map[string]string{"username": "fake-user"}},
+		}))
+	})
+
+	It("records groups users are added to", func() {
+		err := platform.AddUserToGroups("fake-user", []string{"fake-group-1", "fake-group-2"})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(recorder.SideEffects).To(Equal([]fakeaudit.SideEffect{
+			{Kind: "user_groups_added", Details: map[string]string{"username": "fake-user", "groups": "fake-group-1,fake-group-2"}},
+		}))
+	})
+
+	It("records persistent disk mounts with their errors", func() {
+		innerPlatform.MountPersistentDiskErr = errors.New("fake-mount-err")
+
+		err := platform.MountPersistentDisk(boshsettings.DiskSettings{ID: "fake-disk-id"}, "/fake-mount-point")
+		Expect(err).To(Equal(innerPlatform.MountPersistentDiskErr))
+
+		Expect(recorder.SideEffects).To(Equal([]fakeaudit.SideEffect{
+			{
+				Kind:    "persistent_disk_mounted",
+				Details: map[string]string{"disk_id": "fake-disk-id", "mount_point": "/fake-mount-point"},
+				Err:     innerPlatform.MountPersistentDiskErr,
+			},
+		}))
+	})
+
+	It("records persistent disk unmounts only when disk was unmounted", func() {
+		_, err := platform.UnmountPersistentDisk(boshsettings.DiskSettings{ID: "fake-disk-id"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(recorder.SideEffects).To(BeEmpty())
+
+		innerPlatform.UnmountPersistentDiskDidUnmount = true
+
+		didUnmount, err := platform.UnmountPersistentDisk(boshsettings.DiskSettings{ID: "fake-disk-id"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(didUnmount).To(BeTrue())
+		Expect(recorder.SideEffects).To(Equal([]fakeaudit.SideEffect{
+			{Kind: "persistent_disk_unmounted", Details: map[string]string{"disk_id": "fake-disk-id"}},
+		}))
+	})
+
+	It("records fingerprints of updated trusted certificates", func() {
+		err := platform.GetCertManager().UpdateCertificates(trustedCert)
+		Expect(err).ToNot(HaveOccurred())
+
+		certManager := innerPlatform.GetCertManager().(*fakecert.FakeManager)
+		Expect(certManager.UpdateCertificatesCallCount()).To(Equal(1))
+		Expect(certManager.UpdateCertificatesArgsForCall(0)).To(Equal(trustedCert))
+
+		Expect(recorder.SideEffects).To(Equal([]fakeaudit.SideEffect{
+			{
+				Kind:    "trusted_certs_updated",
+				Details: map[string]string{"fingerprints": "sha256:5c2763e799f5453c4979a5b923ce9a7d25b17090b25aa369a5f2a9765eb7327f"},
+			},
+		}))
+	})
+})
@@ -0,0 +1,66 @@
+package audit
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Outcome string
+
+const (
+	OutcomeSucceeded Outcome = "succeeded"
+	OutcomeFailed    Outcome = "failed"
+)
+
+const (
+	eventTypeAction     = "action"
+	eventTypeSideEffect = "side_effect"
+)
+
+// ActionEvent describes a single dispatched agent action.
+// Arguments are left empty and Redacted is set for actions that are not loggable.
+type ActionEvent struct {
+	Type       string          `json:"type"`
+	Action     string          `json:"action"`
+	Caller     string          `json:"caller"`
+	TaskID     string          `json:"task_id,omitempty"`
+	Arguments  json.RawMessage `json:"arguments,omitempty"`
+	Redacted   bool            `json:"redacted,omitempty"`
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt time.Time       `json:"finished_at"`
+	Outcome    Outcome         `json:"outcome"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// SideEffectEvent describes a privileged change made to the machine,
+// e.g. user creation, disk mounts or trusted certificate updates
+type SideEffectEvent struct {
+	Type    string            `json:"type"`
+	Kind    string            `json:"kind"`
+	Time    time.Time         `json:"time"`
+	Details map[string]string `json:"details,omitempty"`
+	Outcome Outcome           `json:"outcome"`
+	Error   string            `json:"error,omitempty"`
+}
+
+// Recorder keeps an audit trail of agent activity.
+// Recording is best effort so that failing to audit never fails an action.
+type Recorder interface {
+	RecordAction(event ActionEvent)
+	RecordSideEffect(kind string, details map[string]string, err error)
+}
+
+func outcomeOf(err error) (Outcome, string) {
+	if err != nil {
+		return OutcomeFailed, err.Error()
+	}
+
+	return OutcomeSucceeded, ""
+}
+
+// Finished returns a copy of the event completed with the outcome of the action
+func (e ActionEvent) Finished(finishedAt time.Time, err error) ActionEvent {
+	e.FinishedAt = finishedAt
+	e.Outcome, e.Error = outcomeOf(err)
+	return e
+}
@@ -0,0 +1,28 @@
+package fakes
+
+import (
+	boshblob "github.com/cloudfoundry/bosh-utils/blobstore"
+)
+
+type FakeProvider struct {
+	GetStoreTypes []string
+	GetOptions    []map[string]interface{}
+
+	GetBlobstores map[string]boshblob.Blobstore
+	GetErr        error
+}
+
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{GetBlobstores: map[string]boshblob.Blobstore{}}
+}
+
+func (p *FakeProvider) Get(storeType string, options map[string]interface{}) (boshblob.Blobstore, error) {
+	p.GetStoreTypes = append(p.GetStoreTypes, storeType)
+	p.GetOptions = append(p.GetOptions, options)
+
+	if p.GetErr != nil {
+		return nil, p.GetErr
+	}
+
+	return p.GetBlobstores[storeType], nil
+}
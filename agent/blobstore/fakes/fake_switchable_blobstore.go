@@ -0,0 +1,32 @@
+package fakes
+
+import (
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshblob "github.com/cloudfoundry/bosh-utils/blobstore"
+	fakeblob "github.com/cloudfoundry/bosh-utils/blobstore/fakes"
+)
+
+type FakeSwitchableBlobstore struct {
+	*fakeblob.FakeBlobstore
+
+	SwitchSettings []boshsettings.Blobstore
+	SwitchErr      error
+
+	SecretsBlobstore *fakeblob.FakeBlobstore
+}
+
+func NewFakeSwitchableBlobstore() *FakeSwitchableBlobstore {
+	return &FakeSwitchableBlobstore{
+		FakeBlobstore:    fakeblob.NewFakeBlobstore(),
+		SecretsBlobstore: fakeblob.NewFakeBlobstore(),
+	}
+}
+
+func (b *FakeSwitchableBlobstore) Switch(settings boshsettings.Blobstore) error {
+	b.SwitchSettings = append(b.SwitchSettings, settings)
+	return b.SwitchErr
+}
+
+func (b *FakeSwitchableBlobstore) Secrets() boshblob.Blobstore {
+	return b.SecretsBlobstore
+}
@@ -0,0 +1,210 @@
+package blobstore
+
+import (
+	"sync"
+
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshUtilsBlobStore "github.com/cloudfoundry/bosh-utils/blobstore"
+	boshcrypto "github.com/cloudfoundry/bosh-utils/crypto"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const switchableLogTag = "switchableBlobstore"
+
+// Provider returns validated blobstores; satisfied by bosh-utils blobstore provider
+type Provider interface {
+	Get(storeType string, options map[string]interface{}) (boshUtilsBlobStore.Blobstore, error)
+}
+
+type SwitchableBlobstore interface {
+	boshUtilsBlobStore.Blobstore
+
+	// Switch uploads and deletes a probe blob through blobstore described
+	// by settings and only then uses it for all subsequent operations;
+	// current blobstore is kept on error
+	Switch(settings boshsettings.Blobstore) error
+
+	// Secrets returns view of current blobstore which downloads
+	// blobs into temp dir of secrets provider's file system
+	Secrets() boshUtilsBlobStore.Blobstore
+}
+
+type switchableBlobstore struct {
+	provider        Provider
+	secretsProvider Provider
+	blobManager     boshUtilsBlobStore.BlobManagerInterface
+	fs              boshsys.FileSystem
+	logger          boshlog.Logger
+
+	blobstore        boshUtilsBlobStore.Blobstore
+	secretsBlobstore boshUtilsBlobStore.Blobstore
+	blobstoreLock    sync.RWMutex
+}
+
+func NewSwitchableBlobstore(
+	settings boshsettings.Blobstore,
+	provider Provider,
+	secretsProvider Provider,
+	blobManager boshUtilsBlobStore.BlobManagerInterface,
+	fs boshsys.FileSystem,
+	logger boshlog.Logger,
+) (SwitchableBlobstore, error) {
+	b := &switchableBlobstore{
+		provider:        provider,
+		secretsProvider: secretsProvider,
+		blobManager:     blobManager,
+		fs:              fs,
+		logger:          logger,
+	}
+
+	innerBlobstore, secretsBlobstore, err := b.get(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	// Initial blobstore is not probed so that agent
+	// still starts while blobstore is temporarily unavailable
+	b.use(settings, innerBlobstore, secretsBlobstore)
+
+	return b, nil
+}
+
+func (b *switchableBlobstore) Switch(settings boshsettings.Blobstore) error {
+	innerBlobstore, secretsBlobstore, err := b.get(settings)
+	if err != nil {
+		return err
+	}
+
+	err = b.probe(innerBlobstore)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Probing blobstore of type %s", settings.Type)
+	}
+
+	b.use(settings, innerBlobstore, secretsBlobstore)
+
+	return nil
+}
+
+func (b *switchableBlobstore) Secrets() boshUtilsBlobStore.Blobstore {
+	return secretsView{b}
+}
+
+func (b *switchableBlobstore) get(settings boshsettings.Blobstore) (boshUtilsBlobStore.Blobstore, boshUtilsBlobStore.Blobstore, error) {
+	innerBlobstore, err := b.provider.Get(settings.Type, settings.Options)
+	if err != nil {
+		return nil, nil, bosherr.WrapError(err, "Getting blobstore")
+	}
+
+	secretsBlobstore, err := b.secretsProvider.Get(settings.Type, settings.Options)
+	if err != nil {
+		return nil, nil, bosherr.WrapError(err, "Getting secrets blobstore")
+	}
+
+	return innerBlobstore, secretsBlobstore, nil
+}
+
+// probe makes sure that blobstore is reachable and accepts
+// credentials since validating options does not contact it
+func (b *switchableBlobstore) probe(innerBlobstore boshUtilsBlobStore.Blobstore) error {
+	probeFile, err := b.fs.TempFile("blobstore-probe")
+	if err != nil {
+		return bosherr.WrapError(err, "Creating probe file")
+	}
+
+	probePath := probeFile.Name()
+
+	defer func() { _ = b.fs.RemoveAll(probePath) }()
+
+	err = probeFile.Close()
+	if err != nil {
+		return bosherr.WrapError(err, "Closing probe file")
+	}
+
+	err = b.fs.WriteFileString(probePath, "bosh-agent blobstore probe")
+	if err != nil {
+		return bosherr.WrapError(err, "Writing probe file")
+	}
+
+	blobID, err := innerBlobstore.Create(probePath)
+	if err != nil {
+		return bosherr.WrapError(err, "Creating probe blob")
+	}
+
+	err = innerBlobstore.Delete(blobID)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Deleting probe blob %s", blobID)
+	}
+
+	return nil
+}
+
+func (b *switchableBlobstore) use(settings boshsettings.Blobstore, innerBlobstore, secretsBlobstore boshUtilsBlobStore.Blobstore) {
+	b.blobstoreLock.Lock()
+	b.blobstore = NewCascadingBlobstore(innerBlobstore, b.blobManager, b.logger)
+	b.secretsBlobstore = secretsBlobstore
+	b.blobstoreLock.Unlock()
+
+	b.logger.Debug(switchableLogTag, "Switched to blobstore of type %s", settings.Type)
+}
+
+func (b *switchableBlobstore) Get(blobID string, digest boshcrypto.Digest) (string, error) {
+	return b.current().Get(blobID, digest)
+}
+
+func (b *switchableBlobstore) CleanUp(fileName string) error {
+	return b.current().CleanUp(fileName)
+}
+
+func (b *switchableBlobstore) Create(fileName string) (string, error) {
+	return b.current().Create(fileName)
+}
+
+func (b *switchableBlobstore) Validate() error {
+	return b.current().Validate()
+}
+
+func (b *switchableBlobstore) Delete(blobID string) error {
+	return b.current().Delete(blobID)
+}
+
+func (b *switchableBlobstore) current() boshUtilsBlobStore.Blobstore {
+	b.blobstoreLock.RLock()
+	defer b.blobstoreLock.RUnlock()
+
+	return b.blobstore
+}
+
+func (b *switchableBlobstore) currentSecrets() boshUtilsBlobStore.Blobstore {
+	b.blobstoreLock.RLock()
+	defer b.blobstoreLock.RUnlock()
+
+	return b.secretsBlobstore
+}
+
+// secretsView does not cascade to blob manager since
+// blobs it keeps are copied through agent's temp dir
+type secretsView struct {
+	switchable *switchableBlobstore
+}
+
+func (v secretsView) Get(blobID string, digest boshcrypto.Digest) (string, error) {
+	return v.switchable.currentSecrets().Get(blobID, digest)
+}
+
+func (v secretsView) CleanUp(fileName string) error {
+	return v.switchable.currentSecrets().CleanUp(fileName)
+}
+
+func (v secretsView) Create(fileName string) (string, error) {
+	return v.switchable.currentSecrets().Create(fileName)
+}
+
+func (v secretsView) Validate() error {
+	return v.switchable.currentSecrets().Validate()
+}
+
+func (v secretsView) Delete(blobID string) error {
+	return v.switchable.currentSecrets().Delete(blobID)
+}
@@ -0,0 +1,200 @@
+package blobstore_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"errors"
+
+	"github.com/cloudfoundry/bosh-agent/agent/blobstore"
+	fakeagentblob "github.com/cloudfoundry/bosh-agent/agent/blobstore/fakes"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	fakeblob "github.com/cloudfoundry/bosh-utils/blobstore/fakes"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("switchableBlobstore", func() {
+	var (
+		provider            *fakeagentblob.FakeProvider
+		secretsProvider     *fakeagentblob.FakeProvider
+		oldSecretsBlobstore *fakeblob.FakeBlobstore
+		newSecretsBlobstore *fakeblob.FakeBlobstore
+		oldBlobstore        *fakeblob.FakeBlobstore
+		newBlobstore        *fakeblob.FakeBlobstore
+		blobManager         *fakeblob.FakeBlobManagerInterface
+		fs                  *fakesys.FakeFileSystem
+		logger              boshlog.Logger
+		switchableBlobstore blobstore.SwitchableBlobstore
+	)
+
+	BeforeEach(func() {
+		provider = fakeagentblob.NewFakeProvider()
+		oldBlobstore = fakeblob.NewFakeBlobstore()
+		newBlobstore = fakeblob.NewFakeBlobstore()
+		provider.GetBlobstores["old-type"] = oldBlobstore
+		provider.GetBlobstores["new-type"] = newBlobstore
+
+		secretsProvider = fakeagentblob.NewFakeProvider()
+		oldSecretsBlobstore = fakeblob.NewFakeBlobstore()
+		newSecretsBlobstore = fakeblob.NewFakeBlobstore()
+		secretsProvider.GetBlobstores["old-type"] = oldSecretsBlobstore
+		secretsProvider.GetBlobstores["new-type"] = newSecretsBlobstore
+
+		blobManager = &fakeblob.FakeBlobManagerInterface{}
+		fs = fakesys.NewFakeFileSystem()
+		fs.ReturnTempFile = fakesys.NewFakeFile("/fake-tmp/blobstore-probe", fs)
+		logger = boshlog.NewLogger(boshlog.LevelNone)
+
+		var err error
+		switchableBlobstore, err = blobstore.NewSwitchableBlobstore(
+			boshsettings.Blobstore{Type: "old-type", Options: map[string]interface{}{"password": "old"}},
+			provider,
+			secretsProvider,
+			blobManager,
+			fs,
+			logger,
+		)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("uses blobstore from initial settings", func() {
+		_, err := switchableBlobstore.Create("fake-file")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(provider.GetOptions).To(Equal([]map[string]interface{}{{"password": "old"}}))
+		Expect(oldBlobstore.CreateFileNames).To(Equal([]string{"fake-file"}))
+	})
+
+	It("does not probe initial blobstore so that agent starts while it is unavailable", func() {
+		oldBlobstore.CreateErr = errors.New("fake-connection-refused")
+
+		_, err := blobstore.NewSwitchableBlobstore(boshsettings.Blobstore{Type: "old-type"}, provider, secretsProvider, blobManager, fs, logger)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(oldBlobstore.CreateFileNames).To(BeEmpty())
+	})
+
+	It("returns an error when initial blobstore is invalid", func() {
+		provider.GetErr = errors.New("fake-validate-err")
+
+		_, err := blobstore.NewSwitchableBlobstore(boshsettings.Blobstore{Type: "old-type"}, provider, secretsProvider, blobManager, fs, logger)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("fake-validate-err"))
+	})
+
+	Describe("Secrets", func() {
+		It("uses blobstore from secrets provider with same settings", func() {
+			_, err := switchableBlobstore.Secrets().Get("fake-blob-id", nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(secretsProvider.GetOptions).To(Equal([]map[string]interface{}{{"password": "old"}}))
+			Expect(oldSecretsBlobstore.GetBlobIDs).To(Equal([]string{"fake-blob-id"}))
+			Expect(oldBlobstore.GetBlobIDs).To(BeEmpty())
+		})
+
+		It("switches together with blobstore", func() {
+			secrets := switchableBlobstore.Secrets()
+
+			err := switchableBlobstore.Switch(boshsettings.Blobstore{Type: "new-type"})
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = secrets.Get("fake-blob-id", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(newSecretsBlobstore.GetBlobIDs).To(Equal([]string{"fake-blob-id"}))
+		})
+
+		It("keeps current blobstores when secrets blobstore is invalid", func() {
+			secretsProvider.GetErr = errors.New("fake-validate-err")
+
+			err := switchableBlobstore.Switch(boshsettings.Blobstore{Type: "new-type"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-validate-err"))
+
+			_, err = switchableBlobstore.Secrets().Get("fake-blob-id", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(oldSecretsBlobstore.GetBlobIDs).To(Equal([]string{"fake-blob-id"}))
+			Expect(newBlobstore.CreateFileNames).To(BeEmpty())
+		})
+	})
+
+	Describe("Switch", func() {
+		It("uses new blobstore for subsequent operations", func() {
+			err := switchableBlobstore.Switch(boshsettings.Blobstore{Type: "new-type"})
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = switchableBlobstore.Create("fake-file")
+			Expect(err).ToNot(HaveOccurred())
+
+			err = switchableBlobstore.Delete("fake-blob-id")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(newBlobstore.CreateFileNames).To(Equal([]string{"/fake-tmp/blobstore-probe", "fake-file"}))
+			Expect(newBlobstore.DeleteBlobID).To(Equal("fake-blob-id"))
+			Expect(oldBlobstore.CreateFileNames).To(BeEmpty())
+		})
+
+		It("uploads and deletes probe blob before switching", func() {
+			newBlobstore.CreateBlobID = "fake-probe-blob-id"
+			newBlobstore.CreateCallBack = func() {
+				Expect(fs.ReadFileString("/fake-tmp/blobstore-probe")).ToNot(BeEmpty())
+			}
+
+			err := switchableBlobstore.Switch(boshsettings.Blobstore{Type: "new-type"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(newBlobstore.CreateFileNames).To(Equal([]string{"/fake-tmp/blobstore-probe"}))
+			Expect(newBlobstore.DeleteBlobID).To(Equal("fake-probe-blob-id"))
+			Expect(fs.FileExists("/fake-tmp/blobstore-probe")).To(BeFalse())
+		})
+
+		It("keeps using current blobstore when new blobstore is unreachable", func() {
+			newBlobstore.CreateErr = errors.New("fake-connection-refused")
+
+			err := switchableBlobstore.Switch(boshsettings.Blobstore{Type: "new-type"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Probing blobstore of type new-type"))
+			Expect(err.Error()).To(ContainSubstring("fake-connection-refused"))
+			Expect(fs.FileExists("/fake-tmp/blobstore-probe")).To(BeFalse())
+
+			newBlobstore.CreateErr = nil
+
+			_, err = switchableBlobstore.Create("fake-file")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(oldBlobstore.CreateFileNames).To(Equal([]string{"fake-file"}))
+			Expect(newBlobstore.CreateFileNames).To(Equal([]string{"/fake-tmp/blobstore-probe"}))
+		})
+
+		It("keeps using current blobstore when probe blob cannot be deleted", func() {
+			newBlobstore.DeleteErr = errors.New("fake-delete-err")
+
+			err := switchableBlobstore.Switch(boshsettings.Blobstore{Type: "new-type"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-delete-err"))
+
+			_, err = switchableBlobstore.Create("fake-file")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(oldBlobstore.CreateFileNames).To(Equal([]string{"fake-file"}))
+		})
+
+		It("returns an error when probe file cannot be created", func() {
+			fs.TempFileError = errors.New("fake-tempfile-err")
+
+			err := switchableBlobstore.Switch(boshsettings.Blobstore{Type: "new-type"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-tempfile-err"))
+			Expect(newBlobstore.CreateFileNames).To(BeEmpty())
+		})
+
+		It("keeps using current blobstore when new blobstore is invalid", func() {
+			provider.GetErr = errors.New("fake-validate-err")
+
+			err := switchableBlobstore.Switch(boshsettings.Blobstore{Type: "new-type"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-validate-err"))
+
+			_, err = switchableBlobstore.Create("fake-file")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(oldBlobstore.CreateFileNames).To(Equal([]string{"fake-file"}))
+		})
+	})
+})
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"path"
 	"path/filepath"
+	"strings"
 
 	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
@@ -59,9 +60,22 @@ func (boot bootstrap) Run() (err error) {
 	}
 
 	if err = boot.settingsService.LoadSettings(); err != nil {
+		if _, ok := err.(boshsettings.VerificationError); ok {
+			return bosherr.WrapError(err, "Verifying settings")
+		}
+
 		return bosherr.WrapError(err, "Fetching settings")
 	}
 
+	// Credentials rotated through update_settings take
+	// precedence over ones returned by settings source
+	updateSettings, err := boot.loadUpdateSettings()
+	if err != nil {
+		return bosherr.WrapError(err, "Loading update settings")
+	}
+
+	boot.settingsService.ApplyUpdateSettings(updateSettings)
+
 	settings := boot.settingsService.GetSettings()
 	envPublicKeys := settings.Env.GetAuthorizedKeys()
 
@@ -77,6 +91,24 @@ func (boot bootstrap) Run() (err error) {
 		}
 	}
 
+	if err = boot.platform.SetupSSHCertificateAuthority(settings.Env.GetTrustedUserCAKeys(), settings.Env.GetSSHPrincipals()); err != nil {
+		return bosherr.WrapError(err, "Setting up ssh certificate authority")
+	}
+
+	// Keys revoked while certificate authority was not trusted yet
+	if updateSettings.RevokedSSHKeys != nil {
+		if err = boot.platform.UpdateRevokedSSHKeys(*updateSettings.RevokedSSHKeys); err != nil {
+			return bosherr.WrapError(err, "Updating revoked ssh keys")
+		}
+	}
+
+	// Certificates delivered through update_settings take precedence
+	if updateSettings.TrustedCerts == "" && settings.TrustedCerts != "" {
+		if err = boot.platform.GetCertManager().UpdateCertificates(settings.TrustedCerts); err != nil {
+			return bosherr.WrapError(err, "Updating trusted certificates")
+		}
+	}
+
 	if err = boot.setUserPasswords(settings.Env); err != nil {
 		return bosherr.WrapError(err, "Settings user password")
 	}
@@ -131,6 +163,10 @@ func (boot bootstrap) Run() (err error) {
 		return bosherr.WrapError(err, "Setting up blobs dir")
 	}
 
+	if err = boot.thawFrozenPersistentDisk(); err != nil {
+		return bosherr.WrapError(err, "Thawing frozen persistent disk")
+	}
+
 	if err = boot.comparePersistentDisk(); err != nil {
 		return bosherr.WrapError(err, "Comparing persistent disks")
 	}
@@ -155,6 +191,10 @@ func (boot bootstrap) Run() (err error) {
 		}
 	}
 
+	if err = boot.mountAssociatedPersistentDisks(); err != nil {
+		return bosherr.WrapError(err, "Mounting associated persistent disks")
+	}
+
 	if err = boot.platform.SetupMonitUser(); err != nil {
 		return bosherr.WrapError(err, "Setting up monit user")
 	}
@@ -178,36 +218,134 @@ func (boot bootstrap) Run() (err error) {
 	return nil
 }
 
+// thawFrozenPersistentDisk thaws disks left frozen by freeze_disk
+// when agent restarted before they were thawed
+func (boot bootstrap) thawFrozenPersistentDisk() error {
+	frozenDiskPath := filepath.Join(boot.platform.GetDirProvider().BoshDir(), boshplatform.FrozenDiskFileName)
+
+	if !boot.platform.GetFs().FileExists(frozenDiskPath) {
+		return nil
+	}
+
+	contents, err := boot.platform.GetFs().ReadFileString(frozenDiskPath)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Reading %s", boshplatform.FrozenDiskFileName)
+	}
+
+	mountPoints := strings.Fields(contents)
+
+	// Nested filesystems are thawed first
+	for i := len(mountPoints) - 1; i >= 0; i-- {
+		mountPoint := mountPoints[i]
+
+		// Filesystem is no longer frozen or mounted after reboot
+		_, isMountPoint, err := boot.platform.IsMountPoint(mountPoint)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Checking mount point %s", mountPoint)
+		}
+
+		if !isMountPoint {
+			continue
+		}
+
+		err = boot.platform.ThawFilesystem(mountPoint)
+		if err != nil {
+			// Record of filesystems still frozen is kept so that thawing is retried on next agent start
+			writeErr := boot.platform.GetFs().WriteFileString(frozenDiskPath, strings.Join(mountPoints[:i+1], "\n"))
+			if writeErr != nil {
+				return bosherr.WrapErrorf(writeErr, "Writing %s", boshplatform.FrozenDiskFileName)
+			}
+
+			return bosherr.WrapErrorf(err, "Thawing filesystem mounted at %s", mountPoint)
+		}
+	}
+
+	return boot.platform.GetFs().RemoveAll(frozenDiskPath)
+}
+
 func (boot bootstrap) comparePersistentDisk() error {
 	settings := boot.settingsService.GetSettings()
-	updateSettingsPath := filepath.Join(boot.platform.GetDirProvider().BoshDir(), "update_settings.json")
 
 	if err := boot.checkLastMountedCid(settings); err != nil {
 		return err
 	}
 
+	updateSettings, err := boot.loadUpdateSettings()
+	if err != nil {
+		return err
+	}
+
+	for _, diskAssociation := range updateSettings.DiskAssociations {
+		if _, ok := settings.PersistentDiskSettings(diskAssociation.DiskCID); !ok {
+			return fmt.Errorf("Disk %s is not attached", diskAssociation.DiskCID)
+		}
+	}
+
+	if len(settings.Disks.Persistent) > 1 {
+		if len(settings.Disks.Persistent) > len(updateSettings.DiskAssociations) {
+			return errors.New("Unexpected disk attached")
+		}
+	}
+
+	return nil
+}
+
+func (boot bootstrap) loadUpdateSettings() (boshsettings.UpdateSettings, error) {
+	updateSettingsPath := filepath.Join(boot.platform.GetDirProvider().BoshDir(), "update_settings.json")
+
 	var updateSettings boshsettings.UpdateSettings
 
 	if boot.platform.GetFs().FileExists(updateSettingsPath) {
 		contents, err := boot.platform.GetFs().ReadFile(updateSettingsPath)
 		if err != nil {
-			return bosherr.WrapError(err, "Reading update_settings.json")
+			return updateSettings, bosherr.WrapError(err, "Reading update_settings.json")
 		}
 
 		if err = json.Unmarshal(contents, &updateSettings); err != nil {
-			return bosherr.WrapError(err, "Unmarshalling update_settings.json")
+			return updateSettings, bosherr.WrapError(err, "Unmarshalling update_settings.json")
 		}
 	}
 
+	return updateSettings, nil
+}
+
+// mountAssociatedPersistentDisks remounts disks that were associated with
+// their own mount points, each one independently of the primary persistent disk
+func (boot bootstrap) mountAssociatedPersistentDisks() error {
+	settings := boot.settingsService.GetSettings()
+
+	updateSettings, err := boot.loadUpdateSettings()
+	if err != nil {
+		return err
+	}
+
 	for _, diskAssociation := range updateSettings.DiskAssociations {
-		if _, ok := settings.PersistentDiskSettings(diskAssociation.DiskCID); !ok {
-			return fmt.Errorf("Disk %s is not attached", diskAssociation.DiskCID)
+		if !diskAssociation.Mount {
+			continue
 		}
-	}
 
-	if len(settings.Disks.Persistent) > 1 {
-		if len(settings.Disks.Persistent) > len(updateSettings.DiskAssociations) {
-			return errors.New("Unexpected disk attached")
+		diskSettings, found := settings.PersistentDiskSettings(diskAssociation.DiskCID)
+		if !found {
+			continue
+		}
+
+		isPartitioned, err := boot.platform.IsPersistentDiskMountable(diskSettings)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Checking if persistent disk '%s' is partitioned", diskAssociation.Name)
+		}
+
+		if !isPartitioned {
+			continue
+		}
+
+		mountPoint, err := diskAssociation.ResolveMountPoint(boot.dirProvider.StoreDir())
+		if err != nil {
+			return err
+		}
+
+		err = boot.platform.MountAssociatedPersistentDisk(diskSettings, mountPoint)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Mounting persistent disk '%s'", diskAssociation.Name)
 		}
 	}
 
@@ -250,13 +388,13 @@ func (boot bootstrap) checkLastMountedCid(settings boshsettings.Settings) error
 }
 
 func (boot bootstrap) lastMountedCid() (string, error) {
-	managedDiskSettingsPath := filepath.Join(boot.platform.GetDirProvider().BoshDir(), "managed_disk_settings.json")
+	managedDiskSettingsPath := filepath.Join(boot.platform.GetDirProvider().BoshDir(), boshplatform.ManagedDiskSettingsFileName)
 	var lastMountedCid string
 
 	if boot.platform.GetFs().FileExists(managedDiskSettingsPath) {
 		contents, err := boot.platform.GetFs().ReadFile(managedDiskSettingsPath)
 		if err != nil {
-			return "", bosherr.WrapError(err, "Reading managed_disk_settings.json")
+			return "", bosherr.WrapErrorf(err, "Reading %s", boshplatform.ManagedDiskSettingsFileName)
 		}
 		lastMountedCid = string(contents)
 
@@ -0,0 +1,34 @@
+package fakes
+
+import (
+	"sync"
+)
+
+type FakeSettingsRefresher struct {
+	RefreshUnsafeFields []string
+	RefreshErr          error
+	RefreshCallback     func()
+
+	refreshCallCount int
+	refreshLock      sync.Mutex
+}
+
+func (r *FakeSettingsRefresher) Refresh() ([]string, error) {
+	r.refreshLock.Lock()
+	defer r.refreshLock.Unlock()
+
+	r.refreshCallCount++
+
+	if r.RefreshCallback != nil {
+		r.RefreshCallback()
+	}
+
+	return r.RefreshUnsafeFields, r.RefreshErr
+}
+
+func (r *FakeSettingsRefresher) RefreshCallCount() int {
+	r.refreshLock.Lock()
+	defer r.refreshLock.Unlock()
+
+	return r.refreshCallCount
+}
@@ -0,0 +1,130 @@
+package agent
+
+import (
+	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+const settingsRefresherLogTag = "settingsRefresher"
+
+type SettingsRefresher interface {
+	// Refresh applies settings changes that are safe to apply while
+	// agent is running and returns names of fields that were not applied
+	Refresh() ([]string, error)
+}
+
+type settingsRefresher struct {
+	settingsService boshsettings.Service
+	platform        boshplatform.Platform
+	logger          boshlog.Logger
+}
+
+func NewSettingsRefresher(
+	settingsService boshsettings.Service,
+	platform boshplatform.Platform,
+	logger boshlog.Logger,
+) SettingsRefresher {
+	return settingsRefresher{
+		settingsService: settingsService,
+		platform:        platform,
+		logger:          logger,
+	}
+}
+
+func (r settingsRefresher) Refresh() ([]string, error) {
+	fetchedSettings, changedFields, err := r.settingsService.FetchSettingsChanges()
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Fetching settings")
+	}
+
+	if len(changedFields) == 0 {
+		return nil, nil
+	}
+
+	r.logger.Info(settingsRefresherLogTag, "Settings changed: %v", changedFields)
+
+	var appliedFields, unsafeFields []string
+	sshCAApplied := false
+
+	for _, field := range changedFields {
+		var err error
+
+		switch field {
+		case boshsettings.SettingsFieldNtp:
+			err = r.platform.SetTimeWithNtpServers(fetchedSettings.Ntp)
+
+		case boshsettings.SettingsFieldAuthorizedKeys:
+			err = r.setupAuthorizedKeys(fetchedSettings.Env)
+
+		case boshsettings.SettingsFieldTrustedUserCAKeys, boshsettings.SettingsFieldSSHPrincipals:
+			// Both fields are applied at once
+			if !sshCAApplied {
+				err = r.platform.SetupSSHCertificateAuthority(
+					fetchedSettings.Env.GetTrustedUserCAKeys(), fetchedSettings.Env.GetSSHPrincipals())
+				sshCAApplied = err == nil
+			}
+
+		case boshsettings.SettingsFieldDNS:
+			// Rest of network configuration is either unchanged or not applied
+			// since changing addresses of running VM is not safe
+			if changedFieldsContain(changedFields, boshsettings.SettingsFieldNetworks) {
+				unsafeFields = append(unsafeFields, field)
+				continue
+			}
+			err = r.platform.SetupDNS(fetchedSettings.Networks)
+
+		case boshsettings.SettingsFieldTrustedCerts:
+			// Certificates delivered through update_settings take precedence
+			if r.settingsService.GetUpdateSettings().TrustedCerts == "" {
+				err = r.platform.GetCertManager().UpdateCertificates(fetchedSettings.TrustedCerts)
+			}
+
+		default:
+			unsafeFields = append(unsafeFields, field)
+			continue
+		}
+
+		// Failed changes are not recorded so that they are retried on next refresh
+		if err != nil {
+			r.logger.Error(settingsRefresherLogTag, "Failed applying settings change to '%s': %s", field, err.Error())
+			continue
+		}
+
+		appliedFields = append(appliedFields, field)
+	}
+
+	if len(appliedFields) > 0 {
+		err := r.settingsService.ApplySettingsChanges(fetchedSettings, appliedFields)
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Saving applied settings changes")
+		}
+	}
+
+	return unsafeFields, nil
+}
+
+func (r settingsRefresher) setupAuthorizedKeys(env boshsettings.Env) error {
+	publicKeys := append([]string{}, env.GetAuthorizedKeys()...)
+
+	iaasPublicKey, err := r.settingsService.PublicSSHKeyForUsername(boshsettings.VCAPUsername)
+	if err != nil {
+		return bosherr.WrapError(err, "Getting iaas public key")
+	}
+
+	if len(iaasPublicKey) > 0 {
+		publicKeys = append(publicKeys, iaasPublicKey)
+	}
+
+	return r.platform.SetupSSH(publicKeys, boshsettings.VCAPUsername)
+}
+
+func changedFieldsContain(fields []string, field string) bool {
+	for _, f := range fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
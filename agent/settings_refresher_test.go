@@ -0,0 +1,196 @@
+package agent_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent"
+	fakecert "github.com/cloudfoundry/bosh-agent/platform/cert/fakes"
+	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	fakesettings "github.com/cloudfoundry/bosh-agent/settings/fakes"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+var _ = Describe("settingsRefresher", func() {
+	var (
+		settingsService *fakesettings.FakeSettingsService
+		platform        *fakeplatform.FakePlatform
+		refresher       SettingsRefresher
+	)
+
+	BeforeEach(func() {
+		settingsService = &fakesettings.FakeSettingsService{}
+		platform = fakeplatform.NewFakePlatform()
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		refresher = NewSettingsRefresher(settingsService, platform, logger)
+
+		settingsService.FetchedSettings = boshsettings.Settings{
+			Ntp: []string{"fake-ntp-server"},
+			Env: boshsettings.Env{
+				Bosh: boshsettings.BoshEnv{
+					AuthorizedKeys:    []string{"fake-authorized-key"},
+					TrustedUserCAKeys: []string{"fake-ca-key"},
+					SSHPrincipals:     map[string][]string{"bosh_sshers": []string{"fake-principal"}},
+				},
+			},
+			Networks: boshsettings.Networks{
+				"fake-net": boshsettings.Network{IP: "10.0.0.10", DNS: []string{"10.0.0.2"}},
+			},
+			TrustedCerts: "fake-trusted-certs",
+		}
+	})
+
+	It("does nothing when settings did not change", func() {
+		unsafeFields, err := refresher.Refresh()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(unsafeFields).To(BeEmpty())
+		Expect(settingsService.AppliedSettingsChanges).To(BeNil())
+	})
+
+	It("applies changed NTP servers", func() {
+		settingsService.FetchedSettingsChanges = []string{boshsettings.SettingsFieldNtp}
+
+		unsafeFields, err := refresher.Refresh()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(unsafeFields).To(BeEmpty())
+
+		Expect(platform.SetTimeWithNtpServersServers).To(Equal([]string{"fake-ntp-server"}))
+		Expect(settingsService.AppliedSettingsChanges).To(Equal([]string{boshsettings.SettingsFieldNtp}))
+	})
+
+	It("applies changed authorized keys keeping iaas public key", func() {
+		settingsService.FetchedSettingsChanges = []string{boshsettings.SettingsFieldAuthorizedKeys}
+		settingsService.PublicKey = "fake-iaas-key"
+
+		_, err := refresher.Refresh()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(platform.SetupSSHPublicKeys["vcap"]).To(Equal([]string{"fake-authorized-key", "fake-iaas-key"}))
+		Expect(settingsService.AppliedSettingsChanges).To(Equal([]string{boshsettings.SettingsFieldAuthorizedKeys}))
+	})
+
+	It("applies changed ssh certificate authority", func() {
+		settingsService.FetchedSettingsChanges = []string{
+			boshsettings.SettingsFieldTrustedUserCAKeys,
+			boshsettings.SettingsFieldSSHPrincipals,
+		}
+
+		_, err := refresher.Refresh()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(platform.SetupSSHCertificateAuthorityCAKeys).To(Equal([]string{"fake-ca-key"}))
+		Expect(platform.SetupSSHCertificateAuthorityPrincipals).To(Equal(map[string][]string{"bosh_sshers": []string{"fake-principal"}}))
+		Expect(settingsService.AppliedSettingsChanges).To(Equal([]string{
+			boshsettings.SettingsFieldTrustedUserCAKeys,
+			boshsettings.SettingsFieldSSHPrincipals,
+		}))
+	})
+
+	It("applies changed DNS servers when the rest of networks is unchanged", func() {
+		settingsService.FetchedSettingsChanges = []string{boshsettings.SettingsFieldDNS}
+
+		_, err := refresher.Refresh()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(platform.SetupDNSNetworks).To(Equal(settingsService.FetchedSettings.Networks))
+		Expect(settingsService.AppliedSettingsChanges).To(Equal([]string{boshsettings.SettingsFieldDNS}))
+	})
+
+	It("does not reconfigure network interfaces when applying changed DNS servers", func() {
+		settingsService.FetchedSettingsChanges = []string{boshsettings.SettingsFieldDNS}
+
+		_, err := refresher.Refresh()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(platform.SetupNetworkingCalled).To(BeFalse())
+	})
+
+	It("applies changed trusted certs", func() {
+		settingsService.FetchedSettingsChanges = []string{boshsettings.SettingsFieldTrustedCerts}
+
+		_, err := refresher.Refresh()
+		Expect(err).ToNot(HaveOccurred())
+
+		certManager := platform.GetCertManager().(*fakecert.FakeManager)
+		Expect(certManager.UpdateCertificatesCallCount()).To(Equal(1))
+		Expect(certManager.UpdateCertificatesArgsForCall(0)).To(Equal("fake-trusted-certs"))
+		Expect(settingsService.AppliedSettingsChanges).To(Equal([]string{boshsettings.SettingsFieldTrustedCerts}))
+	})
+
+	It("keeps trusted certs delivered through update settings", func() {
+		settingsService.FetchedSettingsChanges = []string{boshsettings.SettingsFieldTrustedCerts}
+		settingsService.ApplyUpdateSettings(boshsettings.UpdateSettings{TrustedCerts: "fake-updated-certs"})
+
+		_, err := refresher.Refresh()
+		Expect(err).ToNot(HaveOccurred())
+
+		certManager := platform.GetCertManager().(*fakecert.FakeManager)
+		Expect(certManager.UpdateCertificatesCallCount()).To(Equal(0))
+		Expect(settingsService.AppliedSettingsChanges).To(Equal([]string{boshsettings.SettingsFieldTrustedCerts}))
+	})
+
+	It("does not record changed DNS servers which failed to apply", func() {
+		settingsService.FetchedSettingsChanges = []string{boshsettings.SettingsFieldDNS}
+		platform.SetupDNSErr = errors.New("fake-setup-dns-error")
+
+		_, err := refresher.Refresh()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(settingsService.AppliedSettingsChanges).To(BeEmpty())
+	})
+
+	It("returns changes which are not safe to apply without applying them", func() {
+		settingsService.FetchedSettingsChanges = []string{
+			boshsettings.SettingsFieldDisks,
+			boshsettings.SettingsFieldNetworks,
+			boshsettings.SettingsFieldDNS,
+			boshsettings.SettingsFieldNtp,
+		}
+
+		unsafeFields, err := refresher.Refresh()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(unsafeFields).To(Equal([]string{
+			boshsettings.SettingsFieldDisks,
+			boshsettings.SettingsFieldNetworks,
+			boshsettings.SettingsFieldDNS,
+		}))
+
+		Expect(platform.SetupDNSNetworks).To(BeNil())
+		Expect(platform.SetupNetworkingCalled).To(BeFalse())
+		Expect(settingsService.AppliedSettingsChanges).To(Equal([]string{boshsettings.SettingsFieldNtp}))
+	})
+
+	It("does not record changes which failed to apply so that they are retried", func() {
+		settingsService.FetchedSettingsChanges = []string{
+			boshsettings.SettingsFieldNtp,
+			boshsettings.SettingsFieldAuthorizedKeys,
+		}
+		platform.SetupSSHErr = errors.New("fake-setup-ssh-error")
+
+		unsafeFields, err := refresher.Refresh()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(unsafeFields).To(BeEmpty())
+
+		Expect(settingsService.AppliedSettingsChanges).To(Equal([]string{boshsettings.SettingsFieldNtp}))
+	})
+
+	It("returns an error if fetching settings fails", func() {
+		settingsService.FetchSettingsErr = errors.New("fake-fetch-error")
+
+		_, err := refresher.Refresh()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("fake-fetch-error"))
+	})
+
+	It("returns an error if saving applied changes fails", func() {
+		settingsService.FetchedSettingsChanges = []string{boshsettings.SettingsFieldNtp}
+		settingsService.ApplySettingsChangesError = errors.New("fake-apply-error")
+
+		_, err := refresher.Refresh()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("fake-apply-error"))
+	})
+})
@@ -0,0 +1,49 @@
+package fakes
+
+import (
+	"sync"
+	"time"
+)
+
+type FakeReaper struct {
+	ExpireAfterUsernames []string
+	ExpireAfterTTLs      []time.Duration
+	ExpireAfterErr       error
+
+	RemoveExpiriesMatchingRegexes []string
+	RemoveExpiriesMatchingErr     error
+
+	ReapExpiredCallCount int
+	ReapExpiredErr       error
+
+	runLock      sync.Mutex
+	runCallCount int
+}
+
+func (r *FakeReaper) ExpireAfter(username string, ttl time.Duration) error {
+	r.ExpireAfterUsernames = append(r.ExpireAfterUsernames, username)
+	r.ExpireAfterTTLs = append(r.ExpireAfterTTLs, ttl)
+	return r.ExpireAfterErr
+}
+
+func (r *FakeReaper) RemoveExpiriesMatching(regex string) error {
+	r.RemoveExpiriesMatchingRegexes = append(r.RemoveExpiriesMatchingRegexes, regex)
+	return r.RemoveExpiriesMatchingErr
+}
+
+func (r *FakeReaper) ReapExpired() error {
+	r.ReapExpiredCallCount++
+	return r.ReapExpiredErr
+}
+
+func (r *FakeReaper) Run(stopCh <-chan struct{}) {
+	r.runLock.Lock()
+	defer r.runLock.Unlock()
+	r.runCallCount++
+}
+
+func (r *FakeReaper) RunCallCount() int {
+	r.runLock.Lock()
+	defer r.runLock.Unlock()
+	return r.runCallCount
+}
@@ -0,0 +1,38 @@
+package fakes
+
+import (
+	boshsshusers "github.com/cloudfoundry/bosh-agent/agent/sshusers"
+)
+
+type FakeRoleStore struct {
+	Roles map[string]boshsshusers.Role
+
+	SaveRoleErr error
+	FindRoleErr error
+
+	RemoveRolesMatchingRegex string
+	RemoveRolesMatchingErr   error
+}
+
+func NewFakeRoleStore() *FakeRoleStore {
+	return &FakeRoleStore{Roles: map[string]boshsshusers.Role{}}
+}
+
+func (s *FakeRoleStore) SaveRole(username string, role boshsshusers.Role) error {
+	if s.SaveRoleErr != nil {
+		return s.SaveRoleErr
+	}
+
+	s.Roles[username] = role
+	return nil
+}
+
+func (s *FakeRoleStore) FindRole(username string) (boshsshusers.Role, bool, error) {
+	role, found := s.Roles[username]
+	return role, found, s.FindRoleErr
+}
+
+func (s *FakeRoleStore) RemoveRolesMatching(regex string) error {
+	s.RemoveRolesMatchingRegex = regex
+	return s.RemoveRolesMatchingErr
+}
@@ -0,0 +1,218 @@
+package sshusers
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pivotal-golang/clock"
+
+	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+const (
+	reaperLogTag   = "sshUsersReaper"
+	reapInterval   = time.Minute
+	expiryFileName = "ssh_user_expiries.json"
+)
+
+// Reaper deletes ephemeral ssh users once their TTL elapses.
+// Expiry times are persisted so that users are reaped after agent restarts.
+type Reaper interface {
+	ExpireAfter(username string, ttl time.Duration) error
+
+	// RemoveExpiriesMatching forgets expiry times of users
+	// that were removed or set up again without a TTL
+	RemoveExpiriesMatching(regex string) error
+
+	ReapExpired() error
+
+	// Run periodically reaps expired users until stopCh is closed
+	Run(stopCh <-chan struct{})
+}
+
+type reaper struct {
+	platform    boshplatform.Platform
+	expiryPath  string
+	userRoles   RoleStore
+	timeService clock.Clock
+	logger      boshlog.Logger
+
+	lock sync.Mutex
+}
+
+func NewReaper(
+	platform boshplatform.Platform,
+	dirProvider boshdirs.Provider,
+	userRoles RoleStore,
+	timeService clock.Clock,
+	logger boshlog.Logger,
+) Reaper {
+	return &reaper{
+		platform:    platform,
+		expiryPath:  filepath.Join(dirProvider.BoshDir(), expiryFileName),
+		userRoles:   userRoles,
+		timeService: timeService,
+		logger:      logger,
+	}
+}
+
+func (r *reaper) ExpireAfter(username string, ttl time.Duration) error {
+	if !strings.HasPrefix(username, boshsettings.EphemeralUserPrefix) {
+		return bosherr.Errorf("Expected user '%s' to start with '%s'", username, boshsettings.EphemeralUserPrefix)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	expiries, err := r.loadExpiries()
+	if err != nil {
+		return err
+	}
+
+	expiries[username] = r.timeService.Now().Add(ttl)
+
+	return r.saveExpiries(expiries)
+}
+
+func (r *reaper) RemoveExpiriesMatching(regex string) error {
+	compiledRegex, err := regexp.Compile(regex)
+	if err != nil {
+		return bosherr.WrapError(err, "Compiling regexp")
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	expiries, err := r.loadExpiries()
+	if err != nil {
+		return err
+	}
+
+	var removed bool
+
+	for username := range expiries {
+		if compiledRegex.MatchString(username) {
+			delete(expiries, username)
+			removed = true
+		}
+	}
+
+	if !removed {
+		return nil
+	}
+
+	return r.saveExpiries(expiries)
+}
+
+func (r *reaper) ReapExpired() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	expiries, err := r.loadExpiries()
+	if err != nil {
+		return err
+	}
+
+	now := r.timeService.Now()
+
+	var expiredUsers []string
+
+	for username, expiresAt := range expiries {
+		if !now.Before(expiresAt) {
+			expiredUsers = append(expiredUsers, regexp.QuoteMeta(username))
+		}
+	}
+
+	if len(expiredUsers) == 0 {
+		return nil
+	}
+
+	sort.Strings(expiredUsers)
+
+	r.logger.Info(reaperLogTag, "Deleting expired ssh users %s", expiredUsers)
+
+	expiredRegex := "^(" + strings.Join(expiredUsers, "|") + ")$"
+
+	err = r.platform.DeleteEphemeralUsersMatching(expiredRegex)
+	if err != nil {
+		return bosherr.WrapError(err, "Deleting expired ssh users")
+	}
+
+	err = r.userRoles.RemoveRolesMatching(expiredRegex)
+	if err != nil {
+		return bosherr.WrapError(err, "Removing roles of expired ssh users")
+	}
+
+	for username, expiresAt := range expiries {
+		if !now.Before(expiresAt) {
+			delete(expiries, username)
+		}
+	}
+
+	return r.saveExpiries(expiries)
+}
+
+func (r *reaper) Run(stopCh <-chan struct{}) {
+	defer r.logger.HandlePanic("SSH Users Reaper")
+
+	ticker := r.timeService.NewTicker(reapInterval)
+	defer ticker.Stop()
+
+	for {
+		err := r.ReapExpired()
+		if err != nil {
+			r.logger.Error(reaperLogTag, "Reaping expired ssh users: %s", err.Error())
+		}
+
+		select {
+		case <-ticker.C():
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+func (r *reaper) loadExpiries() (map[string]time.Time, error) {
+	expiries := map[string]time.Time{}
+
+	fs := r.platform.GetFs()
+
+	if !fs.FileExists(r.expiryPath) {
+		return expiries, nil
+	}
+
+	bytes, err := fs.ReadFile(r.expiryPath)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Reading ssh user expiries")
+	}
+
+	err = json.Unmarshal(bytes, &expiries)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Unmarshalling ssh user expiries")
+	}
+
+	return expiries, nil
+}
+
+func (r *reaper) saveExpiries(expiries map[string]time.Time) error {
+	bytes, err := json.Marshal(expiries)
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling ssh user expiries")
+	}
+
+	err = r.platform.GetFs().WriteFile(r.expiryPath, bytes)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing ssh user expiries")
+	}
+
+	return nil
+}
@@ -0,0 +1,229 @@
+package sshusers_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-golang/clock/fakeclock"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/sshusers"
+	fakesshusers "github.com/cloudfoundry/bosh-agent/agent/sshusers/fakes"
+	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
+	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+var _ = Describe("Reaper", func() {
+	var (
+		platform    *fakeplatform.FakePlatform
+		dirProvider boshdirs.Provider
+		userRoles   *fakesshusers.FakeRoleStore
+		timeService *fakeclock.FakeClock
+		logger      boshlog.Logger
+		reaper      Reaper
+	)
+
+	expiryPath := "/fake-base-dir/bosh/ssh_user_expiries.json"
+
+	BeforeEach(func() {
+		platform = fakeplatform.NewFakePlatform()
+		dirProvider = boshdirs.NewProvider("/fake-base-dir")
+		timeService = fakeclock.NewFakeClock(time.Date(2016, time.March, 1, 12, 0, 0, 0, time.UTC))
+		userRoles = fakesshusers.NewFakeRoleStore()
+		logger = boshlog.NewLogger(boshlog.LevelNone)
+		reaper = NewReaper(platform, dirProvider, userRoles, timeService, logger)
+	})
+
+	Describe("ExpireAfter", func() {
+		It("persists expiry time of the user", func() {
+			err := reaper.ExpireAfter("bosh_foo", time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+
+			contents, err := platform.Fs.ReadFileString(expiryPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(contents).To(MatchJSON(`{"bosh_foo":"2016-03-01T13:00:00Z"}`))
+		})
+
+		It("keeps expiry times of other users", func() {
+			err := reaper.ExpireAfter("bosh_foo", time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = reaper.ExpireAfter("bosh_bar", time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+
+			contents, err := platform.Fs.ReadFileString(expiryPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(contents).To(MatchJSON(`{"bosh_foo":"2016-03-01T13:00:00Z","bosh_bar":"2016-03-01T12:01:00Z"}`))
+		})
+
+		It("returns an error when user is not ephemeral", func() {
+			err := reaper.ExpireAfter("vcap", time.Hour)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Expected user 'vcap' to start with 'bosh_'"))
+			Expect(platform.Fs.FileExists(expiryPath)).To(BeFalse())
+		})
+
+		It("returns an error when writing expiry times fails", func() {
+			platform.Fs.WriteFileError = errors.New("fake-write-err")
+
+			err := reaper.ExpireAfter("bosh_foo", time.Hour)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-write-err"))
+		})
+	})
+
+	Describe("RemoveExpiriesMatching", func() {
+		BeforeEach(func() {
+			Expect(reaper.ExpireAfter("bosh_foo", time.Hour)).To(Succeed())
+			Expect(reaper.ExpireAfter("bosh_bar", time.Minute)).To(Succeed())
+		})
+
+		It("removes expiry times of matching users", func() {
+			err := reaper.RemoveExpiriesMatching("^bosh_f")
+			Expect(err).ToNot(HaveOccurred())
+
+			contents, err := platform.Fs.ReadFileString(expiryPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(contents).To(MatchJSON(`{"bosh_bar":"2016-03-01T12:01:00Z"}`))
+		})
+
+		It("does not reap removed users once their expiry time passes", func() {
+			Expect(reaper.RemoveExpiriesMatching("^bosh_bar$")).To(Succeed())
+
+			timeService.Increment(time.Minute)
+
+			err := reaper.ReapExpired()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(platform.DeleteEphemeralUsersMatchingRegex).To(BeEmpty())
+		})
+
+		It("returns an error when regex is invalid", func() {
+			err := reaper.RemoveExpiriesMatching("(")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Compiling regexp"))
+		})
+
+		It("returns an error when writing expiry times fails", func() {
+			platform.Fs.WriteFileError = errors.New("fake-write-err")
+
+			err := reaper.RemoveExpiriesMatching("^bosh_foo$")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-write-err"))
+		})
+	})
+
+	Describe("ReapExpired", func() {
+		BeforeEach(func() {
+			Expect(reaper.ExpireAfter("bosh_foo", time.Hour)).To(Succeed())
+			Expect(reaper.ExpireAfter("bosh_bar.baz", time.Minute)).To(Succeed())
+			Expect(reaper.ExpireAfter("bosh_qux", time.Minute)).To(Succeed())
+		})
+
+		It("does not delete users which did not expire yet", func() {
+			err := reaper.ReapExpired()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(platform.DeleteEphemeralUsersMatchingRegex).To(BeEmpty())
+		})
+
+		It("deletes expired users and forgets about them", func() {
+			timeService.Increment(time.Minute)
+
+			err := reaper.ReapExpired()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(platform.DeleteEphemeralUsersMatchingRegex).To(Equal(`^(bosh_bar\.baz|bosh_qux)$`))
+			Expect(userRoles.RemoveRolesMatchingRegex).To(Equal(`^(bosh_bar\.baz|bosh_qux)$`))
+
+			contents, err := platform.Fs.ReadFileString(expiryPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(contents).To(MatchJSON(`{"bosh_foo":"2016-03-01T13:00:00Z"}`))
+		})
+
+		It("reaps users recorded before agent restart", func() {
+			timeService.Increment(time.Hour)
+
+			restartedReaper := NewReaper(platform, dirProvider, userRoles, timeService, logger)
+
+			err := restartedReaper.ReapExpired()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(platform.DeleteEphemeralUsersMatchingRegex).To(Equal(`^(bosh_bar\.baz|bosh_foo|bosh_qux)$`))
+		})
+
+		It("keeps expired users to retry later when deleting fails", func() {
+			timeService.Increment(time.Minute)
+			platform.DeleteEphemeralUsersMatchingErr = errors.New("fake-delete-err")
+
+			err := reaper.ReapExpired()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-delete-err"))
+
+			contents, err := platform.Fs.ReadFileString(expiryPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(contents).To(ContainSubstring("bosh_qux"))
+		})
+
+		It("keeps expired users to retry later when removing their roles fails", func() {
+			timeService.Increment(time.Minute)
+			userRoles.RemoveRolesMatchingErr = errors.New("fake-remove-err")
+
+			err := reaper.ReapExpired()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-remove-err"))
+
+			contents, err := platform.Fs.ReadFileString(expiryPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(contents).To(ContainSubstring("bosh_qux"))
+		})
+
+		It("returns an error when expiry times cannot be read", func() {
+			platform.Fs.WriteFileString(expiryPath, "invalid-json")
+
+			err := reaper.ReapExpired()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unmarshalling ssh user expiries"))
+		})
+	})
+
+	Describe("Run", func() {
+		var stopCh chan struct{}
+
+		BeforeEach(func() {
+			stopCh = make(chan struct{})
+		})
+
+		AfterEach(func() {
+			close(stopCh)
+		})
+
+		It("periodically reaps expired users", func() {
+			Expect(reaper.ExpireAfter("bosh_foo", 90*time.Second)).To(Succeed())
+
+			go reaper.Run(stopCh)
+
+			timeService.WaitForWatcherAndIncrement(time.Minute)
+			Consistently(platform.GetDeleteEphemeralUsersMatchingRegex).Should(BeEmpty())
+
+			timeService.Increment(time.Minute)
+			Eventually(platform.GetDeleteEphemeralUsersMatchingRegex).Should(Equal("^(bosh_foo)$"))
+		})
+
+		It("stops reaping once stop channel is closed", func() {
+			doneCh := make(chan struct{})
+			stoppedCh := make(chan struct{})
+
+			go func() {
+				reaper.Run(doneCh)
+				close(stoppedCh)
+			}()
+
+			Eventually(timeService.WatcherCount).Should(Equal(1))
+
+			close(doneCh)
+
+			Eventually(stoppedCh).Should(BeClosed())
+			Expect(timeService.WatcherCount()).To(Equal(0))
+		})
+	})
+})
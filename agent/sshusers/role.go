@@ -0,0 +1,80 @@
+package sshusers
+
+import (
+	"path"
+
+	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+type Role string
+
+const (
+	// RoleReadOnly may inspect process status and read job logs
+	RoleReadOnly Role = "read-only"
+
+	// RoleJobOperator may additionally start, stop and restart jobs
+	RoleJobOperator Role = "job-operator"
+
+	// RoleAdmin has unrestricted sudo access
+	RoleAdmin Role = "admin"
+)
+
+// NewRole returns admin role when name is empty to stay
+// compatible with directors that do not send ssh roles
+func NewRole(name string) (Role, error) {
+	switch role := Role(name); role {
+	case "":
+		return RoleAdmin, nil
+	case RoleReadOnly, RoleJobOperator, RoleAdmin:
+		return role, nil
+	}
+
+	return "", bosherr.Errorf("Unknown ssh role '%s'", name)
+}
+
+func (r Role) Groups() []string {
+	if r == RoleAdmin {
+		return []string{boshsettings.VCAPUsername, boshsettings.AdminGroup, boshsettings.SudoersGroup, boshsettings.SshersGroup}
+	}
+
+	return []string{boshsettings.SshersGroup}
+}
+
+// SudoCommands returns sudoers command specs the role is allowed to run;
+// admin role is not restricted since it belongs to sudoers group
+func (r Role) SudoCommands(dirProvider boshdirs.Provider) []string {
+	if r == RoleAdmin {
+		return nil
+	}
+
+	monit := path.Join(dirProvider.BoshBinDir(), "monit")
+	logViewer := path.Join(dirProvider.BoshBinDir(), boshplatform.LogViewerFileName)
+	logs := path.Join(dirProvider.LogsDir(), "*")
+
+	// Logs are read through log viewer since it refuses to follow
+	// symlinks out of logs dir; wildcards in sudoers also match ' '
+	// so that additional arguments are denied explicitly
+	commands := []string{
+		monit + " summary",
+		logViewer + " " + logs,
+		"!" + logViewer + " * *",
+	}
+
+	// Monit options such as -c or -l would let job names be followed
+	// by arbitrary control or log files read and written as root
+	if r == RoleJobOperator {
+		for _, verb := range []string{"start", "stop", "restart"} {
+			commands = append(
+				commands,
+				monit+" "+verb+" *",
+				"!"+monit+" "+verb+" * *",
+				"!"+monit+" "+verb+" -*",
+			)
+		}
+	}
+
+	return commands
+}
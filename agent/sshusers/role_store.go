@@ -0,0 +1,131 @@
+package sshusers
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const rolesFileName = "ssh_user_roles.json"
+
+// RoleStore remembers roles ssh users were created with
+// so that they can be reported in ssh login alerts
+type RoleStore interface {
+	SaveRole(username string, role Role) error
+	FindRole(username string) (Role, bool, error)
+
+	// RemoveRolesMatching forgets roles of deleted users
+	// so that reused user names do not inherit them
+	RemoveRolesMatching(regex string) error
+}
+
+type roleStore struct {
+	fs        boshsys.FileSystem
+	rolesPath string
+
+	lock sync.Mutex
+}
+
+func NewRoleStore(fs boshsys.FileSystem, dirProvider boshdirs.Provider) RoleStore {
+	return &roleStore{
+		fs:        fs,
+		rolesPath: filepath.Join(dirProvider.BoshDir(), rolesFileName),
+	}
+}
+
+func (s *roleStore) SaveRole(username string, role Role) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	roles, err := s.loadRoles()
+	if err != nil {
+		return err
+	}
+
+	roles[username] = role
+
+	return s.saveRoles(roles)
+}
+
+func (s *roleStore) RemoveRolesMatching(regex string) error {
+	compiledRegex, err := regexp.Compile(regex)
+	if err != nil {
+		return bosherr.WrapError(err, "Compiling regexp")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	roles, err := s.loadRoles()
+	if err != nil {
+		return err
+	}
+
+	var removed bool
+
+	for username := range roles {
+		if compiledRegex.MatchString(username) {
+			delete(roles, username)
+			removed = true
+		}
+	}
+
+	if !removed {
+		return nil
+	}
+
+	return s.saveRoles(roles)
+}
+
+func (s *roleStore) FindRole(username string) (Role, bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	roles, err := s.loadRoles()
+	if err != nil {
+		return "", false, err
+	}
+
+	role, found := roles[username]
+
+	return role, found, nil
+}
+
+func (s *roleStore) loadRoles() (map[string]Role, error) {
+	roles := map[string]Role{}
+
+	if !s.fs.FileExists(s.rolesPath) {
+		return roles, nil
+	}
+
+	bytes, err := s.fs.ReadFile(s.rolesPath)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Reading ssh user roles")
+	}
+
+	err = json.Unmarshal(bytes, &roles)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Unmarshalling ssh user roles")
+	}
+
+	return roles, nil
+}
+
+func (s *roleStore) saveRoles(roles map[string]Role) error {
+	bytes, err := json.Marshal(roles)
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling ssh user roles")
+	}
+
+	err = s.fs.WriteFile(s.rolesPath, bytes)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing ssh user roles")
+	}
+
+	return nil
+}
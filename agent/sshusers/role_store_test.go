@@ -0,0 +1,81 @@
+package sshusers_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/sshusers"
+	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("RoleStore", func() {
+	var (
+		fs        *fakesys.FakeFileSystem
+		roleStore RoleStore
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		roleStore = NewRoleStore(fs, boshdirs.NewProvider("/fake-base-dir"))
+	})
+
+	It("finds saved roles", func() {
+		Expect(roleStore.SaveRole("bosh_foo", RoleReadOnly)).To(Succeed())
+		Expect(roleStore.SaveRole("bosh_bar", RoleAdmin)).To(Succeed())
+
+		role, found, err := roleStore.FindRole("bosh_foo")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeTrue())
+		Expect(role).To(Equal(RoleReadOnly))
+
+		contents, err := fs.ReadFileString("/fake-base-dir/bosh/ssh_user_roles.json")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(contents).To(MatchJSON(`{"bosh_foo":"read-only","bosh_bar":"admin"}`))
+	})
+
+	It("does not find roles of unknown users", func() {
+		_, found, err := roleStore.FindRole("vcap")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeFalse())
+	})
+
+	Describe("RemoveRolesMatching", func() {
+		It("removes roles of matching users only", func() {
+			Expect(roleStore.SaveRole("bosh_foo", RoleReadOnly)).To(Succeed())
+			Expect(roleStore.SaveRole("bosh_bar", RoleAdmin)).To(Succeed())
+
+			Expect(roleStore.RemoveRolesMatching("^bosh_f")).To(Succeed())
+
+			_, found, err := roleStore.FindRole("bosh_foo")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeFalse())
+
+			role, found, err := roleStore.FindRole("bosh_bar")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(role).To(Equal(RoleAdmin))
+		})
+
+		It("does not write roles when nothing matches", func() {
+			Expect(roleStore.RemoveRolesMatching("^bosh_")).To(Succeed())
+			Expect(fs.FileExists("/fake-base-dir/bosh/ssh_user_roles.json")).To(BeFalse())
+		})
+
+		It("returns an error when regex is invalid", func() {
+			err := roleStore.RemoveRolesMatching("(")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Compiling regexp"))
+		})
+	})
+
+	It("returns an error when saving roles fails", func() {
+		fs.WriteFileError = errors.New("fake-write-err")
+
+		err := roleStore.SaveRole("bosh_foo", RoleReadOnly)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("fake-write-err"))
+	})
+})
@@ -0,0 +1,85 @@
+package sshusers_test
+
+import (
+	"regexp"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/sshusers"
+	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
+)
+
+var _ = Describe("Role", func() {
+	dirProvider := boshdirs.NewProvider("/var/vcap")
+
+	Describe("NewRole", func() {
+		It("defaults to admin", func() {
+			Expect(NewRole("")).To(Equal(RoleAdmin))
+		})
+
+		It("returns known roles", func() {
+			Expect(NewRole("read-only")).To(Equal(RoleReadOnly))
+			Expect(NewRole("job-operator")).To(Equal(RoleJobOperator))
+			Expect(NewRole("admin")).To(Equal(RoleAdmin))
+		})
+
+		It("returns an error for unknown roles", func() {
+			_, err := NewRole("root")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Unknown ssh role 'root'"))
+		})
+	})
+
+	It("gives admin unrestricted sudo access", func() {
+		Expect(RoleAdmin.Groups()).To(Equal([]string{"vcap", "admin", "bosh_sudoers", "bosh_sshers"}))
+		Expect(RoleAdmin.SudoCommands(dirProvider)).To(BeEmpty())
+	})
+
+	It("only allows read-only role to inspect jobs", func() {
+		Expect(RoleReadOnly.Groups()).To(Equal([]string{"bosh_sshers"}))
+		Expect(RoleReadOnly.SudoCommands(dirProvider)).To(ContainElement("/var/vcap/bosh/bin/monit summary"))
+		Expect(RoleReadOnly.SudoCommands(dirProvider)).To(ContainElement("/var/vcap/bosh/bin/bosh-view-log /var/vcap/sys/log/*"))
+		Expect(RoleReadOnly.SudoCommands(dirProvider)).ToNot(ContainElement(HavePrefix("/bin/cat")))
+		Expect(RoleReadOnly.SudoCommands(dirProvider)).ToNot(ContainElement("/var/vcap/bosh/bin/monit restart *"))
+	})
+
+	It("allows job-operator role to manage jobs", func() {
+		Expect(RoleJobOperator.Groups()).To(Equal([]string{"bosh_sshers"}))
+		Expect(RoleJobOperator.SudoCommands(dirProvider)).To(ContainElement("/var/vcap/bosh/bin/monit summary"))
+		Expect(RoleJobOperator.SudoCommands(dirProvider)).To(ContainElement("/var/vcap/bosh/bin/monit restart *"))
+	})
+
+	It("rejects additional arguments to commands allowed for job-operator role", func() {
+		commands := RoleJobOperator.SudoCommands(dirProvider)
+
+		Expect(sudoAllows(commands, "/var/vcap/bosh/bin/monit summary")).To(BeTrue())
+		Expect(sudoAllows(commands, "/var/vcap/bosh/bin/monit restart router")).To(BeTrue())
+		Expect(sudoAllows(commands, "/var/vcap/bosh/bin/bosh-view-log /var/vcap/sys/log/router/router.log")).To(BeTrue())
+
+		Expect(sudoAllows(commands, "/var/vcap/bosh/bin/monit start router -c /tmp/evil.monitrc")).To(BeFalse())
+		Expect(sudoAllows(commands, "/var/vcap/bosh/bin/monit stop -l /etc/passwd router")).To(BeFalse())
+		Expect(sudoAllows(commands, "/var/vcap/bosh/bin/monit restart -v")).To(BeFalse())
+		Expect(sudoAllows(commands, "/var/vcap/bosh/bin/monit -c /tmp/evil.monitrc start router")).To(BeFalse())
+		Expect(sudoAllows(commands, "/var/vcap/bosh/bin/bosh-view-log /var/vcap/sys/log/a /etc/shadow")).To(BeFalse())
+	})
+})
+
+// sudoAllows matches command the way sudoers does: wildcards also match
+// spaces and the last matching command spec wins
+func sudoAllows(specs []string, command string) bool {
+	allowed := false
+
+	for _, spec := range specs {
+		negated := strings.HasPrefix(spec, "!")
+		pattern := regexp.QuoteMeta(strings.TrimPrefix(spec, "!"))
+		pattern = strings.Replace(pattern, `\*`, ".*", -1)
+
+		if regexp.MustCompile("^" + pattern + "$").MatchString(command) {
+			allowed = !negated
+		}
+	}
+
+	return allowed
+}
@@ -0,0 +1,13 @@
+package sshusers_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestSSHUsers(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "SSH Users Suite")
+}
@@ -1,7 +1,9 @@
 package app
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"path/filepath"
 	"time"
@@ -15,10 +17,12 @@ import (
 	boshbc "github.com/cloudfoundry/bosh-agent/agent/applier/bundlecollection"
 	boshaj "github.com/cloudfoundry/bosh-agent/agent/applier/jobs"
 	boshap "github.com/cloudfoundry/bosh-agent/agent/applier/packages"
+	boshaudit "github.com/cloudfoundry/bosh-agent/agent/audit"
 	boshagentblobstore "github.com/cloudfoundry/bosh-agent/agent/blobstore"
 	boshrunner "github.com/cloudfoundry/bosh-agent/agent/cmdrunner"
 	boshcomp "github.com/cloudfoundry/bosh-agent/agent/compiler"
 	boshscript "github.com/cloudfoundry/bosh-agent/agent/script"
+	boshsshusers "github.com/cloudfoundry/bosh-agent/agent/sshusers"
 	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
 	boshinf "github.com/cloudfoundry/bosh-agent/infrastructure"
 	boshjobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor"
@@ -41,6 +45,19 @@ import (
 type App interface {
 	Setup(args []string) error
 	Run() error
+
+	// ExplainSettings fetches settings without bootstrapping
+	// and writes attempts made by settings sources to out
+	ExplainSettings(args []string, out io.Writer) error
+
+	// SelfTest checks that commands and settings source chosen by config
+	// are available without bootstrapping and writes report to out
+	SelfTest(args []string, out io.Writer) error
+
+	// DryRunBootstrap runs bootstrap recording changes it would make
+	// to the host instead of making them and writes report to out
+	DryRunBootstrap(args []string, out io.Writer) error
+
 	GetPlatform() boshplatform.Platform
 }
 
@@ -75,33 +92,46 @@ func (app *app) Setup(args []string) error {
 	app.dirProvider = boshdirs.NewProvider(opts.BaseDirectory)
 	app.logStemcellInfo()
 
-	statsCollector := boshsigar.NewSigarStatsCollector(&sigar.ConcreteSigar{})
 	auditLoggerProvider := boshplatform.NewAuditLoggerProvider()
 	auditLogger := boshplatform.NewDelayedAuditLogger(auditLoggerProvider, app.logger)
 
-	state, err := boshplatform.NewBootstrapState(app.fs, filepath.Join(app.dirProvider.BoshDir(), "agent_state.json"))
-	if err != nil {
-		return bosherr.WrapError(err, "Loading state")
-	}
-
 	timeService := clock.NewClock()
-	platformProvider := boshplatform.NewProvider(app.logger, app.dirProvider, statsCollector, app.fs, config.Platform, state, timeService, auditLogger)
 
-	app.platform, err = platformProvider.Get(opts.PlatformName)
+	platform, err := app.buildPlatform(opts, config, app.fs, boshsys.NewExecCmdRunner(app.logger), timeService, auditLogger)
 	if err != nil {
-		return bosherr.WrapError(err, "Getting platform")
+		return err
 	}
 
-	settingsSourceFactory := boshinf.NewSettingsSourceFactory(config.Infrastructure.Settings, app.platform, app.logger)
+	auditRecorder := boshaudit.NewFileRecorder(
+		app.fs,
+		filepath.Join(app.dirProvider.BoshDir(), "log", "audit.log"),
+		config.Audit,
+		auditLogger,
+		timeService,
+		app.logger,
+	)
+
+	// Side effects of bootstrap are audited as well as those of actions
+	app.platform = boshaudit.NewPlatform(platform, auditRecorder)
+
+	sourceDiagnostics := boshinf.NewSourceDiagnostics(timeService, app.logger)
+
+	settingsSourceFactory := boshinf.NewSettingsSourceFactory(config.Infrastructure.Settings, app.platform, sourceDiagnostics, app.logger)
 	settingsSource, err := settingsSourceFactory.New()
 	if err != nil {
 		return bosherr.WrapError(err, "Getting Settings Source")
 	}
 
+	settingsVerifier, err := boshsettings.NewVerifier(config.Infrastructure.Settings.SigningPublicKey)
+	if err != nil {
+		return bosherr.WrapError(err, "Getting Settings Verifier")
+	}
+
 	settingsService := boshsettings.NewService(
 		app.platform.GetFs(),
 		filepath.Join(app.dirProvider.BoshDir(), "settings.json"),
 		settingsSource,
+		settingsVerifier,
 		app.platform,
 		app.logger,
 	)
@@ -117,7 +147,7 @@ func (app *app) Setup(args []string) error {
 		return bosherr.WrapError(err, "Running bootstrap")
 	}
 
-	mbusHandlerProvider := boshmbus.NewHandlerProvider(settingsService, app.logger, auditLogger)
+	mbusHandlerProvider := boshmbus.NewHandlerProvider(settingsService, app.logger, auditLogger, timeService)
 
 	mbusHandler, err := mbusHandlerProvider.Get(app.platform, app.dirProvider)
 	if err != nil {
@@ -179,6 +209,16 @@ func (app *app) Setup(args []string) error {
 		app.logger,
 	)
 
+	sshUserRoles := boshsshusers.NewRoleStore(app.platform.GetFs(), app.dirProvider)
+
+	sshUserReaper := boshsshusers.NewReaper(
+		app.platform,
+		app.dirProvider,
+		sshUserRoles,
+		timeService,
+		app.logger,
+	)
+
 	actionFactory := boshaction.NewFactory(
 		settingsService,
 		app.platform,
@@ -186,11 +226,14 @@ func (app *app) Setup(args []string) error {
 		blobManager,
 		taskService,
 		notifier,
+		mbusHandler,
 		applier,
 		compiler,
 		jobSupervisor,
 		specService,
 		jobScriptProvider,
+		sshUserReaper,
+		sshUserRoles,
 		app.logger,
 	)
 
@@ -202,6 +245,8 @@ func (app *app) Setup(args []string) error {
 		taskManager,
 		actionFactory,
 		actionRunner,
+		auditRecorder,
+		timeService,
 	)
 
 	syslogServer := boshsyslog.NewServer(33331, net.Listen, app.logger)
@@ -218,6 +263,10 @@ func (app *app) Setup(args []string) error {
 		settingsService,
 		uuidGen,
 		timeService,
+		sshUserReaper,
+		sshUserRoles,
+		boshagent.NewSettingsRefresher(settingsService, app.platform, app.logger),
+		time.Duration(config.Infrastructure.Settings.RefreshIntervalInSeconds)*time.Second,
 	)
 
 	return nil
@@ -231,13 +280,74 @@ func (app *app) Run() error {
 	return nil
 }
 
+type settingsExplanation struct {
+	Attempts []boshinf.SourceAttempt `json:"attempts"`
+	Error    string                  `json:"error,omitempty"`
+}
+
+func (app *app) ExplainSettings(args []string, out io.Writer) error {
+	opts, err := ParseOptions(args)
+	if err != nil {
+		return bosherr.WrapError(err, "Parsing options")
+	}
+
+	config, err := app.loadConfig(opts.ConfigPath)
+	if err != nil {
+		return bosherr.WrapError(err, "Loading config")
+	}
+
+	app.dirProvider = boshdirs.NewProvider(opts.BaseDirectory)
+
+	auditLoggerProvider := boshplatform.NewAuditLoggerProvider()
+	auditLogger := boshplatform.NewDelayedAuditLogger(auditLoggerProvider, app.logger)
+
+	timeService := clock.NewClock()
+
+	platform, err := app.buildPlatform(opts, config, app.fs, boshsys.NewExecCmdRunner(app.logger), timeService, auditLogger)
+	if err != nil {
+		return err
+	}
+
+	sourceDiagnostics := boshinf.NewSourceDiagnostics(timeService, app.logger)
+
+	settingsSourceFactory := boshinf.NewSettingsSourceFactory(config.Infrastructure.Settings, platform, sourceDiagnostics, app.logger)
+	settingsSource, err := settingsSourceFactory.New()
+	if err != nil {
+		return bosherr.WrapError(err, "Getting Settings Source")
+	}
+
+	// Settings are neither verified nor saved since nothing is applied
+	_, fetchErr := settingsSource.Settings()
+
+	explanation := settingsExplanation{Attempts: sourceDiagnostics.Attempts()}
+	if fetchErr != nil {
+		explanation.Error = fetchErr.Error()
+	}
+
+	explanationJSON, err := json.MarshalIndent(explanation, "", "  ")
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling settings explanation")
+	}
+
+	_, err = fmt.Fprintln(out, string(explanationJSON))
+	if err != nil {
+		return bosherr.WrapError(err, "Writing settings explanation")
+	}
+
+	if fetchErr != nil {
+		return bosherr.WrapError(fetchErr, "Fetching settings")
+	}
+
+	return nil
+}
+
 func (app *app) GetPlatform() boshplatform.Platform {
 	return app.platform
 }
 
 func (app *app) buildApplierAndCompiler(
 	dirProvider boshdirs.Provider,
-	blobstore boshblob.Blobstore,
+	blobstore boshagentblobstore.SwitchableBlobstore,
 	jobSupervisor boshjobsuper.JobSupervisor,
 ) (boshapplier.Applier, boshcomp.Compiler) {
 	fileSystem := app.platform.GetFs()
@@ -265,7 +375,9 @@ func (app *app) buildApplierAndCompiler(
 		jobsBc,
 		jobSupervisor,
 		packageApplierProvider,
+		app.platform,
 		blobstore,
+		blobstore.Secrets(),
 		app.platform.GetCompressor(),
 		fileSystem,
 		app.logger,
@@ -275,6 +387,7 @@ func (app *app) buildApplierAndCompiler(
 		jobApplier,
 		packageApplierProvider.Root(),
 		app.platform,
+		app.platform,
 		jobSupervisor,
 		dirProvider,
 	)
@@ -299,6 +412,31 @@ func (app *app) buildApplierAndCompiler(
 	return applier, compiler
 }
 
+func (app *app) buildPlatform(
+	opts Options,
+	config Config,
+	fs boshsys.FileSystem,
+	runner boshsys.CmdRunner,
+	timeService clock.Clock,
+	auditLogger boshplatform.AuditLogger,
+) (boshplatform.Platform, error) {
+	statsCollector := boshsigar.NewSigarStatsCollector(&sigar.ConcreteSigar{})
+
+	state, err := boshplatform.NewBootstrapState(fs, filepath.Join(app.dirProvider.BoshDir(), "agent_state.json"))
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Loading state")
+	}
+
+	platformProvider := boshplatform.NewProvider(app.logger, app.dirProvider, statsCollector, fs, runner, config.Platform, state, timeService, auditLogger)
+
+	platform, err := platformProvider.Get(opts.PlatformName)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Getting platform")
+	}
+
+	return platform, nil
+}
+
 func (app *app) loadConfig(path string) (Config, error) {
 	// Use one off copy of file system to read configuration file
 	fs := boshsys.NewOsFileSystem(app.logger)
@@ -321,7 +459,7 @@ func (app *app) fileContents(path string) string {
 	return contents
 }
 
-func (app *app) setupBlobstore(blobstoreSettings boshsettings.Blobstore, blobManager boshblob.BlobManagerInterface) (boshblob.Blobstore, error) {
+func (app *app) setupBlobstore(blobstoreSettings boshsettings.Blobstore, blobManager boshblob.BlobManagerInterface) (boshagentblobstore.SwitchableBlobstore, error) {
 	blobstoreProvider := boshblob.NewProvider(
 		app.platform.GetFs(),
 		app.platform.GetRunner(),
@@ -329,10 +467,28 @@ func (app *app) setupBlobstore(blobstoreSettings boshsettings.Blobstore, blobMan
 		app.logger,
 	)
 
-	blobstore, err := blobstoreProvider.Get(blobstoreSettings.Type, blobstoreSettings.Options)
+	// Secrets are downloaded into memory backed staging dir
+	// so that they never reach agent's temp dir on ephemeral disk
+	secretsFs := boshsys.NewOsFileSystemWithStrictTempRoot(app.logger)
+
+	err := secretsFs.ChangeTempRoot(app.dirProvider.SecretsStagingDir())
 	if err != nil {
-		return nil, bosherr.WrapError(err, "Getting blobstore")
+		return nil, bosherr.WrapError(err, "Setting secrets temp root")
 	}
 
-	return boshagentblobstore.NewCascadingBlobstore(blobstore, blobManager, app.logger), nil
+	secretsBlobstoreProvider := boshblob.NewProvider(
+		secretsFs,
+		app.platform.GetRunner(),
+		app.dirProvider.EtcDir(),
+		app.logger,
+	)
+
+	return boshagentblobstore.NewSwitchableBlobstore(
+		blobstoreSettings,
+		blobstoreProvider,
+		secretsBlobstoreProvider,
+		blobManager,
+		app.platform.GetFs(),
+		app.logger,
+	)
 }
@@ -1,6 +1,8 @@
 package app
 
 import (
+	"bytes"
+	"encoding/json"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -152,6 +154,163 @@ func init() {
 			})
 		})
 
+		Context("when DevicePathResolutionType is 'nvme'", func() {
+			BeforeEach(func() {
+				agentConfJSON = `{
+					"Platform": { "Linux": { "DevicePathResolutionType": "nvme" } },
+					"Infrastructure": { "Settings": { "Sources": [{ "Type": "CDROM", "FileName": "/fake-file-name" }] } }
+				}`
+			})
+
+			It("resolves device paths by NVMe serial before falling back to mapped paths", func() {
+				err := app.Setup([]string{"bosh-agent", "-P", "dummy", "-C", agentConfPath, "-b", baseDir})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(app.GetPlatform().GetDevicePathResolver()).To(
+					BeAssignableToTypeOf(devicepathresolver.NewVirtioDevicePathResolver(nil, nil, nil)))
+			})
+		})
+
+		Describe("ExplainSettings", func() {
+			It("writes attempts of settings sources and returns fetching error", func() {
+				out := bytes.NewBuffer([]byte{})
+
+				err := app.ExplainSettings([]string{"bosh-agent", "-P", "dummy", "-C", agentConfPath, "-b", baseDir, "-explain-settings"}, out)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Fetching settings"))
+
+				var explanation struct {
+					Attempts []map[string]interface{} `json:"attempts"`
+					Error    string                   `json:"error"`
+				}
+
+				err = json.Unmarshal(out.Bytes(), &explanation)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(explanation.Error).ToNot(BeEmpty())
+				Expect(explanation.Attempts).ToNot(BeEmpty())
+				Expect(explanation.Attempts[0]["source"]).To(Equal("CDROM"))
+				Expect(explanation.Attempts[0]["endpoint"]).To(Equal("/fake-file-name"))
+				Expect(explanation.Attempts[0]["error"]).ToNot(BeEmpty())
+			})
+		})
+
+		Describe("SelfTest", func() {
+			type selfTestReport struct {
+				Passed bool `json:"passed"`
+				Checks []struct {
+					Component string `json:"component"`
+					Name      string `json:"name"`
+					Passed    bool   `json:"passed"`
+					Error     string `json:"error"`
+				} `json:"checks"`
+			}
+
+			selfTest := func() (selfTestReport, error) {
+				out := bytes.NewBuffer([]byte{})
+
+				err := app.SelfTest([]string{"bosh-agent", "-P", "dummy", "-C", agentConfPath, "-b", baseDir, "-self-test"}, out)
+
+				var report selfTestReport
+				Expect(json.Unmarshal(out.Bytes(), &report)).To(Succeed())
+
+				return report, err
+			}
+
+			It("reports passing options and settings source construction without fetching settings", func() {
+				report, err := selfTest()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(report.Passed).To(BeTrue())
+				Expect(report.Checks).To(HaveLen(2))
+				Expect(report.Checks[0].Component).To(Equal("platform"))
+				Expect(report.Checks[0].Name).To(Equal("options"))
+				Expect(report.Checks[1].Component).To(Equal("settings_source"))
+				Expect(report.Checks[1].Name).To(Equal("construction"))
+				Expect(report.Checks[1].Passed).To(BeTrue())
+			})
+
+			Context("when registry host is resolved with dig", func() {
+				BeforeEach(func() {
+					agentConfJSON = `{
+						"Infrastructure": { "Settings": {
+							"UseRegistry": true,
+							"Sources": [{ "Type": "HTTP", "URI": "http://fake-metadata-host" }]
+						} }
+					}`
+				})
+
+				It("checks dig command", func() {
+					report, _ := selfTest()
+
+					var componentNames []string
+					for _, check := range report.Checks {
+						componentNames = append(componentNames, check.Component+":"+check.Name)
+					}
+					Expect(componentNames).To(ContainElement("settings_source:dig"))
+				})
+			})
+
+			Context("when platform options are invalid", func() {
+				BeforeEach(func() {
+					agentConfJSON = `{
+						"Platform": { "Linux": { "PartitionerType": "fake-partitioner" } },
+						"Infrastructure": { "Settings": { "Sources": [{ "Type": "CDROM", "FileName": "/fake-file-name" }] } }
+					}`
+				})
+
+				It("reports failed options check and returns error", func() {
+					out := bytes.NewBuffer([]byte{})
+
+					err := app.SelfTest([]string{"bosh-agent", "-P", "ubuntu", "-C", agentConfPath, "-b", baseDir, "-self-test"}, out)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(Equal("1 self-test check(s) failed"))
+
+					var report selfTestReport
+					Expect(json.Unmarshal(out.Bytes(), &report)).To(Succeed())
+					Expect(report.Passed).To(BeFalse())
+					Expect(report.Checks).To(HaveLen(1))
+					Expect(report.Checks[0].Name).To(Equal("options"))
+					Expect(report.Checks[0].Error).To(ContainSubstring("Unknown partitioner type 'fake-partitioner'"))
+				})
+			})
+		})
+
+		Describe("DryRunBootstrap", func() {
+			listFiles := func() []string {
+				files := []string{}
+				err := filepath.Walk(baseDir, func(path string, info os.FileInfo, err error) error {
+					files = append(files, path)
+					return err
+				})
+				Expect(err).ToNot(HaveOccurred())
+				return files
+			}
+
+			It("writes changes bootstrap would make without making them", func() {
+				filesBefore := listFiles()
+				out := bytes.NewBuffer([]byte{})
+
+				err := app.DryRunBootstrap([]string{"bosh-agent", "-P", "dummy", "-C", agentConfPath, "-b", baseDir, "-dry-run-bootstrap"}, out)
+				Expect(err).ToNot(HaveOccurred())
+
+				var report struct {
+					Changes []map[string]string `json:"changes"`
+					Error   string              `json:"error"`
+				}
+
+				Expect(json.Unmarshal(out.Bytes(), &report)).To(Succeed())
+				Expect(report.Error).To(BeEmpty())
+				Expect(report.Changes).ToNot(BeEmpty())
+
+				for _, change := range report.Changes {
+					if change["path"] == filepath.Join(baseDir, "bosh", "settings.json") {
+						Expect(change["diff"]).To(Equal("<redacted>"))
+					}
+				}
+
+				Expect(listFiles()).To(Equal(filesBefore))
+			})
+		})
+
 		Context("logging stemcell version and git sha", func() {
 			var (
 				logger                  *loggerfakes.FakeLogger
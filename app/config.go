@@ -3,6 +3,7 @@ package app
 import (
 	"encoding/json"
 
+	boshaudit "github.com/cloudfoundry/bosh-agent/agent/audit"
 	boshinf "github.com/cloudfoundry/bosh-agent/infrastructure"
 	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
@@ -12,6 +13,7 @@ import (
 type Config struct {
 	Platform       boshplatform.Options
 	Infrastructure boshinf.Options
+	Audit          boshaudit.Options
 }
 
 func LoadConfigFromPath(fs boshsys.FileSystem, path string) (Config, error) {
@@ -4,6 +4,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	boshaudit "github.com/cloudfoundry/bosh-agent/agent/audit"
 	boshinf "github.com/cloudfoundry/bosh-agent/infrastructure"
 	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
 	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
@@ -34,7 +35,14 @@ var _ = Describe("LoadConfigFromPath", func() {
 				  "Sources": [
 				  	{
 					  	"Type": "HTTP",
-					  	"URI": "http://fake-uri"
+					  	"URI": "http://fake-uri",
+					  	"Token": {
+					  		"Path": "/fake-token-path",
+					  		"TTLHeader": "fake-ttl-header",
+					  		"TTLInSeconds": 60,
+					  		"Header": "fake-token-header",
+					  		"Optional": true
+					  	}
 					  },
 					  {
 					  	"Type": "ConfigDrive",
@@ -58,11 +66,34 @@ var _ = Describe("LoadConfigFromPath", func() {
 						"URI": "/fake-uri",
 						"Headers": {"fake": "headers"},
 						"SettingsPath": "/fake-settings-path"
+					  },
+					  {
+						"Type": "NoCloud",
+						"DiskPaths": ["/fake-disk-path"],
+						"SeedDirs": ["/fake-seed-dir"]
 					  }
 				  ],
 				  "UseServerName": true,
-				  "UseRegistry": true
+				  "UseRegistry": true,
+				  "SigningPublicKey": "fake-public-key",
+				  "RefreshIntervalInSeconds": 300,
+				  "Registry": {
+					"CACertificate": "fake-ca-certificate",
+					"ClientCertificate": "fake-client-certificate",
+					"ClientPrivateKey": "fake-client-private-key",
+					"Token": {
+						"URI": "http://fake-token-uri",
+						"Headers": {"fake": "headers"},
+						"Path": "/fake-registry-token-path"
+					},
+					"UseNativeDNSResolver": true
+				  }
 				}
+			},
+			"Audit": {
+				"MaxFileSizeInBytes": 1048576,
+				"MaxBackups": 3,
+				"Syslog": true
 			}
 		}`)
 
@@ -83,6 +114,13 @@ var _ = Describe("LoadConfigFromPath", func() {
 					Sources: []boshinf.SourceOptions{
 						boshinf.HTTPSourceOptions{
 							URI: "http://fake-uri",
+							Token: &boshinf.TokenOptions{
+								Path:         "/fake-token-path",
+								TTLHeader:    "fake-ttl-header",
+								TTLInSeconds: 60,
+								Header:       "fake-token-header",
+								Optional:     true,
+							},
 						},
 						boshinf.ConfigDriveSourceOptions{
 							DiskPaths:    []string{"/fake-disk-path1", "/fake-disk-path2"},
@@ -103,11 +141,33 @@ var _ = Describe("LoadConfigFromPath", func() {
 							Headers:      map[string]string{"fake": "headers"},
 							SettingsPath: "/fake-settings-path",
 						},
+						boshinf.NoCloudSourceOptions{
+							DiskPaths: []string{"/fake-disk-path"},
+							SeedDirs:  []string{"/fake-seed-dir"},
+						},
+					},
+					UseServerName:            true,
+					UseRegistry:              true,
+					SigningPublicKey:         "fake-public-key",
+					RefreshIntervalInSeconds: 300,
+					Registry: boshinf.RegistryOptions{
+						CACertificate:     "fake-ca-certificate",
+						ClientCertificate: "fake-client-certificate",
+						ClientPrivateKey:  "fake-client-private-key",
+						Token: &boshinf.RegistryTokenOptions{
+							URI:     "http://fake-token-uri",
+							Headers: map[string]string{"fake": "headers"},
+							Path:    "/fake-registry-token-path",
+						},
+						UseNativeDNSResolver: true,
 					},
-					UseServerName: true,
-					UseRegistry:   true,
 				},
 			},
+			Audit: boshaudit.Options{
+				MaxFileSizeInBytes: 1048576,
+				MaxBackups:         3,
+				Syslog:             true,
+			},
 		}))
 	})
 
@@ -0,0 +1,118 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/pivotal-golang/clock"
+
+	boshagent "github.com/cloudfoundry/bosh-agent/agent"
+	boshinf "github.com/cloudfoundry/bosh-agent/infrastructure"
+	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	boshdryrun "github.com/cloudfoundry/bosh-agent/platform/dryrun"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type dryRunReport struct {
+	Changes []boshdryrun.Change `json:"changes"`
+	Error   string              `json:"error,omitempty"`
+}
+
+func (app *app) DryRunBootstrap(args []string, out io.Writer) error {
+	opts, err := ParseOptions(args)
+	if err != nil {
+		return bosherr.WrapError(err, "Parsing options")
+	}
+
+	config, err := app.loadConfig(opts.ConfigPath)
+	if err != nil {
+		return bosherr.WrapError(err, "Loading config")
+	}
+
+	app.dirProvider = boshdirs.NewProvider(opts.BaseDirectory)
+
+	settingsPath := filepath.Join(app.dirProvider.BoshDir(), "settings.json")
+
+	recorder := boshdryrun.NewRecorder()
+
+	// Settings contain credentials so their contents are not included in report
+	fs := boshdryrun.NewFileSystem(
+		app.fs,
+		recorder,
+		[]string{settingsPath, filepath.Join(app.dirProvider.BoshDir(), "update_settings.json")},
+		app.logger,
+	)
+	runner := boshdryrun.NewCmdRunner(boshsys.NewExecCmdRunner(app.logger), recorder, app.logger)
+
+	auditLoggerProvider := boshplatform.NewAuditLoggerProvider()
+	auditLogger := boshplatform.NewDelayedAuditLogger(auditLoggerProvider, app.logger)
+
+	timeService := clock.NewClock()
+
+	// Platform is not audited since recorded changes are never made
+	platform, err := app.buildPlatform(opts, config, fs, runner, timeService, auditLogger)
+	if err != nil {
+		return err
+	}
+
+	app.platform = platform
+
+	sourceDiagnostics := boshinf.NewSourceDiagnostics(timeService, app.logger)
+
+	settingsSourceFactory := boshinf.NewSettingsSourceFactory(config.Infrastructure.Settings, platform, sourceDiagnostics, app.logger)
+	settingsSource, err := settingsSourceFactory.New()
+	if err != nil {
+		return bosherr.WrapError(err, "Getting Settings Source")
+	}
+
+	settingsVerifier, err := boshsettings.NewVerifier(config.Infrastructure.Settings.SigningPublicKey)
+	if err != nil {
+		return bosherr.WrapError(err, "Getting Settings Verifier")
+	}
+
+	// Sources which mount devices (e.g. CDROM) cannot fetch settings in dry run
+	// so that settings cached by previous bootstrap are used instead
+	settingsService := boshsettings.NewService(
+		fs,
+		settingsPath,
+		settingsSource,
+		settingsVerifier,
+		platform,
+		app.logger,
+	)
+
+	boot := boshagent.NewBootstrap(
+		platform,
+		app.dirProvider,
+		settingsService,
+		app.logger,
+	)
+
+	bootErr := boot.Run()
+
+	report := dryRunReport{Changes: recorder.Changes()}
+	if bootErr != nil {
+		report.Error = bootErr.Error()
+	}
+
+	reportJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling dry run report")
+	}
+
+	_, err = fmt.Fprintln(out, string(reportJSON))
+	if err != nil {
+		return bosherr.WrapError(err, "Writing dry run report")
+	}
+
+	if bootErr != nil {
+		return bosherr.WrapError(bootErr, "Running bootstrap")
+	}
+
+	return nil
+}
@@ -11,6 +11,15 @@ type Options struct {
 	BaseDirectory      string
 	JobSupervisor      string
 	ConfigPath         string
+
+	// Fetch settings and print attempts of settings sources instead of running
+	ExplainSettings bool
+
+	// Check external commands and settings source construction instead of running
+	SelfTest bool
+
+	// Run bootstrap and print changes it would make instead of making them
+	DryRunBootstrap bool
 }
 
 func ParseOptions(args []string) (Options, error) {
@@ -24,6 +33,9 @@ func ParseOptions(args []string) (Options, error) {
 	flagSet.StringVar(&opts.ConfigPath, "C", "", "Config path")
 	flagSet.StringVar(&opts.JobSupervisor, "M", "monit", "Set jobsupervisor")
 	flagSet.StringVar(&opts.BaseDirectory, "b", "/var/vcap", "Set Base Directory")
+	flagSet.BoolVar(&opts.ExplainSettings, "explain-settings", false, "Print attempts of settings sources and exit")
+	flagSet.BoolVar(&opts.SelfTest, "self-test", false, "Check commands and settings source required by config and exit")
+	flagSet.BoolVar(&opts.DryRunBootstrap, "dry-run-bootstrap", false, "Print changes bootstrap would make to the host and exit")
 
 	// The following two options are accepted but ignored for compatibility with the old agent
 	var systemRoot string
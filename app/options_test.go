@@ -25,4 +25,33 @@ var _ = Describe("ParseOptions", func() {
 		Expect(err).ToNot(HaveOccurred())
 		Expect(opts.ConfigPath).To(Equal(""))
 	})
+	It("parses explain settings mode", func() {
+		opts, err := ParseOptions([]string{"bosh-agent", "-explain-settings"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(opts.ExplainSettings).To(BeTrue())
+
+		opts, err = ParseOptions([]string{"bosh-agent"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(opts.ExplainSettings).To(BeFalse())
+	})
+
+	It("parses self-test mode", func() {
+		opts, err := ParseOptions([]string{"bosh-agent", "-self-test"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(opts.SelfTest).To(BeTrue())
+
+		opts, err = ParseOptions([]string{"bosh-agent"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(opts.SelfTest).To(BeFalse())
+	})
+
+	It("parses dry-run-bootstrap mode", func() {
+		opts, err := ParseOptions([]string{"bosh-agent", "-dry-run-bootstrap"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(opts.DryRunBootstrap).To(BeTrue())
+
+		opts, err = ParseOptions([]string{"bosh-agent"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(opts.DryRunBootstrap).To(BeFalse())
+	})
 })
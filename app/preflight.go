@@ -0,0 +1,159 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+
+	"github.com/pivotal-golang/clock"
+
+	boshinf "github.com/cloudfoundry/bosh-agent/infrastructure"
+	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type selfTestCheck struct {
+	Component string `json:"component"`
+	Name      string `json:"name"`
+	Passed    bool   `json:"passed"`
+
+	// e.g. path command was found at
+	Detail string `json:"detail,omitempty"`
+	Error  string `json:"error,omitempty"`
+
+	// Missing optional commands pass with a warning
+	Warning string `json:"warning,omitempty"`
+}
+
+type selfTestReport struct {
+	Passed bool            `json:"passed"`
+	Checks []selfTestCheck `json:"checks"`
+}
+
+func (r *selfTestReport) add(check selfTestCheck) {
+	r.Checks = append(r.Checks, check)
+}
+
+func (r *selfTestReport) addCommands(component string, commands []string) {
+	for _, command := range commands {
+		check := selfTestCheck{Component: component, Name: command}
+
+		path, err := exec.LookPath(command)
+		if err != nil {
+			check.Error = err.Error()
+		} else {
+			check.Passed = true
+			check.Detail = path
+		}
+
+		r.add(check)
+	}
+}
+
+func (r *selfTestReport) addOptionalCommands(component string, commands []string) {
+	for _, command := range commands {
+		check := selfTestCheck{Component: component, Name: command, Passed: true}
+
+		path, err := exec.LookPath(command)
+		if err != nil {
+			check.Warning = err.Error()
+		} else {
+			check.Detail = path
+		}
+
+		r.add(check)
+	}
+}
+
+func (r *selfTestReport) failedCount() int {
+	var count int
+	for _, check := range r.Checks {
+		if !check.Passed {
+			count++
+		}
+	}
+	return count
+}
+
+func (app *app) SelfTest(args []string, out io.Writer) error {
+	opts, err := ParseOptions(args)
+	if err != nil {
+		return bosherr.WrapError(err, "Parsing options")
+	}
+
+	config, err := app.loadConfig(opts.ConfigPath)
+	if err != nil {
+		return bosherr.WrapError(err, "Loading config")
+	}
+
+	app.dirProvider = boshdirs.NewProvider(opts.BaseDirectory)
+
+	report := selfTestReport{Checks: []selfTestCheck{}}
+
+	componentCommands, err := boshplatform.RequiredCommands(opts.PlatformName, config.Platform)
+	if err != nil {
+		report.add(selfTestCheck{Component: "platform", Name: "options", Error: err.Error()})
+	} else {
+		report.add(selfTestCheck{Component: "platform", Name: "options", Passed: true})
+
+		for _, cc := range componentCommands {
+			report.addCommands(cc.Component, cc.Commands)
+			report.addOptionalCommands(cc.Component, cc.OptionalCommands)
+		}
+
+		// Platform is only built with valid options since unknown ones cause panics
+		report.add(app.selfTestSettingsSource(opts, config, &report))
+	}
+
+	report.Passed = report.failedCount() == 0
+
+	reportJSON, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling self-test report")
+	}
+
+	_, err = fmt.Fprintln(out, string(reportJSON))
+	if err != nil {
+		return bosherr.WrapError(err, "Writing self-test report")
+	}
+
+	if !report.Passed {
+		return bosherr.Errorf("%d self-test check(s) failed", report.failedCount())
+	}
+
+	return nil
+}
+
+// selfTestSettingsSource constructs settings source without fetching settings
+func (app *app) selfTestSettingsSource(opts Options, config Config, report *selfTestReport) selfTestCheck {
+	check := selfTestCheck{Component: "settings_source", Name: "construction"}
+
+	auditLoggerProvider := boshplatform.NewAuditLoggerProvider()
+	auditLogger := boshplatform.NewDelayedAuditLogger(auditLoggerProvider, app.logger)
+
+	timeService := clock.NewClock()
+
+	platform, err := app.buildPlatform(opts, config, app.fs, boshsys.NewExecCmdRunner(app.logger), timeService, auditLogger)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+
+	sourceDiagnostics := boshinf.NewSourceDiagnostics(timeService, app.logger)
+
+	settingsSourceFactory := boshinf.NewSettingsSourceFactory(config.Infrastructure.Settings, platform, sourceDiagnostics, app.logger)
+
+	report.addCommands("settings_source", settingsSourceFactory.RequiredCommands())
+
+	_, err = settingsSourceFactory.New()
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+
+	check.Passed = true
+	return check
+}
@@ -10,4 +10,8 @@ type Handler interface {
 	RegisterAdditionalFunc(handlerFunc Func)
 
 	Send(target Target, topic Topic, message interface{}) error
+
+	// SwitchURL moves handler over to another mbus URL,
+	// e.g. after its credentials were rotated
+	SwitchURL(mbusURL string) error
 }
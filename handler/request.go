@@ -12,6 +12,10 @@ type Request struct {
 	ReplyTo string `json:"reply_to"`
 	Method  string
 	Payload []byte
+
+	// Caller identifies who sent the request when transport
+	// authenticates it; defaults to reply-to subject otherwise
+	Caller string `json:"-"`
 }
 
 func (r Request) GetPayload() []byte {
@@ -31,6 +31,10 @@ func (s ComplexSettingsSource) PublicSSHKeyForUsername(string) (string, error) {
 	return s.metadataService.GetPublicKey()
 }
 
+func (s ComplexSettingsSource) InstanceID() (string, error) {
+	return s.metadataService.GetInstanceID()
+}
+
 func (s ComplexSettingsSource) Settings() (boshsettings.Settings, error) {
 	registry, err := s.registryProvider.GetRegistry()
 	if err != nil {
@@ -44,6 +44,16 @@ var _ = Describe("ComplexSettingsSource", func() {
 		})
 	})
 
+	Describe("InstanceID", func() {
+		It("returns instance ID from metadata service", func() {
+			metadataService.InstanceID = "fake-instance-id"
+
+			instanceID, err := source.InstanceID()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instanceID).To(Equal("fake-instance-id"))
+		})
+	})
+
 	Describe("Settings", func() {
 		It("returns settings read from the registry", func() {
 			registryProvider.GetRegistryRegistry = &fakeinf.FakeRegistry{
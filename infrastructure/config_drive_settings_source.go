@@ -11,7 +11,7 @@ import (
 
 type MetadataContentsType struct {
 	PublicKeys map[string]PublicKeyType `json:"public-keys"`
-	InstanceID string                   `json:"instance-id"` // todo remove
+	InstanceID string                   `json:"instance-id"`
 }
 
 type PublicKeyType map[string]string
@@ -47,17 +47,11 @@ func NewConfigDriveSettingsSource(
 }
 
 func (s *ConfigDriveSettingsSource) PublicSSHKeyForUsername(string) (string, error) {
-	metadataContent, err := s.loadFileFromConfigDrive(s.metadataPath)
+	metadata, err := s.loadMetadata()
 	if err != nil {
 		return "", err
 	}
 
-	var metadata MetadataContentsType
-	err = json.Unmarshal(metadataContent, &metadata)
-	if err != nil {
-		return "", bosherr.WrapErrorf(err, "Parsing config drive metadata from '%s'", s.metadataPath)
-	}
-
 	if firstPublicKey, ok := metadata.PublicKeys["0"]; ok {
 		if openSSHKey, ok := firstPublicKey["openssh-key"]; ok {
 			return openSSHKey, nil
@@ -67,6 +61,31 @@ func (s *ConfigDriveSettingsSource) PublicSSHKeyForUsername(string) (string, err
 	return "", nil
 }
 
+func (s *ConfigDriveSettingsSource) InstanceID() (string, error) {
+	metadata, err := s.loadMetadata()
+	if err != nil {
+		return "", err
+	}
+
+	return metadata.InstanceID, nil
+}
+
+func (s *ConfigDriveSettingsSource) loadMetadata() (MetadataContentsType, error) {
+	var metadata MetadataContentsType
+
+	metadataContent, err := s.loadFileFromConfigDrive(s.metadataPath)
+	if err != nil {
+		return metadata, err
+	}
+
+	err = json.Unmarshal(metadataContent, &metadata)
+	if err != nil {
+		return metadata, bosherr.WrapErrorf(err, "Parsing config drive metadata from '%s'", s.metadataPath)
+	}
+
+	return metadata, nil
+}
+
 func (s *ConfigDriveSettingsSource) Settings() (boshsettings.Settings, error) {
 	settingsContent, err := s.loadFileFromConfigDrive(s.settingsPath)
 	if err != nil {
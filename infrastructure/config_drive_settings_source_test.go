@@ -84,6 +84,25 @@ var _ = Describe("ConfigDriveSettingsSource", func() {
 		})
 	})
 
+	Describe("InstanceID", func() {
+		It("returns instance ID from metadata", func() {
+			platform.SetGetFilesContentsFromDisk(
+				"/fake-disk-path-1/fake-metadata-path", []byte(`{"instance-id":"fake-instance-id"}`), nil)
+
+			instanceID, err := source.InstanceID()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instanceID).To(Equal("fake-instance-id"))
+		})
+
+		It("returns an error if metadata cannot be parsed", func() {
+			platform.SetGetFilesContentsFromDisk("/fake-disk-path-1/fake-metadata-path", []byte(`invalid-json`), nil)
+
+			_, err := source.InstanceID()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Parsing config drive metadata"))
+		})
+	})
+
 	Describe("Settings", func() {
 		It("returns settings read from the config drive", func() {
 			platform.SetGetFilesContentsFromDisk(
@@ -7,3 +7,20 @@ import (
 type DevicePathResolver interface {
 	GetRealDevicePath(diskSettings boshsettings.DiskSettings) (realPath string, timedOut bool, err error)
 }
+
+// AttachedDevicePathResolver looks up device path of a disk which is already
+// attached without rescanning buses, triggering udev or waiting for disk to appear
+type AttachedDevicePathResolver interface {
+	GetAttachedDevicePath(diskSettings boshsettings.DiskSettings) (realPath string, found bool, err error)
+}
+
+// GetAttachedDevicePath treats disks as not found
+// when resolver cannot look them up without rescanning
+func GetAttachedDevicePath(resolver DevicePathResolver, diskSettings boshsettings.DiskSettings) (string, bool, error) {
+	attachedResolver, ok := resolver.(AttachedDevicePathResolver)
+	if !ok {
+		return "", false, nil
+	}
+
+	return attachedResolver.GetAttachedDevicePath(diskSettings)
+}
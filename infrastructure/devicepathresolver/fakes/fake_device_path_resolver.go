@@ -10,6 +10,9 @@ type FakeDevicePathResolver struct {
 	GetRealDevicePathStub         func(boshsettings.DiskSettings) (string, bool, error)
 	GetRealDevicePathTimedOut     bool
 	GetRealDevicePathErr          error
+
+	GetAttachedDevicePathDiskSettings []boshsettings.DiskSettings
+	GetAttachedDevicePathStub         func(boshsettings.DiskSettings) (string, bool, error)
 }
 
 func NewFakeDevicePathResolver() *FakeDevicePathResolver {
@@ -29,3 +32,13 @@ func (r *FakeDevicePathResolver) GetRealDevicePath(diskSettings boshsettings.Dis
 
 	return r.RealDevicePath, false, nil
 }
+
+func (r *FakeDevicePathResolver) GetAttachedDevicePath(diskSettings boshsettings.DiskSettings) (string, bool, error) {
+	r.GetAttachedDevicePathDiskSettings = append(r.GetAttachedDevicePathDiskSettings, diskSettings)
+
+	if r.GetAttachedDevicePathStub != nil {
+		return r.GetAttachedDevicePathStub(diskSettings)
+	}
+
+	return "", false, nil
+}
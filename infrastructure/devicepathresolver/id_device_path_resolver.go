@@ -30,15 +30,12 @@ func NewIDDevicePathResolver(
 }
 
 func (idpr idDevicePathResolver) GetRealDevicePath(diskSettings boshsettings.DiskSettings) (string, bool, error) {
-	if diskSettings.ID == "" {
-		return "", false, bosherr.Errorf("Disk ID is not set")
-	}
-
-	if len(diskSettings.ID) < 20 {
-		return "", false, bosherr.Errorf("Disk ID is not the correct format")
+	err := idpr.validateDiskID(diskSettings)
+	if err != nil {
+		return "", false, err
 	}
 
-	err := idpr.udev.Trigger()
+	err = idpr.udev.Trigger()
 	if err != nil {
 		return "", false, bosherr.WrapError(err, "Running udevadm trigger")
 	}
@@ -49,41 +46,67 @@ func (idpr idDevicePathResolver) GetRealDevicePath(diskSettings boshsettings.Dis
 	}
 
 	stopAfter := time.Now().Add(idpr.diskWaitTimeout)
-	found := false
-
-	var realPath string
-
 	diskID := diskSettings.ID[0:20]
-	deviceGlobPattern := fmt.Sprintf("*%s", diskID)
-	deviceIDPathGlobPattern := path.Join("/", "dev", "disk", "by-id", deviceGlobPattern)
 
-	for !found {
+	for {
 		if time.Now().After(stopAfter) {
 			return "", true, bosherr.Errorf("Timed out getting real device path for '%s'", diskID)
 		}
 
 		time.Sleep(100 * time.Millisecond)
-		pathMatches, err := idpr.fs.Glob(deviceIDPathGlobPattern)
+
+		realPath, found, err := idpr.findDevice(diskID)
 		if err != nil {
-			continue
+			return "", true, err
 		}
 
-		switch len(pathMatches) {
-		case 0:
-			continue
-		case 1:
-			realPath, err = idpr.fs.ReadAndFollowLink(pathMatches[0])
-			if err != nil {
-				continue
-			}
-
-			if idpr.fs.FileExists(realPath) {
-				found = true
-			}
-		default:
-			return "", true, bosherr.Errorf("More than one disk matched glob %q while getting real device path for %q", deviceIDPathGlobPattern, diskID)
+		if found {
+			return realPath, false, nil
 		}
 	}
+}
+
+func (idpr idDevicePathResolver) GetAttachedDevicePath(diskSettings boshsettings.DiskSettings) (string, bool, error) {
+	err := idpr.validateDiskID(diskSettings)
+	if err != nil {
+		return "", false, err
+	}
+
+	return idpr.findDevice(diskSettings.ID[0:20])
+}
+
+func (idpr idDevicePathResolver) validateDiskID(diskSettings boshsettings.DiskSettings) error {
+	if diskSettings.ID == "" {
+		return bosherr.Errorf("Disk ID is not set")
+	}
+
+	if len(diskSettings.ID) < 20 {
+		return bosherr.Errorf("Disk ID is not the correct format")
+	}
 
-	return realPath, false, nil
+	return nil
+}
+
+func (idpr idDevicePathResolver) findDevice(diskID string) (string, bool, error) {
+	deviceGlobPattern := fmt.Sprintf("*%s", diskID)
+	deviceIDPathGlobPattern := path.Join("/", "dev", "disk", "by-id", deviceGlobPattern)
+
+	pathMatches, err := idpr.fs.Glob(deviceIDPathGlobPattern)
+	if err != nil {
+		return "", false, nil
+	}
+
+	switch len(pathMatches) {
+	case 0:
+		return "", false, nil
+	case 1:
+		realPath, err := idpr.fs.ReadAndFollowLink(pathMatches[0])
+		if err != nil {
+			return "", false, nil
+		}
+
+		return realPath, idpr.fs.FileExists(realPath), nil
+	default:
+		return "", false, bosherr.Errorf("More than one disk matched glob %q while getting real device path for %q", deviceIDPathGlobPattern, diskID)
+	}
 }
@@ -18,3 +18,7 @@ func (r identityDevicePathResolver) GetRealDevicePath(diskSettings boshsettings.
 
 	return diskSettings.Path, false, nil
 }
+
+func (r identityDevicePathResolver) GetAttachedDevicePath(diskSettings boshsettings.DiskSettings) (string, bool, error) {
+	return diskSettings.Path, len(diskSettings.Path) > 0, nil
+}
@@ -44,6 +44,16 @@ func (dpr mappedDevicePathResolver) GetRealDevicePath(diskSettings boshsettings.
 	return realPath, false, nil
 }
 
+func (dpr mappedDevicePathResolver) GetAttachedDevicePath(diskSettings boshsettings.DiskSettings) (string, bool, error) {
+	if len(diskSettings.Path) == 0 {
+		return "", false, bosherr.Error("Getting real device path: path is missing")
+	}
+
+	realPath, found := dpr.findPossibleDevice(diskSettings.Path)
+
+	return realPath, found, nil
+}
+
 func (dpr mappedDevicePathResolver) findPossibleDevice(devicePath string) (string, bool) {
 	needsMapping := strings.HasPrefix(devicePath, "/dev/sd")
 
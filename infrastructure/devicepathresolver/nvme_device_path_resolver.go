@@ -0,0 +1,185 @@
+package devicepathresolver
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// NVMeDevicePathResolver resolves device path by matching disk's volume ID
+// or device ID against serial numbers of NVMe controllers, e.g. volume
+// "vol-0123456789abcdef0" attached as /dev/sdf shows up as controller with
+// serial "vol0123456789abcdef0". Namespace is chosen by disk's LUN
+// when set, otherwise controller's first namespace is used.
+type NVMeDevicePathResolver struct {
+	diskWaitTimeout time.Duration
+	fs              boshsys.FileSystem
+
+	logTag string
+	logger boshlog.Logger
+}
+
+func NewNVMeDevicePathResolver(
+	diskWaitTimeout time.Duration,
+	fs boshsys.FileSystem,
+	logger boshlog.Logger,
+) NVMeDevicePathResolver {
+	return NVMeDevicePathResolver{
+		diskWaitTimeout: diskWaitTimeout,
+		fs:              fs,
+
+		logTag: "nvmeResolver",
+		logger: logger,
+	}
+}
+
+func (npr NVMeDevicePathResolver) GetRealDevicePath(diskSettings boshsettings.DiskSettings) (string, bool, error) {
+	ids, err := nvmeSerials(diskSettings)
+	if err != nil {
+		return "", false, err
+	}
+
+	stopAfter := time.Now().Add(npr.diskWaitTimeout)
+
+	for {
+		realPath, found, err := npr.findDevice(ids, diskSettings.Lun)
+		if err != nil {
+			return "", false, err
+		}
+
+		if found {
+			npr.logger.Debug(npr.logTag, "Found real path "+realPath)
+			return realPath, false, nil
+		}
+
+		if time.Now().After(stopAfter) {
+			return "", true, bosherr.Errorf("Timed out getting real device path for '%s'", strings.Join(ids, "', '"))
+		}
+
+		npr.logger.Debug(npr.logTag, "Waiting for device to appear")
+
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func (npr NVMeDevicePathResolver) GetAttachedDevicePath(diskSettings boshsettings.DiskSettings) (string, bool, error) {
+	ids, err := nvmeSerials(diskSettings)
+	if err != nil {
+		return "", false, err
+	}
+
+	return npr.findDevice(ids, diskSettings.Lun)
+}
+
+func nvmeSerials(diskSettings boshsettings.DiskSettings) ([]string, error) {
+	var ids []string
+	for _, id := range []string{diskSettings.VolumeID, diskSettings.DeviceID} {
+		if id != "" {
+			ids = append(ids, normalizeNVMeSerial(id))
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil, bosherr.Error("Disk volume ID and device ID are not set")
+	}
+
+	return ids, nil
+}
+
+func (npr NVMeDevicePathResolver) findDevice(ids []string, lun string) (string, bool, error) {
+	serialPaths, err := npr.fs.Glob("/sys/class/nvme/nvme*/serial")
+	if err != nil {
+		return "", false, bosherr.WrapError(err, "Could not list NVMe controllers")
+	}
+
+	for _, serialPath := range serialPaths {
+		serial, err := npr.fs.ReadFileString(serialPath)
+		if err != nil {
+			npr.logger.Debug(npr.logTag, "Reading serial %s: %s", serialPath, err.Error())
+			continue
+		}
+
+		if !containsString(ids, normalizeNVMeSerial(serial)) {
+			continue
+		}
+
+		namespace, found := npr.findNamespace(path.Dir(serialPath), lun)
+		if !found {
+			continue
+		}
+
+		realPath := path.Join("/dev", namespace)
+		if npr.fs.FileExists(realPath) {
+			return realPath, true, nil
+		}
+	}
+
+	return "", false, nil
+}
+
+func (npr NVMeDevicePathResolver) findNamespace(controllerPath, lun string) (string, bool) {
+	controller := path.Base(controllerPath)
+
+	namespacePaths, err := npr.fs.Glob(path.Join(controllerPath, controller+"n*"))
+	if err != nil || len(namespacePaths) == 0 {
+		return "", false
+	}
+
+	namespaces := map[int]string{}
+	var nsids []int
+
+	for _, namespacePath := range namespacePaths {
+		contents, err := npr.fs.ReadFileString(path.Join(namespacePath, "nsid"))
+		if err != nil {
+			continue
+		}
+
+		nsid, err := strconv.Atoi(strings.TrimSpace(contents))
+		if err != nil {
+			continue
+		}
+
+		namespaces[nsid] = path.Base(namespacePath)
+		nsids = append(nsids, nsid)
+	}
+
+	if len(nsids) == 0 {
+		return "", false
+	}
+
+	if lun == "" {
+		sort.Ints(nsids)
+		return namespaces[nsids[0]], true
+	}
+
+	nsid, err := strconv.Atoi(lun)
+	if err != nil {
+		return "", false
+	}
+
+	namespace, found := namespaces[nsid]
+
+	return namespace, found
+}
+
+// normalizeNVMeSerial strips padding and dashes since
+// controllers report serials without them, e.g. "vol0123..."
+func normalizeNVMeSerial(serial string) string {
+	return strings.ToLower(strings.Replace(strings.TrimSpace(serial), "-", "", -1))
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+package devicepathresolver_test
+
+import (
+	"time"
+
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver"
+)
+
+var _ = Describe("NVMeDevicePathResolver", func() {
+	var (
+		fs           *fakesys.FakeFileSystem
+		diskSettings boshsettings.DiskSettings
+		pathResolver DevicePathResolver
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		pathResolver = NewNVMeDevicePathResolver(500*time.Millisecond, fs, boshlog.NewLogger(boshlog.LevelNone))
+		diskSettings = boshsettings.DiskSettings{
+			VolumeID: "vol-0123456789abcdef0",
+			Path:     "/dev/sdf",
+		}
+
+		fs.SetGlob("/sys/class/nvme/nvme*/serial", []string{
+			"/sys/class/nvme/nvme0/serial",
+			"/sys/class/nvme/nvme1/serial",
+		})
+
+		fs.WriteFileString("/sys/class/nvme/nvme0/serial", "vol0fedcba9876543210 \n")
+		fs.SetGlob("/sys/class/nvme/nvme0/nvme0n*", []string{"/sys/class/nvme/nvme0/nvme0n1"})
+		fs.WriteFileString("/sys/class/nvme/nvme0/nvme0n1/nsid", "1\n")
+		fs.WriteFileString("/dev/nvme0n1", "")
+
+		fs.WriteFileString("/sys/class/nvme/nvme1/serial", "vol0123456789abcdef0 \n")
+		fs.SetGlob("/sys/class/nvme/nvme1/nvme1n*", []string{
+			"/sys/class/nvme/nvme1/nvme1n2",
+			"/sys/class/nvme/nvme1/nvme1n1",
+		})
+		fs.WriteFileString("/sys/class/nvme/nvme1/nvme1n1/nsid", "1\n")
+		fs.WriteFileString("/sys/class/nvme/nvme1/nvme1n2/nsid", "2\n")
+		fs.WriteFileString("/dev/nvme1n1", "")
+		fs.WriteFileString("/dev/nvme1n2", "")
+	})
+
+	Describe("GetRealDevicePath", func() {
+		It("returns first namespace of controller whose serial matches volume id", func() {
+			path, timeout, err := pathResolver.GetRealDevicePath(diskSettings)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(path).To(Equal("/dev/nvme1n1"))
+			Expect(timeout).To(BeFalse())
+		})
+
+		It("matches serial against device id when volume id is not set", func() {
+			diskSettings = boshsettings.DiskSettings{DeviceID: "vol0FEDCBA9876543210"}
+
+			path, timeout, err := pathResolver.GetRealDevicePath(diskSettings)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(path).To(Equal("/dev/nvme0n1"))
+			Expect(timeout).To(BeFalse())
+		})
+
+		It("returns namespace whose id matches disk lun", func() {
+			diskSettings.Lun = "2"
+
+			path, _, err := pathResolver.GetRealDevicePath(diskSettings)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(path).To(Equal("/dev/nvme1n2"))
+		})
+
+		Context("when device appears after some time", func() {
+			BeforeEach(func() {
+				fs.RemoveAll("/dev/nvme1n1")
+
+				time.AfterFunc(100*time.Millisecond, func() {
+					fs.WriteFileString("/dev/nvme1n1", "")
+				})
+			})
+
+			It("returns the real path", func() {
+				path, timeout, err := pathResolver.GetRealDevicePath(diskSettings)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(path).To(Equal("/dev/nvme1n1"))
+				Expect(timeout).To(BeFalse())
+			})
+		})
+
+		Context("when no controller serial matches", func() {
+			BeforeEach(func() {
+				diskSettings.VolumeID = "vol-fake"
+			})
+
+			It("times out", func() {
+				_, timeout, err := pathResolver.GetRealDevicePath(diskSettings)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Timed out getting real device path for 'volfake'"))
+				Expect(timeout).To(BeTrue())
+			})
+		})
+
+		Context("when no namespace matches disk lun", func() {
+			BeforeEach(func() {
+				diskSettings.Lun = "3"
+			})
+
+			It("times out", func() {
+				_, timeout, err := pathResolver.GetRealDevicePath(diskSettings)
+				Expect(err).To(HaveOccurred())
+				Expect(timeout).To(BeTrue())
+			})
+		})
+
+		Context("when neither volume id nor device id is set", func() {
+			BeforeEach(func() {
+				diskSettings = boshsettings.DiskSettings{Path: "/dev/sdf"}
+			})
+
+			It("returns an error without waiting", func() {
+				_, timeout, err := pathResolver.GetRealDevicePath(diskSettings)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Disk volume ID and device ID are not set"))
+				Expect(timeout).To(BeFalse())
+			})
+		})
+	})
+
+	Describe("GetAttachedDevicePath", func() {
+		var attachedResolver AttachedDevicePathResolver
+
+		BeforeEach(func() {
+			attachedResolver = pathResolver.(AttachedDevicePathResolver)
+		})
+
+		It("returns namespace of controller whose serial matches volume id", func() {
+			path, found, err := attachedResolver.GetAttachedDevicePath(diskSettings)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(path).To(Equal("/dev/nvme1n1"))
+		})
+
+		It("returns not found without waiting when no controller serial matches", func() {
+			diskSettings.VolumeID = "vol-unknown"
+
+			_, found, err := attachedResolver.GetAttachedDevicePath(diskSettings)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeFalse())
+		})
+	})
+})
@@ -38,3 +38,19 @@ func (sr scsiDevicePathResolver) GetRealDevicePath(diskSettings boshsettings.Dis
 
 	return "", false, bosherr.Error("Neither ID, VolumeID nor (Lun, HostDeviceID) provided in disk settings")
 }
+
+func (sr scsiDevicePathResolver) GetAttachedDevicePath(diskSettings boshsettings.DiskSettings) (string, bool, error) {
+	if len(diskSettings.DeviceID) > 0 {
+		return GetAttachedDevicePath(sr.scsiIDPathResolver, diskSettings)
+	}
+
+	if len(diskSettings.VolumeID) > 0 {
+		return GetAttachedDevicePath(sr.scsiVolumeIDPathResolver, diskSettings)
+	}
+
+	if len(diskSettings.Lun) > 0 && len(diskSettings.HostDeviceID) > 0 {
+		return GetAttachedDevicePath(sr.scsiLunPathResolver, diskSettings)
+	}
+
+	return "", false, bosherr.Error("Neither ID, VolumeID nor (Lun, HostDeviceID) provided in disk settings")
+}
@@ -54,11 +54,8 @@ func (idpr SCSIIDDevicePathResolver) GetRealDevicePath(diskSettings boshsettings
 	}
 
 	stopAfter := time.Now().Add(idpr.diskWaitTimeout)
-	found := false
 
-	var realPath string
-
-	for !found {
+	for {
 		idpr.logger.Debug(idpr.logTag, "Waiting for device to appear")
 
 		if time.Now().After(stopAfter) {
@@ -67,25 +64,44 @@ func (idpr SCSIIDDevicePathResolver) GetRealDevicePath(diskSettings boshsettings
 
 		time.Sleep(100 * time.Millisecond)
 
-		uuid := strings.Replace(diskSettings.DeviceID, "-", "", -1)
-		disks, err := idpr.fs.Glob("/dev/disk/by-id/*" + uuid)
+		realPath, found, err := idpr.findDevice(diskSettings.DeviceID)
+		if err != nil {
+			return "", false, err
+		}
+
+		if found {
+			return realPath, false, nil
+		}
+	}
+}
+
+func (idpr SCSIIDDevicePathResolver) GetAttachedDevicePath(diskSettings boshsettings.DiskSettings) (string, bool, error) {
+	if diskSettings.DeviceID == "" {
+		return "", false, bosherr.Errorf("Disk device ID is not set")
+	}
+
+	return idpr.findDevice(diskSettings.DeviceID)
+}
+
+func (idpr SCSIIDDevicePathResolver) findDevice(deviceID string) (string, bool, error) {
+	uuid := strings.Replace(deviceID, "-", "", -1)
+	disks, err := idpr.fs.Glob("/dev/disk/by-id/*" + uuid)
+	if err != nil {
+		return "", false, bosherr.WrapError(err, "Could not list disks by id")
+	}
+
+	for _, path := range disks {
+		idpr.logger.Debug(idpr.logTag, "Reading link "+path)
+		realPath, err := idpr.fs.ReadAndFollowLink(path)
 		if err != nil {
-			return "", false, bosherr.WrapError(err, "Could not list disks by id")
+			continue
 		}
-		for _, path := range disks {
-			idpr.logger.Debug(idpr.logTag, "Reading link "+path)
-			realPath, err = idpr.fs.ReadAndFollowLink(path)
-			if err != nil {
-				continue
-			}
-
-			if idpr.fs.FileExists(realPath) {
-				idpr.logger.Debug(idpr.logTag, "Found real path "+realPath)
-				found = true
-				break
-			}
+
+		if idpr.fs.FileExists(realPath) {
+			idpr.logger.Debug(idpr.logTag, "Found real path "+realPath)
+			return realPath, true, nil
 		}
 	}
 
-	return realPath, false, nil
+	return "", false, nil
 }
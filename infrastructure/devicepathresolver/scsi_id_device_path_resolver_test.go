@@ -121,4 +121,35 @@ var _ = Describe("ScsiIDDevicePathResolver", func() {
 			})
 		})
 	})
+
+	Describe("GetAttachedDevicePath", func() {
+		var attachedResolver AttachedDevicePathResolver
+
+		BeforeEach(func() {
+			attachedResolver = pathResolver.(AttachedDevicePathResolver)
+		})
+
+		It("returns path of attached device without rescanning SCSI hosts", func() {
+			err := fs.MkdirAll("fake-device-path", os.FileMode(0750))
+			Expect(err).ToNot(HaveOccurred())
+
+			err = fs.Symlink("fake-device-path", "/dev/disk/by-id/scsi-3"+id)
+			Expect(err).ToNot(HaveOccurred())
+
+			path, found, err := attachedResolver.GetAttachedDevicePath(diskSettings)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(path).To(Equal("fake-device-path"))
+
+			for _, host := range hosts {
+				Expect(fs.FileExists(host)).To(BeFalse())
+			}
+		})
+
+		It("returns not found without waiting when device is not attached", func() {
+			_, found, err := attachedResolver.GetAttachedDevicePath(diskSettings)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeFalse())
+		})
+	})
 })
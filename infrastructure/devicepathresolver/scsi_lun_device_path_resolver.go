@@ -35,12 +35,9 @@ func NewSCSILunDevicePathResolver(
 }
 
 func (ldpr SCSILunDevicePathResolver) GetRealDevicePath(diskSettings boshsettings.DiskSettings) (string, bool, error) {
-	if diskSettings.Lun == "" {
-		return "", false, bosherr.Error("Disk lun is not set")
-	}
-
-	if diskSettings.HostDeviceID == "" {
-		return "", false, bosherr.Error("Disk host_device_id is not set")
+	err := ldpr.validateDiskSettings(diskSettings)
+	if err != nil {
+		return "", false, err
 	}
 
 	hostPaths, err := ldpr.fs.Glob("/sys/class/scsi_host/host*/scan")
@@ -59,11 +56,63 @@ func (ldpr SCSILunDevicePathResolver) GetRealDevicePath(diskSettings boshsetting
 
 	stopAfter := time.Now().Add(ldpr.diskWaitTimeout)
 
+	vmBusDeviceForDataDisks, err := ldpr.findVMBusDevice(diskSettings.HostDeviceID)
+	if err != nil {
+		return "", false, err
+	}
+
+	for {
+		ldpr.logger.Debug(ldpr.logTag, "Waiting for device to appear")
+
+		if time.Now().After(stopAfter) {
+			return "", true, bosherr.Errorf("Timed out getting real device path by lun '%s' and host_device_id '%s'", diskSettings.Lun, diskSettings.HostDeviceID)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+
+		realPath, found, err := ldpr.findDevice(vmBusDeviceForDataDisks, diskSettings.Lun)
+		if err != nil {
+			return "", false, err
+		}
+
+		if found {
+			return realPath, false, nil
+		}
+	}
+}
+
+func (ldpr SCSILunDevicePathResolver) GetAttachedDevicePath(diskSettings boshsettings.DiskSettings) (string, bool, error) {
+	err := ldpr.validateDiskSettings(diskSettings)
+	if err != nil {
+		return "", false, err
+	}
+
+	vmBusDeviceForDataDisks, err := ldpr.findVMBusDevice(diskSettings.HostDeviceID)
+	if err != nil {
+		return "", false, err
+	}
+
+	return ldpr.findDevice(vmBusDeviceForDataDisks, diskSettings.Lun)
+}
+
+func (ldpr SCSILunDevicePathResolver) validateDiskSettings(diskSettings boshsettings.DiskSettings) error {
+	if diskSettings.Lun == "" {
+		return bosherr.Error("Disk lun is not set")
+	}
+
+	if diskSettings.HostDeviceID == "" {
+		return bosherr.Error("Disk host_device_id is not set")
+	}
+
+	return nil
+}
+
+func (ldpr SCSILunDevicePathResolver) findVMBusDevice(hostDeviceID string) (string, error) {
 	var vmBusDeviceForDataDisks string
 
 	vmBusDevices, err := ldpr.fs.Glob("/sys/bus/vmbus/devices/*/device_id")
 	if err != nil {
-		return "", false, bosherr.WrapError(err, "Could not list vmbus devices")
+		return "", bosherr.WrapError(err, "Could not list vmbus devices")
 	}
 
 	for _, vmBusDevice := range vmBusDevices {
@@ -72,7 +121,7 @@ func (ldpr SCSILunDevicePathResolver) GetRealDevicePath(diskSettings boshsetting
 			continue
 		}
 
-		if strings.TrimSpace(deviceID) == diskSettings.HostDeviceID {
+		if strings.TrimSpace(deviceID) == hostDeviceID {
 			vmBusDeviceSplits := strings.Split(vmBusDevice, "/")
 			vmBusDeviceForDataDisks = vmBusDeviceSplits[5]
 			break
@@ -80,43 +129,39 @@ func (ldpr SCSILunDevicePathResolver) GetRealDevicePath(diskSettings boshsetting
 	}
 
 	if vmBusDeviceForDataDisks == "" {
-		return "", false, bosherr.WrapErrorf(err, "Cannot find the vmbus device by host_device_id '%s'", diskSettings.HostDeviceID)
+		return "", bosherr.WrapErrorf(err, "Cannot find the vmbus device by host_device_id '%s'", hostDeviceID)
 	}
 
-	ldpr.logger.Debug(ldpr.logTag, "Find the vmbus device '%s' by host_device_id '%s'", vmBusDeviceForDataDisks, diskSettings.HostDeviceID)
+	ldpr.logger.Debug(ldpr.logTag, "Find the vmbus device '%s' by host_device_id '%s'", vmBusDeviceForDataDisks, hostDeviceID)
 
-	deviceGlobPath := fmt.Sprintf("/sys/bus/scsi/devices/*:*:*:%s/block/*", diskSettings.Lun)
+	return vmBusDeviceForDataDisks, nil
+}
 
-	for {
-		ldpr.logger.Debug(ldpr.logTag, "Waiting for device to appear")
+func (ldpr SCSILunDevicePathResolver) findDevice(vmBusDeviceForDataDisks, lun string) (string, bool, error) {
+	deviceGlobPath := fmt.Sprintf("/sys/bus/scsi/devices/*:*:*:%s/block/*", lun)
 
-		if time.Now().After(stopAfter) {
-			return "", true, bosherr.Errorf("Timed out getting real device path by lun '%s' and host_device_id '%s'", diskSettings.Lun, diskSettings.HostDeviceID)
-		}
+	devicePaths, err := ldpr.fs.Glob(deviceGlobPath)
+	if err != nil {
+		return "", false, bosherr.WrapErrorf(err, "Could not list disks by lun '%s'", lun)
+	}
 
-		time.Sleep(100 * time.Millisecond)
+	for _, devicePath := range devicePaths {
+		baseName := path.Base(devicePath)
 
-		devicePaths, err := ldpr.fs.Glob(deviceGlobPath)
+		tempPath, err := ldpr.fs.ReadAndFollowLink(path.Join("/sys/class/block/", baseName))
 		if err != nil {
-			return "", false, bosherr.WrapErrorf(err, "Could not list disks by lun '%s'", diskSettings.Lun)
+			continue
 		}
 
-		for _, devicePath := range devicePaths {
-			baseName := path.Base(devicePath)
-
-			tempPath, err := ldpr.fs.ReadAndFollowLink(path.Join("/sys/class/block/", baseName))
-			if err != nil {
-				continue
-			}
-
-			if strings.Contains(tempPath, fmt.Sprintf("/%s/", vmBusDeviceForDataDisks)) {
-				realPath := path.Join("/dev/", baseName)
+		if strings.Contains(tempPath, fmt.Sprintf("/%s/", vmBusDeviceForDataDisks)) {
+			realPath := path.Join("/dev/", baseName)
 
-				if ldpr.fs.FileExists(realPath) {
-					ldpr.logger.Debug(ldpr.logTag, "Found real path '%s'", realPath)
-					return realPath, false, nil
-				}
+			if ldpr.fs.FileExists(realPath) {
+				ldpr.logger.Debug(ldpr.logTag, "Found real path '%s'", realPath)
+				return realPath, true, nil
 			}
 		}
 	}
+
+	return "", false, nil
 }
@@ -28,32 +28,13 @@ func NewSCSIVolumeIDDevicePathResolver(
 }
 
 func (devicePathResolver SCSIVolumeIDDevicePathResolver) GetRealDevicePath(diskSettings boshsettings.DiskSettings) (realPath string, timedOut bool, err error) {
-	devicePaths, err := devicePathResolver.fs.Glob("/sys/bus/scsi/devices/*:0:0:0/block/*")
-	if err != nil {
+	hostID, err := devicePathResolver.findRootHostID()
+	if err != nil || len(hostID) == 0 {
 		return
 	}
 
-	var hostID string
-
 	volumeID := diskSettings.VolumeID
 
-	for _, rootDevicePath := range devicePaths {
-		if path.Base(rootDevicePath) == "sda" {
-			rootDevicePathSplits := strings.Split(rootDevicePath, "/")
-			if len(rootDevicePathSplits) > 5 {
-				scsiPath := rootDevicePathSplits[5]
-				scsiPathSplits := strings.Split(scsiPath, ":")
-				if len(scsiPathSplits) > 0 {
-					hostID = scsiPathSplits[0]
-				}
-			}
-		}
-	}
-
-	if len(hostID) == 0 {
-		return
-	}
-
 	scanPath := fmt.Sprintf("/sys/class/scsi_host/host%s/scan", hostID)
 	err = devicePathResolver.fs.WriteFileString(scanPath, "- - -")
 	if err != nil {
@@ -62,6 +43,8 @@ func (devicePathResolver SCSIVolumeIDDevicePathResolver) GetRealDevicePath(diskS
 
 	deviceGlobPath := fmt.Sprintf("/sys/bus/scsi/devices/%s:0:%s:0/block/*", hostID, volumeID)
 
+	var devicePaths []string
+
 	for i := 0; i < maxScanRetries; i++ {
 		devicePaths, err = devicePathResolver.fs.Glob(deviceGlobPath)
 		if err != nil || len(devicePaths) == 0 {
@@ -81,3 +64,44 @@ func (devicePathResolver SCSIVolumeIDDevicePathResolver) GetRealDevicePath(diskS
 
 	return
 }
+
+func (devicePathResolver SCSIVolumeIDDevicePathResolver) GetAttachedDevicePath(diskSettings boshsettings.DiskSettings) (string, bool, error) {
+	hostID, err := devicePathResolver.findRootHostID()
+	if err != nil || len(hostID) == 0 {
+		return "", false, err
+	}
+
+	deviceGlobPath := fmt.Sprintf("/sys/bus/scsi/devices/%s:0:%s:0/block/*", hostID, diskSettings.VolumeID)
+
+	devicePaths, err := devicePathResolver.fs.Glob(deviceGlobPath)
+	if err != nil || len(devicePaths) == 0 {
+		return "", false, err
+	}
+
+	return path.Join("/dev/", path.Base(devicePaths[0])), true, nil
+}
+
+// findRootHostID returns ID of SCSI host which root disk sda is attached to
+func (devicePathResolver SCSIVolumeIDDevicePathResolver) findRootHostID() (string, error) {
+	devicePaths, err := devicePathResolver.fs.Glob("/sys/bus/scsi/devices/*:0:0:0/block/*")
+	if err != nil {
+		return "", err
+	}
+
+	var hostID string
+
+	for _, rootDevicePath := range devicePaths {
+		if path.Base(rootDevicePath) == "sda" {
+			rootDevicePathSplits := strings.Split(rootDevicePath, "/")
+			if len(rootDevicePathSplits) > 5 {
+				scsiPath := rootDevicePathSplits[5]
+				scsiPathSplits := strings.Split(scsiPath, ":")
+				if len(scsiPathSplits) > 0 {
+					hostID = scsiPathSplits[0]
+				}
+			}
+		}
+	}
+
+	return hostID, nil
+}
@@ -49,3 +49,12 @@ func (vpr virtioDevicePathResolver) GetRealDevicePath(diskSettings boshsettings.
 
 	return realPath, false, nil
 }
+
+func (vpr virtioDevicePathResolver) GetAttachedDevicePath(diskSettings boshsettings.DiskSettings) (string, bool, error) {
+	realPath, found, err := GetAttachedDevicePath(vpr.idDevicePathResolver, diskSettings)
+	if err == nil && found {
+		return realPath, true, nil
+	}
+
+	return GetAttachedDevicePath(vpr.mappedDevicePathResolver, diskSettings)
+}
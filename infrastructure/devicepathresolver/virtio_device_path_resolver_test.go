@@ -92,4 +92,45 @@ var _ = Describe("VirtioDevicePathResolver", func() {
 			})
 		})
 	})
+
+	Describe("GetAttachedDevicePath", func() {
+		var attachedResolver AttachedDevicePathResolver
+
+		BeforeEach(func() {
+			attachedResolver = pathResolver.(AttachedDevicePathResolver)
+		})
+
+		It("returns path found by id resolver", func() {
+			idDevicePathResolver.GetAttachedDevicePathStub = func(boshsettings.DiskSettings) (string, bool, error) {
+				return "fake-id-resolved-device-path", true, nil
+			}
+
+			realPath, found, err := attachedResolver.GetAttachedDevicePath(diskSettings)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(realPath).To(Equal("fake-id-resolved-device-path"))
+			Expect(mappedDevicePathResolver.GetAttachedDevicePathDiskSettings).To(BeEmpty())
+		})
+
+		It("falls back to mapped resolver when id resolver does not find device", func() {
+			idDevicePathResolver.GetAttachedDevicePathStub = func(boshsettings.DiskSettings) (string, bool, error) {
+				return "", false, errors.New("fake-id-error")
+			}
+			mappedDevicePathResolver.GetAttachedDevicePathStub = func(boshsettings.DiskSettings) (string, bool, error) {
+				return "fake-mapped-resolved-device-path", true, nil
+			}
+
+			realPath, found, err := attachedResolver.GetAttachedDevicePath(diskSettings)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(realPath).To(Equal("fake-mapped-resolved-device-path"))
+		})
+
+		It("does not resolve real device paths", func() {
+			_, _, err := attachedResolver.GetAttachedDevicePath(diskSettings)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(idDevicePathResolver.GetRealDevicePathDiskSettings).To(Equal(boshsettings.DiskSettings{}))
+			Expect(mappedDevicePathResolver.GetRealDevicePathDiskSettings).To(Equal(boshsettings.DiskSettings{}))
+		})
+	})
 })
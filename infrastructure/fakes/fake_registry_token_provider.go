@@ -0,0 +1,10 @@
+package fakes
+
+type FakeRegistryTokenProvider struct {
+	Token    string
+	TokenErr error
+}
+
+func (p *FakeRegistryTokenProvider) GetRegistryToken() (string, error) {
+	return p.Token, p.TokenErr
+}
@@ -10,6 +10,9 @@ type FakeSettingsSource struct {
 
 	SettingsValue boshsettings.Settings
 	SettingsErr   error
+
+	InstanceIDValue string
+	InstanceIDErr   error
 }
 
 func (s FakeSettingsSource) PublicSSHKeyForUsername(string) (string, error) {
@@ -19,3 +22,7 @@ func (s FakeSettingsSource) PublicSSHKeyForUsername(string) (string, error) {
 func (s FakeSettingsSource) Settings() (boshsettings.Settings, error) {
 	return s.SettingsValue, s.SettingsErr
 }
+
+func (s FakeSettingsSource) InstanceID() (string, error) {
+	return s.InstanceIDValue, s.InstanceIDErr
+}
@@ -17,6 +17,7 @@ import (
 type httpMetadataService struct {
 	metadataHost    string
 	metadataHeaders map[string]string
+	tokenProvider   *metadataTokenProvider
 	userdataPath    string
 	instanceIDPath  string
 	sshKeysPath     string
@@ -30,6 +31,7 @@ type httpMetadataService struct {
 func NewHTTPMetadataService(
 	metadataHost string,
 	metadataHeaders map[string]string,
+	tokenOptions *TokenOptions,
 	userdataPath string,
 	instanceIDPath string,
 	sshKeysPath string,
@@ -40,6 +42,7 @@ func NewHTTPMetadataService(
 	return httpMetadataService{
 		metadataHost:    metadataHost,
 		metadataHeaders: metadataHeaders,
+		tokenProvider:   newTokenProvider(metadataHost, metadataHeaders, tokenOptions, logger),
 		userdataPath:    userdataPath,
 		instanceIDPath:  instanceIDPath,
 		sshKeysPath:     sshKeysPath,
@@ -54,6 +57,7 @@ func NewHTTPMetadataService(
 func NewHTTPMetadataServiceWithCustomRetryDelay(
 	metadataHost string,
 	metadataHeaders map[string]string,
+	tokenOptions *TokenOptions,
 	userdataPath string,
 	instanceIDPath string,
 	sshKeysPath string,
@@ -65,6 +69,7 @@ func NewHTTPMetadataServiceWithCustomRetryDelay(
 	return httpMetadataService{
 		metadataHost:    metadataHost,
 		metadataHeaders: metadataHeaders,
+		tokenProvider:   newTokenProvider(metadataHost, metadataHeaders, tokenOptions, logger),
 		userdataPath:    userdataPath,
 		instanceIDPath:  instanceIDPath,
 		sshKeysPath:     sshKeysPath,
@@ -266,6 +271,19 @@ func (ms httpMetadataService) ensureMinimalNetworkSetup() error {
 	return nil
 }
 
+// newTokenProvider returns nil when metadata requests do not require session token
+func newTokenProvider(
+	metadataHost string,
+	metadataHeaders map[string]string,
+	tokenOptions *TokenOptions,
+	logger boshlog.Logger,
+) *metadataTokenProvider {
+	if tokenOptions == nil {
+		return nil
+	}
+	return newMetadataTokenProvider(metadataHost, metadataHeaders, *tokenOptions, logger)
+}
+
 func (ms httpMetadataService) doGet(url string) (*http.Response, error) {
 
 	req, err := http.NewRequest("GET", url, nil)
@@ -277,8 +295,13 @@ func (ms httpMetadataService) doGet(url string) (*http.Response, error) {
 		req.Header.Add(key, value)
 	}
 
+	var delegate boshhttp.Client = &http.Client{}
+	if ms.tokenProvider != nil {
+		delegate = ms.tokenProvider.WrapClient(delegate)
+	}
+
 	client := boshhttp.NewRetryClient(
-		&http.Client{},
+		delegate,
 		10,
 		ms.retryDelay,
 		ms.logger,
@@ -35,7 +35,7 @@ func describeHTTPMetadataService() {
 		dnsResolver = &fakeinf.FakeDNSResolver{}
 		platform = fakeplat.NewFakePlatform()
 		logger = boshlog.NewLogger(boshlog.LevelNone)
-		metadataService = NewHTTPMetadataService("fake-metadata-host", metadataHeaders, "/user-data", "/instanceid", "/ssh-keys", dnsResolver, platform, logger)
+		metadataService = NewHTTPMetadataService("fake-metadata-host", metadataHeaders, nil, "/user-data", "/instanceid", "/ssh-keys", dnsResolver, platform, logger)
 	})
 
 	ItEnsuresMinimalNetworkSetup := func(subject func() (string, error)) {
@@ -102,7 +102,7 @@ func describeHTTPMetadataService() {
 		Context("when the ssh keys path is present", func() {
 			BeforeEach(func() {
 				sshKeysPath = "/ssh-keys"
-				metadataService = NewHTTPMetadataService(ts.URL, metadataHeaders, "/user-data", "/instanceid", sshKeysPath, dnsResolver, platform, logger)
+				metadataService = NewHTTPMetadataService(ts.URL, metadataHeaders, nil, "/user-data", "/instanceid", sshKeysPath, dnsResolver, platform, logger)
 			})
 
 			It("returns fetched public key", func() {
@@ -119,7 +119,7 @@ func describeHTTPMetadataService() {
 		Context("when the ssh keys path is not present", func() {
 			BeforeEach(func() {
 				sshKeysPath = ""
-				metadataService = NewHTTPMetadataService(ts.URL, metadataHeaders, "/user-data", "/instanceid", sshKeysPath, dnsResolver, platform, logger)
+				metadataService = NewHTTPMetadataService(ts.URL, metadataHeaders, nil, "/user-data", "/instanceid", sshKeysPath, dnsResolver, platform, logger)
 			})
 
 			It("returns an empty ssh key", func() {
@@ -156,7 +156,7 @@ func describeHTTPMetadataService() {
 		Context("when the instance ID path is present", func() {
 			BeforeEach(func() {
 				instanceIDPath = "/instanceid"
-				metadataService = NewHTTPMetadataService(ts.URL, metadataHeaders, "/user-data", instanceIDPath, "/ssh-keys", dnsResolver, platform, logger)
+				metadataService = NewHTTPMetadataService(ts.URL, metadataHeaders, nil, "/user-data", instanceIDPath, "/ssh-keys", dnsResolver, platform, logger)
 			})
 
 			It("returns fetched instance id", func() {
@@ -173,7 +173,7 @@ func describeHTTPMetadataService() {
 		Context("when the instance ID path is not present", func() {
 			BeforeEach(func() {
 				instanceIDPath = ""
-				metadataService = NewHTTPMetadataService(ts.URL, metadataHeaders, "/user-data", instanceIDPath, "/ssh-keys", dnsResolver, platform, logger)
+				metadataService = NewHTTPMetadataService(ts.URL, metadataHeaders, nil, "/user-data", instanceIDPath, "/ssh-keys", dnsResolver, platform, logger)
 			})
 
 			It("returns an empty instance ID", func() {
@@ -213,7 +213,7 @@ func describeHTTPMetadataService() {
 
 			handler := http.HandlerFunc(handlerFunc)
 			ts = httptest.NewServer(handler)
-			metadataService = NewHTTPMetadataService(ts.URL, metadataHeaders, "/user-data", "/instanceid", "/ssh-keys", dnsResolver, platform, logger)
+			metadataService = NewHTTPMetadataService(ts.URL, metadataHeaders, nil, "/user-data", "/instanceid", "/ssh-keys", dnsResolver, platform, logger)
 		})
 
 		AfterEach(func() {
@@ -285,7 +285,7 @@ func describeHTTPMetadataService() {
 
 			handler := http.HandlerFunc(handlerFunc)
 			ts = httptest.NewServer(handler)
-			metadataService = NewHTTPMetadataService(ts.URL, metadataHeaders, "/user-data", "/instanceid", "/ssh-keys", dnsResolver, platform, logger)
+			metadataService = NewHTTPMetadataService(ts.URL, metadataHeaders, nil, "/user-data", "/instanceid", "/ssh-keys", dnsResolver, platform, logger)
 		})
 
 		AfterEach(func() {
@@ -399,7 +399,7 @@ func describeHTTPMetadataService() {
 			It("returns the successfully resolved registry endpoint", func() {
 				handler := http.HandlerFunc(createHandlerFunc(9))
 				ts = httptest.NewServer(handler)
-				metadataService = NewHTTPMetadataServiceWithCustomRetryDelay(ts.URL, metadataHeaders, "/user-data", "/instanceid", "/ssh-keys", dnsResolver, platform, logger, 0*time.Second)
+				metadataService = NewHTTPMetadataServiceWithCustomRetryDelay(ts.URL, metadataHeaders, nil, "/user-data", "/instanceid", "/ssh-keys", dnsResolver, platform, logger, 0*time.Second)
 
 				endpoint, err := metadataService.GetRegistryEndpoint()
 				Expect(err).ToNot(HaveOccurred())
@@ -412,7 +412,7 @@ func describeHTTPMetadataService() {
 			It("returns an error containing the HTTP Response", func() {
 				handler := http.HandlerFunc(createHandlerFunc(10))
 				ts = httptest.NewServer(handler)
-				metadataService = NewHTTPMetadataServiceWithCustomRetryDelay(ts.URL, metadataHeaders, "/user-data", "/instanceid", "/ssh-keys", dnsResolver, platform, logger, 0*time.Second)
+				metadataService = NewHTTPMetadataServiceWithCustomRetryDelay(ts.URL, metadataHeaders, nil, "/user-data", "/instanceid", "/ssh-keys", dnsResolver, platform, logger, 0*time.Second)
 
 				_, err := metadataService.GetRegistryEndpoint()
 				Expect(err).ToNot(BeNil())
@@ -422,4 +422,136 @@ func describeHTTPMetadataService() {
 		})
 
 	})
+
+	Describe("session token", func() {
+		var (
+			ts             *httptest.Server
+			tokenOptions   *TokenOptions
+			tokenRequests  int
+			tokenStatus    int
+			rejectedTokens map[string]bool
+		)
+
+		BeforeEach(func() {
+			tokenOptions = &TokenOptions{
+				Path:      "/latest/api/token",
+				TTLHeader: "X-ttl-seconds",
+				Header:    "X-token",
+			}
+			tokenRequests = 0
+			tokenStatus = http.StatusOK
+			rejectedTokens = map[string]bool{}
+
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+
+				Expect(r.Header.Get("key")).To(Equal("value"))
+
+				if r.URL.Path == "/latest/api/token" {
+					Expect(r.Method).To(Equal("PUT"))
+
+					tokenRequests++
+					w.WriteHeader(tokenStatus)
+					w.Write([]byte(fmt.Sprintf("fake-token-%d-ttl-%s", tokenRequests, r.Header.Get("X-ttl-seconds"))))
+					return
+				}
+
+				Expect(r.Method).To(Equal("GET"))
+				Expect(r.URL.Path).To(Equal("/instanceid"))
+
+				token := r.Header.Get("X-token")
+				if rejectedTokens[token] {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+
+				w.Write([]byte("fake-instance-id:" + token))
+			})
+			ts = httptest.NewServer(handler)
+		})
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		buildMetadataService := func() {
+			metadataService = NewHTTPMetadataServiceWithCustomRetryDelay(ts.URL, metadataHeaders, tokenOptions, "/user-data", "/instanceid", "/ssh-keys", dnsResolver, platform, logger, 0*time.Second)
+		}
+
+		It("acquires token with default TTL and passes it with metadata requests", func() {
+			buildMetadataService()
+
+			instanceID, err := metadataService.GetInstanceID()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instanceID).To(Equal("fake-instance-id:fake-token-1-ttl-21600"))
+		})
+
+		It("reuses token for subsequent metadata requests", func() {
+			buildMetadataService()
+
+			_, err := metadataService.GetInstanceID()
+			Expect(err).ToNot(HaveOccurred())
+
+			instanceID, err := metadataService.GetInstanceID()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instanceID).To(Equal("fake-instance-id:fake-token-1-ttl-21600"))
+			Expect(tokenRequests).To(Equal(1))
+		})
+
+		It("acquires new token once most of token TTL has elapsed", func() {
+			tokenOptions.TTLInSeconds = 1
+			buildMetadataService()
+
+			_, err := metadataService.GetInstanceID()
+			Expect(err).ToNot(HaveOccurred())
+
+			time.Sleep(900 * time.Millisecond)
+
+			instanceID, err := metadataService.GetInstanceID()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instanceID).To(Equal("fake-instance-id:fake-token-2-ttl-1"))
+		})
+
+		It("acquires new token when metadata service rejects cached token", func() {
+			buildMetadataService()
+
+			_, err := metadataService.GetInstanceID()
+			Expect(err).ToNot(HaveOccurred())
+
+			rejectedTokens["fake-token-1-ttl-21600"] = true
+
+			instanceID, err := metadataService.GetInstanceID()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instanceID).To(Equal("fake-instance-id:fake-token-2-ttl-21600"))
+		})
+
+		Context("when token cannot be acquired", func() {
+			BeforeEach(func() {
+				tokenStatus = http.StatusForbidden
+			})
+
+			It("returns an error", func() {
+				buildMetadataService()
+
+				_, err := metadataService.GetInstanceID()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Acquiring metadata session token"))
+				Expect(err.Error()).To(ContainSubstring("returned status 403"))
+			})
+
+			Context("when token is optional", func() {
+				BeforeEach(func() {
+					tokenOptions.Optional = true
+				})
+
+				It("requests metadata without token", func() {
+					buildMetadataService()
+
+					instanceID, err := metadataService.GetInstanceID()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(instanceID).To(Equal("fake-instance-id:"))
+				})
+			})
+		})
+	})
 }
@@ -9,23 +9,32 @@ import (
 	boshplat "github.com/cloudfoundry/bosh-agent/platform"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshhttp "github.com/cloudfoundry/bosh-utils/http"
 )
 
 type httpRegistry struct {
 	metadataService   MetadataService
 	platform          boshplat.Platform
 	useServerNameAsID bool
+	client            boshhttp.Client
+	tokenProvider     RegistryTokenProvider
 }
 
+// NewHTTPRegistry returns registry fetching settings with given client;
+// requests carry bearer token when tokenProvider is not nil
 func NewHTTPRegistry(
 	metadataService MetadataService,
 	platform boshplat.Platform,
 	useServerNameAsID bool,
+	client boshhttp.Client,
+	tokenProvider RegistryTokenProvider,
 ) Registry {
 	return httpRegistry{
 		metadataService:   metadataService,
 		platform:          platform,
 		useServerNameAsID: useServerNameAsID,
+		client:            client,
+		tokenProvider:     tokenProvider,
 	}
 }
 
@@ -69,7 +78,21 @@ func (r httpRegistry) GetSettings() (boshsettings.Settings, error) {
 	}
 
 	settingsURL := fmt.Sprintf("%s/instances/%s/settings", registryEndpoint, identifier)
-	wrapperResponse, err := http.Get(settingsURL)
+	settingsRequest, err := http.NewRequest("GET", settingsURL, nil)
+	if err != nil {
+		return settings, bosherr.WrapError(err, "Building settings request")
+	}
+
+	if r.tokenProvider != nil {
+		token, err := r.tokenProvider.GetRegistryToken()
+		if err != nil {
+			return settings, bosherr.WrapError(err, "Getting registry token")
+		}
+
+		settingsRequest.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	wrapperResponse, err := r.client.Do(settingsRequest)
 	if err != nil {
 		return settings, bosherr.WrapError(err, "Getting settings from url")
 	}
@@ -78,6 +101,10 @@ func (r httpRegistry) GetSettings() (boshsettings.Settings, error) {
 		_ = wrapperResponse.Body.Close()
 	}()
 
+	if wrapperResponse.StatusCode != http.StatusOK {
+		return settings, bosherr.Errorf("Getting settings from url: registry responded with status %d", wrapperResponse.StatusCode)
+	}
+
 	wrapperBytes, err := ioutil.ReadAll(wrapperResponse.Body)
 	if err != nil {
 		return settings, bosherr.WrapError(err, "Reading settings response body")
@@ -28,7 +28,7 @@ func describeHTTPRegistry() {
 	BeforeEach(func() {
 		metadataService = &fakeinf.FakeMetadataService{}
 		platform = &fakeplat.FakePlatform{}
-		registry = NewHTTPRegistry(metadataService, platform, false)
+		registry = NewHTTPRegistry(metadataService, platform, false, http.DefaultClient, nil)
 	})
 
 	Describe("GetSettings", func() {
@@ -59,7 +59,7 @@ func describeHTTPRegistry() {
 				settingsJSON = `{"settings": "{\"agent_id\":\"my-agent-id\"}"}`
 				metadataService.InstanceID = "fake-identifier"
 				metadataService.RegistryEndpoint = ts.URL
-				registry = NewHTTPRegistry(metadataService, platform, false)
+				registry = NewHTTPRegistry(metadataService, platform, false, http.DefaultClient, nil)
 			})
 
 			Context("when the metadata has Networks information", func() {
@@ -120,7 +120,7 @@ func describeHTTPRegistry() {
 
 		Context("when registry is configured to not use server name as id", func() {
 			BeforeEach(func() {
-				registry = NewHTTPRegistry(metadataService, platform, false)
+				registry = NewHTTPRegistry(metadataService, platform, false, http.DefaultClient, nil)
 				metadataService.InstanceID = "fake-identifier"
 				metadataService.RegistryEndpoint = ts.URL
 			})
@@ -282,9 +282,53 @@ func describeHTTPRegistry() {
 			})
 		})
 
+		Context("when registry requires bearer token", func() {
+			var tokenProvider *fakeinf.FakeRegistryTokenProvider
+
+			BeforeEach(func() {
+				tokenProvider = &fakeinf.FakeRegistryTokenProvider{Token: "fake-registry-token"}
+				metadataService.InstanceID = "fake-identifier"
+				metadataService.RegistryEndpoint = ts.URL
+				settingsJSON = `{"settings": "{\"agent_id\":\"my-agent-id\"}"}`
+
+				ts.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.Header.Get("Authorization") != "Bearer fake-registry-token" {
+						w.WriteHeader(http.StatusUnauthorized)
+						return
+					}
+					w.Write([]byte(settingsJSON))
+				})
+			})
+
+			It("authorizes settings request with token", func() {
+				registry = NewHTTPRegistry(metadataService, platform, false, http.DefaultClient, tokenProvider)
+
+				settings, err := registry.GetSettings()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(settings).To(Equal(boshsettings.Settings{AgentID: "my-agent-id"}))
+			})
+
+			It("returns error if token cannot be obtained", func() {
+				tokenProvider.TokenErr = errors.New("fake-token-err")
+				registry = NewHTTPRegistry(metadataService, platform, false, http.DefaultClient, tokenProvider)
+
+				_, err := registry.GetSettings()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Getting registry token: fake-token-err"))
+			})
+
+			It("returns error if registry rejects request", func() {
+				registry = NewHTTPRegistry(metadataService, platform, false, http.DefaultClient, nil)
+
+				_, err := registry.GetSettings()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("registry responded with status 401"))
+			})
+		})
+
 		Context("when registry is configured to use server name as id", func() {
 			BeforeEach(func() {
-				registry = NewHTTPRegistry(metadataService, platform, true)
+				registry = NewHTTPRegistry(metadataService, platform, true, http.DefaultClient, nil)
 				metadataService.ServerName = "fake-identifier"
 				metadataService.RegistryEndpoint = ts.URL
 			})
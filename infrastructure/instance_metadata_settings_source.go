@@ -26,6 +26,7 @@ type InstanceMetadataSettingsSource struct {
 func NewInstanceMetadataSettingsSource(
 	metadataHost string,
 	metadataHeaders map[string]string,
+	tokenOptions *TokenOptions,
 	settingsPath string,
 	platform boshplatform.Platform,
 	logger boshlog.Logger,
@@ -42,13 +43,14 @@ func NewInstanceMetadataSettingsSource(
 		logTag: logTag,
 		// The HTTPMetadataService provides more functionality than we need (like custom DNS), so we
 		// pass zero values to the New function and only use its GetValueAtPath method.
-		metadataService: NewHTTPMetadataService(metadataHost, metadataHeaders, "", "", "", nil, platform, logger),
+		metadataService: NewHTTPMetadataService(metadataHost, metadataHeaders, tokenOptions, "", "", "", nil, platform, logger),
 	}
 }
 
 func NewInstanceMetadataSettingsSourceWithoutRetryDelay(
 	metadataHost string,
 	metadataHeaders map[string]string,
+	tokenOptions *TokenOptions,
 	settingsPath string,
 	platform boshplatform.Platform,
 	logger boshlog.Logger,
@@ -65,7 +67,7 @@ func NewInstanceMetadataSettingsSourceWithoutRetryDelay(
 		logTag: logTag,
 		// The HTTPMetadataService provides more functionality than we need (like custom DNS), so we
 		// pass zero values to the New function and only use its GetValueAtPath method.
-		metadataService: NewHTTPMetadataServiceWithCustomRetryDelay(metadataHost, metadataHeaders, "", "", "", nil, platform, logger, 0*time.Second),
+		metadataService: NewHTTPMetadataServiceWithCustomRetryDelay(metadataHost, metadataHeaders, tokenOptions, "", "", "", nil, platform, logger, 0*time.Second),
 	}
 }
 
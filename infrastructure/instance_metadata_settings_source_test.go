@@ -30,7 +30,7 @@ func describeInstanceMetadataSettingsSource() {
 		settingsPath = "/computeMetadata/v1/instance/attributes/bosh_settings"
 		platform = fakeplat.NewFakePlatform()
 		logger = boshlog.NewLogger(boshlog.LevelNone)
-		metadataSource = NewInstanceMetadataSettingsSource("http://fake-metadata-host", metadataHeaders, settingsPath, platform, logger)
+		metadataSource = NewInstanceMetadataSettingsSource("http://fake-metadata-host", metadataHeaders, nil, settingsPath, platform, logger)
 	})
 
 	Describe("PublicSSHKeyForUsername", func() {
@@ -63,7 +63,7 @@ func describeInstanceMetadataSettingsSource() {
 		BeforeEach(func() {
 			handler := http.HandlerFunc(handlerFunc)
 			ts = httptest.NewServer(handler)
-			metadataSource = NewInstanceMetadataSettingsSource(ts.URL, metadataHeaders, settingsPath, platform, logger)
+			metadataSource = NewInstanceMetadataSettingsSource(ts.URL, metadataHeaders, nil, settingsPath, platform, logger)
 		})
 
 		AfterEach(func() {
@@ -77,10 +77,39 @@ func describeInstanceMetadataSettingsSource() {
 		})
 
 		It("returns an error if reading from the instance metadata endpoint fails", func() {
-			metadataSource = NewInstanceMetadataSettingsSourceWithoutRetryDelay("bad-registry-endpoint", metadataHeaders, settingsPath, platform, logger)
+			metadataSource = NewInstanceMetadataSettingsSourceWithoutRetryDelay("bad-registry-endpoint", metadataHeaders, nil, settingsPath, platform, logger)
 			_, err := metadataSource.Settings()
 			Expect(err).To(HaveOccurred())
 		})
 
+		Context("when session token is configured", func() {
+			BeforeEach(func() {
+				ts.Close()
+
+				handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Path == "/token" {
+						w.Write([]byte("fake-token"))
+						return
+					}
+
+					if r.Header.Get("X-token") != "fake-token" {
+						w.WriteHeader(http.StatusUnauthorized)
+						return
+					}
+
+					handlerFunc(w, r)
+				})
+				ts = httptest.NewServer(handler)
+
+				tokenOptions := &TokenOptions{Path: "/token", Header: "X-token"}
+				metadataSource = NewInstanceMetadataSettingsSource(ts.URL, metadataHeaders, tokenOptions, settingsPath, platform, logger)
+			})
+
+			It("reads settings with session token", func() {
+				settings, err := metadataSource.Settings()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(settings.AgentID).To(Equal("123"))
+			})
+		})
 	})
 }
@@ -0,0 +1,194 @@
+package infrastructure
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshhttp "github.com/cloudfoundry/bosh-utils/http"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+const defaultMetadataTokenTTLInSeconds = 21600
+
+// TokenOptions configure session token every metadata request has to carry,
+// e.g. on AWS with IMDSv2: {"Path": "/latest/api/token",
+// "TTLHeader": "X-aws-ec2-metadata-token-ttl-seconds", "Header": "X-aws-ec2-metadata-token"}
+type TokenOptions struct {
+	// Path token is acquired from with PUT request
+	Path string
+
+	// Header token TTL is requested with
+	TTLHeader string
+
+	// Defaults to 6 hours
+	TTLInSeconds int
+
+	// Header token is passed to metadata requests in
+	Header string
+
+	// When set to true metadata is requested without token
+	// if token cannot be acquired, e.g. when token support is disabled
+	Optional bool
+}
+
+// metadataTokenProvider caches session token and
+// acquires new one once most of token TTL has elapsed
+type metadataTokenProvider struct {
+	metadataHost    string
+	metadataHeaders map[string]string
+	options         TokenOptions
+
+	token      string
+	refreshAt  time.Time
+	tokenLock  sync.Mutex
+	httpClient *http.Client
+
+	logTag string
+	logger boshlog.Logger
+}
+
+func newMetadataTokenProvider(
+	metadataHost string,
+	metadataHeaders map[string]string,
+	options TokenOptions,
+	logger boshlog.Logger,
+) *metadataTokenProvider {
+	return &metadataTokenProvider{
+		metadataHost:    metadataHost,
+		metadataHeaders: metadataHeaders,
+		options:         options,
+		httpClient:      &http.Client{},
+		logTag:          "metadataTokenProvider",
+		logger:          logger,
+	}
+}
+
+// AddToken sets token header on request; request is left
+// without token when token is optional and cannot be acquired
+func (p *metadataTokenProvider) AddToken(req *http.Request) error {
+	token, err := p.getToken()
+	if err != nil {
+		if p.options.Optional {
+			p.logger.Warn(p.logTag, "Requesting metadata without session token: %s", err.Error())
+			return nil
+		}
+		return err
+	}
+
+	req.Header.Set(p.options.Header, token)
+
+	return nil
+}
+
+// Invalidate forgets cached token, e.g. after metadata service rejected it
+func (p *metadataTokenProvider) Invalidate() {
+	p.tokenLock.Lock()
+	defer p.tokenLock.Unlock()
+
+	p.token = ""
+}
+
+func (p *metadataTokenProvider) getToken() (string, error) {
+	p.tokenLock.Lock()
+	defer p.tokenLock.Unlock()
+
+	if p.token != "" && time.Now().Before(p.refreshAt) {
+		return p.token, nil
+	}
+
+	ttl := p.ttlInSeconds()
+
+	token, err := p.acquireToken(ttl)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Acquiring metadata session token")
+	}
+
+	// Refresh after 80% of TTL so that token never expires in flight
+	p.token = token
+	p.refreshAt = time.Now().Add(time.Duration(ttl) * time.Second * 4 / 5)
+
+	return p.token, nil
+}
+
+func (p *metadataTokenProvider) acquireToken(ttl int) (string, error) {
+	url := fmt.Sprintf("%s%s", p.metadataHost, p.options.Path)
+
+	req, err := http.NewRequest("PUT", url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	for key, value := range p.metadataHeaders {
+		req.Header.Add(key, value)
+	}
+
+	if p.options.TTLHeader != "" {
+		req.Header.Set(p.options.TTLHeader, strconv.Itoa(ttl))
+	}
+
+	// Not retried here since every metadata request attempt acquires token when needed
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Requesting token from url %s", url)
+	}
+
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			p.logger.Warn(p.logTag, "Failed to close response body when acquiring token: %s", err.Error())
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", bosherr.Errorf("Requesting token from url %s returned status %d", url, resp.StatusCode)
+	}
+
+	bytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Reading token response body")
+	}
+
+	token := strings.TrimSpace(string(bytes))
+	if token == "" {
+		return "", bosherr.Errorf("Empty token returned from url %s", url)
+	}
+
+	return token, nil
+}
+
+// WrapClient returns client that adds token to every request attempt
+// so that token rejected by metadata service is replaced on retry
+func (p *metadataTokenProvider) WrapClient(client boshhttp.Client) boshhttp.Client {
+	return tokenClient{delegate: client, tokenProvider: p}
+}
+
+type tokenClient struct {
+	delegate      boshhttp.Client
+	tokenProvider *metadataTokenProvider
+}
+
+func (c tokenClient) Do(req *http.Request) (*http.Response, error) {
+	err := c.tokenProvider.AddToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.delegate.Do(req)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized {
+		c.tokenProvider.Invalidate()
+	}
+
+	return resp, err
+}
+
+func (p *metadataTokenProvider) ttlInSeconds() int {
+	if p.options.TTLInSeconds <= 0 {
+		return defaultMetadataTokenTTLInSeconds
+	}
+	return p.options.TTLInSeconds
+}
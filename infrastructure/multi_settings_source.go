@@ -59,3 +59,30 @@ func (s *MultiSettingsSource) Settings() (boshsettings.Settings, error) {
 	return boshsettings.Settings{},
 		bosherr.WrapError(err, "Getting settings from all sources")
 }
+
+// InstanceID asks source settings came from, otherwise
+// the first source which knows instance ID
+func (s *MultiSettingsSource) InstanceID() (string, error) {
+	sources := s.sources
+	if s.selectedSettingsSource != nil {
+		sources = []boshsettings.Source{s.selectedSettingsSource}
+	}
+
+	err := bosherr.Error("Settings sources do not provide instance ID")
+
+	for _, source := range sources {
+		instanceIDSource, ok := source.(boshsettings.InstanceIDSource)
+		if !ok {
+			continue
+		}
+
+		var instanceID string
+
+		instanceID, err = instanceIDSource.InstanceID()
+		if err == nil {
+			return instanceID, nil
+		}
+	}
+
+	return "", bosherr.WrapError(err, "Getting instance ID")
+}
@@ -121,5 +121,52 @@ var _ = Describe("MultiSettingsSource", func() {
 				})
 			})
 		})
+
+		Describe("InstanceID", func() {
+			BeforeEach(func() {
+				source1.InstanceIDValue = "fake-instance-id-1"
+				source2.InstanceIDValue = "fake-instance-id-2"
+			})
+
+			Context("when the first source fails to get instance ID", func() {
+				BeforeEach(func() {
+					source1.InstanceIDErr = errors.New("fake-instance-id-err-1")
+				})
+
+				It("returns instance ID from the second source", func() {
+					instanceID, err := source.(boshsettings.InstanceIDSource).InstanceID()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(instanceID).To(Equal("fake-instance-id-2"))
+				})
+			})
+
+			Context("when settings came from the second source", func() {
+				BeforeEach(func() {
+					source2.SettingsErr = nil
+				})
+
+				It("returns instance ID from the second source", func() {
+					_, err := source.Settings()
+					Expect(err).ToNot(HaveOccurred())
+
+					instanceID, err := source.(boshsettings.InstanceIDSource).InstanceID()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(instanceID).To(Equal("fake-instance-id-2"))
+				})
+			})
+
+			Context("when both sources fail to get instance ID", func() {
+				BeforeEach(func() {
+					source1.InstanceIDErr = errors.New("fake-instance-id-err-1")
+					source2.InstanceIDErr = errors.New("fake-instance-id-err-2")
+				})
+
+				It("returns error from the second source", func() {
+					_, err := source.(boshsettings.InstanceIDSource).InstanceID()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("fake-instance-id-err-2"))
+				})
+			})
+		})
 	})
 })
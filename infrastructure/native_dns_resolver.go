@@ -0,0 +1,231 @@
+package infrastructure
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+const nativeDNSResolverLogTag = "Native DNS Resolver"
+
+// NativeDNSResolver queries given DNS servers directly
+// instead of shelling out to dig like DigDNSResolver
+type NativeDNSResolver struct {
+	timeout time.Duration
+	logger  boshlog.Logger
+}
+
+func NewNativeDNSResolver(logger boshlog.Logger) NativeDNSResolver {
+	return NativeDNSResolver{
+		// Same as dig +time=1
+		timeout: 1 * time.Second,
+		logger:  logger,
+	}
+}
+
+func (res NativeDNSResolver) LookupHost(dnsServers []string, host string) (string, error) {
+	if host == "localhost" {
+		return "127.0.0.1", nil
+	}
+
+	ip := net.ParseIP(host)
+	if ip != nil {
+		return host, nil
+	}
+
+	var err error
+	var ipString string
+
+	if len(dnsServers) == 0 {
+		err = errors.New("No DNS servers provided")
+	}
+
+	for _, dnsServer := range dnsServers {
+		ipString, err = res.lookupHostWithDNSServer(dnsServer, host)
+		if err == nil {
+			return ipString, nil
+		}
+
+		res.logger.Debug(nativeDNSResolverLogTag, "Failed resolving '%s' with DNS server '%s': %s", host, dnsServer, err.Error())
+	}
+
+	return "", err
+}
+
+func (res NativeDNSResolver) lookupHostWithDNSServer(dnsServer string, host string) (string, error) {
+	dnsServerAddress := dnsServer
+	if _, _, err := net.SplitHostPort(dnsServer); err != nil {
+		dnsServerAddress = net.JoinHostPort(dnsServer, "53")
+	}
+
+	query, id, err := buildDNSQuery(host)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Resolving host")
+	}
+
+	conn, err := net.DialTimeout("udp", dnsServerAddress, res.timeout)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Resolving host")
+	}
+
+	defer conn.Close()
+
+	err = conn.SetDeadline(time.Now().Add(res.timeout))
+	if err != nil {
+		return "", bosherr.WrapError(err, "Resolving host")
+	}
+
+	_, err = conn.Write(query)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Resolving host")
+	}
+
+	response := make([]byte, dnsMaxUDPMessageLen)
+
+	n, err := conn.Read(response)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Resolving host")
+	}
+
+	ip, err := parseDNSResponse(response[:n], query, id)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Resolving host")
+	}
+
+	return ip.String(), nil
+}
+
+const (
+	dnsHeaderLen        = 12
+	dnsMaxUDPMessageLen = 512
+	dnsTypeA            = 1
+	dnsClassIN          = 1
+	dnsRcodeMask        = 0xf
+	dnsFlagResponse     = 0x8000
+	dnsFlagTruncated    = 0x0200
+)
+
+// buildDNSQuery builds recursive query for A record of host
+// (same as dig which only asks for A records); id is unpredictable
+// so that off-path responses cannot be easily spoofed
+func buildDNSQuery(host string) ([]byte, uint16, error) {
+	idBytes := make([]byte, 2)
+
+	_, err := rand.Read(idBytes)
+	if err != nil {
+		return nil, 0, bosherr.WrapError(err, "Generating DNS query id")
+	}
+
+	id := binary.BigEndian.Uint16(idBytes)
+
+	query := make([]byte, dnsHeaderLen)
+	binary.BigEndian.PutUint16(query[0:2], id)
+	binary.BigEndian.PutUint16(query[2:4], 0x0100) // Recursion desired
+	binary.BigEndian.PutUint16(query[4:6], 1)      // One question
+
+	for _, label := range strings.Split(strings.TrimSuffix(host, "."), ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return nil, 0, bosherr.Errorf("Invalid host name '%s'", host)
+		}
+		query = append(query, byte(len(label)))
+		query = append(query, label...)
+	}
+
+	query = append(query, 0, 0, dnsTypeA, 0, dnsClassIN)
+
+	return query, id, nil
+}
+
+// parseDNSResponse returns first A record found in the answer section
+// of response which echoes question of query
+func parseDNSResponse(response []byte, query []byte, id uint16) (net.IP, error) {
+	if len(response) < dnsHeaderLen {
+		return nil, errors.New("DNS response is too short")
+	}
+
+	if binary.BigEndian.Uint16(response[0:2]) != id {
+		return nil, errors.New("DNS response id does not match query")
+	}
+
+	flags := binary.BigEndian.Uint16(response[2:4])
+
+	if flags&dnsFlagResponse == 0 {
+		return nil, errors.New("DNS response is not a response")
+	}
+
+	// Answers of truncated responses may be incomplete
+	if flags&dnsFlagTruncated != 0 {
+		return nil, errors.New("DNS response is truncated")
+	}
+
+	rcode := flags & dnsRcodeMask
+	if rcode != 0 {
+		return nil, bosherr.Errorf("DNS server responded with rcode %d", rcode)
+	}
+
+	questions := int(binary.BigEndian.Uint16(response[4:6]))
+	answers := int(binary.BigEndian.Uint16(response[6:8]))
+
+	// Servers echo question as is although they may change case of names
+	question := query[dnsHeaderLen:]
+	offset := dnsHeaderLen + len(question)
+
+	if questions != 1 || offset > len(response) || !bytes.EqualFold(response[dnsHeaderLen:offset], question) {
+		return nil, errors.New("DNS response question does not match query")
+	}
+
+	for i := 0; i < answers; i++ {
+		nameEnd, err := skipDNSName(response, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		// Type, class, TTL and data length precede data
+		if nameEnd+10 > len(response) {
+			return nil, errors.New("DNS response is truncated")
+		}
+
+		rrType := binary.BigEndian.Uint16(response[nameEnd : nameEnd+2])
+		rrClass := binary.BigEndian.Uint16(response[nameEnd+2 : nameEnd+4])
+		dataLen := int(binary.BigEndian.Uint16(response[nameEnd+8 : nameEnd+10]))
+		dataStart := nameEnd + 10
+
+		if dataStart+dataLen > len(response) {
+			return nil, errors.New("DNS response is truncated")
+		}
+
+		if rrType == dnsTypeA && rrClass == dnsClassIN && dataLen == net.IPv4len {
+			return net.IP(response[dataStart : dataStart+dataLen]), nil
+		}
+
+		offset = dataStart + dataLen
+	}
+
+	return nil, errors.New("DNS response does not contain A record")
+}
+
+// skipDNSName returns offset right after name starting at offset;
+// compressed names end with a two byte pointer
+func skipDNSName(response []byte, offset int) (int, error) {
+	for offset < len(response) {
+		labelLen := int(response[offset])
+
+		switch {
+		case labelLen == 0:
+			return offset + 1, nil
+		case labelLen&0xc0 == 0xc0:
+			return offset + 2, nil
+		default:
+			offset += 1 + labelLen
+		}
+	}
+
+	return 0, errors.New("DNS response is truncated")
+}
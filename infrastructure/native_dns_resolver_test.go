@@ -0,0 +1,212 @@
+package infrastructure_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/infrastructure"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+var _ = Describe("NativeDNSResolver", func() {
+	var (
+		resolver  NativeDNSResolver
+		dnsServer *fakeDNSServer
+	)
+
+	BeforeEach(func() {
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		resolver = NewNativeDNSResolver(logger)
+		dnsServer = newFakeDNSServer(map[string]net.IP{
+			"fake-registry.example.com.": net.ParseIP("10.0.0.5"),
+		})
+	})
+
+	AfterEach(func() {
+		dnsServer.Close()
+	})
+
+	Describe("LookupHost", func() {
+		It("returns host when host is an ip", func() {
+			ip, err := resolver.LookupHost([]string{dnsServer.Address()}, "74.125.239.101")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ip).To(Equal("74.125.239.101"))
+			Expect(dnsServer.Queries()).To(BeZero())
+		})
+
+		It("returns 127.0.0.1 for 'localhost'", func() {
+			ip, err := resolver.LookupHost([]string{dnsServer.Address()}, "localhost")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ip).To(Equal("127.0.0.1"))
+		})
+
+		It("resolves host with given DNS server", func() {
+			ip, err := resolver.LookupHost([]string{dnsServer.Address()}, "fake-registry.example.com")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ip).To(Equal("10.0.0.5"))
+			Expect(dnsServer.Queries()).ToNot(BeZero())
+		})
+
+		It("tries next DNS server when previous one fails to resolve host", func() {
+			otherDNSServer := newFakeDNSServer(map[string]net.IP{})
+			defer otherDNSServer.Close()
+
+			ip, err := resolver.LookupHost(
+				[]string{otherDNSServer.Address(), dnsServer.Address()}, "fake-registry.example.com")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ip).To(Equal("10.0.0.5"))
+			Expect(otherDNSServer.Queries()).ToNot(BeZero())
+		})
+
+		It("returns error when no DNS server resolves host", func() {
+			_, err := resolver.LookupHost([]string{dnsServer.Address()}, "fake-unknown.example.com")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Resolving host"))
+		})
+
+		It("returns error when response question does not match host", func() {
+			dnsServer.tamper = func(response []byte) []byte {
+				// e.g. response spoofed for fake-registry.example.org
+				response[12+1+len("fake-registry.example.co")] = 'g'
+				return response
+			}
+
+			_, err := resolver.LookupHost([]string{dnsServer.Address()}, "fake-registry.example.com")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("DNS response question does not match query"))
+		})
+
+		It("accepts response question which only differs in case", func() {
+			dnsServer.tamper = func(response []byte) []byte {
+				return bytes.Replace(response, []byte("fake-registry"), []byte("FAKE-Registry"), 1)
+			}
+
+			ip, err := resolver.LookupHost([]string{dnsServer.Address()}, "fake-registry.example.com")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ip).To(Equal("10.0.0.5"))
+		})
+
+		It("returns error when response is truncated", func() {
+			dnsServer.tamper = func(response []byte) []byte {
+				response[2] |= 0x02
+				return response
+			}
+
+			_, err := resolver.LookupHost([]string{dnsServer.Address()}, "fake-registry.example.com")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("DNS response is truncated"))
+		})
+
+		It("returns error when no DNS servers are provided", func() {
+			_, err := resolver.LookupHost([]string{}, "fake-registry.example.com")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("No DNS servers provided"))
+		})
+	})
+})
+
+// fakeDNSServer answers A queries for known names and
+// responds with NXDOMAIN to everything else over UDP
+type fakeDNSServer struct {
+	conn    net.PacketConn
+	records map[string]net.IP
+	queries chan struct{}
+
+	// Modifies responses before they are sent
+	tamper func([]byte) []byte
+}
+
+func newFakeDNSServer(records map[string]net.IP) *fakeDNSServer {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	Expect(err).ToNot(HaveOccurred())
+
+	server := &fakeDNSServer{conn: conn, records: records, queries: make(chan struct{}, 100)}
+	go server.serve()
+
+	return server
+}
+
+func (s *fakeDNSServer) Address() string { return s.conn.LocalAddr().String() }
+
+func (s *fakeDNSServer) Queries() int { return len(s.queries) }
+
+func (s *fakeDNSServer) Close() { _ = s.conn.Close() }
+
+func (s *fakeDNSServer) serve() {
+	buf := make([]byte, 512)
+
+	for {
+		n, addr, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		select {
+		case s.queries <- struct{}{}:
+		default:
+		}
+
+		response := s.respond(buf[:n])
+		if response != nil && s.tamper != nil {
+			response = s.tamper(response)
+		}
+
+		if response != nil {
+			_, _ = s.conn.WriteTo(response, addr)
+		}
+	}
+}
+
+func (s *fakeDNSServer) respond(query []byte) []byte {
+	const headerLen = 12
+
+	if len(query) < headerLen {
+		return nil
+	}
+
+	// Question name is sequence of length prefixed labels
+	name := ""
+	offset := headerLen
+	for offset < len(query) && query[offset] != 0 {
+		labelLen := int(query[offset])
+		if offset+1+labelLen > len(query) {
+			return nil
+		}
+		name += string(query[offset+1:offset+1+labelLen]) + "."
+		offset += 1 + labelLen
+	}
+
+	questionEnd := offset + 1 + 4
+	if questionEnd > len(query) {
+		return nil
+	}
+
+	qType := binary.BigEndian.Uint16(query[offset+1 : offset+3])
+	ip, found := s.records[name]
+
+	response := make([]byte, headerLen)
+	copy(response, query[:2])
+
+	flags := uint16(0x8180)
+	if !found {
+		flags |= 3 // NXDOMAIN
+	}
+	binary.BigEndian.PutUint16(response[2:4], flags)
+	binary.BigEndian.PutUint16(response[4:6], 1)
+
+	response = append(response, query[headerLen:questionEnd]...)
+
+	if found && qType == 1 {
+		binary.BigEndian.PutUint16(response[6:8], 1)
+
+		answer := []byte{0xc0, headerLen, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4}
+		response = append(response, answer...)
+		response = append(response, ip.To4()...)
+	}
+
+	return response
+}
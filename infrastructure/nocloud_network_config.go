@@ -0,0 +1,268 @@
+package infrastructure
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// noCloudNetworkConfig covers subset of cloud-init networking config
+// versions 1 and 2 that can be expressed as agent networks;
+// config may be nested under top level "network" key
+type noCloudNetworkConfig struct {
+	Network *noCloudNetworkConfig `yaml:"network"`
+
+	Version int `yaml:"version"`
+
+	// Version 1
+	Config []noCloudV1Config `yaml:"config"`
+
+	// Version 2
+	Ethernets map[string]noCloudV2Ethernet `yaml:"ethernets"`
+}
+
+type noCloudV1Config struct {
+	Type       string          `yaml:"type"`
+	Name       string          `yaml:"name"`
+	MacAddress string          `yaml:"mac_address"`
+	Subnets    []noCloudSubnet `yaml:"subnets"`
+
+	// Set on nameserver type; either single address or list
+	Address interface{} `yaml:"address"`
+}
+
+type noCloudSubnet struct {
+	Type           string   `yaml:"type"`
+	Address        string   `yaml:"address"`
+	Netmask        string   `yaml:"netmask"`
+	Gateway        string   `yaml:"gateway"`
+	DNSNameservers []string `yaml:"dns_nameservers"`
+}
+
+type noCloudV2Ethernet struct {
+	Match struct {
+		MacAddress string `yaml:"macaddress"`
+	} `yaml:"match"`
+
+	DHCP4     bool     `yaml:"dhcp4"`
+	Addresses []string `yaml:"addresses"`
+	Gateway4  string   `yaml:"gateway4"`
+
+	Routes []struct {
+		To  string `yaml:"to"`
+		Via string `yaml:"via"`
+	} `yaml:"routes"`
+
+	Nameservers struct {
+		Addresses []string `yaml:"addresses"`
+	} `yaml:"nameservers"`
+}
+
+// parseNoCloudNetworkConfig translates network-config into agent networks
+// named after interfaces. Only first IPv4 configuration of each interface
+// is used since agent networks carry single address.
+func parseNoCloudNetworkConfig(contents []byte) (boshsettings.Networks, error) {
+	var config noCloudNetworkConfig
+
+	err := yaml.Unmarshal(contents, &config)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Unmarshalling network config")
+	}
+
+	if config.Network != nil {
+		config = *config.Network
+	}
+
+	var networks boshsettings.Networks
+	var nameservers []string
+
+	switch config.Version {
+	case 1:
+		networks, nameservers, err = noCloudV1Networks(config.Config)
+	case 2:
+		networks, err = noCloudV2Networks(config.Ethernets)
+	default:
+		err = bosherr.Errorf("Unsupported network config version %d", config.Version)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	setNoCloudDefaultNetwork(networks, nameservers)
+
+	return networks, nil
+}
+
+func noCloudV1Networks(configs []noCloudV1Config) (boshsettings.Networks, []string, error) {
+	networks := boshsettings.Networks{}
+	var nameservers []string
+
+	for _, config := range configs {
+		switch config.Type {
+		case "physical":
+			for _, subnet := range config.Subnets {
+				network, found, err := noCloudV1Network(subnet)
+				if err != nil {
+					return nil, nil, bosherr.WrapErrorf(err, "Translating interface '%s'", config.Name)
+				}
+
+				if found {
+					network.Mac = strings.ToLower(config.MacAddress)
+					networks[config.Name] = network
+					break
+				}
+			}
+
+		case "nameserver":
+			nameservers = append(nameservers, noCloudStrings(config.Address)...)
+		}
+	}
+
+	return networks, nameservers, nil
+}
+
+func noCloudV1Network(subnet noCloudSubnet) (boshsettings.Network, bool, error) {
+	switch subnet.Type {
+	case "dhcp", "dhcp4":
+		return boshsettings.Network{Type: boshsettings.NetworkTypeDynamic}, true, nil
+
+	case "static":
+		ip, netmask, err := noCloudAddress(subnet.Address, subnet.Netmask)
+		if err != nil || ip == "" {
+			return boshsettings.Network{}, false, err
+		}
+
+		return boshsettings.Network{
+			IP:      ip,
+			Netmask: netmask,
+			Gateway: subnet.Gateway,
+			DNS:     subnet.DNSNameservers,
+		}, true, nil
+	}
+
+	return boshsettings.Network{}, false, nil
+}
+
+func noCloudV2Networks(ethernets map[string]noCloudV2Ethernet) (boshsettings.Networks, error) {
+	networks := boshsettings.Networks{}
+
+	for name, ethernet := range ethernets {
+		network := boshsettings.Network{
+			Mac: strings.ToLower(ethernet.Match.MacAddress),
+			DNS: ethernet.Nameservers.Addresses,
+		}
+
+		if ethernet.DHCP4 {
+			network.Type = boshsettings.NetworkTypeDynamic
+			networks[name] = network
+			continue
+		}
+
+		for _, address := range ethernet.Addresses {
+			ip, netmask, err := noCloudAddress(address, "")
+			if err != nil {
+				return nil, bosherr.WrapErrorf(err, "Translating interface '%s'", name)
+			}
+
+			if ip != "" {
+				network.IP = ip
+				network.Netmask = netmask
+				break
+			}
+		}
+
+		if network.IP == "" {
+			continue
+		}
+
+		network.Gateway = ethernet.Gateway4
+		for _, route := range ethernet.Routes {
+			if network.Gateway == "" && (route.To == "default" || route.To == "0.0.0.0/0") {
+				network.Gateway = route.Via
+			}
+		}
+
+		networks[name] = network
+	}
+
+	return networks, nil
+}
+
+// noCloudAddress returns IPv4 address and netmask given either
+// in CIDR notation or separately; IPv6 addresses are skipped
+func noCloudAddress(address, netmask string) (string, string, error) {
+	if strings.Contains(address, "/") {
+		ip, ipNet, err := net.ParseCIDR(address)
+		if err != nil {
+			return "", "", bosherr.WrapErrorf(err, "Parsing address '%s'", address)
+		}
+
+		if ip.To4() == nil {
+			return "", "", nil
+		}
+
+		mask := ipNet.Mask
+		return ip.String(), fmt.Sprintf("%d.%d.%d.%d", mask[0], mask[1], mask[2], mask[3]), nil
+	}
+
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return "", "", bosherr.Errorf("Parsing address '%s'", address)
+	}
+
+	if ip.To4() == nil {
+		return "", "", nil
+	}
+
+	return ip.String(), netmask, nil
+}
+
+// setNoCloudDefaultNetwork marks first network with gateway as default
+// when there are several networks; global nameservers are added to it
+func setNoCloudDefaultNetwork(networks boshsettings.Networks, nameservers []string) {
+	var names []string
+	for name := range networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		network := networks[name]
+		if network.Gateway == "" && network.Type != boshsettings.NetworkTypeDynamic {
+			continue
+		}
+
+		if len(networks) > 1 {
+			network.Default = []string{"dns", "gateway"}
+		}
+		if len(network.DNS) == 0 {
+			network.DNS = nameservers
+		}
+
+		networks[name] = network
+		return
+	}
+}
+
+func noCloudStrings(value interface{}) []string {
+	switch typedValue := value.(type) {
+	case string:
+		return []string{typedValue}
+	case []interface{}:
+		var values []string
+		for _, v := range typedValue {
+			if str, ok := v.(string); ok {
+				values = append(values, str)
+			}
+		}
+		return values
+	}
+
+	return nil
+}
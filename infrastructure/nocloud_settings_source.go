@@ -0,0 +1,226 @@
+package infrastructure
+
+import (
+	"encoding/json"
+	"path"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+
+	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+const (
+	noCloudMetaDataFileName      = "meta-data"
+	noCloudUserDataFileName      = "user-data"
+	noCloudNetworkConfigFileName = "network-config"
+)
+
+// NoCloudSettingsSource reads settings laid out for cloud-init's NoCloud
+// datasource: agent settings are expected as JSON user-data and networks
+// are taken from network-config when settings do not specify any.
+type NoCloudSettingsSource struct {
+	diskPaths []string
+	seedDirs  []string
+
+	platform boshplatform.Platform
+
+	logTag string
+	logger boshlog.Logger
+}
+
+type noCloudContents struct {
+	metaData      []byte
+	userData      []byte
+	networkConfig []byte
+}
+
+type noCloudMetaData struct {
+	InstanceID string      `yaml:"instance-id"`
+	PublicKeys interface{} `yaml:"public-keys"`
+}
+
+func NewNoCloudSettingsSource(
+	diskPaths []string,
+	seedDirs []string,
+	platform boshplatform.Platform,
+	logger boshlog.Logger,
+) *NoCloudSettingsSource {
+	return &NoCloudSettingsSource{
+		diskPaths: diskPaths,
+		seedDirs:  seedDirs,
+
+		platform: platform,
+
+		logTag: "NoCloudSettingsSource",
+		logger: logger,
+	}
+}
+
+func (s *NoCloudSettingsSource) PublicSSHKeyForUsername(string) (string, error) {
+	metaData, err := s.loadMetaData()
+	if err != nil {
+		return "", err
+	}
+
+	return firstNoCloudPublicKey(metaData.PublicKeys), nil
+}
+
+func (s *NoCloudSettingsSource) InstanceID() (string, error) {
+	metaData, err := s.loadMetaData()
+	if err != nil {
+		return "", err
+	}
+
+	return metaData.InstanceID, nil
+}
+
+func (s *NoCloudSettingsSource) loadMetaData() (noCloudMetaData, error) {
+	var metaData noCloudMetaData
+
+	contents, err := s.loadContents()
+	if err != nil {
+		return metaData, err
+	}
+
+	err = yaml.Unmarshal(contents.metaData, &metaData)
+	if err != nil {
+		return metaData, bosherr.WrapError(err, "Parsing NoCloud meta-data")
+	}
+
+	return metaData, nil
+}
+
+func (s *NoCloudSettingsSource) Settings() (boshsettings.Settings, error) {
+	contents, err := s.loadContents()
+	if err != nil {
+		return boshsettings.Settings{}, err
+	}
+
+	var settings boshsettings.Settings
+	err = json.Unmarshal(contents.userData, &settings)
+	if err != nil {
+		return boshsettings.Settings{}, bosherr.WrapError(err, "Parsing NoCloud user-data as agent settings")
+	}
+
+	if len(settings.Networks) == 0 && len(contents.networkConfig) > 0 {
+		settings.Networks, err = parseNoCloudNetworkConfig(contents.networkConfig)
+		if err != nil {
+			return boshsettings.Settings{}, bosherr.WrapError(err, "Parsing NoCloud network-config")
+		}
+	}
+
+	return settings, nil
+}
+
+// loadContents prefers seed directories over disks, same as cloud-init;
+// network-config is optional since networks may come from settings or DHCP
+func (s *NoCloudSettingsSource) loadContents() (noCloudContents, error) {
+	var err error
+
+	for _, seedDir := range s.seedDirs {
+		var contents noCloudContents
+
+		contents, err = s.loadContentsFromSeedDir(seedDir)
+		if err == nil {
+			s.logger.Debug(s.logTag, "Successfully loaded NoCloud seed from directory '%s'", seedDir)
+			return contents, nil
+		}
+		s.logger.Debug(s.logTag, "Failed to load NoCloud seed from directory %s - %s", seedDir, err.Error())
+	}
+
+	for _, diskPath := range s.diskPaths {
+		var contents noCloudContents
+
+		contents, err = s.loadContentsFromDisk(diskPath)
+		if err == nil {
+			s.logger.Debug(s.logTag, "Successfully loaded NoCloud seed from disk '%s'", diskPath)
+			return contents, nil
+		}
+		s.logger.Warn(s.logTag, "Failed to load NoCloud seed from disk %s - %s", diskPath, err.Error())
+	}
+
+	if err == nil {
+		err = bosherr.Error("No seed directories or disks configured")
+	}
+
+	return noCloudContents{}, bosherr.WrapError(err, "Loading NoCloud seed")
+}
+
+func (s *NoCloudSettingsSource) loadContentsFromSeedDir(seedDir string) (noCloudContents, error) {
+	fs := s.platform.GetFs()
+
+	metaData, err := fs.ReadFile(path.Join(seedDir, noCloudMetaDataFileName))
+	if err != nil {
+		return noCloudContents{}, err
+	}
+
+	userData, err := fs.ReadFile(path.Join(seedDir, noCloudUserDataFileName))
+	if err != nil {
+		return noCloudContents{}, err
+	}
+
+	contents := noCloudContents{metaData: metaData, userData: userData}
+
+	networkConfigPath := path.Join(seedDir, noCloudNetworkConfigFileName)
+	if fs.FileExists(networkConfigPath) {
+		contents.networkConfig, err = fs.ReadFile(networkConfigPath)
+		if err != nil {
+			return noCloudContents{}, err
+		}
+	}
+
+	return contents, nil
+}
+
+func (s *NoCloudSettingsSource) loadContentsFromDisk(diskPath string) (noCloudContents, error) {
+	files, err := s.platform.GetFilesContentsFromDisk(
+		diskPath, []string{noCloudMetaDataFileName, noCloudUserDataFileName})
+	if err != nil {
+		return noCloudContents{}, err
+	}
+
+	contents := noCloudContents{metaData: files[0], userData: files[1]}
+
+	networkConfig, err := s.platform.GetFilesContentsFromDisk(diskPath, []string{noCloudNetworkConfigFileName})
+	if err != nil {
+		s.logger.Debug(s.logTag, "No network-config found on disk %s - %s", diskPath, err.Error())
+	} else {
+		contents.networkConfig = networkConfig[0]
+	}
+
+	return contents, nil
+}
+
+// firstNoCloudPublicKey handles public keys given as a single
+// string, a list of strings or a map of names to keys
+func firstNoCloudPublicKey(publicKeys interface{}) string {
+	switch keys := publicKeys.(type) {
+	case string:
+		return keys
+	case []interface{}:
+		for _, key := range keys {
+			if keyStr, ok := key.(string); ok {
+				return keyStr
+			}
+		}
+	case map[interface{}]interface{}:
+		var names []string
+		for name := range keys {
+			if nameStr, ok := name.(string); ok {
+				names = append(names, nameStr)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if keyStr, ok := keys[name].(string); ok {
+				return keyStr
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,246 @@
+package infrastructure_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/infrastructure"
+	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+var _ = Describe("NoCloudSettingsSource", func() {
+	var (
+		platform *fakeplatform.FakePlatform
+		source   *NoCloudSettingsSource
+	)
+
+	BeforeEach(func() {
+		platform = fakeplatform.NewFakePlatform()
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		source = NewNoCloudSettingsSource(
+			[]string{"/fake-disk-path-1", "/fake-disk-path-2"},
+			[]string{"/fake-seed-dir"},
+			platform,
+			logger,
+		)
+	})
+
+	BeforeEach(func() {
+		platform.SetGetFilesContentsFromDisk("/fake-disk-path-1/meta-data", []byte("instance-id: fake-instance-id"), nil)
+		platform.SetGetFilesContentsFromDisk("/fake-disk-path-1/user-data", []byte(`{"agent_id": "fake-agent-id"}`), nil)
+		platform.SetGetFilesContentsFromDisk("/fake-disk-path-1/network-config", nil, errors.New("fake-not-found-error"))
+	})
+
+	Describe("PublicSSHKeyForUsername", func() {
+		It("returns first public key from list in meta-data", func() {
+			platform.SetGetFilesContentsFromDisk("/fake-disk-path-1/meta-data", []byte(`
+instance-id: fake-instance-id
+public-keys:
+  - fake-public-key-1
+  - fake-public-key-2
+`), nil)
+
+			publicKey, err := source.PublicSSHKeyForUsername("fake-username")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(publicKey).To(Equal("fake-public-key-1"))
+		})
+
+		It("returns public key given as string in meta-data", func() {
+			platform.SetGetFilesContentsFromDisk("/fake-disk-path-1/meta-data", []byte(`public-keys: fake-public-key`), nil)
+
+			publicKey, err := source.PublicSSHKeyForUsername("fake-username")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(publicKey).To(Equal("fake-public-key"))
+		})
+
+		It("returns an empty string when meta-data does not contain public keys", func() {
+			publicKey, err := source.PublicSSHKeyForUsername("fake-username")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(publicKey).To(Equal(""))
+		})
+
+		It("returns an error if meta-data cannot be parsed", func() {
+			platform.SetGetFilesContentsFromDisk("/fake-disk-path-1/meta-data", []byte(`- [`), nil)
+
+			_, err := source.PublicSSHKeyForUsername("fake-username")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Parsing NoCloud meta-data"))
+		})
+	})
+
+	Describe("InstanceID", func() {
+		It("returns instance ID from meta-data", func() {
+			instanceID, err := source.InstanceID()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instanceID).To(Equal("fake-instance-id"))
+		})
+	})
+
+	Describe("Settings", func() {
+		It("returns settings read from user-data on the first readable disk", func() {
+			platform.SetGetFilesContentsFromDisk("/fake-disk-path-1/meta-data", nil, errors.New("fake-read-disk-error"))
+			platform.SetGetFilesContentsFromDisk("/fake-disk-path-2/meta-data", []byte("instance-id: fake-instance-id"), nil)
+			platform.SetGetFilesContentsFromDisk("/fake-disk-path-2/user-data", []byte(`{"agent_id": "fake-agent-id-2"}`), nil)
+			platform.SetGetFilesContentsFromDisk("/fake-disk-path-2/network-config", nil, errors.New("fake-not-found-error"))
+
+			settings, err := source.Settings()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(settings).To(Equal(boshsettings.Settings{AgentID: "fake-agent-id-2"}))
+		})
+
+		It("prefers seed directory over disks", func() {
+			platform.Fs.WriteFileString("/fake-seed-dir/meta-data", "instance-id: fake-instance-id")
+			platform.Fs.WriteFileString("/fake-seed-dir/user-data", `{"agent_id": "fake-seed-agent-id"}`)
+
+			settings, err := source.Settings()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(settings.AgentID).To(Equal("fake-seed-agent-id"))
+			Expect(platform.GetFileContentsFromDiskCalledTimes).To(Equal(0))
+		})
+
+		It("returns an error if seed cannot be loaded from any location", func() {
+			platform.SetGetFilesContentsFromDisk("/fake-disk-path-1/meta-data", nil, errors.New("fake-read-disk-error-1"))
+			platform.SetGetFilesContentsFromDisk("/fake-disk-path-2/meta-data", nil, errors.New("fake-read-disk-error-2"))
+
+			_, err := source.Settings()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Loading NoCloud seed"))
+			Expect(err.Error()).To(ContainSubstring("fake-read-disk-error-2"))
+		})
+
+		It("returns an error if user-data is not agent settings", func() {
+			platform.SetGetFilesContentsFromDisk("/fake-disk-path-1/user-data", []byte("#cloud-config"), nil)
+
+			_, err := source.Settings()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Parsing NoCloud user-data as agent settings"))
+		})
+
+		Context("when network-config is version 1", func() {
+			BeforeEach(func() {
+				platform.SetGetFilesContentsFromDisk("/fake-disk-path-1/network-config", []byte(`
+version: 1
+config:
+  - type: physical
+    name: eth0
+    mac_address: "52:54:00:AB:CD:01"
+    subnets:
+      - type: static6
+        address: "fd00::10/64"
+      - type: static
+        address: 10.0.0.10/24
+        gateway: 10.0.0.1
+        dns_nameservers: [10.0.0.2]
+  - type: physical
+    name: eth1
+    mac_address: "52:54:00:ab:cd:02"
+    subnets:
+      - type: static
+        address: 192.168.1.10
+        netmask: 255.255.255.0
+  - type: physical
+    name: eth2
+    subnets:
+      - type: dhcp6
+  - type: nameserver
+    address: [8.8.8.8]
+`), nil)
+			})
+
+			It("returns networks translated from network-config", func() {
+				settings, err := source.Settings()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(settings.Networks).To(Equal(boshsettings.Networks{
+					"eth0": boshsettings.Network{
+						IP:      "10.0.0.10",
+						Netmask: "255.255.255.0",
+						Gateway: "10.0.0.1",
+						DNS:     []string{"10.0.0.2"},
+						Default: []string{"dns", "gateway"},
+						Mac:     "52:54:00:ab:cd:01",
+					},
+					"eth1": boshsettings.Network{
+						IP:      "192.168.1.10",
+						Netmask: "255.255.255.0",
+						Mac:     "52:54:00:ab:cd:02",
+					},
+				}))
+			})
+
+			It("keeps networks specified in user-data", func() {
+				platform.SetGetFilesContentsFromDisk("/fake-disk-path-1/user-data",
+					[]byte(`{"networks": {"default": {"type": "dynamic"}}}`), nil)
+
+				settings, err := source.Settings()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(settings.Networks).To(Equal(boshsettings.Networks{
+					"default": boshsettings.Network{Type: boshsettings.NetworkTypeDynamic},
+				}))
+			})
+		})
+
+		Context("when network-config is version 2", func() {
+			BeforeEach(func() {
+				platform.SetGetFilesContentsFromDisk("/fake-disk-path-1/network-config", []byte(`
+network:
+  version: 2
+  ethernets:
+    ens3:
+      match:
+        macaddress: "52:54:00:ab:cd:01"
+      addresses: [10.0.0.10/24]
+      routes:
+        - to: default
+          via: 10.0.0.1
+      nameservers:
+        addresses: [10.0.0.2]
+    ens4:
+      dhcp4: true
+`), nil)
+			})
+
+			It("returns networks translated from network-config", func() {
+				settings, err := source.Settings()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(settings.Networks).To(Equal(boshsettings.Networks{
+					"ens3": boshsettings.Network{
+						IP:      "10.0.0.10",
+						Netmask: "255.255.255.0",
+						Gateway: "10.0.0.1",
+						DNS:     []string{"10.0.0.2"},
+						Default: []string{"dns", "gateway"},
+						Mac:     "52:54:00:ab:cd:01",
+					},
+					"ens4": boshsettings.Network{
+						Type: boshsettings.NetworkTypeDynamic,
+					},
+				}))
+			})
+		})
+
+		It("returns an error if network-config version is not supported", func() {
+			platform.SetGetFilesContentsFromDisk("/fake-disk-path-1/network-config", []byte("version: 3"), nil)
+
+			_, err := source.Settings()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unsupported network config version 3"))
+		})
+
+		It("returns an error if network-config contains invalid address", func() {
+			platform.SetGetFilesContentsFromDisk("/fake-disk-path-1/network-config", []byte(`
+version: 2
+ethernets:
+  ens3:
+    addresses: [not-an-address/24]
+`), nil)
+
+			_, err := source.Settings()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Translating interface 'ens3'"))
+		})
+	})
+})
@@ -0,0 +1,152 @@
+package infrastructure
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshhttp "github.com/cloudfoundry/bosh-utils/http"
+)
+
+// RegistryTokenProvider provides bearer token registry requests are authorized with
+type RegistryTokenProvider interface {
+	GetRegistryToken() (string, error)
+}
+
+// NewRegistryHTTPClient returns client which verifies registry against
+// configured CA certificates and presents client certificate if one is configured.
+// Returned resolver is used to resolve registry endpoint with nameservers from user data;
+// with TLS configured endpoint keeps its hostname so that registry certificate is
+// verified against it and hostname is resolved only when client connects.
+func NewRegistryHTTPClient(options RegistryOptions, dnsResolver DNSResolver) (boshhttp.Client, DNSResolver, error) {
+	if len(options.CACertificate) == 0 && len(options.ClientCertificate) == 0 {
+		return http.DefaultClient, NewRegistryEndpointResolver(dnsResolver), nil
+	}
+
+	tlsConfig := &tls.Config{}
+
+	if len(options.CACertificate) > 0 {
+		certPool := x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM([]byte(options.CACertificate)) {
+			return nil, nil, bosherr.Error("Parsing registry CA certificate")
+		}
+		tlsConfig.RootCAs = certPool
+	}
+
+	if len(options.ClientCertificate) > 0 {
+		clientCertificate, err := tls.X509KeyPair([]byte(options.ClientCertificate), []byte(options.ClientPrivateKey))
+		if err != nil {
+			return nil, nil, bosherr.WrapError(err, "Parsing registry client certificate")
+		}
+		tlsConfig.Certificates = []tls.Certificate{clientCertificate}
+	}
+
+	hostResolver := &registryHostResolver{
+		delegate: dnsResolver,
+		dialer: &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		},
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			DialContext:     hostResolver.DialContext,
+			TLSClientConfig: tlsConfig,
+		},
+	}
+
+	return client, hostResolver, nil
+}
+
+// registryHostResolver leaves registry endpoint as is and remembers nameservers
+// registry hostname is resolved with when connecting to registry
+type registryHostResolver struct {
+	delegate DNSResolver
+	dialer   *net.Dialer
+
+	dnsServers     []string
+	dnsServersLock sync.Mutex
+}
+
+func (r *registryHostResolver) LookupHost(dnsServers []string, endpoint string) (string, error) {
+	_, err := url.Parse(endpoint)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Parsing registry named endpoint")
+	}
+
+	r.dnsServersLock.Lock()
+	r.dnsServers = dnsServers
+	r.dnsServersLock.Unlock()
+
+	return endpoint, nil
+}
+
+func (r *registryHostResolver) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Splitting registry address '%s'", address)
+	}
+
+	r.dnsServersLock.Lock()
+	dnsServers := r.dnsServers
+	r.dnsServersLock.Unlock()
+
+	if len(dnsServers) > 0 && net.ParseIP(host) == nil {
+		ip, err := r.delegate.LookupHost(dnsServers, host)
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Looking up registry")
+		}
+
+		address = net.JoinHostPort(ip, port)
+	}
+
+	return r.dialer.DialContext(ctx, network, address)
+}
+
+type metadataRegistryTokenProvider struct {
+	metadataService DynamicMetadataService
+	path            string
+}
+
+// NewMetadataRegistryTokenProvider returns provider reading token at path of
+// instance metadata. Token is expected either as plain text or as JSON
+// with 'access_token' key as returned by service account token endpoints.
+func NewMetadataRegistryTokenProvider(metadataService DynamicMetadataService, path string) RegistryTokenProvider {
+	return metadataRegistryTokenProvider{
+		metadataService: metadataService,
+		path:            path,
+	}
+}
+
+func (p metadataRegistryTokenProvider) GetRegistryToken() (string, error) {
+	contents, err := p.metadataService.GetValueAtPath(p.path)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Reading registry token from instance metadata")
+	}
+
+	var tokenResponse struct {
+		AccessToken string `json:"access_token"`
+	}
+
+	err = json.Unmarshal([]byte(contents), &tokenResponse)
+	if err == nil && len(tokenResponse.AccessToken) > 0 {
+		return tokenResponse.AccessToken, nil
+	}
+
+	token := strings.TrimSpace(contents)
+	if len(token) == 0 {
+		return "", bosherr.Error("Registry token is empty")
+	}
+
+	return token, nil
+}
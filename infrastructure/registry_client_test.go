@@ -0,0 +1,346 @@
+package infrastructure_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/infrastructure"
+	fakeinf "github.com/cloudfoundry/bosh-agent/infrastructure/fakes"
+	fakeplat "github.com/cloudfoundry/bosh-agent/platform/fakes"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+var _ = Describe("NewRegistryHTTPClient", func() {
+	It("returns default client when neither CA nor client certificate is configured", func() {
+		dnsResolver := &fakeinf.FakeDNSResolver{}
+		dnsResolver.RegisterRecord(fakeinf.FakeDNSRecord{
+			DNSServers: []string{"fake-dns-server-ip"},
+			Host:       "fake-registry.com",
+			IP:         "fake-registry-ip",
+		})
+
+		client, resolver, err := NewRegistryHTTPClient(RegistryOptions{}, dnsResolver)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(client).To(Equal(http.DefaultClient))
+
+		endpoint, err := resolver.LookupHost([]string{"fake-dns-server-ip"}, "http://fake-registry.com:25777")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(endpoint).To(Equal("http://fake-registry-ip:25777"))
+	})
+
+	Context("when registry requires client certificate", func() {
+		var (
+			ca           *testCertificateAuthority
+			ts           *httptest.Server
+			clientCert   string
+			clientKey    string
+			unknownCA    *testCertificateAuthority
+			unknownCert  string
+			unknownKey   string
+			requestCount int
+		)
+
+		BeforeEach(func() {
+			ca = newTestCertificateAuthority()
+			unknownCA = newTestCertificateAuthority()
+			requestCount = 0
+
+			serverCert, serverKey := ca.Issue("127.0.0.1", x509.ExtKeyUsageServerAuth)
+			clientCert, clientKey = ca.Issue("fake-agent", x509.ExtKeyUsageClientAuth)
+			unknownCert, unknownKey = unknownCA.Issue("fake-agent", x509.ExtKeyUsageClientAuth)
+
+			serverKeyPair, err := tls.X509KeyPair([]byte(serverCert), []byte(serverKey))
+			Expect(err).ToNot(HaveOccurred())
+
+			ts = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requestCount++
+				w.Write([]byte("fake-response"))
+			}))
+			ts.TLS = &tls.Config{
+				Certificates: []tls.Certificate{serverKeyPair},
+				ClientCAs:    ca.Pool(),
+				ClientAuth:   tls.RequireAndVerifyClientCert,
+			}
+			ts.StartTLS()
+		})
+
+		AfterEach(func() {
+			ts.Close()
+		})
+
+		It("verifies registry with configured CA and presents client certificate", func() {
+			client, _, err := NewRegistryHTTPClient(RegistryOptions{
+				CACertificate:     ca.CertificatePEM,
+				ClientCertificate: clientCert,
+				ClientPrivateKey:  clientKey,
+			}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			req, err := http.NewRequest("GET", ts.URL, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			resp, err := client.Do(req)
+			Expect(err).ToNot(HaveOccurred())
+			resp.Body.Close()
+
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(requestCount).To(Equal(1))
+		})
+
+		It("fails when registry certificate is not signed by configured CA", func() {
+			client, _, err := NewRegistryHTTPClient(RegistryOptions{
+				CACertificate:     unknownCA.CertificatePEM,
+				ClientCertificate: clientCert,
+				ClientPrivateKey:  clientKey,
+			}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			req, err := http.NewRequest("GET", ts.URL, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = client.Do(req)
+			Expect(err).To(HaveOccurred())
+			Expect(requestCount).To(BeZero())
+		})
+
+		It("is rejected when client certificate is not trusted by registry", func() {
+			client, _, err := NewRegistryHTTPClient(RegistryOptions{
+				CACertificate:     ca.CertificatePEM,
+				ClientCertificate: unknownCert,
+				ClientPrivateKey:  unknownKey,
+			}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			req, err := http.NewRequest("GET", ts.URL, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = client.Do(req)
+			Expect(err).To(HaveOccurred())
+			Expect(requestCount).To(BeZero())
+		})
+
+		Context("when registry hostname is resolved with nameservers", func() {
+			var (
+				dnsResolver *fakeinf.FakeDNSResolver
+				endpoint    string
+			)
+
+			BeforeEach(func() {
+				serverCert, serverKey := ca.Issue("fake-registry.com", x509.ExtKeyUsageServerAuth)
+
+				serverKeyPair, err := tls.X509KeyPair([]byte(serverCert), []byte(serverKey))
+				Expect(err).ToNot(HaveOccurred())
+
+				ts.TLS.Certificates = []tls.Certificate{serverKeyPair}
+
+				_, port, err := net.SplitHostPort(ts.Listener.Addr().String())
+				Expect(err).ToNot(HaveOccurred())
+
+				endpoint = "https://fake-registry.com:" + port
+
+				dnsResolver = &fakeinf.FakeDNSResolver{}
+				dnsResolver.RegisterRecord(fakeinf.FakeDNSRecord{
+					DNSServers: []string{"fake-dns-server-ip"},
+					Host:       "fake-registry.com",
+					IP:         "127.0.0.1",
+				})
+			})
+
+			It("keeps hostname in endpoint so that registry certificate is verified against it", func() {
+				client, resolver, err := NewRegistryHTTPClient(RegistryOptions{
+					CACertificate:     ca.CertificatePEM,
+					ClientCertificate: clientCert,
+					ClientPrivateKey:  clientKey,
+				}, dnsResolver)
+				Expect(err).ToNot(HaveOccurred())
+
+				resolvedEndpoint, err := resolver.LookupHost([]string{"fake-dns-server-ip"}, endpoint)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resolvedEndpoint).To(Equal(endpoint))
+
+				req, err := http.NewRequest("GET", resolvedEndpoint, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				resp, err := client.Do(req)
+				Expect(err).ToNot(HaveOccurred())
+				resp.Body.Close()
+
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				Expect(requestCount).To(Equal(1))
+			})
+
+			It("fails to connect when registry hostname cannot be resolved", func() {
+				dnsResolver.LookupHostErr = errors.New("fake-lookup-host-err")
+
+				client, resolver, err := NewRegistryHTTPClient(RegistryOptions{
+					CACertificate:     ca.CertificatePEM,
+					ClientCertificate: clientCert,
+					ClientPrivateKey:  clientKey,
+				}, dnsResolver)
+				Expect(err).ToNot(HaveOccurred())
+
+				resolvedEndpoint, err := resolver.LookupHost([]string{"fake-dns-server-ip"}, endpoint)
+				Expect(err).ToNot(HaveOccurred())
+
+				req, err := http.NewRequest("GET", resolvedEndpoint, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = client.Do(req)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-lookup-host-err"))
+				Expect(requestCount).To(BeZero())
+			})
+		})
+	})
+
+	It("returns error when CA certificate cannot be parsed", func() {
+		_, _, err := NewRegistryHTTPClient(RegistryOptions{CACertificate: "fake-ca-certificate"}, nil)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("Parsing registry CA certificate"))
+	})
+
+	It("returns error when client certificate cannot be parsed", func() {
+		_, _, err := NewRegistryHTTPClient(RegistryOptions{
+			ClientCertificate: "fake-client-certificate",
+			ClientPrivateKey:  "fake-client-private-key",
+		}, nil)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Parsing registry client certificate"))
+	})
+})
+
+var _ = Describe("metadataRegistryTokenProvider", func() {
+	var (
+		ts           *httptest.Server
+		tokenContent string
+		provider     RegistryTokenProvider
+	)
+
+	BeforeEach(func() {
+		ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			GinkgoRecover()
+
+			Expect(r.URL.Path).To(Equal("/fake-token-path"))
+			Expect(r.Header.Get("Metadata-Flavor")).To(Equal("Google"))
+
+			w.Write([]byte(tokenContent))
+		}))
+
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		platform := fakeplat.NewFakePlatform()
+		metadataService := NewHTTPMetadataService(
+			ts.URL, map[string]string{"Metadata-Flavor": "Google"}, nil, "", "", "", nil, platform, logger)
+
+		provider = NewMetadataRegistryTokenProvider(metadataService, "/fake-token-path")
+	})
+
+	AfterEach(func() {
+		ts.Close()
+	})
+
+	It("returns plain text token", func() {
+		tokenContent = "fake-token\n"
+
+		token, err := provider.GetRegistryToken()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(token).To(Equal("fake-token"))
+	})
+
+	It("returns access token from JSON token response", func() {
+		tokenContent = `{"access_token":"fake-token","expires_in":3599,"token_type":"Bearer"}`
+
+		token, err := provider.GetRegistryToken()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(token).To(Equal("fake-token"))
+	})
+
+	It("returns error when token is empty", func() {
+		tokenContent = ""
+
+		_, err := provider.GetRegistryToken()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("Registry token is empty"))
+	})
+})
+
+type testCertificateAuthority struct {
+	CertificatePEM string
+
+	certificate *x509.Certificate
+	privateKey  *rsa.PrivateKey
+}
+
+func newTestCertificateAuthority() *testCertificateAuthority {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	Expect(err).ToNot(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fake-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	Expect(err).ToNot(HaveOccurred())
+
+	certificate, err := x509.ParseCertificate(der)
+	Expect(err).ToNot(HaveOccurred())
+
+	return &testCertificateAuthority{
+		CertificatePEM: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		certificate:    certificate,
+		privateKey:     privateKey,
+	}
+}
+
+func (ca *testCertificateAuthority) Pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.certificate)
+	return pool
+}
+
+// Issue returns PEM encoded certificate and private key
+func (ca *testCertificateAuthority) Issue(commonName string, usage x509.ExtKeyUsage) (string, string) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	Expect(err).ToNot(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+
+	if ip := net.ParseIP(commonName); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	} else {
+		template.DNSNames = []string{commonName}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.certificate, &privateKey.PublicKey, ca.privateKey)
+	Expect(err).ToNot(HaveOccurred())
+
+	certificatePEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	privateKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+
+	return string(certificatePEM), string(privateKeyPEM)
+}
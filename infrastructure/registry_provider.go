@@ -5,6 +5,7 @@ import (
 
 	boshplat "github.com/cloudfoundry/bosh-agent/platform"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshhttp "github.com/cloudfoundry/bosh-utils/http"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 )
@@ -17,6 +18,8 @@ type registryProvider struct {
 	metadataService MetadataService
 	useServerName   bool
 	platform        boshplat.Platform
+	httpClient      boshhttp.Client
+	tokenProvider   RegistryTokenProvider
 	fs              boshsys.FileSystem
 	logTag          string
 	logger          boshlog.Logger
@@ -26,6 +29,8 @@ func NewRegistryProvider(
 	metadataService MetadataService,
 	platform boshplat.Platform,
 	useServerName bool,
+	httpClient boshhttp.Client,
+	tokenProvider RegistryTokenProvider,
 	fs boshsys.FileSystem,
 	logger boshlog.Logger,
 ) RegistryProvider {
@@ -33,6 +38,8 @@ func NewRegistryProvider(
 		metadataService: metadataService,
 		platform:        platform,
 		useServerName:   useServerName,
+		httpClient:      httpClient,
+		tokenProvider:   tokenProvider,
 		fs:              fs,
 		logTag:          "registryProvider",
 		logger:          logger,
@@ -47,7 +54,7 @@ func (p *registryProvider) GetRegistry() (Registry, error) {
 
 	if strings.HasPrefix(registryEndpoint, "http") {
 		p.logger.Debug(p.logTag, "Using http registry at %s", registryEndpoint)
-		return NewHTTPRegistry(p.metadataService, p.platform, p.useServerName), nil
+		return NewHTTPRegistry(p.metadataService, p.platform, p.useServerName, p.httpClient, p.tokenProvider), nil
 	}
 
 	p.logger.Debug(p.logTag, "Using file registry at %s", registryEndpoint)
@@ -2,6 +2,7 @@ package infrastructure_test
 
 import (
 	"errors"
+	"net/http"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -31,7 +32,7 @@ var _ = Describe("RegistryProvider", func() {
 
 	JustBeforeEach(func() {
 		logger := boshlog.NewLogger(boshlog.LevelNone)
-		registryProvider = NewRegistryProvider(metadataService, platform, useServerName, fs, logger)
+		registryProvider = NewRegistryProvider(metadataService, platform, useServerName, http.DefaultClient, nil, fs, logger)
 	})
 
 	Describe("GetRegistry", func() {
@@ -46,7 +47,7 @@ var _ = Describe("RegistryProvider", func() {
 				It("returns an http registry that does not use server name as id", func() {
 					registry, err := registryProvider.GetRegistry()
 					Expect(err).ToNot(HaveOccurred())
-					Expect(registry).To(Equal(NewHTTPRegistry(metadataService, platform, false)))
+					Expect(registry).To(Equal(NewHTTPRegistry(metadataService, platform, false, http.DefaultClient, nil)))
 				})
 			})
 
@@ -56,7 +57,7 @@ var _ = Describe("RegistryProvider", func() {
 				It("returns an http registry that uses server name as id", func() {
 					registry, err := registryProvider.GetRegistry()
 					Expect(err).ToNot(HaveOccurred())
-					Expect(registry).To(Equal(NewHTTPRegistry(metadataService, platform, true)))
+					Expect(registry).To(Equal(NewHTTPRegistry(metadataService, platform, true, http.DefaultClient, nil)))
 				})
 			})
 		})
@@ -2,6 +2,7 @@ package infrastructure
 
 import (
 	"encoding/json"
+	"strings"
 
 	mapstruc "github.com/mitchellh/mapstructure"
 
@@ -19,6 +20,42 @@ type SettingsOptions struct {
 	Sources       SourceOptionsSlice
 	UseServerName bool
 	UseRegistry   bool
+
+	// PEM encoded RSA or ECDSA public key settings have to be signed with, if any
+	SigningPublicKey string
+
+	// How registry is reached when UseRegistry is set
+	Registry RegistryOptions
+
+	// How often settings are fetched again to apply changes made on the IaaS
+	// side while agent is running; 0 disables refreshing settings
+	RefreshIntervalInSeconds int
+}
+
+type RegistryOptions struct {
+	// PEM encoded CA certificates registry certificate is verified against
+	CACertificate string
+
+	// PEM encoded client certificate and private key presented to registry
+	ClientCertificate string
+	ClientPrivateKey  string
+
+	// Bearer token registry requests are authorized with, if any
+	Token *RegistryTokenOptions
+
+	// Resolve registry host in Go instead of shelling out to dig
+	UseNativeDNSResolver bool
+}
+
+// RegistryTokenOptions describe where in instance metadata registry token is found
+type RegistryTokenOptions struct {
+	URI     string
+	Headers map[string]string
+
+	// Session token metadata requests require, if any
+	SessionToken *TokenOptions
+
+	Path string
 }
 
 // SourceOptionsSlice is used for unmarshalling different source types
@@ -31,6 +68,7 @@ type SourceOptions interface {
 type HTTPSourceOptions struct {
 	URI            string
 	Headers        map[string]string
+	Token          *TokenOptions
 	UserDataPath   string
 	InstanceIDPath string
 	SSHKeysPath    string
@@ -67,26 +105,40 @@ func (o CDROMSourceOptions) sourceOptionsInterface() {}
 type InstanceMetadataSourceOptions struct {
 	URI          string
 	Headers      map[string]string
+	Token        *TokenOptions
 	SettingsPath string
 }
 
 func (o InstanceMetadataSourceOptions) sourceOptionsInterface() {}
 
+type NoCloudSourceOptions struct {
+	// e.g. /dev/disk/by-label/cidata
+	DiskPaths []string
+
+	// e.g. /var/lib/cloud/seed/nocloud; take precedence over disks
+	SeedDirs []string
+}
+
+func (o NoCloudSourceOptions) sourceOptionsInterface() {}
+
 type SettingsSourceFactory struct {
-	options  SettingsOptions
-	platform boshplat.Platform
-	logger   boshlog.Logger
+	options     SettingsOptions
+	platform    boshplat.Platform
+	diagnostics *SourceDiagnostics
+	logger      boshlog.Logger
 }
 
 func NewSettingsSourceFactory(
 	options SettingsOptions,
 	platform boshplat.Platform,
+	diagnostics *SourceDiagnostics,
 	logger boshlog.Logger,
 ) SettingsSourceFactory {
 	return SettingsSourceFactory{
-		options:  options,
-		platform: platform,
-		logger:   logger,
+		options:     options,
+		platform:    platform,
+		diagnostics: diagnostics,
+		logger:      logger,
 	}
 }
 
@@ -98,11 +150,46 @@ func (f SettingsSourceFactory) New() (boshsettings.Source, error) {
 	return f.buildWithoutRegistry()
 }
 
+// RequiredCommands lists external commands settings sources shell out to
+// in addition to the ones used by platform
+func (f SettingsSourceFactory) RequiredCommands() []string {
+	if f.options.UseRegistry && !f.options.Registry.UseNativeDNSResolver {
+		return []string{"dig"}
+	}
+
+	return []string{}
+}
+
 func (f SettingsSourceFactory) buildWithRegistry() (boshsettings.Source, error) {
 	var metadataServices []MetadataService
 
-	digDNSResolver := NewDigDNSResolver(f.platform.GetRunner(), f.logger)
-	resolver := NewRegistryEndpointResolver(digDNSResolver)
+	var dnsResolver DNSResolver = NewDigDNSResolver(f.platform.GetRunner(), f.logger)
+	if f.options.Registry.UseNativeDNSResolver {
+		dnsResolver = NewNativeDNSResolver(f.logger)
+	}
+
+	registryClient, resolver, err := NewRegistryHTTPClient(f.options.Registry, dnsResolver)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Building registry client")
+	}
+
+	var registryTokenProvider RegistryTokenProvider
+
+	if tokenOpts := f.options.Registry.Token; tokenOpts != nil {
+		// Only GetValueAtPath is used hence zero values
+		tokenMetadataService := NewHTTPMetadataService(
+			tokenOpts.URI,
+			tokenOpts.Headers,
+			tokenOpts.SessionToken,
+			"",
+			"",
+			"",
+			nil,
+			f.platform,
+			f.logger,
+		)
+		registryTokenProvider = NewMetadataRegistryTokenProvider(tokenMetadataService, tokenOpts.Path)
+	}
 
 	for _, opts := range f.options.Sources {
 		var metadataService MetadataService
@@ -112,6 +199,7 @@ func (f SettingsSourceFactory) buildWithRegistry() (boshsettings.Source, error)
 			metadataService = NewHTTPMetadataService(
 				typedOpts.URI,
 				typedOpts.Headers,
+				typedOpts.Token,
 				typedOpts.UserDataPath,
 				typedOpts.InstanceIDPath,
 				typedOpts.SSHKeysPath,
@@ -119,6 +207,7 @@ func (f SettingsSourceFactory) buildWithRegistry() (boshsettings.Source, error)
 				f.platform,
 				f.logger,
 			)
+			metadataService = f.diagnostics.MetadataService("HTTP", typedOpts.URI, metadataService)
 
 		case ConfigDriveSourceOptions:
 			metadataService = NewConfigDriveMetadataService(
@@ -129,6 +218,7 @@ func (f SettingsSourceFactory) buildWithRegistry() (boshsettings.Source, error)
 				typedOpts.UserDataPath,
 				f.logger,
 			)
+			metadataService = f.diagnostics.MetadataService("ConfigDrive", sourceEndpoint(typedOpts.DiskPaths...), metadataService)
 
 		case FileSourceOptions:
 			metadataService = NewFileMetadataService(
@@ -138,21 +228,34 @@ func (f SettingsSourceFactory) buildWithRegistry() (boshsettings.Source, error)
 				f.platform.GetFs(),
 				f.logger,
 			)
+			metadataService = f.diagnostics.MetadataService("File", sourceEndpoint(
+				typedOpts.MetaDataPath, typedOpts.UserDataPath, typedOpts.SettingsPath), metadataService)
 
 		case CDROMSourceOptions:
 			return nil, bosherr.Error("CDROM source is not supported when registry is used")
 
 		case InstanceMetadataSourceOptions:
 			return nil, bosherr.Error("Instance Metadata source is not supported when registry is used")
+
+		case NoCloudSourceOptions:
+			return nil, bosherr.Error("NoCloud source is not supported when registry is used")
 		}
 		metadataServices = append(metadataServices, metadataService)
 	}
 
 	metadataService := NewMultiSourceMetadataService(metadataServices...)
-	registryProvider := NewRegistryProvider(metadataService, f.platform, f.options.UseServerName, f.platform.GetFs(), f.logger)
+	registryProvider := NewRegistryProvider(
+		metadataService,
+		f.platform,
+		f.options.UseServerName,
+		registryClient,
+		registryTokenProvider,
+		f.platform.GetFs(),
+		f.logger,
+	)
 	settingsSource := NewComplexSettingsSource(metadataService, registryProvider, f.logger)
 
-	return settingsSource, nil
+	return f.diagnostics.SettingsSource("Registry", "", settingsSource), nil
 }
 
 func (f SettingsSourceFactory) buildWithoutRegistry() (boshsettings.Source, error) {
@@ -173,6 +276,7 @@ func (f SettingsSourceFactory) buildWithoutRegistry() (boshsettings.Source, erro
 				f.platform,
 				f.logger,
 			)
+			settingsSource = f.diagnostics.SettingsSource("ConfigDrive", sourceEndpoint(typedOpts.DiskPaths...), settingsSource)
 
 		case FileSourceOptions:
 			return nil, bosherr.Error("File source is not supported without registry")
@@ -183,15 +287,28 @@ func (f SettingsSourceFactory) buildWithoutRegistry() (boshsettings.Source, erro
 				f.platform,
 				f.logger,
 			)
+			settingsSource = f.diagnostics.SettingsSource("CDROM", typedOpts.FileName, settingsSource)
 
 		case InstanceMetadataSourceOptions:
 			settingsSource = NewInstanceMetadataSettingsSource(
 				typedOpts.URI,
 				typedOpts.Headers,
+				typedOpts.Token,
 				typedOpts.SettingsPath,
 				f.platform,
 				f.logger,
 			)
+			settingsSource = f.diagnostics.SettingsSource("InstanceMetadata", typedOpts.URI+typedOpts.SettingsPath, settingsSource)
+
+		case NoCloudSourceOptions:
+			settingsSource = NewNoCloudSettingsSource(
+				typedOpts.DiskPaths,
+				typedOpts.SeedDirs,
+				f.platform,
+				f.logger,
+			)
+			settingsSource = f.diagnostics.SettingsSource("NoCloud", sourceEndpoint(
+				append(append([]string{}, typedOpts.SeedDirs...), typedOpts.DiskPaths...)...), settingsSource)
 		}
 
 		settingsSources = append(settingsSources, settingsSource)
@@ -200,6 +317,19 @@ func (f SettingsSourceFactory) buildWithoutRegistry() (boshsettings.Source, erro
 	return NewMultiSettingsSource(settingsSources...)
 }
 
+// sourceEndpoint describes where source looks for settings in diagnostics
+func sourceEndpoint(paths ...string) string {
+	var nonEmptyPaths []string
+
+	for _, path := range paths {
+		if len(path) > 0 {
+			nonEmptyPaths = append(nonEmptyPaths, path)
+		}
+	}
+
+	return strings.Join(nonEmptyPaths, ",")
+}
+
 func (s *SourceOptionsSlice) UnmarshalJSON(data []byte) error {
 	var maps []map[string]interface{}
 
@@ -234,6 +364,10 @@ func (s *SourceOptionsSlice) UnmarshalJSON(data []byte) error {
 				var o CDROMSourceOptions
 				err, opts = mapstruc.Decode(m, &o), o
 
+			case optType == "NoCloud":
+				var o NoCloudSourceOptions
+				err, opts = mapstruc.Decode(m, &o), o
+
 			default:
 				err = bosherr.Errorf("Unknown source type '%s'", optType)
 			}
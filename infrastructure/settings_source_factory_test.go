@@ -1,8 +1,12 @@
 package infrastructure_test
 
 import (
+	"net/http"
+	"time"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/clock/fakeclock"
 
 	. "github.com/cloudfoundry/bosh-agent/infrastructure"
 	fakeplat "github.com/cloudfoundry/bosh-agent/platform/fakes"
@@ -12,20 +16,22 @@ import (
 var _ = Describe("SettingsSourceFactory", func() {
 	Describe("New", func() {
 		var (
-			options  SettingsOptions
-			platform *fakeplat.FakePlatform
-			logger   boshlog.Logger
-			factory  SettingsSourceFactory
+			options     SettingsOptions
+			platform    *fakeplat.FakePlatform
+			diagnostics *SourceDiagnostics
+			logger      boshlog.Logger
+			factory     SettingsSourceFactory
 		)
 
 		BeforeEach(func() {
 			options = SettingsOptions{}
 			platform = fakeplat.NewFakePlatform()
 			logger = boshlog.NewLogger(boshlog.LevelNone)
+			diagnostics = NewSourceDiagnostics(fakeclock.NewFakeClock(time.Now()), logger)
 		})
 
 		JustBeforeEach(func() {
-			factory = NewSettingsSourceFactory(options, platform, logger)
+			factory = NewSettingsSourceFactory(options, platform, diagnostics, logger)
 		})
 
 		Context("when UseRegistry is set to true", func() {
@@ -43,10 +49,12 @@ var _ = Describe("SettingsSourceFactory", func() {
 
 					It("returns a settings source that uses HTTP to fetch settings", func() {
 						resolver := NewRegistryEndpointResolver(NewDigDNSResolver(platform.GetRunner(), logger))
-						httpMetadataService := NewHTTPMetadataService("http://fake-url", nil, "", "", "", resolver, platform, logger)
-						multiSourceMetadataService := NewMultiSourceMetadataService(httpMetadataService)
-						registryProvider := NewRegistryProvider(multiSourceMetadataService, platform, useServerName, platform.GetFs(), logger)
-						httpSettingsSource := NewComplexSettingsSource(multiSourceMetadataService, registryProvider, logger)
+						httpMetadataService := NewHTTPMetadataService("http://fake-url", nil, nil, "", "", "", resolver, platform, logger)
+						multiSourceMetadataService := NewMultiSourceMetadataService(
+							diagnostics.MetadataService("HTTP", "http://fake-url", httpMetadataService))
+						registryProvider := NewRegistryProvider(multiSourceMetadataService, platform, useServerName, http.DefaultClient, nil, platform.GetFs(), logger)
+						httpSettingsSource := diagnostics.SettingsSource(
+							"Registry", "", NewComplexSettingsSource(multiSourceMetadataService, registryProvider, logger))
 
 						settingsSource, err := factory.New()
 						Expect(err).ToNot(HaveOccurred())
@@ -78,9 +86,11 @@ var _ = Describe("SettingsSourceFactory", func() {
 							"fake-user-data-path",
 							logger,
 						)
-						multiSourceMetadataService := NewMultiSourceMetadataService(configDriveMetadataService)
-						registryProvider := NewRegistryProvider(multiSourceMetadataService, platform, useServerName, platform.GetFs(), logger)
-						configDriveSettingsSource := NewComplexSettingsSource(multiSourceMetadataService, registryProvider, logger)
+						multiSourceMetadataService := NewMultiSourceMetadataService(
+							diagnostics.MetadataService("ConfigDrive", "/fake-disk-path", configDriveMetadataService))
+						registryProvider := NewRegistryProvider(multiSourceMetadataService, platform, useServerName, http.DefaultClient, nil, platform.GetFs(), logger)
+						configDriveSettingsSource := diagnostics.SettingsSource(
+							"Registry", "", NewComplexSettingsSource(multiSourceMetadataService, registryProvider, logger))
 
 						settingsSource, err := factory.New()
 						Expect(err).ToNot(HaveOccurred())
@@ -108,9 +118,11 @@ var _ = Describe("SettingsSourceFactory", func() {
 							platform.GetFs(),
 							logger,
 						)
-						multiSourceMetadataService := NewMultiSourceMetadataService(fileMetadataService)
-						registryProvider := NewRegistryProvider(multiSourceMetadataService, platform, useServerName, platform.GetFs(), logger)
-						fileSettingsSource := NewComplexSettingsSource(multiSourceMetadataService, registryProvider, logger)
+						multiSourceMetadataService := NewMultiSourceMetadataService(diagnostics.MetadataService(
+							"File", "fake-meta-data-path,fake-user-data-path,fake-settings-path", fileMetadataService))
+						registryProvider := NewRegistryProvider(multiSourceMetadataService, platform, useServerName, http.DefaultClient, nil, platform.GetFs(), logger)
+						fileSettingsSource := diagnostics.SettingsSource(
+							"Registry", "", NewComplexSettingsSource(multiSourceMetadataService, registryProvider, logger))
 
 						settingsSource, err := factory.New()
 						Expect(err).ToNot(HaveOccurred())
@@ -133,8 +145,37 @@ var _ = Describe("SettingsSourceFactory", func() {
 						Expect(err.Error()).To(ContainSubstring("CDROM source is not supported when registry is used"))
 					})
 				})
+
+				Context("when using NoCloud source", func() {
+					BeforeEach(func() {
+						options.Sources = []SourceOptions{
+							NoCloudSourceOptions{
+								DiskPaths: []string{"/fake-disk-path"},
+							},
+						}
+					})
+
+					It("returns error because it is not supported", func() {
+						_, err := factory.New()
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("NoCloud source is not supported when registry is used"))
+					})
+				})
 			}
 
+			Context("when registry CA certificate is invalid", func() {
+				BeforeEach(func() {
+					options.Sources = []SourceOptions{HTTPSourceOptions{URI: "http://fake-url"}}
+					options.Registry = RegistryOptions{CACertificate: "fake-ca-certificate"}
+				})
+
+				It("returns error", func() {
+					_, err := factory.New()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("Building registry client"))
+				})
+			})
+
 			Context("when UseServerName is set to true", func() {
 				BeforeEach(func() { options.UseServerName = true })
 				ItConfiguresSourcesToUseRegistry(true)
@@ -187,7 +228,8 @@ var _ = Describe("SettingsSourceFactory", func() {
 						logger,
 					)
 
-					multiSettingsSource, err := NewMultiSettingsSource(configDriveSettingsSource)
+					multiSettingsSource, err := NewMultiSettingsSource(
+						diagnostics.SettingsSource("ConfigDrive", "/fake-disk-path", configDriveSettingsSource))
 					Expect(err).ToNot(HaveOccurred())
 
 					settingsSource, err := factory.New()
@@ -230,7 +272,38 @@ var _ = Describe("SettingsSourceFactory", func() {
 						logger,
 					)
 
-					multiSettingsSource, err := NewMultiSettingsSource(cdromSettingsSource)
+					multiSettingsSource, err := NewMultiSettingsSource(
+						diagnostics.SettingsSource("CDROM", "fake-file-name", cdromSettingsSource))
+					Expect(err).ToNot(HaveOccurred())
+
+					settingsSource, err := factory.New()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(settingsSource).To(Equal(multiSettingsSource))
+				})
+			})
+
+			Context("when using NoCloud source", func() {
+				BeforeEach(func() {
+					options = SettingsOptions{
+						Sources: []SourceOptions{
+							NoCloudSourceOptions{
+								DiskPaths: []string{"/fake-disk-path"},
+								SeedDirs:  []string{"/fake-seed-dir"},
+							},
+						},
+					}
+				})
+
+				It("returns a settings source that uses the NoCloud seed to fetch settings", func() {
+					noCloudSettingsSource := NewNoCloudSettingsSource(
+						[]string{"/fake-disk-path"},
+						[]string{"/fake-seed-dir"},
+						platform,
+						logger,
+					)
+
+					multiSettingsSource, err := NewMultiSettingsSource(
+						diagnostics.SettingsSource("NoCloud", "/fake-seed-dir,/fake-disk-path", noCloudSettingsSource))
 					Expect(err).ToNot(HaveOccurred())
 
 					settingsSource, err := factory.New()
@@ -41,7 +41,7 @@ func (s *dummyJobSupervisor) Processes() ([]Process, error) {
 	return s.processes, nil
 }
 
-func (s *dummyJobSupervisor) AddJob(jobName string, jobIndex int, configPath string) error {
+func (s *dummyJobSupervisor) AddJob(jobName string, jobIndex int, configPath string, isolation *JobIsolation) error {
 	return nil
 }
 
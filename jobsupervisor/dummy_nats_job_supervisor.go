@@ -70,7 +70,7 @@ func (d *dummyNatsJobSupervisor) Reload() error {
 	return nil
 }
 
-func (d *dummyNatsJobSupervisor) AddJob(jobName string, jobIndex int, configPath string) error {
+func (d *dummyNatsJobSupervisor) AddJob(jobName string, jobIndex int, configPath string, isolation *JobIsolation) error {
 	return nil
 }
 
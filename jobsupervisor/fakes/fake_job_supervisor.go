@@ -35,6 +35,7 @@ type AddJobArgs struct {
 	Name       string
 	Index      int
 	ConfigPath string
+	Isolation  *boshjobsuper.JobIsolation
 }
 
 func NewFakeJobSupervisor() *FakeJobSupervisor {
@@ -46,11 +47,12 @@ func (m *FakeJobSupervisor) Reload() error {
 	return m.ReloadErr
 }
 
-func (m *FakeJobSupervisor) AddJob(jobName string, jobIndex int, configPath string) error {
+func (m *FakeJobSupervisor) AddJob(jobName string, jobIndex int, configPath string, isolation *boshjobsuper.JobIsolation) error {
 	args := AddJobArgs{
 		Name:       jobName,
 		Index:      jobIndex,
 		ConfigPath: configPath,
+		Isolation:  isolation,
 	}
 	m.AddJobArgs = append(m.AddJobArgs, args)
 	return nil
@@ -0,0 +1,176 @@
+package jobsupervisor
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	jobCgroupsDir = "/sys/fs/cgroup/bosh"
+
+	// Only exists when cgroup v2 unified hierarchy is mounted
+	cgroupControllersFileName = "cgroup.controllers"
+	cgroupSubtreeControlName  = "cgroup.subtree_control"
+
+	// Quota is expressed in microseconds per period
+	cgroupCPUPeriod = 100000
+)
+
+var (
+	// Controllers backing limits set by cgroupLimits
+	jobCgroupControllers = []string{"cpu", "memory", "pids"}
+
+	monitStartProgramExpression = regexp.MustCompile(`(?im)^(\s*start\s+program\s*=?\s*")([^"]*)"(.*)$`)
+	monitAsUIDExpression        = regexp.MustCompile(`(?i)\s+as\s+uid\s+(\S+)`)
+	monitGIDExpression          = regexp.MustCompile(`(?i)(?:\s+and)?\s+gid\s+(\S+)`)
+	monitCheckProcessExpression = regexp.MustCompile(`(?im)^\s*check\s+process\s+(\S+)`)
+)
+
+// JobIsolation confines processes started through monit configuration of a job;
+// jobs with several monit files are expected to share a single cgroup
+type JobIsolation struct {
+	// Name of the cgroup that job processes are placed into
+	Cgroup string `json:"cgroup"`
+
+	// Zero limits are not enforced
+	MemoryLimitBytes uint64 `json:"memory_limit_bytes"`
+	CPULimitPercent  uint64 `json:"cpu_limit_percent"`
+	PidsLimit        uint64 `json:"pids_limit"`
+
+	// Processes see their own empty /tmp
+	PrivateTmp bool `json:"private_tmp"`
+
+	// Processes see root file system read-only;
+	// separately mounted file systems such as data and store are not affected
+	ReadOnlyRoot bool `json:"read_only_root"`
+}
+
+// IsolationVitals reports limits enforced for processes of an isolated job
+// together with current usage of the whole job cgroup
+type IsolationVitals struct {
+	Cgroup          string `json:"cgroup"`
+	MemoryLimitKb   uint64 `json:"mem_limit_kb,omitempty"`
+	MemoryKb        uint64 `json:"mem_kb"`
+	CPULimitPercent uint64 `json:"cpu_limit_percent,omitempty"`
+	PidsLimit       uint64 `json:"pids_limit,omitempty"`
+	Pids            uint64 `json:"pids"`
+}
+
+func (i JobIsolation) cgroupDir() string {
+	return path.Join(jobCgroupsDir, i.Cgroup)
+}
+
+// cgroupLimits returns contents of cgroup v2 interface files
+func (i JobIsolation) cgroupLimits() map[string]string {
+	limits := map[string]string{
+		"memory.max": "max",
+		"cpu.max":    fmt.Sprintf("max %d", cgroupCPUPeriod),
+		"pids.max":   "max",
+	}
+
+	if i.MemoryLimitBytes > 0 {
+		limits["memory.max"] = strconv.FormatUint(i.MemoryLimitBytes, 10)
+	}
+
+	if i.CPULimitPercent > 0 {
+		limits["cpu.max"] = fmt.Sprintf("%d %d", i.CPULimitPercent*cgroupCPUPeriod/100, cgroupCPUPeriod)
+	}
+
+	if i.PidsLimit > 0 {
+		limits["pids.max"] = strconv.FormatUint(i.PidsLimit, 10)
+	}
+
+	return limits
+}
+
+// missingCgroupControllers returns job cgroup controllers which are not listed
+// in contents of cgroup.controllers or cgroup.subtree_control interface files
+func missingCgroupControllers(contents string) []string {
+	listed := map[string]bool{}
+
+	for _, controller := range strings.Fields(contents) {
+		listed[strings.TrimPrefix(controller, "+")] = true
+	}
+
+	var missing []string
+
+	for _, controller := range jobCgroupControllers {
+		if !listed[controller] {
+			missing = append(missing, controller)
+		}
+	}
+
+	return missing
+}
+
+// wrapperScript moves itself into job cgroup and then
+// executes given start program in a private mount namespace if needed
+func (i JobIsolation) wrapperScript() string {
+	var buf bytes.Buffer
+
+	fmt.Fprintln(&buf, "#!/bin/bash")
+	fmt.Fprintln(&buf, "set -e")
+	fmt.Fprintf(&buf, "echo $$ > %s\n", path.Join(i.cgroupDir(), "cgroup.procs"))
+
+	if !i.PrivateTmp && !i.ReadOnlyRoot {
+		fmt.Fprintln(&buf, `exec "$@"`)
+		return buf.String()
+	}
+
+	fmt.Fprintln(&buf, "exec unshare --mount --propagation private /bin/bash -e -c '")
+
+	if i.PrivateTmp {
+		fmt.Fprintln(&buf, "mount -t tmpfs -o mode=1777,nodev,nosuid tmpfs /tmp")
+	}
+
+	if i.ReadOnlyRoot {
+		fmt.Fprintln(&buf, "mount -o remount,bind,ro /")
+	}
+
+	fmt.Fprintln(&buf, `exec "$@"' isolated-job "$@"`)
+
+	return buf.String()
+}
+
+// isolateMonitConfig prefixes start programs with isolation wrapper.
+// Wrapper has to run as root to move itself into job cgroup and to set up
+// mount namespace hence uid and gid switch is moved from monit into wrapper.
+func isolateMonitConfig(config []byte, wrapperPath string) []byte {
+	return monitStartProgramExpression.ReplaceAllFunc(config, func(line []byte) []byte {
+		match := monitStartProgramExpression.FindSubmatch(line)
+		prefix, program, options := string(match[1]), string(match[2]), string(match[3])
+
+		var switchUser []string
+
+		if uid := monitAsUIDExpression.FindStringSubmatch(options); uid != nil {
+			options = monitAsUIDExpression.ReplaceAllString(options, "")
+			switchUser = append(switchUser, "setpriv", "--reuid="+uid[1])
+
+			if gid := monitGIDExpression.FindStringSubmatch(options); gid != nil {
+				options = monitGIDExpression.ReplaceAllString(options, "")
+				switchUser = append(switchUser, "--regid="+gid[1])
+			}
+
+			switchUser = append(switchUser, "--init-groups")
+		}
+
+		command := append([]string{wrapperPath}, switchUser...)
+		command = append(command, program)
+
+		return []byte(prefix + strings.Join(command, " ") + `"` + options)
+	})
+}
+
+func monitConfigProcessNames(config []byte) []string {
+	var names []string
+
+	for _, match := range monitCheckProcessExpression.FindAllSubmatch(config, -1) {
+		names = append(names, string(match[1]))
+	}
+
+	return names
+}
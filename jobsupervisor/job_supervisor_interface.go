@@ -10,6 +10,9 @@ type Process struct {
 	Uptime UptimeVitals `json:"uptime,omitempty"`
 	Memory MemoryVitals `json:"mem,omitempty"`
 	CPU    CPUVitals    `json:"cpu,omitempty"`
+
+	// Only set for processes of isolated jobs
+	Isolation *IsolationVitals `json:"isolation,omitempty"`
 }
 
 type UptimeVitals struct {
@@ -44,7 +47,8 @@ type JobSupervisor interface {
 	Status() string
 	Processes() ([]Process, error)
 	// Job management
-	AddJob(jobName string, jobIndex int, configPath string) error
+	// Isolation is opt-in; nil isolation leaves job processes unconfined
+	AddJob(jobName string, jobIndex int, configPath string, isolation *JobIsolation) error
 	RemoveAllJobs() error
 
 	MonitorJobFailures(handler JobFailureHandler) error
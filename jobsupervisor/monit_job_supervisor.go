@@ -1,8 +1,11 @@
 package jobsupervisor
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,7 +20,12 @@ import (
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 )
 
-const monitJobSupervisorLogTag = "monitJobSupervisor"
+const (
+	monitJobSupervisorLogTag = "monitJobSupervisor"
+
+	isolatedProcessesFileName = "isolated_processes.json"
+	isolationWrappersDirName  = "isolation"
+)
 
 type monitJobSupervisor struct {
 	fs                    boshsys.FileSystem
@@ -282,6 +290,11 @@ func (m monitJobSupervisor) Processes() (processes []Process, err error) {
 		return processes, bosherr.WrapError(err, "Getting service status")
 	}
 
+	isolatedProcesses, err := m.loadIsolatedProcesses()
+	if err != nil {
+		return processes, err
+	}
+
 	for _, service := range monitStatus.ServicesInGroup("vcap") {
 		process := Process{
 			Name:  service.Name,
@@ -297,6 +310,11 @@ func (m monitJobSupervisor) Processes() (processes []Process, err error) {
 				Total: service.CPUPercentTotal,
 			},
 		}
+
+		if isolation, found := isolatedProcesses[service.Name]; found {
+			process.Isolation = m.isolationVitals(isolation)
+		}
+
 		processes = append(processes, process)
 	}
 
@@ -312,7 +330,7 @@ func (m monitJobSupervisor) getIncarnation() (int, error) {
 	return monitStatus.GetIncarnation()
 }
 
-func (m monitJobSupervisor) AddJob(jobName string, jobIndex int, configPath string) error {
+func (m monitJobSupervisor) AddJob(jobName string, jobIndex int, configPath string, isolation *JobIsolation) error {
 	targetFilename := fmt.Sprintf("%04d_%s.monitrc", jobIndex, jobName)
 	targetConfigPath := path.Join(m.dirProvider.MonitJobsDir(), targetFilename)
 
@@ -321,6 +339,13 @@ func (m monitJobSupervisor) AddJob(jobName string, jobIndex int, configPath stri
 		return bosherr.WrapError(err, "Reading job config from file")
 	}
 
+	if isolation != nil {
+		configContent, err = m.isolateJob(configContent, *isolation)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Isolating job %s", jobName)
+		}
+	}
+
 	err = m.fs.WriteFile(targetConfigPath, configContent)
 	if err != nil {
 		return bosherr.WrapError(err, "Writing to job config file")
@@ -329,10 +354,190 @@ func (m monitJobSupervisor) AddJob(jobName string, jobIndex int, configPath stri
 	return nil
 }
 
+// RemoveAllJobs keeps job cgroups since they cannot be removed
+// while processes are still running; limits are reset once job is added again
 func (m monitJobSupervisor) RemoveAllJobs() error {
 	return m.fs.RemoveAll(m.dirProvider.MonitJobsDir())
 }
 
+func (m monitJobSupervisor) isolateJob(configContent []byte, isolation JobIsolation) ([]byte, error) {
+	if isolation.Cgroup == "" || strings.ContainsAny(isolation.Cgroup, "/") || isolation.Cgroup == "." || isolation.Cgroup == ".." {
+		return nil, bosherr.Errorf("Invalid cgroup name '%s'", isolation.Cgroup)
+	}
+
+	err := m.setUpJobCgroup(isolation)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapperPath := path.Join(m.dirProvider.MonitJobsDir(), isolationWrappersDirName, isolation.Cgroup)
+
+	err = m.fs.WriteFileString(wrapperPath, isolation.wrapperScript())
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Writing isolation wrapper")
+	}
+
+	err = m.fs.Chmod(wrapperPath, os.FileMode(0700))
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Chmoding isolation wrapper")
+	}
+
+	isolatedProcesses, err := m.loadIsolatedProcesses()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range monitConfigProcessNames(configContent) {
+		isolatedProcesses[name] = isolation
+	}
+
+	err = m.saveIsolatedProcesses(isolatedProcesses)
+	if err != nil {
+		return nil, err
+	}
+
+	return isolateMonitConfig(configContent, wrapperPath), nil
+}
+
+func (m monitJobSupervisor) setUpJobCgroup(isolation JobIsolation) error {
+	rootCgroupDir := path.Dir(jobCgroupsDir)
+
+	// Limits are written to cgroup v2 interface files which
+	// do not exist when only cgroup v1 hierarchies are mounted
+	controllersPath := path.Join(rootCgroupDir, cgroupControllersFileName)
+
+	if !m.fs.FileExists(controllersPath) {
+		return bosherr.Errorf("Isolating jobs requires cgroup v2 unified hierarchy but %s does not exist", controllersPath)
+	}
+
+	err := m.requireCgroupControllers(controllersPath)
+	if err != nil {
+		return err
+	}
+
+	enableControllers := "+" + strings.Join(jobCgroupControllers, " +")
+
+	// Controllers have to be enabled by each ancestor of job cgroup
+	for _, dir := range []string{rootCgroupDir, jobCgroupsDir} {
+		err = m.fs.MkdirAll(dir, os.FileMode(0755))
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Creating cgroup %s", dir)
+		}
+
+		subtreeControlPath := path.Join(dir, cgroupSubtreeControlName)
+
+		err = m.fs.WriteFileString(subtreeControlPath, enableControllers)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Enabling cgroup controllers in %s", dir)
+		}
+
+		err = m.requireCgroupControllers(subtreeControlPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	cgroupDir := isolation.cgroupDir()
+
+	err = m.fs.MkdirAll(cgroupDir, os.FileMode(0755))
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Creating cgroup %s", cgroupDir)
+	}
+
+	for name, value := range isolation.cgroupLimits() {
+		err = m.fs.WriteFileString(path.Join(cgroupDir, name), value)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Setting cgroup limit %s", name)
+		}
+	}
+
+	return nil
+}
+
+func (m monitJobSupervisor) requireCgroupControllers(interfacePath string) error {
+	contents, err := m.fs.ReadFileString(interfacePath)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Reading %s", interfacePath)
+	}
+
+	missing := missingCgroupControllers(contents)
+	if len(missing) > 0 {
+		return bosherr.Errorf("Cgroup controllers %s are not listed in %s", strings.Join(missing, ", "), interfacePath)
+	}
+
+	return nil
+}
+
+func (m monitJobSupervisor) isolationVitals(isolation JobIsolation) *IsolationVitals {
+	vitals := &IsolationVitals{
+		Cgroup:          isolation.Cgroup,
+		MemoryLimitKb:   isolation.MemoryLimitBytes / 1024,
+		CPULimitPercent: isolation.CPULimitPercent,
+		PidsLimit:       isolation.PidsLimit,
+	}
+
+	memoryBytes, err := m.readCgroupCounter(isolation, "memory.current")
+	if err != nil {
+		m.logger.Warn(monitJobSupervisorLogTag, "Reading memory usage of cgroup %s: %s", isolation.Cgroup, err.Error())
+	}
+
+	vitals.MemoryKb = memoryBytes / 1024
+
+	vitals.Pids, err = m.readCgroupCounter(isolation, "pids.current")
+	if err != nil {
+		m.logger.Warn(monitJobSupervisorLogTag, "Reading pids of cgroup %s: %s", isolation.Cgroup, err.Error())
+	}
+
+	return vitals
+}
+
+func (m monitJobSupervisor) readCgroupCounter(isolation JobIsolation, name string) (uint64, error) {
+	contents, err := m.fs.ReadFileString(path.Join(isolation.cgroupDir(), name))
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseUint(strings.TrimSpace(contents), 10, 64)
+}
+
+func (m monitJobSupervisor) isolatedProcessesPath() string {
+	return path.Join(m.dirProvider.MonitJobsDir(), isolatedProcessesFileName)
+}
+
+func (m monitJobSupervisor) loadIsolatedProcesses() (map[string]JobIsolation, error) {
+	isolatedProcesses := map[string]JobIsolation{}
+
+	if !m.fs.FileExists(m.isolatedProcessesPath()) {
+		return isolatedProcesses, nil
+	}
+
+	bytes, err := m.fs.ReadFile(m.isolatedProcessesPath())
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Reading isolated processes")
+	}
+
+	err = json.Unmarshal(bytes, &isolatedProcesses)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Unmarshalling isolated processes")
+	}
+
+	return isolatedProcesses, nil
+}
+
+func (m monitJobSupervisor) saveIsolatedProcesses(isolatedProcesses map[string]JobIsolation) error {
+	bytes, err := json.Marshal(isolatedProcesses)
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling isolated processes")
+	}
+
+	err = m.fs.WriteFile(m.isolatedProcessesPath(), bytes)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing isolated processes")
+	}
+
+	return nil
+}
+
 func (m monitJobSupervisor) MonitorJobFailures(handler JobFailureHandler) (err error) {
 	alertHandler := func(smtpd.Connection, smtpd.MailAddress) (env smtpd.Envelope, err error) {
 		env = &alertEnvelope{
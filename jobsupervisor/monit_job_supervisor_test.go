@@ -660,6 +660,42 @@ var _ = Describe("monitJobSupervisor", func() {
 			}))
 		})
 
+		It("reports limits and usage of isolated processes", func() {
+			fs.WriteFileString("/sys/fs/cgroup/cgroup.controllers", "cpuset cpu io memory pids\n")
+			fs.WriteFileString("/some/config/path", "check process fake-service-1\n  start program \"/bin/fake-start\"\n")
+
+			err := monit.AddJob("fake-job", 0, "/some/config/path", &JobIsolation{
+				Cgroup:           "fake-job",
+				MemoryLimitBytes: 512 * 1024 * 1024,
+				CPULimitPercent:  50,
+				PidsLimit:        100,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			fs.WriteFileString("/sys/fs/cgroup/bosh/fake-job/memory.current", "1048576\n")
+			fs.WriteFileString("/sys/fs/cgroup/bosh/fake-job/pids.current", "3\n")
+
+			client.StatusStatus = fakemonit.FakeMonitStatus{
+				Services: []boshmonit.Service{
+					{Name: "fake-service-1", Monitored: true, Status: "running"},
+					{Name: "fake-service-2", Monitored: true, Status: "running"},
+				},
+			}
+
+			processes, err := monit.Processes()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(processes).To(HaveLen(2))
+			Expect(processes[0].Isolation).To(Equal(&IsolationVitals{
+				Cgroup:          "fake-job",
+				MemoryLimitKb:   512 * 1024,
+				MemoryKb:        1024,
+				CPULimitPercent: 50,
+				PidsLimit:       100,
+				Pids:            3,
+			}))
+			Expect(processes[1].Isolation).To(BeNil())
+		})
+
 		It("returns error when failing to get service status", func() {
 			client.StatusErr = errors.New("fake-monit-client-error")
 
@@ -724,7 +760,7 @@ var _ = Describe("monitJobSupervisor", func() {
 		Context("when reading configuration from config path succeeds", func() {
 			Context("when writing job configuration succeeds", func() {
 				It("returns no error because monit can track added job in jobs directory", func() {
-					err := monit.AddJob("router", 0, "/some/config/path")
+					err := monit.AddJob("router", 0, "/some/config/path", nil)
 					Expect(err).ToNot(HaveOccurred())
 
 					writtenConfig, err := fs.ReadFileString(
@@ -738,7 +774,7 @@ var _ = Describe("monitJobSupervisor", func() {
 				It("returns error", func() {
 					fs.WriteFileError = errors.New("fake-write-error")
 
-					err := monit.AddJob("router", 0, "/some/config/path")
+					err := monit.AddJob("router", 0, "/some/config/path", nil)
 					Expect(err).To(HaveOccurred())
 					Expect(err.Error()).To(ContainSubstring("fake-write-error"))
 				})
@@ -749,11 +785,155 @@ var _ = Describe("monitJobSupervisor", func() {
 			It("returns error", func() {
 				fs.ReadFileError = errors.New("fake-read-error")
 
-				err := monit.AddJob("router", 0, "/some/config/path")
+				err := monit.AddJob("router", 0, "/some/config/path", nil)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("fake-read-error"))
 			})
 		})
+
+		Context("when job is isolated", func() {
+			var isolation *JobIsolation
+
+			BeforeEach(func() {
+				fs.WriteFileString("/some/config/path", `check process router
+  with pidfile /var/vcap/sys/run/router/router.pid
+  start program "/var/vcap/jobs/router/bin/ctl start"
+  stop program "/var/vcap/jobs/router/bin/ctl stop"
+  group vcap
+`)
+
+				isolation = &JobIsolation{
+					Cgroup:           "router",
+					MemoryLimitBytes: 512 * 1024 * 1024,
+					CPULimitPercent:  150,
+					PidsLimit:        100,
+				}
+
+				fs.WriteFileString("/sys/fs/cgroup/cgroup.controllers", "cpuset cpu io memory pids\n")
+			})
+
+			It("starts job processes through isolation wrapper", func() {
+				err := monit.AddJob("router", 0, "/some/config/path", isolation)
+				Expect(err).ToNot(HaveOccurred())
+
+				writtenConfig, err := fs.ReadFileString(dirProvider.MonitJobsDir() + "/0000_router.monitrc")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(writtenConfig).To(Equal(`check process router
+  with pidfile /var/vcap/sys/run/router/router.pid
+  start program "/var/vcap/monit/job/isolation/router /var/vcap/jobs/router/bin/ctl start"
+  stop program "/var/vcap/jobs/router/bin/ctl stop"
+  group vcap
+`))
+
+				wrapper := fs.GetFileTestStat("/var/vcap/monit/job/isolation/router")
+				Expect(wrapper.FileMode).To(Equal(os.FileMode(0700)))
+				Expect(wrapper.StringContents()).To(Equal(`#!/bin/bash
+set -e
+echo $$ > /sys/fs/cgroup/bosh/router/cgroup.procs
+exec "$@"
+`))
+			})
+
+			It("switches to start program user inside isolation wrapper", func() {
+				fs.WriteFileString("/some/config/path", `check process router
+  start program "/var/vcap/jobs/router/bin/ctl start" as uid vcap and gid vcap with timeout 60 seconds
+  stop program "/var/vcap/jobs/router/bin/ctl stop" as uid vcap
+`)
+
+				err := monit.AddJob("router", 0, "/some/config/path", isolation)
+				Expect(err).ToNot(HaveOccurred())
+
+				writtenConfig, err := fs.ReadFileString(dirProvider.MonitJobsDir() + "/0000_router.monitrc")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(writtenConfig).To(Equal(`check process router
+  start program "/var/vcap/monit/job/isolation/router setpriv --reuid=vcap --regid=vcap --init-groups /var/vcap/jobs/router/bin/ctl start" with timeout 60 seconds
+  stop program "/var/vcap/jobs/router/bin/ctl stop" as uid vcap
+`))
+			})
+
+			It("sets up cgroup with job limits", func() {
+				err := monit.AddJob("router", 0, "/some/config/path", isolation)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fs.ReadFileString("/sys/fs/cgroup/cgroup.subtree_control")).To(Equal("+cpu +memory +pids"))
+				Expect(fs.ReadFileString("/sys/fs/cgroup/bosh/cgroup.subtree_control")).To(Equal("+cpu +memory +pids"))
+				Expect(fs.ReadFileString("/sys/fs/cgroup/bosh/router/memory.max")).To(Equal("536870912"))
+				Expect(fs.ReadFileString("/sys/fs/cgroup/bosh/router/cpu.max")).To(Equal("150000 100000"))
+				Expect(fs.ReadFileString("/sys/fs/cgroup/bosh/router/pids.max")).To(Equal("100"))
+			})
+
+			It("removes limits that are not set", func() {
+				err := monit.AddJob("router", 0, "/some/config/path", &JobIsolation{Cgroup: "router"})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fs.ReadFileString("/sys/fs/cgroup/bosh/router/memory.max")).To(Equal("max"))
+				Expect(fs.ReadFileString("/sys/fs/cgroup/bosh/router/cpu.max")).To(Equal("max 100000"))
+				Expect(fs.ReadFileString("/sys/fs/cgroup/bosh/router/pids.max")).To(Equal("max"))
+			})
+
+			It("gives job private /tmp and read-only root in its own mount namespace", func() {
+				isolation.PrivateTmp = true
+				isolation.ReadOnlyRoot = true
+
+				err := monit.AddJob("router", 0, "/some/config/path", isolation)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fs.ReadFileString("/var/vcap/monit/job/isolation/router")).To(Equal(`#!/bin/bash
+set -e
+echo $$ > /sys/fs/cgroup/bosh/router/cgroup.procs
+exec unshare --mount --propagation private /bin/bash -e -c '
+mount -t tmpfs -o mode=1777,nodev,nosuid tmpfs /tmp
+mount -o remount,bind,ro /
+exec "$@"' isolated-job "$@"
+`))
+			})
+
+			It("returns error when cgroup name is invalid", func() {
+				isolation.Cgroup = "../router"
+
+				err := monit.AddJob("router", 0, "/some/config/path", isolation)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Invalid cgroup name '../router'"))
+				Expect(fs.FileExists(dirProvider.MonitJobsDir() + "/0000_router.monitrc")).To(BeFalse())
+			})
+
+			It("returns error when cgroup v2 is not mounted", func() {
+				fs.RemoveAll("/sys/fs/cgroup/cgroup.controllers")
+
+				err := monit.AddJob("router", 0, "/some/config/path", isolation)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("requires cgroup v2 unified hierarchy but /sys/fs/cgroup/cgroup.controllers does not exist"))
+				Expect(fs.FileExists("/sys/fs/cgroup/bosh/router")).To(BeFalse())
+				Expect(fs.FileExists(dirProvider.MonitJobsDir() + "/0000_router.monitrc")).To(BeFalse())
+			})
+
+			It("returns error when cgroup controllers are not available", func() {
+				fs.WriteFileString("/sys/fs/cgroup/cgroup.controllers", "cpuset cpu io\n")
+
+				err := monit.AddJob("router", 0, "/some/config/path", isolation)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Cgroup controllers memory, pids are not listed in /sys/fs/cgroup/cgroup.controllers"))
+				Expect(fs.FileExists(dirProvider.MonitJobsDir() + "/0000_router.monitrc")).To(BeFalse())
+			})
+
+			It("returns error when cgroup controllers are not enabled for job cgroups", func() {
+				fs.RegisterReadFileError("/sys/fs/cgroup/bosh/cgroup.subtree_control", errors.New("fake-read-err"))
+
+				err := monit.AddJob("router", 0, "/some/config/path", isolation)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Reading /sys/fs/cgroup/bosh/cgroup.subtree_control: fake-read-err"))
+				Expect(fs.FileExists(dirProvider.MonitJobsDir() + "/0000_router.monitrc")).To(BeFalse())
+			})
+
+			It("returns error when setting cgroup limits fails", func() {
+				fs.WriteFileErrors["/sys/fs/cgroup/bosh/router/memory.max"] = errors.New("fake-write-error")
+
+				err := monit.AddJob("router", 0, "/some/config/path", isolation)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-write-error"))
+				Expect(fs.FileExists(dirProvider.MonitJobsDir() + "/0000_router.monitrc")).To(BeFalse())
+			})
+		})
 	})
 
 	Describe("RemoveAllJobs", func() {
@@ -421,7 +421,11 @@ func (w *windowsJobSupervisor) Processes() ([]Process, error) {
 	return procs, nil
 }
 
-func (w *windowsJobSupervisor) AddJob(jobName string, jobIndex int, configPath string) error {
+func (w *windowsJobSupervisor) AddJob(jobName string, jobIndex int, configPath string, isolation *JobIsolation) error {
+	if isolation != nil {
+		return bosherr.Errorf("Isolating job %s is not supported", jobName)
+	}
+
 	configFileContents, err := w.fs.ReadFile(configPath)
 	if err != nil {
 		return err
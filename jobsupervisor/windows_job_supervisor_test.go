@@ -200,7 +200,7 @@ var _ = Describe("WindowsJobSupervisor", func() {
 			if err != nil {
 				return conf, err
 			}
-			return conf, jobSupervisor.AddJob(jobName, 0, confPath)
+			return conf, jobSupervisor.AddJob(jobName, 0, confPath, nil)
 		}
 
 		AfterEach(func() {
@@ -57,7 +57,40 @@ func startAgent(logger logger.Logger) error {
 	}
 }
 
+// runDiagnostic keeps stdout for diagnostic output and logs to stderr
+func runDiagnostic(name string, diagnose func(app boshapp.App) error) int {
+	logger := boshlog.NewWriterLogger(boshlog.LevelDebug, os.Stderr, os.Stderr)
+
+	fs := boshsys.NewOsFileSystem(logger)
+	app := boshapp.New(logger, fs)
+
+	err := diagnose(app)
+	if err != nil {
+		logger.Error(mainLogTag, "%s failed: %s", name, err)
+		return 1
+	}
+
+	return 0
+}
+
 func main() {
+	if opts, err := boshapp.ParseOptions(os.Args); err == nil {
+		switch {
+		case opts.ExplainSettings:
+			os.Exit(runDiagnostic("Explaining settings", func(app boshapp.App) error {
+				return app.ExplainSettings(os.Args, os.Stdout)
+			}))
+		case opts.SelfTest:
+			os.Exit(runDiagnostic("Self-test", func(app boshapp.App) error {
+				return app.SelfTest(os.Args, os.Stdout)
+			}))
+		case opts.DryRunBootstrap:
+			os.Exit(runDiagnostic("Dry run of bootstrap", func(app boshapp.App) error {
+				return app.DryRunBootstrap(os.Args, os.Stdout)
+			}))
+		}
+	}
+
 	asyncLog := boshlog.NewAsyncWriterLogger(boshlog.LevelDebug, os.Stdout, os.Stderr)
 	logger := newSignalableLogger(asyncLog)
 
@@ -23,6 +23,9 @@ type FakeHandler struct {
 
 	SendCallback func(SendInput)
 	SendErr      error
+
+	SwitchURLURLs []string
+	SwitchURLErr  error
 }
 
 type SendInput struct {
@@ -83,6 +86,11 @@ func (h *FakeHandler) Send(target boshhandler.Target, topic boshhandler.Topic, m
 	return h.SendErr
 }
 
+func (h *FakeHandler) SwitchURL(mbusURL string) error {
+	h.SwitchURLURLs = append(h.SwitchURLURLs, mbusURL)
+	return h.SwitchURLErr
+}
+
 func (h *FakeHandler) SendInputs() []SendInput {
 	h.sendLock.Lock()
 	defer h.sendLock.Unlock()
@@ -4,6 +4,7 @@ import (
 	"net/url"
 
 	"github.com/cloudfoundry/yagnats"
+	"github.com/pivotal-golang/clock"
 
 	boshhandler "github.com/cloudfoundry/bosh-agent/handler"
 	boshmicro "github.com/cloudfoundry/bosh-agent/micro"
@@ -18,6 +19,7 @@ type HandlerProvider struct {
 	settingsService boshsettings.Service
 	logger          boshlog.Logger
 	auditLogger     boshplatform.AuditLogger
+	timeService     clock.Clock
 	handler         boshhandler.Handler
 }
 
@@ -25,10 +27,12 @@ func NewHandlerProvider(
 	settingsService boshsettings.Service,
 	logger boshlog.Logger,
 	auditLogger boshplatform.AuditLogger,
+	timeService clock.Clock,
 ) (p HandlerProvider) {
 	p.settingsService = settingsService
 	p.logger = logger
 	p.auditLogger = auditLogger
+	p.timeService = timeService
 	return
 }
 
@@ -49,7 +53,7 @@ func (p HandlerProvider) Get(
 
 	switch mbusURL.Scheme {
 	case "nats":
-		handler = NewNatsHandler(p.settingsService, yagnats.NewClient(), p.logger, platform)
+		handler = NewNatsHandler(p.settingsService, newNATSClient, p.logger, platform, p.timeService)
 	case "https":
 		handler = boshmicro.NewHTTPSHandler(mbusURL, p.logger, platform.GetFs(), dirProvider, p.auditLogger)
 	default:
@@ -60,3 +64,7 @@ func (p HandlerProvider) Get(
 
 	return
 }
+
+func newNATSClient() yagnats.NATSClient {
+	return yagnats.NewClient()
+}
@@ -3,10 +3,12 @@ package mbus_test
 import (
 	gourl "net/url"
 	"reflect"
+	"time"
 
 	"github.com/cloudfoundry/yagnats"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/clock/fakeclock"
 
 	. "github.com/cloudfoundry/bosh-agent/mbus"
 	"github.com/cloudfoundry/bosh-agent/micro"
@@ -30,7 +32,7 @@ var _ = Describe("HandlerProvider", func() {
 		logger = boshlog.NewLogger(boshlog.LevelNone)
 		platform = fakeplatform.NewFakePlatform()
 		dirProvider = boshdir.NewProvider("/var/vcap")
-		provider = NewHandlerProvider(settingsService, logger, fakeplatform.NewFakeAuditLogger())
+		provider = NewHandlerProvider(settingsService, logger, fakeplatform.NewFakeAuditLogger(), fakeclock.NewFakeClock(time.Now()))
 	})
 
 	Describe("Get", func() {
@@ -40,7 +42,7 @@ var _ = Describe("HandlerProvider", func() {
 			Expect(err).ToNot(HaveOccurred())
 
 			// yagnats.NewClient returns new object every time
-			expectedHandler := NewNatsHandler(settingsService, yagnats.NewClient(), logger, platform)
+			expectedHandler := NewNatsHandler(settingsService, func() yagnats.NATSClient { return yagnats.NewClient() }, logger, platform, fakeclock.NewFakeClock(time.Now()))
 			Expect(reflect.TypeOf(handler)).To(Equal(reflect.TypeOf(expectedHandler)))
 		})
 
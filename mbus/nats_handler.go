@@ -13,6 +13,7 @@ import (
 	"syscall"
 
 	"github.com/cloudfoundry/yagnats"
+	"github.com/pivotal-golang/clock"
 
 	boshhandler "github.com/cloudfoundry/bosh-agent/handler"
 	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
@@ -24,6 +25,12 @@ import (
 const (
 	responseMaxLength = 1024 * 1024
 	natsHandlerLogTag = "NATS Handler"
+
+	cefSeveritySuccess = 1
+	cefSeverityError   = 7
+
+	// Rejected requests stand out from requests that failed to be handled
+	cefSeverityRejected = 10
 )
 
 type Handler interface {
@@ -31,17 +38,27 @@ type Handler interface {
 	Start(boshhandler.Func) error
 	RegisterAdditionalFunc(boshhandler.Func)
 	Send(target boshhandler.Target, topic boshhandler.Topic, message interface{}) error
+	SwitchURL(mbusURL string) error
 	Stop()
 }
 
+// NATSClientFactory creates a client for every connection
+// since switching mbus URL requires a second connection
+type NATSClientFactory func() yagnats.NATSClient
+
 type natsHandler struct {
 	settingsService boshsettings.Service
-	client          yagnats.NATSClient
+	clientFactory   NATSClientFactory
 	platform        boshplatform.Platform
 
+	client     yagnats.NATSClient
+	clientLock sync.RWMutex
+
 	handlerFuncs     []boshhandler.Func
 	handlerFuncsLock sync.Mutex
 
+	requestVerifier *requestVerifier
+
 	logger      boshlog.Logger
 	auditLogger boshplatform.AuditLogger
 	logTag      string
@@ -49,14 +66,17 @@ type natsHandler struct {
 
 func NewNatsHandler(
 	settingsService boshsettings.Service,
-	client yagnats.NATSClient,
+	clientFactory NATSClientFactory,
 	logger boshlog.Logger,
 	platform boshplatform.Platform,
+	timeService clock.Clock,
 ) Handler {
 	return &natsHandler{
 		settingsService: settingsService,
-		client:          client,
+		clientFactory:   clientFactory,
+		client:          clientFactory(),
 		platform:        platform,
+		requestVerifier: newRequestVerifier(timeService),
 
 		logger:      logger,
 		logTag:      natsHandlerLogTag,
@@ -80,12 +100,52 @@ func (h *natsHandler) Run(handlerFunc boshhandler.Func) error {
 func (h *natsHandler) Start(handlerFunc boshhandler.Func) error {
 	h.RegisterAdditionalFunc(handlerFunc)
 
-	connProvider, err := h.getConnectionInfo()
+	connProvider, err := h.getConnectionInfo(h.settingsService.GetSettings().Mbus)
 	if err != nil {
 		return bosherr.WrapError(err, "Getting connection info")
 	}
 
-	h.client.BeforeConnectCallback(func() {
+	client := h.getClient()
+
+	err = h.connect(client, connProvider)
+	if err != nil {
+		return err
+	}
+
+	return h.subscribe(client)
+}
+
+// SwitchURL connects to the new mbus URL before dropping current
+// connection so that invalid credentials never disconnect the agent
+func (h *natsHandler) SwitchURL(mbusURL string) error {
+	connProvider, err := h.getConnectionInfo(mbusURL)
+	if err != nil {
+		return bosherr.WrapError(err, "Getting connection info")
+	}
+
+	client := h.clientFactory()
+
+	err = h.connect(client, connProvider)
+	if err != nil {
+		return err
+	}
+
+	h.clientLock.Lock()
+	oldClient := h.client
+	h.client = client
+	h.clientLock.Unlock()
+
+	// Disconnect before subscribing so that
+	// no message is handled by both connections
+	oldClient.Disconnect()
+
+	h.logger.Info(h.logTag, "Switched mbus connection to %s", connProvider.Addr)
+
+	return h.subscribe(client)
+}
+
+func (h *natsHandler) connect(client yagnats.NATSClient, connProvider *yagnats.ConnectionInfo) error {
+	client.BeforeConnectCallback(func() {
 		hostSplit := strings.Split(connProvider.Addr, ":")
 		ip := hostSplit[0]
 
@@ -93,31 +153,43 @@ func (h *natsHandler) Start(handlerFunc boshhandler.Func) error {
 			return
 		}
 
-		err = h.platform.DeleteARPEntryWithIP(ip)
+		err := h.platform.DeleteARPEntryWithIP(ip)
 		if err != nil {
 			h.logger.Error(h.logTag, "Cleaning ip-mac address cache for: %s", ip)
 		}
 	})
 
-	err = h.client.Connect(connProvider)
+	err := client.Connect(connProvider)
 	if err != nil {
 		return bosherr.WrapError(err, "Connecting")
 	}
 
+	return nil
+}
+
+func (h *natsHandler) subscribe(client yagnats.NATSClient) error {
 	settings := h.settingsService.GetSettings()
 
 	subject := fmt.Sprintf("agent.%s", settings.AgentID)
 
 	h.logger.Info(h.logTag, "Subscribing to %s", subject)
 
-	_, err = h.client.Subscribe(subject, func(natsMsg *yagnats.Message) {
+	_, err := client.Subscribe(subject, func(natsMsg *yagnats.Message) {
+		// Verify once since nonce may only be used once for all handler funcs
+		payload, err := h.requestVerifier.Verify(h.settingsService.GetSettings().Env.GetMbusRequestKey(), natsMsg.Payload)
+		if err != nil {
+			h.logger.Error(h.logTag, "Rejecting request: %s", err.Error())
+			h.generateCEFLog(natsMsg, payload, cefSeverityRejected, "Rejected request: "+err.Error())
+			return
+		}
+
 		// Do not lock handler funcs around possible network calls!
 		h.handlerFuncsLock.Lock()
 		handlerFuncs := h.handlerFuncs
 		h.handlerFuncsLock.Unlock()
 
 		for _, handlerFunc := range handlerFuncs {
-			h.handleNatsMsg(natsMsg, handlerFunc)
+			h.handleNatsMsg(natsMsg, payload, handlerFunc)
 		}
 	})
 	if err != nil {
@@ -127,6 +199,13 @@ func (h *natsHandler) Start(handlerFunc boshhandler.Func) error {
 	return nil
 }
 
+func (h *natsHandler) getClient() yagnats.NATSClient {
+	h.clientLock.RLock()
+	defer h.clientLock.RUnlock()
+
+	return h.client
+}
+
 func (h *natsHandler) RegisterAdditionalFunc(handlerFunc boshhandler.Func) {
 	// Currently not locking since RegisterAdditionalFunc
 	// is not a primary way of adding handlerFunc.
@@ -147,16 +226,16 @@ func (h *natsHandler) Send(target boshhandler.Target, topic boshhandler.Topic, m
 	settings := h.settingsService.GetSettings()
 
 	subject := fmt.Sprintf("%s.agent.%s.%s", target, topic, settings.AgentID)
-	return h.client.Publish(subject, bytes)
+	return h.getClient().Publish(subject, bytes)
 }
 
 func (h *natsHandler) Stop() {
-	h.client.Disconnect()
+	h.getClient().Disconnect()
 }
 
-func (h *natsHandler) handleNatsMsg(natsMsg *yagnats.Message, handlerFunc boshhandler.Func) {
+func (h *natsHandler) handleNatsMsg(natsMsg *yagnats.Message, payload []byte, handlerFunc boshhandler.Func) {
 	respBytes, req, err := boshhandler.PerformHandlerWithJSON(
-		natsMsg.Payload,
+		payload,
 		handlerFunc,
 		responseMaxLength,
 		h.logger,
@@ -164,24 +243,24 @@ func (h *natsHandler) handleNatsMsg(natsMsg *yagnats.Message, handlerFunc boshha
 
 	if err != nil {
 		h.logger.Error(h.logTag, "Running handler: %s", err)
-		h.generateCEFLog(natsMsg, 7, err.Error())
+		h.generateCEFLog(natsMsg, payload, cefSeverityError, err.Error())
 		return
 	}
 
 	if len(respBytes) > 0 {
-		err = h.client.Publish(req.ReplyTo, respBytes)
+		err = h.getClient().Publish(req.ReplyTo, respBytes)
 		if err != nil {
-			h.generateCEFLog(natsMsg, 7, err.Error())
+			h.generateCEFLog(natsMsg, payload, cefSeverityError, err.Error())
 			h.logger.Error(h.logTag, "Publishing to the client: %s", err.Error())
 			return
 		}
 	}
 
-	h.generateCEFLog(natsMsg, 1, "")
+	h.generateCEFLog(natsMsg, payload, cefSeveritySuccess, "")
 }
 
 func (h *natsHandler) runUntilInterrupted() {
-	defer h.client.Disconnect()
+	defer h.Stop()
 
 	keepRunning := true
 
@@ -196,10 +275,8 @@ func (h *natsHandler) runUntilInterrupted() {
 	}
 }
 
-func (h *natsHandler) getConnectionInfo() (*yagnats.ConnectionInfo, error) {
-	settings := h.settingsService.GetSettings()
-
-	natsURL, err := url.Parse(settings.Mbus)
+func (h *natsHandler) getConnectionInfo(mbusURL string) (*yagnats.ConnectionInfo, error) {
+	natsURL, err := url.Parse(mbusURL)
 	if err != nil {
 		return nil, bosherr.WrapError(err, "Parsing Nats URL")
 	}
@@ -220,7 +297,8 @@ func (h *natsHandler) getConnectionInfo() (*yagnats.ConnectionInfo, error) {
 	return connInfo, nil
 }
 
-func (h *natsHandler) generateCEFLog(natsMsg *yagnats.Message, severity int, statusReason string) {
+// generateCEFLog takes request payload separately since it may have been enveloped
+func (h *natsHandler) generateCEFLog(natsMsg *yagnats.Message, requestPayload []byte, severity int, statusReason string) {
 	cef := boshhandler.NewCommonEventFormat()
 
 	settings := h.settingsService.GetSettings()
@@ -237,7 +315,7 @@ func (h *natsHandler) generateCEFLog(natsMsg *yagnats.Message, severity int, sta
 		Method  string `json:"method"`
 		ReplyTo string `json:"reply_to"`
 	}{}
-	err = json.Unmarshal(natsMsg.Payload, &payload)
+	err = json.Unmarshal(requestPayload, &payload)
 	if err != nil {
 		h.logger.Error(natsHandlerLogTag, err.Error())
 	}
@@ -248,7 +326,7 @@ func (h *natsHandler) generateCEFLog(natsMsg *yagnats.Message, severity int, sta
 		return
 	}
 
-	if severity == 7 {
+	if severity >= cefSeverityError {
 		h.auditLogger.Err(cefString)
 		return
 	}
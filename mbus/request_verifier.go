@@ -0,0 +1,113 @@
+package mbus
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pivotal-golang/clock"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// Requests are only accepted within clock skew of agent time
+// so that nonces only need to be remembered for as long
+const requestClockSkew = 5 * time.Minute
+
+// requestEnvelope protects a request against being replayed;
+// HMAC is hex encoded HMAC-SHA256 of "<timestamp>\n<nonce>\n<request>"
+type requestEnvelope struct {
+	Request   json.RawMessage `json:"request"`
+	Timestamp int64           `json:"timestamp"`
+	Nonce     string          `json:"nonce"`
+	HMAC      string          `json:"hmac"`
+}
+
+// requestVerifier keeps used nonces in memory; requests replayed
+// after agent restarts are still rejected once outside of clock skew
+type requestVerifier struct {
+	timeService clock.Clock
+
+	nonces     map[string]time.Time
+	noncesLock sync.Mutex
+}
+
+func newRequestVerifier(timeService clock.Clock) *requestVerifier {
+	return &requestVerifier{
+		timeService: timeService,
+		nonces:      map[string]time.Time{},
+	}
+}
+
+// Verify returns request carried by payload. Envelopes are required when key is set,
+// otherwise they are optional and not verified. Request is returned even when
+// verification fails so that rejected request can be logged.
+func (v *requestVerifier) Verify(key string, payload []byte) ([]byte, error) {
+	var envelope requestEnvelope
+
+	err := json.Unmarshal(payload, &envelope)
+	if err != nil || len(envelope.Request) == 0 {
+		if key != "" {
+			return payload, bosherr.Error("Request is not enveloped")
+		}
+		return payload, nil
+	}
+
+	if key == "" {
+		return envelope.Request, nil
+	}
+
+	if !hmac.Equal([]byte(envelope.HMAC), []byte(requestHMAC(key, envelope))) {
+		return envelope.Request, bosherr.Error("Request HMAC does not match")
+	}
+
+	timestamp := time.Unix(envelope.Timestamp, 0)
+	now := v.timeService.Now()
+
+	if timestamp.Before(now.Add(-requestClockSkew)) || timestamp.After(now.Add(requestClockSkew)) {
+		return envelope.Request, bosherr.Errorf(
+			"Request timestamp %s is outside of allowed clock skew", timestamp.UTC().Format(time.RFC3339))
+	}
+
+	if envelope.Nonce == "" {
+		return envelope.Request, bosherr.Error("Request nonce is empty")
+	}
+
+	err = v.useNonce(envelope.Nonce, timestamp, now)
+	if err != nil {
+		return envelope.Request, err
+	}
+
+	return envelope.Request, nil
+}
+
+func (v *requestVerifier) useNonce(nonce string, timestamp, now time.Time) error {
+	v.noncesLock.Lock()
+	defer v.noncesLock.Unlock()
+
+	// Requests with nonces older than clock skew are rejected by timestamp
+	for usedNonce, usedTimestamp := range v.nonces {
+		if usedTimestamp.Before(now.Add(-requestClockSkew)) {
+			delete(v.nonces, usedNonce)
+		}
+	}
+
+	if _, found := v.nonces[nonce]; found {
+		return bosherr.Errorf("Request nonce '%s' was already used", nonce)
+	}
+
+	v.nonces[nonce] = timestamp
+
+	return nil
+}
+
+func requestHMAC(key string, envelope requestEnvelope) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	fmt.Fprintf(mac, "%d\n%s\n", envelope.Timestamp, envelope.Nonce)
+	mac.Write(envelope.Request)
+	return hex.EncodeToString(mac.Sum(nil))
+}
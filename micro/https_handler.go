@@ -2,6 +2,7 @@ package micro
 
 import (
 	"bufio"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -71,6 +72,10 @@ func (h HTTPSHandler) Send(target boshhandler.Target, topic boshhandler.Topic, m
 	return nil
 }
 
+func (h HTTPSHandler) SwitchURL(mbusURL string) error {
+	return bosherr.Error("HTTPSHandler does not support switching mbus URL")
+}
+
 func (h HTTPSHandler) agentHandler(handlerFunc boshhandler.Func) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "POST" {
@@ -92,7 +97,10 @@ func (h HTTPSHandler) agentHandler(handlerFunc boshhandler.Func) func(http.Respo
 
 		respBytes, _, err := boshhandler.PerformHandlerWithJSON(
 			rawJSONPayload,
-			handlerFunc,
+			func(req boshhandler.Request) boshhandler.Response {
+				req.Caller = httpsCaller(r)
+				return handlerFunc(req)
+			},
 			boshhandler.UnlimitedResponseLength,
 			h.logger,
 		)
@@ -185,6 +193,14 @@ func (h HTTPSHandler) generateCEFLog(r *http.Request, respStatusCode int, respJS
 	h.auditLogger.Debug(cefString)
 }
 
+// httpsCaller identifies authenticated mbus user and address request came from
+func httpsCaller(r *http.Request) string {
+	if username, _, ok := r.BasicAuth(); ok {
+		return fmt.Sprintf("%s@%s", username, r.RemoteAddr)
+	}
+	return r.RemoteAddr
+}
+
 // Utils:
 
 type concreteHTTPHandler struct {
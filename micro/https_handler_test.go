@@ -68,6 +68,7 @@ var _ = Describe("HTTPSHandler", func() {
 			Expect(receivedRequest.ReplyTo).To(Equal("reply to me!"))
 			Expect(receivedRequest.Method).To(Equal("ping"))
 			Expect(receivedRequest.GetPayload()).To(Equal([]byte(postBody)))
+			Expect(receivedRequest.Caller).To(HavePrefix("user@127.0.0.1:"))
 
 			httpBody, readErr := ioutil.ReadAll(httpResponse.Body)
 			Expect(readErr).ToNot(HaveOccurred())
@@ -33,6 +33,11 @@ type Manager interface {
 	UpdateCertificates(certs string) error
 }
 
+const (
+	ubuntuUpdateCertsCmdPath = "/usr/sbin/update-ca-certificates"
+	centOSUpdateCertsCmdPath = "/usr/bin/update-ca-trust"
+)
+
 type certManager struct {
 	fs            boshsys.FileSystem
 	runner        boshsys.CmdRunner
@@ -51,7 +56,7 @@ func NewUbuntuCertManager(fs boshsys.FileSystem, runner boshsys.CmdRunner, timeo
 		fs:            fs,
 		runner:        runner,
 		path:          "/usr/local/share/ca-certificates/",
-		updateCmdPath: "/usr/sbin/update-ca-certificates",
+		updateCmdPath: ubuntuUpdateCertsCmdPath,
 		updateCmdArgs: []string{"-f"},
 		logger:        logger,
 		logTag:        "UbuntuCertManager",
@@ -64,13 +69,23 @@ func NewCentOSCertManager(fs boshsys.FileSystem, runner boshsys.CmdRunner, timeo
 		fs:            fs,
 		runner:        runner,
 		path:          "/etc/pki/ca-trust/source/anchors/",
-		updateCmdPath: "/usr/bin/update-ca-trust",
+		updateCmdPath: centOSUpdateCertsCmdPath,
 		logger:        logger,
 		logTag:        "CentOSCertManager",
 		updateTimeout: timeout,
 	}
 }
 
+// UbuntuCertManagerCommands lists external commands Ubuntu cert manager shells out to
+func UbuntuCertManagerCommands() []string {
+	return []string{ubuntuUpdateCertsCmdPath}
+}
+
+// CentOSCertManagerCommands lists external commands CentOS cert manager shells out to
+func CentOSCertManagerCommands() []string {
+	return []string{centOSUpdateCertsCmdPath}
+}
+
 func NewDummyCertManager(fs boshsys.FileSystem, runner boshsys.CmdRunner, timeout time.Duration, logger logger.Logger) Manager {
 	return &certManager{
 		fs:            fs,
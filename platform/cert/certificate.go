@@ -0,0 +1,49 @@
+package cert
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// Certificate describes a trusted CA certificate
+// without exposing its encoded contents
+type Certificate struct {
+	Subject     string    `json:"subject"`
+	Issuer      string    `json:"issuer"`
+	Fingerprint string    `json:"fingerprint"`
+	Expiry      time.Time `json:"expiry"`
+}
+
+// ParseCertificates describes each PEM certificate in the given string
+// in the same order as they are passed to UpdateCertificates
+func ParseCertificates(certs string) ([]Certificate, error) {
+	result := []Certificate{}
+
+	for i, pemCert := range splitCerts(certs) {
+		block, _ := pem.Decode([]byte(pemCert))
+		if block == nil {
+			return nil, bosherr.Errorf("Decoding trusted certificate %d", i+1)
+		}
+
+		x509Cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Parsing trusted certificate %d", i+1)
+		}
+
+		fingerprint := sha256.Sum256(x509Cert.Raw)
+
+		result = append(result, Certificate{
+			Subject:     x509Cert.Subject.String(),
+			Issuer:      x509Cert.Issuer.String(),
+			Fingerprint: "sha256:" + hex.EncodeToString(fingerprint[:]),
+			Expiry:      x509Cert.NotAfter.UTC(),
+		})
+	}
+
+	return result, nil
+}
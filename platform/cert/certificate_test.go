@@ -0,0 +1,51 @@
+package cert_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/bosh-agent/platform/cert"
+)
+
+// Self-signed certificate valid until 2036-10-16
+const fakeCACert = `-----BEGIN CERTIFICATE-----
+MIIBmDCCAT2gAwIBAgIUHV7zfsuSIWe9qdKgl/GwROlB5FswCgYIKoZIzj0EAwIw
+ITENMAsGA1UECgwEYm9zaDEQMA4GA1UEAwwHZmFrZS1jYTAeFw0yNjEwMTkwNjU2
+MTVaFw0zNjEwMTYwNjU2MTVaMCExDTALBgNVBAoMBGJvc2gxEDAOBgNVBAMMB2Zh
+a2UtY2EwWTATBgcqhkjOPQIBBggqhkjOPQMBBwNCAATDycAHSAx59KnbI8+m6z/f
+6tkezbbPOxEPMZ1Upq1UCne++AYEaJfOjaopdaFO/h8oM6JAO5WYiaUJrYpeNbNx
+o1MwUTAdBgNVHQ4EFgQUBA2pcSpl1SkAaE3FR34vlYAUFqMwHwYDVR0jBBgwFoAU
+BA2pcSpl1SkAaE3FR34vlYAUFqMwDwYDVR0TAQH/BAUwAwEB/zAKBggqhkjOPQQD
+AgNJADBGAiEA5sNiaOkavlXIcUkzmh3g66ZnAIjQ0J+6B/7lSSkgZ8UCIQDxuXdY
+D5e6cymwBfeY1Fm3kS1ivC+SHtIsRtq38CpI1g==
+-----END CERTIFICATE-----`
+
+var _ = Describe("ParseCertificates", func() {
+	It("describes each certificate", func() {
+		certs, err := cert.ParseCertificates("junk\n" + fakeCACert + "\njunk\n" + fakeCACert)
+		Expect(err).ToNot(HaveOccurred())
+
+		expectedCert := cert.Certificate{
+			Subject:     "CN=fake-ca,O=bosh",
+			Issuer:      "CN=fake-ca,O=bosh",
+			Fingerprint: "sha256:5c2763e799f5453c4979a5b923ce9a7d25b17090b25aa369a5f2a9765eb7327f",
+			Expiry:      time.Date(2036, time.October, 16, 6, 56, 15, 0, time.UTC),
+		}
+
+		Expect(certs).To(Equal([]cert.Certificate{expectedCert, expectedCert}))
+	})
+
+	It("returns no certificates for an empty string", func() {
+		certs, err := cert.ParseCertificates("")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(certs).To(BeEmpty())
+	})
+
+	It("returns an error when certificate cannot be parsed", func() {
+		_, err := cert.ParseCertificates("-----BEGIN CERTIFICATE-----\nZmFrZS1jZXJ0\n-----END CERTIFICATE-----")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Parsing trusted certificate 1"))
+	})
+})
@@ -31,6 +31,11 @@ func NewWindowsCertManager(fs boshsys.FileSystem, runner boshsys.CmdRunner, dirP
 	}
 }
 
+// WindowsCertManagerCommands lists external commands windowsCertManager shells out to
+func WindowsCertManagerCommands() []string {
+	return []string{"powershell"}
+}
+
 func (c *windowsCertManager) createBackup() error {
 	if _, err := os.Stat(c.backupPath); os.IsNotExist(err) {
 		err = c.fs.MkdirAll(c.dirProvider.TmpDir(), os.FileMode(0777))
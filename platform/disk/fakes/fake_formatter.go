@@ -8,15 +8,17 @@ type FakeFormatter struct {
 	FormatCalled         bool
 	FormatPartitionPaths []string
 	FormatFsTypes        []boshdisk.FileSystemType
+	FormatMkfsOptions    [][]string
 	FormatError          error
 }
 
-func (p *FakeFormatter) Format(partitionPath string, fsType boshdisk.FileSystemType) (err error) {
+func (p *FakeFormatter) Format(partitionPath string, fsType boshdisk.FileSystemType, mkfsOptions ...string) (err error) {
 	if p.FormatError != nil {
 		return p.FormatError
 	}
 	p.FormatCalled = true
 	p.FormatPartitionPaths = append(p.FormatPartitionPaths, partitionPath)
 	p.FormatFsTypes = append(p.FormatFsTypes, fsType)
+	p.FormatMkfsOptions = append(p.FormatMkfsOptions, mkfsOptions)
 	return
 }
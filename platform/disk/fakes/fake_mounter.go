@@ -24,9 +24,11 @@ type FakeMounter struct {
 	SwapOnPartitionPaths []string
 	SwapOnErr            error
 
-	UnmountPartitionPathOrMountPoint string
-	UnmountDidUnmount                bool
-	UnmountErr                       error
+	UnmountPartitionPathOrMountPoint   string
+	UnmountPartitionPathsOrMountPoints []string
+	UnmountStub                        func(string) (bool, error)
+	UnmountDidUnmount                  bool
+	UnmountErr                         error
 
 	IsMountPointPath          string
 	IsMountPointPartitionPath string
@@ -67,6 +69,10 @@ func (m *FakeMounter) SwapOn(partitionPath string) (err error) {
 
 func (m *FakeMounter) Unmount(partitionPathOrMountPoint string) (didUnmount bool, err error) {
 	m.UnmountPartitionPathOrMountPoint = partitionPathOrMountPoint
+	m.UnmountPartitionPathsOrMountPoints = append(m.UnmountPartitionPathsOrMountPoints, partitionPathOrMountPoint)
+	if m.UnmountStub != nil {
+		return m.UnmountStub(partitionPathOrMountPoint)
+	}
 	return m.UnmountDidUnmount, m.UnmountErr
 }
 
@@ -13,11 +13,18 @@ type FakePartitioner struct {
 	GetDeviceSizeInBytesDevicePath string
 	GetDeviceSizeInBytesSizes      map[string]uint64
 	GetDeviceSizeInBytesErr        error
+
+	GetPartitionsDevicePaths []string
+	GetPartitionsPartitions  map[string][]boshdisk.Partition
+	GetPartitionsIsGPT       map[string]bool
+	GetPartitionsErr         error
 }
 
 func NewFakePartitioner() *FakePartitioner {
 	return &FakePartitioner{
 		GetDeviceSizeInBytesSizes: make(map[string]uint64),
+		GetPartitionsPartitions:   make(map[string][]boshdisk.Partition),
+		GetPartitionsIsGPT:        make(map[string]bool),
 	}
 }
 
@@ -32,3 +39,8 @@ func (p *FakePartitioner) GetDeviceSizeInBytes(devicePath string) (uint64, error
 	p.GetDeviceSizeInBytesDevicePath = devicePath
 	return p.GetDeviceSizeInBytesSizes[devicePath], p.GetDeviceSizeInBytesErr
 }
+
+func (p *FakePartitioner) GetPartitions(devicePath string) ([]boshdisk.Partition, bool, error) {
+	p.GetPartitionsDevicePaths = append(p.GetPartitionsDevicePaths, devicePath)
+	return p.GetPartitionsPartitions[devicePath], p.GetPartitionsIsGPT[devicePath], p.GetPartitionsErr
+}
@@ -0,0 +1,75 @@
+package disk
+
+import (
+	"strconv"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// Mount options that may be requested for persistent disks
+var allowedMountOptions = map[string]bool{
+	"noatime":    true,
+	"nodiratime": true,
+	"relatime":   true,
+	"discard":    true,
+}
+
+// mkfs options that may be requested for persistent disks
+// mapped to the mke2fs flags they translate to
+var allowedExt4MkfsOptions = map[string]string{
+	// Bytes per inode
+	"inode_ratio": "-i",
+
+	// Percentage of filesystem blocks reserved for the super-user
+	"reserved_blocks": "-m",
+}
+
+// MountOptionsArgs validates mount options against the allowlist
+// and converts them into arguments for the mount command
+func MountOptionsArgs(options []string) ([]string, error) {
+	if len(options) == 0 {
+		return nil, nil
+	}
+
+	for _, option := range options {
+		if !allowedMountOptions[option] {
+			return nil, bosherr.Errorf("The mount option \"%s\" is not supported", option)
+		}
+	}
+
+	return []string{"-o", strings.Join(options, ",")}, nil
+}
+
+// MkfsOptionsArgs validates mkfs options (e.g. "inode_ratio=16384") against the allowlist
+// for the filesystem type and converts them into arguments for its mkfs command
+func MkfsOptionsArgs(fsType FileSystemType, options []string) ([]string, error) {
+	var args []string
+
+	for _, option := range options {
+		nameAndValue := strings.SplitN(option, "=", 2)
+		if len(nameAndValue) != 2 {
+			return nil, bosherr.Errorf("The mkfs option \"%s\" must be in the form name=value", option)
+		}
+
+		name, value := nameAndValue[0], nameAndValue[1]
+
+		flag, found := allowedExt4MkfsOptions[name]
+		if !found {
+			return nil, bosherr.Errorf("The mkfs option \"%s\" is not supported", name)
+		}
+
+		if fsType != FileSystemExt4 {
+			return nil, bosherr.Errorf("The mkfs option \"%s\" is not supported for filesystem type \"%s\"", name, fsType)
+		}
+
+		_, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, bosherr.Errorf("The mkfs option \"%s\" must have a numeric value", name)
+		}
+
+		args = append(args, flag, value)
+	}
+
+	return args, nil
+}
@@ -0,0 +1,68 @@
+package disk_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/disk"
+)
+
+var _ = Describe("Filesystem options", func() {
+	Describe("MountOptionsArgs", func() {
+		It("returns no arguments when there are no options", func() {
+			args, err := MountOptionsArgs(nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(args).To(BeNil())
+		})
+
+		It("joins allowed options into single -o argument", func() {
+			args, err := MountOptionsArgs([]string{"noatime", "discard", "relatime"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(args).To(Equal([]string{"-o", "noatime,discard,relatime"}))
+		})
+
+		It("returns an error when option is not allowed", func() {
+			_, err := MountOptionsArgs([]string{"noatime", "exec,suid"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal(`The mount option "exec,suid" is not supported`))
+		})
+
+		It("returns an error for nobarrier since it risks filesystem corruption on power loss", func() {
+			_, err := MountOptionsArgs([]string{"nobarrier"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal(`The mount option "nobarrier" is not supported`))
+		})
+	})
+
+	Describe("MkfsOptionsArgs", func() {
+		It("translates ext4 options into mke2fs flags", func() {
+			args, err := MkfsOptionsArgs(FileSystemExt4, []string{"inode_ratio=16384", "reserved_blocks=0"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(args).To(Equal([]string{"-i", "16384", "-m", "0"}))
+		})
+
+		It("returns an error when option is not in the form name=value", func() {
+			_, err := MkfsOptionsArgs(FileSystemExt4, []string{"-F"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal(`The mkfs option "-F" must be in the form name=value`))
+		})
+
+		It("returns an error when option is not allowed", func() {
+			_, err := MkfsOptionsArgs(FileSystemExt4, []string{"features=^has_journal"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal(`The mkfs option "features" is not supported`))
+		})
+
+		It("returns an error when option value is not numeric", func() {
+			_, err := MkfsOptionsArgs(FileSystemExt4, []string{"inode_ratio=1 -F"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal(`The mkfs option "inode_ratio" must have a numeric value`))
+		})
+
+		It("returns an error when option is not supported by filesystem type", func() {
+			_, err := MkfsOptionsArgs(FileSystemBTRFS, []string{"reserved_blocks=1"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal(`The mkfs option "reserved_blocks" is not supported for filesystem type "btrfs"`))
+		})
+	})
+})
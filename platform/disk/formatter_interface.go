@@ -6,9 +6,10 @@ const (
 	FileSystemSwap    FileSystemType = "swap"
 	FileSystemExt4    FileSystemType = "ext4"
 	FileSystemXFS     FileSystemType = "xfs"
+	FileSystemBTRFS   FileSystemType = "btrfs"
 	FileSystemDefault FileSystemType = ""
 )
 
 type Formatter interface {
-	Format(partitionPath string, fsType FileSystemType) (err error)
+	Format(partitionPath string, fsType FileSystemType, mkfsOptions ...string) (err error)
 }
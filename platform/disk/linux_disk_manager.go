@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"time"
 
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 	"github.com/pivotal-golang/clock"
@@ -56,8 +57,15 @@ func NewLinuxDiskManager(
 	}
 
 	var partitioner Partitioner
+	partedPartitioner := NewPartedPartitioner(logger, runner, clock.NewClock())
+	rootDevicePartitioner := NewRootDevicePartitioner(logger, runner, uint64(20*1024*1024))
 
 	switch opts.PartitionerType {
+	case "native":
+		// Native partitioner handles disks of any size and root device as well
+		partitioner = NewNativePartitioner(logger, fs)
+		partedPartitioner = partitioner
+		rootDevicePartitioner = NewNativeRootDevicePartitioner(logger, fs, uint64(20*1024*1024))
 	case "parted":
 		partitioner = NewPartedPartitioner(logger, runner, clock.NewClock())
 	case "":
@@ -68,8 +76,8 @@ func NewLinuxDiskManager(
 
 	return linuxDiskManager{
 		partitioner:           partitioner,
-		rootDevicePartitioner: NewRootDevicePartitioner(logger, runner, uint64(20*1024*1024)),
-		partedPartitioner:     NewPartedPartitioner(logger, runner, clock.NewClock()),
+		rootDevicePartitioner: rootDevicePartitioner,
+		partedPartitioner:     partedPartitioner,
 		formatter:             NewLinuxFormatter(runner, fs),
 		mounter:               mounter,
 		mountsSearcher:        mountsSearcher,
@@ -79,6 +87,32 @@ func NewLinuxDiskManager(
 	}
 }
 
+// LinuxDiskManagerCommands lists external commands linuxDiskManager shells out to
+// when configured with opts. Tools such as mkfs.xfs are optional since they
+// are only needed by disks which explicitly ask for such filesystem type.
+func LinuxDiskManagerCommands(opts LinuxDiskManagerOpts) ([]string, error) {
+	commands := []string{"lsblk", "blkid", "mke2fs", "mkswap", "mount", "umount", "swapon"}
+
+	switch opts.PartitionerType {
+	case "native":
+	case "parted":
+		commands = append(commands, "parted", "dmsetup")
+	case "":
+		// Root device and large disks are partitioned with parted
+		commands = append(commands, "sfdisk", "parted", "dmsetup")
+	default:
+		return nil, bosherr.Errorf("Unknown partitioner type '%s'", opts.PartitionerType)
+	}
+
+	return commands, nil
+}
+
+// LinuxDiskManagerOptionalCommands lists commands only needed by xfs and btrfs
+// persistent disks and by multipath disks attached through open-iscsi
+func LinuxDiskManagerOptionalCommands() []string {
+	return []string{"mkfs.xfs", "mkfs.btrfs", "/etc/init.d/open-iscsi"}
+}
+
 func (m linuxDiskManager) GetPartitioner() Partitioner           { return m.partitioner }
 func (m linuxDiskManager) GetPartedPartitioner() Partitioner     { return m.partedPartitioner }
 func (m linuxDiskManager) GetRootDevicePartitioner() Partitioner { return m.rootDevicePartitioner }
@@ -62,6 +62,16 @@ var _ = Describe("NewLinuxDiskManager", func() {
 		})
 	})
 
+	Context("when partitioner type is 'native'", func() {
+		It("returns disk manager configured to use native partitioners only", func() {
+			opts := LinuxDiskManagerOpts{PartitionerType: "native"}
+			diskManager := NewLinuxDiskManager(logger, runner, fs, opts)
+			Expect(diskManager.GetPartitioner()).To(Equal(NewNativePartitioner(logger, fs)))
+			Expect(diskManager.GetPartedPartitioner()).To(Equal(NewNativePartitioner(logger, fs)))
+			Expect(diskManager.GetRootDevicePartitioner()).To(Equal(NewNativeRootDevicePartitioner(logger, fs, 20*1024*1024)))
+		})
+	})
+
 	Context("when partitioner type is unknown", func() {
 		It("panics", func() {
 			opts := LinuxDiskManagerOpts{PartitionerType: "unknown"}
@@ -19,7 +19,12 @@ func NewLinuxFormatter(runner boshsys.CmdRunner, fs boshsys.FileSystem) Formatte
 	}
 }
 
-func (f linuxFormatter) Format(partitionPath string, fsType FileSystemType) (err error) {
+func (f linuxFormatter) Format(partitionPath string, fsType FileSystemType, mkfsOptions ...string) (err error) {
+	mkfsArgs, err := MkfsOptionsArgs(fsType, mkfsOptions)
+	if err != nil {
+		return bosherr.WrapError(err, "Validating mkfs options")
+	}
+
 	existingFsType, err := f.getPartitionFormatType(partitionPath)
 	if err != nil {
 		return bosherr.WrapError(err, "Checking filesystem format of partition")
@@ -30,7 +35,7 @@ func (f linuxFormatter) Format(partitionPath string, fsType FileSystemType) (err
 			return
 		}
 		// swap is not user-configured, so we're not concerned about reformatting
-	} else if existingFsType == FileSystemExt4 || existingFsType == FileSystemXFS {
+	} else if existingFsType == FileSystemExt4 || existingFsType == FileSystemXFS || existingFsType == FileSystemBTRFS {
 		// never reformat if it is already formatted in a supported format
 		return
 	}
@@ -43,10 +48,10 @@ func (f linuxFormatter) Format(partitionPath string, fsType FileSystemType) (err
 		}
 
 	case FileSystemExt4:
-		err = f.makeFileSystemExt4(partitionPath)
+		err = f.makeFileSystemExt4(partitionPath, mkfsArgs)
 		if err != nil {
 			if strings.Contains(err.Error(), "apparently in use by the system") {
-				err = f.makeFileSystemExt4(partitionPath)
+				err = f.makeFileSystemExt4(partitionPath, mkfsArgs)
 			}
 		}
 		if err != nil {
@@ -54,21 +59,29 @@ func (f linuxFormatter) Format(partitionPath string, fsType FileSystemType) (err
 		}
 
 	case FileSystemXFS:
-		_, _, _, err = f.runner.RunCommand("mkfs.xfs", partitionPath)
+		_, _, _, err = f.runner.RunCommand("mkfs.xfs", append(mkfsArgs, partitionPath)...)
 		if err != nil {
 			err = bosherr.WrapError(err, "Shelling out to mkfs.xfs")
 		}
+
+	case FileSystemBTRFS:
+		_, _, _, err = f.runner.RunCommand("mkfs.btrfs", append(mkfsArgs, partitionPath)...)
+		if err != nil {
+			err = bosherr.WrapError(err, "Shelling out to mkfs.btrfs")
+		}
 	}
 	return
 }
 
-func (f linuxFormatter) makeFileSystemExt4(partitionPath string) error {
-	var err error
+func (f linuxFormatter) makeFileSystemExt4(partitionPath string, mkfsArgs []string) error {
+	args := []string{"-t", string(FileSystemExt4), "-j"}
 	if f.fs.FileExists("/sys/fs/ext4/features/lazy_itable_init") {
-		_, _, _, err = f.runner.RunCommand("mke2fs", "-t", string(FileSystemExt4), "-j", "-E", "lazy_itable_init=1", partitionPath)
-	} else {
-		_, _, _, err = f.runner.RunCommand("mke2fs", "-t", string(FileSystemExt4), "-j", partitionPath)
+		args = append(args, "-E", "lazy_itable_init=1")
 	}
+	args = append(args, mkfsArgs...)
+	args = append(args, partitionPath)
+
+	_, _, _, err := f.runner.RunCommand("mke2fs", args...)
 	return err
 }
 
@@ -205,4 +205,71 @@ var _ = Describe("Linux Formatter", func() {
 			Expect(err.Error()).To(Equal("Shelling out to mkfs.xfs: Sadness"))
 		})
 	})
+
+	Describe("when using btrfs", func() {
+		It("formats a blank disk with type btrfs", func() {
+			fakeRunner := fakesys.NewFakeCmdRunner()
+			fakeFs := fakesys.NewFakeFileSystem()
+			fakeRunner.AddCmdResult("blkid -p /dev/xvda2", fakesys.FakeCmdResult{ExitStatus: 2, Error: errors.New("Exit code 2")})
+
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
+			err := formatter.Format("/dev/xvda2", FileSystemBTRFS)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(2).To(Equal(len(fakeRunner.RunCommands)))
+			Expect(fakeRunner.RunCommands[1]).To(Equal([]string{"mkfs.btrfs", "/dev/xvda2"}))
+		})
+
+		It("does not re-format if fs is already btrfs", func() {
+			fakeRunner := fakesys.NewFakeCmdRunner()
+			fakeFs := fakesys.NewFakeFileSystem()
+			fakeRunner.AddCmdResult("blkid -p /dev/xvda1", fakesys.FakeCmdResult{Stdout: `xxxxx TYPE="btrfs" yyyy zzzz`})
+
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
+			formatter.Format("/dev/xvda1", FileSystemBTRFS)
+
+			Expect(1).To(Equal(len(fakeRunner.RunCommands)))
+			Expect(fakeRunner.RunCommands[0]).To(Equal([]string{"blkid", "-p", "/dev/xvda1"}))
+		})
+
+		It("throws an error if formatting filesystem fails", func() {
+			fakeRunner := fakesys.NewFakeCmdRunner()
+			fakeFs := fakesys.NewFakeFileSystem()
+			fakeRunner.AddCmdResult("mkfs.btrfs /dev/xvda2", fakesys.FakeCmdResult{Error: errors.New("Sadness")})
+			fakeRunner.AddCmdResult("blkid -p /dev/xvda2", fakesys.FakeCmdResult{Stderr: "", ExitStatus: 2})
+
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
+			err := formatter.Format("/dev/xvda2", FileSystemBTRFS)
+
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Shelling out to mkfs.btrfs: Sadness"))
+		})
+	})
+
+	Describe("when using mkfs options", func() {
+		It("passes them to mke2fs", func() {
+			fakeRunner := fakesys.NewFakeCmdRunner()
+			fakeFs := fakesys.NewFakeFileSystem()
+			fakeRunner.AddCmdResult("blkid -p /dev/xvda2", fakesys.FakeCmdResult{ExitStatus: 2, Error: errors.New("Exit code 2")})
+
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
+			err := formatter.Format("/dev/xvda2", FileSystemExt4, "inode_ratio=65536", "reserved_blocks=1")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(2).To(Equal(len(fakeRunner.RunCommands)))
+			Expect(fakeRunner.RunCommands[1]).To(Equal([]string{"mke2fs", "-t", "ext4", "-j", "-i", "65536", "-m", "1", "/dev/xvda2"}))
+		})
+
+		It("returns an error without formatting when options are not supported", func() {
+			fakeRunner := fakesys.NewFakeCmdRunner()
+			fakeFs := fakesys.NewFakeFileSystem()
+
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
+			err := formatter.Format("/dev/xvda2", FileSystemXFS, "inode_ratio=65536")
+
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(`The mkfs option "inode_ratio" is not supported for filesystem type "xfs"`))
+			Expect(fakeRunner.RunCommands).To(BeEmpty())
+		})
+	})
 })
@@ -0,0 +1,271 @@
+package disk
+
+import (
+	"os"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	// Used for image files and when device does not report its logical sector size
+	nativeDefaultSectorSize = 512
+
+	// To support optimal reads on HDDs and optimal erasure on SSD: use 1MiB partition alignments.
+	nativeAlignmentInBytes = 1048576
+)
+
+// nativePartitioner reads and writes MBR or GPT directly on the device
+// instead of shelling out to sfdisk or parted. Existing GPT is kept as GPT;
+// otherwise MBR is used as long as partitions fit into it.
+type nativePartitioner struct {
+	fs     boshsys.FileSystem
+	logger boshlog.Logger
+	logTag string
+}
+
+func NewNativePartitioner(logger boshlog.Logger, fs boshsys.FileSystem) Partitioner {
+	return nativePartitioner{
+		fs:     fs,
+		logger: logger,
+		logTag: "NativePartitioner",
+	}
+}
+
+func (p nativePartitioner) Partition(devicePath string, partitions []Partition) error {
+	if strings.Contains(devicePath, "/dev/mapper/") {
+		return bosherr.Errorf("Partitioning multipath device `%s' is not supported by native partitioner", devicePath)
+	}
+
+	device, table, err := openPartitionTable(p.fs, devicePath)
+	if err != nil {
+		return err
+	}
+
+	defer device.Close()
+
+	p.logger.Debug(p.logTag, "Current %s partitions of `%s': %#v", table.Kind, devicePath, table.Entries)
+
+	if p.partitionsMatch(table, partitions) {
+		p.logger.Info(p.logTag, "%s already partitioned as expected, skipping", devicePath)
+		return nil
+	}
+
+	if table.Kind != partitionTableKindGPT && (len(partitions) > mbrEntryCount || table.SectorCount > mbrMaxSectorCount || hasNames(partitions)) {
+		table.Kind = partitionTableKindGPT
+	}
+
+	// Only diskGUID of existing GPT is kept
+	table.Entries = []partitionEntry{}
+
+	err = appendPartitions(&table, table.FirstUsableLBA(), partitions, p.logger, p.logTag)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Partitioning disk `%s'", devicePath)
+	}
+
+	err = commitPartitionTable(device, table)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Partitioning disk `%s'", devicePath)
+	}
+
+	p.logger.Info(p.logTag, "Succeeded in partitioning %s with %s %#v", devicePath, table.Kind, table.Entries)
+
+	return nil
+}
+
+func (p nativePartitioner) GetDeviceSizeInBytes(devicePath string) (uint64, error) {
+	device, err := p.fs.OpenFile(devicePath, os.O_RDONLY, 0)
+	if err != nil {
+		return 0, bosherr.WrapErrorf(err, "Opening device `%s'", devicePath)
+	}
+
+	defer device.Close()
+
+	return deviceSizeInBytes(device)
+}
+
+func (p nativePartitioner) GetPartitions(devicePath string) ([]Partition, bool, error) {
+	device, err := p.fs.OpenFile(devicePath, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, false, bosherr.WrapErrorf(err, "Opening device `%s'", devicePath)
+	}
+
+	defer device.Close()
+
+	table, err := readDevicePartitionTable(device)
+	if err != nil {
+		return nil, false, bosherr.WrapErrorf(err, "Getting existing partitions of `%s'", devicePath)
+	}
+
+	partitions := []Partition{}
+
+	for _, entry := range table.Entries {
+		partitions = append(partitions, Partition{
+			SizeInBytes: entry.SizeInBytes(table.SectorSize),
+			Type:        entry.Type,
+			Name:        entry.Name(),
+		})
+	}
+
+	return partitions, table.Kind == partitionTableKindGPT, nil
+}
+
+func (p nativePartitioner) partitionsMatch(table partitionTable, partitions []Partition) bool {
+	if len(table.Entries) < len(partitions) {
+		return false
+	}
+
+	remainingDiskSpace := table.SectorCount * table.SectorSize
+
+	for index, partition := range partitions {
+		if index == len(partitions)-1 {
+			partition.SizeInBytes = remainingDiskSpace
+		}
+
+		existingPartition := table.Entries[index].existingPartition(table.SectorSize)
+		switch {
+		case existingPartition.Type != partition.Type:
+			return false
+		case !withinDelta(existingPartition.SizeInBytes, partition.SizeInBytes, 20*1024*1024):
+			return false
+		case partition.Name != "" && (table.Kind != partitionTableKindGPT || table.Entries[index].Name() != partition.Name):
+			return false
+		}
+
+		remainingDiskSpace = remainingDiskSpace - partition.SizeInBytes
+	}
+
+	return true
+}
+
+func hasNames(partitions []Partition) bool {
+	for _, partition := range partitions {
+		if partition.Name != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// openPartitionTable opens device for writing and reads its current partition table
+func openPartitionTable(fs boshsys.FileSystem, devicePath string) (boshsys.File, partitionTable, error) {
+	device, err := fs.OpenFile(devicePath, os.O_RDWR, 0)
+	if err != nil {
+		return nil, partitionTable{}, bosherr.WrapErrorf(err, "Opening device `%s'", devicePath)
+	}
+
+	table, err := readDevicePartitionTable(device)
+	if err != nil {
+		device.Close()
+		return nil, partitionTable{}, bosherr.WrapErrorf(err, "Getting existing partitions of `%s'", devicePath)
+	}
+
+	return device, table, nil
+}
+
+func readDevicePartitionTable(device boshsys.File) (partitionTable, error) {
+	sizeInBytes, err := deviceSizeInBytes(device)
+	if err != nil {
+		return partitionTable{}, err
+	}
+
+	sectorSize, err := deviceSectorSize(device)
+	if err != nil {
+		return partitionTable{}, err
+	}
+
+	// Smallest GPT takes 34 sectors on each end of the device
+	if sizeInBytes/sectorSize < 2*34 {
+		return partitionTable{}, bosherr.Errorf("Device of %d bytes is too small to be partitioned", sizeInBytes)
+	}
+
+	return readPartitionTable(device, sectorSize, sizeInBytes/sectorSize)
+}
+
+func deviceSizeInBytes(device boshsys.File) (uint64, error) {
+	// Unlike Stat, seeking to the end reports size of block devices too
+	size, err := device.Seek(0, os.SEEK_END)
+	if err != nil {
+		return 0, bosherr.WrapErrorf(err, "Getting size of `%s'", device.Name())
+	}
+
+	return uint64(size), nil
+}
+
+// appendPartitions adds entries for partitions to the table starting at aligned
+// startLBA; partitions of 0 size or not fitting on the device take remaining space
+func appendPartitions(table *partitionTable, startLBA uint64, partitions []Partition, logger boshlog.Logger, logTag string) error {
+	alignment := uint64(nativeAlignmentInBytes) / table.SectorSize
+	lastUsableLBA := table.LastUsableLBA()
+
+	start := roundUpToMultiple(startLBA, alignment)
+
+	for index, partition := range partitions {
+		if start > lastUsableLBA {
+			return bosherr.Errorf("No space left on device for partition %d", index)
+		}
+
+		end := lastUsableLBA + 1
+
+		if partition.SizeInBytes > 0 {
+			sectors := partition.SizeInBytes / table.SectorSize
+			if start+sectors <= end {
+				end = start + sectors
+			} else {
+				logger.Info(logTag, "Partition %d would be larger than remaining space. Reducing size to %dB", index, (end-start)*table.SectorSize)
+			}
+		}
+
+		// Keep following partition aligned without leaving gaps
+		if alignedEnd := roundDownToMultiple(end, alignment); alignedEnd > start {
+			end = alignedEnd
+		}
+
+		table.Entries = append(table.Entries, partitionEntry{
+			Index:    table.NextIndex(),
+			FirstLBA: start,
+			LastLBA:  end - 1,
+			Type:     partition.Type,
+			name:     gptName(partition.Name),
+		})
+
+		start = roundUpToMultiple(end, alignment)
+	}
+
+	return nil
+}
+
+// commitPartitionTable writes table to device and lets kernel know about new partitions
+func commitPartitionTable(device boshsys.File, table partitionTable) error {
+	err := writePartitionTable(device, table)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing partition table")
+	}
+
+	if syncer, ok := device.(interface {
+		Sync() error
+	}); ok {
+		err = syncer.Sync()
+		if err != nil {
+			return bosherr.WrapError(err, "Syncing partition table")
+		}
+	}
+
+	return reloadPartitionTable(device, table)
+}
+
+func roundUpToMultiple(numToRound, multiple uint64) uint64 {
+	if multiple == 0 || numToRound%multiple == 0 {
+		return numToRound
+	}
+	return numToRound + multiple - numToRound%multiple
+}
+
+func roundDownToMultiple(numToRound, multiple uint64) uint64 {
+	if multiple == 0 {
+		return numToRound
+	}
+	return numToRound - numToRound%multiple
+}
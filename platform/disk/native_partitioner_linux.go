@@ -0,0 +1,108 @@
+// +build linux
+
+package disk
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// See linux/fs.h and linux/blkpg.h
+const (
+	ioctlBlkRRPart = 0x125F
+	ioctlBlkSSZGet = 0x1268
+	ioctlBlkPG     = 0x1269
+
+	blkPGAddPartition = 1
+)
+
+type blkPGPartition struct {
+	Start   int64
+	Length  int64
+	Pno     int32
+	Devname [64]byte
+	Volname [64]byte
+}
+
+type blkPGIoctlArg struct {
+	Op      int32
+	Flags   int32
+	Datalen int32
+	Data    unsafe.Pointer
+}
+
+// deviceSectorSize returns logical sector size of block device
+func deviceSectorSize(device boshsys.File) (uint64, error) {
+	fd, ok := blockDeviceFd(device)
+	if !ok {
+		return nativeDefaultSectorSize, nil
+	}
+
+	var sectorSize int32
+
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlBlkSSZGet, uintptr(unsafe.Pointer(&sectorSize)))
+	if errno != 0 {
+		return 0, bosherr.WrapErrorf(errno, "Getting sector size of `%s'", device.Name())
+	}
+
+	return uint64(sectorSize), nil
+}
+
+// reloadPartitionTable makes kernel re-read partition table of block device.
+// Devices with partitions in use, e.g. root device, cannot be re-read as a whole
+// hence partitions are added one by one; existing ones are left as they are.
+func reloadPartitionTable(device boshsys.File, table partitionTable) error {
+	fd, ok := blockDeviceFd(device)
+	if !ok {
+		return nil
+	}
+
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlBlkRRPart, 0)
+	if errno == 0 {
+		return nil
+	} else if errno != syscall.EBUSY {
+		return bosherr.WrapErrorf(errno, "Re-reading partition table of `%s'", device.Name())
+	}
+
+	for _, entry := range table.Entries {
+		partition := blkPGPartition{
+			Start:  int64(entry.FirstLBA * table.SectorSize),
+			Length: int64(entry.SizeInBytes(table.SectorSize)),
+			Pno:    int32(entry.Index),
+		}
+
+		arg := blkPGIoctlArg{
+			Op:      blkPGAddPartition,
+			Datalen: int32(unsafe.Sizeof(partition)),
+			Data:    unsafe.Pointer(&partition),
+		}
+
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlBlkPG, uintptr(unsafe.Pointer(&arg)))
+		if errno != 0 && errno != syscall.EBUSY {
+			return bosherr.WrapErrorf(errno, "Adding partition %d of `%s'", entry.Index, device.Name())
+		}
+	}
+
+	return nil
+}
+
+// blockDeviceFd returns descriptor of device unless it is a regular file, e.g. disk image
+func blockDeviceFd(device boshsys.File) (uintptr, bool) {
+	info, err := device.Stat()
+	if err != nil || info.Mode()&os.ModeDevice == 0 {
+		return 0, false
+	}
+
+	fdDevice, ok := device.(interface {
+		Fd() uintptr
+	})
+	if !ok {
+		return 0, false
+	}
+
+	return fdDevice.Fd(), true
+}
@@ -0,0 +1,381 @@
+package disk_test
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/disk"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const mib = 1024 * 1024
+
+type mbrEntry struct {
+	Type     byte
+	FirstLBA uint32
+	Sectors  uint32
+}
+
+type gptEntry struct {
+	TypeGUID []byte
+	FirstLBA uint64
+	LastLBA  uint64
+}
+
+// Mixed endian form of Linux filesystem and swap type GUIDs
+var (
+	gptLinuxTypeGUID = []byte{0xAF, 0x3D, 0xC6, 0x0F, 0x83, 0x84, 0x72, 0x47, 0x8E, 0x79, 0x3D, 0x69, 0xD8, 0x47, 0x7D, 0xE4}
+	gptSwapTypeGUID  = []byte{0x6D, 0xFD, 0x57, 0x06, 0xAB, 0xA4, 0xC4, 0x43, 0x84, 0xE5, 0x09, 0x33, 0xC8, 0x4B, 0x4F, 0x4F}
+)
+
+func createImage(sizeInBytes int64) string {
+	image, err := ioutil.TempFile("", "native-partitioner-image")
+	Expect(err).ToNot(HaveOccurred())
+
+	defer image.Close()
+
+	Expect(image.Truncate(sizeInBytes)).To(Succeed())
+
+	return image.Name()
+}
+
+func resizeImage(imagePath string, sizeInBytes int64) {
+	Expect(os.Truncate(imagePath, sizeInBytes)).To(Succeed())
+}
+
+func readImage(imagePath string, offset int64, length int) []byte {
+	image, err := os.Open(imagePath)
+	Expect(err).ToNot(HaveOccurred())
+
+	defer image.Close()
+
+	data := make([]byte, length)
+	_, err = image.ReadAt(data, offset)
+	Expect(err).ToNot(HaveOccurred())
+
+	return data
+}
+
+func writeImage(imagePath string, offset int64, data []byte) {
+	image, err := os.OpenFile(imagePath, os.O_RDWR, 0)
+	Expect(err).ToNot(HaveOccurred())
+
+	defer image.Close()
+
+	_, err = image.WriteAt(data, offset)
+	Expect(err).ToNot(HaveOccurred())
+}
+
+func writeMBREntries(imagePath string, entries ...mbrEntry) {
+	mbr := make([]byte, 512)
+	for i, entry := range entries {
+		raw := mbr[446+i*16:]
+		raw[4] = entry.Type
+		binary.LittleEndian.PutUint32(raw[8:], entry.FirstLBA)
+		binary.LittleEndian.PutUint32(raw[12:], entry.Sectors)
+	}
+	binary.LittleEndian.PutUint16(mbr[510:], 0xAA55)
+
+	writeImage(imagePath, 0, mbr)
+}
+
+func readMBREntries(imagePath string) []mbrEntry {
+	mbr := readImage(imagePath, 0, 512)
+	Expect(binary.LittleEndian.Uint16(mbr[510:])).To(Equal(uint16(0xAA55)))
+
+	entries := []mbrEntry{}
+	for i := 0; i < 4; i++ {
+		raw := mbr[446+i*16:]
+		if raw[4] == 0 {
+			continue
+		}
+
+		entries = append(entries, mbrEntry{
+			Type:     raw[4],
+			FirstLBA: binary.LittleEndian.Uint32(raw[8:]),
+			Sectors:  binary.LittleEndian.Uint32(raw[12:]),
+		})
+	}
+	return entries
+}
+
+// readGPTEntries verifies header at lba and returns entries it describes
+func readGPTEntries(imagePath string, lba int64) []gptEntry {
+	header := readImage(imagePath, lba*512, 92)
+	Expect(string(header[0:8])).To(Equal("EFI PART"))
+	Expect(binary.LittleEndian.Uint64(header[24:])).To(Equal(uint64(lba)))
+
+	checksum := binary.LittleEndian.Uint32(header[16:])
+	binary.LittleEndian.PutUint32(header[16:], 0)
+	Expect(crc32.ChecksumIEEE(header)).To(Equal(checksum))
+
+	entriesLBA := int64(binary.LittleEndian.Uint64(header[72:]))
+	rawEntries := readImage(imagePath, entriesLBA*512, 128*128)
+	Expect(crc32.ChecksumIEEE(rawEntries)).To(Equal(binary.LittleEndian.Uint32(header[88:])))
+
+	entries := []gptEntry{}
+	for i := 0; i < 128; i++ {
+		raw := rawEntries[i*128 : (i+1)*128]
+		if string(raw[0:16]) == string(make([]byte, 16)) {
+			continue
+		}
+
+		entries = append(entries, gptEntry{
+			TypeGUID: raw[0:16],
+			FirstLBA: binary.LittleEndian.Uint64(raw[32:]),
+			LastLBA:  binary.LittleEndian.Uint64(raw[40:]),
+		})
+	}
+	return entries
+}
+
+var _ = Describe("nativePartitioner", func() {
+	var (
+		imagePath   string
+		partitioner Partitioner
+	)
+
+	BeforeEach(func() {
+		imagePath = createImage(100 * mib)
+
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		partitioner = NewNativePartitioner(logger, boshsys.NewOsFileSystem(logger))
+	})
+
+	AfterEach(func() {
+		os.Remove(imagePath)
+	})
+
+	Describe("GetDeviceSizeInBytes", func() {
+		It("returns size of the device", func() {
+			size, err := partitioner.GetDeviceSizeInBytes(imagePath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(size).To(Equal(uint64(100 * mib)))
+		})
+
+		It("returns error when device cannot be opened", func() {
+			_, err := partitioner.GetDeviceSizeInBytes("/fake-missing-device")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Opening device `/fake-missing-device'"))
+		})
+	})
+
+	Describe("Partition", func() {
+		It("creates MBR partitions aligned to 1MiB with last one taking remaining space", func() {
+			err := partitioner.Partition(imagePath, []Partition{
+				{Type: PartitionTypeSwap, SizeInBytes: 20 * mib},
+				{Type: PartitionTypeLinux},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(readMBREntries(imagePath)).To(Equal([]mbrEntry{
+				{Type: 0x82, FirstLBA: 2048, Sectors: 40960},
+				{Type: 0x83, FirstLBA: 43008, Sectors: 161792},
+			}))
+		})
+
+		It("keeps boot code when rewriting MBR", func() {
+			writeImage(imagePath, 0, []byte("fake-boot-code"))
+
+			err := partitioner.Partition(imagePath, []Partition{{Type: PartitionTypeLinux}})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(string(readImage(imagePath, 0, 14))).To(Equal("fake-boot-code"))
+		})
+
+		Context("when device is already partitioned", func() {
+			BeforeEach(func() {
+				writeMBREntries(imagePath,
+					mbrEntry{Type: 0x82, FirstLBA: 2048, Sectors: 40960},
+					mbrEntry{Type: 0x83, FirstLBA: 43008, Sectors: 161792},
+				)
+			})
+
+			It("does not repartition when partitions match within delta", func() {
+				err := partitioner.Partition(imagePath, []Partition{
+					{Type: PartitionTypeSwap, SizeInBytes: 30 * mib},
+					{Type: PartitionTypeLinux},
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(readMBREntries(imagePath)[0].Sectors).To(Equal(uint32(40960)))
+			})
+
+			It("repartitions when partition sizes differ more than delta", func() {
+				err := partitioner.Partition(imagePath, []Partition{
+					{Type: PartitionTypeSwap, SizeInBytes: 50 * mib},
+					{Type: PartitionTypeLinux},
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(readMBREntries(imagePath)).To(Equal([]mbrEntry{
+					{Type: 0x82, FirstLBA: 2048, Sectors: 102400},
+					{Type: 0x83, FirstLBA: 104448, Sectors: 100352},
+				}))
+			})
+
+			It("repartitions when partition types differ", func() {
+				err := partitioner.Partition(imagePath, []Partition{
+					{Type: PartitionTypeLinux, SizeInBytes: 20 * mib},
+					{Type: PartitionTypeSwap},
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				entries := readMBREntries(imagePath)
+				Expect(entries[0].Type).To(Equal(byte(0x83)))
+				Expect(entries[1].Type).To(Equal(byte(0x82)))
+			})
+		})
+
+		Context("when partitions do not fit into MBR", func() {
+			partitions := []Partition{
+				{Type: PartitionTypeSwap, SizeInBytes: 10 * mib},
+				{Type: PartitionTypeLinux, SizeInBytes: 10 * mib},
+				{Type: PartitionTypeLinux, SizeInBytes: 10 * mib},
+				{Type: PartitionTypeLinux, SizeInBytes: 10 * mib},
+				{Type: PartitionTypeLinux},
+			}
+
+			BeforeEach(func() {
+				err := partitioner.Partition(imagePath, partitions)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("creates GPT with protective MBR and backup header at the end of device", func() {
+				Expect(readMBREntries(imagePath)).To(Equal([]mbrEntry{
+					{Type: 0xEE, FirstLBA: 1, Sectors: 204799},
+				}))
+
+				primaryEntries := readGPTEntries(imagePath, 1)
+				Expect(primaryEntries).To(HaveLen(5))
+				Expect(primaryEntries[0]).To(Equal(gptEntry{TypeGUID: gptSwapTypeGUID, FirstLBA: 2048, LastLBA: 22527}))
+				Expect(primaryEntries[1]).To(Equal(gptEntry{TypeGUID: gptLinuxTypeGUID, FirstLBA: 22528, LastLBA: 43007}))
+
+				// Last partition ends before backup GPT and is aligned down
+				Expect(primaryEntries[4]).To(Equal(gptEntry{TypeGUID: gptLinuxTypeGUID, FirstLBA: 83968, LastLBA: 202751}))
+
+				Expect(readGPTEntries(imagePath, 204799)).To(Equal(primaryEntries))
+			})
+
+			It("keeps GPT and disk GUID when repartitioning", func() {
+				diskGUID := readImage(imagePath, 512+56, 16)
+
+				err := partitioner.Partition(imagePath, []Partition{{Type: PartitionTypeLinux}})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(readGPTEntries(imagePath, 1)).To(Equal([]gptEntry{
+					{TypeGUID: gptLinuxTypeGUID, FirstLBA: 2048, LastLBA: 202751},
+				}))
+				Expect(readImage(imagePath, 512+56, 16)).To(Equal(diskGUID))
+			})
+
+			It("reads partitions from backup GPT when primary header is corrupted", func() {
+				writeImage(imagePath, 512, []byte("fake-corruption"))
+
+				err := partitioner.Partition(imagePath, partitions)
+				Expect(err).ToNot(HaveOccurred())
+
+				// Nothing is written since partitions match
+				Expect(string(readImage(imagePath, 512, 15))).To(Equal("fake-corruption"))
+			})
+		})
+
+		It("returns error for multipath devices", func() {
+			err := partitioner.Partition("/dev/mapper/fake-device", []Partition{{Type: PartitionTypeLinux}})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("not supported by native partitioner"))
+		})
+
+		It("returns error when device cannot be opened", func() {
+			err := partitioner.Partition("/fake-missing-device", []Partition{{Type: PartitionTypeLinux}})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Opening device `/fake-missing-device'"))
+		})
+
+		It("returns error when there is no space left for partition", func() {
+			err := partitioner.Partition(imagePath, []Partition{
+				{Type: PartitionTypeSwap, SizeInBytes: 100 * mib},
+				{Type: PartitionTypeLinux},
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("No space left on device for partition 1"))
+		})
+
+		Context("when partitions have names", func() {
+			It("creates GPT even when partitions fit into MBR", func() {
+				err := partitioner.Partition(imagePath, []Partition{{Type: PartitionTypeLinux, Name: "raw-ephemeral-0"}})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(readGPTEntries(imagePath, 1)).To(Equal([]gptEntry{
+					{TypeGUID: gptLinuxTypeGUID, FirstLBA: 2048, LastLBA: 202751},
+				}))
+
+				// UTF-16LE name follows type and unique GUIDs, LBAs and attributes
+				Expect(readImage(imagePath, 2*512+56, 6)).To(Equal([]byte{'r', 0, 'a', 0, 'w', 0}))
+			})
+
+			It("repartitions when names differ", func() {
+				err := partitioner.Partition(imagePath, []Partition{{Type: PartitionTypeLinux, Name: "raw-ephemeral-0"}})
+				Expect(err).ToNot(HaveOccurred())
+
+				err = partitioner.Partition(imagePath, []Partition{{Type: PartitionTypeLinux, Name: "raw-ephemeral-1"}})
+				Expect(err).ToNot(HaveOccurred())
+
+				partitions, _, err := partitioner.(PartitionTableReader).GetPartitions(imagePath)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(partitions[0].Name).To(Equal("raw-ephemeral-1"))
+			})
+		})
+	})
+
+	Describe("GetPartitions", func() {
+		var reader PartitionTableReader
+
+		BeforeEach(func() {
+			reader = partitioner.(PartitionTableReader)
+		})
+
+		It("returns no partitions when device is not partitioned", func() {
+			partitions, isGPT, err := reader.GetPartitions(imagePath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(partitions).To(BeEmpty())
+			Expect(isGPT).To(BeFalse())
+		})
+
+		It("returns MBR partitions", func() {
+			writeMBREntries(imagePath, mbrEntry{Type: 0x82, FirstLBA: 2048, Sectors: 20480}, mbrEntry{Type: 0x83, FirstLBA: 22528, Sectors: 182272})
+
+			partitions, isGPT, err := reader.GetPartitions(imagePath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(isGPT).To(BeFalse())
+			Expect(partitions).To(Equal([]Partition{
+				{Type: PartitionTypeSwap, SizeInBytes: 10 * mib},
+				{Type: PartitionTypeLinux, SizeInBytes: 89 * mib},
+			}))
+		})
+
+		It("returns GPT partitions with their names", func() {
+			err := partitioner.Partition(imagePath, []Partition{{Type: PartitionTypeLinux, Name: "raw-ephemeral-0"}})
+			Expect(err).ToNot(HaveOccurred())
+
+			partitions, isGPT, err := reader.GetPartitions(imagePath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(isGPT).To(BeTrue())
+			Expect(partitions).To(Equal([]Partition{
+				{Type: PartitionTypeLinux, SizeInBytes: 200704 * 512, Name: "raw-ephemeral-0"},
+			}))
+		})
+
+		It("returns error when device cannot be opened", func() {
+			_, _, err := reader.GetPartitions("/fake-missing-device")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Opening device `/fake-missing-device'"))
+		})
+	})
+})
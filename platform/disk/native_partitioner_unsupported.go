@@ -0,0 +1,15 @@
+// +build !linux
+
+package disk
+
+import (
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+func deviceSectorSize(device boshsys.File) (uint64, error) {
+	return nativeDefaultSectorSize, nil
+}
+
+func reloadPartitionTable(device boshsys.File, table partitionTable) error {
+	return nil
+}
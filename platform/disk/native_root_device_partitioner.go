@@ -0,0 +1,116 @@
+package disk
+
+import (
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// nativeRootDevicePartitioner adds partitions after the first partition
+// of root device keeping existing partition table and its kind
+type nativeRootDevicePartitioner struct {
+	fs           boshsys.FileSystem
+	logger       boshlog.Logger
+	deltaInBytes uint64
+	logTag       string
+}
+
+func NewNativeRootDevicePartitioner(logger boshlog.Logger, fs boshsys.FileSystem, deltaInBytes uint64) Partitioner {
+	return nativeRootDevicePartitioner{
+		fs:           fs,
+		logger:       logger,
+		deltaInBytes: deltaInBytes,
+		logTag:       "NativeRootDevicePartitioner",
+	}
+}
+
+func (p nativeRootDevicePartitioner) Partition(devicePath string, partitions []Partition) error {
+	device, table, err := openPartitionTable(p.fs, devicePath)
+	if err != nil {
+		return err
+	}
+
+	defer device.Close()
+
+	existingEntries := rootDeviceEntries(table)
+	p.logger.Debug(p.logTag, "Current partitions: %#v", existingEntries)
+
+	if len(existingEntries) == 0 {
+		return bosherr.Errorf("Missing first partition on `%s'", devicePath)
+	}
+
+	if p.partitionsMatch(table, existingEntries[1:], partitions) {
+		p.logger.Info(p.logTag, "Partitions already match, skipping partitioning")
+		return nil
+	}
+
+	if len(existingEntries) > 1 {
+		p.logger.Error(p.logTag,
+			"Failed to create ephemeral partitions on root device `%s'. Expected 1 partition, found %d: %#v",
+			devicePath,
+			len(existingEntries),
+			existingEntries,
+		)
+		return bosherr.Errorf("Found %d unexpected partitions on `%s'", len(existingEntries)-1, devicePath)
+	}
+
+	err = appendPartitions(&table, existingEntries[0].LastLBA+1, partitions, p.logger, p.logTag)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Partitioning disk `%s'", devicePath)
+	}
+
+	// Backup GPT is moved to the end of root device grown by IaaS
+	err = commitPartitionTable(device, table)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Partitioning disk `%s'", devicePath)
+	}
+
+	return nil
+}
+
+func (p nativeRootDevicePartitioner) GetDeviceSizeInBytes(devicePath string) (uint64, error) {
+	p.logger.Debug(p.logTag, "Getting size of disk remaining after first partition")
+
+	device, table, err := openPartitionTable(p.fs, devicePath)
+	if err != nil {
+		return 0, bosherr.WrapErrorf(err, "Getting remaining size of `%s'", devicePath)
+	}
+
+	defer device.Close()
+
+	existingEntries := rootDeviceEntries(table)
+	if len(existingEntries) == 0 {
+		return 0, bosherr.Errorf("Getting remaining size of `%s'", devicePath)
+	}
+
+	firstPartition := existingEntries[0].existingPartition(table.SectorSize)
+
+	return table.SectorCount*table.SectorSize - firstPartition.EndInBytes - 1, nil
+}
+
+func (p nativeRootDevicePartitioner) partitionsMatch(table partitionTable, existingEntries []partitionEntry, partitions []Partition) bool {
+	if len(existingEntries) != len(partitions) {
+		return false
+	}
+
+	for index, partition := range partitions {
+		existingPartition := existingEntries[index].existingPartition(table.SectorSize)
+
+		if !withinDelta(partition.SizeInBytes, existingPartition.SizeInBytes, p.deltaInBytes) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// rootDeviceEntries ignores PReP partition on ppc64le
+func rootDeviceEntries(table partitionTable) []partitionEntry {
+	entries := []partitionEntry{}
+	for _, entry := range table.Entries {
+		if !entry.PReP {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
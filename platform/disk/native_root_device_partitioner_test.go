@@ -0,0 +1,110 @@
+package disk_test
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/disk"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+var _ = Describe("nativeRootDevicePartitioner", func() {
+	var (
+		imagePath   string
+		logger      boshlog.Logger
+		fs          boshsys.FileSystem
+		partitioner Partitioner
+	)
+
+	BeforeEach(func() {
+		// 100MiB device, 30MiB partition 0 starting at 1MiB, 69MiB remaining
+		imagePath = createImage(100 * mib)
+		writeMBREntries(imagePath, mbrEntry{Type: 0x83, FirstLBA: 2048, Sectors: 61440})
+
+		logger = boshlog.NewLogger(boshlog.LevelNone)
+		fs = boshsys.NewOsFileSystem(logger)
+		partitioner = NewNativeRootDevicePartitioner(logger, fs, 1)
+	})
+
+	AfterEach(func() {
+		os.Remove(imagePath)
+	})
+
+	Describe("GetDeviceSizeInBytes", func() {
+		It("returns size of device remaining after first partition", func() {
+			size, err := partitioner.GetDeviceSizeInBytes(imagePath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(size).To(Equal(uint64(69 * mib)))
+		})
+	})
+
+	Describe("Partition", func() {
+		partitions := []Partition{
+			{Type: PartitionTypeSwap, SizeInBytes: 20 * mib},
+			{Type: PartitionTypeLinux, SizeInBytes: 49 * mib},
+		}
+
+		It("adds partitions after first partition keeping it as is", func() {
+			err := partitioner.Partition(imagePath, partitions)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(readMBREntries(imagePath)).To(Equal([]mbrEntry{
+				{Type: 0x83, FirstLBA: 2048, Sectors: 61440},
+				{Type: 0x82, FirstLBA: 63488, Sectors: 40960},
+				{Type: 0x83, FirstLBA: 104448, Sectors: 100352},
+			}))
+		})
+
+		It("does nothing when partitions already exist", func() {
+			Expect(partitioner.Partition(imagePath, partitions)).To(Succeed())
+			Expect(partitioner.Partition(imagePath, partitions)).To(Succeed())
+
+			Expect(readMBREntries(imagePath)).To(HaveLen(3))
+		})
+
+		It("returns error when other partitions exist", func() {
+			Expect(partitioner.Partition(imagePath, partitions)).To(Succeed())
+
+			err := partitioner.Partition(imagePath, []Partition{{Type: PartitionTypeSwap, SizeInBytes: 10 * mib}})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Found 2 unexpected partitions on `" + imagePath + "'"))
+		})
+
+		It("returns error when first partition is missing", func() {
+			writeMBREntries(imagePath)
+
+			err := partitioner.Partition(imagePath, partitions)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Missing first partition on `" + imagePath + "'"))
+		})
+
+		It("moves backup GPT to the end of root device which was grown", func() {
+			resizeImage(imagePath, 30*mib)
+			writeImage(imagePath, 0, make([]byte, 512))
+
+			// Five partitions make native partitioner create GPT
+			Expect(NewNativePartitioner(logger, fs).Partition(imagePath, []Partition{
+				{SizeInBytes: mib}, {SizeInBytes: mib}, {SizeInBytes: mib}, {SizeInBytes: mib}, {},
+			})).To(Succeed())
+			Expect(NewNativePartitioner(logger, fs).Partition(imagePath, []Partition{
+				{Type: PartitionTypeLinux},
+			})).To(Succeed())
+
+			resizeImage(imagePath, 100*mib)
+
+			err := partitioner.Partition(imagePath, partitions)
+			Expect(err).ToNot(HaveOccurred())
+
+			entries := readGPTEntries(imagePath, 204799)
+			Expect(entries).To(Equal([]gptEntry{
+				{TypeGUID: gptLinuxTypeGUID, FirstLBA: 2048, LastLBA: 59391},
+				{TypeGUID: gptSwapTypeGUID, FirstLBA: 59392, LastLBA: 100351},
+				{TypeGUID: gptLinuxTypeGUID, FirstLBA: 100352, LastLBA: 200703},
+			}))
+			Expect(readGPTEntries(imagePath, 1)).To(Equal(entries))
+		})
+	})
+})
@@ -0,0 +1,525 @@
+package disk
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"hash/crc32"
+	"io"
+	"strings"
+	"unicode/utf16"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+type partitionTableKind string
+
+const (
+	partitionTableKindMBR partitionTableKind = "mbr"
+	partitionTableKindGPT partitionTableKind = "gpt"
+)
+
+const (
+	mbrSignature        = 0xAA55
+	mbrSignatureOffset  = 510
+	mbrEntriesOffset    = 446
+	mbrEntrySize        = 16
+	mbrEntryCount       = 4
+	mbrMaxSectorCount   = 0xFFFFFFFF
+	mbrTypeEmpty        = 0x00
+	mbrTypePReP         = 0x41
+	mbrTypeSwap         = 0x82
+	mbrTypeLinux        = 0x83
+	mbrTypeGPTProtected = 0xEE
+
+	gptSignature     = "EFI PART"
+	gptRevision      = 0x00010000
+	gptHeaderSize    = 92
+	gptEntrySize     = 128
+	gptEntryCount    = 128
+	gptEntriesSize   = gptEntrySize * gptEntryCount
+	gptNameSizeBytes = 72
+)
+
+var (
+	// GUIDs are stored in their on-disk mixed endian form
+	gptTypeLinux = mustParseGUID("0FC63DAF-8483-4772-8E79-3D69D8477DE4")
+	gptTypeSwap  = mustParseGUID("0657FD6D-A4AB-43C4-84E5-0933C84B4F4F")
+	gptTypePReP  = mustParseGUID("9E1A2D38-C612-4316-AA26-8B49521E5A8B")
+)
+
+type readerWriterAt interface {
+	io.ReaderAt
+	io.WriterAt
+}
+
+// partitionTable is an in memory representation of MBR or GPT
+// read from or written to a device in units of sectors
+type partitionTable struct {
+	Kind        partitionTableKind
+	SectorSize  uint64
+	SectorCount uint64
+	Entries     []partitionEntry
+
+	diskGUID [16]byte
+}
+
+type partitionEntry struct {
+	// 1-based slot in the table, i.e. number of partition device
+	Index    int
+	FirstLBA uint64
+	// Inclusive
+	LastLBA uint64
+	Type    PartitionType
+	PReP    bool
+
+	mbrType    byte
+	mbrStatus  byte
+	typeGUID   [16]byte
+	uniqueGUID [16]byte
+	attributes uint64
+	name       [gptNameSizeBytes]byte
+}
+
+func (e partitionEntry) SizeInBytes(sectorSize uint64) uint64 {
+	return (e.LastLBA - e.FirstLBA + 1) * sectorSize
+}
+
+func (e partitionEntry) existingPartition(sectorSize uint64) existingPartition {
+	return existingPartition{
+		Index:        e.Index,
+		SizeInBytes:  e.SizeInBytes(sectorSize),
+		StartInBytes: e.FirstLBA * sectorSize,
+		EndInBytes:   (e.LastLBA+1)*sectorSize - 1,
+		Type:         e.Type,
+	}
+}
+
+// Name decodes UTF-16LE GPT partition name
+func (e partitionEntry) Name() string {
+	units := make([]uint16, 0, gptNameSizeBytes/2)
+	for i := 0; i < gptNameSizeBytes; i += 2 {
+		unit := binary.LittleEndian.Uint16(e.name[i:])
+		if unit == 0 {
+			break
+		}
+		units = append(units, unit)
+	}
+	return string(utf16.Decode(units))
+}
+
+// FirstUsableLBA returns first sector partitions may start at
+func (t partitionTable) FirstUsableLBA() uint64 {
+	if t.Kind == partitionTableKindGPT {
+		return 2 + gptEntriesSize/t.SectorSize
+	}
+	return 1
+}
+
+// LastUsableLBA returns last sector partitions may end at
+func (t partitionTable) LastUsableLBA() uint64 {
+	if t.Kind == partitionTableKindGPT {
+		return t.SectorCount - 2 - gptEntriesSize/t.SectorSize
+	}
+	if t.SectorCount > mbrMaxSectorCount {
+		return mbrMaxSectorCount - 1
+	}
+	return t.SectorCount - 1
+}
+
+// NextIndex returns first slot not taken by any entry
+func (t partitionTable) NextIndex() int {
+	next := 1
+	for _, entry := range t.Entries {
+		if entry.Index >= next {
+			next = entry.Index + 1
+		}
+	}
+	return next
+}
+
+// readPartitionTable returns table without entries when device has
+// neither valid MBR nor valid GPT
+func readPartitionTable(device io.ReaderAt, sectorSize, sectorCount uint64) (partitionTable, error) {
+	table := partitionTable{
+		Kind:        partitionTableKindMBR,
+		SectorSize:  sectorSize,
+		SectorCount: sectorCount,
+		Entries:     []partitionEntry{},
+	}
+
+	mbr := make([]byte, sectorSize)
+	_, err := device.ReadAt(mbr, 0)
+	if err != nil {
+		return table, bosherr.WrapError(err, "Reading MBR")
+	}
+
+	if binary.LittleEndian.Uint16(mbr[mbrSignatureOffset:]) != mbrSignature {
+		return table, nil
+	}
+
+	for i := 0; i < mbrEntryCount; i++ {
+		if mbr[mbrEntriesOffset+i*mbrEntrySize+4] == mbrTypeGPTProtected {
+			return readGPT(device, table)
+		}
+	}
+
+	for i := 0; i < mbrEntryCount; i++ {
+		raw := mbr[mbrEntriesOffset+i*mbrEntrySize : mbrEntriesOffset+(i+1)*mbrEntrySize]
+
+		mbrType := raw[4]
+		firstLBA := uint64(binary.LittleEndian.Uint32(raw[8:]))
+		sectors := uint64(binary.LittleEndian.Uint32(raw[12:]))
+
+		if mbrType == mbrTypeEmpty || sectors == 0 {
+			continue
+		}
+
+		table.Entries = append(table.Entries, partitionEntry{
+			Index:     i + 1,
+			FirstLBA:  firstLBA,
+			LastLBA:   firstLBA + sectors - 1,
+			Type:      mbrPartitionType(mbrType),
+			PReP:      mbrType == mbrTypePReP,
+			mbrType:   mbrType,
+			mbrStatus: raw[0],
+		})
+	}
+
+	return table, nil
+}
+
+func readGPT(device io.ReaderAt, table partitionTable) (partitionTable, error) {
+	table.Kind = partitionTableKindGPT
+
+	// Backup header is used when primary one got corrupted
+	header, err := readGPTHeader(device, 1, table.SectorSize)
+	if err != nil {
+		header, err = readGPTHeader(device, table.SectorCount-1, table.SectorSize)
+		if err != nil {
+			return table, bosherr.WrapError(err, "Reading GPT header")
+		}
+	}
+
+	copy(table.diskGUID[:], header[56:72])
+
+	entriesLBA := binary.LittleEndian.Uint64(header[72:])
+	entryCount := binary.LittleEndian.Uint32(header[80:])
+	entrySize := binary.LittleEndian.Uint32(header[84:])
+
+	if entrySize < gptEntrySize || uint64(entryCount)*uint64(entrySize) > 1024*1024 {
+		return table, bosherr.Errorf("Unsupported GPT entries layout: %d entries of %d bytes", entryCount, entrySize)
+	}
+
+	entries := make([]byte, uint64(entryCount)*uint64(entrySize))
+	_, err = device.ReadAt(entries, int64(entriesLBA*table.SectorSize))
+	if err != nil {
+		return table, bosherr.WrapError(err, "Reading GPT entries")
+	}
+
+	if crc32.ChecksumIEEE(entries) != binary.LittleEndian.Uint32(header[88:]) {
+		return table, bosherr.Error("GPT entries checksum mismatch")
+	}
+
+	for i := 0; i < int(entryCount); i++ {
+		raw := entries[i*int(entrySize) : (i+1)*int(entrySize)]
+
+		var entry partitionEntry
+		copy(entry.typeGUID[:], raw[0:16])
+
+		if entry.typeGUID == [16]byte{} {
+			continue
+		}
+
+		copy(entry.uniqueGUID[:], raw[16:32])
+		copy(entry.name[:], raw[56:56+gptNameSizeBytes])
+
+		entry.Index = i + 1
+		entry.FirstLBA = binary.LittleEndian.Uint64(raw[32:])
+		entry.LastLBA = binary.LittleEndian.Uint64(raw[40:])
+		entry.attributes = binary.LittleEndian.Uint64(raw[48:])
+		entry.Type = gptPartitionType(entry.typeGUID)
+		entry.PReP = entry.typeGUID == gptTypePReP
+
+		table.Entries = append(table.Entries, entry)
+	}
+
+	return table, nil
+}
+
+func readGPTHeader(device io.ReaderAt, lba, sectorSize uint64) ([]byte, error) {
+	header := make([]byte, sectorSize)
+
+	_, err := device.ReadAt(header, int64(lba*sectorSize))
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Reading GPT header at LBA %d", lba)
+	}
+
+	if string(header[0:8]) != gptSignature {
+		return nil, bosherr.Errorf("Missing GPT signature at LBA %d", lba)
+	}
+
+	headerSize := binary.LittleEndian.Uint32(header[12:])
+	if headerSize < gptHeaderSize || uint64(headerSize) > sectorSize {
+		return nil, bosherr.Errorf("Unsupported GPT header size %d at LBA %d", headerSize, lba)
+	}
+
+	checksum := binary.LittleEndian.Uint32(header[16:])
+
+	checked := make([]byte, headerSize)
+	copy(checked, header[:headerSize])
+	binary.LittleEndian.PutUint32(checked[16:], 0)
+
+	if crc32.ChecksumIEEE(checked) != checksum {
+		return nil, bosherr.Errorf("GPT header checksum mismatch at LBA %d", lba)
+	}
+
+	return header, nil
+}
+
+func writePartitionTable(device readerWriterAt, table partitionTable) error {
+	if table.Kind == partitionTableKindGPT {
+		return writeGPT(device, table)
+	}
+
+	return writeMBR(device, table)
+}
+
+func writeMBR(device readerWriterAt, table partitionTable) error {
+	// Boot code and disk signature are kept as is
+	mbr := make([]byte, table.SectorSize)
+	_, err := device.ReadAt(mbr, 0)
+	if err != nil {
+		return bosherr.WrapError(err, "Reading MBR")
+	}
+
+	for i := mbrEntriesOffset; i < mbrSignatureOffset; i++ {
+		mbr[i] = 0
+	}
+
+	for _, entry := range table.Entries {
+		if entry.Index < 1 || entry.Index > mbrEntryCount {
+			return bosherr.Errorf("MBR cannot hold partition %d", entry.Index)
+		}
+
+		mbrType := entry.mbrType
+		if mbrType == mbrTypeEmpty {
+			mbrType = mbrTypeFor(entry.Type)
+		}
+
+		raw := mbr[mbrEntriesOffset+(entry.Index-1)*mbrEntrySize:]
+		raw[0] = entry.mbrStatus
+		copy(raw[1:4], chsAddress(entry.FirstLBA))
+		raw[4] = mbrType
+		copy(raw[5:8], chsAddress(entry.LastLBA))
+		binary.LittleEndian.PutUint32(raw[8:], uint32(entry.FirstLBA))
+		binary.LittleEndian.PutUint32(raw[12:], uint32(entry.LastLBA-entry.FirstLBA+1))
+	}
+
+	binary.LittleEndian.PutUint16(mbr[mbrSignatureOffset:], mbrSignature)
+
+	_, err = device.WriteAt(mbr, 0)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing MBR")
+	}
+
+	return nil
+}
+
+func writeGPT(device io.WriterAt, table partitionTable) error {
+	if table.diskGUID == [16]byte{} {
+		diskGUID, err := randomGUID()
+		if err != nil {
+			return err
+		}
+		table.diskGUID = diskGUID
+	}
+
+	entries := make([]byte, gptEntriesSize)
+
+	for _, entry := range table.Entries {
+		if entry.Index < 1 || entry.Index > gptEntryCount {
+			return bosherr.Errorf("GPT cannot hold partition %d", entry.Index)
+		}
+
+		if entry.typeGUID == [16]byte{} {
+			entry.typeGUID = gptTypeFor(entry.Type)
+		}
+
+		if entry.uniqueGUID == [16]byte{} {
+			uniqueGUID, err := randomGUID()
+			if err != nil {
+				return err
+			}
+			entry.uniqueGUID = uniqueGUID
+		}
+
+		raw := entries[(entry.Index-1)*gptEntrySize:]
+		copy(raw[0:16], entry.typeGUID[:])
+		copy(raw[16:32], entry.uniqueGUID[:])
+		binary.LittleEndian.PutUint64(raw[32:], entry.FirstLBA)
+		binary.LittleEndian.PutUint64(raw[40:], entry.LastLBA)
+		binary.LittleEndian.PutUint64(raw[48:], entry.attributes)
+		copy(raw[56:56+gptNameSizeBytes], entry.name[:])
+	}
+
+	lastLBA := table.SectorCount - 1
+	entriesSectors := uint64(gptEntriesSize) / table.SectorSize
+
+	writes := []struct {
+		offset uint64
+		data   []byte
+	}{
+		{0, protectiveMBR(table)},
+		{2 * table.SectorSize, entries},
+		{(lastLBA - entriesSectors) * table.SectorSize, entries},
+		// Headers are written after entries they describe
+		{table.SectorSize, gptHeader(table, 1, lastLBA, 2, entries)},
+		{lastLBA * table.SectorSize, gptHeader(table, lastLBA, 1, lastLBA-entriesSectors, entries)},
+	}
+
+	for _, w := range writes {
+		_, err := device.WriteAt(w.data, int64(w.offset))
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Writing GPT at offset %d", w.offset)
+		}
+	}
+
+	return nil
+}
+
+func protectiveMBR(table partitionTable) []byte {
+	mbr := make([]byte, table.SectorSize)
+
+	sectors := table.SectorCount - 1
+	if sectors > mbrMaxSectorCount {
+		sectors = mbrMaxSectorCount
+	}
+
+	raw := mbr[mbrEntriesOffset:]
+	copy(raw[1:4], []byte{0x00, 0x02, 0x00})
+	raw[4] = mbrTypeGPTProtected
+	copy(raw[5:8], []byte{0xFF, 0xFF, 0xFF})
+	binary.LittleEndian.PutUint32(raw[8:], 1)
+	binary.LittleEndian.PutUint32(raw[12:], uint32(sectors))
+
+	binary.LittleEndian.PutUint16(mbr[mbrSignatureOffset:], mbrSignature)
+
+	return mbr
+}
+
+func gptHeader(table partitionTable, currentLBA, backupLBA, entriesLBA uint64, entries []byte) []byte {
+	header := make([]byte, table.SectorSize)
+
+	copy(header[0:8], gptSignature)
+	binary.LittleEndian.PutUint32(header[8:], gptRevision)
+	binary.LittleEndian.PutUint32(header[12:], gptHeaderSize)
+	binary.LittleEndian.PutUint64(header[24:], currentLBA)
+	binary.LittleEndian.PutUint64(header[32:], backupLBA)
+	binary.LittleEndian.PutUint64(header[40:], table.FirstUsableLBA())
+	binary.LittleEndian.PutUint64(header[48:], table.LastUsableLBA())
+	copy(header[56:72], table.diskGUID[:])
+	binary.LittleEndian.PutUint64(header[72:], entriesLBA)
+	binary.LittleEndian.PutUint32(header[80:], gptEntryCount)
+	binary.LittleEndian.PutUint32(header[84:], gptEntrySize)
+	binary.LittleEndian.PutUint32(header[88:], crc32.ChecksumIEEE(entries))
+
+	binary.LittleEndian.PutUint32(header[16:], crc32.ChecksumIEEE(header[:gptHeaderSize]))
+
+	return header
+}
+
+// chsAddress returns CHS address of LBA assuming 255 heads and 63 sectors per track;
+// addresses which do not fit are capped as done by other partitioning tools
+func chsAddress(lba uint64) []byte {
+	const heads, sectorsPerTrack = 255, 63
+
+	cylinder := lba / (heads * sectorsPerTrack)
+	if cylinder > 1023 {
+		return []byte{0xFE, 0xFF, 0xFF}
+	}
+
+	head := (lba / sectorsPerTrack) % heads
+	sector := lba%sectorsPerTrack + 1
+
+	return []byte{byte(head), byte(sector) | byte((cylinder>>2)&0xC0), byte(cylinder)}
+}
+
+func mbrPartitionType(mbrType byte) PartitionType {
+	switch mbrType {
+	case mbrTypeLinux:
+		return PartitionTypeLinux
+	case mbrTypeSwap:
+		return PartitionTypeSwap
+	}
+	return PartitionTypeUnknown
+}
+
+func mbrTypeFor(partitionType PartitionType) byte {
+	if partitionType == PartitionTypeSwap {
+		return mbrTypeSwap
+	}
+	return mbrTypeLinux
+}
+
+func gptPartitionType(typeGUID [16]byte) PartitionType {
+	switch typeGUID {
+	case gptTypeLinux:
+		return PartitionTypeLinux
+	case gptTypeSwap:
+		return PartitionTypeSwap
+	}
+	return PartitionTypeUnknown
+}
+
+func gptTypeFor(partitionType PartitionType) [16]byte {
+	if partitionType == PartitionTypeSwap {
+		return gptTypeSwap
+	}
+	return gptTypeLinux
+}
+
+// gptName encodes name as UTF-16LE truncating it to fit into GPT entry
+func gptName(name string) [gptNameSizeBytes]byte {
+	var raw [gptNameSizeBytes]byte
+
+	units := utf16.Encode([]rune(name))
+	for i := 0; i < len(units) && i < gptNameSizeBytes/2; i++ {
+		binary.LittleEndian.PutUint16(raw[i*2:], units[i])
+	}
+
+	return raw
+}
+
+func randomGUID() ([16]byte, error) {
+	var guid [16]byte
+
+	_, err := rand.Read(guid[:])
+	if err != nil {
+		return guid, bosherr.WrapError(err, "Generating GUID")
+	}
+
+	// Version 4, variant 1; version is in the little endian third field
+	guid[7] = (guid[7] & 0x0F) | 0x40
+	guid[8] = (guid[8] & 0x3F) | 0x80
+
+	return guid, nil
+}
+
+// mustParseGUID converts textual GUID into mixed endian form used by GPT
+func mustParseGUID(text string) [16]byte {
+	var guid [16]byte
+
+	raw, err := hex.DecodeString(strings.Replace(text, "-", "", -1))
+	if err != nil || len(raw) != 16 {
+		panic("Invalid GUID " + text)
+	}
+
+	// First three groups are little endian
+	guid[0], guid[1], guid[2], guid[3] = raw[3], raw[2], raw[1], raw[0]
+	guid[4], guid[5] = raw[5], raw[4]
+	guid[6], guid[7] = raw[7], raw[6]
+	copy(guid[8:], raw[8:])
+
+	return guid
+}
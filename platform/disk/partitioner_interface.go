@@ -14,6 +14,9 @@ const (
 type Partition struct {
 	SizeInBytes uint64
 	Type        PartitionType
+	// GPT partition name; only native partitioner writes names
+	// and it always uses GPT for partitions with names
+	Name string
 }
 
 type Partitioner interface {
@@ -21,6 +24,13 @@ type Partitioner interface {
 	GetDeviceSizeInBytes(devicePath string) (size uint64, err error)
 }
 
+// PartitionTableReader is implemented by partitioners which read partition
+// tables themselves instead of shelling out to sfdisk or parted
+type PartitionTableReader interface {
+	// GetPartitions returns no partitions when device is not partitioned
+	GetPartitions(devicePath string) (partitions []Partition, isGPT bool, err error)
+}
+
 func (p Partition) String() string {
 	return fmt.Sprintf("[Type: %s, SizeInBytes: %d]", p.Type, p.SizeInBytes)
 }
@@ -0,0 +1,247 @@
+package platform
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const diskMigrationProgressLogInterval = 10
+
+var (
+	rsyncProgressRegexp    = regexp.MustCompile(`^\s*([\d,]+)\s+(\d+)%.*xfr#(\d+)`)
+	rsyncFilesStatRegexp   = regexp.MustCompile(`^Number of regular files transferred: ([\d,]+)`)
+	rsyncBytesStatRegexp   = regexp.MustCompile(`^Total transferred file size: ([\d,]+) bytes`)
+	rsyncProgressSeparator = regexp.MustCompile(`[\r\n]`)
+)
+
+// diskMigrationProgress consumes rsync --info=progress2 --stats output,
+// logging copy progress and collecting transfer totals
+type diskMigrationProgress struct {
+	logger boshlog.Logger
+
+	lock           sync.Mutex
+	buf            bytes.Buffer
+	lastLoggedPct  int
+	filesCopied    uint64
+	bytesCopied    uint64
+	statsCollected bool
+}
+
+func newDiskMigrationProgress(logger boshlog.Logger) *diskMigrationProgress {
+	return &diskMigrationProgress{logger: logger, lastLoggedPct: -diskMigrationProgressLogInterval}
+}
+
+func (p *diskMigrationProgress) Write(data []byte) (int, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.buf.Write(data)
+
+	for {
+		loc := rsyncProgressSeparator.FindIndex(p.buf.Bytes())
+		if loc == nil {
+			break
+		}
+
+		line := string(p.buf.Next(loc[1])[:loc[0]])
+		p.processLine(line)
+	}
+
+	return len(data), nil
+}
+
+func (p *diskMigrationProgress) Totals() (filesCopied, bytesCopied uint64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.buf.Len() > 0 {
+		p.processLine(p.buf.String())
+		p.buf.Reset()
+	}
+
+	return p.filesCopied, p.bytesCopied
+}
+
+func (p *diskMigrationProgress) processLine(line string) {
+	if matches := rsyncProgressRegexp.FindStringSubmatch(line); matches != nil {
+		pct, _ := strconv.Atoi(matches[2])
+		if !p.statsCollected {
+			p.bytesCopied = parseRsyncNumber(matches[1])
+			p.filesCopied = parseRsyncNumber(matches[3])
+		}
+
+		if pct >= p.lastLoggedPct+diskMigrationProgressLogInterval || pct == 100 && p.lastLoggedPct != 100 {
+			p.lastLoggedPct = pct
+			p.logger.Info(logTag, "Migrating persistent disk: %d%% (%d files, %d bytes copied)", pct, p.filesCopied, p.bytesCopied)
+		}
+		return
+	}
+
+	if matches := rsyncFilesStatRegexp.FindStringSubmatch(line); matches != nil {
+		p.filesCopied = parseRsyncNumber(matches[1])
+		p.statsCollected = true
+		return
+	}
+
+	if matches := rsyncBytesStatRegexp.FindStringSubmatch(line); matches != nil {
+		p.bytesCopied = parseRsyncNumber(matches[1])
+		p.statsCollected = true
+	}
+}
+
+func parseRsyncNumber(number string) uint64 {
+	parsed, _ := strconv.ParseUint(strings.Replace(number, ",", "", -1), 10, 64)
+	return parsed
+}
+
+type diskTreeEntry struct {
+	mode os.FileMode
+	size int64
+}
+
+type diskTreeSummary struct {
+	entries map[string]diskTreeEntry
+	files   uint64
+	bytes   uint64
+}
+
+func summarizeDiskTree(fs boshsys.FileSystem, root string) (diskTreeSummary, error) {
+	summary := diskTreeSummary{entries: map[string]diskTreeEntry{}}
+
+	err := fs.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		if relPath == "." {
+			return nil
+		}
+
+		// Only regular files are counted; directories are told apart
+		// by IsDir since FileSystem implementations may only report
+		// permission bits in Mode
+		entry := diskTreeEntry{mode: info.Mode() & os.ModeType}
+		if info.IsDir() {
+			entry.mode = os.ModeDir
+		}
+
+		if entry.mode.IsRegular() {
+			entry.size = info.Size()
+			summary.files++
+			summary.bytes += uint64(info.Size())
+		}
+
+		summary.entries[relPath] = entry
+
+		return nil
+	})
+	if err != nil {
+		return summary, bosherr.WrapErrorf(err, "Walking '%s'", root)
+	}
+
+	return summary, nil
+}
+
+// verifyDiskMigration compares file types, counts and sizes of both trees;
+// optionally it also compares contents of every regular file.
+// It returns number of regular files and bytes found on new disk.
+func verifyDiskMigration(fs boshsys.FileSystem, fromMountPoint, toMountPoint string, verifyChecksums bool) (uint64, uint64, error) {
+	from, err := summarizeDiskTree(fs, fromMountPoint)
+	if err != nil {
+		return 0, 0, bosherr.WrapError(err, "Summarizing old persistent disk")
+	}
+
+	to, err := summarizeDiskTree(fs, toMountPoint)
+	if err != nil {
+		return 0, 0, bosherr.WrapError(err, "Summarizing new persistent disk")
+	}
+
+	if from.files != to.files || from.bytes != to.bytes {
+		return 0, 0, bosherr.Errorf(
+			"Expected %d files (%d bytes) on new persistent disk but found %d files (%d bytes)",
+			from.files, from.bytes, to.files, to.bytes,
+		)
+	}
+
+	relPaths := make([]string, 0, len(from.entries))
+	for relPath := range from.entries {
+		relPaths = append(relPaths, relPath)
+	}
+	sort.Strings(relPaths)
+
+	for _, relPath := range relPaths {
+		fromEntry := from.entries[relPath]
+
+		toEntry, found := to.entries[relPath]
+		if !found {
+			return 0, 0, bosherr.Errorf("Missing '%s' on new persistent disk", relPath)
+		}
+
+		if fromEntry != toEntry {
+			return 0, 0, bosherr.Errorf("Mismatched type or size of '%s' on new persistent disk", relPath)
+		}
+
+		if verifyChecksums && fromEntry.mode.IsRegular() {
+			err = compareFileChecksums(fs, filepath.Join(fromMountPoint, relPath), filepath.Join(toMountPoint, relPath))
+			if err != nil {
+				return 0, 0, bosherr.WrapErrorf(err, "Verifying '%s'", relPath)
+			}
+		}
+	}
+
+	return to.files, to.bytes, nil
+}
+
+func compareFileChecksums(fs boshsys.FileSystem, fromPath, toPath string) error {
+	fromChecksum, err := fileChecksum(fs, fromPath)
+	if err != nil {
+		return err
+	}
+
+	toChecksum, err := fileChecksum(fs, toPath)
+	if err != nil {
+		return err
+	}
+
+	if fromChecksum != toChecksum {
+		return bosherr.Errorf("Expected checksum '%s' but found '%s'", fromChecksum, toChecksum)
+	}
+
+	return nil
+}
+
+func fileChecksum(fs boshsys.FileSystem, path string) (string, error) {
+	file, err := fs.OpenFile(path, os.O_RDONLY, 0)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Opening '%s'", path)
+	}
+
+	defer file.Close()
+
+	hash := sha256.New()
+
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Reading '%s'", path)
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
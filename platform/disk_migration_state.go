@@ -0,0 +1,113 @@
+package platform
+
+import (
+	"encoding/json"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type DiskMigrationPhase string
+
+const (
+	DiskMigrationPhaseCopying  DiskMigrationPhase = "copying"
+	DiskMigrationPhaseVerified DiskMigrationPhase = "verified"
+)
+
+// DiskMigrationState is persisted while a persistent disk migration is in
+// progress so that the migration can be resumed after an agent restart.
+type DiskMigrationState struct {
+	FromMountPoint string             `json:"from_mount_point"`
+	ToMountPoint   string             `json:"to_mount_point"`
+	Phase          DiskMigrationPhase `json:"phase"`
+
+	// Partition of new disk which has to be mounted again when resuming
+	ToPartitionPath string `json:"to_partition_path,omitempty"`
+
+	// Mount options of new disk recorded when it was mounted for migration
+	MountOptions []string `json:"mount_options,omitempty"`
+
+	// Disks mounted within old disk, e.g. associated disks, which are
+	// unmounted before copying and mounted again on top of new disk
+	NestedMounts []DiskMigrationNestedMount `json:"nested_mounts,omitempty"`
+
+	// Totals of regular files found on new disk when it was verified
+	FilesCopied uint64 `json:"files_copied"`
+	BytesCopied uint64 `json:"bytes_copied"`
+
+	path string
+	fs   boshsys.FileSystem
+}
+
+// DiskMigrationTotals reports regular files found on new disk when it was verified
+type DiskMigrationTotals struct {
+	FilesCopied uint64 `json:"files_copied"`
+	BytesCopied uint64 `json:"bytes_copied"`
+}
+
+type DiskMigrationNestedMount struct {
+	PartitionPath string `json:"partition_path"`
+	MountPoint    string `json:"mount_point"`
+
+	// Mount options recorded when associated disk was mounted
+	MountOptions []string `json:"mount_options,omitempty"`
+}
+
+type nestedMountsByMountPoint []DiskMigrationNestedMount
+
+func (m nestedMountsByMountPoint) Len() int           { return len(m) }
+func (m nestedMountsByMountPoint) Less(i, j int) bool { return m[i].MountPoint < m[j].MountPoint }
+func (m nestedMountsByMountPoint) Swap(i, j int)      { m[i], m[j] = m[j], m[i] }
+
+func NewDiskMigrationState(fs boshsys.FileSystem, path string) (*DiskMigrationState, error) {
+	state := DiskMigrationState{fs: fs, path: path}
+
+	if !fs.FileExists(path) {
+		return &state, nil
+	}
+
+	bytes, err := fs.ReadFile(path)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Reading disk migration state file")
+	}
+
+	err = json.Unmarshal(bytes, &state)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Unmarshalling disk migration state")
+	}
+
+	return &state, nil
+}
+
+// Matches returns true when the state was recorded for the same pair of mount points
+func (s *DiskMigrationState) Matches(fromMountPoint, toMountPoint string) bool {
+	return s.FromMountPoint == fromMountPoint && s.ToMountPoint == toMountPoint
+}
+
+// Started returns true when migration of files has already begun
+func (s *DiskMigrationState) Started() bool {
+	return s.Phase != ""
+}
+
+func (s *DiskMigrationState) SaveState() error {
+	jsonState, err := json.Marshal(*s)
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling disk migration state")
+	}
+
+	err = s.fs.WriteFile(s.path, jsonState)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing disk migration state to file")
+	}
+
+	return nil
+}
+
+func (s *DiskMigrationState) Clear() error {
+	err := s.fs.RemoveAll(s.path)
+	if err != nil {
+		return bosherr.WrapError(err, "Removing disk migration state file")
+	}
+
+	return nil
+}
@@ -0,0 +1,181 @@
+package dryrun
+
+import (
+	"io/ioutil"
+	"strings"
+	"time"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// readOnlyCommands are run even in dry run since they only inspect the host
+// and their output is used to decide what changes are necessary
+var readOnlyCommands = map[string]func(args []string) bool{
+	"blkid":    anyArgs,
+	"lsblk":    anyArgs,
+	"readlink": anyArgs,
+	"dig":      anyArgs,
+	"route":    anyArgs,
+	"mount":    noArgs,
+	"hostname": noArgs,
+	"swapon":   firstArgIn("-s"),
+	"dmsetup":  firstArgIn("ls"),
+	"sfdisk":   firstArgIn("-d", "-s"),
+	"parted":   lastArgIn("print", "p"),
+	"sshd":     firstArgIn("-t"),
+	"visudo":   firstArgIn("-c"),
+	"udevadm":  firstArgIn("settle"),
+}
+
+const redactedArg = "<redacted>"
+
+// redactedFlags are followed by values such as password hashes which
+// are not shown in recorded commands
+var redactedFlags = map[string][]string{
+	"useradd": {"-p"},
+	"usermod": {"-p"},
+}
+
+// cmdRunner runs read only commands and records all others
+type cmdRunner struct {
+	runner   boshsys.CmdRunner
+	recorder *Recorder
+	logger   boshlog.Logger
+	logTag   string
+}
+
+func NewCmdRunner(runner boshsys.CmdRunner, recorder *Recorder, logger boshlog.Logger) boshsys.CmdRunner {
+	return cmdRunner{
+		runner:   runner,
+		recorder: recorder,
+		logger:   logger,
+		logTag:   "DryRunCmdRunner",
+	}
+}
+
+func (r cmdRunner) RunComplexCommand(cmd boshsys.Command) (string, string, int, error) {
+	if isReadOnly(cmd.Name, cmd.Args) {
+		return r.runner.RunComplexCommand(cmd)
+	}
+
+	r.recordCommand(cmd)
+
+	return "", "", 0, nil
+}
+
+func (r cmdRunner) RunComplexCommandAsync(cmd boshsys.Command) (boshsys.Process, error) {
+	if isReadOnly(cmd.Name, cmd.Args) {
+		return r.runner.RunComplexCommandAsync(cmd)
+	}
+
+	r.recordCommand(cmd)
+
+	return finishedProcess{}, nil
+}
+
+func (r cmdRunner) RunCommand(cmdName string, args ...string) (string, string, int, error) {
+	if isReadOnly(cmdName, args) {
+		return r.runner.RunCommand(cmdName, args...)
+	}
+
+	r.record(Change{Kind: "command", Command: formatCommand(cmdName, args)})
+
+	return "", "", 0, nil
+}
+
+func (r cmdRunner) RunCommandWithInput(input, cmdName string, args ...string) (string, string, int, error) {
+	if isReadOnly(cmdName, args) {
+		return r.runner.RunCommandWithInput(input, cmdName, args...)
+	}
+
+	r.record(Change{Kind: "command", Command: formatCommand(cmdName, args), Input: input})
+
+	return "", "", 0, nil
+}
+
+func (r cmdRunner) CommandExists(cmdName string) bool {
+	return r.runner.CommandExists(cmdName)
+}
+
+func (r cmdRunner) recordCommand(cmd boshsys.Command) {
+	change := Change{Kind: "command", Command: formatCommand(cmd.Name, cmd.Args)}
+
+	if cmd.Stdin != nil {
+		input, err := ioutil.ReadAll(cmd.Stdin)
+		if err != nil {
+			r.logger.Warn(r.logTag, "Failed to read input of `%s': %s", change.Command, err.Error())
+		}
+		change.Input = string(input)
+	}
+
+	r.record(change)
+}
+
+func (r cmdRunner) record(change Change) {
+	r.logger.Debug(r.logTag, "Recording command `%s'", change.Command)
+	r.recorder.Record(change)
+}
+
+func isReadOnly(cmdName string, args []string) bool {
+	matches, found := readOnlyCommands[cmdName]
+	return found && matches(args)
+}
+
+func formatCommand(cmdName string, args []string) string {
+	return strings.Join(append([]string{cmdName}, redactArgs(cmdName, args)...), " ")
+}
+
+func redactArgs(cmdName string, args []string) []string {
+	flags, found := redactedFlags[cmdName]
+	if !found {
+		return args
+	}
+
+	redacted := append([]string{}, args...)
+	for i := 1; i < len(redacted); i++ {
+		if containsString(flags, redacted[i-1]) {
+			redacted[i] = redactedArg
+		}
+	}
+
+	return redacted
+}
+
+func anyArgs(args []string) bool { return true }
+
+func noArgs(args []string) bool { return len(args) == 0 }
+
+func firstArgIn(values ...string) func(args []string) bool {
+	return func(args []string) bool {
+		return len(args) > 0 && containsString(values, args[0])
+	}
+}
+
+func lastArgIn(values ...string) func(args []string) bool {
+	return func(args []string) bool {
+		return len(args) > 0 && containsString(values, args[len(args)-1])
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// finishedProcess stands for recorded command which exited successfully
+type finishedProcess struct{}
+
+func (p finishedProcess) Wait() <-chan boshsys.Result {
+	resultCh := make(chan boshsys.Result, 1)
+	resultCh <- boshsys.Result{}
+	return resultCh
+}
+
+func (p finishedProcess) TerminateNicely(killGracePeriod time.Duration) error {
+	return nil
+}
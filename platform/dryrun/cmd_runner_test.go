@@ -0,0 +1,103 @@
+package dryrun_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/dryrun"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("cmdRunner", func() {
+	var (
+		fakeRunner *fakesys.FakeCmdRunner
+		recorder   *Recorder
+		runner     boshsys.CmdRunner
+	)
+
+	BeforeEach(func() {
+		fakeRunner = fakesys.NewFakeCmdRunner()
+		recorder = NewRecorder()
+		runner = NewCmdRunner(fakeRunner, recorder, boshlog.NewLogger(boshlog.LevelNone))
+	})
+
+	It("runs read only commands and returns their output", func() {
+		fakeRunner.AddCmdResult("sfdisk -d /dev/sda", fakesys.FakeCmdResult{Stdout: "fake-partitions"})
+
+		stdout, _, _, err := runner.RunCommand("sfdisk", "-d", "/dev/sda")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stdout).To(Equal("fake-partitions"))
+
+		Expect(fakeRunner.RunCommands).To(Equal([][]string{{"sfdisk", "-d", "/dev/sda"}}))
+		Expect(recorder.Changes()).To(BeEmpty())
+	})
+
+	It("runs parted print in both its long and short form", func() {
+		_, _, _, err := runner.RunCommand("parted", "-s", "/dev/sda", "print")
+		Expect(err).ToNot(HaveOccurred())
+
+		_, _, _, err = runner.RunCommand("parted", "-s", "/dev/sdb", "p")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(fakeRunner.RunCommands).To(Equal([][]string{
+			{"parted", "-s", "/dev/sda", "print"},
+			{"parted", "-s", "/dev/sdb", "p"},
+		}))
+		Expect(recorder.Changes()).To(BeEmpty())
+	})
+
+	It("records other commands and reports them as successful", func() {
+		_, _, exitStatus, err := runner.RunCommandWithInput("fake-input", "sfdisk", "/dev/sda")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exitStatus).To(Equal(0))
+
+		_, _, _, err = runner.RunComplexCommand(boshsys.Command{
+			Name:  "chpasswd",
+			Stdin: strings.NewReader("fake-user:fake-password"),
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(fakeRunner.RunCommandsWithInput).To(BeEmpty())
+		Expect(fakeRunner.RunComplexCommands).To(BeEmpty())
+		Expect(recorder.Changes()).To(Equal([]Change{
+			{Kind: "command", Command: "sfdisk /dev/sda", Input: "fake-input"},
+			{Kind: "command", Command: "chpasswd", Input: "fake-user:fake-password"},
+		}))
+	})
+
+	It("records commands which only look read only with different arguments", func() {
+		_, _, _, err := runner.RunCommand("parted", "-s", "/dev/sda", "mklabel", "gpt")
+		Expect(err).ToNot(HaveOccurred())
+
+		_, _, _, err = runner.RunCommand("hostname", "fake-hostname")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(fakeRunner.RunCommands).To(BeEmpty())
+		Expect(recorder.Changes()).To(HaveLen(2))
+	})
+
+	It("redacts passwords of recorded user commands", func() {
+		_, _, _, err := runner.RunCommand("usermod", "-p", "fake-encrypted-password", "vcap")
+		Expect(err).ToNot(HaveOccurred())
+
+		_, _, _, err = runner.RunCommand("useradd", "-m", "-p", "fake-encrypted-password", "fake-user")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(recorder.Changes()).To(Equal([]Change{
+			{Kind: "command", Command: "usermod -p <redacted> vcap"},
+			{Kind: "command", Command: "useradd -m -p <redacted> fake-user"},
+		}))
+	})
+
+	It("returns finished process for recorded async commands", func() {
+		process, err := runner.RunComplexCommandAsync(boshsys.Command{Name: "update-ca-certificates", Args: []string{"-f"}})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(<-process.Wait()).To(Equal(boshsys.Result{}))
+		Expect(recorder.Changes()).To(Equal([]Change{{Kind: "command", Command: "update-ca-certificates -f"}}))
+	})
+})
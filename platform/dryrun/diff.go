@@ -0,0 +1,164 @@
+package dryrun
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	diffContextLines = 3
+
+	// Files with more lines than this are not diffed line by line
+	maxDiffLines = 2000
+)
+
+type diffOp struct {
+	kind    byte
+	text    string
+	oldLine int
+	newLine int
+}
+
+// unifiedDiff returns differences between old and new contents of file at path
+func unifiedDiff(path string, oldContent, newContent []byte, existed bool) string {
+	if bytes.Equal(oldContent, newContent) && existed {
+		return ""
+	}
+
+	oldName := path
+	if !existed {
+		oldName = "/dev/null"
+	}
+
+	header := fmt.Sprintf("--- %s\n+++ %s\n", oldName, path)
+
+	if isBinary(oldContent) || isBinary(newContent) {
+		return header + fmt.Sprintf("Binary contents differ (%d bytes -> %d bytes)\n", len(oldContent), len(newContent))
+	}
+
+	oldLines, newLines := splitLines(oldContent), splitLines(newContent)
+	if len(oldLines) > maxDiffLines || len(newLines) > maxDiffLines {
+		return header + fmt.Sprintf("Contents differ (%d lines -> %d lines)\n", len(oldLines), len(newLines))
+	}
+
+	ops := diffLines(oldLines, newLines)
+
+	var buffer bytes.Buffer
+	buffer.WriteString(header)
+
+	for _, hunk := range diffHunks(ops) {
+		writeHunk(&buffer, ops[hunk[0]:hunk[1]])
+	}
+
+	return buffer.String()
+}
+
+func isBinary(content []byte) bool {
+	return bytes.IndexByte(content, 0) != -1 || !utf8.Valid(content)
+}
+
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return []string{}
+	}
+	return strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+}
+
+// diffLines returns edit script based on longest common subsequence of lines
+func diffLines(oldLines, newLines []string) []diffOp {
+	lcs := make([][]int, len(oldLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(newLines)+1)
+	}
+
+	for i := len(oldLines) - 1; i >= 0; i-- {
+		for j := len(newLines) - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	ops := []diffOp{}
+	i, j := 0, 0
+
+	for i < len(oldLines) || j < len(newLines) {
+		switch {
+		case i < len(oldLines) && j < len(newLines) && oldLines[i] == newLines[j]:
+			ops = append(ops, diffOp{kind: ' ', text: oldLines[i], oldLine: i, newLine: j})
+			i++
+			j++
+		case j < len(newLines) && (i == len(oldLines) || lcs[i][j+1] > lcs[i+1][j]):
+			ops = append(ops, diffOp{kind: '+', text: newLines[j], oldLine: i, newLine: j})
+			j++
+		default:
+			ops = append(ops, diffOp{kind: '-', text: oldLines[i], oldLine: i, newLine: j})
+			i++
+		}
+	}
+
+	return ops
+}
+
+// diffHunks returns [start, end) ranges of ops with changes surrounded by context
+func diffHunks(ops []diffOp) [][2]int {
+	hunks := [][2]int{}
+
+	for i, op := range ops {
+		if op.kind == ' ' {
+			continue
+		}
+
+		start := i - diffContextLines
+		if start < 0 {
+			start = 0
+		}
+
+		end := i + diffContextLines + 1
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		if len(hunks) > 0 && start <= hunks[len(hunks)-1][1] {
+			hunks[len(hunks)-1][1] = end
+		} else {
+			hunks = append(hunks, [2]int{start, end})
+		}
+	}
+
+	return hunks
+}
+
+func writeHunk(buffer *bytes.Buffer, ops []diffOp) {
+	var oldCount, newCount int
+	for _, op := range ops {
+		if op.kind != '+' {
+			oldCount++
+		}
+		if op.kind != '-' {
+			newCount++
+		}
+	}
+
+	oldStart, newStart := ops[0].oldLine, ops[0].newLine
+	if oldCount > 0 {
+		oldStart++
+	}
+	if newCount > 0 {
+		newStart++
+	}
+
+	fmt.Fprintf(buffer, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+
+	for _, op := range ops {
+		buffer.WriteByte(op.kind)
+		buffer.WriteString(op.text)
+		buffer.WriteByte('\n')
+	}
+}
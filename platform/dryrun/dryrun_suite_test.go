@@ -0,0 +1,13 @@
+package dryrun_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestDryrun(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Dry Run Suite")
+}
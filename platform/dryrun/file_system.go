@@ -0,0 +1,533 @@
+package dryrun
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const redactedDiff = "<redacted>"
+
+// fileSystem reads from underlying file system but only records changes.
+// Changed contents are kept in memory so that subsequent reads observe them.
+type fileSystem struct {
+	fs            boshsys.FileSystem
+	recorder      *Recorder
+	redactedPaths map[string]bool
+	logger        boshlog.Logger
+	logTag        string
+
+	files    map[string][]byte
+	dirs     map[string]bool
+	symlinks map[string]string
+	removed  map[string]bool
+	lock     sync.Mutex
+}
+
+// NewFileSystem returns file system which records changes instead of making them.
+// Contents written to redactedPaths (e.g. settings with credentials) are not diffed.
+func NewFileSystem(
+	fs boshsys.FileSystem,
+	recorder *Recorder,
+	redactedPaths []string,
+	logger boshlog.Logger,
+) boshsys.FileSystem {
+	redacted := map[string]bool{}
+	for _, path := range redactedPaths {
+		redacted[filepath.Clean(path)] = true
+	}
+
+	return &fileSystem{
+		fs:            fs,
+		recorder:      recorder,
+		redactedPaths: redacted,
+		logger:        logger,
+		logTag:        "DryRunFileSystem",
+
+		files:    map[string][]byte{},
+		dirs:     map[string]bool{},
+		symlinks: map[string]string{},
+		removed:  map[string]bool{},
+	}
+}
+
+func (f *fileSystem) HomeDir(username string) (string, error) {
+	return f.fs.HomeDir(username)
+}
+
+func (f *fileSystem) ExpandPath(path string) (string, error) {
+	return f.fs.ExpandPath(path)
+}
+
+func (f *fileSystem) MkdirAll(path string, perm os.FileMode) error {
+	path = filepath.Clean(path)
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.exists(path) {
+		return nil
+	}
+
+	f.record(Change{Kind: "mkdir", Path: path, Mode: formatMode(perm)})
+
+	for dir := path; dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		delete(f.removed, dir)
+	}
+	f.dirs[path] = true
+
+	return nil
+}
+
+func (f *fileSystem) RemoveAll(fileOrDir string) error {
+	fileOrDir = filepath.Clean(fileOrDir)
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if !f.exists(fileOrDir) {
+		return nil
+	}
+
+	f.record(Change{Kind: "remove", Path: fileOrDir})
+	f.forget(fileOrDir)
+	f.removed[fileOrDir] = true
+
+	return nil
+}
+
+func (f *fileSystem) Chown(path, username string) error {
+	f.record(Change{Kind: "chown", Path: filepath.Clean(path), Owner: username})
+	return nil
+}
+
+func (f *fileSystem) Chmod(path string, perm os.FileMode) error {
+	f.record(Change{Kind: "chmod", Path: filepath.Clean(path), Mode: formatMode(perm)})
+	return nil
+}
+
+// OpenFile opens file for writing as read only so that
+// writes (e.g. to partition tables of devices) can be recorded
+func (f *fileSystem) OpenFile(path string, flag int, perm os.FileMode) (boshsys.File, error) {
+	writeFlags := os.O_WRONLY | os.O_RDWR | os.O_APPEND | os.O_CREATE | os.O_TRUNC
+	if flag&writeFlags == 0 {
+		return f.fs.OpenFile(path, flag, perm)
+	}
+
+	path = filepath.Clean(path)
+
+	if !f.FileExists(path) {
+		if flag&os.O_CREATE == 0 {
+			return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+		}
+		return &recordingFile{path: path, recorder: f.recorder}, nil
+	}
+
+	file, err := f.fs.OpenFile(path, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &recordingFile{file: file, path: path, recorder: f.recorder}, nil
+}
+
+func (f *fileSystem) WriteFileString(path, content string) error {
+	return f.WriteFile(path, []byte(content))
+}
+
+func (f *fileSystem) WriteFile(path string, content []byte) error {
+	path = filepath.Clean(path)
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.write(Change{Kind: "write_file", Path: path}, path, content)
+
+	return nil
+}
+
+func (f *fileSystem) ConvergeFileContents(path string, content []byte) (bool, error) {
+	path = filepath.Clean(path)
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if existingContent, found := f.contents(path); found && bytes.Equal(existingContent, content) {
+		return false, nil
+	}
+
+	f.write(Change{Kind: "write_file", Path: path}, path, content)
+
+	return true, nil
+}
+
+func (f *fileSystem) ReadFileString(path string) (string, error) {
+	content, err := f.ReadFile(path)
+	return string(content), err
+}
+
+func (f *fileSystem) ReadFile(path string) ([]byte, error) {
+	path = filepath.Clean(path)
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if content, found := f.files[path]; found {
+		return content, nil
+	}
+
+	if f.isRemoved(path) {
+		return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+	}
+
+	return f.fs.ReadFile(path)
+}
+
+func (f *fileSystem) FileExists(path string) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.exists(filepath.Clean(path))
+}
+
+func (f *fileSystem) Stat(path string) (os.FileInfo, error) {
+	return f.stat(path, f.fs.Stat)
+}
+
+func (f *fileSystem) Lstat(path string) (os.FileInfo, error) {
+	return f.stat(path, f.fs.Lstat)
+}
+
+func (f *fileSystem) Rename(oldPath, newPath string) error {
+	oldPath, newPath = filepath.Clean(oldPath), filepath.Clean(newPath)
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if !f.exists(oldPath) {
+		return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: os.ErrNotExist}
+	}
+
+	change := Change{Kind: "rename", Path: newPath, Target: oldPath}
+
+	// Renaming usually moves freshly written file into place
+	// so that diff against the replaced file is what matters
+	content, found := f.contents(oldPath)
+	if found {
+		f.write(change, newPath, content)
+	} else {
+		f.record(change)
+		f.dirs[newPath] = true
+	}
+
+	f.forget(oldPath)
+	f.removed[oldPath] = true
+
+	return nil
+}
+
+func (f *fileSystem) Symlink(oldPath, newPath string) error {
+	newPath = filepath.Clean(newPath)
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if target, err := f.readlink(newPath); err == nil && target == oldPath {
+		return nil
+	}
+
+	f.record(Change{Kind: "symlink", Path: newPath, Target: oldPath})
+	f.forget(newPath)
+	delete(f.removed, newPath)
+	f.symlinks[newPath] = oldPath
+
+	return nil
+}
+
+func (f *fileSystem) ReadAndFollowLink(symlinkPath string) (string, error) {
+	f.lock.Lock()
+	target, found := f.symlinks[filepath.Clean(symlinkPath)]
+	f.lock.Unlock()
+
+	if found {
+		return target, nil
+	}
+
+	return f.fs.ReadAndFollowLink(symlinkPath)
+}
+
+func (f *fileSystem) Readlink(symlinkPath string) (string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.readlink(filepath.Clean(symlinkPath))
+}
+
+func (f *fileSystem) CopyFile(srcPath, dstPath string) error {
+	srcPath, dstPath = filepath.Clean(srcPath), filepath.Clean(dstPath)
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	content, found := f.contents(srcPath)
+	if !found {
+		return &os.PathError{Op: "open", Path: srcPath, Err: os.ErrNotExist}
+	}
+
+	f.write(Change{Kind: "copy", Path: dstPath, Target: srcPath}, dstPath, content)
+
+	return nil
+}
+
+func (f *fileSystem) CopyDir(srcPath, dstPath string) error {
+	dstPath = filepath.Clean(dstPath)
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.record(Change{Kind: "copy", Path: dstPath, Target: filepath.Clean(srcPath)})
+	delete(f.removed, dstPath)
+	f.dirs[dstPath] = true
+
+	return nil
+}
+
+// TempFile and TempDir are used for scratch data and are not recorded
+func (f *fileSystem) TempFile(prefix string) (boshsys.File, error) {
+	return f.fs.TempFile(prefix)
+}
+
+func (f *fileSystem) TempDir(prefix string) (string, error) {
+	return f.fs.TempDir(prefix)
+}
+
+// ChangeTempRoot keeps current temp root since new one is never created
+func (f *fileSystem) ChangeTempRoot(path string) error {
+	f.logger.Debug(f.logTag, "Keeping temp root instead of changing it to `%s'", path)
+	return nil
+}
+
+func (f *fileSystem) Glob(pattern string) ([]string, error) {
+	return f.fs.Glob(pattern)
+}
+
+func (f *fileSystem) RecursiveGlob(pattern string) ([]string, error) {
+	return f.fs.RecursiveGlob(pattern)
+}
+
+func (f *fileSystem) Walk(root string, walkFunc filepath.WalkFunc) error {
+	return f.fs.Walk(root, walkFunc)
+}
+
+func (f *fileSystem) record(change Change) {
+	f.logger.Debug(f.logTag, "Recording %s of `%s'", change.Kind, change.Path)
+	f.recorder.Record(change)
+}
+
+// write must be called with lock held
+func (f *fileSystem) write(change Change, path string, content []byte) {
+	existingContent, existed := f.contents(path)
+
+	if f.redactedPaths[path] {
+		change.Diff = redactedDiff
+	} else {
+		change.Diff = unifiedDiff(path, existingContent, content, existed)
+	}
+
+	f.record(change)
+
+	delete(f.removed, path)
+	delete(f.symlinks, path)
+	f.files[path] = content
+}
+
+// contents must be called with lock held
+func (f *fileSystem) contents(path string) ([]byte, bool) {
+	if content, found := f.files[path]; found {
+		return content, true
+	}
+
+	if f.isRemoved(path) {
+		return nil, false
+	}
+
+	content, err := f.fs.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	return content, true
+}
+
+// exists must be called with lock held
+func (f *fileSystem) exists(path string) bool {
+	if _, found := f.files[path]; found {
+		return true
+	}
+	if f.dirs[path] {
+		return true
+	}
+	if _, found := f.symlinks[path]; found {
+		return true
+	}
+	if f.isRemoved(path) {
+		return false
+	}
+	return f.fs.FileExists(path)
+}
+
+// readlink must be called with lock held
+func (f *fileSystem) readlink(path string) (string, error) {
+	if target, found := f.symlinks[path]; found {
+		return target, nil
+	}
+
+	if f.isRemoved(path) {
+		return "", &os.PathError{Op: "readlink", Path: path, Err: os.ErrNotExist}
+	}
+
+	return f.fs.Readlink(path)
+}
+
+// isRemoved returns true if path or one of its parents was removed
+func (f *fileSystem) isRemoved(path string) bool {
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if f.removed[dir] {
+			return true
+		}
+		if dir == filepath.Dir(dir) {
+			return false
+		}
+	}
+}
+
+// forget drops recorded contents of path and everything under it
+func (f *fileSystem) forget(path string) {
+	prefix := path + string(filepath.Separator)
+
+	for _, entries := range []map[string]bool{f.dirs, f.removed} {
+		for entryPath := range entries {
+			if entryPath == path || strings.HasPrefix(entryPath, prefix) {
+				delete(entries, entryPath)
+			}
+		}
+	}
+
+	for filePath := range f.files {
+		if filePath == path || strings.HasPrefix(filePath, prefix) {
+			delete(f.files, filePath)
+		}
+	}
+
+	for linkPath := range f.symlinks {
+		if linkPath == path || strings.HasPrefix(linkPath, prefix) {
+			delete(f.symlinks, linkPath)
+		}
+	}
+}
+
+func (f *fileSystem) stat(path string, statFunc func(string) (os.FileInfo, error)) (os.FileInfo, error) {
+	path = filepath.Clean(path)
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if content, found := f.files[path]; found {
+		return fileInfo{name: filepath.Base(path), size: int64(len(content)), mode: 0644}, nil
+	}
+
+	if f.dirs[path] {
+		return fileInfo{name: filepath.Base(path), mode: os.ModeDir | 0755}, nil
+	}
+
+	if _, found := f.symlinks[path]; found {
+		return fileInfo{name: filepath.Base(path), mode: os.ModeSymlink | 0777}, nil
+	}
+
+	if f.isRemoved(path) {
+		return nil, &os.PathError{Op: "stat", Path: path, Err: os.ErrNotExist}
+	}
+
+	return statFunc(path)
+}
+
+func formatMode(perm os.FileMode) string {
+	return fmt.Sprintf("%04o", uint32(perm.Perm()))
+}
+
+// recordingFile reads from underlying file (if it exists) and records writes
+type recordingFile struct {
+	file     boshsys.File
+	path     string
+	recorder *Recorder
+}
+
+func (f *recordingFile) Read(p []byte) (int, error) {
+	if f.file == nil {
+		return 0, io.EOF
+	}
+	return f.file.Read(p)
+}
+
+func (f *recordingFile) ReadAt(p []byte, off int64) (int, error) {
+	if f.file == nil {
+		return 0, io.EOF
+	}
+	return f.file.ReadAt(p, off)
+}
+
+func (f *recordingFile) Seek(offset int64, whence int) (int64, error) {
+	if f.file == nil {
+		return 0, nil
+	}
+	return f.file.Seek(offset, whence)
+}
+
+func (f *recordingFile) Write(p []byte) (int, error) {
+	f.recorder.Record(Change{Kind: "write", Path: f.path, Detail: fmt.Sprintf("%d bytes", len(p))})
+	return len(p), nil
+}
+
+func (f *recordingFile) WriteAt(p []byte, off int64) (int, error) {
+	f.recorder.Record(Change{Kind: "write", Path: f.path, Detail: fmt.Sprintf("%d bytes at offset %d", len(p), off)})
+	return len(p), nil
+}
+
+func (f *recordingFile) Stat() (os.FileInfo, error) {
+	if f.file == nil {
+		return fileInfo{name: filepath.Base(f.path), mode: 0644}, nil
+	}
+	return f.file.Stat()
+}
+
+func (f *recordingFile) Name() string {
+	return f.path
+}
+
+func (f *recordingFile) Close() error {
+	if f.file == nil {
+		return nil
+	}
+	return f.file.Close()
+}
+
+type fileInfo struct {
+	name string
+	size int64
+	mode os.FileMode
+}
+
+func (i fileInfo) Name() string       { return i.name }
+func (i fileInfo) Size() int64        { return i.size }
+func (i fileInfo) Mode() os.FileMode  { return i.mode }
+func (i fileInfo) ModTime() time.Time { return time.Time{} }
+func (i fileInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i fileInfo) Sys() interface{}   { return nil }
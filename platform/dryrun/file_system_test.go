@@ -0,0 +1,170 @@
+package dryrun_test
+
+import (
+	"os"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/dryrun"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("fileSystem", func() {
+	var (
+		realFs   *fakesys.FakeFileSystem
+		recorder *Recorder
+		fs       boshsys.FileSystem
+	)
+
+	BeforeEach(func() {
+		realFs = fakesys.NewFakeFileSystem()
+		recorder = NewRecorder()
+		fs = NewFileSystem(realFs, recorder, []string{"/fake-settings.json"}, boshlog.NewLogger(boshlog.LevelNone))
+	})
+
+	Describe("WriteFile", func() {
+		It("records diff against existing contents without writing", func() {
+			err := realFs.WriteFileString("/etc/hosts", "127.0.0.1 localhost\n10.0.0.1 old-host\n")
+			Expect(err).ToNot(HaveOccurred())
+
+			err = fs.WriteFileString("/etc/hosts", "127.0.0.1 localhost\n10.0.0.2 new-host\n")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(recorder.Changes()).To(Equal([]Change{{
+				Kind: "write_file",
+				Path: "/etc/hosts",
+				Diff: strings.Join([]string{
+					"--- /etc/hosts",
+					"+++ /etc/hosts",
+					"@@ -1,2 +1,2 @@",
+					" 127.0.0.1 localhost",
+					"-10.0.0.1 old-host",
+					"+10.0.0.2 new-host",
+					"",
+				}, "\n"),
+			}}))
+
+			Expect(realFs.ReadFileString("/etc/hosts")).To(Equal("127.0.0.1 localhost\n10.0.0.1 old-host\n"))
+		})
+
+		It("makes written contents visible to subsequent reads", func() {
+			err := fs.WriteFileString("/fake-file", "fake-content")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.FileExists("/fake-file")).To(BeTrue())
+			Expect(fs.ReadFileString("/fake-file")).To(Equal("fake-content"))
+			Expect(realFs.FileExists("/fake-file")).To(BeFalse())
+
+			Expect(recorder.Changes()[0].Diff).To(HavePrefix("--- /dev/null\n+++ /fake-file\n@@ -0,0 +1,1 @@\n+fake-content\n"))
+		})
+
+		It("redacts contents of redacted paths", func() {
+			err := fs.WriteFileString("/fake-settings.json", `{"password":"fake-password"}`)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(recorder.Changes()).To(Equal([]Change{{Kind: "write_file", Path: "/fake-settings.json", Diff: "<redacted>"}}))
+		})
+	})
+
+	Describe("ConvergeFileContents", func() {
+		It("does not record anything when contents match", func() {
+			err := realFs.WriteFileString("/fake-file", "fake-content")
+			Expect(err).ToNot(HaveOccurred())
+
+			written, err := fs.ConvergeFileContents("/fake-file", []byte("fake-content"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(written).To(BeFalse())
+			Expect(recorder.Changes()).To(BeEmpty())
+
+			written, err = fs.ConvergeFileContents("/fake-file", []byte("new-content"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(written).To(BeTrue())
+			Expect(recorder.Changes()).To(HaveLen(1))
+		})
+	})
+
+	Describe("directories and attributes", func() {
+		It("records changes without making them", func() {
+			err := realFs.WriteFileString("/fake-dir/fake-file", "fake-content")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.MkdirAll("/fake-new-dir", os.FileMode(0750))).To(Succeed())
+			Expect(fs.MkdirAll("/fake-dir", os.FileMode(0750))).To(Succeed())
+			Expect(fs.Chown("/fake-new-dir", "vcap")).To(Succeed())
+			Expect(fs.Chmod("/fake-dir/fake-file", os.FileMode(0600))).To(Succeed())
+			Expect(fs.Symlink("/fake-dir", "/fake-link")).To(Succeed())
+			Expect(fs.RemoveAll("/fake-dir")).To(Succeed())
+			Expect(fs.RemoveAll("/fake-missing-dir")).To(Succeed())
+
+			Expect(recorder.Changes()).To(Equal([]Change{
+				{Kind: "mkdir", Path: "/fake-new-dir", Mode: "0750"},
+				{Kind: "chown", Path: "/fake-new-dir", Owner: "vcap"},
+				{Kind: "chmod", Path: "/fake-dir/fake-file", Mode: "0600"},
+				{Kind: "symlink", Path: "/fake-link", Target: "/fake-dir"},
+				{Kind: "remove", Path: "/fake-dir"},
+			}))
+
+			Expect(fs.FileExists("/fake-new-dir")).To(BeTrue())
+			Expect(fs.FileExists("/fake-dir/fake-file")).To(BeFalse())
+			Expect(fs.Readlink("/fake-link")).To(Equal("/fake-dir"))
+
+			Expect(realFs.FileExists("/fake-new-dir")).To(BeFalse())
+			Expect(realFs.FileExists("/fake-dir/fake-file")).To(BeTrue())
+		})
+	})
+
+	Describe("Rename", func() {
+		It("records diff of renamed file against file it replaces", func() {
+			err := realFs.WriteFileString("/etc/resolv.conf", "nameserver 8.8.8.8\n")
+			Expect(err).ToNot(HaveOccurred())
+
+			err = fs.WriteFileString("/etc/resolv.conf.tmp", "nameserver 10.0.0.2\n")
+			Expect(err).ToNot(HaveOccurred())
+
+			err = fs.Rename("/etc/resolv.conf.tmp", "/etc/resolv.conf")
+			Expect(err).ToNot(HaveOccurred())
+
+			changes := recorder.Changes()
+			Expect(changes).To(HaveLen(2))
+			Expect(changes[1].Kind).To(Equal("rename"))
+			Expect(changes[1].Target).To(Equal("/etc/resolv.conf.tmp"))
+			Expect(changes[1].Diff).To(ContainSubstring("-nameserver 8.8.8.8\n+nameserver 10.0.0.2\n"))
+
+			Expect(fs.FileExists("/etc/resolv.conf.tmp")).To(BeFalse())
+			Expect(fs.ReadFileString("/etc/resolv.conf")).To(Equal("nameserver 10.0.0.2\n"))
+		})
+	})
+
+	Describe("OpenFile", func() {
+		It("reads existing contents and records writes", func() {
+			err := realFs.WriteFileString("/dev/fake-device", "fake-partition-table")
+			Expect(err).ToNot(HaveOccurred())
+
+			file, err := fs.OpenFile("/dev/fake-device", os.O_RDWR, 0)
+			Expect(err).ToNot(HaveOccurred())
+
+			data := make([]byte, 4)
+			_, err = file.ReadAt(data, 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).To(Equal("fake"))
+
+			_, err = file.WriteAt([]byte("new"), 512)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(file.Close()).To(Succeed())
+
+			Expect(recorder.Changes()).To(Equal([]Change{
+				{Kind: "write", Path: "/dev/fake-device", Detail: "3 bytes at offset 512"},
+			}))
+			Expect(realFs.ReadFileString("/dev/fake-device")).To(Equal("fake-partition-table"))
+		})
+
+		It("returns error when file does not exist and is not created", func() {
+			_, err := fs.OpenFile("/dev/fake-missing-device", os.O_RDWR, 0)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,52 @@
+package dryrun
+
+import (
+	"sync"
+)
+
+// Change describes a single modification of the host
+// which would have been made outside of dry run
+type Change struct {
+	// e.g. write_file, mkdir, remove, chmod, chown, rename, symlink, copy, write, command
+	Kind string `json:"kind"`
+
+	Path    string `json:"path,omitempty"`
+	Target  string `json:"target,omitempty"`
+	Owner   string `json:"owner,omitempty"`
+	Mode    string `json:"mode,omitempty"`
+	Command string `json:"command,omitempty"`
+	Input   string `json:"input,omitempty"`
+
+	// Unified diff of file contents
+	Diff string `json:"diff,omitempty"`
+
+	// e.g. size and offset of data written to a device
+	Detail string `json:"detail,omitempty"`
+}
+
+// Recorder collects changes in the order they were requested
+type Recorder struct {
+	changes []Change
+	lock    sync.Mutex
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{changes: []Change{}}
+}
+
+func (r *Recorder) Record(change Change) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.changes = append(r.changes, change)
+}
+
+func (r *Recorder) Changes() []Change {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	changes := make([]Change, len(r.changes))
+	copy(changes, r.changes)
+
+	return changes
+}
@@ -119,6 +119,10 @@ func (p dummyPlatform) AddUserToGroups(username string, groups []string) (err er
 	return
 }
 
+func (p dummyPlatform) SetupUserSudoCommands(username string, commands []string) (err error) {
+	return
+}
+
 func (p dummyPlatform) DeleteEphemeralUsersMatching(regex string) (err error) {
 	return
 }
@@ -131,6 +135,14 @@ func (p dummyPlatform) SetupSSH(publicKey []string, username string) (err error)
 	return
 }
 
+func (p dummyPlatform) SetupSSHCertificateAuthority(trustedUserCAKeys []string, principals map[string][]string) (err error) {
+	return
+}
+
+func (p dummyPlatform) UpdateRevokedSSHKeys(revokedKeys []string) (err error) {
+	return
+}
+
 func (p dummyPlatform) SetUserPassword(user, encryptedPwd string) (err error) {
 	credentialsPath := filepath.Join(p.dirProvider.BoshDir(), user, CredentialFileName)
 	return p.fs.WriteFileString(credentialsPath, encryptedPwd)
@@ -155,6 +167,10 @@ func (p dummyPlatform) SetupNetworking(networks boshsettings.Networks) (err erro
 	return
 }
 
+func (p dummyPlatform) SetupDNS(networks boshsettings.Networks) (err error) {
+	return
+}
+
 func (p dummyPlatform) GetConfiguredNetworkInterfaces() (interfaces []string, err error) {
 	return
 }
@@ -167,6 +183,18 @@ func (p dummyPlatform) SetupLogrotate(groupName, basePath, size string) (err err
 	return
 }
 
+func (p dummyPlatform) SetupJobSecretsStaging(secretSizes []uint64) (stagingDir string, err error) {
+	return p.dirProvider.SecretsStagingDir(), nil
+}
+
+func (p dummyPlatform) SetupJobSecrets(jobName string, secrets map[string][]byte) (secretsDir string, err error) {
+	return p.dirProvider.JobSecretsDir(jobName), nil
+}
+
+func (p dummyPlatform) RemoveJobSecrets() (err error) {
+	return
+}
+
 func (p dummyPlatform) SetTimeWithNtpServers(servers []string) (err error) {
 	return
 }
@@ -236,7 +264,7 @@ func (p dummyPlatform) MountPersistentDisk(diskSettings boshsettings.DiskSetting
 		return err
 	}
 
-	managedSettingsPath := filepath.Join(p.dirProvider.BoshDir(), "managed_disk_settings.json")
+	managedSettingsPath := filepath.Join(p.dirProvider.BoshDir(), ManagedDiskSettingsFileName)
 
 	if isMountPoint {
 		currentManagedDisk, err := p.fs.ReadFileString(managedSettingsPath)
@@ -270,6 +298,27 @@ func (p dummyPlatform) MountPersistentDisk(diskSettings boshsettings.DiskSetting
 	return p.fs.WriteFile(p.mountsPath(), mountsJSON)
 }
 
+func (p dummyPlatform) MountAssociatedPersistentDisk(diskSettings boshsettings.DiskSettings, mountPoint string) error {
+	mounts, err := p.existingMounts()
+	if err != nil {
+		return err
+	}
+
+	for _, existingMount := range mounts {
+		if existingMount.DiskCid == diskSettings.ID && existingMount.MountDir == mountPoint {
+			return nil
+		}
+	}
+
+	mounts = append(mounts, mount{MountDir: mountPoint, DiskCid: diskSettings.ID})
+	mountsJSON, err := json.Marshal(mounts)
+	if err != nil {
+		return err
+	}
+
+	return p.fs.WriteFile(p.mountsPath(), mountsJSON)
+}
+
 func (p dummyPlatform) UnmountPersistentDisk(diskSettings boshsettings.DiskSettings) (didUnmount bool, err error) {
 	mounts, err := p.existingMounts()
 	if err != nil {
@@ -308,23 +357,23 @@ func (p dummyPlatform) GetFilesContentsFromDisk(diskPath string, fileNames []str
 	return
 }
 
-func (p dummyPlatform) MigratePersistentDisk(fromMountPoint, toMountPoint string) (err error) {
+func (p dummyPlatform) MigratePersistentDisk(fromMountPoint, toMountPoint string) (totals DiskMigrationTotals, err error) {
 	diskMigrationsPath := filepath.Join(p.dirProvider.BoshDir(), "disk_migrations.json")
 	var diskMigrations []diskMigration
 	if p.fs.FileExists(diskMigrationsPath) {
 		bytes, err := p.fs.ReadFile(diskMigrationsPath)
 		if err != nil {
-			return err
+			return totals, err
 		}
 		err = json.Unmarshal(bytes, &diskMigrations)
 		if err != nil {
-			return err
+			return totals, err
 		}
 	}
 
 	mounts, err := p.existingMounts()
 	if err != nil {
-		return err
+		return totals, err
 	}
 	fromDiskCid := p.getDiskCidByMountPoint(fromMountPoint, mounts)
 	toDiskCid := p.getDiskCidByMountPoint(toMountPoint, mounts)
@@ -333,10 +382,18 @@ func (p dummyPlatform) MigratePersistentDisk(fromMountPoint, toMountPoint string
 
 	diskMigrationsJSON, err := json.Marshal(diskMigrations)
 	if err != nil {
-		return err
+		return totals, err
 	}
 
-	return p.fs.WriteFile(diskMigrationsPath, diskMigrationsJSON)
+	return totals, p.fs.WriteFile(diskMigrationsPath, diskMigrationsJSON)
+}
+
+func (p dummyPlatform) FreezeFilesystem(mountPoint string) error {
+	return nil
+}
+
+func (p dummyPlatform) ThawFilesystem(mountPoint string) error {
+	return nil
 }
 
 func (p dummyPlatform) IsMountPoint(mountPointPath string) (partitionPath string, result bool, err error) {
@@ -2,6 +2,7 @@ package fakes
 
 import (
 	"path"
+	"sync"
 
 	boshdpresolv "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver"
 	fakedpresolv "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver/fakes"
@@ -38,9 +39,32 @@ type FakePlatform struct {
 	CreateUserBasePath string
 
 	AddUserToGroupsGroups             map[string][]string
+	SetupUserSudoCommandsCommands     map[string][]string
+	SetupUserSudoCommandsErr          error
+	deleteEphemeralUsersMutex         sync.RWMutex
 	DeleteEphemeralUsersMatchingRegex string
+	DeleteEphemeralUsersMatchingErr   error
 	SetupSSHPublicKeys                map[string][]string
 
+	SetupJobSecretsStagingCalled      bool
+	SetupJobSecretsStagingSecretSizes []uint64
+	SetupJobSecretsStagingErr         error
+
+	SetupJobSecretsSecrets map[string]map[string][]byte
+	SetupJobSecretsErr     error
+
+	RemoveJobSecretsCalled bool
+	RemoveJobSecretsErr    error
+
+	SetupSSHCertificateAuthorityCalled     bool
+	SetupSSHCertificateAuthorityCAKeys     []string
+	SetupSSHCertificateAuthorityPrincipals map[string][]string
+	SetupSSHCertificateAuthorityErr        error
+
+	UpdateRevokedSSHKeysCalled bool
+	UpdateRevokedSSHKeysKeys   []string
+	UpdateRevokedSSHKeysErr    error
+
 	SetupSSHCalled    bool
 	SetupSSHPublicKey []string
 	SetupSSHUsername  string
@@ -85,11 +109,18 @@ type FakePlatform struct {
 	SetupNetworkingNetworks boshsettings.Networks
 	SetupNetworkingErr      error
 
+	SetupDNSNetworks boshsettings.Networks
+	SetupDNSErr      error
+
 	MountPersistentDiskCalled     bool
 	MountPersistentDiskSettings   boshsettings.DiskSettings
 	MountPersistentDiskMountPoint string
 	MountPersistentDiskErr        error
 
+	MountAssociatedPersistentDiskSettings    []boshsettings.DiskSettings
+	MountAssociatedPersistentDiskMountPoints []string
+	MountAssociatedPersistentDiskErr         error
+
 	UnmountPersistentDiskDidUnmount bool
 	UnmountPersistentDiskSettings   boshsettings.DiskSettings
 
@@ -112,6 +143,17 @@ type FakePlatform struct {
 
 	MigratePersistentDiskFromMountPoint string
 	MigratePersistentDiskToMountPoint   string
+	MigratePersistentDiskTotals         platform.DiskMigrationTotals
+	MigratePersistentDiskErr            error
+
+	freezeMutex                 sync.RWMutex
+	FreezeFilesystemMountPoint  string
+	FreezeFilesystemMountPoints []string
+	FreezeFilesystemErr         error
+	FreezeFilesystemStub        func(string) error
+
+	ThawFilesystemMountPoints []string
+	ThawFilesystemErr         error
 
 	IsPersistentDiskMountableResult bool
 	IsPersistentDiskMountableErr    error
@@ -169,6 +211,8 @@ func NewFakePlatform() (platform *FakePlatform) {
 	platform.FakeVitalsService = fakevitals.NewFakeService()
 	platform.DevicePathResolver = fakedpresolv.NewFakeDevicePathResolver()
 	platform.AddUserToGroupsGroups = make(map[string][]string)
+	platform.SetupUserSudoCommandsCommands = make(map[string][]string)
+	platform.SetupJobSecretsSecrets = make(map[string]map[string][]byte)
 	platform.SetupSSHPublicKeys = make(map[string][]string)
 	platform.UserPasswords = make(map[string]string)
 	platform.ScsiDiskMap = make(map[string]string)
@@ -241,9 +285,24 @@ func (p *FakePlatform) AddUserToGroups(username string, groups []string) (err er
 	return
 }
 
+func (p *FakePlatform) SetupUserSudoCommands(username string, commands []string) error {
+	p.SetupUserSudoCommandsCommands[username] = commands
+	return p.SetupUserSudoCommandsErr
+}
+
 func (p *FakePlatform) DeleteEphemeralUsersMatching(regex string) (err error) {
+	p.deleteEphemeralUsersMutex.Lock()
+	defer p.deleteEphemeralUsersMutex.Unlock()
+
 	p.DeleteEphemeralUsersMatchingRegex = regex
-	return
+	return p.DeleteEphemeralUsersMatchingErr
+}
+
+func (p *FakePlatform) GetDeleteEphemeralUsersMatchingRegex() string {
+	p.deleteEphemeralUsersMutex.RLock()
+	defer p.deleteEphemeralUsersMutex.RUnlock()
+
+	return p.DeleteEphemeralUsersMatchingRegex
 }
 
 func (p *FakePlatform) SetupRootDisk(ephemeralDiskPath string) (err error) {
@@ -262,6 +321,19 @@ func (p *FakePlatform) SetupSSH(publicKey []string, username string) error {
 	return p.SetupSSHErr
 }
 
+func (p *FakePlatform) SetupSSHCertificateAuthority(trustedUserCAKeys []string, principals map[string][]string) error {
+	p.SetupSSHCertificateAuthorityCalled = true
+	p.SetupSSHCertificateAuthorityCAKeys = trustedUserCAKeys
+	p.SetupSSHCertificateAuthorityPrincipals = principals
+	return p.SetupSSHCertificateAuthorityErr
+}
+
+func (p *FakePlatform) UpdateRevokedSSHKeys(revokedKeys []string) error {
+	p.UpdateRevokedSSHKeysCalled = true
+	p.UpdateRevokedSSHKeysKeys = revokedKeys
+	return p.UpdateRevokedSSHKeysErr
+}
+
 func (p *FakePlatform) SetUserPassword(user, encryptedPwd string) (err error) {
 	p.UserPasswords[user] = encryptedPwd
 	return
@@ -284,6 +356,11 @@ func (p *FakePlatform) SetupNetworking(networks boshsettings.Networks) error {
 	return p.SetupNetworkingErr
 }
 
+func (p *FakePlatform) SetupDNS(networks boshsettings.Networks) error {
+	p.SetupDNSNetworks = networks
+	return p.SetupDNSErr
+}
+
 func (p *FakePlatform) GetConfiguredNetworkInterfaces() ([]string, error) {
 	return p.GetConfiguredNetworkInterfacesInterfaces, p.GetConfiguredNetworkInterfacesErr
 }
@@ -296,6 +373,26 @@ func (p *FakePlatform) SetupLogrotate(groupName, basePath, size string) (err err
 	return
 }
 
+func (p *FakePlatform) SetupJobSecretsStaging(secretSizes []uint64) (stagingDir string, err error) {
+	p.SetupJobSecretsStagingCalled = true
+	p.SetupJobSecretsStagingSecretSizes = secretSizes
+	return p.GetDirProvider().SecretsStagingDir(), p.SetupJobSecretsStagingErr
+}
+
+func (p *FakePlatform) SetupJobSecrets(jobName string, secrets map[string][]byte) (secretsDir string, err error) {
+	if p.SetupJobSecretsErr != nil {
+		return "", p.SetupJobSecretsErr
+	}
+
+	p.SetupJobSecretsSecrets[jobName] = secrets
+	return p.GetDirProvider().JobSecretsDir(jobName), nil
+}
+
+func (p *FakePlatform) RemoveJobSecrets() (err error) {
+	p.RemoveJobSecretsCalled = true
+	return p.RemoveJobSecretsErr
+}
+
 func (p *FakePlatform) SetTimeWithNtpServers(servers []string) (err error) {
 	p.SetTimeWithNtpServersServers = servers
 	return
@@ -350,6 +447,12 @@ func (p *FakePlatform) MountPersistentDisk(diskSettings boshsettings.DiskSetting
 	return p.MountPersistentDiskErr
 }
 
+func (p *FakePlatform) MountAssociatedPersistentDisk(diskSettings boshsettings.DiskSettings, mountPoint string) error {
+	p.MountAssociatedPersistentDiskSettings = append(p.MountAssociatedPersistentDiskSettings, diskSettings)
+	p.MountAssociatedPersistentDiskMountPoints = append(p.MountAssociatedPersistentDiskMountPoints, mountPoint)
+	return p.MountAssociatedPersistentDiskErr
+}
+
 func (p *FakePlatform) UnmountPersistentDisk(diskSettings boshsettings.DiskSettings) (didUnmount bool, err error) {
 	p.UnmountPersistentDiskSettings = diskSettings
 	didUnmount = p.UnmountPersistentDiskDidUnmount
@@ -393,10 +496,46 @@ func (p *FakePlatform) SetGetFilesContentsFromDisk(fileName string, contents []b
 	p.GetFileContentsFromDiskErrs[fileName] = err
 }
 
-func (p *FakePlatform) MigratePersistentDisk(fromMountPoint, toMountPoint string) (err error) {
+func (p *FakePlatform) MigratePersistentDisk(fromMountPoint, toMountPoint string) (platform.DiskMigrationTotals, error) {
 	p.MigratePersistentDiskFromMountPoint = fromMountPoint
 	p.MigratePersistentDiskToMountPoint = toMountPoint
-	return
+	return p.MigratePersistentDiskTotals, p.MigratePersistentDiskErr
+}
+
+func (p *FakePlatform) FreezeFilesystem(mountPoint string) error {
+	p.freezeMutex.Lock()
+	defer p.freezeMutex.Unlock()
+
+	p.FreezeFilesystemMountPoint = mountPoint
+	p.FreezeFilesystemMountPoints = append(p.FreezeFilesystemMountPoints, mountPoint)
+
+	if p.FreezeFilesystemStub != nil {
+		return p.FreezeFilesystemStub(mountPoint)
+	}
+
+	return p.FreezeFilesystemErr
+}
+
+func (p *FakePlatform) ThawFilesystem(mountPoint string) error {
+	p.freezeMutex.Lock()
+	defer p.freezeMutex.Unlock()
+
+	p.ThawFilesystemMountPoints = append(p.ThawFilesystemMountPoints, mountPoint)
+	return p.ThawFilesystemErr
+}
+
+func (p *FakePlatform) GetThawFilesystemMountPoints() []string {
+	p.freezeMutex.RLock()
+	defer p.freezeMutex.RUnlock()
+
+	return append([]string{}, p.ThawFilesystemMountPoints...)
+}
+
+func (p *FakePlatform) SetThawFilesystemErr(err error) {
+	p.freezeMutex.Lock()
+	defer p.freezeMutex.Unlock()
+
+	p.ThawFilesystemErr = err
 }
 
 func (p *FakePlatform) IsMountPoint(path string) (string, bool, error) {
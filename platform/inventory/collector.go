@@ -0,0 +1,197 @@
+package inventory
+
+import (
+	"path"
+	"strconv"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	// Sizes in /sys/block are always given in 512 byte sectors
+	sectorSizeInBytes = 512
+)
+
+// Block devices which are never backed by hardware
+var ignoredBlockDevicePrefixes = []string{"loop", "ram"}
+
+type Collector interface {
+	Get() (Inventory, error)
+}
+
+// procCollector reads inventory from /proc and /sys; details which cannot
+// be read, e.g. speed of virtual interfaces, are left empty
+type procCollector struct {
+	fs     boshsys.FileSystem
+	logTag string
+	logger boshlog.Logger
+}
+
+func NewCollector(fs boshsys.FileSystem, logger boshlog.Logger) Collector {
+	return procCollector{
+		fs:     fs,
+		logTag: "inventoryCollector",
+		logger: logger,
+	}
+}
+
+func (c procCollector) Get() (Inventory, error) {
+	var inventory Inventory
+	var err error
+
+	inventory.CPU = c.getCPU()
+	inventory.Memory = c.getMemory()
+	inventory.Kernel = c.readTrimmed("/proc/sys/kernel/osrelease")
+
+	inventory.BlockDevices, err = c.getBlockDevices()
+	if err != nil {
+		return inventory, bosherr.WrapError(err, "Getting block devices")
+	}
+
+	inventory.NetworkInterfaces, err = c.getNetworkInterfaces()
+	if err != nil {
+		return inventory, bosherr.WrapError(err, "Getting network interfaces")
+	}
+
+	return inventory, nil
+}
+
+func (c procCollector) getCPU() CPU {
+	var cpu CPU
+
+	for _, line := range strings.Split(c.readTrimmed("/proc/cpuinfo"), "\n") {
+		key, value := splitProcLine(line)
+
+		switch key {
+		case "processor":
+			cpu.Count++
+		case "model name":
+			if cpu.Model == "" {
+				cpu.Model = value
+			}
+		}
+	}
+
+	return cpu
+}
+
+func (c procCollector) getMemory() Memory {
+	var memory Memory
+
+	for _, line := range strings.Split(c.readTrimmed("/proc/meminfo"), "\n") {
+		key, value := splitProcLine(line)
+		if key != "MemTotal" {
+			continue
+		}
+
+		// e.g. "MemTotal:        2048000 kB"
+		memory.TotalInKb, _ = strconv.ParseUint(strings.TrimSuffix(value, " kB"), 10, 64)
+	}
+
+	return memory
+}
+
+func (c procCollector) getBlockDevices() ([]BlockDevice, error) {
+	devicePaths, err := c.fs.Glob("/sys/block/*")
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Globbing /sys/block")
+	}
+
+	blockDevices := []BlockDevice{}
+
+	for _, devicePath := range devicePaths {
+		name := path.Base(devicePath)
+		if isIgnoredBlockDevice(name) {
+			continue
+		}
+
+		blockDevice := BlockDevice{
+			Name: name,
+			Path: path.Join("/dev", name),
+		}
+
+		sectors, err := strconv.ParseUint(c.readTrimmed(path.Join(devicePath, "size")), 10, 64)
+		if err == nil {
+			blockDevice.SizeInBytes = sectors * sectorSizeInBytes
+		}
+
+		// NVMe and SCSI devices expose serial on device, virtio ones on block device itself
+		for _, serialPath := range []string{path.Join(devicePath, "device", "serial"), path.Join(devicePath, "serial")} {
+			if serial := c.readTrimmed(serialPath); serial != "" {
+				blockDevice.Serial = serial
+				break
+			}
+		}
+
+		blockDevices = append(blockDevices, blockDevice)
+	}
+
+	return blockDevices, nil
+}
+
+func (c procCollector) getNetworkInterfaces() ([]NetworkInterface, error) {
+	interfacePaths, err := c.fs.Glob("/sys/class/net/*")
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Globbing /sys/class/net")
+	}
+
+	networkInterfaces := []NetworkInterface{}
+
+	for _, interfacePath := range interfacePaths {
+		name := path.Base(interfacePath)
+		if name == "lo" {
+			continue
+		}
+
+		networkInterface := NetworkInterface{
+			Name: name,
+			MAC:  c.readTrimmed(path.Join(interfacePath, "address")),
+		}
+
+		driverPath, err := c.fs.Readlink(path.Join(interfacePath, "device", "driver"))
+		if err == nil {
+			networkInterface.Driver = path.Base(driverPath)
+		}
+
+		// Virtual interfaces report -1 or fail reading speed
+		speed, err := strconv.Atoi(c.readTrimmed(path.Join(interfacePath, "speed")))
+		if err == nil && speed > 0 {
+			networkInterface.SpeedInMbps = speed
+		}
+
+		networkInterfaces = append(networkInterfaces, networkInterface)
+	}
+
+	return networkInterfaces, nil
+}
+
+func (c procCollector) readTrimmed(filePath string) string {
+	contents, err := c.fs.ReadFileString(filePath)
+	if err != nil {
+		c.logger.Debug(c.logTag, "Failed reading '%s': %s", filePath, err.Error())
+		return ""
+	}
+
+	return strings.TrimSpace(contents)
+}
+
+func splitProcLine(line string) (string, string) {
+	parts := strings.SplitN(line, ":", 2)
+	if len(parts) != 2 {
+		return "", ""
+	}
+
+	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+}
+
+func isIgnoredBlockDevice(name string) bool {
+	for _, prefix := range ignoredBlockDevicePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package inventory_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/inventory"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("Collector", func() {
+	var (
+		fs        *fakesys.FakeFileSystem
+		collector Collector
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		collector = NewCollector(fs, logger)
+	})
+
+	Describe("Get", func() {
+		It("returns CPU model and count", func() {
+			fs.WriteFileString("/proc/cpuinfo", `processor	: 0
+model name	: Intel(R) Xeon(R) CPU @ 2.20GHz
+
+processor	: 1
+model name	: Intel(R) Xeon(R) CPU @ 2.20GHz
+`)
+
+			inventory, err := collector.Get()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(inventory.CPU).To(Equal(CPU{Model: "Intel(R) Xeon(R) CPU @ 2.20GHz", Count: 2}))
+		})
+
+		It("returns total memory and kernel release", func() {
+			fs.WriteFileString("/proc/meminfo", "MemTotal:        2048000 kB\nMemFree:          512000 kB\n")
+			fs.WriteFileString("/proc/sys/kernel/osrelease", "4.4.0-97-generic\n")
+
+			inventory, err := collector.Get()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(inventory.Memory).To(Equal(Memory{TotalInKb: 2048000}))
+			Expect(inventory.Kernel).To(Equal("4.4.0-97-generic"))
+		})
+
+		It("returns block devices with size and serial skipping loop devices", func() {
+			fs.SetGlob("/sys/block/*", []string{"/sys/block/loop0", "/sys/block/nvme0n1", "/sys/block/vdb"})
+			fs.WriteFileString("/sys/block/loop0/size", "100")
+			fs.WriteFileString("/sys/block/nvme0n1/size", "20971520\n")
+			fs.WriteFileString("/sys/block/nvme0n1/device/serial", "vol0123456789abcdef0  \n")
+			fs.WriteFileString("/sys/block/vdb/size", "2097152")
+			fs.WriteFileString("/sys/block/vdb/serial", "fake-virtio-serial")
+
+			inventory, err := collector.Get()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(inventory.BlockDevices).To(Equal([]BlockDevice{
+				{Name: "nvme0n1", Path: "/dev/nvme0n1", SizeInBytes: 10737418240, Serial: "vol0123456789abcdef0"},
+				{Name: "vdb", Path: "/dev/vdb", SizeInBytes: 1073741824, Serial: "fake-virtio-serial"},
+			}))
+		})
+
+		It("returns network interfaces with MAC, driver and speed skipping loopback", func() {
+			fs.SetGlob("/sys/class/net/*", []string{"/sys/class/net/eth0", "/sys/class/net/lo", "/sys/class/net/veth0"})
+			fs.WriteFileString("/sys/class/net/eth0/address", "0a:1b:2c:3d:4e:5f\n")
+			fs.WriteFileString("/sys/class/net/eth0/speed", "10000\n")
+			fs.Symlink("../../../../bus/pci/drivers/ena", "/sys/class/net/eth0/device/driver")
+			fs.WriteFileString("/sys/class/net/veth0/address", "aa:bb:cc:dd:ee:ff")
+			fs.WriteFileString("/sys/class/net/veth0/speed", "-1")
+
+			inventory, err := collector.Get()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(inventory.NetworkInterfaces).To(Equal([]NetworkInterface{
+				{Name: "eth0", MAC: "0a:1b:2c:3d:4e:5f", Driver: "ena", SpeedInMbps: 10000},
+				{Name: "veth0", MAC: "aa:bb:cc:dd:ee:ff"},
+			}))
+		})
+
+		It("leaves details which cannot be read empty", func() {
+			inventory, err := collector.Get()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(inventory).To(Equal(Inventory{
+				BlockDevices:      []BlockDevice{},
+				NetworkInterfaces: []NetworkInterface{},
+			}))
+		})
+
+		It("returns an error if block devices cannot be listed", func() {
+			fs.GlobErrs = map[string]error{"/sys/block/*": errors.New("fake-glob-err")}
+
+			_, err := collector.Get()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-glob-err"))
+		})
+	})
+})
@@ -0,0 +1,12 @@
+package fakes
+
+import boshinventory "github.com/cloudfoundry/bosh-agent/platform/inventory"
+
+type FakeCollector struct {
+	GetInventory boshinventory.Inventory
+	GetErr       error
+}
+
+func (c *FakeCollector) Get() (boshinventory.Inventory, error) {
+	return c.GetInventory, c.GetErr
+}
@@ -0,0 +1,43 @@
+package inventory
+
+// EphemeralDiskID identifies ephemeral disk among block devices
+// since it has no ID of its own in settings
+const EphemeralDiskID = "ephemeral"
+
+type Inventory struct {
+	CPU               CPU                `json:"cpu"`
+	Memory            Memory             `json:"memory"`
+	BlockDevices      []BlockDevice      `json:"block_devices"`
+	NetworkInterfaces []NetworkInterface `json:"network_interfaces"`
+	Kernel            string             `json:"kernel,omitempty"`
+}
+
+type CPU struct {
+	Model string `json:"model,omitempty"`
+	Count int    `json:"count"`
+}
+
+type Memory struct {
+	TotalInKb uint64 `json:"total_kb"`
+}
+
+type BlockDevice struct {
+	Name        string `json:"name"`
+	Path        string `json:"path"`
+	SizeInBytes uint64 `json:"size_bytes"`
+	Serial      string `json:"serial,omitempty"`
+
+	// ID of persistent disk device was resolved to,
+	// EphemeralDiskID for ephemeral disk or empty
+	DiskID string `json:"disk_id,omitempty"`
+}
+
+type NetworkInterface struct {
+	Name        string `json:"name"`
+	MAC         string `json:"mac,omitempty"`
+	Driver      string `json:"driver,omitempty"`
+	SpeedInMbps int    `json:"speed_mbps,omitempty"`
+
+	// Name of network interface is configured for, if any
+	Network string `json:"network,omitempty"`
+}
@@ -2,11 +2,13 @@ package platform
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"text/template"
@@ -42,11 +44,29 @@ const (
 	blobsDirPermissions       = os.FileMode(0700)
 	systemTmpDirPermissions   = os.FileMode(0770)
 
+	sudoersDropInPermissions = os.FileMode(0440)
+
+	jobSecretsDirPermissions        = os.FileMode(0750)
+	jobSecretsStagingDirPermissions = os.FileMode(0700)
+	jobSecretPermissions            = os.FileMode(0400)
+
+	// tmpfs allocates whole pages for each file; headroom covers directory entries
+	jobSecretsTmpfsPageSize = 4096
+	jobSecretsTmpfsHeadroom = 1024 * 1024
+
 	sshDirPermissions          = os.FileMode(0700)
 	sshAuthKeysFilePermissions = os.FileMode(0600)
 
 	minRootEphemeralSpaceInBytes = uint64(1024 * 1024 * 1024)
 	maxFdiskPartitionSize        = uint64(2 * 1024 * 1024 * 1024 * 1024)
+
+	rawEphemeralArrayPath = "/dev/md/bosh-ephemeral"
+
+	sudoersDropInDir = "/etc/sudoers.d"
+
+	// Records mount options of associated disks by mount point
+	// so that they can be mounted again after disk migration
+	associatedDiskMountOptionsFileName = "associated_disk_mount_options.json"
 )
 
 type LinuxOptions struct {
@@ -74,12 +94,62 @@ type LinuxOptions struct {
 	SkipDiskSetup bool
 
 	// Strategy for resolving device paths;
-	// possible values: virtio, scsi, ""
+	// possible values: virtio, scsi, nvme, ""
 	DevicePathResolutionType string
 
 	// Strategy for resolving ephemeral & persistent disk partitioners;
-	// possible values: parted, "" (default is sfdisk if disk < 2TB, parted otherwise)
+	// possible values: parted, native, "" (default is sfdisk if disk < 2TB, parted otherwise);
+	// native reads and writes partition tables without shelling out to sfdisk or parted
 	PartitionerType string
+
+	// When set to true the agent will compare checksums of all files
+	// after migrating persistent disk in addition to file counts and sizes
+	VerifyPersistentDiskMigrationChecksums bool
+
+	// When set to true all raw ephemeral devices are striped into a single
+	// md RAID-0 array which is then used as the ephemeral disk
+	StripeRawEphemeralDisks bool
+}
+
+// linuxCommands lists external commands linux platform shells out to;
+// RequiredCommands reports them for self-test
+var linuxCommands = []string{
+	"chmod", "chown", "mkdir", "rm", "touch", "readlink",
+	"useradd", "usermod", "userdel", "pkill", "visudo",
+	"hostname", "sshd", "service", "ntpdate", "arp", "route",
+	"udevadm", "eject", "fsfreeze", "rsync",
+	"sv", "monit", "bosh-agent-rc",
+	"/var/vcap/bosh/bin/bosh-start-logging-and-auditing",
+}
+
+// linuxPlatformCommands adds commands which depend on options: sfdisk unless
+// native partitioner reads partition tables and mdadm for striping raw ephemeral disks
+func linuxPlatformCommands(options LinuxOptions) []string {
+	commands := append([]string{}, linuxCommands...)
+
+	if options.PartitionerType != "native" {
+		commands = append(commands, "sfdisk")
+	}
+
+	if options.SkipDiskSetup {
+		return commands
+	}
+
+	if options.StripeRawEphemeralDisks {
+		commands = append(commands, "mdadm")
+	}
+
+	return commands
+}
+
+// linuxPlatformOptionalCommands returns growpart and resize2fs for growing
+// root disk which is skipped on stemcells without growpart, e.g. bosh-lite
+func linuxPlatformOptionalCommands(options LinuxOptions) []string {
+	if options.SkipDiskSetup {
+		return nil
+	}
+
+	return []string{"growpart", "resize2fs"}
 }
 
 type linux struct {
@@ -216,6 +286,10 @@ func (p linux) SetupNetworking(networks boshsettings.Networks) (err error) {
 	return p.netManager.SetupNetworking(networks, nil)
 }
 
+func (p linux) SetupDNS(networks boshsettings.Networks) (err error) {
+	return p.netManager.SetupDNS(networks)
+}
+
 func (p linux) GetConfiguredNetworkInterfaces() ([]string, error) {
 	return p.netManager.GetConfiguredNetworkInterfaces()
 }
@@ -233,6 +307,136 @@ func (p linux) GetHostPublicKey() (string, error) {
 	return hostPublicKey, nil
 }
 
+func (p linux) SetupSSHCertificateAuthority(trustedUserCAKeys []string, principals map[string][]string) error {
+	err := validateSSHCertificateAuthority(trustedUserCAKeys, principals)
+	if err != nil {
+		return bosherr.WrapError(err, "Validating ssh certificate authority settings")
+	}
+
+	enabled := len(trustedUserCAKeys) > 0
+
+	if !p.fs.FileExists(sshdConfigPath) {
+		if !enabled {
+			return nil
+		}
+		return bosherr.Errorf("Cannot trust ssh certificate authority without %s", sshdConfigPath)
+	}
+
+	config, err := p.fs.ReadFileString(sshdConfigPath)
+	if err != nil {
+		return bosherr.WrapError(err, "Reading sshd config")
+	}
+
+	if enabled {
+		err = p.writeSSHCertificateAuthorityFiles(trustedUserCAKeys, principals)
+		if err != nil {
+			return err
+		}
+	} else {
+		err = p.fs.RemoveAll(sshTrustedUserCAKeysPath)
+		if err != nil {
+			return bosherr.WrapError(err, "Removing trusted user CA keys")
+		}
+	}
+
+	newConfig := renderSSHDConfig(config, enabled, principals)
+	if newConfig == config {
+		return nil
+	}
+
+	p.logger.Info(logTag, "Updating sshd config to trust ssh certificate authority: %t", enabled)
+
+	err = p.fs.WriteFileString(sshdConfigPath, newConfig)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing sshd config")
+	}
+
+	_, _, _, err = p.cmdRunner.RunCommand("sshd", "-t")
+	if err != nil {
+		restoreErr := p.fs.WriteFileString(sshdConfigPath, config)
+		if restoreErr != nil {
+			p.logger.Error(logTag, "Restoring sshd config: %s", restoreErr.Error())
+		}
+		return bosherr.WrapError(err, "Validating sshd config")
+	}
+
+	// Ubuntu and CentOS name sshd service differently
+	_, _, _, err = p.cmdRunner.RunCommand("service", "ssh", "reload")
+	if err != nil {
+		_, _, _, err = p.cmdRunner.RunCommand("service", "sshd", "reload")
+		if err != nil {
+			return bosherr.WrapError(err, "Reloading sshd")
+		}
+	}
+
+	return nil
+}
+
+func (p linux) writeSSHCertificateAuthorityFiles(trustedUserCAKeys []string, principals map[string][]string) error {
+	err := p.fs.WriteFileString(sshTrustedUserCAKeysPath, strings.Join(trustedUserCAKeys, "\n")+"\n")
+	if err != nil {
+		return bosherr.WrapError(err, "Writing trusted user CA keys")
+	}
+
+	// sshd rejects all keys when revoked keys file is missing
+	if !p.fs.FileExists(sshRevokedKeysPath) {
+		err = p.fs.WriteFileString(sshRevokedKeysPath, "")
+		if err != nil {
+			return bosherr.WrapError(err, "Writing revoked ssh keys")
+		}
+	}
+
+	for _, group := range sshPrincipalGroups {
+		principalsPath := sshAuthorizedPrincipalsPath(group)
+
+		if len(principals[group]) == 0 {
+			err = p.fs.RemoveAll(principalsPath)
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Removing ssh principals of group '%s'", group)
+			}
+			continue
+		}
+
+		err = p.fs.MkdirAll(sshAuthorizedPrincipalDir, userBaseDirPermissions)
+		if err != nil {
+			return bosherr.WrapError(err, "Creating ssh principals dir")
+		}
+
+		err = p.fs.WriteFileString(principalsPath, strings.Join(principals[group], "\n")+"\n")
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Writing ssh principals of group '%s'", group)
+		}
+	}
+
+	return nil
+}
+
+func (p linux) UpdateRevokedSSHKeys(revokedKeys []string) error {
+	for _, revokedKey := range revokedKeys {
+		if err := validateSSHConfigLine(revokedKey); err != nil {
+			return bosherr.WrapError(err, "Validating revoked ssh key")
+		}
+	}
+
+	// sshd only reads revoked keys when certificate authority is trusted
+	if !p.fs.FileExists(sshTrustedUserCAKeysPath) {
+		p.logger.Debug(logTag, "Not writing revoked ssh keys since no ssh certificate authority is trusted")
+		return nil
+	}
+
+	var contents string
+	if len(revokedKeys) > 0 {
+		contents = strings.Join(revokedKeys, "\n") + "\n"
+	}
+
+	err := p.fs.WriteFileString(sshRevokedKeysPath, contents)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing revoked ssh keys")
+	}
+
+	return nil
+}
+
 func (p linux) SetupRuntimeConfiguration() (err error) {
 	_, _, _, err = p.cmdRunner.RunCommand("bosh-agent-rc")
 	if err != nil {
@@ -280,6 +484,64 @@ func (p linux) AddUserToGroups(username string, groups []string) error {
 	return nil
 }
 
+func (p linux) SetupUserSudoCommands(username string, commands []string) error {
+	// sudo silently skips drop-ins whose names contain '.' or end with '~'
+	if username == "" || strings.ContainsAny(username, "/.~") {
+		return bosherr.Errorf("Cannot create sudoers drop-in for user '%s'", username)
+	}
+
+	for _, command := range commands {
+		if err := validateSSHConfigLine(command); err != nil {
+			return bosherr.WrapError(err, "Validating sudo command")
+		}
+	}
+
+	dropInPath := path.Join(sudoersDropInDir, username)
+
+	if len(commands) == 0 {
+		err := p.fs.RemoveAll(dropInPath)
+		if err != nil {
+			return bosherr.WrapError(err, "Removing sudoers drop-in")
+		}
+		return nil
+	}
+
+	err := p.setupLogViewer()
+	if err != nil {
+		return bosherr.WrapError(err, "Setting up log viewer")
+	}
+
+	// Drop-in is validated before being moved into place
+	// since sudo refuses to run when any drop-in is invalid
+	tmpPath := dropInPath + ".tmp"
+
+	contents := fmt.Sprintf("%s ALL=(root) NOPASSWD: %s\n", username, strings.Join(commands, ", "))
+
+	err = p.fs.WriteFileString(tmpPath, contents)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing sudoers drop-in")
+	}
+
+	defer func() { _ = p.fs.RemoveAll(tmpPath) }()
+
+	err = p.fs.Chmod(tmpPath, sudoersDropInPermissions)
+	if err != nil {
+		return bosherr.WrapError(err, "Chmoding sudoers drop-in")
+	}
+
+	_, _, _, err = p.cmdRunner.RunCommand("visudo", "-c", "-f", tmpPath)
+	if err != nil {
+		return bosherr.WrapError(err, "Validating sudoers drop-in")
+	}
+
+	err = p.fs.Rename(tmpPath, dropInPath)
+	if err != nil {
+		return bosherr.WrapError(err, "Moving sudoers drop-in")
+	}
+
+	return nil
+}
+
 func (p linux) DeleteEphemeralUsersMatching(reg string) error {
 	compiledReg, err := regexp.Compile(reg)
 	if err != nil {
@@ -300,9 +562,31 @@ func (p linux) DeleteEphemeralUsersMatching(reg string) error {
 	return nil
 }
 
-func (p linux) deleteUser(user string) (err error) {
+func (p linux) deleteUser(user string) error {
+	// Lock and expire account first so that no new sessions
+	// can be established while existing ones are being killed
+	_, _, _, err := p.cmdRunner.RunCommand("usermod", "-L", "-e", "1", user)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Locking user %s", user)
+	}
+
+	// pkill exits with 1 when user has no running processes
+	_, _, exitStatus, err := p.cmdRunner.RunCommand("pkill", "-KILL", "-u", user)
+	if err != nil && exitStatus != 1 {
+		return bosherr.WrapErrorf(err, "Killing sessions of user %s", user)
+	}
+
 	_, _, _, err = p.cmdRunner.RunCommand("userdel", "-r", user)
-	return
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Removing user %s", user)
+	}
+
+	err = p.fs.RemoveAll(path.Join(sudoersDropInDir, user))
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Removing sudoers drop-in of user %s", user)
+	}
+
+	return nil
 }
 
 func (p linux) findEphemeralUsersMatching(reg *regexp.Regexp) (matchingUsers []string, err error) {
@@ -362,7 +646,7 @@ func (p linux) SetupRootDisk(ephemeralDiskPath string) error {
 	_, _, _, err = p.cmdRunner.RunCommand(
 		"resize2fs",
 		"-f",
-		fmt.Sprintf("%s%d", rootDevicePath, rootDeviceNumber),
+		PartitionPath(rootDevicePath, rootDeviceNumber),
 	)
 
 	if err != nil {
@@ -527,6 +811,157 @@ const etcLogrotateDTemplate = `# Generated by bosh-agent
 }
 `
 
+// SetupJobSecretsStaging mounts memory backed dir into which secrets
+// are downloaded before being placed into their job secrets dirs;
+// dir is mounted again every time so that it fits given secrets
+func (p linux) SetupJobSecretsStaging(secretSizes []uint64) (string, error) {
+	stagingDir := p.dirProvider.SecretsStagingDir()
+
+	mounter := p.diskManager.GetMounter()
+
+	_, isMountPoint, err := mounter.IsMountPoint(stagingDir)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Checking if %s is mounted", stagingDir)
+	}
+
+	if isMountPoint {
+		_, err = mounter.Unmount(stagingDir)
+		if err != nil {
+			return "", bosherr.WrapErrorf(err, "Unmounting %s", stagingDir)
+		}
+	}
+
+	err = p.fs.MkdirAll(stagingDir, jobSecretsStagingDirPermissions)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Making %s dir", stagingDir)
+	}
+
+	tmpfsOptions := fmt.Sprintf("size=%dk,mode=0700,nodev,nosuid,noexec", jobSecretsTmpfsSize(secretSizes)/1024)
+
+	err = mounter.Mount("tmpfs", stagingDir, "-t", "tmpfs", "-o", tmpfsOptions)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Mounting tmpfs to %s", stagingDir)
+	}
+
+	return stagingDir, nil
+}
+
+// SetupJobSecrets replaces job secrets with ones given in a memory backed
+// mount so that they never reach disks and are gone after reboot
+func (p linux) SetupJobSecrets(jobName string, secrets map[string][]byte) (string, error) {
+	secretsDir := p.dirProvider.JobSecretsDir(jobName)
+
+	if jobName == "" || strings.ContainsAny(jobName, "/") || jobName == "." || jobName == ".." {
+		return "", bosherr.Errorf("Invalid job name '%s' for secrets", jobName)
+	}
+
+	for name := range secrets {
+		if name == "" || strings.ContainsAny(name, "/") || name == "." || name == ".." {
+			return "", bosherr.Errorf("Invalid secret name '%s' for job '%s'", name, jobName)
+		}
+	}
+
+	err := p.removeJobSecretsDir(secretsDir)
+	if err != nil {
+		return "", err
+	}
+
+	err = p.fs.MkdirAll(secretsDir, jobSecretsDirPermissions)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Making %s dir", secretsDir)
+	}
+
+	var secretSizes []uint64
+	for _, contents := range secrets {
+		secretSizes = append(secretSizes, uint64(len(contents)))
+	}
+
+	// tmpfs is sized for all secrets so that writing them cannot run out of space
+	tmpfsOptions := fmt.Sprintf("size=%dk,mode=0750,nodev,nosuid,noexec", jobSecretsTmpfsSize(secretSizes)/1024)
+
+	err = p.diskManager.GetMounter().Mount("tmpfs", secretsDir, "-t", "tmpfs", "-o", tmpfsOptions)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Mounting tmpfs to %s", secretsDir)
+	}
+
+	_, _, _, err = p.cmdRunner.RunCommand("chown", fmt.Sprintf("root:%s", boshsettings.VCAPUsername), secretsDir)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "chown %s", secretsDir)
+	}
+
+	for name, contents := range secrets {
+		secretPath := path.Join(secretsDir, name)
+
+		err = p.fs.WriteFile(secretPath, contents)
+		if err != nil {
+			return "", bosherr.WrapErrorf(err, "Writing secret %s", name)
+		}
+
+		err = p.fs.Chmod(secretPath, jobSecretPermissions)
+		if err != nil {
+			return "", bosherr.WrapErrorf(err, "Chmoding secret %s", name)
+		}
+
+		_, _, _, err = p.cmdRunner.RunCommand("chown", fmt.Sprintf("%s:%s", boshsettings.VCAPUsername, boshsettings.VCAPUsername), secretPath)
+		if err != nil {
+			return "", bosherr.WrapErrorf(err, "chown %s", secretPath)
+		}
+	}
+
+	return secretsDir, nil
+}
+
+func jobSecretsTmpfsSize(secretSizes []uint64) uint64 {
+	size := uint64(jobSecretsTmpfsHeadroom)
+
+	for _, secretSize := range secretSizes {
+		pages := (secretSize + jobSecretsTmpfsPageSize - 1) / jobSecretsTmpfsPageSize
+		size += pages * jobSecretsTmpfsPageSize
+	}
+
+	return size
+}
+
+func (p linux) RemoveJobSecrets() error {
+	secretsDirs, err := p.fs.Glob(path.Join(p.dirProvider.SecretsDir(), "*"))
+	if err != nil {
+		return bosherr.WrapError(err, "Listing job secrets dirs")
+	}
+
+	for _, secretsDir := range secretsDirs {
+		err = p.removeJobSecretsDir(secretsDir)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p linux) removeJobSecretsDir(secretsDir string) error {
+	mounter := p.diskManager.GetMounter()
+
+	_, isMountPoint, err := mounter.IsMountPoint(secretsDir)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Checking if %s is mounted", secretsDir)
+	}
+
+	// Unmounting tmpfs discards secrets without writing them anywhere
+	if isMountPoint {
+		_, err = mounter.Unmount(secretsDir)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Unmounting %s", secretsDir)
+		}
+	}
+
+	err = p.fs.RemoveAll(secretsDir)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Removing %s", secretsDir)
+	}
+
+	return nil
+}
+
 func (p linux) SetTimeWithNtpServers(servers []string) (err error) {
 	serversFilePath := path.Join(p.dirProvider.BaseDir(), "/bosh/etc/ntpserver")
 	if len(servers) == 0 {
@@ -645,12 +1080,24 @@ func (p linux) SetupRawEphemeralDisks(devices []boshsettings.DiskSettings) (err
 
 	p.logger.Info(logTag, "Setting up raw ephemeral disks")
 
+	if p.options.StripeRawEphemeralDisks {
+		return p.setupStripedRawEphemeralDisks(devices)
+	}
+
 	for i, device := range devices {
 		realPath, _, err := p.devicePathResolver.GetRealDevicePath(device)
 		if err != nil {
 			return bosherr.WrapError(err, "Getting real device path")
 		}
 
+		if p.options.PartitionerType == "native" {
+			err = p.partitionRawEphemeralDiskNatively(realPath, i)
+			if err != nil {
+				return bosherr.WrapError(err, "Setting up raw ephemeral disks")
+			}
+			continue
+		}
+
 		// check if device is already partitioned correctly
 		stdout, stderr, _, err := p.cmdRunner.RunCommand(
 			"parted",
@@ -695,24 +1142,139 @@ func (p linux) SetupRawEphemeralDisks(devices []boshsettings.DiskSettings) (err
 	return nil
 }
 
-func (p linux) scrubEphemeralDisk(contents []string) error {
-	agentVersionFilePath := path.Join(p.dirProvider.DataDir(), ".bosh", "agent_version")
-	stemcellVersionFilePath := path.Join(p.dirProvider.EtcDir(), "stemcell_version")
-	stemcellVersion, err := p.fs.ReadFileString(stemcellVersionFilePath)
+// partitionRawEphemeralDiskNatively keeps the same GPT partition named
+// raw-ephemeral-<index> as parted would create
+func (p linux) partitionRawEphemeralDiskNatively(realPath string, index int) error {
+	reader, err := p.partitionTableReader()
 	if err != nil {
-		return bosherr.WrapError(err, "Reading stemcell version file")
+		return err
 	}
 
-	if !p.fs.FileExists(agentVersionFilePath) {
-		// need to remove contents when it is upgrading from a stemcell without scrubEphemeralDisk enabled
-		for _, content := range contents {
-			err = p.fs.RemoveAll(content)
-			if err != nil {
-				return bosherr.WrapErrorf(err, "Removing '%s'", content)
+	partitions, isGPT, err := reader.GetPartitions(realPath)
+	if err != nil {
+		return err
+	}
+
+	if isGPT {
+		for _, partition := range partitions {
+			if strings.HasPrefix(partition.Name, "raw-ephemeral-") {
+				return nil
 			}
 		}
+	}
 
-		err = p.fs.WriteFileString(agentVersionFilePath, stemcellVersion)
+	p.logger.Info(logTag, "Creating partition on `%s'", realPath)
+
+	return p.diskManager.GetPartitioner().Partition(realPath, []boshdisk.Partition{
+		{Type: boshdisk.PartitionTypeLinux, Name: fmt.Sprintf("raw-ephemeral-%d", index)},
+	})
+}
+
+func (p linux) partitionTableReader() (boshdisk.PartitionTableReader, error) {
+	reader, ok := p.diskManager.GetPartitioner().(boshdisk.PartitionTableReader)
+	if !ok {
+		return nil, bosherr.Error("Partitioner cannot read partition tables")
+	}
+
+	return reader, nil
+}
+
+// setupStripedRawEphemeralDisks reassembles previously created array
+// so that its data survives reboots; array is only created when none of
+// the devices carry its superblock
+func (p linux) setupStripedRawEphemeralDisks(devices []boshsettings.DiskSettings) error {
+	if len(devices) == 0 {
+		return nil
+	}
+
+	if p.fs.FileExists(rawEphemeralArrayPath) {
+		p.logger.Info(logTag, "Raw ephemeral disk array `%s' is already assembled", rawEphemeralArrayPath)
+		return nil
+	}
+
+	var realPaths []string
+
+	for _, device := range devices {
+		realPath, _, err := p.devicePathResolver.GetRealDevicePath(device)
+		if err != nil {
+			return bosherr.WrapError(err, "Getting real device path")
+		}
+
+		realPaths = append(realPaths, realPath)
+	}
+
+	assembleArgs := append([]string{"--assemble", rawEphemeralArrayPath}, realPaths...)
+
+	_, _, _, assembleErr := p.cmdRunner.RunCommand("mdadm", assembleArgs...)
+	if assembleErr == nil {
+		p.logger.Info(logTag, "Reassembled raw ephemeral disk array `%s' from %s", rawEphemeralArrayPath, realPaths)
+		return nil
+	}
+
+	for _, realPath := range realPaths {
+		hasSuperblock, err := p.hasMdSuperblock(realPath)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Examining raw ephemeral device `%s'", realPath)
+		}
+
+		if hasSuperblock {
+			return bosherr.WrapErrorf(assembleErr, "Assembling raw ephemeral disk array, `%s' has existing superblock", realPath)
+		}
+	}
+
+	p.logger.Info(logTag, "Creating raw ephemeral disk array `%s' from %s", rawEphemeralArrayPath, realPaths)
+
+	createArgs := []string{
+		"--create", rawEphemeralArrayPath,
+		"--run",
+		"--force",
+		"--level=0",
+		"--name=" + path.Base(rawEphemeralArrayPath),
+		fmt.Sprintf("--raid-devices=%d", len(realPaths)),
+	}
+
+	_, _, _, err := p.cmdRunner.RunCommand("mdadm", append(createArgs, realPaths...)...)
+	if err != nil {
+		return bosherr.WrapError(err, "Creating raw ephemeral disk array")
+	}
+
+	return nil
+}
+
+// hasMdSuperblock reports whether device was previously part of an md array;
+// mdadm exits with non-zero status both when superblock is missing and when
+// device cannot be examined, so only the former is treated as not present
+func (p linux) hasMdSuperblock(devicePath string) (bool, error) {
+	_, stderr, _, err := p.cmdRunner.RunCommand("mdadm", "--examine", devicePath)
+	if err == nil {
+		return true, nil
+	}
+
+	if strings.Contains(stderr, "No md superblock detected") {
+		return false, nil
+	}
+
+	return false, err
+}
+
+func (p linux) scrubEphemeralDisk(contents []string) error {
+	agentVersionFilePath := path.Join(p.dirProvider.DataDir(), ".bosh", "agent_version")
+	stemcellVersionFilePath := path.Join(p.dirProvider.EtcDir(), "stemcell_version")
+	stemcellVersion, err := p.fs.ReadFileString(stemcellVersionFilePath)
+	if err != nil {
+		return bosherr.WrapError(err, "Reading stemcell version file")
+	}
+
+	if !p.fs.FileExists(agentVersionFilePath) {
+		// need to remove contents when it is upgrading from a stemcell without scrubEphemeralDisk enabled
+		for _, content := range contents {
+			err = p.fs.RemoveAll(content)
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Removing '%s'", content)
+			}
+		}
+
+		err = p.fs.WriteFileString(agentVersionFilePath, stemcellVersion)
 		if err != nil {
 			return bosherr.WrapError(err, "Writting agent version file")
 		}
@@ -1015,18 +1577,150 @@ func (p linux) MountPersistentDisk(diskSetting boshsettings.DiskSettings, mountP
 	}
 	p.logger.Info(logTag, "realPath = %s, devicePath = %s, isMountPoint = %s", realPath, devicePath, isMountPoint)
 
-	partitionPath := realPath + "1"
-	if strings.Contains(realPath, "/dev/mapper/") {
-		partitionPath = realPath + "-part1"
+	if isMountPoint {
+		if p.persistentDiskMountPath(realPath) == devicePath {
+			p.logger.Info(logTag, "device: %s is already mounted on %s, skipping mounting", devicePath, mountPoint)
+			return nil
+		}
+
+		err = p.partitionFormatAndMount(diskSetting, realPath, p.dirProvider.StoreMigrationDir())
+		if err != nil {
+			return err
+		}
+
+		// Mount options are needed when new disk is moved to original mount point
+		err = p.recordMigrationTarget(mountPoint, p.dirProvider.StoreMigrationDir(), diskSetting.MountOptions)
+		if err != nil {
+			return err
+		}
+	} else {
+		err = p.partitionFormatAndMount(diskSetting, realPath, mountPoint)
+		if err != nil {
+			return err
+		}
+	}
+
+	managedSettingsPath := filepath.Join(p.dirProvider.BoshDir(), ManagedDiskSettingsFileName)
+
+	err = p.fs.WriteFileString(managedSettingsPath, diskSetting.ID)
+
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Writing %s", ManagedDiskSettingsFileName)
+	}
+
+	return nil
+}
+
+func (p linux) MountAssociatedPersistentDisk(diskSetting boshsettings.DiskSettings, mountPoint string) error {
+	p.logger.Debug(logTag, "Mounting associated persistent disk %+v at %s", diskSetting, mountPoint)
+
+	realPath, _, err := p.devicePathResolver.GetRealDevicePath(diskSetting)
+	if err != nil {
+		return bosherr.WrapError(err, "Getting real device path")
+	}
+
+	devicePath, isMountPoint, err := p.IsMountPoint(mountPoint)
+	if err != nil {
+		return bosherr.WrapError(err, "Checking mount point")
 	}
 
 	if isMountPoint {
-		if partitionPath == devicePath {
+		if p.persistentDiskMountPath(realPath) == devicePath {
 			p.logger.Info(logTag, "device: %s is already mounted on %s, skipping mounting", devicePath, mountPoint)
 			return nil
 		}
 
-		mountPoint = p.dirProvider.StoreMigrationDir()
+		return bosherr.Errorf("Device %s is already mounted on %s", devicePath, mountPoint)
+	}
+
+	err = p.partitionFormatAndMount(diskSetting, realPath, mountPoint)
+	if err != nil {
+		return err
+	}
+
+	return p.recordAssociatedDiskMountOptions(mountPoint, diskSetting.MountOptions)
+}
+
+func (p linux) recordAssociatedDiskMountOptions(mountPoint string, mountOptions []string) error {
+	allMountOptions, err := p.associatedDiskMountOptions()
+	if err != nil {
+		return err
+	}
+
+	allMountOptions[mountPoint] = mountOptions
+
+	contents, err := json.Marshal(allMountOptions)
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling associated disk mount options")
+	}
+
+	err = p.fs.WriteFile(filepath.Join(p.dirProvider.BoshDir(), associatedDiskMountOptionsFileName), contents)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Writing %s", associatedDiskMountOptionsFileName)
+	}
+
+	return nil
+}
+
+func (p linux) associatedDiskMountOptions() (map[string][]string, error) {
+	allMountOptions := map[string][]string{}
+
+	optionsPath := filepath.Join(p.dirProvider.BoshDir(), associatedDiskMountOptionsFileName)
+	if !p.fs.FileExists(optionsPath) {
+		return allMountOptions, nil
+	}
+
+	contents, err := p.fs.ReadFile(optionsPath)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Reading %s", associatedDiskMountOptionsFileName)
+	}
+
+	err = json.Unmarshal(contents, &allMountOptions)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Unmarshalling %s", associatedDiskMountOptionsFileName)
+	}
+
+	return allMountOptions, nil
+}
+
+func (p linux) persistentDiskPartitionPath(realPath string) string {
+	if strings.Contains(realPath, "/dev/mapper/") {
+		return realPath + "-part1"
+	}
+
+	return PartitionPath(realPath, 1)
+}
+
+// PartitionPath returns path of numbered partition on device; kernel separates
+// partition number with 'p' when device name itself ends in a digit
+// e.g. /dev/sdb -> /dev/sdb1 but /dev/nvme1n1 -> /dev/nvme1n1p1
+func PartitionPath(devicePath string, number int) string {
+	if len(devicePath) > 0 {
+		lastChar := devicePath[len(devicePath)-1]
+		if lastChar >= '0' && lastChar <= '9' {
+			return devicePath + "p" + strconv.Itoa(number)
+		}
+	}
+
+	return devicePath + strconv.Itoa(number)
+}
+
+// persistentDiskMountPath returns path persistent disk is mounted from
+// which is the device itself when disks come preformatted
+func (p linux) persistentDiskMountPath(realPath string) string {
+	if p.options.UsePreformattedPersistentDisk {
+		return realPath
+	}
+
+	return p.persistentDiskPartitionPath(realPath)
+}
+
+func (p linux) partitionFormatAndMount(diskSetting boshsettings.DiskSettings, realPath, mountPoint string) error {
+	partitionPath := p.persistentDiskPartitionPath(realPath)
+
+	mountOptions, err := boshdisk.MountOptionsArgs(diskSetting.MountOptions)
+	if err != nil {
+		return bosherr.WrapError(err, "Validating persistent disk mount options")
 	}
 
 	err = p.fs.MkdirAll(mountPoint, persistentDiskPermissions)
@@ -1057,14 +1751,14 @@ func (p linux) MountPersistentDisk(diskSetting boshsettings.DiskSettings, mountP
 
 		persistentDiskFS := diskSetting.FileSystemType
 		switch persistentDiskFS {
-		case boshdisk.FileSystemExt4, boshdisk.FileSystemXFS:
+		case boshdisk.FileSystemExt4, boshdisk.FileSystemXFS, boshdisk.FileSystemBTRFS:
 		case boshdisk.FileSystemDefault:
 			persistentDiskFS = boshdisk.FileSystemExt4
 		default:
 			return bosherr.Error(fmt.Sprintf(`The filesystem type "%s" is not supported`, diskSetting.FileSystemType))
 		}
 
-		err = p.diskManager.GetFormatter().Format(partitionPath, persistentDiskFS)
+		err = p.diskManager.GetFormatter().Format(partitionPath, persistentDiskFS, diskSetting.MkfsOptions...)
 		if err != nil {
 			return bosherr.WrapError(err, fmt.Sprintf("Formatting partition with %s", diskSetting.FileSystemType))
 		}
@@ -1072,20 +1766,12 @@ func (p linux) MountPersistentDisk(diskSetting boshsettings.DiskSettings, mountP
 		realPath = partitionPath
 	}
 
-	err = p.diskManager.GetMounter().Mount(realPath, mountPoint)
+	err = p.diskManager.GetMounter().Mount(realPath, mountPoint, mountOptions...)
 
 	if err != nil {
 		return bosherr.WrapError(err, "Mounting partition")
 	}
 
-	managedSettingsPath := filepath.Join(p.dirProvider.BoshDir(), "managed_disk_settings.json")
-
-	err = p.fs.WriteFileString(managedSettingsPath, diskSetting.ID)
-
-	if err != nil {
-		return bosherr.WrapError(err, "Writing managed_disk_settings.json")
-	}
-
 	return nil
 }
 
@@ -1100,18 +1786,68 @@ func (p linux) UnmountPersistentDisk(diskSettings boshsettings.DiskSettings) (bo
 		return false, bosherr.WrapError(err, "Getting real device path")
 	}
 
-	if !p.options.UsePreformattedPersistentDisk {
-		if strings.Contains(realPath, "/dev/mapper/") {
-			realPath = realPath + "-part1"
-		} else {
-			realPath += "1"
+	partitionPath := p.persistentDiskMountPath(realPath)
+
+	err = p.unmountNestedMounts(partitionPath)
+	if err != nil {
+		return false, err
+	}
+
+	return p.diskManager.GetMounter().Unmount(partitionPath)
+}
+
+// unmountNestedMounts unmounts disks mounted below mount points of partition,
+// e.g. associated disks mounted within store directory of primary disk
+func (p linux) unmountNestedMounts(partitionPath string) error {
+	mounts, err := p.diskManager.GetMountsSearcher().SearchMounts()
+	if err != nil {
+		return bosherr.WrapError(err, "Searching mounts")
+	}
+
+	var nestedMountPoints []string
+
+	for _, mount := range mounts {
+		if mount.PartitionPath != partitionPath {
+			continue
+		}
+
+		for _, nestedMount := range mountsWithin(mounts, mount.MountPoint) {
+			nestedMountPoints = append(nestedMountPoints, nestedMount.MountPoint)
+		}
+	}
+
+	// Deepest mount points are unmounted first
+	sort.Sort(sort.Reverse(sort.StringSlice(nestedMountPoints)))
+
+	for _, mountPoint := range nestedMountPoints {
+		p.logger.Info(logTag, "Unmounting %s mounted within mount point of %s", mountPoint, partitionPath)
+
+		_, err = p.diskManager.GetMounter().Unmount(mountPoint)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Unmounting %s", mountPoint)
+		}
+	}
+
+	return nil
+}
+
+func mountsWithin(mounts []boshdisk.Mount, mountPoint string) []boshdisk.Mount {
+	var nestedMounts []boshdisk.Mount
+
+	for _, mount := range mounts {
+		if strings.HasPrefix(mount.MountPoint, strings.TrimSuffix(mountPoint, "/")+"/") {
+			nestedMounts = append(nestedMounts, mount)
 		}
 	}
 
-	return p.diskManager.GetMounter().Unmount(realPath)
+	return nestedMounts
 }
 
 func (p linux) GetEphemeralDiskPath(diskSettings boshsettings.DiskSettings) string {
+	if p.options.StripeRawEphemeralDisks && p.fs.FileExists(rawEphemeralArrayPath) {
+		return rawEphemeralArrayPath
+	}
+
 	realPath, _, err := p.devicePathResolver.GetRealDevicePath(diskSettings)
 	if err != nil {
 		return ""
@@ -1126,6 +1862,20 @@ func (p linux) IsPersistentDiskMountable(diskSettings boshsettings.DiskSettings)
 		return false, bosherr.WrapErrorf(err, "Validating path: %s", diskSettings.Path)
 	}
 
+	if p.options.PartitionerType == "native" {
+		reader, err := p.partitionTableReader()
+		if err != nil {
+			return false, err
+		}
+
+		partitions, _, err := reader.GetPartitions(realPath)
+		if err != nil {
+			return false, bosherr.WrapErrorf(err, "Reading partition table of %s", realPath)
+		}
+
+		return len(partitions) > 0, nil
+	}
+
 	stdout, stderr, _, _ := p.cmdRunner.RunCommand("sfdisk", "-d", realPath)
 	if strings.Contains(stderr, "unrecognized partition table type") {
 		return false, nil
@@ -1135,39 +1885,295 @@ func (p linux) IsPersistentDiskMountable(diskSettings boshsettings.DiskSettings)
 	return lines > 4, nil
 }
 
+func (p linux) FreezeFilesystem(mountPoint string) error {
+	p.logger.Debug(logTag, "Freezing filesystem mounted at %s", mountPoint)
+
+	_, _, _, err := p.cmdRunner.RunCommand("fsfreeze", "--freeze", mountPoint)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Freezing filesystem mounted at %s", mountPoint)
+	}
+
+	return nil
+}
+
+func (p linux) ThawFilesystem(mountPoint string) error {
+	p.logger.Debug(logTag, "Thawing filesystem mounted at %s", mountPoint)
+
+	_, _, _, err := p.cmdRunner.RunCommand("fsfreeze", "--unfreeze", mountPoint)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Thawing filesystem mounted at %s", mountPoint)
+	}
+
+	return nil
+}
+
 func (p linux) IsMountPoint(path string) (string, bool, error) {
 	return p.diskManager.GetMounter().IsMountPoint(path)
 }
 
-func (p linux) MigratePersistentDisk(fromMountPoint, toMountPoint string) (err error) {
+func (p linux) MigratePersistentDisk(fromMountPoint, toMountPoint string) (DiskMigrationTotals, error) {
+	var totals DiskMigrationTotals
+
+	err := p.migratePersistentDisk(fromMountPoint, toMountPoint, &totals)
+	if err != nil {
+		return DiskMigrationTotals{}, err
+	}
+
+	return totals, nil
+}
+
+func (p linux) migratePersistentDisk(fromMountPoint, toMountPoint string, totals *DiskMigrationTotals) (err error) {
 	p.logger.Debug(logTag, "Migrating persistent disk %v to %v", fromMountPoint, toMountPoint)
 
-	err = p.diskManager.GetMounter().RemountAsReadonly(fromMountPoint)
+	state, err := NewDiskMigrationState(p.fs, filepath.Join(p.dirProvider.BoshDir(), "persistent_disk_migration.json"))
 	if err != nil {
-		err = bosherr.WrapError(err, "Remounting persistent disk as readonly")
-		return
+		return bosherr.WrapError(err, "Loading disk migration state")
+	}
+
+	if !state.Matches(fromMountPoint, toMountPoint) || !state.Started() {
+		if !state.Matches(fromMountPoint, toMountPoint) {
+			state.MountOptions = nil
+			state.NestedMounts = nil
+		}
+
+		toPartitionPath, isMountPoint, err := p.diskManager.GetMounter().IsMountPoint(toMountPoint)
+		if err != nil {
+			return bosherr.WrapError(err, "Checking new persistent disk mount point")
+		}
+
+		if !isMountPoint {
+			return bosherr.Errorf("New persistent disk is not mounted at %s", toMountPoint)
+		}
+
+		state.FromMountPoint = fromMountPoint
+		state.ToMountPoint = toMountPoint
+		state.ToPartitionPath = toPartitionPath
+		state.Phase = DiskMigrationPhaseCopying
+		state.FilesCopied = 0
+		state.BytesCopied = 0
+	} else {
+		p.logger.Info(logTag, "Resuming persistent disk migration in phase '%s'", state.Phase)
+
+		err = p.remountMigrationTarget(state)
+		if err != nil {
+			return err
+		}
 	}
 
-	// Golang does not implement a file copy that would allow us to preserve dates...
-	// So we have to shell out to tar to perform the copy instead of delegating to the FileSystem
-	tarCopy := fmt.Sprintf("(tar -C %s -cf - .) | (tar -C %s -xpf -)", fromMountPoint, toMountPoint)
-	_, _, _, err = p.cmdRunner.RunCommand("sh", "-c", tarCopy)
+	// rsync does not cross into nested mounts hence they would be missing on new disk
+	err = p.unmountMigrationNestedMounts(state)
 	if err != nil {
-		err = bosherr.WrapError(err, "Copying files from old disk to new disk")
-		return
+		return err
+	}
+
+	if state.Phase != DiskMigrationPhaseVerified {
+		err = p.copyPersistentDisk(state)
+		if err != nil {
+			return err
+		}
 	}
 
 	_, err = p.diskManager.GetMounter().Unmount(fromMountPoint)
 	if err != nil {
-		err = bosherr.WrapError(err, "Unmounting old persistent disk")
-		return
+		return bosherr.WrapError(err, "Unmounting old persistent disk")
 	}
 
-	err = p.diskManager.GetMounter().Remount(toMountPoint, fromMountPoint)
+	mountOptions, err := boshdisk.MountOptionsArgs(state.MountOptions)
 	if err != nil {
-		err = bosherr.WrapError(err, "Remounting new disk on original mountpoint")
+		return bosherr.WrapError(err, "Validating persistent disk mount options")
 	}
-	return
+
+	err = p.diskManager.GetMounter().Remount(toMountPoint, fromMountPoint, mountOptions...)
+	if err != nil {
+		return bosherr.WrapError(err, "Remounting new disk on original mountpoint")
+	}
+
+	for _, nestedMount := range state.NestedMounts {
+		p.logger.Info(logTag, "Mounting %s at %s on top of new persistent disk", nestedMount.PartitionPath, nestedMount.MountPoint)
+
+		nestedMountOptions, err := boshdisk.MountOptionsArgs(nestedMount.MountOptions)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Validating mount options of %s", nestedMount.MountPoint)
+		}
+
+		err = p.diskManager.GetMounter().Mount(nestedMount.PartitionPath, nestedMount.MountPoint, nestedMountOptions...)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Mounting %s at %s", nestedMount.PartitionPath, nestedMount.MountPoint)
+		}
+	}
+
+	// Totals are only kept in state which is cleared once disks are switched
+	totals.FilesCopied = state.FilesCopied
+	totals.BytesCopied = state.BytesCopied
+
+	return state.Clear()
+}
+
+// unmountMigrationNestedMounts records disks mounted within old disk before
+// unmounting them so that an interrupted migration still mounts them again
+func (p linux) unmountMigrationNestedMounts(state *DiskMigrationState) error {
+	mounts, err := p.diskManager.GetMountsSearcher().SearchMounts()
+	if err != nil {
+		return bosherr.WrapError(err, "Searching mounts")
+	}
+
+	allMountOptions, err := p.associatedDiskMountOptions()
+	if err != nil {
+		return bosherr.WrapError(err, "Loading associated disk mount options")
+	}
+
+	var nestedMounts []DiskMigrationNestedMount
+
+	for _, mount := range mountsWithin(mounts, state.FromMountPoint) {
+		nestedMount := DiskMigrationNestedMount{
+			PartitionPath: mount.PartitionPath,
+			MountPoint:    mount.MountPoint,
+			MountOptions:  allMountOptions[mount.MountPoint],
+		}
+		nestedMounts = append(nestedMounts, nestedMount)
+
+		recorded := false
+		for _, recordedMount := range state.NestedMounts {
+			recorded = recorded || recordedMount.PartitionPath == nestedMount.PartitionPath && recordedMount.MountPoint == nestedMount.MountPoint
+		}
+
+		if !recorded {
+			state.NestedMounts = append(state.NestedMounts, nestedMount)
+		}
+	}
+
+	if len(nestedMounts) == 0 {
+		return nil
+	}
+
+	// Shallowest mount points are mounted again first and unmounted last
+	sort.Sort(nestedMountsByMountPoint(state.NestedMounts))
+	sort.Sort(sort.Reverse(nestedMountsByMountPoint(nestedMounts)))
+
+	err = state.SaveState()
+	if err != nil {
+		return bosherr.WrapError(err, "Saving disk migration state")
+	}
+
+	for _, nestedMount := range nestedMounts {
+		p.logger.Info(logTag, "Unmounting %s mounted within old persistent disk", nestedMount.MountPoint)
+
+		_, err = p.diskManager.GetMounter().Unmount(nestedMount.MountPoint)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Unmounting %s", nestedMount.MountPoint)
+		}
+	}
+
+	return nil
+}
+
+// recordMigrationTarget saves mount options of new disk mounted for migration
+func (p linux) recordMigrationTarget(fromMountPoint, toMountPoint string, mountOptions []string) error {
+	state, err := NewDiskMigrationState(p.fs, filepath.Join(p.dirProvider.BoshDir(), "persistent_disk_migration.json"))
+	if err != nil {
+		return bosherr.WrapError(err, "Loading disk migration state")
+	}
+
+	if state.Matches(fromMountPoint, toMountPoint) && state.Started() {
+		return nil
+	}
+
+	state.FromMountPoint = fromMountPoint
+	state.ToMountPoint = toMountPoint
+	state.Phase = ""
+	state.ToPartitionPath = ""
+	state.MountOptions = mountOptions
+
+	err = state.SaveState()
+	if err != nil {
+		return bosherr.WrapError(err, "Saving disk migration state")
+	}
+
+	return nil
+}
+
+// remountMigrationTarget mounts new disk again since bootstrap
+// only remounts the old one after agent restart. Otherwise files
+// would be copied onto the root filesystem under the mount point.
+func (p linux) remountMigrationTarget(state *DiskMigrationState) error {
+	partitionPath, isMountPoint, err := p.diskManager.GetMounter().IsMountPoint(state.ToMountPoint)
+	if err != nil {
+		return bosherr.WrapError(err, "Checking new persistent disk mount point")
+	}
+
+	if isMountPoint {
+		if state.ToPartitionPath != "" && partitionPath != state.ToPartitionPath {
+			return bosherr.Errorf("Found %s mounted at %s instead of new persistent disk %s", partitionPath, state.ToMountPoint, state.ToPartitionPath)
+		}
+		return nil
+	}
+
+	if state.ToPartitionPath == "" {
+		return bosherr.Errorf("New persistent disk is not mounted at %s", state.ToMountPoint)
+	}
+
+	mountOptions, err := boshdisk.MountOptionsArgs(state.MountOptions)
+	if err != nil {
+		return bosherr.WrapError(err, "Validating persistent disk mount options")
+	}
+
+	p.logger.Info(logTag, "Remounting new persistent disk %s at %s", state.ToPartitionPath, state.ToMountPoint)
+
+	err = p.diskManager.GetMounter().Mount(state.ToPartitionPath, state.ToMountPoint, mountOptions...)
+	if err != nil {
+		return bosherr.WrapError(err, "Remounting new persistent disk")
+	}
+
+	return nil
+}
+
+func (p linux) copyPersistentDisk(state *DiskMigrationState) error {
+	err := state.SaveState()
+	if err != nil {
+		return bosherr.WrapError(err, "Saving disk migration state")
+	}
+
+	err = p.diskManager.GetMounter().RemountAsReadonly(state.FromMountPoint)
+	if err != nil {
+		return bosherr.WrapError(err, "Remounting persistent disk as readonly")
+	}
+
+	// Golang does not implement a file copy that would allow us to preserve dates, hardlinks, ACLs and xattrs...
+	// So we have to shell out to rsync which also skips files already copied by an interrupted migration
+	progress := newDiskMigrationProgress(p.logger)
+
+	_, _, _, err = p.cmdRunner.RunComplexCommand(boshsys.Command{
+		Name: "rsync",
+		Args: []string{
+			"--archive", "--hard-links", "--acls", "--xattrs", "--numeric-ids",
+			"--one-file-system", "--delete", "--partial", "--info=progress2", "--stats",
+			state.FromMountPoint + "/", state.ToMountPoint + "/",
+		},
+		Stdout: progress,
+	})
+	if err != nil {
+		return bosherr.WrapError(err, "Copying files from old disk to new disk")
+	}
+
+	// Resumed migration only transfers files which were not copied before
+	filesTransferred, bytesTransferred := progress.Totals()
+	p.logger.Info(logTag, "Transferred %d files (%d bytes) from old disk to new disk", filesTransferred, bytesTransferred)
+
+	state.FilesCopied, state.BytesCopied, err = verifyDiskMigration(p.fs, state.FromMountPoint, state.ToMountPoint, p.options.VerifyPersistentDiskMigrationChecksums)
+	if err != nil {
+		return bosherr.WrapError(err, "Verifying files copied from old disk to new disk")
+	}
+
+	p.logger.Info(logTag, "Verified %d files (%d bytes) on new disk", state.FilesCopied, state.BytesCopied)
+
+	state.Phase = DiskMigrationPhaseVerified
+
+	err = state.SaveState()
+	if err != nil {
+		return bosherr.WrapError(err, "Saving disk migration state")
+	}
+
+	return nil
 }
 
 func (p linux) IsPersistentDiskMounted(diskSettings boshsettings.DiskSettings) (bool, error) {
@@ -1181,15 +2187,7 @@ func (p linux) IsPersistentDiskMounted(diskSettings boshsettings.DiskSettings) (
 		return false, bosherr.WrapError(err, "Getting real device path")
 	}
 
-	if !p.options.UsePreformattedPersistentDisk {
-		if strings.Contains(realPath, "/dev/mapper/") {
-			realPath = realPath + "-part1"
-		} else {
-			realPath += "1"
-		}
-	}
-
-	return p.diskManager.GetMounter().IsMounted(realPath)
+	return p.diskManager.GetMounter().IsMounted(p.persistentDiskMountPath(realPath))
 }
 
 func (p linux) StartMonit() error {
@@ -1356,8 +2354,8 @@ func (p linux) createEphemeralPartitionsOnRootDevice() (string, string, error) {
 		return "", "", bosherr.WrapErrorf(err, "Partitioning root device `%s'", rootDevicePath)
 	}
 
-	swapPartitionPath := rootDevicePath + strconv.Itoa(rootDeviceNumber+1)
-	dataPartitionPath := rootDevicePath + strconv.Itoa(rootDeviceNumber+2)
+	swapPartitionPath := PartitionPath(rootDevicePath, rootDeviceNumber+1)
+	dataPartitionPath := PartitionPath(rootDevicePath, rootDeviceNumber+2)
 	return swapPartitionPath, dataPartitionPath, nil
 }
 
@@ -1384,14 +2382,14 @@ func (p linux) partitionEphemeralDisk(realPath string, desiredSwapSizeInBytes *u
 			{SizeInBytes: linuxSizeInBytes, Type: boshdisk.PartitionTypeLinux},
 		}
 		swapPartitionPath = ""
-		dataPartitionPath = realPath + "1"
+		dataPartitionPath = PartitionPath(realPath, 1)
 	} else {
 		partitions = []boshdisk.Partition{
 			{SizeInBytes: swapSizeInBytes, Type: boshdisk.PartitionTypeSwap},
 			{SizeInBytes: linuxSizeInBytes, Type: boshdisk.PartitionTypeLinux},
 		}
-		swapPartitionPath = realPath + "1"
-		dataPartitionPath = realPath + "2"
+		swapPartitionPath = PartitionPath(realPath, 1)
+		dataPartitionPath = PartitionPath(realPath, 2)
 	}
 
 	p.logger.Info(logTag, "Partitioning ephemeral disk `%s' with %s", realPath, partitions)
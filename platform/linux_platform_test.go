@@ -206,6 +206,216 @@ func describeLinuxPlatform() {
 		})
 	})
 
+	Describe("SetupUserSudoCommands", func() {
+		It("writes validated sudoers drop-in for the user", func() {
+			err := platform.SetupUserSudoCommands("bosh_foo", []string{"/usr/bin/foo", "/usr/bin/bar *"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{{"visudo", "-c", "-f", "/etc/sudoers.d/bosh_foo.tmp"}}))
+
+			dropIn := fs.GetFileTestStat("/etc/sudoers.d/bosh_foo")
+			Expect(dropIn.StringContents()).To(Equal("bosh_foo ALL=(root) NOPASSWD: /usr/bin/foo, /usr/bin/bar *\n"))
+			Expect(dropIn.FileMode).To(Equal(os.FileMode(0440)))
+			Expect(fs.FileExists("/etc/sudoers.d/bosh_foo.tmp")).To(BeFalse())
+		})
+
+		It("installs log viewer which only reads files within logs dir", func() {
+			err := platform.SetupUserSudoCommands("bosh_foo", []string{"/usr/bin/foo"})
+			Expect(err).ToNot(HaveOccurred())
+
+			logViewer := fs.GetFileTestStat("/fake-dir/bosh/bin/bosh-view-log")
+			Expect(logViewer.FileMode).To(Equal(os.FileMode(0755)))
+			Expect(logViewer.StringContents()).To(ContainSubstring(`logs_dir="/fake-dir/sys/log"`))
+			Expect(logViewer.StringContents()).To(ContainSubstring(`opened="$(readlink "/proc/$$/fd/3")"`))
+		})
+
+		It("returns an error when installing log viewer fails", func() {
+			fs.WriteFileError = errors.New("fake-write-err")
+
+			err := platform.SetupUserSudoCommands("bosh_foo", []string{"/usr/bin/foo"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-write-err"))
+			Expect(fs.FileExists("/etc/sudoers.d/bosh_foo")).To(BeFalse())
+		})
+
+		It("does not install invalid sudoers drop-in", func() {
+			cmdRunner.AddCmdResult("visudo -c -f /etc/sudoers.d/bosh_foo.tmp", fakesys.FakeCmdResult{Error: errors.New("fake-visudo-err")})
+
+			err := platform.SetupUserSudoCommands("bosh_foo", []string{"/usr/bin/foo"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-visudo-err"))
+			Expect(fs.FileExists("/etc/sudoers.d/bosh_foo")).To(BeFalse())
+			Expect(fs.FileExists("/etc/sudoers.d/bosh_foo.tmp")).To(BeFalse())
+		})
+
+		It("removes sudoers drop-in when there are no commands", func() {
+			fs.WriteFileString("/etc/sudoers.d/bosh_foo", "fake-drop-in")
+
+			err := platform.SetupUserSudoCommands("bosh_foo", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fs.FileExists("/etc/sudoers.d/bosh_foo")).To(BeFalse())
+		})
+
+		It("returns an error when user name cannot be used as sudoers drop-in name", func() {
+			err := platform.SetupUserSudoCommands("bosh_foo.bar", []string{"/usr/bin/foo"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Cannot create sudoers drop-in for user 'bosh_foo.bar'"))
+		})
+	})
+
+	Describe("SetupJobSecretsStaging", func() {
+		var mounter *fakedisk.FakeMounter
+
+		BeforeEach(func() {
+			mounter = diskManager.FakeMounter
+		})
+
+		It("mounts tmpfs readable only by root to staging dir", func() {
+			stagingDir, err := platform.SetupJobSecretsStaging(nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stagingDir).To(Equal("/fake-dir/data/secrets-staging"))
+
+			Expect(fs.GetFileTestStat("/fake-dir/data/secrets-staging").FileMode).To(Equal(os.FileMode(0700)))
+			Expect(mounter.MountPartitionPaths).To(Equal([]string{"tmpfs"}))
+			Expect(mounter.MountMountPoints).To(Equal([]string{"/fake-dir/data/secrets-staging"}))
+			Expect(mounter.MountMountOptions).To(Equal([][]string{{"-t", "tmpfs", "-o", "size=1024k,mode=0700,nodev,nosuid,noexec"}}))
+		})
+
+		It("sizes tmpfs to fit all secrets in whole pages", func() {
+			_, err := platform.SetupJobSecretsStaging([]uint64{1, 4097, 0})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mounter.MountMountOptions).To(Equal([][]string{{"-t", "tmpfs", "-o", "size=1036k,mode=0700,nodev,nosuid,noexec"}}))
+		})
+
+		It("mounts staging dir again so that it fits given secrets", func() {
+			mounter.IsMountPointResult = true
+
+			_, err := platform.SetupJobSecretsStaging([]uint64{5 * 1024 * 1024})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mounter.UnmountPartitionPathsOrMountPoints).To(Equal([]string{"/fake-dir/data/secrets-staging"}))
+			Expect(mounter.MountMountOptions).To(Equal([][]string{{"-t", "tmpfs", "-o", "size=6144k,mode=0700,nodev,nosuid,noexec"}}))
+		})
+
+		It("returns error when unmounting staging dir fails", func() {
+			mounter.IsMountPointResult = true
+			mounter.UnmountErr = errors.New("fake-unmount-err")
+
+			_, err := platform.SetupJobSecretsStaging(nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-unmount-err"))
+			Expect(mounter.MountPartitionPaths).To(BeEmpty())
+		})
+
+		It("returns error when mounting tmpfs fails", func() {
+			mounter.MountErr = errors.New("fake-mount-err")
+
+			_, err := platform.SetupJobSecretsStaging(nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-mount-err"))
+		})
+	})
+
+	Describe("SetupJobSecrets", func() {
+		var mounter *fakedisk.FakeMounter
+
+		BeforeEach(func() {
+			mounter = diskManager.FakeMounter
+		})
+
+		It("writes secrets readable only by job user to tmpfs mounted secrets dir", func() {
+			secretsDir, err := platform.SetupJobSecrets("fake-job", map[string][]byte{"fake-secret": []byte("fake-value")})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(secretsDir).To(Equal("/fake-dir/data/secrets/fake-job"))
+
+			Expect(mounter.MountPartitionPaths).To(Equal([]string{"tmpfs"}))
+			Expect(mounter.MountMountPoints).To(Equal([]string{"/fake-dir/data/secrets/fake-job"}))
+			Expect(mounter.MountMountOptions).To(Equal([][]string{{"-t", "tmpfs", "-o", "size=1028k,mode=0750,nodev,nosuid,noexec"}}))
+
+			secret := fs.GetFileTestStat("/fake-dir/data/secrets/fake-job/fake-secret")
+			Expect(secret.StringContents()).To(Equal("fake-value"))
+			Expect(secret.FileMode).To(Equal(os.FileMode(0400)))
+
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{
+				{"chown", "root:vcap", "/fake-dir/data/secrets/fake-job"},
+				{"chown", "vcap:vcap", "/fake-dir/data/secrets/fake-job/fake-secret"},
+			}))
+		})
+
+		It("sizes tmpfs to fit all secrets", func() {
+			_, err := platform.SetupJobSecrets("fake-job", map[string][]byte{
+				"fake-secret-1": make([]byte, 1024*1024),
+				"fake-secret-2": make([]byte, 4097),
+				"fake-secret-3": []byte{},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(mounter.MountMountOptions).To(Equal([][]string{{"-t", "tmpfs", "-o", "size=2056k,mode=0750,nodev,nosuid,noexec"}}))
+		})
+
+		It("unmounts and removes previous secrets of the job", func() {
+			mounter.IsMountPointResult = true
+			fs.WriteFileString("/fake-dir/data/secrets/fake-job/fake-old-secret", "fake-old-value")
+
+			_, err := platform.SetupJobSecrets("fake-job", map[string][]byte{"fake-secret": []byte("fake-value")})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(mounter.UnmountPartitionPathOrMountPoint).To(Equal("/fake-dir/data/secrets/fake-job"))
+			Expect(fs.FileExists("/fake-dir/data/secrets/fake-job/fake-old-secret")).To(BeFalse())
+		})
+
+		It("returns error when mounting tmpfs fails", func() {
+			mounter.MountErr = errors.New("fake-mount-err")
+
+			_, err := platform.SetupJobSecrets("fake-job", map[string][]byte{"fake-secret": []byte("fake-value")})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-mount-err"))
+			Expect(fs.FileExists("/fake-dir/data/secrets/fake-job/fake-secret")).To(BeFalse())
+		})
+
+		It("returns error when secret name would escape secrets dir", func() {
+			_, err := platform.SetupJobSecrets("fake-job", map[string][]byte{"../fake-secret": []byte("fake-value")})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid secret name '../fake-secret' for job 'fake-job'"))
+			Expect(mounter.MountPartitionPaths).To(BeEmpty())
+		})
+
+		It("returns error when job name would escape secrets dir", func() {
+			_, err := platform.SetupJobSecrets("..", map[string][]byte{"fake-secret": []byte("fake-value")})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid job name '..' for secrets"))
+		})
+	})
+
+	Describe("RemoveJobSecrets", func() {
+		var mounter *fakedisk.FakeMounter
+
+		BeforeEach(func() {
+			mounter = diskManager.FakeMounter
+		})
+
+		It("unmounts and removes secrets of all jobs", func() {
+			mounter.IsMountPointResult = true
+			fs.WriteFileString("/fake-dir/data/secrets/fake-job/fake-secret", "fake-value")
+			fs.SetGlob("/fake-dir/data/secrets/*", []string{"/fake-dir/data/secrets/fake-job"})
+
+			err := platform.RemoveJobSecrets()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(mounter.UnmountPartitionPathOrMountPoint).To(Equal("/fake-dir/data/secrets/fake-job"))
+			Expect(fs.FileExists("/fake-dir/data/secrets/fake-job")).To(BeFalse())
+		})
+
+		It("returns error when unmounting secrets fails", func() {
+			mounter.IsMountPointResult = true
+			mounter.UnmountErr = errors.New("fake-unmount-err")
+			fs.SetGlob("/fake-dir/data/secrets/*", []string{"/fake-dir/data/secrets/fake-job"})
+
+			err := platform.RemoveJobSecrets()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-unmount-err"))
+		})
+	})
+
 	Describe("DeleteEphemeralUsersMatching", func() {
 		It("deletes users with prefix and regex", func() {
 			passwdFile := `bosh_foo:...
@@ -219,9 +429,42 @@ bosh_foobar:...`
 
 			err := platform.DeleteEphemeralUsersMatching("bar$")
 			Expect(err).NotTo(HaveOccurred())
-			Expect(len(cmdRunner.RunCommands)).To(Equal(2))
-			Expect(cmdRunner.RunCommands[0]).To(Equal([]string{"userdel", "-r", "bosh_bar"}))
-			Expect(cmdRunner.RunCommands[1]).To(Equal([]string{"userdel", "-r", "bosh_foobar"}))
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{
+				{"usermod", "-L", "-e", "1", "bosh_bar"},
+				{"pkill", "-KILL", "-u", "bosh_bar"},
+				{"userdel", "-r", "bosh_bar"},
+				{"usermod", "-L", "-e", "1", "bosh_foobar"},
+				{"pkill", "-KILL", "-u", "bosh_foobar"},
+				{"userdel", "-r", "bosh_foobar"},
+			}))
+		})
+
+		It("deletes users without running processes", func() {
+			fs.WriteFileString("/etc/passwd", "bosh_foo:...")
+			cmdRunner.AddCmdResult("pkill -KILL -u bosh_foo", fakesys.FakeCmdResult{ExitStatus: 1, Error: errors.New("fake-pkill-err")})
+
+			err := platform.DeleteEphemeralUsersMatching("foo$")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cmdRunner.RunCommands[2]).To(Equal([]string{"userdel", "-r", "bosh_foo"}))
+		})
+
+		It("removes sudoers drop-ins of deleted users", func() {
+			fs.WriteFileString("/etc/passwd", "bosh_foo:...")
+			fs.WriteFileString("/etc/sudoers.d/bosh_foo", "fake-drop-in")
+
+			err := platform.DeleteEphemeralUsersMatching("foo$")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fs.FileExists("/etc/sudoers.d/bosh_foo")).To(BeFalse())
+		})
+
+		It("does not delete user when locking fails", func() {
+			fs.WriteFileString("/etc/passwd", "bosh_foo:...")
+			cmdRunner.AddCmdResult("usermod -L -e 1 bosh_foo", fakesys.FakeCmdResult{Error: errors.New("fake-usermod-err")})
+
+			err := platform.DeleteEphemeralUsersMatching("foo$")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-usermod-err"))
+			Expect(cmdRunner.RunCommands).To(HaveLen(1))
 		})
 	})
 
@@ -440,6 +683,150 @@ bosh_foobar:...`
 
 	})
 
+	Describe("SetupSSHCertificateAuthority", func() {
+		principals := map[string][]string{
+			"bosh_sshers":  {"operator"},
+			"bosh_sudoers": {"admin", "oncall"},
+		}
+
+		BeforeEach(func() {
+			fs.WriteFileString("/etc/ssh/sshd_config", "PermitRootLogin no\n")
+		})
+
+		It("writes trusted CA keys, principals and empty revoked keys", func() {
+			err := platform.SetupSSHCertificateAuthority([]string{"ssh-rsa fake-ca-1", "ssh-rsa fake-ca-2"}, principals)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.ReadFileString("/etc/ssh/bosh_trusted_user_ca_keys")).To(Equal("ssh-rsa fake-ca-1\nssh-rsa fake-ca-2\n"))
+			Expect(fs.ReadFileString("/etc/ssh/bosh_revoked_keys")).To(Equal(""))
+			Expect(fs.ReadFileString("/etc/ssh/bosh_auth_principals/bosh_sshers")).To(Equal("operator\n"))
+			Expect(fs.ReadFileString("/etc/ssh/bosh_auth_principals/bosh_sudoers")).To(Equal("admin\noncall\n"))
+		})
+
+		It("configures sshd, validates its config and reloads it", func() {
+			err := platform.SetupSSHCertificateAuthority([]string{"ssh-rsa fake-ca"}, principals)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.ReadFileString("/etc/ssh/sshd_config")).To(Equal(`# BEGIN bosh-agent ssh certificate authority
+TrustedUserCAKeys /etc/ssh/bosh_trusted_user_ca_keys
+RevokedKeys /etc/ssh/bosh_revoked_keys
+# END bosh-agent ssh certificate authority
+PermitRootLogin no
+# BEGIN bosh-agent ssh certificate principals
+Match Group bosh_sudoers
+  AuthorizedPrincipalsFile /etc/ssh/bosh_auth_principals/bosh_sudoers
+Match Group bosh_sshers
+  AuthorizedPrincipalsFile /etc/ssh/bosh_auth_principals/bosh_sshers
+# END bosh-agent ssh certificate principals
+`))
+
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{
+				{"sshd", "-t"},
+				{"service", "ssh", "reload"},
+			}))
+		})
+
+		It("does not overwrite existing revoked keys", func() {
+			fs.WriteFileString("/etc/ssh/bosh_revoked_keys", "ssh-rsa fake-revoked\n")
+
+			err := platform.SetupSSHCertificateAuthority([]string{"ssh-rsa fake-ca"}, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fs.ReadFileString("/etc/ssh/bosh_revoked_keys")).To(Equal("ssh-rsa fake-revoked\n"))
+		})
+
+		It("does not reload sshd when its config did not change", func() {
+			err := platform.SetupSSHCertificateAuthority([]string{"ssh-rsa fake-ca"}, principals)
+			Expect(err).ToNot(HaveOccurred())
+
+			cmdRunner.RunCommands = nil
+
+			err = platform.SetupSSHCertificateAuthority([]string{"ssh-rsa fake-other-ca"}, principals)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cmdRunner.RunCommands).To(BeEmpty())
+			Expect(fs.ReadFileString("/etc/ssh/bosh_trusted_user_ca_keys")).To(Equal("ssh-rsa fake-other-ca\n"))
+		})
+
+		It("reloads sshd service on systems where it is named sshd", func() {
+			cmdRunner.AddCmdResult("service ssh reload", fakesys.FakeCmdResult{Error: errors.New("fake-reload-err")})
+
+			err := platform.SetupSSHCertificateAuthority([]string{"ssh-rsa fake-ca"}, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cmdRunner.RunCommands[2]).To(Equal([]string{"service", "sshd", "reload"}))
+		})
+
+		It("removes managed configuration when no CA keys are trusted", func() {
+			err := platform.SetupSSHCertificateAuthority([]string{"ssh-rsa fake-ca"}, principals)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = platform.SetupSSHCertificateAuthority(nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fs.ReadFileString("/etc/ssh/sshd_config")).To(Equal("PermitRootLogin no\n"))
+			Expect(fs.FileExists("/etc/ssh/bosh_trusted_user_ca_keys")).To(BeFalse())
+		})
+
+		It("does nothing when no CA keys are trusted and sshd was not configured", func() {
+			err := platform.SetupSSHCertificateAuthority(nil, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cmdRunner.RunCommands).To(BeEmpty())
+			Expect(fs.FileExists("/etc/ssh/bosh_trusted_user_ca_keys")).To(BeFalse())
+		})
+
+		It("restores previous sshd config when new config is invalid", func() {
+			cmdRunner.AddCmdResult("sshd -t", fakesys.FakeCmdResult{Error: errors.New("fake-sshd-err")})
+
+			err := platform.SetupSSHCertificateAuthority([]string{"ssh-rsa fake-ca"}, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-sshd-err"))
+			Expect(fs.ReadFileString("/etc/ssh/sshd_config")).To(Equal("PermitRootLogin no\n"))
+		})
+
+		It("returns an error when principals are mapped to unsupported group", func() {
+			err := platform.SetupSSHCertificateAuthority([]string{"ssh-rsa fake-ca"}, map[string][]string{"vcap": {"fake-principal"}})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Mapping ssh principals to group 'vcap' is not supported"))
+		})
+
+		It("returns an error when CA key spans multiple lines", func() {
+			err := platform.SetupSSHCertificateAuthority([]string{"ssh-rsa fake-ca\nPermitRootLogin yes"}, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(fs.ReadFileString("/etc/ssh/sshd_config")).To(Equal("PermitRootLogin no\n"))
+		})
+	})
+
+	Describe("UpdateRevokedSSHKeys", func() {
+		BeforeEach(func() {
+			fs.WriteFileString("/etc/ssh/bosh_trusted_user_ca_keys", "ssh-rsa fake-ca\n")
+		})
+
+		It("writes revoked keys", func() {
+			err := platform.UpdateRevokedSSHKeys([]string{"ssh-rsa fake-key-1", "ssh-rsa fake-key-2"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fs.ReadFileString("/etc/ssh/bosh_revoked_keys")).To(Equal("ssh-rsa fake-key-1\nssh-rsa fake-key-2\n"))
+		})
+
+		It("empties revoked keys", func() {
+			fs.WriteFileString("/etc/ssh/bosh_revoked_keys", "ssh-rsa fake-key\n")
+
+			err := platform.UpdateRevokedSSHKeys(nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fs.ReadFileString("/etc/ssh/bosh_revoked_keys")).To(Equal(""))
+		})
+
+		It("returns an error when revoked key spans multiple lines", func() {
+			err := platform.UpdateRevokedSSHKeys([]string{"ssh-rsa fake-key\nssh-rsa fake-key-2"})
+			Expect(err).To(HaveOccurred())
+			Expect(fs.FileExists("/etc/ssh/bosh_revoked_keys")).To(BeFalse())
+		})
+
+		It("does not write revoked keys when no ssh certificate authority is trusted", func() {
+			fs.RemoveAll("/etc/ssh/bosh_trusted_user_ca_keys")
+
+			err := platform.UpdateRevokedSSHKeys([]string{"ssh-rsa fake-key-1"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fs.FileExists("/etc/ssh/bosh_revoked_keys")).To(BeFalse())
+		})
+	})
+
 	Describe("SetUserPassword", func() {
 		It("set user password", func() {
 			platform.SetUserPassword("my-user", "my-encrypted-password")
@@ -701,6 +1088,18 @@ fake-base-path/data/sys/log/*.log fake-base-path/data/sys/log/.*.log fake-base-p
 				Expect(mounter.SwapOnPartitionPaths[0]).To(Equal("/dev/xvda1"))
 			})
 
+			It("separates partition number with 'p' when device name ends in a digit", func() {
+				collector.MemStats.Total = uint64(1024 * 1024)
+				partitioner.GetDeviceSizeInBytesSizes["/dev/nvme1n1"] = uint64(1024 * 1024)
+
+				err := platform.SetupEphemeralDiskWithPath("/dev/nvme1n1", nil)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(formatter.FormatPartitionPaths).To(Equal([]string{"/dev/nvme1n1p1", "/dev/nvme1n1p2"}))
+				Expect(mounter.SwapOnPartitionPaths).To(Equal([]string{"/dev/nvme1n1p1"}))
+				Expect(mounter.MountPartitionPaths).To(Equal([]string{"/dev/nvme1n1p2"}))
+			})
+
 			It("creates swap the size of the memory and the rest for data when disk is bigger than twice the memory", func() {
 				memSizeInBytes := uint64(1024 * 1024 * 1024)
 				diskSizeInBytes := 2*memSizeInBytes + 64
@@ -1457,6 +1856,53 @@ Number  Start   End     Size    File system  Name             Flags
 			Expect(cmdRunner.RunCommands[0]).To(Equal([]string{"parted", "-s", "/dev/xvda", "p"}))
 		})
 
+		Context("when native partitioner is configured", func() {
+			var partitioner *fakedisk.FakePartitioner
+
+			BeforeEach(func() {
+				options.PartitionerType = "native"
+				partitioner = diskManager.FakePartitioner
+
+				devicePathResolver.GetRealDevicePathStub = func(diskSettings boshsettings.DiskSettings) (string, bool, error) {
+					return diskSettings.Path, false, nil
+				}
+			})
+
+			It("labels unpartitioned disks without shelling out to parted", func() {
+				err := platform.SetupRawEphemeralDisks([]boshsettings.DiskSettings{{Path: "/dev/xvdb"}, {Path: "/dev/xvdc"}})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(cmdRunner.RunCommands).To(BeEmpty())
+				Expect(partitioner.GetPartitionsDevicePaths).To(Equal([]string{"/dev/xvdb", "/dev/xvdc"}))
+				Expect(partitioner.PartitionDevicePath).To(Equal("/dev/xvdc"))
+				Expect(partitioner.PartitionPartitions).To(Equal([]boshdisk.Partition{
+					{Type: boshdisk.PartitionTypeLinux, Name: "raw-ephemeral-1"},
+				}))
+			})
+
+			It("does not label disks which already have raw ephemeral GPT partition", func() {
+				partitioner.GetPartitionsIsGPT["/dev/xvda"] = true
+				partitioner.GetPartitionsPartitions["/dev/xvda"] = []boshdisk.Partition{
+					{SizeInBytes: 40265318400, Type: boshdisk.PartitionTypeLinux, Name: "raw-ephemeral-0"},
+				}
+
+				err := platform.SetupRawEphemeralDisks([]boshsettings.DiskSettings{{Path: "/dev/xvda"}})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(cmdRunner.RunCommands).To(BeEmpty())
+				Expect(partitioner.PartitionCalled).To(BeFalse())
+			})
+
+			It("returns error when partition table cannot be read", func() {
+				partitioner.GetPartitionsErr = errors.New("fake-get-partitions-err")
+
+				err := platform.SetupRawEphemeralDisks([]boshsettings.DiskSettings{{Path: "/dev/xvdb"}})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-get-partitions-err"))
+				Expect(partitioner.PartitionCalled).To(BeFalse())
+			})
+		})
+
 		Context("when SkipDiskSetup is true", func() {
 			BeforeEach(func() {
 				options.SkipDiskSetup = true
@@ -1469,51 +1915,158 @@ Number  Start   End     Size    File system  Name             Flags
 				Expect(len(cmdRunner.RunCommands)).To(Equal(0))
 			})
 		})
-	})
 
-	Describe("SetupDataDir", func() {
-		var mounter *fakedisk.FakeMounter
-		BeforeEach(func() {
-			mounter = diskManager.FakeMounter
-		})
+		Context("when StripeRawEphemeralDisks is true", func() {
+			assembleCmd := "mdadm --assemble /dev/md/bosh-ephemeral /dev/xvdb /dev/xvdc"
+
+			noSuperblock := func(devicePath string) fakesys.FakeCmdResult {
+				return fakesys.FakeCmdResult{
+					Stderr:     "mdadm: No md superblock detected on " + devicePath + ".\n",
+					ExitStatus: 1,
+					Error:      errors.New("fake-examine-err"),
+				}
+			}
 
-		Context("when sys/run is already mounted", func() {
 			BeforeEach(func() {
-				mounter.IsMountPointResult = true
+				options.StripeRawEphemeralDisks = true
+
+				devicePathResolver.GetRealDevicePathStub = func(diskSettings boshsettings.DiskSettings) (string, bool, error) {
+					return diskSettings.Path, false, nil
+				}
 			})
 
-			It("creates sys/log directory in data directory", func() {
-				err := platform.SetupDataDir()
-				Expect(err).NotTo(HaveOccurred())
+			It("creates a RAID-0 array from all devices when none of them has a superblock", func() {
+				cmdRunner.AddCmdResult(assembleCmd, fakesys.FakeCmdResult{Error: errors.New("fake-assemble-err")})
+				cmdRunner.AddCmdResult("mdadm --examine /dev/xvdb", noSuperblock("/dev/xvdb"))
+				cmdRunner.AddCmdResult("mdadm --examine /dev/xvdc", noSuperblock("/dev/xvdc"))
 
-				sysLogStats := fs.GetFileTestStat("/fake-dir/data/sys/log")
-				Expect(sysLogStats).ToNot(BeNil())
-				Expect(sysLogStats.FileType).To(Equal(fakesys.FakeFileTypeDir))
-				Expect(sysLogStats.FileMode).To(Equal(os.FileMode(0750)))
-				Expect(cmdRunner.RunCommands[0]).To(Equal([]string{"chown", "root:vcap", "/fake-dir/data/sys"}))
-				Expect(cmdRunner.RunCommands[1]).To(Equal([]string{"chown", "root:vcap", "/fake-dir/data/sys/log"}))
+				err := platform.SetupRawEphemeralDisks([]boshsettings.DiskSettings{{Path: "/dev/xvdb"}, {Path: "/dev/xvdc"}})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(cmdRunner.RunCommands).To(Equal([][]string{
+					{"mdadm", "--assemble", "/dev/md/bosh-ephemeral", "/dev/xvdb", "/dev/xvdc"},
+					{"mdadm", "--examine", "/dev/xvdb"},
+					{"mdadm", "--examine", "/dev/xvdc"},
+					{
+						"mdadm", "--create", "/dev/md/bosh-ephemeral", "--run", "--force", "--level=0",
+						"--name=bosh-ephemeral", "--raid-devices=2", "/dev/xvdb", "/dev/xvdc",
+					},
+				}))
 			})
 
-			It("creates symlink from sys to data/sys", func() {
-				err := platform.SetupDataDir()
-				Expect(err).NotTo(HaveOccurred())
+			It("reassembles previously created array", func() {
+				err := platform.SetupRawEphemeralDisks([]boshsettings.DiskSettings{{Path: "/dev/xvdb"}, {Path: "/dev/xvdc"}})
+				Expect(err).ToNot(HaveOccurred())
 
-				sysStats := fs.GetFileTestStat("/fake-dir/sys")
-				Expect(sysStats).ToNot(BeNil())
-				Expect(sysStats.FileType).To(Equal(fakesys.FakeFileTypeSymlink))
-				Expect(sysStats.SymlinkTarget).To(Equal("/fake-dir/data/sys"))
+				Expect(cmdRunner.RunCommands).To(Equal([][]string{
+					{"mdadm", "--assemble", "/dev/md/bosh-ephemeral", "/dev/xvdb", "/dev/xvdc"},
+				}))
 			})
 
-			It("does not create new sys/run dir", func() {
-				err := platform.SetupDataDir()
-				Expect(err).NotTo(HaveOccurred())
+			It("does nothing when array is already assembled", func() {
+				fs.WriteFileString("/dev/md/bosh-ephemeral", "")
 
-				sysRunStats := fs.GetFileTestStat("/fake-dir/data/sys/run")
-				Expect(sysRunStats).To(BeNil())
+				err := platform.SetupRawEphemeralDisks([]boshsettings.DiskSettings{{Path: "/dev/xvdb"}, {Path: "/dev/xvdc"}})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cmdRunner.RunCommands).To(BeEmpty())
 			})
 
-			It("does not mount tmpfs again", func() {
-				err := platform.SetupDataDir()
+			It("does nothing when there are no raw ephemeral devices", func() {
+				err := platform.SetupRawEphemeralDisks(nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cmdRunner.RunCommands).To(BeEmpty())
+			})
+
+			It("returns assemble error without creating array when a device has a superblock", func() {
+				cmdRunner.AddCmdResult(assembleCmd, fakesys.FakeCmdResult{Error: errors.New("fake-assemble-err")})
+				cmdRunner.AddCmdResult("mdadm --examine /dev/xvdb", noSuperblock("/dev/xvdb"))
+				cmdRunner.AddCmdResult("mdadm --examine /dev/xvdc", fakesys.FakeCmdResult{Stdout: "/dev/xvdc:\n          Magic : a92b4efc\n"})
+
+				err := platform.SetupRawEphemeralDisks([]boshsettings.DiskSettings{{Path: "/dev/xvdb"}, {Path: "/dev/xvdc"}})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-assemble-err"))
+
+				Expect(cmdRunner.RunCommands).To(Equal([][]string{
+					{"mdadm", "--assemble", "/dev/md/bosh-ephemeral", "/dev/xvdb", "/dev/xvdc"},
+					{"mdadm", "--examine", "/dev/xvdb"},
+					{"mdadm", "--examine", "/dev/xvdc"},
+				}))
+			})
+
+			It("returns an error without creating array when a device cannot be examined", func() {
+				cmdRunner.AddCmdResult(assembleCmd, fakesys.FakeCmdResult{Error: errors.New("fake-assemble-err")})
+				cmdRunner.AddCmdResult("mdadm --examine /dev/xvdb", fakesys.FakeCmdResult{
+					Stderr:     "mdadm: cannot open /dev/xvdb: No such file or directory\n",
+					ExitStatus: 1,
+					Error:      errors.New("fake-examine-err"),
+				})
+
+				err := platform.SetupRawEphemeralDisks([]boshsettings.DiskSettings{{Path: "/dev/xvdb"}, {Path: "/dev/xvdc"}})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-examine-err"))
+
+				Expect(cmdRunner.RunCommands).To(HaveLen(2))
+			})
+
+			It("returns an error when creating array fails", func() {
+				cmdRunner.AddCmdResult(assembleCmd, fakesys.FakeCmdResult{Error: errors.New("fake-assemble-err")})
+				cmdRunner.AddCmdResult("mdadm --examine /dev/xvdb", noSuperblock("/dev/xvdb"))
+				cmdRunner.AddCmdResult("mdadm --examine /dev/xvdc", noSuperblock("/dev/xvdc"))
+				cmdRunner.AddCmdResult(
+					"mdadm --create /dev/md/bosh-ephemeral --run --force --level=0 --name=bosh-ephemeral --raid-devices=2 /dev/xvdb /dev/xvdc",
+					fakesys.FakeCmdResult{Error: errors.New("fake-create-err")},
+				)
+
+				err := platform.SetupRawEphemeralDisks([]boshsettings.DiskSettings{{Path: "/dev/xvdb"}, {Path: "/dev/xvdc"}})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-create-err"))
+			})
+		})
+	})
+
+	Describe("SetupDataDir", func() {
+		var mounter *fakedisk.FakeMounter
+		BeforeEach(func() {
+			mounter = diskManager.FakeMounter
+		})
+
+		Context("when sys/run is already mounted", func() {
+			BeforeEach(func() {
+				mounter.IsMountPointResult = true
+			})
+
+			It("creates sys/log directory in data directory", func() {
+				err := platform.SetupDataDir()
+				Expect(err).NotTo(HaveOccurred())
+
+				sysLogStats := fs.GetFileTestStat("/fake-dir/data/sys/log")
+				Expect(sysLogStats).ToNot(BeNil())
+				Expect(sysLogStats.FileType).To(Equal(fakesys.FakeFileTypeDir))
+				Expect(sysLogStats.FileMode).To(Equal(os.FileMode(0750)))
+				Expect(cmdRunner.RunCommands[0]).To(Equal([]string{"chown", "root:vcap", "/fake-dir/data/sys"}))
+				Expect(cmdRunner.RunCommands[1]).To(Equal([]string{"chown", "root:vcap", "/fake-dir/data/sys/log"}))
+			})
+
+			It("creates symlink from sys to data/sys", func() {
+				err := platform.SetupDataDir()
+				Expect(err).NotTo(HaveOccurred())
+
+				sysStats := fs.GetFileTestStat("/fake-dir/sys")
+				Expect(sysStats).ToNot(BeNil())
+				Expect(sysStats.FileType).To(Equal(fakesys.FakeFileTypeSymlink))
+				Expect(sysStats.SymlinkTarget).To(Equal("/fake-dir/data/sys"))
+			})
+
+			It("does not create new sys/run dir", func() {
+				err := platform.SetupDataDir()
+				Expect(err).NotTo(HaveOccurred())
+
+				sysRunStats := fs.GetFileTestStat("/fake-dir/data/sys/run")
+				Expect(sysRunStats).To(BeNil())
+			})
+
+			It("does not mount tmpfs again", func() {
+				err := platform.SetupDataDir()
 				Expect(err).NotTo(HaveOccurred())
 				Expect(len(mounter.MountPartitionPaths)).To(Equal(0))
 			})
@@ -2220,6 +2773,25 @@ Number  Start   End     Size    File system  Name             Flags
 						Expect(mounter.MountMountPoints).To(Equal([]string{"/fake-dir/store_migration_target"}))
 						Expect(mounter.MountMountOptions).To(Equal([][]string{nil}))
 					})
+
+					It("records mount options of new disk for migration", func() {
+						err := platform.MountPersistentDisk(
+							boshsettings.DiskSettings{ID: "fake-unique-id", Path: "fake-volume-id", MountOptions: []string{"noatime"}},
+							"/mnt/point",
+						)
+						Expect(err).ToNot(HaveOccurred())
+
+						contents, err := fs.ReadFileString("/fake-dir/bosh/persistent_disk_migration.json")
+						Expect(err).ToNot(HaveOccurred())
+						Expect(contents).To(MatchJSON(`{
+							"from_mount_point": "/mnt/point",
+							"to_mount_point": "/fake-dir/store_migration_target",
+							"phase": "",
+							"mount_options": ["noatime"],
+							"files_copied": 0,
+							"bytes_copied": 0
+						}`))
+					})
 				})
 			})
 
@@ -2358,6 +2930,18 @@ Number  Start   End     Size    File system  Name             Flags
 						})
 					})
 
+					Context("with btrfs", func() {
+						It("formats in using the given format", func() {
+							err := platform.MountPersistentDisk(
+								boshsettings.DiskSettings{Path: "fake-volume-id", FileSystemType: boshdisk.FileSystemBTRFS},
+								"/mnt/point",
+							)
+
+							Expect(err).ToNot(HaveOccurred())
+							Expect(formatter.FormatFsTypes).To(Equal([]boshdisk.FileSystemType{boshdisk.FileSystemBTRFS}))
+						})
+					})
+
 					Context("with an unsupported type", func() {
 						It("it errors", func() {
 							err := platform.MountPersistentDisk(
@@ -2371,6 +2955,37 @@ Number  Start   End     Size    File system  Name             Flags
 					})
 				})
 
+				Context("when settings specify mkfs and mount options", func() {
+					It("formats and mounts with the given options", func() {
+						err := platform.MountPersistentDisk(
+							boshsettings.DiskSettings{
+								Path:           "fake-volume-id",
+								FileSystemType: boshdisk.FileSystemExt4,
+								MkfsOptions:    []string{"inode_ratio=65536"},
+								MountOptions:   []string{"noatime", "discard"},
+							},
+							"/mnt/point",
+						)
+
+						Expect(err).ToNot(HaveOccurred())
+						Expect(formatter.FormatMkfsOptions).To(Equal([][]string{{"inode_ratio=65536"}}))
+						Expect(mounter.MountPartitionPaths).To(Equal([]string{"fake-real-device-path1"}))
+						Expect(mounter.MountMountOptions).To(Equal([][]string{{"-o", "noatime,discard"}}))
+					})
+
+					It("returns an error without partitioning when mount options are not allowed", func() {
+						err := platform.MountPersistentDisk(
+							boshsettings.DiskSettings{Path: "fake-volume-id", MountOptions: []string{"exec"}},
+							"/mnt/point",
+						)
+
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring(`The mount option "exec" is not supported`))
+						Expect(diskManager.PartitionerCalled).To(BeFalse())
+						Expect(mounter.MountCalled).To(BeFalse())
+					})
+				})
+
 				It("returns an error when disk could not be formatted", func() {
 					formatter.FormatError = errors.New("Oh noes!")
 					err := platform.MountPersistentDisk(
@@ -2468,6 +3083,113 @@ Number  Start   End     Size    File system  Name             Flags
 		})
 	})
 
+	Describe("MountAssociatedPersistentDisk", func() {
+		var (
+			formatter *fakedisk.FakeFormatter
+			mounter   *fakedisk.FakeMounter
+		)
+
+		act := func() error {
+			return platform.MountAssociatedPersistentDisk(
+				boshsettings.DiskSettings{ID: "fake-unique-id", Path: "fake-volume-id", MountOptions: []string{"noatime"}},
+				"/fake-dir/store/fake-disk-name",
+			)
+		}
+
+		BeforeEach(func() {
+			formatter = diskManager.FakeFormatter
+			mounter = diskManager.FakeMounter
+			devicePathResolver.RealDevicePath = "fake-real-device-path"
+		})
+
+		It("partitions, formats and mounts the disk at given mount point", func() {
+			err := act()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.GetFileTestStat("/fake-dir/store/fake-disk-name").FileType).To(Equal(fakesys.FakeFileTypeDir))
+			Expect(diskManager.PartitionerCalled).To(BeTrue())
+			Expect(formatter.FormatPartitionPaths).To(Equal([]string{"fake-real-device-path1"}))
+			Expect(mounter.MountPartitionPaths).To(Equal([]string{"fake-real-device-path1"}))
+			Expect(mounter.MountMountPoints).To(Equal([]string{"/fake-dir/store/fake-disk-name"}))
+			Expect(mounter.MountMountOptions).To(Equal([][]string{{"-o", "noatime"}}))
+		})
+
+		It("separates partition number with 'p' when device name ends in a digit", func() {
+			devicePathResolver.RealDevicePath = "/dev/nvme1n1"
+
+			err := act()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(formatter.FormatPartitionPaths).To(Equal([]string{"/dev/nvme1n1p1"}))
+			Expect(mounter.MountPartitionPaths).To(Equal([]string{"/dev/nvme1n1p1"}))
+		})
+
+		It("does not record the disk as managed persistent disk", func() {
+			err := act()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fs.FileExists("/fake-dir/bosh/managed_disk_settings.json")).To(BeFalse())
+		})
+
+		It("records mount options by mount point", func() {
+			fs.WriteFileString("/fake-dir/bosh/associated_disk_mount_options.json", `{"/fake-dir/store/other":["discard"]}`)
+
+			err := act()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fs.ReadFileString("/fake-dir/bosh/associated_disk_mount_options.json")).To(MatchJSON(
+				`{"/fake-dir/store/other":["discard"],"/fake-dir/store/fake-disk-name":["noatime"]}`,
+			))
+		})
+
+		Context("when the disk is already mounted at given mount point", func() {
+			BeforeEach(func() {
+				mounter.IsMountPointResult = true
+				mounter.IsMountPointPartitionPath = "fake-real-device-path1"
+			})
+
+			It("skips mounting", func() {
+				err := act()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(mounter.MountCalled).To(BeFalse())
+			})
+		})
+
+		Context("when the preformatted disk is already mounted at given mount point", func() {
+			BeforeEach(func() {
+				options.UsePreformattedPersistentDisk = true
+				mounter.IsMountPointResult = true
+				mounter.IsMountPointPartitionPath = "fake-real-device-path"
+			})
+
+			It("skips mounting", func() {
+				err := act()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(mounter.MountCalled).To(BeFalse())
+			})
+		})
+
+		Context("when a different device is mounted at given mount point", func() {
+			BeforeEach(func() {
+				mounter.IsMountPointResult = true
+				mounter.IsMountPointPartitionPath = "fake-other-device-path1"
+			})
+
+			It("returns an error", func() {
+				err := act()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Device fake-other-device-path1 is already mounted on /fake-dir/store/fake-disk-name"))
+				Expect(mounter.MountCalled).To(BeFalse())
+			})
+		})
+
+		It("returns an error when device path is not successfully resolved", func() {
+			devicePathResolver.GetRealDevicePathErr = errors.New("fake-get-real-device-path-err")
+
+			err := act()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-get-real-device-path-err"))
+		})
+	})
+
 	Describe("UnmountPersistentDisk", func() {
 		act := func() (bool, error) {
 			return platform.UnmountPersistentDisk(boshsettings.DiskSettings{Path: "fake-device-path"})
@@ -2567,6 +3289,37 @@ Number  Start   End     Size    File system  Name             Flags
 
 				ItUnmountsPersistentDisk("fake-real-device-path") // note no '1'; no partitions
 			})
+
+			Context("when other disks are mounted within mount point of the disk", func() {
+				BeforeEach(func() {
+					diskManager.FakeMountsSearcher.SearchMountsMounts = []boshdisk.Mount{
+						{PartitionPath: "fake-real-device-path1", MountPoint: "/fake-dir/store"},
+						{PartitionPath: "fake-associated-device-path1", MountPoint: "/fake-dir/store/fake-disk-name"},
+						{PartitionPath: "fake-nested-device-path1", MountPoint: "/fake-dir/store/fake-disk-name/nested"},
+						{PartitionPath: "fake-other-device-path1", MountPoint: "/fake-dir/store-other"},
+					}
+				})
+
+				It("unmounts nested disks first, deepest first", func() {
+					_, err := act()
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(mounter.UnmountPartitionPathsOrMountPoints).To(Equal([]string{
+						"/fake-dir/store/fake-disk-name/nested",
+						"/fake-dir/store/fake-disk-name",
+						"fake-real-device-path1",
+					}))
+				})
+
+				It("returns error when searching mounts fails", func() {
+					diskManager.FakeMountsSearcher.SearchMountsErr = errors.New("fake-search-mounts-err")
+
+					_, err := act()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("fake-search-mounts-err"))
+					Expect(mounter.UnmountPartitionPathsOrMountPoints).To(BeEmpty())
+				})
+			})
 		})
 
 		Context("when device path cannot be resolved", func() {
@@ -2696,26 +3449,425 @@ Number  Start   End     Size    File system  Name             Flags
 				Expect(realPath).To(Equal(""))
 			})
 		})
+
+		Context("when StripeRawEphemeralDisks is true", func() {
+			BeforeEach(func() {
+				options.StripeRawEphemeralDisks = true
+				devicePathResolver.RealDevicePath = "fake-real-device-path"
+			})
+
+			It("returns raw ephemeral disk array path when array is assembled", func() {
+				fs.WriteFileString("/dev/md/bosh-ephemeral", "")
+
+				realPath := platform.GetEphemeralDiskPath(boshsettings.DiskSettings{Path: "fake-device-path"})
+				Expect(realPath).To(Equal("/dev/md/bosh-ephemeral"))
+			})
+
+			It("returns real device path when array is not assembled", func() {
+				realPath := platform.GetEphemeralDiskPath(boshsettings.DiskSettings{Path: "fake-device-path"})
+				Expect(realPath).To(Equal("fake-real-device-path"))
+			})
+		})
 	})
 
 	Describe("MigratePersistentDisk", func() {
-		var mounter *fakedisk.FakeMounter
+		var (
+			mounter   *fakedisk.FakeMounter
+			statePath string
+		)
+
+		rsyncCmd := "rsync --archive --hard-links --acls --xattrs --numeric-ids --one-file-system --delete --partial --info=progress2 --stats /from/path/ /to/path/"
+
 		BeforeEach(func() {
 			mounter = diskManager.FakeMounter
+			mounter.IsMountPointResult = true
+			mounter.IsMountPointPartitionPath = "/dev/fake-new-disk1"
+			statePath = "/fake-dir/bosh/persistent_disk_migration.json"
+
+			fs.WriteFileString("/from/path/data/file", "fake-contents")
+			fs.WriteFileString("/to/path/data/file", "fake-contents")
 		})
 
 		It("migrate persistent disk", func() {
-			err := platform.MigratePersistentDisk("/from/path", "/to/path")
+			_, err := platform.MigratePersistentDisk("/from/path", "/to/path")
 			Expect(err).ToNot(HaveOccurred())
 
 			Expect(mounter.RemountAsReadonlyPath).To(Equal("/from/path"))
 
-			Expect(len(cmdRunner.RunCommands)).To(Equal(1))
-			Expect(cmdRunner.RunCommands[0]).To(Equal([]string{"sh", "-c", "(tar -C /from/path -cf - .) | (tar -C /to/path -xpf -)"}))
+			Expect(len(cmdRunner.RunComplexCommands)).To(Equal(1))
+			Expect(cmdRunner.RunComplexCommands[0].Name).To(Equal("rsync"))
+			Expect(strings.Join(append([]string{"rsync"}, cmdRunner.RunComplexCommands[0].Args...), " ")).To(Equal(rsyncCmd))
 
 			Expect(mounter.UnmountPartitionPathOrMountPoint).To(Equal("/from/path"))
 			Expect(mounter.RemountFromMountPoint).To(Equal("/to/path"))
 			Expect(mounter.RemountToMountPoint).To(Equal("/from/path"))
+
+			Expect(fs.FileExists(statePath)).To(BeFalse())
+		})
+
+		It("returns number of files and bytes verified on new disk", func() {
+			fs.WriteFileString("/from/path/data/other-file", "fake-other-contents")
+			fs.WriteFileString("/to/path/data/other-file", "fake-other-contents")
+
+			totals, err := platform.MigratePersistentDisk("/from/path", "/to/path")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(totals).To(Equal(DiskMigrationTotals{FilesCopied: 2, BytesCopied: 32}))
+		})
+
+		It("returns totals recorded when files were verified before migration was interrupted", func() {
+			fs.WriteFileString(statePath, `{"from_mount_point":"/from/path","to_mount_point":"/to/path","phase":"verified","files_copied":5,"bytes_copied":500}`)
+
+			totals, err := platform.MigratePersistentDisk("/from/path", "/to/path")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(totals).To(Equal(DiskMigrationTotals{FilesCopied: 5, BytesCopied: 500}))
+		})
+
+		Context("when disks are mounted within old disk", func() {
+			BeforeEach(func() {
+				diskManager.FakeMountsSearcher.SearchMountsMounts = []boshdisk.Mount{
+					{PartitionPath: "/dev/fake-old-disk1", MountPoint: "/from/path"},
+					{PartitionPath: "/dev/fake-new-disk1", MountPoint: "/to/path"},
+					{PartitionPath: "/dev/fake-associated-disk1", MountPoint: "/from/path/associated"},
+					{PartitionPath: "/dev/fake-nested-disk1", MountPoint: "/from/path/associated/nested"},
+				}
+
+				// Only empty mount point is left on old disk once nested disks are unmounted
+				fs.WriteFileString("/from/path/associated/nested/file", "fake-nested-contents")
+				fs.MkdirAll("/to/path/associated", os.FileMode(0750))
+				mounter.UnmountStub = func(mountPoint string) (bool, error) {
+					if mountPoint == "/from/path/associated" {
+						fs.RemoveAll(mountPoint)
+						fs.MkdirAll(mountPoint, os.FileMode(0750))
+					}
+					return true, nil
+				}
+			})
+
+			It("unmounts them before copying and mounts them again on top of new disk", func() {
+				_, err := platform.MigratePersistentDisk("/from/path", "/to/path")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(mounter.UnmountPartitionPathsOrMountPoints).To(Equal([]string{
+					"/from/path/associated/nested",
+					"/from/path/associated",
+					"/from/path",
+				}))
+				Expect(mounter.RemountToMountPoint).To(Equal("/from/path"))
+				Expect(mounter.MountPartitionPaths).To(Equal([]string{"/dev/fake-associated-disk1", "/dev/fake-nested-disk1"}))
+				Expect(mounter.MountMountPoints).To(Equal([]string{"/from/path/associated", "/from/path/associated/nested"}))
+
+				Expect(fs.FileExists(statePath)).To(BeFalse())
+			})
+
+			It("mounts them again with mount options they were mounted with", func() {
+				fs.WriteFileString("/fake-dir/bosh/associated_disk_mount_options.json", `{"/from/path/associated":["noatime","discard"]}`)
+
+				_, err := platform.MigratePersistentDisk("/from/path", "/to/path")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(mounter.MountMountPoints).To(Equal([]string{"/from/path/associated", "/from/path/associated/nested"}))
+				Expect(mounter.MountMountOptions).To(Equal([][]string{{"-o", "noatime,discard"}, nil}))
+			})
+
+			It("mounts them again when resuming interrupted migration", func() {
+				mounter.UnmountStub = func(string) (bool, error) {
+					return false, errors.New("fake-unmount-err")
+				}
+
+				_, err := platform.MigratePersistentDisk("/from/path", "/to/path")
+				Expect(err).To(HaveOccurred())
+
+				// Nested disks are not mounted anymore after reboot
+				mounter.UnmountStub = nil
+				diskManager.FakeMountsSearcher.SearchMountsMounts = nil
+				fs.RemoveAll("/from/path/associated")
+				fs.MkdirAll("/from/path/associated", os.FileMode(0750))
+
+				_, err = platform.MigratePersistentDisk("/from/path", "/to/path")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(mounter.MountMountPoints).To(Equal([]string{"/from/path/associated", "/from/path/associated/nested"}))
+			})
+
+			It("keeps their mount options when resuming interrupted migration", func() {
+				fs.WriteFileString("/fake-dir/bosh/associated_disk_mount_options.json", `{"/from/path/associated":["noatime"]}`)
+				mounter.UnmountStub = func(string) (bool, error) {
+					return false, errors.New("fake-unmount-err")
+				}
+
+				_, err := platform.MigratePersistentDisk("/from/path", "/to/path")
+				Expect(err).To(HaveOccurred())
+
+				mounter.UnmountStub = nil
+				diskManager.FakeMountsSearcher.SearchMountsMounts = nil
+				fs.RemoveAll("/fake-dir/bosh/associated_disk_mount_options.json")
+				fs.RemoveAll("/from/path/associated")
+				fs.MkdirAll("/from/path/associated", os.FileMode(0750))
+
+				_, err = platform.MigratePersistentDisk("/from/path", "/to/path")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(mounter.MountMountOptions).To(Equal([][]string{{"-o", "noatime"}, nil}))
+			})
+		})
+
+		It("returns error and does not switch disks when copied files do not match", func() {
+			fs.WriteFileString("/to/path/data/file", "fake-truncated")
+
+			_, err := platform.MigratePersistentDisk("/from/path", "/to/path")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Verifying files copied from old disk to new disk"))
+
+			Expect(mounter.UnmountPartitionPathOrMountPoint).To(BeEmpty())
+			Expect(fs.FileExists(statePath)).To(BeTrue())
+		})
+
+		It("returns error and does not switch disks when a file is missing on the new disk", func() {
+			fs.WriteFileString("/from/path/data/other-file", "")
+			fs.WriteFileString("/to/path/data/different-file", "")
+
+			_, err := platform.MigratePersistentDisk("/from/path", "/to/path")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Missing 'data/other-file' on new persistent disk"))
+
+			Expect(mounter.UnmountPartitionPathOrMountPoint).To(BeEmpty())
+		})
+
+		It("returns error and does not switch disks when copying fails", func() {
+			cmdRunner.AddCmdResult(rsyncCmd, fakesys.FakeCmdResult{Error: errors.New("fake-rsync-err")})
+
+			_, err := platform.MigratePersistentDisk("/from/path", "/to/path")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-rsync-err"))
+
+			Expect(mounter.UnmountPartitionPathOrMountPoint).To(BeEmpty())
+		})
+
+		It("records the number of files and bytes verified on new disk", func() {
+			cmdRunner.AddCmdResult(rsyncCmd, fakesys.FakeCmdResult{
+				Stdout: "    6,500  50%  1.00MB/s  0:00:01 (xfr#1, to-chk=1/3)\r" +
+					"   13,000 100%  1.00MB/s  0:00:01 (xfr#2, to-chk=0/3)\n\n" +
+					"Number of regular files transferred: 2\n" +
+					"Total transferred file size: 13,000 bytes\n",
+			})
+			fs.WriteFileString("/from/path/data/other-file", "fake-other-contents")
+			fs.WriteFileString("/to/path/data/other-file", "fake-other-contents")
+			mounter.UnmountErr = errors.New("fake-unmount-err")
+
+			_, err := platform.MigratePersistentDisk("/from/path", "/to/path")
+			Expect(err).To(HaveOccurred())
+
+			contents, err := fs.ReadFileString(statePath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(contents).To(MatchJSON(`{
+				"from_mount_point": "/from/path",
+				"to_mount_point": "/to/path",
+				"phase": "verified",
+				"to_partition_path": "/dev/fake-new-disk1",
+				"files_copied": 2,
+				"bytes_copied": 32
+			}`))
+		})
+
+		It("records totals of whole disk when resumed copying transfers only remaining files", func() {
+			fs.WriteFileString(statePath, `{"from_mount_point":"/from/path","to_mount_point":"/to/path","phase":"copying","to_partition_path":"/dev/fake-new-disk1"}`)
+			cmdRunner.AddCmdResult(rsyncCmd, fakesys.FakeCmdResult{
+				Stdout: "Number of regular files transferred: 0\n" +
+					"Total transferred file size: 0 bytes\n",
+			})
+			mounter.UnmountErr = errors.New("fake-unmount-err")
+
+			_, err := platform.MigratePersistentDisk("/from/path", "/to/path")
+			Expect(err).To(HaveOccurred())
+
+			contents, err := fs.ReadFileString(statePath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(contents).To(MatchJSON(`{
+				"from_mount_point": "/from/path",
+				"to_mount_point": "/to/path",
+				"phase": "verified",
+				"to_partition_path": "/dev/fake-new-disk1",
+				"files_copied": 1,
+				"bytes_copied": 13
+			}`))
+		})
+
+		It("keeps mount options of new disk recorded when it was mounted", func() {
+			fs.WriteFileString(statePath, `{"from_mount_point":"/from/path","to_mount_point":"/to/path","phase":"","mount_options":["noatime","discard"]}`)
+
+			_, err := platform.MigratePersistentDisk("/from/path", "/to/path")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(len(cmdRunner.RunComplexCommands)).To(Equal(1))
+			Expect(mounter.RemountFromMountPoint).To(Equal("/to/path"))
+			Expect(mounter.RemountToMountPoint).To(Equal("/from/path"))
+			Expect(mounter.RemountMountOptions).To(Equal([]string{"-o", "noatime,discard"}))
+		})
+
+		It("returns error and does not copy files when new disk is not mounted", func() {
+			mounter.IsMountPointResult = false
+
+			_, err := platform.MigratePersistentDisk("/from/path", "/to/path")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("New persistent disk is not mounted at /to/path"))
+
+			Expect(mounter.IsMountPointPath).To(Equal("/to/path"))
+			Expect(cmdRunner.RunComplexCommands).To(BeEmpty())
+			Expect(fs.FileExists(statePath)).To(BeFalse())
+		})
+
+		Context("when checksum verification is enabled", func() {
+			BeforeEach(func() {
+				options.VerifyPersistentDiskMigrationChecksums = true
+			})
+
+			It("migrates persistent disk when contents match", func() {
+				_, err := platform.MigratePersistentDisk("/from/path", "/to/path")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(mounter.RemountToMountPoint).To(Equal("/from/path"))
+			})
+
+			It("returns error when contents differ", func() {
+				fs.WriteFileString("/to/path/data/file", "fake-contentz")
+
+				_, err := platform.MigratePersistentDisk("/from/path", "/to/path")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Verifying 'data/file'"))
+				Expect(mounter.UnmountPartitionPathOrMountPoint).To(BeEmpty())
+			})
+		})
+
+		Context("when a previous migration was interrupted", func() {
+			It("resumes copying when files were not verified yet", func() {
+				fs.WriteFileString(statePath, `{"from_mount_point":"/from/path","to_mount_point":"/to/path","phase":"copying"}`)
+
+				_, err := platform.MigratePersistentDisk("/from/path", "/to/path")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(len(cmdRunner.RunComplexCommands)).To(Equal(1))
+				Expect(mounter.RemountToMountPoint).To(Equal("/from/path"))
+				Expect(fs.FileExists(statePath)).To(BeFalse())
+			})
+
+			It("only switches disks when files were already verified", func() {
+				fs.WriteFileString(statePath, `{"from_mount_point":"/from/path","to_mount_point":"/to/path","phase":"verified"}`)
+
+				_, err := platform.MigratePersistentDisk("/from/path", "/to/path")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(cmdRunner.RunComplexCommands).To(BeEmpty())
+				Expect(mounter.RemountAsReadonlyPath).To(BeEmpty())
+				Expect(mounter.RemountFromMountPoint).To(Equal("/to/path"))
+				Expect(mounter.RemountToMountPoint).To(Equal("/from/path"))
+				Expect(fs.FileExists(statePath)).To(BeFalse())
+			})
+
+			Context("when agent restarted and new disk is no longer mounted", func() {
+				BeforeEach(func() {
+					mounter.IsMountPointResult = false
+				})
+
+				It("mounts new disk again before copying files", func() {
+					fs.WriteFileString(statePath, `{"from_mount_point":"/from/path","to_mount_point":"/to/path","phase":"copying","to_partition_path":"/dev/fake-new-disk1","mount_options":["noatime"]}`)
+
+					_, err := platform.MigratePersistentDisk("/from/path", "/to/path")
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(mounter.MountPartitionPaths).To(Equal([]string{"/dev/fake-new-disk1"}))
+					Expect(mounter.MountMountPoints).To(Equal([]string{"/to/path"}))
+					Expect(mounter.MountMountOptions).To(Equal([][]string{{"-o", "noatime"}}))
+					Expect(mounter.RemountMountOptions).To(Equal([]string{"-o", "noatime"}))
+					Expect(len(cmdRunner.RunComplexCommands)).To(Equal(1))
+					Expect(mounter.RemountFromMountPoint).To(Equal("/to/path"))
+					Expect(mounter.RemountToMountPoint).To(Equal("/from/path"))
+				})
+
+				It("mounts new disk again before switching disks when files were already verified", func() {
+					fs.WriteFileString(statePath, `{"from_mount_point":"/from/path","to_mount_point":"/to/path","phase":"verified","to_partition_path":"/dev/fake-new-disk1"}`)
+
+					_, err := platform.MigratePersistentDisk("/from/path", "/to/path")
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(mounter.MountPartitionPaths).To(Equal([]string{"/dev/fake-new-disk1"}))
+					Expect(cmdRunner.RunComplexCommands).To(BeEmpty())
+					Expect(mounter.RemountToMountPoint).To(Equal("/from/path"))
+				})
+
+				It("returns error without copying or switching disks when new disk partition is unknown", func() {
+					fs.WriteFileString(statePath, `{"from_mount_point":"/from/path","to_mount_point":"/to/path","phase":"verified"}`)
+
+					_, err := platform.MigratePersistentDisk("/from/path", "/to/path")
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("New persistent disk is not mounted at /to/path"))
+
+					Expect(cmdRunner.RunComplexCommands).To(BeEmpty())
+					Expect(mounter.UnmountPartitionPathOrMountPoint).To(BeEmpty())
+					Expect(fs.FileExists(statePath)).To(BeTrue())
+				})
+
+				It("returns error when mounting new disk again fails", func() {
+					fs.WriteFileString(statePath, `{"from_mount_point":"/from/path","to_mount_point":"/to/path","phase":"copying","to_partition_path":"/dev/fake-new-disk1"}`)
+					mounter.MountErr = errors.New("fake-mount-err")
+
+					_, err := platform.MigratePersistentDisk("/from/path", "/to/path")
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("fake-mount-err"))
+
+					Expect(cmdRunner.RunComplexCommands).To(BeEmpty())
+				})
+			})
+
+			It("returns error when other device is mounted at new disk mount point", func() {
+				fs.WriteFileString(statePath, `{"from_mount_point":"/from/path","to_mount_point":"/to/path","phase":"copying","to_partition_path":"/dev/fake-other-disk1"}`)
+
+				_, err := platform.MigratePersistentDisk("/from/path", "/to/path")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Found /dev/fake-new-disk1 mounted at /to/path instead of new persistent disk /dev/fake-other-disk1"))
+
+				Expect(cmdRunner.RunComplexCommands).To(BeEmpty())
+			})
+
+			It("starts over when state was recorded for other mount points", func() {
+				fs.WriteFileString(statePath, `{"from_mount_point":"/other/from","to_mount_point":"/other/to","phase":"verified"}`)
+
+				_, err := platform.MigratePersistentDisk("/from/path", "/to/path")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(len(cmdRunner.RunComplexCommands)).To(Equal(1))
+				Expect(mounter.RemountAsReadonlyPath).To(Equal("/from/path"))
+			})
+		})
+	})
+
+	Describe("FreezeFilesystem", func() {
+		It("freezes filesystem at mount point", func() {
+			err := platform.FreezeFilesystem("/fake-mount-point")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{{"fsfreeze", "--freeze", "/fake-mount-point"}}))
+		})
+
+		It("returns error when freezing fails", func() {
+			cmdRunner.AddCmdResult("fsfreeze --freeze /fake-mount-point", fakesys.FakeCmdResult{Error: errors.New("fake-fsfreeze-err")})
+
+			err := platform.FreezeFilesystem("/fake-mount-point")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-fsfreeze-err"))
+		})
+	})
+
+	Describe("ThawFilesystem", func() {
+		It("thaws filesystem at mount point", func() {
+			err := platform.ThawFilesystem("/fake-mount-point")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{{"fsfreeze", "--unfreeze", "/fake-mount-point"}}))
+		})
+
+		It("returns error when thawing fails", func() {
+			cmdRunner.AddCmdResult("fsfreeze --unfreeze /fake-mount-point", fakesys.FakeCmdResult{Error: errors.New("fake-fsfreeze-err")})
+
+			err := platform.ThawFilesystem("/fake-mount-point")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-fsfreeze-err"))
 		})
 	})
 
@@ -2926,6 +4078,46 @@ unit: sectors
 				Expect(isMounted).To(Equal(true))
 			})
 		})
+
+		Context("when native partitioner is configured", func() {
+			var (
+				partitioner  *fakedisk.FakePartitioner
+				diskSettings boshsettings.DiskSettings
+			)
+
+			BeforeEach(func() {
+				options.PartitionerType = "native"
+				partitioner = diskManager.FakePartitioner
+				diskSettings = boshsettings.DiskSettings{Path: "/fake/device"}
+			})
+
+			It("returns true when drive has partitions without shelling out to sfdisk", func() {
+				partitioner.GetPartitionsPartitions["/fake/device"] = []boshdisk.Partition{
+					{SizeInBytes: 5997984 * 512, Type: boshdisk.PartitionTypeLinux},
+				}
+
+				isMountable, err := platform.IsPersistentDiskMountable(diskSettings)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(isMountable).To(BeTrue())
+
+				Expect(partitioner.GetPartitionsDevicePaths).To(Equal([]string{"/fake/device"}))
+				Expect(cmdRunner.RunCommands).To(BeEmpty())
+			})
+
+			It("returns false when there is no partition on drive", func() {
+				isMountable, err := platform.IsPersistentDiskMountable(diskSettings)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(isMountable).To(BeFalse())
+			})
+
+			It("returns error when partition table cannot be read", func() {
+				partitioner.GetPartitionsErr = errors.New("fake-get-partitions-err")
+
+				_, err := platform.IsPersistentDiskMountable(diskSettings)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-get-partitions-err"))
+			})
+		})
 	})
 
 	Describe("StartMonit", func() {
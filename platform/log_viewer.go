@@ -0,0 +1,79 @@
+package platform
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"text/template"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// LogViewerFileName is the helper in bosh bin dir through which restricted
+// ssh users read job logs; sudo itself cannot prevent commands such as
+// cat from following symlinks placed in logs dir to arbitrary files
+const LogViewerFileName = "bosh-view-log"
+
+const logViewerPermissions = os.FileMode(0755)
+
+// Path is resolved before being opened and the opened file is checked
+// again through /proc so that it cannot be swapped in between
+const logViewerTemplate = `#!/bin/bash
+# Generated by bosh-agent
+
+set -e
+
+logs_dir="{{ .LogsDir }}"
+
+if [ $# -ne 1 ]; then
+  echo "Usage: $0 <path>" >&2
+  exit 1
+fi
+
+deny() {
+  echo "$0: $1 is not within $logs_dir" >&2
+  exit 1
+}
+
+resolved="$(readlink -e -- "$1")" || deny "$1"
+case "$resolved" in "$logs_dir"/*) ;; *) deny "$1" ;; esac
+
+exec 3<"$resolved"
+
+opened="$(readlink "/proc/$$/fd/3")"
+case "$opened" in "$logs_dir"/*) ;; *) deny "$1" ;; esac
+
+if [ -d "/proc/$$/fd/3" ]; then
+  exec /bin/ls -l "/proc/$$/fd/3/"
+fi
+
+exec /bin/cat <&3
+`
+
+func (p linux) setupLogViewer() error {
+	buffer := bytes.NewBuffer([]byte{})
+	t := template.Must(template.New("log-viewer").Parse(logViewerTemplate))
+
+	type logViewerArgs struct {
+		LogsDir string
+	}
+
+	err := t.Execute(buffer, logViewerArgs{p.dirProvider.LogsDir()})
+	if err != nil {
+		return bosherr.WrapError(err, "Generating log viewer")
+	}
+
+	logViewerPath := path.Join(p.dirProvider.BoshBinDir(), LogViewerFileName)
+
+	_, err = p.fs.ConvergeFileContents(logViewerPath, buffer.Bytes())
+	if err != nil {
+		return bosherr.WrapError(err, "Writing log viewer")
+	}
+
+	err = p.fs.Chmod(logViewerPath, logViewerPermissions)
+	if err != nil {
+		return bosherr.WrapError(err, "Chmoding log viewer")
+	}
+
+	return nil
+}
@@ -50,6 +50,11 @@ func NewCentosNetManager(
 	}
 }
 
+// CentosNetManagerCommands lists external commands centosNetManager shells out to
+func CentosNetManagerCommands() []string {
+	return []string{"service", "arping"}
+}
+
 func (net centosNetManager) SetupNetworking(networks boshsettings.Networks, errCh chan error) error {
 	nonVipNetworks := boshsettings.Networks{}
 	for networkName, networkSettings := range networks {
@@ -101,6 +106,50 @@ func (net centosNetManager) SetupNetworking(networks boshsettings.Networks, errC
 	return nil
 }
 
+func (net centosNetManager) SetupDNS(networks boshsettings.Networks) error {
+	nonVipNetworks := boshsettings.Networks{}
+	for networkName, networkSettings := range networks {
+		if networkSettings.IsVIP() {
+			continue
+		}
+		nonVipNetworks[networkName] = networkSettings
+	}
+
+	staticInterfaceConfigurations, dhcpInterfaceConfigurations, err := net.buildInterfaces(nonVipNetworks)
+	if err != nil {
+		return err
+	}
+
+	dnsNetwork, _ := nonVipNetworks.DefaultNetworkFor("dns")
+	dnsServers := dnsNetwork.DNS
+
+	// Network service is not restarted; configuration is only kept in sync
+	// so that it does not revert DNS servers when interfaces come up again
+	_, err = net.writeNetworkInterfaces(dhcpInterfaceConfigurations, staticInterfaceConfigurations, dnsServers)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing network configuration")
+	}
+
+	if len(dhcpInterfaceConfigurations) > 0 {
+		_, err = net.writeDHCPConfiguration(dnsServers, dhcpInterfaceConfigurations)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = net.writeResolvConf(dnsServers)
+	if err != nil {
+		return err
+	}
+
+	err = net.dnsValidator.Validate(dnsServers)
+	if err != nil {
+		return bosherr.WrapError(err, "Validating dns configuration")
+	}
+
+	return nil
+}
+
 func (net centosNetManager) GetConfiguredNetworkInterfaces() ([]string, error) {
 	interfaces := []string{}
 
@@ -241,6 +290,33 @@ func (net centosNetManager) restartNetworkingInterfaces() {
 	}
 }
 
+const centosResolvConfTemplate = `# Generated by bosh-agent
+{{ range . }}nameserver {{ . }}
+{{ end }}`
+
+// writeResolvConf rewrites resolv.conf the way network scripts do on ifup
+// with PEERDNS=no; it is left alone when there are no DNS servers to apply
+func (net centosNetManager) writeResolvConf(dnsServers []string) error {
+	if len(dnsServers) == 0 {
+		return nil
+	}
+
+	buffer := bytes.NewBuffer([]byte{})
+	t := template.Must(template.New("resolv-conf").Parse(centosResolvConfTemplate))
+
+	err := t.Execute(buffer, dnsServers)
+	if err != nil {
+		return bosherr.WrapError(err, "Generating DNS config from template")
+	}
+
+	err = net.fs.WriteFile("/etc/resolv.conf", buffer.Bytes())
+	if err != nil {
+		return bosherr.WrapError(err, "Writing to /etc/resolv.conf")
+	}
+
+	return nil
+}
+
 // DHCP Config file - /etc/dhcp3/dhclient.conf
 const centosDHCPConfigTemplate = `# Generated by bosh-agent
 
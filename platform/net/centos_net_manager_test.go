@@ -586,6 +586,51 @@ DNS1=8.8.8.8
 
 	})
 
+	Describe("SetupDNS", func() {
+		var staticNetwork boshsettings.Network
+
+		BeforeEach(func() {
+			staticNetwork = boshsettings.Network{
+				Type:    "manual",
+				IP:      "1.2.3.4",
+				Default: []string{"dns", "gateway"},
+				DNS:     []string{"10.0.0.2", "10.0.0.3"},
+				Netmask: "255.255.255.0",
+				Gateway: "3.4.5.6",
+				Mac:     "fake-static-mac-address",
+			}
+
+			fs.SetGlob("/sys/class/net/*", []string{writeNetworkDevice("ethstatic", staticNetwork.Mac, true)})
+			fs.WriteFileString("/etc/resolv.conf", "nameserver 8.8.8.8\n")
+		})
+
+		It("rewrites /etc/resolv.conf without restarting network", func() {
+			err := netManager.SetupDNS(boshsettings.Networks{"static-network": staticNetwork})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.ReadFileString("/etc/resolv.conf")).To(Equal(`# Generated by bosh-agent
+nameserver 10.0.0.2
+nameserver 10.0.0.3
+`))
+			Expect(cmdRunner.RunCommands).To(BeEmpty())
+		})
+
+		It("keeps DNS servers in network scripts in sync", func() {
+			err := netManager.SetupDNS(boshsettings.Networks{"static-network": staticNetwork})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.ReadFileString("/etc/sysconfig/network-scripts/ifcfg-ethstatic")).To(HaveSuffix("DNS1=10.0.0.2\nDNS2=10.0.0.3\n"))
+		})
+
+		It("returns error when writing /etc/resolv.conf fails", func() {
+			fs.WriteFileErrors["/etc/resolv.conf"] = errors.New("fake-write-error")
+
+			err := netManager.SetupDNS(boshsettings.Networks{"static-network": staticNetwork})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Writing to /etc/resolv.conf: fake-write-error"))
+		})
+	})
+
 	Describe("GetConfiguredNetworkInterfaces", func() {
 		Context("when there are network devices", func() {
 			BeforeEach(func() {
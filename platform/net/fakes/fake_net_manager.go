@@ -10,6 +10,9 @@ type FakeManager struct {
 	SetupNetworkingNetworks boshsettings.Networks
 	SetupNetworkingErr      error
 
+	SetupDNSNetworks boshsettings.Networks
+	SetupDNSErr      error
+
 	GetConfiguredNetworkInterfacesInterfaces []string
 	GetConfiguredNetworkInterfacesErr        error
 
@@ -22,6 +25,11 @@ func (net *FakeManager) SetupNetworking(networks boshsettings.Networks, errCh ch
 	return net.SetupNetworkingErr
 }
 
+func (net *FakeManager) SetupDNS(networks boshsettings.Networks) error {
+	net.SetupDNSNetworks = networks
+	return net.SetupDNSErr
+}
+
 func (net *FakeManager) GetConfiguredNetworkInterfaces() ([]string, error) {
 	return net.GetConfiguredNetworkInterfacesInterfaces, net.GetConfiguredNetworkInterfacesErr
 }
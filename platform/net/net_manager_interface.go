@@ -10,6 +10,10 @@ type Manager interface {
 	// upon completion of background network reconfiguration (e.g. arping).
 	SetupNetworking(networks boshsettings.Networks, errCh chan error) error
 
+	// SetupDNS updates resolver configuration with DNS servers of networks
+	// without reconfiguring or restarting network interfaces.
+	SetupDNS(networks boshsettings.Networks) error
+
 	// Returns the list of interfaces that have configurations for them present
 	GetConfiguredNetworkInterfaces() ([]string, error)
 }
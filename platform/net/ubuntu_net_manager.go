@@ -50,6 +50,11 @@ func NewUbuntuNetManager(
 	}
 }
 
+// UbuntuNetManagerCommands lists external commands UbuntuNetManager shells out to
+func UbuntuNetManagerCommands() []string {
+	return []string{"ifup", "ifdown", "resolvconf", "pkill", "arping"}
+}
+
 // DHCP Config file - /etc/dhcp/dhclient.conf
 // Ubuntu 14.04 accepts several DNS as a list in a single prepend directive
 const ubuntuDHCPConfigTemplate = `# Generated by bosh-agent
@@ -135,6 +140,45 @@ func (net UbuntuNetManager) SetupNetworking(networks boshsettings.Networks, errC
 	return nil
 }
 
+func (net UbuntuNetManager) SetupDNS(networks boshsettings.Networks) error {
+	if networks.IsPreconfigured() {
+		return net.writeResolvConf(networks)
+	}
+
+	staticConfigs, dhcpConfigs, dnsServers, err := net.ComputeNetworkConfig(networks)
+	if err != nil {
+		return bosherr.WrapError(err, "Computing network configuration")
+	}
+
+	// Interfaces are not restarted; configuration is only kept in sync
+	// so that it does not revert DNS servers when interfaces come up again
+	_, err = net.writeNetworkInterfaces(dhcpConfigs, staticConfigs, dnsServers)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing network configuration")
+	}
+
+	if len(dhcpConfigs) > 0 {
+		_, err = net.writeDHCPConfiguration(dnsServers)
+		if err != nil {
+			return err
+		}
+	}
+
+	net.removeInterfaceDNSRecords(net.ifaceNames(dhcpConfigs, staticConfigs))
+
+	err = net.writeResolvConf(networks)
+	if err != nil {
+		return err
+	}
+
+	err = net.dnsValidator.Validate(dnsServers)
+	if err != nil {
+		return bosherr.WrapError(err, "Validating dns configuration")
+	}
+
+	return nil
+}
+
 func (net UbuntuNetManager) GetConfiguredNetworkInterfaces() ([]string, error) {
 	interfaces := []string{}
 
@@ -184,6 +228,19 @@ func (net UbuntuNetManager) removeDhcpDNSConfiguration() error {
 	return nil
 }
 
+// removeInterfaceDNSRecords deletes resolvconf records added by ifup and dhclient
+// which still hold previous DNS servers since interfaces were brought up
+func (net UbuntuNetManager) removeInterfaceDNSRecords(ifaceNames []string) {
+	for _, ifaceName := range ifaceNames {
+		for _, record := range []string{ifaceName + ".inet", ifaceName + ".dhclient"} {
+			_, _, _, err := net.cmdRunner.RunCommand("resolvconf", "-d", record)
+			if err != nil {
+				net.logger.Error(UbuntuNetManagerLogTag, "Ignoring failure calling 'resolvconf -d %s': %s", record, err)
+			}
+		}
+	}
+}
+
 func (net UbuntuNetManager) buildInterfaces(networks boshsettings.Networks) ([]StaticInterfaceConfiguration, []DHCPInterfaceConfiguration, error) {
 	interfacesByMacAddress, err := net.detectMacAddresses()
 	if err != nil {
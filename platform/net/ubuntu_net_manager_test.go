@@ -870,6 +870,59 @@ iface ethstatic inet static
 		})
 	})
 
+	Describe("SetupDNS", func() {
+		var staticNetwork boshsettings.Network
+
+		BeforeEach(func() {
+			staticNetwork = boshsettings.Network{
+				Type:    "manual",
+				IP:      "1.2.3.4",
+				Default: []string{"dns", "gateway"},
+				DNS:     []string{"10.0.0.2", "10.0.0.3"},
+				Netmask: "255.255.255.0",
+				Gateway: "3.4.5.6",
+				Mac:     "fake-static-mac-address",
+			}
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethstatic": staticNetwork,
+			})
+
+			fs.WriteFileString("/etc/resolv.conf", "nameserver 10.0.0.2\n")
+		})
+
+		It("updates resolver configuration without restarting interfaces", func() {
+			err := netManager.SetupDNS(boshsettings.Networks{"static-network": staticNetwork})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.ReadFileString("/etc/resolvconf/resolv.conf.d/base")).To(Equal(`# Generated by bosh-agent
+nameserver 10.0.0.2
+nameserver 10.0.0.3
+`))
+
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{
+				{"resolvconf", "-d", "ethstatic.inet"},
+				{"resolvconf", "-d", "ethstatic.dhclient"},
+				{"resolvconf", "-u"},
+			}))
+		})
+
+		It("keeps DNS servers in network interfaces configuration in sync", func() {
+			err := netManager.SetupDNS(boshsettings.Networks{"static-network": staticNetwork})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.ReadFileString("/etc/network/interfaces")).To(HaveSuffix("dns-nameservers 10.0.0.2 10.0.0.3"))
+		})
+
+		It("returns error when new DNS servers are not found in /etc/resolv.conf", func() {
+			fs.WriteFileString("/etc/resolv.conf", "nameserver 8.8.8.8\n")
+
+			err := netManager.SetupDNS(boshsettings.Networks{"static-network": staticNetwork})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Validating dns configuration"))
+		})
+	})
+
 	Describe("GetConfiguredNetworkInterfaces", func() {
 		Context("when there are network devices", func() {
 			BeforeEach(func() {
@@ -68,6 +68,11 @@ func NewWindowsNetManager(
 	}
 }
 
+// WindowsNetManagerCommands lists external commands WindowsNetManager shells out to
+func WindowsNetManagerCommands() []string {
+	return []string{"powershell"}
+}
+
 const (
 	SetDNSTemplate = `
 [array]$interfaces = Get-DNSClientServerAddress
@@ -141,6 +146,15 @@ func (net WindowsNetManager) SetupNetworking(networks boshsettings.Networks, err
 	return dns
 }
 
+func (net WindowsNetManager) SetupDNS(networks boshsettings.Networks) error {
+	_, _, dnsServers, err := net.ComputeNetworkConfig(networks)
+	if err != nil {
+		return bosherr.WrapError(err, "Computing network configuration")
+	}
+
+	return net.setupDNS(dnsServers)
+}
+
 func (net WindowsNetManager) setupInterfaces(staticConfigs []StaticInterfaceConfiguration) error {
 	for _, conf := range staticConfigs {
 		var gateway string
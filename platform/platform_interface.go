@@ -13,6 +13,14 @@ import (
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 )
 
+// ManagedDiskSettingsFileName records ID of persistent disk
+// which is mounted as store dir; it is kept in bosh dir
+const ManagedDiskSettingsFileName = "managed_disk_settings.json"
+
+// FrozenDiskFileName records frozen mount points, one per line,
+// so that bootstrap can thaw them after agent restart; it is kept in bosh dir
+const FrozenDiskFileName = "frozen_persistent_disk"
+
 type AuditLogger interface {
 	Debug(string)
 	Err(string)
@@ -37,15 +45,22 @@ type Platform interface {
 	// User management
 	CreateUser(username, password, basePath string) (err error)
 	AddUserToGroups(username string, groups []string) (err error)
+	SetupUserSudoCommands(username string, commands []string) (err error)
 	DeleteEphemeralUsersMatching(regex string) (err error)
 
 	// Bootstrap functionality
 	SetupRootDisk(ephemeralDiskPath string) (err error)
 	SetupSSH(publicKey []string, username string) (err error)
+	SetupSSHCertificateAuthority(trustedUserCAKeys []string, principals map[string][]string) (err error)
+	UpdateRevokedSSHKeys(revokedKeys []string) (err error)
 	SetUserPassword(user, encryptedPwd string) (err error)
 	SetupHostname(hostname string) (err error)
 	SetupNetworking(networks boshsettings.Networks) (err error)
+	SetupDNS(networks boshsettings.Networks) (err error)
 	SetupLogrotate(groupName, basePath, size string) (err error)
+	SetupJobSecretsStaging(secretSizes []uint64) (stagingDir string, err error)
+	SetupJobSecrets(jobName string, secrets map[string][]byte) (secretsDir string, err error)
+	RemoveJobSecrets() (err error)
 	SetTimeWithNtpServers(servers []string) (err error)
 	SetupEphemeralDiskWithPath(devicePath string, desiredSwapSizeInBytes *uint64) (err error)
 	SetupRawEphemeralDisks(devices []boshsettings.DiskSettings) (err error)
@@ -61,13 +76,16 @@ type Platform interface {
 
 	// Disk management
 	MountPersistentDisk(diskSettings boshsettings.DiskSettings, mountPoint string) error
+	MountAssociatedPersistentDisk(diskSettings boshsettings.DiskSettings, mountPoint string) error
 	UnmountPersistentDisk(diskSettings boshsettings.DiskSettings) (didUnmount bool, err error)
-	MigratePersistentDisk(fromMountPoint, toMountPoint string) (err error)
+	MigratePersistentDisk(fromMountPoint, toMountPoint string) (totals DiskMigrationTotals, err error)
 	GetEphemeralDiskPath(diskSettings boshsettings.DiskSettings) string
 	IsMountPoint(path string) (partitionPath string, result bool, err error)
 	IsPersistentDiskMounted(diskSettings boshsettings.DiskSettings) (result bool, err error)
 	IsPersistentDiskMountable(diskSettings boshsettings.DiskSettings) (bool, error)
 	AssociateDisk(name string, settings boshsettings.DiskSettings) error
+	FreezeFilesystem(mountPoint string) error
+	ThawFilesystem(mountPoint string) error
 
 	GetFileContentsFromCDROM(filePath string) (contents []byte, err error)
 	GetFilesContentsFromDisk(diskPath string, fileNames []string) (contents [][]byte, err error)
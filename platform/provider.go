@@ -46,9 +46,7 @@ type Options struct {
 	Linux LinuxOptions
 }
 
-func NewProvider(logger boshlog.Logger, dirProvider boshdirs.Provider, statsCollector boshstats.Collector, fs boshsys.FileSystem, options Options, bootstrapState *BootstrapState, clock clock.Clock, auditLogger AuditLogger) Provider {
-	runner := boshsys.NewExecCmdRunner(logger)
-
+func NewProvider(logger boshlog.Logger, dirProvider boshdirs.Provider, statsCollector boshstats.Collector, fs boshsys.FileSystem, runner boshsys.CmdRunner, options Options, bootstrapState *BootstrapState, clock clock.Clock, auditLogger AuditLogger) Provider {
 	diskManagerOpts := boshdisk.LinuxDiskManagerOpts{
 		BindMount:       options.Linux.BindMountPersistentDisk,
 		PartitionerType: options.Linux.PartitionerType,
@@ -105,6 +103,11 @@ func NewProvider(logger boshlog.Logger, dirProvider boshdirs.Provider, statsColl
 		scsiVolumeIDPathResolver := devicepathresolver.NewSCSIVolumeIDDevicePathResolver(500*time.Millisecond, fs)
 		scsiLunPathResolver := devicepathresolver.NewSCSILunDevicePathResolver(50000*time.Millisecond, fs, logger)
 		devicePathResolver = devicepathresolver.NewScsiDevicePathResolver(scsiVolumeIDPathResolver, scsiIDPathResolver, scsiLunPathResolver)
+	case "nvme":
+		// Disks without volume or device ID, e.g. ephemeral disks, are resolved by path
+		nvmeDevicePathResolver := devicepathresolver.NewNVMeDevicePathResolver(30000*time.Millisecond, fs, logger)
+		mappedDevicePathResolver := devicepathresolver.NewMappedDevicePathResolver(30000*time.Millisecond, fs)
+		devicePathResolver = devicepathresolver.NewVirtioDevicePathResolver(nvmeDevicePathResolver, mappedDevicePathResolver, logger)
 	default:
 		devicePathResolver = devicepathresolver.NewIdentityDevicePathResolver()
 	}
@@ -0,0 +1,65 @@
+package platform
+
+import (
+	boshcert "github.com/cloudfoundry/bosh-agent/platform/cert"
+	boshdisk "github.com/cloudfoundry/bosh-agent/platform/disk"
+	boshnet "github.com/cloudfoundry/bosh-agent/platform/net"
+	bosherror "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// ComponentCommands lists external commands one of platform components shells out to
+type ComponentCommands struct {
+	Component string
+	Commands  []string
+
+	// Commands whose absence only disables a feature, e.g. growing root filesystem
+	OptionalCommands []string
+}
+
+// RequiredCommands returns external commands named platform together with
+// its net manager, disk manager and cert manager will shell out to
+// when configured with options
+func RequiredCommands(name string, options Options) ([]ComponentCommands, error) {
+	switch name {
+	case "ubuntu", "centos":
+		diskCommands, err := boshdisk.LinuxDiskManagerCommands(boshdisk.LinuxDiskManagerOpts{
+			BindMount:       options.Linux.BindMountPersistentDisk,
+			PartitionerType: options.Linux.PartitionerType,
+		})
+		if err != nil {
+			return nil, bosherror.WrapError(err, "Getting disk manager commands")
+		}
+
+		netCommands, certCommands := boshnet.UbuntuNetManagerCommands(), boshcert.UbuntuCertManagerCommands()
+		if name == "centos" {
+			netCommands, certCommands = boshnet.CentosNetManagerCommands(), boshcert.CentOSCertManagerCommands()
+		}
+
+		return []ComponentCommands{
+			{
+				Component:        "platform",
+				Commands:         linuxPlatformCommands(options.Linux),
+				OptionalCommands: linuxPlatformOptionalCommands(options.Linux),
+			},
+			{Component: "net_manager", Commands: netCommands},
+			{
+				Component:        "disk_manager",
+				Commands:         diskCommands,
+				OptionalCommands: boshdisk.LinuxDiskManagerOptionalCommands(),
+			},
+			{Component: "cert_manager", Commands: certCommands},
+		}, nil
+
+	case "windows":
+		return []ComponentCommands{
+			{Component: "platform", Commands: windowsCommands},
+			{Component: "net_manager", Commands: boshnet.WindowsNetManagerCommands()},
+			{Component: "cert_manager", Commands: boshcert.WindowsCertManagerCommands()},
+		}, nil
+
+	case "dummy":
+		return []ComponentCommands{}, nil
+	}
+
+	return nil, bosherror.Errorf("Platform %s could not be found", name)
+}
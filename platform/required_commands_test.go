@@ -0,0 +1,209 @@
+package platform_test
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform"
+)
+
+var _ = Describe("RequiredCommands", func() {
+	It("returns commands of ubuntu platform components", func() {
+		componentCommands, err := RequiredCommands("ubuntu", Options{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(componentCommands).To(HaveLen(4))
+
+		Expect(componentCommands[0].Component).To(Equal("platform"))
+		Expect(componentCommands[0].Commands).To(ContainElement("monit"))
+		Expect(componentCommands[0].Commands).To(ContainElement("sfdisk"))
+		Expect(componentCommands[1]).To(Equal(ComponentCommands{
+			Component: "net_manager",
+			Commands:  []string{"ifup", "ifdown", "resolvconf", "pkill", "arping"},
+		}))
+		Expect(componentCommands[2].Component).To(Equal("disk_manager"))
+		Expect(componentCommands[2].Commands).To(ContainElement("sfdisk"))
+		Expect(componentCommands[3]).To(Equal(ComponentCommands{
+			Component: "cert_manager",
+			Commands:  []string{"/usr/sbin/update-ca-certificates"},
+		}))
+	})
+
+	It("returns commands of centos platform components", func() {
+		componentCommands, err := RequiredCommands("centos", Options{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(componentCommands[1].Commands).To(Equal([]string{"service", "arping"}))
+		Expect(componentCommands[3].Commands).To(Equal([]string{"/usr/bin/update-ca-trust"}))
+	})
+
+	It("requires disk setup commands depending on options", func() {
+		componentCommands, err := RequiredCommands("ubuntu", Options{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(componentCommands[0].Commands).ToNot(ContainElement("mdadm"))
+
+		componentCommands, err = RequiredCommands("ubuntu", Options{Linux: LinuxOptions{StripeRawEphemeralDisks: true}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(componentCommands[0].Commands).To(ContainElement("mdadm"))
+
+		componentCommands, err = RequiredCommands("ubuntu", Options{Linux: LinuxOptions{SkipDiskSetup: true, StripeRawEphemeralDisks: true}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(componentCommands[0].OptionalCommands).To(BeEmpty())
+		Expect(componentCommands[0].Commands).ToNot(ContainElement("mdadm"))
+	})
+
+	It("does not require commands for growing root disk since it is skipped without growpart", func() {
+		componentCommands, err := RequiredCommands("ubuntu", Options{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(componentCommands[0].Commands).ToNot(ContainElement("growpart"))
+		Expect(componentCommands[0].Commands).ToNot(ContainElement("resize2fs"))
+		Expect(componentCommands[0].OptionalCommands).To(Equal([]string{"growpart", "resize2fs"}))
+	})
+
+	It("does not require sfdisk when parted partitioner is configured", func() {
+		componentCommands, err := RequiredCommands("ubuntu", Options{Linux: LinuxOptions{PartitionerType: "parted"}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(componentCommands[2].Commands).To(ContainElement("parted"))
+		Expect(componentCommands[2].Commands).ToNot(ContainElement("sfdisk"))
+	})
+
+	It("requires neither sfdisk nor parted when native partitioner is configured", func() {
+		componentCommands, err := RequiredCommands("ubuntu", Options{Linux: LinuxOptions{PartitionerType: "native"}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(componentCommands[0].Commands).ToNot(ContainElement("sfdisk"))
+		Expect(componentCommands[2].Commands).ToNot(ContainElement("parted"))
+		Expect(componentCommands[2].Commands).ToNot(ContainElement("sfdisk"))
+	})
+
+	It("returns error when partitioner type is unknown", func() {
+		_, err := RequiredCommands("ubuntu", Options{Linux: LinuxOptions{PartitionerType: "fake-partitioner"}})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Unknown partitioner type 'fake-partitioner'"))
+	})
+
+	It("lists every command linux platform components run", func() {
+		var listed []string
+
+		for _, options := range []Options{
+			{},
+			{Linux: LinuxOptions{PartitionerType: "parted", StripeRawEphemeralDisks: true}},
+		} {
+			componentCommands, err := RequiredCommands("ubuntu", options)
+			Expect(err).ToNot(HaveOccurred())
+
+			for _, cc := range componentCommands {
+				listed = append(listed, cc.Commands...)
+				listed = append(listed, cc.OptionalCommands...)
+			}
+		}
+
+		ranCommands, probedCommands := linuxSourceCommands(".")
+		Expect(ranCommands).ToNot(BeEmpty())
+
+		for _, command := range ranCommands {
+			// Commands probed before being run are fallbacks which may be missing
+			if probedCommands[command] {
+				continue
+			}
+
+			Expect(listed).To(ContainElement(command), "Command '%s' is run but not listed", command)
+		}
+	})
+
+	It("returns no commands for dummy platform", func() {
+		Expect(RequiredCommands("dummy", Options{})).To(BeEmpty())
+	})
+
+	It("returns error when platform is unknown", func() {
+		_, err := RequiredCommands("fake-platform", Options{})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("Platform fake-platform could not be found"))
+	})
+})
+
+// linuxSourceCommands returns names of commands which are run (and ones
+// which are probed with CommandExists) by non-windows sources under dir
+func linuxSourceCommands(dir string) ([]string, map[string]bool) {
+	var ranCommands []string
+	probedCommands := map[string]bool{}
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name := info.Name()
+
+		if info.IsDir() {
+			if name == "fakes" || name == "dryrun" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") ||
+			strings.Contains(name, "windows") || strings.HasPrefix(name, "dummy") {
+			return nil
+		}
+
+		file, err := parser.ParseFile(token.NewFileSet(), path, nil, 0)
+		if err != nil {
+			return err
+		}
+
+		ast.Inspect(file, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+
+			selector, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+
+			var argIndex int
+
+			switch selector.Sel.Name {
+			case "RunCommand", "RunCommandQuietly", "CommandExists":
+				argIndex = 0
+			case "RunCommandWithInput":
+				argIndex = 1
+			default:
+				return true
+			}
+
+			if len(call.Args) <= argIndex {
+				return true
+			}
+
+			// Commands held in variables cannot be resolved without running code
+			literal, ok := call.Args[argIndex].(*ast.BasicLit)
+			if !ok || literal.Kind != token.STRING {
+				return true
+			}
+
+			command, err := strconv.Unquote(literal.Value)
+			Expect(err).ToNot(HaveOccurred())
+
+			if selector.Sel.Name == "CommandExists" {
+				probedCommands[command] = true
+			} else {
+				ranCommands = append(ranCommands, command)
+			}
+
+			return true
+		})
+
+		return nil
+	})
+	Expect(err).ToNot(HaveOccurred())
+
+	return ranCommands, probedCommands
+}
@@ -0,0 +1,126 @@
+package platform
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+const (
+	sshdConfigPath            = "/etc/ssh/sshd_config"
+	sshTrustedUserCAKeysPath  = "/etc/ssh/bosh_trusted_user_ca_keys"
+	sshRevokedKeysPath        = "/etc/ssh/bosh_revoked_keys"
+	sshAuthorizedPrincipalDir = "/etc/ssh/bosh_auth_principals"
+
+	// Global options have to precede any Match block
+	// while Match blocks extend until the end of the file
+	sshdConfigGlobalBegin = "# BEGIN bosh-agent ssh certificate authority"
+	sshdConfigGlobalEnd   = "# END bosh-agent ssh certificate authority"
+	sshdConfigMatchBegin  = "# BEGIN bosh-agent ssh certificate principals"
+	sshdConfigMatchEnd    = "# END bosh-agent ssh certificate principals"
+)
+
+// Groups are ordered by privilege since sshd applies the first
+// matching AuthorizedPrincipalsFile to users belonging to both groups
+var sshPrincipalGroups = []string{boshsettings.SudoersGroup, boshsettings.SshersGroup}
+
+func validateSSHCertificateAuthority(caKeys []string, principals map[string][]string) error {
+	for _, caKey := range caKeys {
+		if err := validateSSHConfigLine(caKey); err != nil {
+			return bosherr.WrapError(err, "Validating trusted user CA key")
+		}
+	}
+
+	for group, groupPrincipals := range principals {
+		if group != boshsettings.SudoersGroup && group != boshsettings.SshersGroup {
+			return bosherr.Errorf("Mapping ssh principals to group '%s' is not supported", group)
+		}
+
+		for _, principal := range groupPrincipals {
+			if principal == "" || strings.ContainsAny(principal, " \t\r\n,") {
+				return bosherr.Errorf("Invalid ssh principal '%s'", principal)
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateSSHConfigLine(line string) error {
+	if strings.TrimSpace(line) == "" || strings.ContainsAny(line, "\r\n") {
+		return bosherr.Errorf("Expected '%s' to be a single non-empty line", line)
+	}
+
+	return nil
+}
+
+func sshAuthorizedPrincipalsPath(group string) string {
+	return path.Join(sshAuthorizedPrincipalDir, group)
+}
+
+// renderSSHDConfig returns sshd configuration with managed blocks replaced;
+// managed blocks are only removed when enabled is false
+func renderSSHDConfig(config string, enabled bool, principals map[string][]string) string {
+	config = removeSSHDConfigBlock(config, sshdConfigGlobalBegin, sshdConfigGlobalEnd)
+	config = removeSSHDConfigBlock(config, sshdConfigMatchBegin, sshdConfigMatchEnd)
+
+	if !enabled {
+		return config
+	}
+
+	var buf bytes.Buffer
+
+	fmt.Fprintln(&buf, sshdConfigGlobalBegin)
+	fmt.Fprintf(&buf, "TrustedUserCAKeys %s\n", sshTrustedUserCAKeysPath)
+	fmt.Fprintf(&buf, "RevokedKeys %s\n", sshRevokedKeysPath)
+	fmt.Fprintln(&buf, sshdConfigGlobalEnd)
+
+	buf.WriteString(config)
+
+	if len(config) > 0 && !strings.HasSuffix(config, "\n") {
+		buf.WriteString("\n")
+	}
+
+	var groups []string
+	for _, group := range sshPrincipalGroups {
+		if len(principals[group]) > 0 {
+			groups = append(groups, group)
+		}
+	}
+
+	if len(groups) > 0 {
+		fmt.Fprintln(&buf, sshdConfigMatchBegin)
+		for _, group := range groups {
+			fmt.Fprintf(&buf, "Match Group %s\n", group)
+			fmt.Fprintf(&buf, "  AuthorizedPrincipalsFile %s\n", sshAuthorizedPrincipalsPath(group))
+		}
+		fmt.Fprintln(&buf, sshdConfigMatchEnd)
+	}
+
+	return buf.String()
+}
+
+func removeSSHDConfigBlock(config, begin, end string) string {
+	var kept []string
+
+	inBlock := false
+
+	for _, line := range strings.SplitAfter(config, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == begin:
+			inBlock = true
+		case trimmed == end:
+			inBlock = false
+		case !inBlock:
+			kept = append(kept, line)
+		}
+	}
+
+	return strings.Join(kept, "")
+}
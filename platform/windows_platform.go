@@ -20,6 +20,9 @@ import (
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 )
 
+// windowsCommands lists external commands windows platform shells out to
+var windowsCommands = []string{"powershell.exe", "net", "w32tm", "arp"}
+
 type WindowsPlatform struct {
 	collector              boshstats.Collector
 	fs                     boshsys.FileSystem
@@ -67,6 +70,10 @@ func (p WindowsPlatform) AssociateDisk(name string, settings boshsettings.DiskSe
 	return errors.New("unimplemented")
 }
 
+func (p WindowsPlatform) MountAssociatedPersistentDisk(diskSettings boshsettings.DiskSettings, mountPoint string) error {
+	return errors.New("unimplemented")
+}
+
 func (p WindowsPlatform) GetFs() (fs boshsys.FileSystem) {
 	return p.fs
 }
@@ -111,6 +118,13 @@ func (p WindowsPlatform) AddUserToGroups(username string, groups []string) (err
 	return
 }
 
+func (p WindowsPlatform) SetupUserSudoCommands(username string, commands []string) (err error) {
+	if len(commands) > 0 {
+		return errors.New("unimplemented")
+	}
+	return
+}
+
 func (p WindowsPlatform) DeleteEphemeralUsersMatching(regex string) (err error) {
 	return
 }
@@ -123,6 +137,20 @@ func (p WindowsPlatform) SetupSSH(publicKey []string, username string) (err erro
 	return
 }
 
+func (p WindowsPlatform) SetupSSHCertificateAuthority(trustedUserCAKeys []string, principals map[string][]string) (err error) {
+	if len(trustedUserCAKeys) > 0 {
+		return errors.New("unimplemented")
+	}
+	return
+}
+
+func (p WindowsPlatform) UpdateRevokedSSHKeys(revokedKeys []string) (err error) {
+	if len(revokedKeys) > 0 {
+		return errors.New("unimplemented")
+	}
+	return
+}
+
 func (p WindowsPlatform) SetUserPassword(user, encryptedPwd string) (err error) {
 	return
 }
@@ -139,6 +167,10 @@ func (p WindowsPlatform) SetupNetworking(networks boshsettings.Networks) (err er
 	return p.netManager.SetupNetworking(networks, nil)
 }
 
+func (p WindowsPlatform) SetupDNS(networks boshsettings.Networks) (err error) {
+	return p.netManager.SetupDNS(networks)
+}
+
 func (p WindowsPlatform) GetConfiguredNetworkInterfaces() (interfaces []string, err error) {
 	return
 }
@@ -151,6 +183,21 @@ func (p WindowsPlatform) SetupLogrotate(groupName, basePath, size string) (err e
 	return
 }
 
+func (p WindowsPlatform) SetupJobSecretsStaging(secretSizes []uint64) (stagingDir string, err error) {
+	return "", errors.New("unimplemented")
+}
+
+func (p WindowsPlatform) SetupJobSecrets(jobName string, secrets map[string][]byte) (secretsDir string, err error) {
+	if len(secrets) > 0 {
+		return "", errors.New("unimplemented")
+	}
+	return
+}
+
+func (p WindowsPlatform) RemoveJobSecrets() (err error) {
+	return
+}
+
 func (p WindowsPlatform) SetTimeWithNtpServers(servers []string) (err error) {
 	if len(servers) == 0 {
 		return
@@ -261,10 +308,18 @@ func (p WindowsPlatform) GetFilesContentsFromDisk(diskPath string, fileNames []s
 	return
 }
 
-func (p WindowsPlatform) MigratePersistentDisk(fromMountPoint, toMountPoint string) (err error) {
+func (p WindowsPlatform) MigratePersistentDisk(fromMountPoint, toMountPoint string) (totals DiskMigrationTotals, err error) {
 	return
 }
 
+func (p WindowsPlatform) FreezeFilesystem(mountPoint string) error {
+	return errors.New("unimplemented")
+}
+
+func (p WindowsPlatform) ThawFilesystem(mountPoint string) error {
+	return errors.New("unimplemented")
+}
+
 func (p WindowsPlatform) IsMountPoint(path string) (string, bool, error) {
 	return "", true, nil
 }
@@ -0,0 +1,138 @@
+package settings
+
+import (
+	"reflect"
+)
+
+// Names of settings fields reported as changed by DiffSettings
+const (
+	SettingsFieldAgentID           = "agent_id"
+	SettingsFieldBlobstore         = "blobstore"
+	SettingsFieldDisks             = "disks"
+	SettingsFieldEnv               = "env"
+	SettingsFieldAuthorizedKeys    = "env.bosh.authorized_keys"
+	SettingsFieldTrustedUserCAKeys = "env.bosh.trusted_user_ca_keys"
+	SettingsFieldSSHPrincipals     = "env.bosh.ssh_principals"
+	SettingsFieldNetworks          = "networks"
+	SettingsFieldDNS               = "networks.dns"
+	SettingsFieldNtp               = "ntp"
+	SettingsFieldMbus              = "mbus"
+	SettingsFieldVM                = "vm"
+	SettingsFieldTrustedCerts      = "trusted_certs"
+)
+
+// DiffSettings returns names of fields that differ between settings.
+// Env fields which can be changed on their own are reported separately from
+// the rest of env; same goes for DNS servers and the rest of networks.
+func DiffSettings(current, desired Settings) []string {
+	var fields []string
+
+	diff := func(field string, currentValue, desiredValue interface{}) {
+		if !reflect.DeepEqual(currentValue, desiredValue) {
+			fields = append(fields, field)
+		}
+	}
+
+	diff(SettingsFieldAgentID, current.AgentID, desired.AgentID)
+	diff(SettingsFieldBlobstore, current.Blobstore, desired.Blobstore)
+	diff(SettingsFieldDisks, current.Disks, desired.Disks)
+	diff(SettingsFieldEnv, envWithoutSSHAccess(current.Env), envWithoutSSHAccess(desired.Env))
+	diff(SettingsFieldAuthorizedKeys, current.Env.Bosh.AuthorizedKeys, desired.Env.Bosh.AuthorizedKeys)
+	diff(SettingsFieldTrustedUserCAKeys, current.Env.Bosh.TrustedUserCAKeys, desired.Env.Bosh.TrustedUserCAKeys)
+	diff(SettingsFieldSSHPrincipals, current.Env.Bosh.SSHPrincipals, desired.Env.Bosh.SSHPrincipals)
+	diff(SettingsFieldNetworks, networksWithoutDNS(current.Networks), networksWithoutDNS(desired.Networks))
+	diff(SettingsFieldDNS, networksDNS(current.Networks), networksDNS(desired.Networks))
+	diff(SettingsFieldNtp, current.Ntp, desired.Ntp)
+	diff(SettingsFieldMbus, current.Mbus, desired.Mbus)
+	diff(SettingsFieldVM, current.VM, desired.VM)
+	diff(SettingsFieldTrustedCerts, current.TrustedCerts, desired.TrustedCerts)
+
+	return fields
+}
+
+// mergeSettingsFields returns current settings with
+// given fields replaced by those of desired settings
+func mergeSettingsFields(current, desired Settings, fields []string) Settings {
+	merged := current
+
+	for _, field := range fields {
+		switch field {
+		case SettingsFieldAgentID:
+			merged.AgentID = desired.AgentID
+		case SettingsFieldBlobstore:
+			merged.Blobstore = desired.Blobstore
+		case SettingsFieldDisks:
+			merged.Disks = desired.Disks
+		case SettingsFieldEnv:
+			bosh := merged.Env.Bosh
+			merged.Env = desired.Env
+			merged.Env.Bosh.AuthorizedKeys = bosh.AuthorizedKeys
+			merged.Env.Bosh.TrustedUserCAKeys = bosh.TrustedUserCAKeys
+			merged.Env.Bosh.SSHPrincipals = bosh.SSHPrincipals
+		case SettingsFieldAuthorizedKeys:
+			merged.Env.Bosh.AuthorizedKeys = desired.Env.Bosh.AuthorizedKeys
+		case SettingsFieldTrustedUserCAKeys:
+			merged.Env.Bosh.TrustedUserCAKeys = desired.Env.Bosh.TrustedUserCAKeys
+		case SettingsFieldSSHPrincipals:
+			merged.Env.Bosh.SSHPrincipals = desired.Env.Bosh.SSHPrincipals
+		case SettingsFieldNetworks:
+			merged.Networks = desired.Networks
+		case SettingsFieldNtp:
+			merged.Ntp = desired.Ntp
+		case SettingsFieldMbus:
+			merged.Mbus = desired.Mbus
+		case SettingsFieldVM:
+			merged.VM = desired.VM
+		case SettingsFieldTrustedCerts:
+			merged.TrustedCerts = desired.TrustedCerts
+		}
+	}
+
+	// DNS servers are merged last so that they end up in whichever networks are kept
+	for _, field := range fields {
+		if field != SettingsFieldDNS {
+			continue
+		}
+
+		networks := Networks{}
+		for name, network := range merged.Networks {
+			if desiredNetwork, found := desired.Networks[name]; found {
+				network.DNS = desiredNetwork.DNS
+			}
+			networks[name] = network
+		}
+		merged.Networks = networks
+	}
+
+	return merged
+}
+
+func envWithoutSSHAccess(env Env) Env {
+	env.Bosh.AuthorizedKeys = nil
+	env.Bosh.TrustedUserCAKeys = nil
+	env.Bosh.SSHPrincipals = nil
+	return env
+}
+
+func networksWithoutDNS(networks Networks) Networks {
+	if networks == nil {
+		return nil
+	}
+
+	result := Networks{}
+	for name, network := range networks {
+		network.DNS = nil
+		result[name] = network
+	}
+	return result
+}
+
+func networksDNS(networks Networks) map[string][]string {
+	dns := map[string][]string{}
+	for name, network := range networks {
+		if len(network.DNS) > 0 {
+			dns[name] = network.DNS
+		}
+	}
+	return dns
+}
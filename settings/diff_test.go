@@ -0,0 +1,88 @@
+package settings_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/settings"
+)
+
+var _ = Describe("DiffSettings", func() {
+	var current Settings
+
+	BeforeEach(func() {
+		current = Settings{
+			AgentID: "fake-agent-id",
+			Env: Env{
+				Bosh: BoshEnv{
+					Password:       "fake-password",
+					AuthorizedKeys: []string{"fake-key"},
+				},
+			},
+			Networks: Networks{
+				"fake-net": Network{IP: "10.0.0.10", DNS: []string{"10.0.0.2"}},
+			},
+			Ntp: []string{"fake-ntp-server"},
+		}
+	})
+
+	It("returns no fields when settings are equal", func() {
+		Expect(DiffSettings(current, current)).To(BeEmpty())
+	})
+
+	It("ignores settings version", func() {
+		desired := current
+		desired.Version = 5
+
+		Expect(DiffSettings(current, desired)).To(BeEmpty())
+	})
+
+	It("reports changed top level fields", func() {
+		desired := current
+		desired.AgentID = "fake-new-agent-id"
+		desired.Ntp = []string{"fake-new-ntp-server"}
+		desired.Disks = Disks{System: "/dev/sda"}
+		desired.TrustedCerts = "fake-new-certs"
+
+		Expect(DiffSettings(current, desired)).To(Equal([]string{
+			SettingsFieldAgentID,
+			SettingsFieldDisks,
+			SettingsFieldNtp,
+			SettingsFieldTrustedCerts,
+		}))
+	})
+
+	It("reports changed ssh access separately from the rest of env", func() {
+		desired := current
+		desired.Env.Bosh.AuthorizedKeys = []string{"fake-new-key"}
+		desired.Env.Bosh.TrustedUserCAKeys = []string{"fake-ca-key"}
+
+		Expect(DiffSettings(current, desired)).To(Equal([]string{
+			SettingsFieldAuthorizedKeys,
+			SettingsFieldTrustedUserCAKeys,
+		}))
+
+		desired.Env.Bosh.Password = "fake-new-password"
+
+		Expect(DiffSettings(current, desired)).To(Equal([]string{
+			SettingsFieldEnv,
+			SettingsFieldAuthorizedKeys,
+			SettingsFieldTrustedUserCAKeys,
+		}))
+	})
+
+	It("reports changed DNS servers separately from the rest of networks", func() {
+		desired := current
+		desired.Networks = Networks{
+			"fake-net": Network{IP: "10.0.0.10", DNS: []string{"10.0.0.3"}},
+		}
+
+		Expect(DiffSettings(current, desired)).To(Equal([]string{SettingsFieldDNS}))
+
+		desired.Networks = Networks{
+			"fake-net": Network{IP: "10.0.0.11", DNS: []string{"10.0.0.3"}},
+		}
+
+		Expect(DiffSettings(current, desired)).To(Equal([]string{SettingsFieldNetworks, SettingsFieldDNS}))
+	})
+})
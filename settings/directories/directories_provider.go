@@ -93,3 +93,17 @@ func (p Provider) DisksDir() string {
 func (p Provider) BlobsDir() string {
 	return filepath.Join(p.DataDir(), "blobs")
 }
+
+func (p Provider) SecretsDir() string {
+	return filepath.Join(p.DataDir(), "secrets")
+}
+
+func (p Provider) JobSecretsDir(jobName string) string {
+	return filepath.Join(p.SecretsDir(), jobName)
+}
+
+// SecretsStagingDir is kept outside of secrets dir
+// so that it is not mistaken for job secrets dir
+func (p Provider) SecretsStagingDir() string {
+	return filepath.Join(p.DataDir(), "secrets-staging")
+}
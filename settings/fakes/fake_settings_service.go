@@ -8,13 +8,37 @@ type FakeSettingsService struct {
 	PublicKey    string
 	PublicKeyErr error
 
+	InstanceID       string
+	GetInstanceIDErr error
+
 	LoadSettingsError  error
 	SettingsWereLoaded bool
 
 	InvalidateSettingsError error
 	SettingsWereInvalidated bool
 
+	AppliedUpdateSettings *boshsettings.UpdateSettings
+
 	Settings boshsettings.Settings
+
+	FetchedSettings        boshsettings.Settings
+	FetchedSettingsChanges []string
+	FetchSettingsErr       error
+
+	AppliedSettingsChanges    []string
+	ApplySettingsChangesError error
+}
+
+func (service *FakeSettingsService) ApplyUpdateSettings(updateSettings boshsettings.UpdateSettings) {
+	service.AppliedUpdateSettings = &updateSettings
+}
+
+func (service *FakeSettingsService) GetUpdateSettings() boshsettings.UpdateSettings {
+	if service.AppliedUpdateSettings == nil {
+		return boshsettings.UpdateSettings{}
+	}
+
+	return *service.AppliedUpdateSettings
 }
 
 func (service *FakeSettingsService) InvalidateSettings() error {
@@ -26,6 +50,10 @@ func (service *FakeSettingsService) PublicSSHKeyForUsername(_ string) (string, e
 	return service.PublicKey, service.PublicKeyErr
 }
 
+func (service *FakeSettingsService) GetInstanceID() (string, error) {
+	return service.InstanceID, service.GetInstanceIDErr
+}
+
 func (service *FakeSettingsService) LoadSettings() error {
 	service.SettingsWereLoaded = true
 	return service.LoadSettingsError
@@ -34,3 +62,12 @@ func (service *FakeSettingsService) LoadSettings() error {
 func (service FakeSettingsService) GetSettings() boshsettings.Settings {
 	return service.Settings
 }
+
+func (service *FakeSettingsService) FetchSettingsChanges() (boshsettings.Settings, []string, error) {
+	return service.FetchedSettings, service.FetchedSettingsChanges, service.FetchSettingsErr
+}
+
+func (service *FakeSettingsService) ApplySettingsChanges(fetchedSettings boshsettings.Settings, fields []string) error {
+	service.AppliedSettingsChanges = fields
+	return service.ApplySettingsChangesError
+}
@@ -0,0 +1,15 @@
+package fakes
+
+import (
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+)
+
+type FakeVerifier struct {
+	VerifySettings []boshsettings.Settings
+	VerifyErr      error
+}
+
+func (v *FakeVerifier) Verify(settings boshsettings.Settings) error {
+	v.VerifySettings = append(v.VerifySettings, settings)
+	return v.VerifyErr
+}
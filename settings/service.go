@@ -2,6 +2,9 @@ package settings
 
 import (
 	"encoding/json"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
@@ -17,17 +20,41 @@ type Service interface {
 
 	PublicSSHKeyForUsername(string) (string, error)
 
+	// GetInstanceID returns IaaS instance ID if settings source knows it
+	GetInstanceID() (string, error)
+
 	InvalidateSettings() error
+
+	// ApplyUpdateSettings overrides settings fetched from settings source
+	// with mbus and blobstore credentials rotated through update_settings
+	ApplyUpdateSettings(UpdateSettings)
+
+	// GetUpdateSettings returns update settings last applied
+	GetUpdateSettings() UpdateSettings
+
+	// FetchSettingsChanges returns verified settings from settings source and names
+	// of fields that differ from settings in use; settings in use are left as is
+	FetchSettingsChanges() (Settings, []string, error)
+
+	// ApplySettingsChanges replaces given fields of settings in use with fetched ones
+	ApplySettingsChanges(fetchedSettings Settings, fields []string) error
 }
 
 const settingsServiceLogTag = "settingsService"
 
+// settingsVersionFileName keeps version of last accepted settings next to
+// cached settings; unlike them it survives InvalidateSettings so that older
+// settings are still refused after reboot
+const settingsVersionFileName = "settings_version"
+
 type settingsService struct {
 	fs                     boshsys.FileSystem
 	settingsPath           string
 	settings               Settings
+	updateSettings         UpdateSettings
 	settingsMutex          sync.Mutex
 	settingsSource         Source
+	settingsVerifier       Verifier
 	defaultNetworkResolver DefaultNetworkResolver
 	logger                 boshlog.Logger
 }
@@ -42,6 +69,7 @@ func NewService(
 	fs boshsys.FileSystem,
 	settingsPath string,
 	settingsSource Source,
+	settingsVerifier Verifier,
 	defaultNetworkResolver DefaultNetworkResolver,
 	logger boshlog.Logger,
 ) (service Service) {
@@ -50,6 +78,7 @@ func NewService(
 		settingsPath:           settingsPath,
 		settings:               Settings{},
 		settingsSource:         settingsSource,
+		settingsVerifier:       settingsVerifier,
 		defaultNetworkResolver: defaultNetworkResolver,
 		logger:                 logger,
 	}
@@ -59,6 +88,15 @@ func (s *settingsService) PublicSSHKeyForUsername(username string) (string, erro
 	return s.settingsSource.PublicSSHKeyForUsername(username)
 }
 
+func (s *settingsService) GetInstanceID() (string, error) {
+	instanceIDSource, ok := s.settingsSource.(InstanceIDSource)
+	if !ok {
+		return "", bosherr.Error("Settings source does not provide instance ID")
+	}
+
+	return instanceIDSource.InstanceID()
+}
+
 func (s *settingsService) LoadSettings() error {
 	s.logger.Debug(settingsServiceLogTag, "Loading settings from fetcher")
 
@@ -90,16 +128,67 @@ func (s *settingsService) LoadSettings() error {
 	}
 
 	s.logger.Debug(settingsServiceLogTag, "Successfully received settings from fetcher")
+
+	// Verification errors are returned as is so that callers can tell them
+	// apart; settings must not fall back to cached ones in that case
+	err := s.verifySettings(newSettings)
+	if err != nil {
+		s.logger.Error(settingsServiceLogTag, "Failed verifying settings: %s", err.Error())
+		return err
+	}
+
+	s.settingsMutex.Lock()
+	s.settings = newSettings
+	s.settingsMutex.Unlock()
+
+	return s.writeSettings(newSettings)
+}
+
+func (s *settingsService) FetchSettingsChanges() (Settings, []string, error) {
+	fetchedSettings, err := s.settingsSource.Settings()
+	if err != nil {
+		return Settings{}, nil, bosherr.WrapError(err, "Invoking settings fetcher")
+	}
+
+	err = s.verifySettings(fetchedSettings)
+	if err != nil {
+		return Settings{}, nil, err
+	}
+
+	s.settingsMutex.Lock()
+	currentSettings := s.settings
+	s.settingsMutex.Unlock()
+
+	return fetchedSettings, DiffSettings(currentSettings, fetchedSettings), nil
+}
+
+func (s *settingsService) ApplySettingsChanges(fetchedSettings Settings, fields []string) error {
 	s.settingsMutex.Lock()
+	newSettings := mergeSettingsFields(s.settings, fetchedSettings, fields)
+	if fetchedSettings.Version > newSettings.Version {
+		newSettings.Version = fetchedSettings.Version
+	}
 	s.settings = newSettings
 	s.settingsMutex.Unlock()
 
-	newSettingsJSON, err := json.Marshal(newSettings)
+	return s.writeSettings(newSettings)
+}
+
+func (s *settingsService) writeSettings(settings Settings) error {
+	settingsJSON, err := json.Marshal(settings)
 	if err != nil {
 		return bosherr.WrapError(err, "Marshalling settings json")
 	}
 
-	err = s.fs.WriteFile(s.settingsPath, newSettingsJSON)
+	// Version is recorded first so that it cannot fall behind cached settings
+	if settings.Version > s.recordedVersion() {
+		err = s.fs.WriteFileString(s.settingsVersionPath(), strconv.FormatUint(settings.Version, 10))
+		if err != nil {
+			return bosherr.WrapError(err, "Writing settings version")
+		}
+	}
+
+	err = s.fs.WriteFile(s.settingsPath, settingsJSON)
 	if err != nil {
 		return bosherr.WrapError(err, "Writing setting json")
 	}
@@ -107,12 +196,77 @@ func (s *settingsService) LoadSettings() error {
 	return nil
 }
 
+func (s *settingsService) settingsVersionPath() string {
+	return filepath.Join(filepath.Dir(s.settingsPath), settingsVersionFileName)
+}
+
+func (s *settingsService) recordedVersion() uint64 {
+	if !s.fs.FileExists(s.settingsVersionPath()) {
+		return 0
+	}
+
+	contents, err := s.fs.ReadFileString(s.settingsVersionPath())
+	if err != nil {
+		s.logger.Error(settingsServiceLogTag, "Failed reading settings version: %s", err.Error())
+		return 0
+	}
+
+	version, err := strconv.ParseUint(strings.TrimSpace(contents), 10, 64)
+	if err != nil {
+		s.logger.Error(settingsServiceLogTag, "Failed parsing settings version: %s", err.Error())
+		return 0
+	}
+
+	return version
+}
+
+func (s *settingsService) verifySettings(newSettings Settings) error {
+	err := s.settingsVerifier.Verify(newSettings)
+	if err != nil {
+		return err
+	}
+
+	s.settingsMutex.Lock()
+	acceptedVersion := s.settings.Version
+	s.settingsMutex.Unlock()
+
+	if recordedVersion := s.recordedVersion(); recordedVersion > acceptedVersion {
+		acceptedVersion = recordedVersion
+	}
+
+	// Cached settings written before version was recorded separately
+	if s.fs.FileExists(s.settingsPath) {
+		var cachedSettings Settings
+
+		cachedSettingsJSON, err := s.fs.ReadFile(s.settingsPath)
+		if err == nil && json.Unmarshal(cachedSettingsJSON, &cachedSettings) == nil {
+			if cachedSettings.Version > acceptedVersion {
+				acceptedVersion = cachedSettings.Version
+			}
+		}
+	}
+
+	if newSettings.Version < acceptedVersion {
+		return NewVerificationError("Refusing to roll back settings from version %d to %d", acceptedVersion, newSettings.Version)
+	}
+
+	return nil
+}
+
 // GetSettings returns setting even if it fails to resolve IPs for dynamic networks.
 func (s *settingsService) GetSettings() Settings {
 	s.settingsMutex.Lock()
 
 	settingsCopy := s.settings
 
+	if s.updateSettings.Mbus != "" {
+		settingsCopy.Mbus = s.updateSettings.Mbus
+	}
+
+	if s.updateSettings.Blobstore != nil {
+		settingsCopy.Blobstore = *s.updateSettings.Blobstore
+	}
+
 	if s.settings.Networks != nil {
 		settingsCopy.Networks = make(map[string]Network)
 	}
@@ -137,6 +291,19 @@ func (s *settingsService) GetSettings() Settings {
 	return settingsCopy
 }
 
+func (s *settingsService) ApplyUpdateSettings(updateSettings UpdateSettings) {
+	s.settingsMutex.Lock()
+	s.updateSettings = updateSettings
+	s.settingsMutex.Unlock()
+}
+
+func (s *settingsService) GetUpdateSettings() UpdateSettings {
+	s.settingsMutex.Lock()
+	defer s.settingsMutex.Unlock()
+
+	return s.updateSettings
+}
+
 func (s *settingsService) InvalidateSettings() error {
 	err := s.fs.RemoveAll(s.settingsPath)
 	if err != nil {
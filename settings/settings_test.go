@@ -2,6 +2,7 @@ package settings_test
 
 import (
 	"encoding/json"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -73,6 +74,29 @@ var _ = Describe("Settings", func() {
 					}))
 				})
 
+				It("gets mkfs and mount options from env", func() {
+					settingsJSON := `{"env": {
+						"persistent_disk_fs": "ext4",
+						"persistent_disk_mkfs_options": ["inode_ratio=65536"],
+						"persistent_disk_mount_options": ["noatime", "discard"]
+					}}`
+
+					err := json.Unmarshal([]byte(settingsJSON), &settings)
+					Expect(err).NotTo(HaveOccurred())
+					diskSettings, _ := settings.PersistentDiskSettings("fake-disk-id")
+					Expect(diskSettings).To(Equal(DiskSettings{
+						ID:             "fake-disk-id",
+						DeviceID:       "fake-disk-device-id",
+						VolumeID:       "fake-disk-volume-id",
+						Path:           "fake-disk-path",
+						Lun:            "fake-disk-lun",
+						HostDeviceID:   "fake-disk-host-device-id",
+						FileSystemType: disk.FileSystemExt4,
+						MkfsOptions:    []string{"inode_ratio=65536"},
+						MountOptions:   []string{"noatime", "discard"},
+					}))
+				})
+
 				It("does not crash if env does not have a filesystem type", func() {
 					settingsJSON := `{"env": {"bosh": {"password": "secret"}}}`
 
@@ -316,6 +340,38 @@ var _ = Describe("Settings", func() {
 		})
 	})
 
+	Describe("DiskAssociation", func() {
+		Describe("ResolveMountPoint", func() {
+			It("defaults to a directory named after the disk within store dir", func() {
+				mountPoint, err := DiskAssociation{Name: "fake-disk-name"}.ResolveMountPoint("/var/vcap/store")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(mountPoint).To(Equal("/var/vcap/store/fake-disk-name"))
+			})
+
+			It("returns an error when disk name cannot be used as a directory name", func() {
+				for _, name := range []string{"", ".", "..", "fake/disk"} {
+					_, err := DiskAssociation{Name: name}.ResolveMountPoint("/var/vcap/store")
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("cannot be used as mount point"))
+				}
+			})
+
+			It("returns declared mount point when it is within store dir", func() {
+				mountPoint, err := DiskAssociation{Name: "fake-disk-name", MountPoint: "/var/vcap/store/data/"}.ResolveMountPoint("/var/vcap/store")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(mountPoint).To(Equal("/var/vcap/store/data"))
+			})
+
+			It("returns an error when declared mount point is outside of store dir", func() {
+				for _, declared := range []string{"/var/vcap/store", "/var/vcap/data/fake", "/var/vcap/store/../data", "/var/vcap/storefake"} {
+					_, err := DiskAssociation{Name: "fake-disk-name", MountPoint: declared}.ResolveMountPoint("/var/vcap/store")
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("must be within '/var/vcap/store'"))
+				}
+			})
+		})
+	})
+
 	Describe("DefaultNetworkFor", func() {
 		Context("when networks is empty", func() {
 			It("returns found=false", func() {
@@ -661,6 +717,28 @@ var _ = Describe("Settings", func() {
 				Expect(env.GetSwapSizeInBytes()).To(BeNil())
 			})
 		})
+
+		Describe("GetTrustedCertsExpiryWindow", func() {
+			It("returns configured window", func() {
+				var env Env
+				err := json.Unmarshal([]byte(`{"bosh": {"trusted_certs_expiry_window_days": 7}}`), &env)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(env.GetTrustedCertsExpiryWindow()).To(Equal(7 * 24 * time.Hour))
+			})
+
+			It("returns zero window when expiry alerts are disabled", func() {
+				var env Env
+				err := json.Unmarshal([]byte(`{"bosh": {"trusted_certs_expiry_window_days": 0}}`), &env)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(env.GetTrustedCertsExpiryWindow()).To(BeZero())
+			})
+
+			It("returns 30 days when window is not configured", func() {
+				Expect(Env{}.GetTrustedCertsExpiryWindow()).To(Equal(30 * 24 * time.Hour))
+			})
+		})
 	})
 
 	Describe("UpdateSettings", func() {
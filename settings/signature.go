@@ -0,0 +1,58 @@
+package settings
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// Signature is a detached signature over exact settings bytes
+// since re-marshalling settings does not preserve them
+type Signature struct {
+	Payload []byte
+	Value   []byte
+}
+
+// signedEnvelope wraps base64 encoded settings JSON and
+// signature over it, e.g. {"signed_settings": "...", "signature": "..."}
+type signedEnvelope struct {
+	SignedSettings string `json:"signed_settings"`
+	Signature      string `json:"signature"`
+}
+
+type unsignedSettings Settings
+
+// UnmarshalJSON accepts both plain and signed settings
+// so that every settings source supports signed settings
+func (s *Settings) UnmarshalJSON(data []byte) error {
+	var envelope signedEnvelope
+
+	err := json.Unmarshal(data, &envelope)
+	if err != nil {
+		return err
+	}
+
+	if envelope.SignedSettings == "" {
+		return json.Unmarshal(data, (*unsignedSettings)(s))
+	}
+
+	payload, err := base64.StdEncoding.DecodeString(envelope.SignedSettings)
+	if err != nil {
+		return bosherr.WrapError(err, "Decoding signed settings")
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(envelope.Signature)
+	if err != nil {
+		return bosherr.WrapError(err, "Decoding settings signature")
+	}
+
+	err = json.Unmarshal(payload, (*unsignedSettings)(s))
+	if err != nil {
+		return bosherr.WrapError(err, "Unmarshalling signed settings")
+	}
+
+	s.Signature = &Signature{Payload: payload, Value: signature}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package settings
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// VerificationError is returned for settings which were fetched
+// successfully but must not be trusted
+type VerificationError struct {
+	reason string
+}
+
+func NewVerificationError(format string, args ...interface{}) VerificationError {
+	return VerificationError{reason: fmt.Sprintf(format, args...)}
+}
+
+func (e VerificationError) Error() string {
+	return e.reason
+}
+
+type Verifier interface {
+	Verify(settings Settings) error
+}
+
+type signatureVerifier struct {
+	publicKey crypto.PublicKey
+}
+
+type noopVerifier struct{}
+
+// NewVerifier returns a verifier requiring settings to be signed with the RSA or
+// ECDSA private key matching PEM encoded public key; without public key settings
+// are not verified
+func NewVerifier(publicKeyPEM string) (Verifier, error) {
+	if publicKeyPEM == "" {
+		return noopVerifier{}, nil
+	}
+
+	block, _ := pem.Decode([]byte(publicKeyPEM))
+	if block == nil {
+		return nil, bosherr.Error("Decoding settings public key PEM")
+	}
+
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Parsing settings public key")
+	}
+
+	switch publicKey.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return signatureVerifier{publicKey: publicKey}, nil
+	}
+
+	return nil, bosherr.Errorf("Unsupported settings public key type %T", publicKey)
+}
+
+func (v signatureVerifier) Verify(settings Settings) error {
+	if settings.Signature == nil {
+		return NewVerificationError("Expected settings to be signed")
+	}
+
+	payload := settings.Signature.Payload
+	signature := settings.Signature.Value
+	digest := sha256.Sum256(payload)
+
+	var verified bool
+
+	switch publicKey := v.publicKey.(type) {
+	case *rsa.PublicKey:
+		verified = rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature) == nil
+	case *ecdsa.PublicKey:
+		verified = verifyECDSA(publicKey, digest[:], signature)
+	}
+
+	if !verified {
+		return NewVerificationError("Settings signature does not match settings public key")
+	}
+
+	return nil
+}
+
+// ecdsaSignature is ASN.1 DER structure ECDSA signatures are encoded in
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+func verifyECDSA(publicKey *ecdsa.PublicKey, digest, signature []byte) bool {
+	var parsed ecdsaSignature
+
+	rest, err := asn1.Unmarshal(signature, &parsed)
+	if err != nil || len(rest) > 0 || parsed.R == nil || parsed.S == nil {
+		return false
+	}
+
+	return ecdsa.Verify(publicKey, digest, parsed.R, parsed.S)
+}
+
+func (v noopVerifier) Verify(_ Settings) error {
+	return nil
+}
@@ -0,0 +1,144 @@
+package settings_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/settings"
+)
+
+var _ = Describe("Verifier", func() {
+	var (
+		payload []byte
+	)
+
+	encodePublicKey := func(publicKey crypto.PublicKey) string {
+		der, err := x509.MarshalPKIXPublicKey(publicKey)
+		Expect(err).ToNot(HaveOccurred())
+
+		return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	}
+
+	signedSettings := func(payload, signature []byte) Settings {
+		envelope := fmt.Sprintf(
+			`{"signed_settings":%q,"signature":%q}`,
+			base64.StdEncoding.EncodeToString(payload),
+			base64.StdEncoding.EncodeToString(signature),
+		)
+
+		var settings Settings
+
+		err := json.Unmarshal([]byte(envelope), &settings)
+		Expect(err).ToNot(HaveOccurred())
+
+		return settings
+	}
+
+	BeforeEach(func() {
+		payload = []byte(`{"agent_id":"fake-agent-id","version":7}`)
+	})
+
+	It("unmarshals signed settings envelope", func() {
+		settings := signedSettings(payload, []byte("fake-signature"))
+
+		Expect(settings.AgentID).To(Equal("fake-agent-id"))
+		Expect(settings.Version).To(Equal(uint64(7)))
+		Expect(settings.Signature).To(Equal(&Signature{Payload: payload, Value: []byte("fake-signature")}))
+	})
+
+	Context("without public key", func() {
+		It("accepts unsigned settings", func() {
+			verifier, err := NewVerifier("")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(verifier.Verify(Settings{AgentID: "fake-agent-id"})).To(Succeed())
+		})
+	})
+
+	Context("with ecdsa public key", func() {
+		var (
+			privateKey *ecdsa.PrivateKey
+			verifier   Verifier
+		)
+
+		sign := func(payload []byte) []byte {
+			digest := sha256.Sum256(payload)
+
+			r, s, err := ecdsa.Sign(rand.Reader, privateKey, digest[:])
+			Expect(err).ToNot(HaveOccurred())
+
+			signature, err := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+			Expect(err).ToNot(HaveOccurred())
+
+			return signature
+		}
+
+		BeforeEach(func() {
+			var err error
+
+			privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+
+			verifier, err = NewVerifier(encodePublicKey(&privateKey.PublicKey))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("accepts settings signed with matching private key", func() {
+			settings := signedSettings(payload, sign(payload))
+			Expect(verifier.Verify(settings)).To(Succeed())
+		})
+
+		It("rejects tampered settings", func() {
+			settings := signedSettings([]byte(`{"agent_id":"fake-agent-id","version":8}`), sign(payload))
+
+			err := verifier.Verify(settings)
+			Expect(err).To(Equal(NewVerificationError("Settings signature does not match settings public key")))
+		})
+
+		It("rejects signatures which are not ASN.1 encoded", func() {
+			err := verifier.Verify(signedSettings(payload, []byte("fake-signature")))
+			Expect(err).To(Equal(NewVerificationError("Settings signature does not match settings public key")))
+		})
+
+		It("rejects unsigned settings", func() {
+			err := verifier.Verify(Settings{AgentID: "fake-agent-id"})
+			Expect(err).To(Equal(NewVerificationError("Expected settings to be signed")))
+		})
+	})
+
+	Context("with rsa public key", func() {
+		It("accepts settings signed with matching private key", func() {
+			privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+			Expect(err).ToNot(HaveOccurred())
+
+			verifier, err := NewVerifier(encodePublicKey(&privateKey.PublicKey))
+			Expect(err).ToNot(HaveOccurred())
+
+			digest := sha256.Sum256(payload)
+			signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, digest[:])
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(verifier.Verify(signedSettings(payload, signature))).To(Succeed())
+		})
+	})
+
+	It("returns an error when public key is not PEM encoded", func() {
+		_, err := NewVerifier("fake-public-key")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Decoding settings public key PEM"))
+	})
+})